@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// Tracker state of a busy runner being drained. It finishes its job and, being ephemeral,
+// is removed afterwards; until then it is not counted as idle.
+const runnerStateDraining = "draining"
+
+// requireAdmin wraps an admin handler with bearer token authentication against ADMIN_TOKEN,
+// accepting only the given method
+func (s *MessageQueueScaler) requireAdmin(method string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.config.AdminToken)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.Method != method {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// handleAdminStatus reports whether scaling is paused and the limits in effect
+func (s *MessageQueueScaler) handleAdminStatus(w http.ResponseWriter, r *http.Request) {
+	minRunners, maxRunners := s.scalingLimits()
+	s.runnerTracker.mu.RLock()
+	tracked := len(s.runnerTracker.instances)
+	s.runnerTracker.mu.RUnlock()
+	s.limitsMu.RLock()
+	limitWindow := ""
+	if s.limitWindow != nil {
+		limitWindow = s.limitWindow.Spec
+	}
+	s.limitsMu.RUnlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"paused":         s.paused.Load(),
+		"minRunners":     minRunners,
+		"maxRunners":     maxRunners,
+		"limitWindow":    limitWindow,
+		"trackedRunners": tracked,
+	})
+}
+
+// handleAdminPause switches maintenance mode on
+func (s *MessageQueueScaler) handleAdminPause(w http.ResponseWriter, r *http.Request) {
+	s.setPaused(true, "admin", r.RemoteAddr)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"paused": true})
+}
+
+// handleAdminResume switches maintenance mode off and reconciles right away
+func (s *MessageQueueScaler) handleAdminResume(w http.ResponseWriter, r *http.Request) {
+	s.setPaused(false, "admin", r.RemoteAddr)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"paused": false})
+}
+
+// handleAdminLimits sets the min and/or max runners at runtime from a JSON body such as
+// {"minRunners": 2, "maxRunners": 20}. The limits hold until the process restarts or the
+// AppConfig scaling policy changes.
+func (s *MessageQueueScaler) handleAdminLimits(w http.ResponseWriter, r *http.Request) {
+	var request ScalingPolicy
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "invalid limits: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.limitsMu.Lock()
+	minRunners, maxRunners, err := policyLimits(s.minRunners, s.maxRunners, &request)
+	if err != nil {
+		s.limitsMu.Unlock()
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.logger.Info("Admin action: runner limits set",
+		"minRunners", minRunners, "previousMinRunners", s.minRunners,
+		"maxRunners", maxRunners, "previousMaxRunners", s.maxRunners,
+		"remote", r.RemoteAddr)
+	s.minRunners, s.maxRunners = minRunners, maxRunners
+	s.limitsMu.Unlock()
+
+	s.requestReconcile()
+	writeJSON(w, http.StatusOK, map[string]interface{}{"minRunners": minRunners, "maxRunners": maxRunners})
+}
+
+// handleAdminReconcile makes the polling loop recalculate the desired runners from the
+// last statistics without waiting for the next message
+func (s *MessageQueueScaler) handleAdminReconcile(w http.ResponseWriter, r *http.Request) {
+	s.requestReconcile()
+	s.logger.Info("Admin action: reconcile requested", "remote", r.RemoteAddr)
+	writeJSON(w, http.StatusAccepted, map[string]interface{}{"reconcile": "requested"})
+}
+
+// handleAdminDrain drains one runner, named by runner name or instance ID in a JSON body
+// such as {"runner": "i-0abc"}. An idle runner is terminated; a busy one finishes its job.
+func (s *MessageQueueScaler) handleAdminDrain(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Runner string `json:"runner"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.Runner == "" {
+		http.Error(w, "expected a JSON body with the runner name or instance ID", http.StatusBadRequest)
+		return
+	}
+
+	instance, state, err := s.drainRunner(r.Context(), request.Runner)
+	if err != nil {
+		http.Error(w, "failed to drain runner: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	if instance == nil {
+		http.Error(w, "no tracked runner "+request.Runner, http.StatusNotFound)
+		return
+	}
+	s.logger.Info("Admin action: runner drained", "runner", request.Runner,
+		"instanceId", instance.InstanceID, "state", state, "remote", r.RemoteAddr)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"runnerName": instance.RunnerName,
+		"instanceId": instance.InstanceID,
+		"state":      state,
+	})
+}
+
+// drainRunner removes the runner when it is idle and otherwise marks it draining. It
+// returns the runner and its new state, or nil when the runner is not tracked.
+func (s *MessageQueueScaler) drainRunner(ctx context.Context, runner string) (*EC2RunnerInstance, string, error) {
+	s.runnerTracker.mu.Lock()
+	found := s.runnerTracker.findByRunner(0, runner)
+	if found == nil {
+		s.runnerTracker.mu.Unlock()
+		return nil, "", nil
+	}
+	instance := *found
+	if instance.JobID != 0 {
+		found.State = runnerStateDraining
+		s.runnerTracker.mu.Unlock()
+		return &instance, runnerStateDraining, nil
+	}
+	s.runnerTracker.mu.Unlock()
+
+	if err := s.removeRunner(ctx, &instance); err != nil {
+		return nil, "", err
+	}
+	s.runnerTracker.mu.Lock()
+	delete(s.runnerTracker.instances, instance.InstanceID)
+	s.runnerTracker.mu.Unlock()
+
+	if instance.RunnerName != "" {
+		if err := s.runnerStore.UpdateStatus(ctx, instance.RunnerName, instance.InstanceID, runnerStatusRemoved); err != nil {
+			s.logger.Error(err, "Failed to record drained runner", "runnerName", instance.RunnerName)
+		}
+	}
+	return &instance, "terminated", nil
+}
+
+// requestReconcile asks the polling loop for a reconcile; requests made while one is
+// pending are merged
+func (s *MessageQueueScaler) requestReconcile() {
+	select {
+	case s.reconcileRequests <- struct{}{}:
+	default:
+	}
+}
+
+// reconcile recalculates the desired runners from the last statistics
+func (s *MessageQueueScaler) reconcile(ctx context.Context) {
+	s.mu.RLock()
+	assignedJobs := 0
+	if s.lastStatistics != nil {
+		assignedJobs = s.lastStatistics.TotalAssignedJobs
+	}
+	s.mu.RUnlock()
+
+	if _, err := s.handleDesiredRunnerCount(ctx, assignedJobs, 0); err != nil {
+		s.logger.Error(err, "Forced reconcile failed")
+		s.metrics.Count(metricErrors, 1)
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+)
+
+const testAdminAPIToken = "admin-api-token"
+
+// fakeProvider records the runners terminated through it
+type fakeProvider struct {
+	terminated []string
+}
+
+func (p *fakeProvider) CreateRunner(ctx context.Context, spec RunnerSpec) (string, error) {
+	return "", nil
+}
+
+func (p *fakeProvider) TerminateRunner(ctx context.Context, id string) error {
+	p.terminated = append(p.terminated, id)
+	return nil
+}
+
+func (p *fakeProvider) ListRunners(ctx context.Context) ([]ProvisionedRunner, error) {
+	return nil, nil
+}
+
+func (p *fakeProvider) GetCapacity(ctx context.Context) (int, error) {
+	return unlimitedCapacity, nil
+}
+
+// newAdminTestServer registers a scaler's endpoints under prefix with the given ADMIN_TOKEN
+func newAdminTestServer(t *testing.T, adminToken string, provider RunnerProvider, prefix string) (*MessageQueueScaler, http.Handler) {
+	t.Helper()
+	cfg := &Config{RunnerScaleSetName: "ghaec2-scaler", AdminToken: adminToken, MaxRunners: 10}
+	metrics := NewMetricsPublisher(nil, "test", cfg.RunnerScaleSetName, "eu-north-1", logr.Discard())
+	runnerStore := NewRunnerStore(nil, "", 0, logr.Discard())
+	s := NewMessageQueueScaler(cfg, provider, metrics, nil, nil, runnerStore, nil, nil, nil, nil, logr.Discard())
+	h := NewHTTPServer(":0", logr.Discard())
+	s.registerHandlers(h, prefix)
+	return s, h.mux
+}
+
+// adminRequest sends an admin API request with the given bearer token
+func adminRequest(handler http.Handler, method, path, token, body string) *httptest.ResponseRecorder {
+	var req *http.Request
+	if body == "" {
+		req = httptest.NewRequest(method, path, nil)
+	} else {
+		req = httptest.NewRequest(method, path, strings.NewReader(body))
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAdminStatusOfPool(t *testing.T) {
+	_, handler := newAdminTestServer(t, testAdminAPIToken, nil, "/pools/gpu")
+
+	if rec := adminRequest(handler, http.MethodGet, "/pools/gpu/admin/status", testAdminAPIToken, ""); rec.Code != http.StatusOK {
+		t.Errorf("GET /pools/gpu/admin/status = %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+	if rec := adminRequest(handler, http.MethodPost, "/pools/gpu/admin/pause", testAdminAPIToken, ""); rec.Code != http.StatusOK {
+		t.Errorf("POST /pools/gpu/admin/pause = %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestAdminAuthentication(t *testing.T) {
+	_, handler := newAdminTestServer(t, testAdminAPIToken, nil, "")
+	tests := []struct {
+		name  string
+		token string
+		want  int
+	}{
+		{"missing token", "", http.StatusUnauthorized},
+		{"wrong token", "wrong-token", http.StatusUnauthorized},
+		{"correct token", testAdminAPIToken, http.StatusOK},
+	}
+	for _, tt := range tests {
+		rec := adminRequest(handler, http.MethodGet, "/admin/status", tt.token, "")
+		if rec.Code != tt.want {
+			t.Errorf("%s: GET /admin/status = %d, want %d", tt.name, rec.Code, tt.want)
+		}
+		if tt.want == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") != "Bearer" {
+			t.Errorf("%s: WWW-Authenticate = %q, want Bearer", tt.name, rec.Header().Get("WWW-Authenticate"))
+		}
+	}
+}
+
+func TestAdminMethods(t *testing.T) {
+	s, handler := newAdminTestServer(t, testAdminAPIToken, nil, "")
+	tests := []struct {
+		method string
+		path   string
+		want   int
+	}{
+		{http.MethodGet, "/admin/status", http.StatusOK},
+		{http.MethodPost, "/admin/status", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/admin/pause", http.StatusMethodNotAllowed},
+		{http.MethodPost, "/admin/pause", http.StatusOK},
+		{http.MethodDelete, "/admin/resume", http.StatusMethodNotAllowed},
+		{http.MethodPost, "/admin/resume", http.StatusOK},
+	}
+	for _, tt := range tests {
+		if rec := adminRequest(handler, tt.method, tt.path, testAdminAPIToken, ""); rec.Code != tt.want {
+			t.Errorf("%s %s = %d, want %d", tt.method, tt.path, rec.Code, tt.want)
+		}
+	}
+	if s.paused.Load() {
+		t.Error("scaler is paused after pause and resume")
+	}
+}
+
+func TestAdminDrain(t *testing.T) {
+	provider := &fakeProvider{}
+	s, handler := newAdminTestServer(t, testAdminAPIToken, provider, "")
+	s.runnerTracker.instances["i-busy"] = &EC2RunnerInstance{InstanceID: "i-busy", RunnerName: "runner-busy", State: "running", JobID: 7}
+	s.runnerTracker.instances["i-idle"] = &EC2RunnerInstance{InstanceID: "i-idle", RunnerName: "runner-idle", State: "running"}
+
+	tests := []struct {
+		runner    string
+		want      int
+		wantState string
+	}{
+		{"runner-busy", http.StatusOK, runnerStateDraining},
+		{"i-idle", http.StatusOK, "terminated"},
+		{"runner-unknown", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		rec := adminRequest(handler, http.MethodPost, "/admin/drain", testAdminAPIToken, `{"runner":"`+tt.runner+`"}`)
+		if rec.Code != tt.want {
+			t.Fatalf("drain %s = %d, want %d: %s", tt.runner, rec.Code, tt.want, rec.Body.String())
+		}
+		if tt.wantState == "" {
+			continue
+		}
+		var response struct {
+			State string `json:"state"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&response); err != nil || response.State != tt.wantState {
+			t.Errorf("drain %s state = %q (%v), want %q", tt.runner, response.State, err, tt.wantState)
+		}
+	}
+
+	// The busy runner finishes its job; only the idle one is terminated and forgotten
+	if len(provider.terminated) != 1 || provider.terminated[0] != "i-idle" {
+		t.Errorf("terminated = %v, want only i-idle", provider.terminated)
+	}
+	if busy := s.runnerTracker.instances["i-busy"]; busy == nil || busy.State != runnerStateDraining {
+		t.Errorf("busy runner = %+v, want it tracked as draining", busy)
+	}
+	if _, ok := s.runnerTracker.instances["i-idle"]; ok {
+		t.Error("idle runner is still tracked after draining")
+	}
+}
+
+func TestAdminRoutesRequireAdminToken(t *testing.T) {
+	_, handler := newAdminTestServer(t, "", nil, "")
+	for _, path := range []string{"/admin/status", "/admin/pause", "/admin/drain"} {
+		if rec := adminRequest(handler, http.MethodPost, path, "", ""); rec.Code != http.StatusNotFound {
+			t.Errorf("POST %s without ADMIN_TOKEN = %d, want 404", path, rec.Code)
+		}
+	}
+}
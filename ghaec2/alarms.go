@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	cwtypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/go-logr/logr"
+)
+
+// alarmPeriodSeconds is the evaluation period used by all scaler alarms
+const alarmPeriodSeconds = 300
+
+// AlarmProvisioner creates the CloudWatch alarms that monitor the scaler
+type AlarmProvisioner struct {
+	client *cloudwatch.Client
+	config *Config
+	logger logr.Logger
+}
+
+// NewAlarmProvisioner creates a new alarm provisioner
+func NewAlarmProvisioner(client *cloudwatch.Client, config *Config, logger logr.Logger) *AlarmProvisioner {
+	return &AlarmProvisioner{
+		client: client,
+		config: config,
+		logger: logger,
+	}
+}
+
+// EnsureAlarms creates or updates the scaler alarms. PutMetricAlarm is an upsert, so this is safe to run on every start.
+func (p *AlarmProvisioner) EnsureAlarms(ctx context.Context) error {
+	for _, alarm := range p.buildAlarms() {
+		if _, err := p.client.PutMetricAlarm(ctx, alarm); err != nil {
+			return fmt.Errorf("failed to create alarm %s: %w", aws.ToString(alarm.AlarmName), err)
+		}
+		p.logger.Info("CloudWatch alarm provisioned", "alarm", aws.ToString(alarm.AlarmName))
+	}
+
+	return nil
+}
+
+// buildAlarms returns the alarm definitions for the current configuration
+func (p *AlarmProvisioner) buildAlarms() []*cloudwatch.PutMetricAlarmInput {
+	prefix := p.config.RunnerScaleSetName
+	scaleSetDimension := metricDimensions(p.config.RunnerScaleSetName, p.config.AWSRegion)
+
+	alarms := []*cloudwatch.PutMetricAlarmInput{
+		{
+			AlarmName:          aws.String(prefix + "-error-rate"),
+			AlarmDescription:   aws.String("Scaler is reporting errors while polling or handling messages"),
+			Namespace:          aws.String(p.config.CloudWatchNamespace),
+			MetricName:         aws.String(metricErrors),
+			Dimensions:         scaleSetDimension,
+			Statistic:          cwtypes.StatisticSum,
+			Period:             aws.Int32(alarmPeriodSeconds),
+			EvaluationPeriods:  aws.Int32(1),
+			Threshold:          aws.Float64(float64(p.config.AlarmErrorThreshold)),
+			ComparisonOperator: cwtypes.ComparisonOperatorGreaterThanOrEqualToThreshold,
+			TreatMissingData:   aws.String("notBreaching"),
+		},
+		{
+			AlarmName:          aws.String(prefix + "-no-successful-polls"),
+			AlarmDescription:   aws.String("Scaler has not completed a successful message queue poll"),
+			Namespace:          aws.String(p.config.CloudWatchNamespace),
+			MetricName:         aws.String(metricSuccessfulPolls),
+			Dimensions:         scaleSetDimension,
+			Statistic:          cwtypes.StatisticSum,
+			Period:             aws.Int32(alarmPeriodSeconds),
+			EvaluationPeriods:  aws.Int32(1),
+			Threshold:          aws.Float64(1),
+			ComparisonOperator: cwtypes.ComparisonOperatorLessThanThreshold,
+			// No data at all means the scaler is down, which is exactly what this alarm is for
+			TreatMissingData: aws.String("breaching"),
+		},
+		{
+			AlarmName:        aws.String(prefix + "-heartbeat-missing"),
+			AlarmDescription: aws.String("Scaler polling loop has stopped emitting heartbeats and may be wedged"),
+			Namespace:        aws.String(p.config.CloudWatchNamespace),
+			MetricName:       aws.String(metricHeartbeat),
+			Dimensions:       scaleSetDimension,
+			Statistic:        cwtypes.StatisticSampleCount,
+			Period:           aws.Int32(60),
+			// Long polls can legitimately take close to a minute, so require several empty minutes
+			EvaluationPeriods:  aws.Int32(p.config.HeartbeatAlarmMinutes),
+			Threshold:          aws.Float64(1),
+			ComparisonOperator: cwtypes.ComparisonOperatorLessThanThreshold,
+			TreatMissingData:   aws.String("breaching"),
+		},
+		{
+			AlarmName:          aws.String(prefix + "-queued-jobs"),
+			AlarmDescription:   aws.String("Jobs are queued for the scale set above the configured threshold"),
+			Namespace:          aws.String(p.config.CloudWatchNamespace),
+			MetricName:         aws.String(metricQueuedJobs),
+			Dimensions:         scaleSetDimension,
+			Statistic:          cwtypes.StatisticMaximum,
+			Period:             aws.Int32(alarmPeriodSeconds),
+			EvaluationPeriods:  aws.Int32(2),
+			Threshold:          aws.Float64(float64(p.config.AlarmQueuedJobsThreshold)),
+			ComparisonOperator: cwtypes.ComparisonOperatorGreaterThanThreshold,
+			TreatMissingData:   aws.String("notBreaching"),
+		},
+	}
+
+	// Lambda failures are only relevant when the Lambda scaler is deployed alongside
+	if p.config.LambdaFunctionName != "" {
+		alarms = append(alarms, &cloudwatch.PutMetricAlarmInput{
+			AlarmName:        aws.String(p.config.LambdaFunctionName + "-failures"),
+			AlarmDescription: aws.String("Scaler Lambda invocations are failing"),
+			Namespace:        aws.String("AWS/Lambda"),
+			MetricName:       aws.String("Errors"),
+			Dimensions: []cwtypes.Dimension{
+				{Name: aws.String("FunctionName"), Value: aws.String(p.config.LambdaFunctionName)},
+			},
+			Statistic:          cwtypes.StatisticSum,
+			Period:             aws.Int32(alarmPeriodSeconds),
+			EvaluationPeriods:  aws.Int32(1),
+			Threshold:          aws.Float64(1),
+			ComparisonOperator: cwtypes.ComparisonOperatorGreaterThanOrEqualToThreshold,
+			TreatMissingData:   aws.String("notBreaching"),
+		})
+	}
+
+	if p.config.AlarmSNSTopicARN != "" {
+		for _, alarm := range alarms {
+			alarm.AlarmActions = []string{p.config.AlarmSNSTopicARN}
+			alarm.OKActions = []string{p.config.AlarmSNSTopicARN}
+		}
+	}
+
+	return alarms
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-logr/logr"
+)
+
+// GHESCapabilities lists the server features the scaler depends on
+type GHESCapabilities struct {
+	Version      string // empty when the server does not report one (GHE.com / GitHub.com)
+	ScaleSetsAPI bool   // runner scale sets and message sessions
+	LongPolling  bool   // long-polling the scale set message queue
+	JITConfig    bool   // just-in-time runner configuration
+}
+
+// minimumGHESVersions is the first GHES release that ships each feature
+var minimumGHESVersions = []struct {
+	feature string
+	major   int
+	minor   int
+	enabled func(*GHESCapabilities) *bool
+}{
+	{"scale sets API", 3, 9, func(c *GHESCapabilities) *bool { return &c.ScaleSetsAPI }},
+	{"message queue long-polling", 3, 9, func(c *GHESCapabilities) *bool { return &c.LongPolling }},
+	{"JIT runner config", 3, 10, func(c *GHESCapabilities) *bool { return &c.JITConfig }},
+}
+
+// detectCapabilities works out the supported features from the reported server version.
+// Servers that do not report a parseable version are assumed to support everything.
+func detectCapabilities(version string) GHESCapabilities {
+	caps := GHESCapabilities{Version: version}
+
+	major, minor, ok := parseGHESVersion(version)
+	for _, v := range minimumGHESVersions {
+		*v.enabled(&caps) = !ok || major > v.major || (major == v.major && minor >= v.minor)
+	}
+	return caps
+}
+
+// parseGHESVersion extracts the major and minor version from strings like "3.9.2"
+func parseGHESVersion(version string) (int, int, bool) {
+	parts := strings.SplitN(version, ".", 3)
+	if len(parts) < 2 {
+		return 0, 0, false
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, false
+	}
+	minor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, 0, false
+	}
+	return major, minor, true
+}
+
+// RESTScanningRequired reports whether the scaler must fall back to scanning workflow jobs over REST
+func (c GHESCapabilities) RESTScanningRequired() bool {
+	return !c.ScaleSetsAPI || !c.LongPolling
+}
+
+// Report logs which features are available and how the scaler will operate
+func (c GHESCapabilities) Report(logger logr.Logger) {
+	version := c.Version
+	if version == "" {
+		version = "not reported"
+	}
+
+	var missing []string
+	for _, v := range minimumGHESVersions {
+		if !*v.enabled(&c) {
+			missing = append(missing, fmt.Sprintf("%s (GHES %d.%d+)", v.feature, v.major, v.minor))
+		}
+	}
+
+	mode := "message queue"
+	if c.RESTScanningRequired() {
+		mode = "REST job scanning"
+	}
+
+	logger.Info("GitHub Enterprise capability report",
+		"version", version,
+		"scaleSetsAPI", c.ScaleSetsAPI,
+		"longPolling", c.LongPolling,
+		"jitConfig", c.JITConfig,
+		"unavailable", missing,
+		"scalingMode", mode)
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/go-logr/logr"
+)
+
+// runCommand executes a one-shot subcommand and returns the process exit code
+func runCommand(name string, args []string, cfg *Config, logger logr.Logger) int {
+	ctx := context.Background()
+
+	// The healthcheck runs inside the container every few seconds and needs no AWS access
+	if name == "healthcheck" {
+		return runHealthcheck(ctx, cfg)
+	}
+	if name == "simulate" {
+		return runSimulate(args, cfg, os.Stdout)
+	}
+
+	awsConfig, err := config.LoadDefaultConfig(ctx, config.WithRegion(cfg.AWSRegion))
+	if err != nil {
+		logger.Error(err, "Failed to load AWS configuration")
+		return 1
+	}
+
+	switch name {
+	case "setup-alarms":
+		provisioner := NewAlarmProvisioner(cloudwatch.NewFromConfig(awsConfig), cfg, logger.WithName("alarms"))
+		if err := provisioner.EnsureAlarms(ctx); err != nil {
+			logger.Error(err, "Failed to provision CloudWatch alarms")
+			return 1
+		}
+		logger.Info("CloudWatch alarms provisioned")
+		return 0
+	case "validate":
+		return runValidate(ctx, ec2.NewFromConfig(awsConfig), cfg)
+	case "history":
+		store := NewDecisionStore(dynamodb.NewFromConfig(awsConfig), cfg, logger.WithName("decision-store"))
+		return runHistory(ctx, args, store)
+	case "migrate":
+		return runMigrate(ctx, args, dynamodb.NewFromConfig(awsConfig), cfg, os.Stdout)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\nAvailable commands:\n"+
+			"  setup-alarms  create or update the CloudWatch alarms for the scaler\n"+
+			"  validate      check the configuration and that runners in EC2_SUBNET_ID can reach GHES, github.com, S3 and SSM\n"+
+			"  history       print the scaling decisions in a time range: history [--from 6h] [--to 2024-05-01T12:00:00Z] [--json]\n"+
+			"  migrate       create the configured DynamoDB tables or add missing indexes and TTL: migrate [--dry-run]\n"+
+			"  simulate      print the scaling actions for a statistics snapshot: simulate --stats stats.json [--policy policy.yaml]\n"+
+			"  config        print the effective configuration and its sources: config dump [--redacted] [--config=file.json]\n"+
+			"  healthcheck   exit 0 when the running scaler's polling loop is live (for container HEALTHCHECK)\n", name)
+		return 2
+	}
+}
+
+// runHealthcheck queries the /live endpoint of the scaler running in this container
+func runHealthcheck(ctx context.Context, cfg *Config) int {
+	_, port, err := net.SplitHostPort(cfg.HTTPListenAddr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid HTTP_LISTEN_ADDR: %v\n", err)
+		return 1
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://127.0.0.1:"+port+"/live", nil)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		fmt.Fprintf(os.Stderr, "scaler is not live (HTTP %d)\n", resp.StatusCode)
+		return 1
+	}
+	return 0
+}
+
+// runValidate checks the configuration and the reachability of the runner subnet. Runners
+// that cannot reach GHES or download the runner never register, and the scaler then only
+// sees jobs that stay queued, so these are checked before anything is launched.
+func runValidate(ctx context.Context, client *ec2.Client, cfg *Config) int {
+	if err := cfg.Validate(); err != nil {
+		fmt.Printf("❌ Configuration: %v\n", err)
+		return 1
+	}
+	fmt.Println("✅ Configuration")
+
+	checks, err := CheckRunnerReachability(ctx, client, cfg)
+	if err != nil {
+		fmt.Printf("❌ Runner subnet %s: %v\n", cfg.EC2SubnetID, err)
+		return 1
+	}
+
+	fmt.Printf("\nReachability from runner subnet %s:\n", cfg.EC2SubnetID)
+	failed := 0
+	for _, check := range checks {
+		if check.OK {
+			fmt.Printf("  ✅ %s: %s\n", check.Name, check.Detail)
+			continue
+		}
+		failed++
+		fmt.Printf("  ❌ %s: %s\n     %s\n", check.Name, check.Detail, check.Hint)
+	}
+	if failed > 0 {
+		fmt.Println("\nRoutes and endpoints are read from the VPC configuration and DNS is resolved from this host;")
+		fmt.Println("network ACLs, firewalls and proxies are not checked.")
+		return 1
+	}
+	return 0
+}
+
+// runHistory prints the scaling decisions recorded in DECISIONS_TABLE_NAME, oldest first.
+// Range bounds are RFC 3339 times or durations before now.
+func runHistory(ctx context.Context, args []string, store *DecisionStore) int {
+	flags := flag.NewFlagSet("history", flag.ContinueOnError)
+	fromValue := flags.String("from", "24h", "start of the range, an RFC 3339 time or a duration before now")
+	toValue := flags.String("to", "", "end of the range, an RFC 3339 time or a duration before now; defaults to now")
+	asJSON := flags.Bool("json", false, "print the decisions as JSON")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	from, err := parseHistoryTime(*fromValue, time.Time{})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid --from: %v\n", err)
+		return 2
+	}
+	to, err := parseHistoryTime(*toValue, time.Now())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid --to: %v\n", err)
+		return 2
+	}
+
+	decisions, err := store.Between(ctx, from, to)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(decisions); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tASSIGNED\tCOMPLETED\tCURRENT\tDESIRED\tLIMITS\tREASON\tLAUNCHED\tTERMINATED")
+	for _, d := range decisions {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%d-%d\t%s\t%s\t%s\n",
+			d.Timestamp.UTC().Format(time.RFC3339), d.AssignedJobs, d.CompletedJobs, d.CurrentRunners,
+			d.DesiredRunners, d.MinRunners, d.MaxRunners, d.Reason,
+			strings.Join(d.LaunchedInstances, ","), strings.Join(d.TerminatedInstances, ","))
+	}
+	w.Flush()
+	return 0
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/Anshuman2121/actionsspot/internal/configsource"
+	"github.com/Anshuman2121/actionsspot/internal/runnername"
+)
+
+// Configuration is resolved per setting by configsource: flags given before the subcommand
+// (or anywhere in config dump), then the environment, then CONFIG_FILE, then the defaults in
+// configSettings.
+
+// configSettings lists every setting the scaler reads
+var configSettings = []configsource.Setting{
+	{Name: "ADAPTIVE_POLLING", Default: "false"},
+	{Name: "ADMIN_TOKEN", Secret: true},
+	{Name: "ALARM_ERROR_THRESHOLD", Default: "10"},
+	{Name: "ALARM_QUEUED_JOBS_THRESHOLD", Default: "20"},
+	{Name: "ALARM_SNS_TOPIC_ARN"},
+	{Name: "ALLOWED_REPOSITORIES"},
+	{Name: "APPCONFIG_AGENT_URL", Default: "http://localhost:2772"},
+	{Name: "APPCONFIG_APPLICATION"},
+	{Name: "APPCONFIG_ENVIRONMENT"},
+	{Name: "APPCONFIG_POLL_INTERVAL", Default: "1m"},
+	{Name: "APPCONFIG_PROFILE"},
+	{Name: "AWS_REGION", Default: "eu-north-1"},
+	{Name: "CLOUDWATCH_ALARMS_ENABLED", Default: "false"},
+	{Name: "CLOUDWATCH_METRICS_ENABLED", Default: "true"},
+	{Name: "CLOUDWATCH_NAMESPACE", Default: "GHAEC2/Scaler"},
+	{Name: "CONTROL_POLL_INTERVAL", Default: "15s"},
+	{Name: "CONTROL_TABLE_NAME"},
+	{Name: "DEADMAN_SNS_TOPIC_ARN"},
+	{Name: "DEADMAN_THRESHOLD", Default: "15m"},
+	{Name: "DECISIONS_RETENTION", Default: "2160h"},
+	{Name: "DECISIONS_TABLE_NAME"},
+	{Name: "DIAGNOSTICS_INTERVAL", Default: "2m"},
+	{Name: "DRAIN_TIMEOUT", Default: "25s"},
+	{Name: "DYNAMODB_TABLE_NAME"},
+	{Name: "EC2_AMI_ID"},
+	{Name: "EC2_INSTANCE_TYPE", Default: "t3.medium"},
+	{Name: "EC2_KEY_PAIR_NAME"},
+	{Name: "EC2_LAUNCH_TEMPLATE_ID"},
+	{Name: "EC2_LAUNCH_TEMPLATE_VERSION"},
+	{Name: "EC2_SECURITY_GROUP_ID"},
+	{Name: "EC2_SECURITY_GROUP_IDS"},
+	{Name: "EC2_SPOT_PRICE"},
+	{Name: "EC2_SPOT_PRICES"},
+	{Name: "EC2_SUBNET_ID"},
+	{Name: "EXCLUDED_LABELS"},
+	{Name: "GITHUB_APP_ID"},
+	{Name: "GITHUB_APP_INSTALLATION_ID"},
+	{Name: "GITHUB_APP_PRIVATE_KEY_SECRET_ARN"},
+	{Name: "GITHUB_ENTERPRISE_URL"},
+	{Name: "GITHUB_TOKEN", Secret: true},
+	{Name: "GITHUB_TOKEN_REFRESH_INTERVAL", Default: "5m"},
+	{Name: "GITHUB_TOKEN_SECRET_ARN"},
+	{Name: "HEARTBEAT_ALARM_MINUTES", Default: "3"},
+	{Name: "HTTP_LISTEN_ADDR", Default: ":8080"},
+	{Name: "JOB_ACQUISITION_MODE", Default: acquisitionModeBatch},
+	{Name: "LABEL_LAUNCH_SPECS"},
+	{Name: "LAMBDA_FUNCTION_NAME"},
+	{Name: "LEADER_ELECTION_TABLE_NAME"},
+	{Name: "LEADER_LEASE_DURATION", Default: "30s"},
+	{Name: "LEADER_RENEW_INTERVAL", Default: "10s"},
+	{Name: "LIVENESS_THRESHOLD", Default: "3m"},
+	{Name: "MAINTENANCE_TABLE_NAME"},
+	{Name: "MAX_RUNNERS", Default: "10"},
+	{Name: "MAX_RUNNERS_WINDOWS"},
+	{Name: "MAX_RUNNERS_WINDOWS_TZ", Default: "UTC"},
+	{Name: "MAX_SCALE_UP_PER_CYCLE", Default: "0"},
+	{Name: "MIN_RUNNERS", Default: "0"},
+	{Name: "ON_DEMAND_ONLY", Default: "false"},
+	{Name: "ORGANIZATION_NAME"},
+	{Name: "OTEL_EXPORTER_OTLP_ENDPOINT"},
+	{Name: "OTEL_SERVICE_NAME", Default: "ghaec2"},
+	{Name: "POLL_CHECK_INTERVAL", Default: "30s"},
+	{Name: "POLL_ERROR_BACKOFF", Default: "5s"},
+	{Name: "POLL_IDLE_AFTER", Default: "10m"},
+	{Name: "POLL_IDLE_INTERVAL", Default: "1m"},
+	{Name: "POLL_INTERVAL", Default: "5s"},
+	{Name: "POOLS_CONFIG_FILE"},
+	{Name: "PROMETHEUS_METRICS_ENABLED", Default: "true"},
+	{Name: "REST_SCAN_CONCURRENCY", Default: "4"},
+	{Name: "REST_SCAN_COOLOFF", Default: "10m"},
+	{Name: "RIGHTSIZING_INTERVAL", Default: "24h"},
+	{Name: "RIGHTSIZING_WINDOW", Default: "168h"},
+	{Name: "RUNNER_GROUP_ID", Default: "1"}, // the "Default" group
+	{Name: "RUNNER_LABELS", Default: "self-hosted,linux,x64,ghalistener-managed"},
+	{Name: "RUNNER_NAME_PREFIX", Default: "ghaec2-runner"},
+	{Name: "RUNNER_NAME_TEMPLATE", Default: runnername.DefaultTemplate},
+	{Name: "RUNNER_RECORD_RETENTION", Default: "720h"},
+	{Name: "RUNNER_SCALE_SET_ID"},
+	{Name: "RUNNER_SCALE_SET_NAME", Default: "ghaec2-scaler"},
+	{Name: "RUNNER_SYNC_INTERVAL", Default: "5m"},
+	{Name: "SCALE_DOWN_COOLDOWN", Default: "0s"},
+	{Name: "SCALE_DOWN_MAX_PER_DECISION", Default: "0"},
+	{Name: "SCALE_DOWN_STABILIZATION", Default: "1"},
+	{Name: "SCALE_UP_BATCH_INTERVAL", Default: "1s"},
+	{Name: "SCALE_UP_BATCH_SIZE", Default: "10"},
+	{Name: "SCALING_SOURCE", Default: scalingSourceMessageQueue},
+	{Name: "SESSIONS_TABLE_NAME"},
+	{Name: "SESSION_RECORD_RETENTION", Default: "168h"},
+	{Name: "STARVATION_SLACK_WEBHOOK_URL", Secret: true},
+	{Name: "STARVATION_SNS_TOPIC_ARN"},
+	{Name: "STARVATION_THRESHOLD", Default: "20m"},
+	{Name: "STATS_HISTORY_INTERVAL", Default: "1m"},
+	{Name: "STATS_HISTORY_SIZE", Default: "360"}, // 6 hours at the default interval
+	{Name: "STATS_RETENTION", Default: "720h"},
+	{Name: "STATS_TABLE_NAME"},
+	{Name: "SUPERVISOR_INITIAL_BACKOFF", Default: "5s"},
+	{Name: "SUPERVISOR_MAX_BACKOFF", Default: "5m"},
+	{Name: "SUPERVISOR_MAX_RESTARTS", Default: "10"},
+	{Name: "SUPERVISOR_STABLE_AFTER", Default: "10m"},
+	{Name: "WEBHOOK_SECRET", Secret: true},
+}
+
+// configSource resolves the settings of the scaler
+type configSource = configsource.Source
+
+// newConfigSource creates a source with the given flag values, keyed by setting name
+func newConfigSource(flags map[string]string) (*configSource, error) {
+	return configsource.New(configSettings, flags)
+}
+
+// parseStartupArgs splits the command line into the setting flags that lead it, such as
+// --config=file.json or --max-runners=5, and the subcommand with its own arguments:
+//
+//	ghaec2 [--setting-name=value ...] [command [args ...]]
+func parseStartupArgs(args []string) (map[string]string, []string, error) {
+	n := 0
+	for n < len(args) && strings.HasPrefix(args[n], "--") {
+		n++
+	}
+	flags, rest, err := configsource.ParseFlags(configSettings, args[:n])
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(rest) > 0 {
+		return nil, nil, fmt.Errorf("flag %s needs a value, e.g. %s=value", rest[0], rest[0])
+	}
+	return flags, args[n:], nil
+}
+
+// runConfigDump prints the effective configuration and checks that it is valid. Setting
+// flags may come before config or after dump:
+//
+//	ghaec2 config dump [--redacted] [--config=file.json] [--max-runners=5 ...]
+func runConfigDump(startupFlags map[string]string, args []string, w io.Writer) int {
+	flags, rest, err := configsource.ParseFlags(configSettings, args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	for name, value := range startupFlags {
+		if _, ok := flags[name]; !ok {
+			flags[name] = value
+		}
+	}
+
+	redacted := false
+	var command []string
+	for _, arg := range rest {
+		if arg == "--redacted" {
+			redacted = true
+			continue
+		}
+		command = append(command, arg)
+	}
+	if len(command) != 1 || command[0] != "dump" {
+		fmt.Fprintln(os.Stderr, "usage: config dump [--redacted] [--config=file.json] [--setting-name=value ...]")
+		return 2
+	}
+
+	src, err := newConfigSource(flags)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	src.Dump(w, redacted)
+
+	cfg, err := loadConfigFrom(src)
+	if err == nil {
+		err = cfg.Validate()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "\nConfiguration is invalid: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseStartupArgs(t *testing.T) {
+	flags, args, err := parseStartupArgs([]string{"--max-runners=5", "--config=c.json", "history", "--from=6h"})
+	if err != nil {
+		t.Fatalf("parseStartupArgs() error = %v", err)
+	}
+	if flags["MAX_RUNNERS"] != "5" || flags["CONFIG_FILE"] != "c.json" || len(flags) != 2 {
+		t.Errorf("parseStartupArgs() flags = %v", flags)
+	}
+	if strings.Join(args, " ") != "history --from=6h" {
+		t.Errorf("parseStartupArgs() args = %v, want the command with its own flags", args)
+	}
+
+	if _, _, err := parseStartupArgs([]string{"--max-runners", "5"}); err == nil {
+		t.Error("parseStartupArgs() accepted a flag without a value")
+	}
+	if _, _, err := parseStartupArgs([]string{"--max-runer=5"}); err == nil {
+		t.Error("parseStartupArgs() accepted an unknown setting")
+	}
+}
+
+func TestLoadConfigFlagsOverrideEnvironment(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("MAX_RUNNERS", "20")
+	cfg, err := LoadConfig(map[string]string{"MAX_RUNNERS": "5"})
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if cfg.MaxRunners != 5 {
+		t.Errorf("MaxRunners = %d, want the flag value 5", cfg.MaxRunners)
+	}
+}
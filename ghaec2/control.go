@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/go-logr/logr"
+)
+
+// ControlOverrides are the runtime overrides of a scale set from its control item
+type ControlOverrides struct {
+	Paused     bool
+	Reason     string
+	MinRunners *int
+	MaxRunners *int
+}
+
+func (o ControlOverrides) equal(other ControlOverrides) bool {
+	sameLimit := func(a, b *int) bool {
+		return (a == nil && b == nil) || (a != nil && b != nil && *a == *b)
+	}
+	return o.Paused == other.Paused && o.Reason == other.Reason &&
+		sameLimit(o.MinRunners, other.MinRunners) && sameLimit(o.MaxRunners, other.MaxRunners)
+}
+
+// ControlStore reads the control item of the scale set from a DynamoDB table keyed by scale
+// set name, e.g. {"scale_set_name": "linux-x64", "paused": true, "reason": "GHES upgrade",
+// "max_runners": 5}, so on-call engineers can pause or clamp the scaler without access to
+// the host or a deployment. Every attribute besides the key is optional.
+type ControlStore struct {
+	client    *dynamodb.Client
+	tableName string
+	scaleSet  string
+	logger    logr.Logger
+}
+
+// NewControlStore creates a control store. A nil client or empty table name disables it.
+func NewControlStore(client *dynamodb.Client, config *Config, logger logr.Logger) *ControlStore {
+	return &ControlStore{
+		client:    client,
+		tableName: config.ControlTableName,
+		scaleSet:  config.RunnerScaleSetName,
+		logger:    logger,
+	}
+}
+
+// Enabled reports whether the control item is read
+func (c *ControlStore) Enabled() bool {
+	return c.client != nil && c.tableName != ""
+}
+
+// Get returns the overrides; a missing item means no overrides
+func (c *ControlStore) Get(ctx context.Context) (ControlOverrides, error) {
+	output, err := c.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(c.tableName),
+		Key: map[string]types.AttributeValue{
+			"scale_set_name": &types.AttributeValueMemberS{Value: c.scaleSet},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return ControlOverrides{}, fmt.Errorf("failed to get control item from %s: %w", c.tableName, err)
+	}
+
+	limit := func(name string) *int {
+		if v, ok := output.Item[name].(*types.AttributeValueMemberN); ok {
+			if n, err := strconv.Atoi(v.Value); err == nil {
+				return &n
+			}
+		}
+		return nil
+	}
+	overrides := ControlOverrides{
+		MinRunners: limit("min_runners"),
+		MaxRunners: limit("max_runners"),
+	}
+	if v, ok := output.Item["paused"].(*types.AttributeValueMemberBOOL); ok {
+		overrides.Paused = v.Value
+	}
+	if v, ok := output.Item["reason"].(*types.AttributeValueMemberS); ok {
+		overrides.Reason = v.Value
+	}
+	return overrides, nil
+}
+
+// setPaused switches maintenance mode on or off. While paused no jobs are acquired and no
+// instances launched or terminated, but messages are still polled, so the session stays
+// alive and statistics keep being reported.
+func (s *MessageQueueScaler) setPaused(paused bool, source, reason string) {
+	if s.paused.Swap(paused) == paused {
+		return
+	}
+	if paused {
+		s.logger.Info("Maintenance mode on, scaling paused", "source", source, "reason", reason)
+	} else {
+		s.logger.Info("Maintenance mode off, scaling resumed", "source", source)
+		s.requestReconcile()
+	}
+}
+
+// applyControlLimits clamps the runner limits with the control item's min and max, on top
+// of the configuration and the AppConfig policy. The control item is an emergency brake:
+// its max can only lower the max, and a max above it or negative limits are rejected.
+func (s *MessageQueueScaler) applyControlLimits(overrides ControlOverrides) error {
+	s.limitsMu.Lock()
+	defer s.limitsMu.Unlock()
+
+	var limits *ScalingPolicy
+	if overrides.MinRunners != nil || overrides.MaxRunners != nil {
+		limits = &ScalingPolicy{MinRunners: overrides.MinRunners, MaxRunners: overrides.MaxRunners}
+		if err := validateControlLimits(s.maxRunners, limits); err != nil {
+			return err
+		}
+	}
+	s.controlLimits = limits
+	return nil
+}
+
+// validateControlLimits checks the control item's limits against the current max
+func validateControlLimits(maxRunners int, limits *ScalingPolicy) error {
+	if limits.MaxRunners != nil && (*limits.MaxRunners < 0 || *limits.MaxRunners > maxRunners) {
+		return fmt.Errorf("max_runners=%d must be between 0 and the current max of %d, the control item can only lower it",
+			*limits.MaxRunners, maxRunners)
+	}
+	if limits.MinRunners != nil && *limits.MinRunners < 0 {
+		return fmt.Errorf("min_runners=%d must be >= 0", *limits.MinRunners)
+	}
+	return nil
+}
+
+// clampControlLimits applies validated control limits: the max is only ever lowered and
+// the min never exceeds the resulting max
+func clampControlLimits(minRunners, maxRunners int, limits *ScalingPolicy) (int, int) {
+	if limits.MaxRunners != nil {
+		maxRunners = min(maxRunners, *limits.MaxRunners)
+	}
+	if limits.MinRunners != nil {
+		minRunners = *limits.MinRunners
+	}
+	return min(minRunners, maxRunners), maxRunners
+}
+
+// watchControl polls the control item and applies its changes until the context is
+// cancelled. Only changes are applied, so a pause from the admin API or SIGUSR1 holds until
+// the item itself changes.
+func (s *MessageQueueScaler) watchControl(ctx context.Context, store *ControlStore, interval time.Duration) {
+	var last *ControlOverrides
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		overrides, err := store.Get(ctx)
+		switch {
+		case err != nil:
+			s.logger.Error(err, "Failed to read control item, keeping current overrides")
+		case last == nil || !last.equal(overrides):
+			if err := s.applyControlLimits(overrides); err != nil {
+				s.logger.Error(err, "Rejected runner limits from control item")
+			} else {
+				minRunners, maxRunners := s.scalingLimits()
+				s.logger.Info("Applied control item", "paused", overrides.Paused,
+					"minRunners", minRunners, "maxRunners", maxRunners)
+				s.requestReconcile()
+			}
+			s.setPaused(overrides.Paused, "control-table", overrides.Reason)
+			last = &overrides
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
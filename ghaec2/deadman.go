@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/go-logr/logr"
+)
+
+// DeadmanMonitor alerts when the message queue has gone quiet while jobs are still waiting.
+// A healthy session delivers a message shortly after a job is queued, so a long silence
+// combined with pending jobs means the session or queue is broken even though polls succeed.
+type DeadmanMonitor struct {
+	threshold time.Duration
+	snsClient *sns.Client
+	topicARN  string
+	scaleSet  string
+	metrics   *MetricsPublisher
+	logger    logr.Logger
+
+	mu                   sync.Mutex
+	lastMessageProcessed time.Time
+	alerted              bool
+}
+
+// NewDeadmanMonitor creates a deadman monitor. A nil SNS client or empty topic disables notifications.
+func NewDeadmanMonitor(config *Config, snsClient *sns.Client, metrics *MetricsPublisher, logger logr.Logger) *DeadmanMonitor {
+	return &DeadmanMonitor{
+		threshold:            config.DeadmanThreshold,
+		snsClient:            snsClient,
+		topicARN:             config.DeadmanSNSTopicARN,
+		scaleSet:             config.RunnerScaleSetName,
+		metrics:              metrics,
+		logger:               logger,
+		lastMessageProcessed: time.Now(),
+	}
+}
+
+// RecordMessageProcessed marks that a message was received and handled
+func (d *DeadmanMonitor) RecordMessageProcessed() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.lastMessageProcessed = time.Now()
+	if d.alerted {
+		d.logger.Info("Message queue recovered, clearing deadman alert")
+		d.alerted = false
+	}
+}
+
+// Check evaluates the deadman condition against the number of jobs currently waiting
+func (d *DeadmanMonitor) Check(ctx context.Context, pendingJobs int) {
+	d.mu.Lock()
+	silence := time.Since(d.lastMessageProcessed)
+	shouldAlert := silence > d.threshold && pendingJobs > 0 && !d.alerted
+	if shouldAlert {
+		d.alerted = true
+	}
+	d.mu.Unlock()
+
+	d.metrics.Gauge(metricSecondsSinceLastMessage, silence.Seconds())
+
+	if !shouldAlert {
+		return
+	}
+
+	d.logger.Error(nil, "No messages processed while jobs are pending",
+		"silence", silence.Round(time.Second),
+		"threshold", d.threshold,
+		"pendingJobs", pendingJobs)
+	d.metrics.Count(metricDeadmanTriggered, 1)
+
+	if err := d.notify(ctx, silence, pendingJobs); err != nil {
+		d.logger.Error(err, "Failed to send deadman notification")
+	}
+}
+
+func (d *DeadmanMonitor) notify(ctx context.Context, silence time.Duration, pendingJobs int) error {
+	if d.snsClient == nil || d.topicARN == "" {
+		return nil
+	}
+
+	message := fmt.Sprintf("Scale set %s has not processed a message queue message for %s while %d job(s) are pending. "+
+		"The message session may be broken; check the scaler logs and consider restarting it.",
+		d.scaleSet, silence.Round(time.Second), pendingJobs)
+
+	_, err := d.snsClient.Publish(ctx, &sns.PublishInput{
+		TopicArn: aws.String(d.topicARN),
+		Subject:  aws.String(fmt.Sprintf("ghaec2 deadman: %s", d.scaleSet)),
+		Message:  aws.String(message),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish to SNS: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/go-logr/logr"
+)
+
+// ScalingDecision records one desired-runner calculation: what the scaler saw, what it
+// decided and which instances it launched or terminated as a result
+type ScalingDecision struct {
+	Timestamp           time.Time `json:"timestamp"`
+	AssignedJobs        int       `json:"assignedJobs"`
+	CompletedJobs       int       `json:"completedJobs"`
+	CurrentRunners      int       `json:"currentRunners"`
+	MinRunners          int       `json:"minRunners"`
+	MaxRunners          int       `json:"maxRunners"`
+	DesiredRunners      int       `json:"desiredRunners"`
+	Reason              string    `json:"reason"`
+	ScaleDownHeld       string    `json:"scaleDownHeld,omitempty"` // why fewer runners were terminated than wanted
+	LaunchedInstances   []string  `json:"launchedInstances,omitempty"`
+	TerminatedInstances []string  `json:"terminatedInstances,omitempty"`
+}
+
+// DecisionStore persists scaling decisions to a DynamoDB table keyed by scale set and time
+// in milliseconds, so decisions made within the same second do not overwrite each other.
+// Items expire through the table's TTL attribute.
+type DecisionStore struct {
+	client    *dynamodb.Client
+	tableName string
+	scaleSet  string
+	retention time.Duration
+	logger    logr.Logger
+}
+
+// NewDecisionStore creates a decision store. A nil client or empty table name disables persistence.
+func NewDecisionStore(client *dynamodb.Client, config *Config, logger logr.Logger) *DecisionStore {
+	return &DecisionStore{
+		client:    client,
+		tableName: config.DecisionsTableName,
+		scaleSet:  config.RunnerScaleSetName,
+		retention: config.DecisionsRetention,
+		logger:    logger,
+	}
+}
+
+// Enabled reports whether decisions are persisted
+func (d *DecisionStore) Enabled() bool {
+	return d.client != nil && d.tableName != ""
+}
+
+// Put writes a decision to the table
+func (d *DecisionStore) Put(ctx context.Context, decision ScalingDecision) error {
+	if !d.Enabled() {
+		return nil
+	}
+
+	number := func(v int) types.AttributeValue {
+		return &types.AttributeValueMemberN{Value: strconv.Itoa(v)}
+	}
+	item := map[string]types.AttributeValue{
+		"scale_set_name":  &types.AttributeValueMemberS{Value: d.scaleSet},
+		"timestamp":       &types.AttributeValueMemberN{Value: strconv.FormatInt(decision.Timestamp.UnixMilli(), 10)},
+		"assigned_jobs":   number(decision.AssignedJobs),
+		"completed_jobs":  number(decision.CompletedJobs),
+		"current_runners": number(decision.CurrentRunners),
+		"min_runners":     number(decision.MinRunners),
+		"max_runners":     number(decision.MaxRunners),
+		"desired_runners": number(decision.DesiredRunners),
+		"reason":          &types.AttributeValueMemberS{Value: decision.Reason},
+		"expires_at":      &types.AttributeValueMemberN{Value: strconv.FormatInt(decision.Timestamp.Add(d.retention).Unix(), 10)},
+	}
+	if decision.ScaleDownHeld != "" {
+		item["scale_down_held"] = &types.AttributeValueMemberS{Value: decision.ScaleDownHeld}
+	}
+	// DynamoDB rejects empty sets, so instance lists are only written when present
+	if len(decision.LaunchedInstances) > 0 {
+		item["launched_instances"] = &types.AttributeValueMemberSS{Value: decision.LaunchedInstances}
+	}
+	if len(decision.TerminatedInstances) > 0 {
+		item["terminated_instances"] = &types.AttributeValueMemberSS{Value: decision.TerminatedInstances}
+	}
+
+	_, err := d.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(d.tableName),
+		Item:      item,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put scaling decision to %s: %w", d.tableName, err)
+	}
+	return nil
+}
+
+// Between returns the decisions made in [from, to], oldest first
+func (d *DecisionStore) Between(ctx context.Context, from, to time.Time) ([]ScalingDecision, error) {
+	if !d.Enabled() {
+		return nil, fmt.Errorf("decision history is disabled: DECISIONS_TABLE_NAME is not set")
+	}
+
+	paginator := dynamodb.NewQueryPaginator(d.client, &dynamodb.QueryInput{
+		TableName:              aws.String(d.tableName),
+		KeyConditionExpression: aws.String("scale_set_name = :scale_set_name AND #timestamp BETWEEN :from AND :to"),
+		ExpressionAttributeNames: map[string]string{
+			"#timestamp": "timestamp",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":scale_set_name": &types.AttributeValueMemberS{Value: d.scaleSet},
+			":from":           &types.AttributeValueMemberN{Value: strconv.FormatInt(from.UnixMilli(), 10)},
+			":to":             &types.AttributeValueMemberN{Value: strconv.FormatInt(to.UnixMilli(), 10)},
+		},
+	})
+
+	var decisions []ScalingDecision
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query scaling decisions in %s: %w", d.tableName, err)
+		}
+		for _, item := range page.Items {
+			decisions = append(decisions, scalingDecisionFromItem(item))
+		}
+	}
+	return decisions, nil
+}
+
+func scalingDecisionFromItem(item map[string]types.AttributeValue) ScalingDecision {
+	str := func(name string) string {
+		if v, ok := item[name].(*types.AttributeValueMemberS); ok {
+			return v.Value
+		}
+		return ""
+	}
+	num := func(name string) int64 {
+		if v, ok := item[name].(*types.AttributeValueMemberN); ok {
+			n, _ := strconv.ParseInt(v.Value, 10, 64)
+			return n
+		}
+		return 0
+	}
+	set := func(name string) []string {
+		if v, ok := item[name].(*types.AttributeValueMemberSS); ok {
+			return v.Value
+		}
+		return nil
+	}
+
+	return ScalingDecision{
+		Timestamp:           time.UnixMilli(num("timestamp")),
+		AssignedJobs:        int(num("assigned_jobs")),
+		CompletedJobs:       int(num("completed_jobs")),
+		CurrentRunners:      int(num("current_runners")),
+		MinRunners:          int(num("min_runners")),
+		MaxRunners:          int(num("max_runners")),
+		DesiredRunners:      int(num("desired_runners")),
+		Reason:              str("reason"),
+		ScaleDownHeld:       str("scale_down_held"),
+		LaunchedInstances:   set("launched_instances"),
+		TerminatedInstances: set("terminated_instances"),
+	}
+}
+
+// parseHistoryTime parses a history range bound, either an RFC 3339 time or a duration
+// such as 3h meaning that long before now. An empty value returns def.
+func parseHistoryTime(value string, def time.Time) (time.Time, error) {
+	if value == "" {
+		return def, nil
+	}
+	if window, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-window), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/go-logr/logr"
+
+	"github.com/Anshuman2121/actionsspot/internal/ec2launch"
+)
+
+// EC2SpotProvider runs runners on EC2 spot instances, or on-demand ones with ON_DEMAND_ONLY
+type EC2SpotProvider struct {
+	client  *ec2.Client
+	config  *Config
+	metrics *MetricsPublisher
+	logger  logr.Logger
+}
+
+// NewEC2SpotProvider creates the EC2 runner provider
+func NewEC2SpotProvider(client *ec2.Client, config *Config, metrics *MetricsPublisher, logger logr.Logger) *EC2SpotProvider {
+	return &EC2SpotProvider{
+		client:  client,
+		config:  config,
+		metrics: metrics,
+		logger:  logger,
+	}
+}
+
+var _ RunnerProvider = (*EC2SpotProvider)(nil)
+
+// CreateRunner launches an EC2 runner instance and returns its instance ID. The instance is
+// tagged ManagedBy=ghaec2, and Pool=<pool> for a pool's runners, so ListRunners finds it.
+// With EC2_LAUNCH_TEMPLATE_ID it launches from the template, which provides the AMI, key
+// pair and security groups.
+func (p *EC2SpotProvider) CreateRunner(ctx context.Context, spec RunnerSpec) (string, error) {
+	// The runner registers in the scale set with its JIT config, GITHUB_TOKEN stays with the
+	// scaler, so a runner without one could never register
+	if spec.JITConfig == "" {
+		return "", fmt.Errorf("runner %s has no JIT config to register with", spec.Name)
+	}
+	requestedAt := time.Now()
+
+	input := &ec2.RunInstancesInput{
+		MinCount:                          aws.Int32(1),
+		MaxCount:                          aws.Int32(1),
+		InstanceType:                      ec2types.InstanceType(p.config.EC2InstanceType),
+		SubnetId:                          aws.String(p.config.EC2SubnetID),
+		UserData:                          aws.String(base64.StdEncoding.EncodeToString([]byte(runnerUserData(spec.JITConfig)))),
+		InstanceInitiatedShutdownBehavior: ec2types.ShutdownBehaviorTerminate,
+		TagSpecifications: []ec2types.TagSpecification{{
+			ResourceType: ec2types.ResourceTypeInstance,
+			Tags:         p.runnerTags(spec.Name),
+		}},
+	}
+	if p.config.EC2LaunchTemplateID != "" {
+		input.LaunchTemplate = &ec2types.LaunchTemplateSpecification{LaunchTemplateId: aws.String(p.config.EC2LaunchTemplateID)}
+		if p.config.EC2LaunchTemplateVersion != "" {
+			input.LaunchTemplate.Version = aws.String(p.config.EC2LaunchTemplateVersion)
+		}
+	} else {
+		input.ImageId = aws.String(p.config.EC2AMI)
+		input.KeyName = aws.String(p.config.EC2KeyPairName)
+		input.SecurityGroupIds = p.config.EC2SecurityGroupIDs
+	}
+
+	market := "on-demand"
+	if !p.config.OnDemandOnly {
+		market = "spot"
+		input.InstanceMarketOptions = &ec2types.InstanceMarketOptionsRequest{
+			MarketType: ec2types.MarketTypeSpot,
+			SpotOptions: &ec2types.SpotMarketOptions{
+				MaxPrice:                     ec2launch.SpotPriceFor(p.config.EC2SpotPrices, p.config.EC2InstanceType),
+				SpotInstanceType:             ec2types.SpotInstanceTypeOneTime,
+				InstanceInterruptionBehavior: ec2types.InstanceInterruptionBehaviorTerminate,
+			},
+		}
+	}
+
+	result, err := p.client.RunInstances(ctx, input)
+	if err != nil {
+		return "", fmt.Errorf("failed to launch %s instance: %w", market, err)
+	}
+	if len(result.Instances) == 0 {
+		return "", fmt.Errorf("RunInstances returned no instance")
+	}
+	instanceID := aws.ToString(result.Instances[0].InstanceId)
+
+	if market == "spot" {
+		p.metrics.Duration(metricSpotFulfillmentTime, time.Since(requestedAt), p.config.metricsPool())
+	}
+	p.logger.Info("EC2 runner instance created", "instanceId", instanceID, "runnerName", spec.Name, "market", market,
+		"instanceType", p.config.EC2InstanceType, "ami", p.config.EC2AMI, "launchTemplate", p.config.EC2LaunchTemplateID)
+	return instanceID, nil
+}
+
+// runnerTags returns the tags of a runner instance
+func (p *EC2SpotProvider) runnerTags(runnerName string) []ec2types.Tag {
+	tags := []ec2types.Tag{
+		{Key: aws.String("Name"), Value: aws.String(runnerName)},
+		{Key: aws.String("RunnerName"), Value: aws.String(runnerName)},
+		{Key: aws.String("ManagedBy"), Value: aws.String(runnerManagedBy)},
+	}
+	if p.config.PoolName != "" {
+		tags = append(tags, ec2types.Tag{Key: aws.String("Pool"), Value: aws.String(p.config.PoolName)})
+	}
+	return tags
+}
+
+// runnerUserData renders the user data of a runner instance. The AMI provides the runner in
+// /home/runner; it starts from its JIT config, which registers it in the scale set for a
+// single job, and the instance powers off, and so terminates, once the runner exits.
+func runnerUserData(jitConfig string) string {
+	return fmt.Sprintf(`#!/bin/bash
+sudo -u runner bash -c 'cd /home/runner && ./run.sh --jitconfig %s'
+shutdown -h now
+`, jitConfig)
+}
+
+// TerminateRunner terminates a runner instance. Its runner is removed from the scale set by
+// the caller, or deregisters itself once it ran its job.
+func (p *EC2SpotProvider) TerminateRunner(ctx context.Context, instanceID string) error {
+	if _, err := p.client.TerminateInstances(ctx, &ec2.TerminateInstancesInput{
+		InstanceIds: []string{instanceID},
+	}); err != nil {
+		return fmt.Errorf("failed to terminate instance %s: %w", instanceID, err)
+	}
+	p.logger.Info("EC2 runner instance terminated", "instanceId", instanceID)
+	return nil
+}
+
+// ListRunners lists the pending and running instances this scaler launched, only those of
+// its pool when it runs one
+func (p *EC2SpotProvider) ListRunners(ctx context.Context) ([]ProvisionedRunner, error) {
+	filters := []ec2types.Filter{
+		{Name: aws.String("tag:ManagedBy"), Values: []string{runnerManagedBy}},
+		{Name: aws.String("instance-state-name"), Values: []string{"pending", "running"}},
+	}
+	if p.config.PoolName != "" {
+		filters = append(filters, ec2types.Filter{Name: aws.String("tag:Pool"), Values: []string{p.config.PoolName}})
+	}
+
+	var runners []ProvisionedRunner
+	paginator := ec2.NewDescribeInstancesPaginator(p.client, &ec2.DescribeInstancesInput{Filters: filters})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe runner instances: %w", err)
+		}
+		for _, reservation := range page.Reservations {
+			for _, instance := range reservation.Instances {
+				runner := ProvisionedRunner{
+					ID:         aws.ToString(instance.InstanceId),
+					LaunchTime: aws.ToTime(instance.LaunchTime),
+				}
+				if instance.State != nil {
+					runner.State = string(instance.State.Name)
+				}
+				for _, tag := range instance.Tags {
+					if aws.ToString(tag.Key) == "RunnerName" {
+						runner.Name = aws.ToString(tag.Value)
+					}
+				}
+				runners = append(runners, runner)
+			}
+		}
+	}
+	return runners, nil
+}
+
+// GetCapacity reports no fixed limit: EC2 capacity and quota shortfalls surface as failed launches
+func (p *EC2SpotProvider) GetCapacity(ctx context.Context) (int, error) {
+	return unlimitedCapacity, nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// stalled reports whether the polling loop has gone longer than threshold without a
+// heartbeat. Startup before the first heartbeat, supervisor restarts and standing by for
+// leadership are not stalls.
+func (s *MessageQueueScaler) stalled(threshold time.Duration) bool {
+	if s.restarting.Load() || s.standby.Load() {
+		return false
+	}
+	last := s.LastHeartbeat()
+	if last.Before(s.startedAt) {
+		last = s.startedAt
+	}
+	return time.Since(last) > threshold
+}
+
+// handleLive answers container liveness probes from the polling loop heartbeat
+func (s *MessageQueueScaler) handleLive(w http.ResponseWriter, r *http.Request) {
+	status := http.StatusOK
+	if s.stalled(s.config.LivenessThreshold) {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, map[string]interface{}{
+		"live":          status == http.StatusOK,
+		"lastHeartbeat": s.LastHeartbeat(),
+	})
+}
+
+// drain runs on shutdown after the polling loop has stopped. It records the tracked
+// instances in the runner table so the next process can adopt them instead of leaving
+// them orphaned. The message session itself is deleted when Run returns.
+func (s *MessageQueueScaler) drain(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.config.DrainTimeout)
+	defer cancel()
+
+	s.runnerTracker.mu.RLock()
+	instances := make([]EC2RunnerInstance, 0, len(s.runnerTracker.instances))
+	for _, instance := range s.runnerTracker.instances {
+		instances = append(instances, *instance)
+	}
+	s.runnerTracker.mu.RUnlock()
+
+	s.logger.Info("Draining scaler", "trackedInstances", len(instances), "timeout", s.config.DrainTimeout)
+
+	for _, instance := range instances {
+		name := instance.RunnerName
+		if name == "" {
+			name = instance.InstanceID
+		}
+		status := runnerStatusPending
+		if instance.State == "running" {
+			status = runnerStatusRunning
+		}
+		if err := s.runnerStore.UpdateStatus(ctx, name, instance.InstanceID, status); err != nil {
+			s.logger.Error(err, "Failed to record runner during drain", "runnerName", name)
+		}
+	}
+}
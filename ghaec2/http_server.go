@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// HTTPServer serves the scaler's HTTP endpoints
+type HTTPServer struct {
+	server *http.Server
+	mux    *http.ServeMux
+	logger logr.Logger
+}
+
+// NewHTTPServer creates an HTTP server listening on addr
+func NewHTTPServer(addr string, logger logr.Logger) *HTTPServer {
+	mux := http.NewServeMux()
+	return &HTTPServer{
+		server: &http.Server{
+			Addr:              addr,
+			Handler:           mux,
+			ReadHeaderTimeout: 10 * time.Second,
+		},
+		mux:    mux,
+		logger: logger,
+	}
+}
+
+// Handle registers a handler for the given path
+func (h *HTTPServer) Handle(pattern string, handler http.HandlerFunc) {
+	h.mux.HandleFunc(pattern, handler)
+}
+
+// registerHandlers registers the scaler's endpoints under prefix
+func (s *MessageQueueScaler) registerHandlers(h *HTTPServer, prefix string) {
+	h.Handle(prefix+"/stats/history", s.handleStatisticsHistory)
+	h.Handle(prefix+"/stats/job-latency", s.handleJobLatency)
+	h.Handle(prefix+"/decisions/history", s.handleDecisionHistory)
+	h.Handle(prefix+"/recommendations/max-runners", s.handleMaxRunnersRecommendation)
+	h.Handle(prefix+"/live", s.handleLive)
+	if s.config.PrometheusEnabled {
+		h.Handle(prefix+"/metrics", s.metrics.handlePrometheus)
+	}
+	if s.config.ScalingSource == scalingSourceWebhook {
+		h.Handle(prefix+"/webhook", s.handleWorkflowJobWebhook)
+	}
+	if s.config.AdminToken != "" {
+		h.Handle(prefix+"/admin/status", s.requireAdmin(http.MethodGet, s.handleAdminStatus))
+		h.Handle(prefix+"/admin/pause", s.requireAdmin(http.MethodPost, s.handleAdminPause))
+		h.Handle(prefix+"/admin/resume", s.requireAdmin(http.MethodPost, s.handleAdminResume))
+		h.Handle(prefix+"/admin/limits", s.requireAdmin(http.MethodPost, s.handleAdminLimits))
+		h.Handle(prefix+"/admin/reconcile", s.requireAdmin(http.MethodPost, s.handleAdminReconcile))
+		h.Handle(prefix+"/admin/drain", s.requireAdmin(http.MethodPost, s.handleAdminDrain))
+	}
+}
+
+// Run serves requests until the context is cancelled
+func (h *HTTPServer) Run(ctx context.Context) {
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+		if err := h.server.Shutdown(shutdownCtx); err != nil {
+			h.logger.Error(err, "Failed to shut down HTTP server")
+		}
+	}()
+
+	h.logger.Info("Starting HTTP server", "addr", h.server.Addr)
+	if err := h.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		h.logger.Error(err, "HTTP server failed")
+	}
+}
+
+// writeJSON writes v as a JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// handleStatisticsHistory serves the statistics history. The optional "since" query
+// parameter is a duration such as 3h; by default the whole buffer is returned.
+func (s *MessageQueueScaler) handleStatisticsHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var since time.Time
+	if value := r.URL.Query().Get("since"); value != "" {
+		window, err := time.ParseDuration(value)
+		if err != nil {
+			http.Error(w, "invalid since: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		since = time.Now().Add(-window)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"scaleSetName": s.config.RunnerScaleSetName,
+		"interval":     s.config.StatsHistoryInterval.String(),
+		"snapshots":    s.history.Since(since),
+	})
+}
+
+// handleDecisionHistory serves the persisted scaling decisions. The optional "from" and "to"
+// query parameters are RFC 3339 times or durations such as 3h before now; by default the
+// last 24 hours are returned.
+func (s *MessageQueueScaler) handleDecisionHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.decisionStore.Enabled() {
+		http.Error(w, "decision history is disabled: DECISIONS_TABLE_NAME is not set", http.StatusNotFound)
+		return
+	}
+
+	from, err := parseHistoryTime(r.URL.Query().Get("from"), time.Now().Add(-24*time.Hour))
+	if err != nil {
+		http.Error(w, "invalid from: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	to, err := parseHistoryTime(r.URL.Query().Get("to"), time.Now())
+	if err != nil {
+		http.Error(w, "invalid to: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	decisions, err := s.decisionStore.Between(r.Context(), from, to)
+	if err != nil {
+		s.logger.Error(err, "Failed to read scaling decisions")
+		http.Error(w, "failed to read scaling decisions", http.StatusBadGateway)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"scaleSetName": s.config.RunnerScaleSetName,
+		"from":         from.UTC().Format(time.RFC3339),
+		"to":           to.UTC().Format(time.RFC3339),
+		"decisions":    decisions,
+	})
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// latencySampleSize is the number of recent start latencies kept for the summary endpoint
+	latencySampleSize = 1000
+	// queuedJobRetention bounds how long an unstarted job is remembered
+	queuedJobRetention = 24 * time.Hour
+)
+
+// JobLatencyTracker correlates when a job was queued with when it started on a runner,
+// to measure how much latency provisioning adds compared to hosted runners
+type JobLatencyTracker struct {
+	mu      sync.Mutex
+	queued  map[int64]time.Time // runnerRequestId -> queue time
+	samples []time.Duration
+	next    int
+}
+
+// NewJobLatencyTracker creates an empty latency tracker
+func NewJobLatencyTracker() *JobLatencyTracker {
+	return &JobLatencyTracker{
+		queued: make(map[int64]time.Time),
+	}
+}
+
+// JobQueued records the queue time reported in a JobAvailable message
+func (t *JobLatencyTracker) JobQueued(runnerRequestID int64, queueTime time.Time) {
+	if queueTime.IsZero() {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, ok := t.queued[runnerRequestID]; !ok {
+		t.queued[runnerRequestID] = queueTime
+	}
+
+	// Jobs that were cancelled or taken by another scale set never start here
+	for id, queued := range t.queued {
+		if time.Since(queued) > queuedJobRetention {
+			delete(t.queued, id)
+		}
+	}
+}
+
+// JobDropped forgets a queued job that will not start on this scale set, because it was
+// skipped by policy, taken by another scaler or cancelled
+func (t *JobLatencyTracker) JobDropped(runnerRequestID int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.queued, runnerRequestID)
+}
+
+// OldestQueued returns how long the longest-waiting job that has not started has been queued
+func (t *JobLatencyTracker) OldestQueued(now time.Time) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var oldest time.Duration
+	for _, queued := range t.queued {
+		if wait := now.Sub(queued); wait > oldest {
+			oldest = wait
+		}
+	}
+	return oldest
+}
+
+// JobStarted returns the time between queueing and start for a job and records it as a sample.
+// The queue time from the JobStarted message is used when the JobAvailable was not seen.
+func (t *JobLatencyTracker) JobStarted(runnerRequestID int64, fallbackQueueTime, startedAt time.Time) (time.Duration, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	queueTime, ok := t.queued[runnerRequestID]
+	delete(t.queued, runnerRequestID)
+	if !ok {
+		queueTime = fallbackQueueTime
+	}
+	if queueTime.IsZero() || startedAt.Before(queueTime) {
+		return 0, false
+	}
+
+	latency := startedAt.Sub(queueTime)
+	if len(t.samples) < latencySampleSize {
+		t.samples = append(t.samples, latency)
+	} else {
+		t.samples[t.next] = latency
+		t.next = (t.next + 1) % latencySampleSize
+	}
+
+	return latency, true
+}
+
+// Summary returns percentiles over the recent start latencies
+func (t *JobLatencyTracker) Summary() map[string]interface{} {
+	t.mu.Lock()
+	sorted := append([]time.Duration{}, t.samples...)
+	t.mu.Unlock()
+
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	percentile := func(p float64) float64 {
+		if len(sorted) == 0 {
+			return 0
+		}
+		return sorted[int(p*float64(len(sorted)-1))].Seconds()
+	}
+
+	return map[string]interface{}{
+		"samples":    len(sorted),
+		"p50Seconds": percentile(0.50),
+		"p90Seconds": percentile(0.90),
+		"p99Seconds": percentile(0.99),
+		"maxSeconds": percentile(1),
+	}
+}
+
+// handleJobLatency serves the job start latency summary
+func (s *MessageQueueScaler) handleJobLatency(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, s.jobLatency.Summary())
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"strings"
+)
+
+// Job acquisition modes
+const (
+	// acquisitionModeBatch acquires every available job in one AcquireJobs call
+	acquisitionModeBatch = "batch"
+	// acquisitionModePerJob acquires jobs one at a time through their acquireJobUrl
+	acquisitionModePerJob = "per-job"
+)
+
+// JobPolicy decides which available jobs this scaler is willing to acquire
+type JobPolicy struct {
+	allowedRepositories map[string]struct{}
+	labels              *LabelMatcher
+}
+
+// NewJobPolicy builds a job policy from the configuration.
+// An empty repository allowlist allows every repository.
+func NewJobPolicy(config *Config) *JobPolicy {
+	// Jobs target a scale set by its name, so the name counts as a label we can serve
+	labels := append([]string{config.RunnerScaleSetName}, config.RunnerLabels...)
+
+	policy := &JobPolicy{
+		allowedRepositories: make(map[string]struct{}, len(config.AllowedRepositories)),
+		labels:              NewLabelMatcher(labels, jobLabelOptions.WithExclusions(config.ExcludedLabels)),
+	}
+
+	for _, repo := range config.AllowedRepositories {
+		policy.allowedRepositories[strings.ToLower(repo)] = struct{}{}
+	}
+
+	return policy
+}
+
+// Allows reports whether the job passes the policy, and the reason when it does not
+func (p *JobPolicy) Allows(job *JobAvailable) (bool, string) {
+	if len(p.allowedRepositories) > 0 {
+		fullName := strings.ToLower(job.OwnerName + "/" + job.RepositoryName)
+		_, fullMatch := p.allowedRepositories[fullName]
+		_, nameMatch := p.allowedRepositories[strings.ToLower(job.RepositoryName)]
+		if !fullMatch && !nameMatch {
+			return false, "repository not in allowlist"
+		}
+	}
+
+	if reason := p.labels.Reject(job.RequestLabels); reason != "" {
+		return false, reason
+	}
+
+	return true, ""
+}
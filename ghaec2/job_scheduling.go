@@ -0,0 +1,60 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// sortOldestFirst orders jobs by queue time so the longest-waiting jobs are served first.
+// Jobs without a queue time keep their relative order after the timed ones.
+func sortOldestFirst(jobs []*JobAvailable) {
+	sort.SliceStable(jobs, func(i, j int) bool {
+		a, b := jobs[i].QueueTime, jobs[j].QueueTime
+		if a.IsZero() || b.IsZero() {
+			return !a.IsZero() && b.IsZero()
+		}
+		return a.Before(b)
+	})
+}
+
+// maxWaitTime returns how long the oldest job has been queued
+func maxWaitTime(jobs []*JobAvailable, now time.Time) time.Duration {
+	var longest time.Duration
+	for _, job := range jobs {
+		if job.QueueTime.IsZero() {
+			continue
+		}
+		if wait := now.Sub(job.QueueTime); wait > longest {
+			longest = wait
+		}
+	}
+	return longest
+}
+
+// fairOrder interleaves jobs round-robin across repositories, keeping each repository's
+// jobs in their original order. Callers sort oldest-first beforehand, so repositories
+// are visited in order of their longest-waiting job. One repository that queued many jobs then cannot take
+// every free slot ahead of other repositories with a single queued job.
+func fairOrder(jobs []*JobAvailable) []*JobAvailable {
+	var repoOrder []string
+	byRepo := make(map[string][]*JobAvailable)
+	for _, job := range jobs {
+		repo := strings.ToLower(job.OwnerName + "/" + job.RepositoryName)
+		if _, ok := byRepo[repo]; !ok {
+			repoOrder = append(repoOrder, repo)
+		}
+		byRepo[repo] = append(byRepo[repo], job)
+	}
+
+	ordered := make([]*JobAvailable, 0, len(jobs))
+	for round := 0; len(ordered) < len(jobs); round++ {
+		for _, repo := range repoOrder {
+			if round < len(byRepo[repo]) {
+				ordered = append(ordered, byRepo[repo][round])
+			}
+		}
+	}
+
+	return ordered
+}
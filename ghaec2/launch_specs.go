@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/Anshuman2121/actionsspot/internal/ec2launch"
+)
+
+// LaunchSpec is the EC2 profile of the runners for jobs requesting all of its labels, e.g.
+// {"labels": ["gpu"], "instanceType": "g5.xlarge", "ami": "ami-0abc"}. Every spec runs as a
+// pool of its own: a scale set labelled with RUNNER_LABELS plus the spec's labels, so the
+// Actions Service routes the jobs requesting them to it. Empty fields keep EC2_INSTANCE_TYPE,
+// EC2_AMI_ID and the launch template of the scaler.
+type LaunchSpec struct {
+	Name                  string   `json:"name,omitempty"` // pool name, defaults to <scale set>-<labels>
+	Labels                []string `json:"labels"`
+	InstanceType          string   `json:"instanceType,omitempty"`
+	AMI                   string   `json:"ami,omitempty"`
+	LaunchTemplateID      string   `json:"launchTemplateId,omitempty"`
+	LaunchTemplateVersion string   `json:"launchTemplateVersion,omitempty"`
+}
+
+// parseLaunchSpecs parses the LABEL_LAUNCH_SPECS JSON array
+func parseLaunchSpecs(raw string) ([]LaunchSpec, error) {
+	var specs []LaunchSpec
+	if err := json.Unmarshal([]byte(raw), &specs); err != nil {
+		return nil, err
+	}
+	return specs, nil
+}
+
+// validateLaunchSpecs checks that every spec has labels and launches something different
+func validateLaunchSpecs(specs []LaunchSpec) error {
+	for i, spec := range specs {
+		if len(spec.Labels) == 0 {
+			return fmt.Errorf("launch spec %d has no labels", i)
+		}
+		if spec.InstanceType == "" && spec.AMI == "" && spec.LaunchTemplateID == "" {
+			return fmt.Errorf("launch spec %v sets no instance type, AMI or launch template", spec.Labels)
+		}
+		if err := ec2launch.ValidateLaunchTemplate(spec.LaunchTemplateID, spec.LaunchTemplateVersion); err != nil {
+			return fmt.Errorf("launch spec %v: %w", spec.Labels, err)
+		}
+	}
+	return nil
+}
+
+// poolName returns the name of the spec's pool
+func (s LaunchSpec) poolName(scaleSetName string) string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return strings.ToLower(scaleSetName + "-" + strings.Join(s.Labels, "-"))
+}
+
+// launchSpecPools returns the pools the launch specs run as. Without POOLS_CONFIG_FILE the
+// top-level configuration becomes the first pool, so jobs requesting none of the spec labels
+// keep their scale set. Spec pools keep no idle runners unless MIN_RUNNERS is set per pool.
+func (c *Config) launchSpecPools() []PoolConfig {
+	var pools []PoolConfig
+	if len(c.Pools) == 0 {
+		pools = append(pools, PoolConfig{
+			Name:   c.RunnerScaleSetName,
+			Labels: c.RunnerLabels,
+		})
+	}
+	noIdleRunners := 0
+	for _, spec := range c.LaunchSpecs {
+		pools = append(pools, PoolConfig{
+			Name:              spec.poolName(c.RunnerScaleSetName),
+			Labels:            appendUniqueLabels(append([]string(nil), c.RunnerLabels...), spec.Labels),
+			MinRunners:        &noIdleRunners,
+			InstanceType:      spec.InstanceType,
+			AMI:               spec.AMI,
+			LaunchTemplateID:  spec.LaunchTemplateID,
+			LaunchTemplateVer: spec.LaunchTemplateVersion,
+		})
+	}
+	return pools
+}
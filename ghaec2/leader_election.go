@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/go-logr/logr"
+)
+
+// LeaderElector elects one leader among the replicas of a scale set through a lease item in
+// a DynamoDB table keyed by scale set name, e.g. {"scale_set_name": "linux-x64", "holder":
+// "host-1a2b3c4d", "expires_at": 1700000000}. Only the leader runs the polling loop and holds
+// the message session. It renews the lease every LEADER_RENEW_INTERVAL; when it dies, the
+// lease runs out after LEADER_LEASE_DURATION and a standby takes over, resuming the session
+// the leader persisted. Expiry is compared against the writers' clocks, so the lease must be
+// well above their skew.
+type LeaderElector struct {
+	client        *dynamodb.Client
+	tableName     string
+	scaleSet      string
+	identity      string
+	lease         time.Duration
+	renewInterval time.Duration
+	logger        logr.Logger
+}
+
+// NewLeaderElector creates a leader elector. A nil client or empty table name disables it.
+func NewLeaderElector(client *dynamodb.Client, config *Config, logger logr.Logger) *LeaderElector {
+	hostname, _ := os.Hostname()
+	if hostname == "" {
+		hostname = "ghaec2-scaler"
+	}
+	randomBytes := make([]byte, 4)
+	_, _ = rand.Read(randomBytes)
+
+	return &LeaderElector{
+		client:        client,
+		tableName:     config.LeaderElectionTableName,
+		scaleSet:      config.RunnerScaleSetName,
+		identity:      fmt.Sprintf("%s-%s", hostname, hex.EncodeToString(randomBytes)),
+		lease:         config.LeaderLeaseDuration,
+		renewInterval: config.LeaderRenewInterval,
+		logger:        logger,
+	}
+}
+
+// Enabled reports whether replicas elect a leader
+func (e *LeaderElector) Enabled() bool {
+	return e.client != nil && e.tableName != ""
+}
+
+// Lead waits until this replica holds the lease and runs lead with a context that is
+// cancelled when the lease is lost, then stands by again. It returns when ctx is cancelled,
+// or with lead's result when lead returns on its own; the lease is released either way so a
+// standby takes over without waiting for it to expire.
+func (e *LeaderElector) Lead(ctx context.Context, lead func(ctx context.Context) error) error {
+	e.logger.Info("Standing by for leadership", "identity", e.identity, "scaleSet", e.scaleSet)
+
+	for {
+		acquired, err := e.tryAcquire(ctx)
+		if err != nil && ctx.Err() == nil {
+			e.logger.Error(err, "Failed to acquire leadership")
+		}
+		if !acquired {
+			sleepContext(ctx, e.renewInterval)
+			if ctx.Err() != nil {
+				return nil
+			}
+			continue
+		}
+
+		e.logger.Info("Acquired leadership", "identity", e.identity)
+		leaderCtx, cancel := context.WithCancel(ctx)
+		lost := make(chan struct{})
+		go e.holdLease(leaderCtx, cancel, lost)
+
+		err = lead(leaderCtx)
+		cancel()
+		select {
+		case <-lost:
+			if ctx.Err() == nil {
+				e.logger.Info("Lost leadership, standing by", "identity", e.identity)
+				continue
+			}
+		default:
+		}
+
+		e.release(ctx)
+		return err
+	}
+}
+
+// holdLease renews the lease until ctx is cancelled. It cancels the leadership when another
+// replica took the lease, or when renewals kept failing so long that the lease could expire
+// before the next one, and closes lost in both cases.
+func (e *LeaderElector) holdLease(ctx context.Context, cancel context.CancelFunc, lost chan<- struct{}) {
+	renewed := time.Now()
+	ticker := time.NewTicker(e.renewInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		acquired, err := e.tryAcquire(ctx)
+		switch {
+		case ctx.Err() != nil:
+			return
+		case acquired:
+			renewed = time.Now()
+			continue
+		case err == nil:
+			e.logger.Info("Leadership taken over by another replica")
+		case time.Since(renewed)+e.renewInterval < e.lease:
+			e.logger.Error(err, "Failed to renew leadership, retrying")
+			continue
+		default:
+			e.logger.Error(err, "Failed to renew leadership before the lease could expire, stepping down")
+		}
+		close(lost)
+		cancel()
+		return
+	}
+}
+
+// tryAcquire takes or renews the lease, which succeeds when nobody holds it, its lease has
+// expired, or this replica already holds it. It reports false without an error when another
+// replica holds a live lease.
+func (e *LeaderElector) tryAcquire(ctx context.Context) (bool, error) {
+	now := time.Now()
+	_, err := e.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(e.tableName),
+		Item: map[string]types.AttributeValue{
+			"scale_set_name": &types.AttributeValueMemberS{Value: e.scaleSet},
+			"holder":         &types.AttributeValueMemberS{Value: e.identity},
+			"expires_at":     &types.AttributeValueMemberN{Value: strconv.FormatInt(now.Add(e.lease).Unix(), 10)},
+		},
+		ConditionExpression: aws.String("attribute_not_exists(scale_set_name) OR expires_at < :now OR holder = :holder"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":now":    &types.AttributeValueMemberN{Value: strconv.FormatInt(now.Unix(), 10)},
+			":holder": &types.AttributeValueMemberS{Value: e.identity},
+		},
+	})
+	if err != nil {
+		var conditionFailed *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionFailed) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to write lease in %s: %w", e.tableName, err)
+	}
+	return true, nil
+}
+
+// release gives up the lease, but only if this replica still holds it
+func (e *LeaderElector) release(ctx context.Context) {
+	// Runs on shutdown too, when ctx is already cancelled
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+
+	_, err := e.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(e.tableName),
+		Key: map[string]types.AttributeValue{
+			"scale_set_name": &types.AttributeValueMemberS{Value: e.scaleSet},
+		},
+		ConditionExpression: aws.String("holder = :holder"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":holder": &types.AttributeValueMemberS{Value: e.identity},
+		},
+	})
+	if err != nil {
+		var conditionFailed *types.ConditionalCheckFailedException
+		if !errors.As(err, &conditionFailed) {
+			e.logger.Error(err, "Failed to release leadership")
+		}
+		return
+	}
+	e.logger.Info("Released leadership", "identity", e.identity)
+}
+
+// SuperviseAsLeader runs Supervise and the runner sync only while this replica is the
+// leader. Losing the leadership stops the polling loop, which deletes the message session,
+// and records the tracked runners for the next leader before standing by again.
+func (s *MessageQueueScaler) SuperviseAsLeader(ctx context.Context, elector *LeaderElector) error {
+	s.standby.Store(true)
+	return elector.Lead(ctx, func(leaderCtx context.Context) error {
+		s.standby.Store(false)
+		defer s.standby.Store(true)
+
+		go s.runRunnerSync(leaderCtx, s.config.RunnerSyncInterval)
+		err := s.Supervise(leaderCtx)
+		s.drain(leaderCtx)
+		s.resetConnection()
+		return err
+	})
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// LimitWindow replaces MAX_RUNNERS during a daily time window, optionally on some weekdays
+// only. A window ending before it starts runs past midnight, and its days are the days it
+// starts on.
+type LimitWindow struct {
+	Spec       string
+	Days       [7]bool
+	Start      int // minutes since midnight
+	End        int // minutes since midnight, up to 24:00
+	MaxRunners int
+}
+
+// parseLimitWindows parses comma-separated windows such as
+// "Mon-Fri 08:00-18:00=15, 22:00-06:00=40". The first matching window applies.
+func parseLimitWindows(spec string) ([]LimitWindow, error) {
+	var windows []LimitWindow
+	for _, entry := range strings.Split(spec, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		window, err := parseLimitWindow(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid window %q: %w", entry, err)
+		}
+		windows = append(windows, window)
+	}
+	return windows, nil
+}
+
+func parseLimitWindow(entry string) (LimitWindow, error) {
+	window := LimitWindow{Spec: entry}
+	hours, limit, ok := strings.Cut(entry, "=")
+	if !ok {
+		return window, fmt.Errorf("expected [days] HH:MM-HH:MM=max")
+	}
+	maxRunners, err := strconv.Atoi(strings.TrimSpace(limit))
+	if err != nil {
+		return window, fmt.Errorf("invalid max runners: %w", err)
+	}
+	window.MaxRunners = maxRunners
+
+	fields := strings.Fields(hours)
+	switch len(fields) {
+	case 1:
+		for day := range window.Days {
+			window.Days[day] = true
+		}
+	case 2:
+		if window.Days, err = parseWeekdays(fields[0]); err != nil {
+			return window, err
+		}
+		fields = fields[1:]
+	default:
+		return window, fmt.Errorf("expected [days] HH:MM-HH:MM=max")
+	}
+
+	start, end, ok := strings.Cut(fields[0], "-")
+	if !ok {
+		return window, fmt.Errorf("expected a time range HH:MM-HH:MM")
+	}
+	if window.Start, err = parseClock(start); err != nil {
+		return window, err
+	}
+	if window.End, err = parseClock(end); err != nil {
+		return window, err
+	}
+	if window.Start == window.End || window.Start == 24*60 {
+		return window, fmt.Errorf("empty time range %s", fields[0])
+	}
+	return window, nil
+}
+
+// parseWeekdays parses a day such as Sat or a range such as Mon-Fri, which may wrap
+// around the end of the week
+func parseWeekdays(spec string) ([7]bool, error) {
+	var days [7]bool
+	first, last, isRange := strings.Cut(spec, "-")
+	if !isRange {
+		last = first
+	}
+	from, ok := weekdayNames[strings.ToLower(first)]
+	if !ok {
+		return days, fmt.Errorf("unknown weekday %q", first)
+	}
+	to, ok := weekdayNames[strings.ToLower(last)]
+	if !ok {
+		return days, fmt.Errorf("unknown weekday %q", last)
+	}
+	for day := from; ; day = (day + 1) % 7 {
+		days[day] = true
+		if day == to {
+			break
+		}
+	}
+	return days, nil
+}
+
+// parseClock parses HH:MM into minutes since midnight; 24:00 is the end of the day
+func parseClock(clock string) (int, error) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		if clock == "24:00" {
+			return 24 * 60, nil
+		}
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", clock)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// contains reports whether the window covers the given time, in the time's location
+func (w LimitWindow) contains(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	day := t.Weekday()
+	if w.Start < w.End {
+		return w.Days[day] && minute >= w.Start && minute < w.End
+	}
+	// Past midnight: the evening part on the window's days, the morning part the day after
+	return (w.Days[day] && minute >= w.Start) || (w.Days[(day+6)%7] && minute < w.End)
+}
+
+// activeLimitWindow returns the first window covering the given time, or nil
+func activeLimitWindow(windows []LimitWindow, t time.Time) *LimitWindow {
+	for i := range windows {
+		if windows[i].contains(t) {
+			return &windows[i]
+		}
+	}
+	return nil
+}
+
+// updateLimitWindow selects the limit window for the current time and logs when it
+// changes the max runners. It is called by the decision loop before every scaling decision.
+func (s *MessageQueueScaler) updateLimitWindow(now time.Time) {
+	if len(s.config.LimitWindows) == 0 {
+		return
+	}
+	window := activeLimitWindow(s.config.LimitWindows, now.In(s.config.LimitWindowsLocation))
+
+	s.limitsMu.Lock()
+	previous := s.limitWindow
+	s.limitWindow = window
+	s.limitsMu.Unlock()
+	if previous == window {
+		return
+	}
+
+	minRunners, maxRunners := s.scalingLimits()
+	switch {
+	case window == nil:
+		s.logger.Info("Runner limit window ended, back to the default max runners",
+			"previousWindow", previous.Spec, "minRunners", minRunners, "maxRunners", maxRunners)
+	case previous == nil:
+		s.logger.Info("Runner limit window started",
+			"window", window.Spec, "minRunners", minRunners, "maxRunners", maxRunners)
+	default:
+		s.logger.Info("Runner limit window changed",
+			"window", window.Spec, "previousWindow", previous.Spec,
+			"minRunners", minRunners, "maxRunners", maxRunners)
+	}
+}
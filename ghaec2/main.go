@@ -2,26 +2,52 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/smithy-go/middleware"
+	"github.com/go-logr/logr"
 	"github.com/go-logr/zapr"
 	"go.uber.org/zap"
 	"log"
 	"os"
 	"os/signal"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
+	"time"
+
+	"github.com/Anshuman2121/actionsspot/internal/ec2launch"
+	"github.com/Anshuman2121/actionsspot/internal/runnername"
 )
 
 // Configuration from environment variables
 type Config struct {
 	// GitHub Configuration
-	GitHubToken         string
-	GitHubEnterpriseURL string
-	OrganizationName    string
-	RunnerLabels        []string
+	GitHubToken string
+	// GitHubTokenSecretARN names a Secrets Manager secret holding the token instead, re-read
+	// every GitHubTokenRefreshInterval
+	GitHubTokenSecretARN       string
+	GitHubTokenRefreshInterval time.Duration
+	gitHubTokenSecret          *SecretValue
+	// GitHubAppID, GitHubAppInstallationID and GitHubAppPrivateKeySecretARN authenticate as a
+	// GitHub App installation instead, with the App's private key read from Secrets Manager
+	GitHubAppID                  int64
+	GitHubAppInstallationID      int64
+	GitHubAppPrivateKeySecretARN string
+	gitHubAppToken               *AppToken
+	GitHubEnterpriseURL          string
+	OrganizationName             string
+	RunnerLabels                 []string
+	ExcludedLabels               []string // jobs carrying any of these labels are never acquired
+	RunnerNamePrefix             string
+	RunnerNameTemplate           string // placeholders: {prefix} {scaleset} {pool} {id} {timestamp}
 
 	// Runner Scale Set Configuration
 	RunnerScaleSetID   int
@@ -30,88 +56,495 @@ type Config struct {
 	MinRunners         int
 	MaxRunners         int
 
+	// Runner pools reconciled by the process, each with its own scale set (optional).
+	// PoolName is set on the configuration of a pool's scaler.
+	Pools    []PoolConfig
+	PoolName string
+
+	// Scale-down dampening, against thrashing between messages
+	ScaleDownCooldown       time.Duration // no scale-down this long after a scale-up
+	ScaleDownStabilization  int           // consecutive decisions that must want fewer runners
+	ScaleDownMaxPerDecision int           // most runners terminated by one decision; 0 is unlimited
+
+	// Scale-up pacing, against EC2 API throttling on bursts of queued jobs
+	MaxScaleUpPerCycle   int           // most runners launched by one decision; 0 is unlimited
+	ScaleUpBatchSize     int           // runners launched concurrently
+	ScaleUpBatchInterval time.Duration // pause between batches
+
+	// MAX_RUNNERS overrides per time window, e.g. more runners overnight (optional)
+	LimitWindows         []LimitWindow
+	LimitWindowsLocation *time.Location
+
+	// Job Acquisition Configuration
+	JobAcquisitionMode  string
+	AllowedRepositories []string
+
 	// AWS Configuration
-	AWSRegion          string
-	EC2SubnetID        string
-	EC2SecurityGroupID string
-	EC2KeyPairName     string
-	EC2InstanceType    string
-	EC2AMI             string
-	EC2SpotPrice       string
+	AWSRegion           string
+	EC2SubnetID         string
+	EC2SecurityGroupIDs []string // base group from EC2_SECURITY_GROUP_ID, then EC2_SECURITY_GROUP_IDS
+	EC2KeyPairName      string
+	EC2InstanceType     string
+	EC2AMI              string
+	EC2SpotPrices       map[string]string // ceilings per instance type, "default" for the rest
+	OnDemandOnly        bool              // launch on-demand instances instead of spot
+	LaunchSpecs         []LaunchSpec      // EC2 profiles of jobs requesting given labels, each run as a pool
+
+	// Launch template providing AMI, block devices, IAM profile and metadata options (optional)
+	EC2LaunchTemplateID      string
+	EC2LaunchTemplateVersion string // number, $Latest or $Default; empty uses the template's default version
+
+	// Runner table shared with the Lambda scaler (optional)
+	DynamoDBTableName     string
+	RunnerSyncInterval    time.Duration // how often the tracker and runner table are synced with EC2
+	RunnerRecordRetention time.Duration // runner records expire this long after their last update
+	// Message session table, for resuming the session after a crash (optional)
+	SessionsTableName      string
+	SessionRecordRetention time.Duration // session records expire this long after their last refresh
+
+	// Polling Configuration
+	PollInterval        time.Duration
+	PollErrorBackoff    time.Duration
+	PollCheckInterval   time.Duration
+	DiagnosticsInterval time.Duration
+	AdaptivePolling     bool
+	PollIdleInterval    time.Duration
+	PollIdleAfter       time.Duration
+
+	// REST scanning, for servers without runner scale sets: repositories scanned in parallel,
+	// and how long to scan slower after GitHub's abuse detection kicks in
+	RESTScanConcurrency int
+	RESTScanCooloff     time.Duration
+
+	// Where scaling decisions come from: the scale set message queue, or workflow_job
+	// webhooks signed with WebhookSecret for servers without the message API
+	ScalingSource string
+	WebhookSecret string
+
+	// Listener supervision: restart backoff and crash budget
+	SupervisorInitialBackoff time.Duration
+	SupervisorMaxBackoff     time.Duration
+	SupervisorStableAfter    time.Duration
+	SupervisorMaxRestarts    int
+
+	// HTTP Configuration
+	HTTPListenAddr       string
+	LivenessThreshold    time.Duration
+	DrainTimeout         time.Duration
+	StatsHistorySize     int
+	StatsHistoryInterval time.Duration
+	StatsTableName       string
+	StatsRetention       time.Duration
+	RightsizingInterval  time.Duration // how often a MaxRunners recommendation is made
+	RightsizingWindow    time.Duration // statistics the recommendation is made from
+	DecisionsTableName   string
+	DecisionsRetention   time.Duration
+	AdminToken           string // bearer token for the /admin endpoints; empty disables them
+
+	// Control item table, for pausing or clamping the scaler at runtime (optional)
+	ControlTableName    string
+	ControlPollInterval time.Duration
+
+	// Lease table for electing one leader among replicas of the scale set (optional)
+	LeaderElectionTableName string
+	LeaderLeaseDuration     time.Duration
+	LeaderRenewInterval     time.Duration
+
+	// Dynamic scaling policy from AWS AppConfig (optional)
+	AppConfigApplication  string
+	AppConfigEnvironment  string
+	AppConfigProfile      string
+	AppConfigAgentURL     string
+	AppConfigPollInterval time.Duration
+
+	// Monitoring Configuration
+	CloudWatchNamespace      string
+	MetricsEnabled           bool
+	PrometheusEnabled        bool // serve the metrics on /metrics in the Prometheus text format
+	AlarmsEnabled            bool
+	AlarmSNSTopicARN         string
+	AlarmErrorThreshold      int
+	AlarmQueuedJobsThreshold int
+	HeartbeatAlarmMinutes    int32
+	LambdaFunctionName       string
+	DeadmanThreshold         time.Duration
+	DeadmanSNSTopicARN       string
+
+	// OpenTelemetry tracing: OTLP/HTTP collector the spans are exported to (optional)
+	OTLPEndpoint    string
+	OTelServiceName string
+
+	// Queue starvation alerts: jobs queued longer than the threshold while at the max
+	// runners or failing to launch
+	StarvationThreshold       time.Duration
+	StarvationSNSTopicARN     string
+	StarvationSlackWebhookURL string
 }
 
-// LoadConfig loads configuration from environment variables
-func LoadConfig() (*Config, error) {
+// LoadConfig loads configuration from the given flags, the environment and CONFIG_FILE
+func LoadConfig(flags map[string]string) (*Config, error) {
+	src, err := newConfigSource(flags)
+	if err != nil {
+		return nil, err
+	}
+	return loadConfigFrom(src)
+}
+
+// loadConfigFrom parses the configuration resolved by src
+func loadConfigFrom(src *configSource) (*Config, error) {
 	config := &Config{
-		GitHubToken:         os.Getenv("GITHUB_TOKEN"),
-		GitHubEnterpriseURL: strings.TrimSuffix(os.Getenv("GITHUB_ENTERPRISE_URL"), "/"),
-		OrganizationName:    os.Getenv("ORGANIZATION_NAME"),
-		RunnerScaleSetName:  os.Getenv("RUNNER_SCALE_SET_NAME"),
-		AWSRegion:           os.Getenv("AWS_REGION"),
-		EC2SubnetID:         os.Getenv("EC2_SUBNET_ID"),
-		EC2SecurityGroupID:  os.Getenv("EC2_SECURITY_GROUP_ID"),
-		EC2KeyPairName:      os.Getenv("EC2_KEY_PAIR_NAME"),
-		EC2InstanceType:     os.Getenv("EC2_INSTANCE_TYPE"),
-		EC2AMI:              os.Getenv("EC2_AMI_ID"),
-		EC2SpotPrice:        os.Getenv("EC2_SPOT_PRICE"),
+		GitHubToken:                  src.Get("GITHUB_TOKEN"),
+		GitHubTokenSecretARN:         src.Get("GITHUB_TOKEN_SECRET_ARN"),
+		GitHubAppPrivateKeySecretARN: src.Get("GITHUB_APP_PRIVATE_KEY_SECRET_ARN"),
+		GitHubEnterpriseURL:          strings.TrimSuffix(src.Get("GITHUB_ENTERPRISE_URL"), "/"),
+		OrganizationName:             src.Get("ORGANIZATION_NAME"),
+		RunnerScaleSetName:           src.Get("RUNNER_SCALE_SET_NAME"),
+		AWSRegion:                    src.Get("AWS_REGION"),
+		EC2SubnetID:                  src.Get("EC2_SUBNET_ID"),
+		EC2KeyPairName:               src.Get("EC2_KEY_PAIR_NAME"),
+		EC2InstanceType:              src.Get("EC2_INSTANCE_TYPE"),
+		EC2AMI:                       src.Get("EC2_AMI_ID"),
+
+		EC2LaunchTemplateID:      src.Get("EC2_LAUNCH_TEMPLATE_ID"),
+		EC2LaunchTemplateVersion: src.Get("EC2_LAUNCH_TEMPLATE_VERSION"),
+
+		CloudWatchNamespace: src.Get("CLOUDWATCH_NAMESPACE"),
+		AlarmSNSTopicARN:    src.Get("ALARM_SNS_TOPIC_ARN"),
+		LambdaFunctionName:  src.Get("LAMBDA_FUNCTION_NAME"),
+		DeadmanSNSTopicARN:  src.Get("DEADMAN_SNS_TOPIC_ARN"),
+		JobAcquisitionMode:  src.Get("JOB_ACQUISITION_MODE"),
+		DynamoDBTableName:   src.Get("DYNAMODB_TABLE_NAME"),
+		SessionsTableName:   src.Get("SESSIONS_TABLE_NAME"),
+		HTTPListenAddr:      src.Get("HTTP_LISTEN_ADDR"),
+		StatsTableName:      src.Get("STATS_TABLE_NAME"),
+		DecisionsTableName:  src.Get("DECISIONS_TABLE_NAME"),
+		AdminToken:          src.Get("ADMIN_TOKEN"),
+		ScalingSource:       src.Get("SCALING_SOURCE"),
+		OTLPEndpoint:        src.Get("OTEL_EXPORTER_OTLP_ENDPOINT"),
+		OTelServiceName:     src.Get("OTEL_SERVICE_NAME"),
+		WebhookSecret:       src.Get("WEBHOOK_SECRET"),
+		RunnerNamePrefix:    src.Get("RUNNER_NAME_PREFIX"),
+		RunnerNameTemplate:  src.Get("RUNNER_NAME_TEMPLATE"),
+
+		AppConfigApplication: src.Get("APPCONFIG_APPLICATION"),
+		AppConfigEnvironment: src.Get("APPCONFIG_ENVIRONMENT"),
+		AppConfigProfile:     src.Get("APPCONFIG_PROFILE"),
+		AppConfigAgentURL:    src.Get("APPCONFIG_AGENT_URL"),
+
+		ControlTableName: src.Get("CONTROL_TABLE_NAME"),
+
+		LeaderElectionTableName: src.Get("LEADER_ELECTION_TABLE_NAME"),
+
+		StarvationSNSTopicARN:     src.Get("STARVATION_SNS_TOPIC_ARN"),
+		StarvationSlackWebhookURL: src.Get("STARVATION_SLACK_WEBHOOK_URL"),
+	}
+
+	// MAINTENANCE_TABLE_NAME is the setting of the control table before it held limits; its
+	// items have the same key and paused/reason attributes, so they are read as they are
+	if config.ControlTableName == "" {
+		config.ControlTableName = src.Get("MAINTENANCE_TABLE_NAME")
 	}
 
 	// Parse runner labels
-	if labels := os.Getenv("RUNNER_LABELS"); labels != "" {
+	if labels := src.Get("RUNNER_LABELS"); labels != "" {
 		config.RunnerLabels = strings.Split(labels, ",")
 		for i, label := range config.RunnerLabels {
 			config.RunnerLabels[i] = strings.TrimSpace(label)
 		}
-	} else {
-		config.RunnerLabels = []string{"self-hosted", "linux", "x64", "ghalistener-managed"}
+	}
+
+	// Parse exclusion labels
+	if labels := src.Get("EXCLUDED_LABELS"); labels != "" {
+		for _, label := range strings.Split(labels, ",") {
+			if label = strings.TrimSpace(label); label != "" {
+				config.ExcludedLabels = append(config.ExcludedLabels, label)
+			}
+		}
+	}
+
+	// Parse spot price ceilings (instance-type=price pairs)
+	if prices := src.Get("EC2_SPOT_PRICES"); prices != "" {
+		config.EC2SpotPrices = make(map[string]string)
+		for _, pair := range strings.Split(prices, ",") {
+			if pair = strings.TrimSpace(pair); pair == "" {
+				continue
+			}
+			instanceType, price, ok := strings.Cut(pair, "=")
+			if !ok {
+				return nil, fmt.Errorf("invalid EC2_SPOT_PRICES entry %q: expected instance-type=price", pair)
+			}
+			config.EC2SpotPrices[strings.TrimSpace(instanceType)] = strings.TrimSpace(price)
+		}
+	}
+	// EC2_SPOT_PRICE predates per-type ceilings and still sets the default ceiling
+	if price := src.Get("EC2_SPOT_PRICE"); price != "" {
+		if _, ok := config.EC2SpotPrices[ec2launch.DefaultSpotPriceKey]; !ok {
+			if config.EC2SpotPrices == nil {
+				config.EC2SpotPrices = make(map[string]string)
+			}
+			config.EC2SpotPrices[ec2launch.DefaultSpotPriceKey] = price
+		}
+	}
+
+	// Parse security groups: the base group plus any additional ones
+	if groupID := strings.TrimSpace(src.Get("EC2_SECURITY_GROUP_ID")); groupID != "" {
+		config.EC2SecurityGroupIDs = append(config.EC2SecurityGroupIDs, groupID)
+	}
+	if groups := src.Get("EC2_SECURITY_GROUP_IDS"); groups != "" {
+		for _, groupID := range strings.Split(groups, ",") {
+			if groupID = strings.TrimSpace(groupID); groupID != "" && !slices.Contains(config.EC2SecurityGroupIDs, groupID) {
+				config.EC2SecurityGroupIDs = append(config.EC2SecurityGroupIDs, groupID)
+			}
+		}
+	}
+
+	// Parse repository allowlist (owner/repo or repo names)
+	if repos := src.Get("ALLOWED_REPOSITORIES"); repos != "" {
+		for _, repo := range strings.Split(repos, ",") {
+			if repo = strings.TrimSpace(repo); repo != "" {
+				config.AllowedRepositories = append(config.AllowedRepositories, repo)
+			}
+		}
 	}
 
 	// Parse integer values
 	var err error
-	if scaleSetID := os.Getenv("RUNNER_SCALE_SET_ID"); scaleSetID != "" {
+	if scaleSetID := src.Get("RUNNER_SCALE_SET_ID"); scaleSetID != "" {
 		config.RunnerScaleSetID, err = strconv.Atoi(scaleSetID)
 		if err != nil {
 			return nil, fmt.Errorf("invalid RUNNER_SCALE_SET_ID: %w", err)
 		}
 	}
 
-	if runnerGroupID := os.Getenv("RUNNER_GROUP_ID"); runnerGroupID != "" {
+	if appID := src.Get("GITHUB_APP_ID"); appID != "" {
+		config.GitHubAppID, err = strconv.ParseInt(appID, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid GITHUB_APP_ID: %w", err)
+		}
+	}
+
+	if installationID := src.Get("GITHUB_APP_INSTALLATION_ID"); installationID != "" {
+		config.GitHubAppInstallationID, err = strconv.ParseInt(installationID, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid GITHUB_APP_INSTALLATION_ID: %w", err)
+		}
+	}
+
+	if runnerGroupID := src.Get("RUNNER_GROUP_ID"); runnerGroupID != "" {
 		config.RunnerGroupID, err = strconv.Atoi(runnerGroupID)
 		if err != nil {
 			return nil, fmt.Errorf("invalid RUNNER_GROUP_ID: %w", err)
 		}
-	} else {
-		config.RunnerGroupID = 1 // Default to "Default" group
 	}
 
-	if minRunners := os.Getenv("MIN_RUNNERS"); minRunners != "" {
+	if minRunners := src.Get("MIN_RUNNERS"); minRunners != "" {
 		config.MinRunners, err = strconv.Atoi(minRunners)
 		if err != nil {
 			return nil, fmt.Errorf("invalid MIN_RUNNERS: %w", err)
 		}
 	}
 
-	if maxRunners := os.Getenv("MAX_RUNNERS"); maxRunners != "" {
+	if maxRunners := src.Get("MAX_RUNNERS"); maxRunners != "" {
 		config.MaxRunners, err = strconv.Atoi(maxRunners)
 		if err != nil {
 			return nil, fmt.Errorf("invalid MAX_RUNNERS: %w", err)
 		}
-	} else {
-		config.MaxRunners = 10 // Default
 	}
 
-	// Set defaults
-	if config.EC2InstanceType == "" {
-		config.EC2InstanceType = "t3.medium"
+	if stabilization := src.Get("SCALE_DOWN_STABILIZATION"); stabilization != "" {
+		config.ScaleDownStabilization, err = strconv.Atoi(stabilization)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SCALE_DOWN_STABILIZATION: %w", err)
+		}
+	}
+
+	if maxPerDecision := src.Get("SCALE_DOWN_MAX_PER_DECISION"); maxPerDecision != "" {
+		config.ScaleDownMaxPerDecision, err = strconv.Atoi(maxPerDecision)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SCALE_DOWN_MAX_PER_DECISION: %w", err)
+		}
+	}
+
+	if maxScaleUp := src.Get("MAX_SCALE_UP_PER_CYCLE"); maxScaleUp != "" {
+		config.MaxScaleUpPerCycle, err = strconv.Atoi(maxScaleUp)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MAX_SCALE_UP_PER_CYCLE: %w", err)
+		}
+	}
+
+	if batchSize := src.Get("SCALE_UP_BATCH_SIZE"); batchSize != "" {
+		config.ScaleUpBatchSize, err = strconv.Atoi(batchSize)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SCALE_UP_BATCH_SIZE: %w", err)
+		}
+	}
+
+	// Parse time-windowed max runners, evaluated in MAX_RUNNERS_WINDOWS_TZ
+	if windows := src.Get("MAX_RUNNERS_WINDOWS"); windows != "" {
+		config.LimitWindows, err = parseLimitWindows(windows)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MAX_RUNNERS_WINDOWS: %w", err)
+		}
+	}
+	if tz := src.Get("MAX_RUNNERS_WINDOWS_TZ"); tz != "" {
+		config.LimitWindowsLocation, err = time.LoadLocation(tz)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MAX_RUNNERS_WINDOWS_TZ: %w", err)
+		}
+	}
+
+	// Parse monitoring configuration
+	if metricsEnabled := src.Get("CLOUDWATCH_METRICS_ENABLED"); metricsEnabled != "" {
+		config.MetricsEnabled, err = strconv.ParseBool(metricsEnabled)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CLOUDWATCH_METRICS_ENABLED: %w", err)
+		}
+	}
+	if prometheusEnabled := src.Get("PROMETHEUS_METRICS_ENABLED"); prometheusEnabled != "" {
+		config.PrometheusEnabled, err = strconv.ParseBool(prometheusEnabled)
+		if err != nil {
+			return nil, fmt.Errorf("invalid PROMETHEUS_METRICS_ENABLED: %w", err)
+		}
+	}
+
+	if alarmsEnabled := src.Get("CLOUDWATCH_ALARMS_ENABLED"); alarmsEnabled != "" {
+		config.AlarmsEnabled, err = strconv.ParseBool(alarmsEnabled)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CLOUDWATCH_ALARMS_ENABLED: %w", err)
+		}
+	}
+
+	if threshold := src.Get("ALARM_ERROR_THRESHOLD"); threshold != "" {
+		config.AlarmErrorThreshold, err = strconv.Atoi(threshold)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ALARM_ERROR_THRESHOLD: %w", err)
+		}
+	}
+
+	if threshold := src.Get("ALARM_QUEUED_JOBS_THRESHOLD"); threshold != "" {
+		config.AlarmQueuedJobsThreshold, err = strconv.Atoi(threshold)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ALARM_QUEUED_JOBS_THRESHOLD: %w", err)
+		}
+	}
+
+	if minutes := src.Get("HEARTBEAT_ALARM_MINUTES"); minutes != "" {
+		parsed, err := strconv.ParseInt(minutes, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid HEARTBEAT_ALARM_MINUTES: %w", err)
+		}
+		config.HeartbeatAlarmMinutes = int32(parsed)
+	}
+
+	// Parse polling intervals
+	durations := []struct {
+		name   string
+		target *time.Duration
+	}{
+		{"POLL_INTERVAL", &config.PollInterval},
+		{"GITHUB_TOKEN_REFRESH_INTERVAL", &config.GitHubTokenRefreshInterval},
+		{"SCALE_DOWN_COOLDOWN", &config.ScaleDownCooldown},
+		{"SCALE_UP_BATCH_INTERVAL", &config.ScaleUpBatchInterval},
+		{"POLL_ERROR_BACKOFF", &config.PollErrorBackoff},
+		{"POLL_CHECK_INTERVAL", &config.PollCheckInterval},
+		{"DIAGNOSTICS_INTERVAL", &config.DiagnosticsInterval},
+		{"POLL_IDLE_INTERVAL", &config.PollIdleInterval},
+		{"POLL_IDLE_AFTER", &config.PollIdleAfter},
+		{"REST_SCAN_COOLOFF", &config.RESTScanCooloff},
+		{"STATS_HISTORY_INTERVAL", &config.StatsHistoryInterval},
+		{"RUNNER_SYNC_INTERVAL", &config.RunnerSyncInterval},
+		{"RUNNER_RECORD_RETENTION", &config.RunnerRecordRetention},
+		{"SESSION_RECORD_RETENTION", &config.SessionRecordRetention},
+		{"STATS_RETENTION", &config.StatsRetention},
+		{"RIGHTSIZING_INTERVAL", &config.RightsizingInterval},
+		{"RIGHTSIZING_WINDOW", &config.RightsizingWindow},
+		{"DECISIONS_RETENTION", &config.DecisionsRetention},
+		{"APPCONFIG_POLL_INTERVAL", &config.AppConfigPollInterval},
+		{"CONTROL_POLL_INTERVAL", &config.ControlPollInterval},
+		{"LEADER_LEASE_DURATION", &config.LeaderLeaseDuration},
+		{"LEADER_RENEW_INTERVAL", &config.LeaderRenewInterval},
+		{"SUPERVISOR_INITIAL_BACKOFF", &config.SupervisorInitialBackoff},
+		{"SUPERVISOR_MAX_BACKOFF", &config.SupervisorMaxBackoff},
+		{"SUPERVISOR_STABLE_AFTER", &config.SupervisorStableAfter},
+		{"LIVENESS_THRESHOLD", &config.LivenessThreshold},
+		{"DRAIN_TIMEOUT", &config.DrainTimeout},
+		{"STARVATION_THRESHOLD", &config.StarvationThreshold},
+	}
+	for _, d := range durations {
+		if value := src.Get(d.name); value != "" {
+			*d.target, err = time.ParseDuration(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", d.name, err)
+			}
+		}
+	}
+
+	if restarts := src.Get("SUPERVISOR_MAX_RESTARTS"); restarts != "" {
+		config.SupervisorMaxRestarts, err = strconv.Atoi(restarts)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SUPERVISOR_MAX_RESTARTS: %w", err)
+		}
+	}
+
+	if concurrency := src.Get("REST_SCAN_CONCURRENCY"); concurrency != "" {
+		config.RESTScanConcurrency, err = strconv.Atoi(concurrency)
+		if err != nil {
+			return nil, fmt.Errorf("invalid REST_SCAN_CONCURRENCY: %w", err)
+		}
+	}
+
+	if size := src.Get("STATS_HISTORY_SIZE"); size != "" {
+		config.StatsHistorySize, err = strconv.Atoi(size)
+		if err != nil {
+			return nil, fmt.Errorf("invalid STATS_HISTORY_SIZE: %w", err)
+		}
+	}
+
+	if onDemandOnly := src.Get("ON_DEMAND_ONLY"); onDemandOnly != "" {
+		config.OnDemandOnly, err = strconv.ParseBool(onDemandOnly)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ON_DEMAND_ONLY: %w", err)
+		}
+	}
+
+	if adaptive := src.Get("ADAPTIVE_POLLING"); adaptive != "" {
+		config.AdaptivePolling, err = strconv.ParseBool(adaptive)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ADAPTIVE_POLLING: %w", err)
+		}
+	}
+
+	if threshold := src.Get("DEADMAN_THRESHOLD"); threshold != "" {
+		config.DeadmanThreshold, err = time.ParseDuration(threshold)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DEADMAN_THRESHOLD: %w", err)
+		}
+	}
+
+	if specs := src.Get("LABEL_LAUNCH_SPECS"); specs != "" {
+		config.LaunchSpecs, err = parseLaunchSpecs(specs)
+		if err != nil {
+			return nil, fmt.Errorf("invalid LABEL_LAUNCH_SPECS JSON: %w", err)
+		}
+	}
+
+	if path := src.Get("POOLS_CONFIG_FILE"); path != "" {
+		config.Pools, err = loadPools(path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid POOLS_CONFIG_FILE: %w", err)
+		}
 	}
-	if config.EC2SpotPrice == "" {
-		config.EC2SpotPrice = "0.05"
+
+	// The SNS topics default to the alarm topic
+	if config.DeadmanSNSTopicARN == "" {
+		config.DeadmanSNSTopicARN = config.AlarmSNSTopicARN
 	}
-	if config.AWSRegion == "" {
-		config.AWSRegion = "eu-north-1"
+	if config.StarvationSNSTopicARN == "" {
+		config.StarvationSNSTopicARN = config.AlarmSNSTopicARN
 	}
-	if config.RunnerScaleSetName == "" {
-		config.RunnerScaleSetName = "ghaec2-scaler"
+	// Every launch spec is a pool with a scale set of its own
+	if len(config.LaunchSpecs) > 0 {
+		config.Pools = append(config.Pools, config.launchSpecPools()...)
 	}
 
 	return config, nil
@@ -124,11 +557,23 @@ func (c *Config) Validate() error {
 		"GITHUB_ENTERPRISE_URL": c.GitHubEnterpriseURL,
 		"ORGANIZATION_NAME":     c.OrganizationName,
 		"EC2_SUBNET_ID":         c.EC2SubnetID,
-		"EC2_SECURITY_GROUP_ID": c.EC2SecurityGroupID,
+		"EC2_SECURITY_GROUP_ID": strings.Join(c.EC2SecurityGroupIDs, ","),
 		"EC2_KEY_PAIR_NAME":     c.EC2KeyPairName,
 		"EC2_AMI_ID":            c.EC2AMI,
 	}
 
+	// The token may come from Secrets Manager or a GitHub App instead
+	if c.GitHubTokenSecretARN != "" || c.GitHubAppPrivateKeySecretARN != "" {
+		delete(required, "GITHUB_TOKEN")
+	}
+
+	// A launch template provides the AMI, key pair and security groups instead
+	if c.EC2LaunchTemplateID != "" {
+		delete(required, "EC2_SECURITY_GROUP_ID")
+		delete(required, "EC2_KEY_PAIR_NAME")
+		delete(required, "EC2_AMI_ID")
+	}
+
 	for name, value := range required {
 		if value == "" {
 			return fmt.Errorf("required environment variable %s is not set", name)
@@ -155,6 +600,20 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("MAX_RUNNERS must be > 0")
 	}
 
+	if c.GitHubTokenSecretARN != "" && !strings.HasPrefix(c.GitHubTokenSecretARN, "arn:") {
+		return fmt.Errorf("GITHUB_TOKEN_SECRET_ARN must be a secret ARN")
+	}
+	if c.GitHubAppPrivateKeySecretARN != "" && !strings.HasPrefix(c.GitHubAppPrivateKeySecretARN, "arn:") {
+		return fmt.Errorf("GITHUB_APP_PRIVATE_KEY_SECRET_ARN must be a secret ARN")
+	}
+	if (c.GitHubAppID > 0 || c.GitHubAppInstallationID > 0 || c.GitHubAppPrivateKeySecretARN != "") &&
+		(c.GitHubAppID <= 0 || c.GitHubAppInstallationID <= 0 || c.GitHubAppPrivateKeySecretARN == "") {
+		return fmt.Errorf("GITHUB_APP_ID, GITHUB_APP_INSTALLATION_ID and GITHUB_APP_PRIVATE_KEY_SECRET_ARN must be set together")
+	}
+	if c.GitHubTokenRefreshInterval <= 0 {
+		return fmt.Errorf("GITHUB_TOKEN_REFRESH_INTERVAL must be > 0")
+	}
+
 	if c.MinRunners < 0 {
 		return fmt.Errorf("MIN_RUNNERS must be >= 0")
 	}
@@ -163,6 +622,160 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("MIN_RUNNERS (%d) cannot be greater than MAX_RUNNERS (%d)", c.MinRunners, c.MaxRunners)
 	}
 
+	if c.ScaleDownCooldown < 0 {
+		return fmt.Errorf("SCALE_DOWN_COOLDOWN must be >= 0")
+	}
+
+	if c.ScaleDownStabilization < 1 {
+		return fmt.Errorf("SCALE_DOWN_STABILIZATION must be >= 1")
+	}
+
+	if c.ScaleDownMaxPerDecision < 0 {
+		return fmt.Errorf("SCALE_DOWN_MAX_PER_DECISION must be >= 0")
+	}
+
+	if c.MaxScaleUpPerCycle < 0 {
+		return fmt.Errorf("MAX_SCALE_UP_PER_CYCLE must be >= 0")
+	}
+
+	if c.ScaleUpBatchSize <= 0 {
+		return fmt.Errorf("SCALE_UP_BATCH_SIZE must be > 0")
+	}
+
+	if c.ScaleUpBatchInterval < 0 {
+		return fmt.Errorf("SCALE_UP_BATCH_INTERVAL must be >= 0")
+	}
+
+	for _, window := range c.LimitWindows {
+		if window.MaxRunners <= 0 {
+			return fmt.Errorf("MAX_RUNNERS_WINDOWS window %q must allow > 0 runners", window.Spec)
+		}
+	}
+
+	if err := validateLabels(c.RunnerLabels); err != nil {
+		return fmt.Errorf("invalid RUNNER_LABELS: %w", err)
+	}
+
+	if err := validateLabels(c.ExcludedLabels); err != nil {
+		return fmt.Errorf("invalid EXCLUDED_LABELS: %w", err)
+	}
+
+	if err := ec2launch.ValidateSpotPrices(c.EC2SpotPrices); err != nil {
+		return fmt.Errorf("invalid EC2_SPOT_PRICES: %w", err)
+	}
+
+	if err := ec2launch.ValidateLaunchTemplate(c.EC2LaunchTemplateID, c.EC2LaunchTemplateVersion); err != nil {
+		return fmt.Errorf("invalid EC2_LAUNCH_TEMPLATE_ID: %w", err)
+	}
+
+	if err := validateLaunchSpecs(c.LaunchSpecs); err != nil {
+		return fmt.Errorf("invalid LABEL_LAUNCH_SPECS: %w", err)
+	}
+
+	if err := runnername.ValidateTemplate(c.RunnerNameTemplate); err != nil {
+		return fmt.Errorf("invalid RUNNER_NAME_TEMPLATE: %w", err)
+	}
+
+	if c.HeartbeatAlarmMinutes <= 0 {
+		return fmt.Errorf("HEARTBEAT_ALARM_MINUTES must be > 0")
+	}
+
+	if c.PollInterval <= 0 || c.PollErrorBackoff <= 0 || c.PollCheckInterval <= 0 || c.DiagnosticsInterval <= 0 {
+		return fmt.Errorf("POLL_INTERVAL, POLL_ERROR_BACKOFF, POLL_CHECK_INTERVAL and DIAGNOSTICS_INTERVAL must be > 0")
+	}
+
+	if c.RESTScanConcurrency < 1 || c.RESTScanCooloff <= 0 {
+		return fmt.Errorf("REST_SCAN_CONCURRENCY must be >= 1 and REST_SCAN_COOLOFF > 0")
+	}
+
+	if c.AdaptivePolling && c.PollIdleInterval < c.PollInterval {
+		return fmt.Errorf("POLL_IDLE_INTERVAL (%s) must be >= POLL_INTERVAL (%s)", c.PollIdleInterval, c.PollInterval)
+	}
+
+	if c.StatsHistorySize <= 0 || c.StatsHistoryInterval <= 0 {
+		return fmt.Errorf("STATS_HISTORY_SIZE and STATS_HISTORY_INTERVAL must be > 0")
+	}
+
+	if c.StatsTableName != "" && c.StatsRetention <= 0 {
+		return fmt.Errorf("STATS_RETENTION must be > 0")
+	}
+
+	if c.DecisionsTableName != "" && c.DecisionsRetention <= 0 {
+		return fmt.Errorf("DECISIONS_RETENTION must be > 0")
+	}
+
+	if c.DynamoDBTableName != "" && c.RunnerRecordRetention <= 0 {
+		return fmt.Errorf("RUNNER_RECORD_RETENTION must be > 0")
+	}
+
+	if c.SessionsTableName != "" && c.SessionRecordRetention <= 0 {
+		return fmt.Errorf("SESSION_RECORD_RETENTION must be > 0")
+	}
+
+	if c.DeadmanThreshold <= 0 {
+		return fmt.Errorf("DEADMAN_THRESHOLD must be > 0")
+	}
+
+	if c.StarvationThreshold <= 0 {
+		return fmt.Errorf("STARVATION_THRESHOLD must be > 0")
+	}
+
+	if c.RunnerSyncInterval <= 0 {
+		return fmt.Errorf("RUNNER_SYNC_INTERVAL must be > 0")
+	}
+
+	if c.RightsizingInterval <= 0 || c.RightsizingWindow <= 0 {
+		return fmt.Errorf("RIGHTSIZING_INTERVAL and RIGHTSIZING_WINDOW must be > 0")
+	}
+
+	if c.JobAcquisitionMode != acquisitionModeBatch && c.JobAcquisitionMode != acquisitionModePerJob {
+		return fmt.Errorf("JOB_ACQUISITION_MODE must be '%s' or '%s'", acquisitionModeBatch, acquisitionModePerJob)
+	}
+
+	if c.ScalingSource != scalingSourceMessageQueue && c.ScalingSource != scalingSourceWebhook {
+		return fmt.Errorf("SCALING_SOURCE must be '%s' or '%s'", scalingSourceMessageQueue, scalingSourceWebhook)
+	}
+
+	if c.ScalingSource == scalingSourceWebhook && c.WebhookSecret == "" {
+		return fmt.Errorf("WEBHOOK_SECRET is required with SCALING_SOURCE=%s", scalingSourceWebhook)
+	}
+
+	if c.AppConfigApplication != "" && (c.AppConfigEnvironment == "" || c.AppConfigProfile == "") {
+		return fmt.Errorf("APPCONFIG_ENVIRONMENT and APPCONFIG_PROFILE are required with APPCONFIG_APPLICATION")
+	}
+
+	if c.AppConfigPollInterval <= 0 {
+		return fmt.Errorf("APPCONFIG_POLL_INTERVAL must be > 0")
+	}
+	if c.ControlTableName != "" && c.ControlPollInterval <= 0 {
+		return fmt.Errorf("CONTROL_POLL_INTERVAL must be > 0")
+	}
+
+	if c.LeaderElectionTableName != "" && (c.LeaderRenewInterval <= 0 || c.LeaderLeaseDuration <= c.LeaderRenewInterval) {
+		return fmt.Errorf("LEADER_RENEW_INTERVAL (%s) must be > 0 and below LEADER_LEASE_DURATION (%s)", c.LeaderRenewInterval, c.LeaderLeaseDuration)
+	}
+
+	if c.SupervisorInitialBackoff <= 0 || c.SupervisorMaxBackoff < c.SupervisorInitialBackoff {
+		return fmt.Errorf("SUPERVISOR_INITIAL_BACKOFF must be > 0 and not exceed SUPERVISOR_MAX_BACKOFF")
+	}
+
+	if c.LivenessThreshold <= 0 || c.DrainTimeout <= 0 {
+		return fmt.Errorf("LIVENESS_THRESHOLD and DRAIN_TIMEOUT must be > 0")
+	}
+
+	if c.SupervisorMaxRestarts < 0 {
+		return fmt.Errorf("SUPERVISOR_MAX_RESTARTS must be >= 0")
+	}
+
+	if err := validatePools(c.Pools); err != nil {
+		return fmt.Errorf("invalid POOLS_CONFIG_FILE: %w", err)
+	}
+	for _, pool := range c.Pools {
+		if err := c.forPool(pool).Validate(); err != nil {
+			return fmt.Errorf("pool %q: %w", pool.Name, err)
+		}
+	}
+
 	return nil
 }
 
@@ -176,13 +789,30 @@ func main() {
 
 	logger := zapr.NewLogger(zapLogger)
 
+	// Setting flags such as --max-runners=5 come before the subcommand, if any
+	flags, args, err := parseStartupArgs(os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	// config dump resolves the configuration itself
+	if len(args) > 0 && args[0] == "config" {
+		os.Exit(runConfigDump(flags, args[1:], os.Stdout))
+	}
+
 	// Load configuration
-	cfg, err := LoadConfig()
+	cfg, err := LoadConfig(flags)
 	if err != nil {
 		logger.Error(err, "Failed to load configuration")
 		os.Exit(1)
 	}
 
+	// Run a one-shot subcommand instead of the scaler if one was given
+	if len(args) > 0 {
+		os.Exit(runCommand(args[0], args[1:], cfg, logger))
+	}
+
 	if err := cfg.Validate(); err != nil {
 		logger.Error(err, "Configuration validation failed")
 		os.Exit(1)
@@ -192,34 +822,120 @@ func main() {
 		"organization", cfg.OrganizationName,
 		"minRunners", cfg.MinRunners,
 		"maxRunners", cfg.MaxRunners,
+		"limitWindows", len(cfg.LimitWindows),
 		"runnerLabels", cfg.RunnerLabels,
+		"excludedLabels", cfg.ExcludedLabels,
 		"scaleSetName", cfg.RunnerScaleSetName,
+		"onDemandOnly", cfg.OnDemandOnly,
+		"scalingSource", cfg.ScalingSource,
+		"pools", len(cfg.Pools),
 	)
 
+	// Spans of AWS calls come from a middleware on every client built from the AWS config
+	tracer := NewTracer(cfg.OTLPEndpoint, cfg.OTelServiceName, logger.WithName("tracing"))
+	awsOptions := []func(*config.LoadOptions) error{config.WithRegion(cfg.AWSRegion)}
+	if tracer != nil {
+		awsOptions = append(awsOptions, config.WithAPIOptions([]func(*middleware.Stack) error{tracer.AWSMiddleware}))
+	}
+
 	// Initialize AWS clients
 	ctx := context.Background()
-	awsConfig, err := config.LoadDefaultConfig(ctx, config.WithRegion(cfg.AWSRegion))
+	awsConfig, err := config.LoadDefaultConfig(ctx, awsOptions...)
 	if err != nil {
 		logger.Error(err, "Failed to load AWS configuration")
 		os.Exit(1)
 	}
 
-	ec2Client := ec2.NewFromConfig(awsConfig)
+	// The GitHub token is read from Secrets Manager before any scaler connects
+	if cfg.GitHubTokenSecretARN != "" {
+		cfg.gitHubTokenSecret = NewSecretValue(awsConfig, cfg.GitHubTokenSecretARN, logger.WithName("secrets"))
+		if err := cfg.gitHubTokenSecret.Refresh(ctx); err != nil {
+			logger.Error(err, "Failed to read the GitHub token from Secrets Manager", "secretArn", cfg.GitHubTokenSecretARN)
+			os.Exit(1)
+		}
+	}
+	if cfg.GitHubAppPrivateKeySecretARN != "" {
+		privateKey := NewSecretValue(awsConfig, cfg.GitHubAppPrivateKeySecretARN, logger.WithName("secrets"))
+		cfg.gitHubAppToken = NewAppToken(cfg, privateKey, logger.WithName("github-app"))
+		if err := cfg.gitHubAppToken.Refresh(ctx); err != nil {
+			logger.Error(err, "Failed to create a GitHub App installation token", "secretArn", cfg.GitHubAppPrivateKeySecretARN)
+			os.Exit(1)
+		}
+	}
 
-	// Create the message queue-based scaler service (following actions-runner-controller pattern)
-	scaler := NewMessageQueueScaler(cfg, ec2Client, logger)
+	clients := awsClients{
+		ec2:        ec2.NewFromConfig(awsConfig),
+		cloudWatch: cloudwatch.NewFromConfig(awsConfig),
+		sns:        sns.NewFromConfig(awsConfig),
+		dynamoDB:   dynamodb.NewFromConfig(awsConfig),
+	}
+
+	// Provision monitoring alongside the scaler when requested
+	if cfg.AlarmsEnabled {
+		if err := NewAlarmProvisioner(clients.cloudWatch, cfg, logger.WithName("alarms")).EnsureAlarms(ctx); err != nil {
+			logger.Error(err, "Failed to provision CloudWatch alarms")
+		}
+	}
 
 	// Setup graceful shutdown
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
+	go tracer.Run(ctx, 10*time.Second)
+	if cfg.gitHubTokenSecret != nil {
+		go cfg.gitHubTokenSecret.Run(ctx, cfg.GitHubTokenRefreshInterval)
+	}
+	if cfg.gitHubAppToken != nil {
+		go cfg.gitHubAppToken.Run(ctx, cfg.GitHubTokenRefreshInterval)
+	}
+
+	// One scaler per pool, each reconciling its own scale set. A pool's endpoints are served
+	// under /pools/<name>.
+	httpServer := NewHTTPServer(cfg.HTTPListenAddr, logger.WithName("http"))
+	var scalers []*MessageQueueScaler
+	for _, poolConfig := range cfg.poolConfigs() {
+		poolLogger, prefix := logger, ""
+		if poolConfig.PoolName != "" {
+			poolLogger, prefix = logger.WithValues("pool", poolConfig.PoolName), "/pools/"+poolConfig.PoolName
+			poolLogger.Info("Configured runner pool",
+				"scaleSetName", poolConfig.RunnerScaleSetName,
+				"runnerLabels", poolConfig.RunnerLabels,
+				"instanceType", poolConfig.EC2InstanceType,
+				"minRunners", poolConfig.MinRunners,
+				"maxRunners", poolConfig.MaxRunners)
+		}
+		scaler := newScaler(ctx, poolConfig, clients, tracer, poolLogger)
+		scaler.registerHandlers(httpServer, prefix)
+		scalers = append(scalers, scaler)
+	}
+	if len(cfg.Pools) > 0 {
+		httpServer.Handle("/live", handlePoolsLive(scalers))
+		httpServer.Handle("/recommendations/max-runners", handlePoolsMaxRunnersRecommendations(scalers))
+	}
+	go httpServer.Run(ctx)
+	go notifySystemd(ctx, scalers, logger.WithName("systemd"))
+
 	// Handle shutdown signals
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
+	// SIGUSR1 toggles maintenance mode
+	maintenanceChan := make(chan os.Signal, 1)
+	signal.Notify(maintenanceChan, syscall.SIGUSR1)
+	go func() {
+		for range maintenanceChan {
+			for _, scaler := range scalers {
+				scaler.setPaused(!scaler.paused.Load(), "SIGUSR1", "")
+			}
+		}
+	}()
+
 	go func() {
 		sig := <-sigChan
 		logger.Info("Received shutdown signal", "signal", sig)
+		if err := sdNotify("STOPPING=1"); err != nil {
+			logger.Error(err, "Failed to notify systemd")
+		}
 		cancel()
 	}()
 
@@ -229,10 +945,89 @@ func main() {
 		"method", "message-queue-polling",
 		"compatibility", "works-with-any-GHES-version")
 
-	if err := scaler.Run(ctx); err != nil {
+	// Pools are reconciled independently: one failing does not stop the others
+	var wg sync.WaitGroup
+	errs := make([]error, len(scalers))
+	for i, scaler := range scalers {
+		wg.Add(1)
+		go func(i int, scaler *MessageQueueScaler) {
+			defer wg.Done()
+			elector := NewLeaderElector(clients.dynamoDB, scaler.config, scaler.logger.WithName("leader-election"))
+			if errs[i] = runScaler(ctx, scaler, elector); errs[i] != nil && scaler.config.PoolName != "" {
+				errs[i] = fmt.Errorf("pool %s: %w", scaler.config.PoolName, errs[i])
+				scaler.logger.Error(errs[i], "Pool scaler failed")
+			}
+		}(i, scaler)
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
 		logger.Error(err, "Message queue scaler failed")
 		os.Exit(1)
 	}
 
 	logger.Info("GitHub Actions Message Queue Scaler stopped")
 }
+
+// awsClients are the AWS clients shared by the scalers of every pool
+type awsClients struct {
+	ec2        *ec2.Client
+	cloudWatch *cloudwatch.Client
+	sns        *sns.Client
+	dynamoDB   *dynamodb.Client
+}
+
+// newScaler creates the scaler of one scale set with its monitors and stores, and starts
+// its background loops
+func newScaler(ctx context.Context, cfg *Config, clients awsClients, tracer *Tracer, logger logr.Logger) *MessageQueueScaler {
+	// Unreachable endpoints only surface as runners that never register, so warn up front
+	if checks, err := CheckRunnerReachability(ctx, clients.ec2, cfg); err != nil {
+		logger.Error(err, "Runner subnet preflight failed")
+	} else {
+		for _, check := range checks {
+			if !check.OK {
+				logger.Info("Runner subnet preflight check failed, run 'ghaec2 validate' for details",
+					"check", check.Name, "detail", check.Detail)
+			}
+		}
+	}
+
+	var metricsClient *cloudwatch.Client
+	if cfg.MetricsEnabled {
+		metricsClient = clients.cloudWatch
+	}
+	metrics := NewMetricsPublisher(metricsClient, cfg.CloudWatchNamespace, cfg.RunnerScaleSetName, cfg.AWSRegion, logger.WithName("metrics"))
+
+	deadman := NewDeadmanMonitor(cfg, clients.sns, metrics, logger.WithName("deadman"))
+	starvation := NewStarvationMonitor(cfg, clients.sns, metrics, logger.WithName("starvation"))
+
+	// Create the message queue-based scaler service (following actions-runner-controller pattern)
+	runnerStore := NewRunnerStore(clients.dynamoDB, cfg.DynamoDBTableName, cfg.RunnerRecordRetention, logger.WithName("runner-store"))
+	sessionStore := NewSessionStore(clients.dynamoDB, cfg.SessionsTableName, cfg.SessionRecordRetention, logger.WithName("session-store"))
+	statsStore := NewStatisticsStore(clients.dynamoDB, cfg, logger.WithName("stats-store"))
+	decisionStore := NewDecisionStore(clients.dynamoDB, cfg, logger.WithName("decision-store"))
+	provider := NewEC2SpotProvider(clients.ec2, cfg, metrics, logger.WithName("ec2-provider"))
+	scaler := NewMessageQueueScaler(cfg, provider, metrics, deadman, starvation, runnerStore, sessionStore, statsStore, decisionStore, tracer, logger)
+
+	go metrics.Run(ctx, time.Minute)
+	go scaler.recordStatisticsHistory(ctx, cfg.StatsHistoryInterval)
+	go scaler.runRightsizing(ctx, cfg.RightsizingInterval, cfg.RightsizingWindow)
+	if source := NewAppConfigSource(cfg, logger.WithName("appconfig")); source != nil {
+		go scaler.watchScalingPolicy(ctx, source, cfg.AppConfigPollInterval)
+	}
+	if store := NewControlStore(clients.dynamoDB, cfg, logger.WithName("control")); store.Enabled() {
+		go scaler.watchControl(ctx, store, cfg.ControlPollInterval)
+	}
+	return scaler
+}
+
+// runScaler runs the scaler until ctx is cancelled. With leader election only the leader
+// polls; the others stand by to take over.
+func runScaler(ctx context.Context, scaler *MessageQueueScaler, elector *LeaderElector) error {
+	if elector.Enabled() {
+		return scaler.SuperviseAsLeader(ctx, elector)
+	}
+	go scaler.runRunnerSync(ctx, scaler.config.RunnerSyncInterval)
+	err := scaler.Supervise(ctx)
+	scaler.drain(ctx)
+	return err
+}
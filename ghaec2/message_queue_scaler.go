@@ -6,28 +6,80 @@ import (
 	"encoding/json"
 	"encoding/hex"
 	"fmt"
+	"net/http"
 	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/Anshuman2121/actionsspot/internal/actions"
+	"github.com/Anshuman2121/actionsspot/internal/runnername"
 	"github.com/go-logr/logr"
-	"github.com/google/uuid"
 )
 
 // MessageQueueScaler implements the same pattern as actions-runner-controller AutoscalingListener
-// It polls GitHub's Actions Service message queue for job events and scales EC2 instances accordingly
+// It polls GitHub's Actions Service message queue for job events and scales runners on its RunnerProvider accordingly
 type MessageQueueScaler struct {
 	config        *Config
-	ec2Client     *ec2.Client
+	provider      RunnerProvider
 	actionsClient *ActionsServiceClient
+	metrics       *MetricsPublisher
+	deadman       *DeadmanMonitor
+	starvation    *StarvationMonitor
+	jobPolicy     *JobPolicy
+	runnerStore   *RunnerStore
+	sessionStore  *SessionStore
+	tracer        *Tracer
 	logger        logr.Logger
 
-	// Scale set and session management (like AutoscalingListener)
-	scaleSet      *RunnerScaleSet
-	session       *RunnerScaleSetSession
-	lastMessageID int64
+	// Scale set and session management (like AutoscalingListener), guarded by mu since
+	// resetConnection swaps them while admin reconciles may be launching runners
+	scaleSet         *RunnerScaleSet
+	session          *RunnerScaleSetSession
+	sessionCreatedAt time.Time
+	lastMessageID    int64
+
+	// Most recent statistics reported by the Actions Service (guarded by mu)
+	lastStatistics *RunnerScaleSetStatistic
+	history        *StatisticsHistory
+	statsStore     *StatisticsStore
+	decisionStore  *DecisionStore
+	jobLatency     *JobLatencyTracker
+
+	// Latest MaxRunners right-sizing recommendation (guarded by mu)
+	maxRunnersRecommendation *MaxRunnersRecommendation
+
+	// Liveness tracking: unix nanoseconds of the last completed polling loop iteration
+	lastHeartbeat atomic.Int64
+	// Set from a crash until the restarted loop heartbeats again
+	restarting atomic.Bool
+	// Set while another replica is the leader and this one waits to take over
+	standby   atomic.Bool
+	startedAt time.Time
+
+	// Adaptive polling state, only touched by the polling loop
+	lastActivity time.Time
+	pollingIdle  bool
+
+	// Scale-down dampening, only touched by the loop making scaling decisions
+	scaleDown *ScaleDownPolicy
+
+	// Runner limits, seeded from the config and updated by the AppConfig scaling policy,
+	// with the active time window's max and the control item's overrides applied on top
+	limitsMu      sync.RWMutex
+	minRunners    int
+	maxRunners    int
+	limitWindow   *LimitWindow
+	controlLimits *ScalingPolicy
+
+	// Set through the admin API: paused stops scaling actions, and a reconcile request
+	// makes the polling loop recalculate the desired runners
+	paused            atomic.Bool
+	reconcileRequests chan struct{}
+
+	// workflow_job webhooks handed from the HTTP server to the webhook loop
+	webhookEvents chan workflowJobEvent
 
 	// Runner tracking
 	runnerTracker *EC2RunnerTracker
@@ -48,13 +100,17 @@ type EC2RunnerInstance struct {
 	State        string    `json:"state"` // "pending", "running", "terminating"
 	JobID        int64     `json:"jobId,omitempty"`
 	RunnerID     int64     `json:"runnerId,omitempty"`
+	RunnerName   string    `json:"runnerName,omitempty"`
 	Labels       []string  `json:"labels"`
 	LastActivity time.Time `json:"lastActivity"`
+	// traceparent is the W3C trace context of the create-runner span, which the runner's
+	// bootstrap span is recorded under once it registers
+	traceparent string
 }
 
 // NewMessageQueueScaler creates a new message queue-based scaler
-func NewMessageQueueScaler(config *Config, ec2Client *ec2.Client, logger logr.Logger) *MessageQueueScaler {
-	actionsClient := NewActionsServiceClient(config.GitHubEnterpriseURL, config.GitHubToken, logger.WithName("actions-client"))
+func NewMessageQueueScaler(config *Config, provider RunnerProvider, metrics *MetricsPublisher, deadman *DeadmanMonitor, starvation *StarvationMonitor, runnerStore *RunnerStore, sessionStore *SessionStore, statsStore *StatisticsStore, decisionStore *DecisionStore, tracer *Tracer, logger logr.Logger) *MessageQueueScaler {
+	actionsClient := NewActionsServiceClient(config.GitHubEnterpriseURL, config.gitHubToken, tracer, logger.WithName("actions-client"))
 
 	tracker := &EC2RunnerTracker{
 		instances: make(map[string]*EC2RunnerInstance),
@@ -63,10 +119,28 @@ func NewMessageQueueScaler(config *Config, ec2Client *ec2.Client, logger logr.Lo
 
 	return &MessageQueueScaler{
 		config:        config,
-		ec2Client:     ec2Client,
+		provider:      provider,
 		actionsClient: actionsClient,
+		metrics:       metrics,
+		deadman:       deadman,
+		starvation:    starvation,
+		jobPolicy:     NewJobPolicy(config),
+		runnerStore:   runnerStore,
+		sessionStore:  sessionStore,
+		tracer:        tracer,
+		history:       NewStatisticsHistory(config.StatsHistorySize),
+		statsStore:    statsStore,
+		decisionStore: decisionStore,
+		jobLatency:    NewJobLatencyTracker(),
+		scaleDown:     NewScaleDownPolicy(config),
+		startedAt:     time.Now(),
+		minRunners:    config.MinRunners,
+		maxRunners:    config.MaxRunners,
 		logger:        logger.WithName("message-queue-scaler"),
 		runnerTracker: tracker,
+
+		reconcileRequests: make(chan struct{}, 1),
+		webhookEvents:     make(chan workflowJobEvent, 100),
 	}
 }
 
@@ -74,19 +148,36 @@ func NewMessageQueueScaler(config *Config, ec2Client *ec2.Client, logger logr.Lo
 func (s *MessageQueueScaler) Run(ctx context.Context) error {
 	s.logger.Info("Starting Message Queue Scaler")
 
+	// Webhook mode needs neither the Actions Service nor a scale set
+	if s.config.ScalingSource == scalingSourceWebhook {
+		return s.runWebhookReceiver(ctx)
+	}
+
 	// Initialize Actions Service connection (like actions-runner-controller)
 	if err := s.initializeActionsService(ctx); err != nil {
 		return fmt.Errorf("failed to initialize Actions Service: %w", err)
 	}
 
+	// Older GHES releases have no scale set message queue to listen on
+	if client, _ := s.connection(); client.Capabilities().RESTScanningRequired() {
+		return s.runRESTScanning(ctx)
+	}
+
 	// Initialize or get existing runner scale set
 	if err := s.initializeScaleSet(ctx); err != nil {
 		return fmt.Errorf("failed to initialize scale set: %w", err)
 	}
 
-	// Create message session (like AutoscalingListener.createSession)
-	if err := s.createMessageSession(ctx); err != nil {
-		return fmt.Errorf("failed to create message session: %w", err)
+	// Adopt the runners a previous process was tracking before scaling decisions are made
+	if err := s.recoverRunners(ctx); err != nil {
+		s.logger.Error(err, "Failed to recover runner state")
+	}
+
+	// Resume the previous process's session, or create one (like AutoscalingListener.createSession)
+	if !s.resumeMessageSession(ctx) {
+		if err := s.createMessageSession(ctx); err != nil {
+			return fmt.Errorf("failed to create message session: %w", err)
+		}
 	}
 	defer s.cleanupSession(ctx)
 
@@ -98,12 +189,13 @@ func (s *MessageQueueScaler) Run(ctx context.Context) error {
 func (s *MessageQueueScaler) initializeActionsService(ctx context.Context) error {
 	s.logger.Info("Initializing Actions Service connection")
 
-	if err := s.actionsClient.Initialize(ctx, s.config.OrganizationName); err != nil {
+	client, _ := s.connection()
+	if err := client.Initialize(ctx, s.config.OrganizationName); err != nil {
 		return fmt.Errorf("failed to initialize Actions Service client: %w", err)
 	}
 
 	s.logger.Info("Actions Service connection established",
-		"actionsServiceURL", s.actionsClient.actionsServiceURL)
+		"actionsServiceURL", client.service.ServiceURL())
 
 	return nil
 }
@@ -112,12 +204,15 @@ func (s *MessageQueueScaler) initializeActionsService(ctx context.Context) error
 func (s *MessageQueueScaler) initializeScaleSet(ctx context.Context) error {
 	s.logger.Info("Initializing runner scale set", "name", s.config.RunnerScaleSetName)
 
-	scaleSet, err := s.actionsClient.GetOrCreateRunnerScaleSet(ctx, s.config.RunnerScaleSetName, s.config.RunnerLabels, s.config.RunnerGroupID)
+	client, _ := s.connection()
+	scaleSet, err := client.GetOrCreateRunnerScaleSet(ctx, s.config.RunnerScaleSetName, literalLabels(s.config.RunnerLabels), s.config.RunnerGroupID)
 	if err != nil {
 		return fmt.Errorf("failed to get or create scale set: %w", err)
 	}
 
+	s.mu.Lock()
 	s.scaleSet = scaleSet
+	s.mu.Unlock()
 	s.config.RunnerScaleSetID = scaleSet.ID
 
 	s.logger.Info("Scale set initialized",
@@ -144,7 +239,8 @@ func (s *MessageQueueScaler) createMessageSession(ctx context.Context) error {
 
 	s.logger.Info("Creating message session", "owner", uniqueOwner)
 
-	session, err := s.actionsClient.CreateMessageSession(ctx, s.config.RunnerScaleSetID, uniqueOwner)
+	client, _ := s.connection()
+	session, err := client.CreateMessageSession(ctx, s.config.RunnerScaleSetID, uniqueOwner)
 	if err != nil {
 		// Check if it's a session conflict error
 		if strings.Contains(err.Error(), "already has an active session") {
@@ -156,7 +252,7 @@ func (s *MessageQueueScaler) createMessageSession(ctx context.Context) error {
 			uniqueOwner = fmt.Sprintf("ghaec2-%s", hex.EncodeToString(randomBytes))
 			
 			s.logger.Info("Retrying with different owner", "owner", uniqueOwner)
-			session, err = s.actionsClient.CreateMessageSession(ctx, s.config.RunnerScaleSetID, uniqueOwner)
+			session, err = client.CreateMessageSession(ctx, s.config.RunnerScaleSetID, uniqueOwner)
 			if err != nil {
 				return fmt.Errorf("failed to create message session after retry: %w", err)
 			}
@@ -165,8 +261,8 @@ func (s *MessageQueueScaler) createMessageSession(ctx context.Context) error {
 		}
 	}
 
-	s.session = session
-	s.lastMessageID = 0
+	s.setSession(session, time.Now(), 0)
+	s.saveSessionState(ctx)
 
 	s.logger.Info("Message session created",
 		"sessionId", session.SessionID,
@@ -179,25 +275,27 @@ func (s *MessageQueueScaler) createMessageSession(ctx context.Context) error {
 // startMessagePolling starts the message polling loop (exactly like Listener.Listen)
 func (s *MessageQueueScaler) startMessagePolling(ctx context.Context) error {
 	// Handle initial message with statistics (exactly like Listener.Listen does)
+	_, session := s.connection()
 	initialMessage := &RunnerScaleSetMessage{
 		MessageID:   0,
 		MessageType: "RunnerScaleSetJobMessages",
-		Statistics:  s.session.Statistics,
+		Statistics:  session.Statistics,
 		Body:        "",
 	}
 
-	if s.session.Statistics == nil {
+	if session.Statistics == nil {
 		return fmt.Errorf("session statistics is nil")
 	}
 
 	s.logger.Info("Initial runner scale set statistics",
-		"availableJobs", s.session.Statistics.TotalAvailableJobs,
-		"assignedJobs", s.session.Statistics.TotalAssignedJobs,
-		"runningJobs", s.session.Statistics.TotalRunningJobs,
-		"registeredRunners", s.session.Statistics.TotalRegisteredRunners,
-		"busyRunners", s.session.Statistics.TotalBusyRunners,
-		"idleRunners", s.session.Statistics.TotalIdleRunners,
+		"availableJobs", session.Statistics.TotalAvailableJobs,
+		"assignedJobs", session.Statistics.TotalAssignedJobs,
+		"runningJobs", session.Statistics.TotalRunningJobs,
+		"registeredRunners", session.Statistics.TotalRegisteredRunners,
+		"busyRunners", session.Statistics.TotalBusyRunners,
+		"idleRunners", session.Statistics.TotalIdleRunners,
 	)
+	s.metrics.RecordStatistics(session.Statistics)
 
 	// Handle initial desired runner count (like Listener.Listen)
 	desiredRunners, err := s.handleDesiredRunnerCount(ctx, initialMessage.Statistics.TotalAssignedJobs, 0)
@@ -207,17 +305,22 @@ func (s *MessageQueueScaler) startMessagePolling(ctx context.Context) error {
 	s.logger.Info("Initial desired runners calculated", "desiredRunners", desiredRunners)
 
 	// Start the message polling loop (exactly like Listener.Listen)
-	s.logger.Info("Starting message polling loop")
+	s.logger.Info("Starting message polling loop",
+		"pollInterval", s.config.PollInterval,
+		"adaptivePolling", s.config.AdaptivePolling)
+	s.lastActivity = time.Now()
 
 	// Add a ticker for more frequent polling when no messages are received
-	ticker := time.NewTicker(30 * time.Second)
+	ticker := time.NewTicker(s.config.PollCheckInterval)
 	defer ticker.Stop()
 
 	// Add a diagnostic ticker to periodically check for issues
-	diagnosticTicker := time.NewTicker(2 * time.Minute)
+	diagnosticTicker := time.NewTicker(s.config.DiagnosticsInterval)
 	defer diagnosticTicker.Stop()
 
 	for {
+		s.heartbeat()
+
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
@@ -229,16 +332,20 @@ func (s *MessageQueueScaler) startMessagePolling(ctx context.Context) error {
 			if err := s.runDiagnostics(ctx); err != nil {
 				s.logger.Error(err, "Diagnostics failed")
 			}
+		case <-s.reconcileRequests:
+			s.reconcile(ctx)
 		default:
 		}
 
 		// Get next message (like Listener.getMessage)
 		msg, err := s.getMessage(ctx)
 		if err != nil {
-			s.logger.Error(err, "Failed to get message, will retry in 5 seconds")
-			time.Sleep(5 * time.Second)
+			s.logger.Error(err, "Failed to get message, will retry", "backoff", s.config.PollErrorBackoff)
+			s.metrics.Count(metricErrors, 1)
+			sleepContext(ctx, s.config.PollErrorBackoff)
 			continue
 		}
+		s.metrics.Count(metricSuccessfulPolls, 1)
 
 		if msg == nil {
 			// No new messages - handle as null message (like Listener.Listen)
@@ -246,9 +353,10 @@ func (s *MessageQueueScaler) startMessagePolling(ctx context.Context) error {
 			_, err := s.handleDesiredRunnerCount(ctx, 0, 0)
 			if err != nil {
 				s.logger.Error(err, "Failed to handle null message")
+				s.metrics.Count(metricErrors, 1)
 				continue
 			}
-			time.Sleep(5 * time.Second) // Wait before next poll
+			sleepContext(ctx, s.nextPollDelay()) // Wait before next poll
 			continue
 		}
 
@@ -257,25 +365,50 @@ func (s *MessageQueueScaler) startMessagePolling(ctx context.Context) error {
 			"messageType", msg.MessageType,
 			"bodyLength", len(msg.Body),
 			"hasStatistics", msg.Statistics != nil)
+		s.recordActivity(msg.Statistics)
 
 		// Handle the message (like Listener.handleMessage)
 		// Use context.WithoutCancel to avoid cancelling message handling
-		if err := s.handleMessage(context.WithoutCancel(ctx), msg); err != nil {
+		receivedAt := time.Now()
+		msgCtx, span := s.tracer.Start(context.WithoutCancel(ctx), "handle-message",
+			"messageId", msg.MessageID, "messageType", msg.MessageType)
+		err = s.handleMessage(msgCtx, msg)
+		span.End(err)
+		s.metrics.Duration(metricMessageLatency, time.Since(receivedAt), s.config.metricsPool())
+		if err != nil {
 			s.logger.Error(err, "Failed to handle message, will continue polling")
+			s.metrics.Count(metricErrors, 1)
 			continue
 		}
+		s.deadman.RecordMessageProcessed()
 	}
 }
 
+// heartbeat records that the polling loop is alive. The matching alarm fires when
+// heartbeats stop, which catches a loop wedged on a hung call rather than a crashed process.
+func (s *MessageQueueScaler) heartbeat() {
+	s.lastHeartbeat.Store(time.Now().UnixNano())
+	s.restarting.Store(false)
+	s.metrics.Count(metricHeartbeat, 1)
+}
+
+// LastHeartbeat returns the time of the last polling loop iteration
+func (s *MessageQueueScaler) LastHeartbeat() time.Time {
+	return time.Unix(0, s.lastHeartbeat.Load())
+}
+
 // getMessage gets the next message from the queue (like Listener.getMessage)
 func (s *MessageQueueScaler) getMessage(ctx context.Context) (*RunnerScaleSetMessage, error) {
-	s.logger.V(1).Info("Getting next message", "lastMessageID", s.lastMessageID)
+	lastMessageID := s.messageCursor()
+	s.logger.V(1).Info("Getting next message", "lastMessageID", lastMessageID)
 
-	msg, err := s.actionsClient.GetMessage(ctx,
-		s.session.MessageQueueURL,
-		s.session.MessageQueueAccessToken,
-		s.lastMessageID,
-		s.config.MaxRunners)
+	_, maxRunners := s.scalingLimits()
+	client, session := s.connection()
+	msg, err := client.GetMessage(ctx,
+		session.MessageQueueURL,
+		session.MessageQueueAccessToken,
+		lastMessageID,
+		maxRunners)
 
 	if err == nil {
 		return msg, nil
@@ -288,11 +421,12 @@ func (s *MessageQueueScaler) getMessage(ctx context.Context) (*RunnerScaleSetMes
 		}
 
 		// Retry after session refresh
-		msg, err = s.actionsClient.GetMessage(ctx,
-			s.session.MessageQueueURL,
-			s.session.MessageQueueAccessToken,
-			s.lastMessageID,
-			s.config.MaxRunners)
+		client, session = s.connection()
+		msg, err = client.GetMessage(ctx,
+			session.MessageQueueURL,
+			session.MessageQueueAccessToken,
+			lastMessageID,
+			maxRunners)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get next message after session refresh: %w", err)
 		}
@@ -311,16 +445,15 @@ func (s *MessageQueueScaler) handleMessage(ctx context.Context, msg *RunnerScale
 	}
 
 	// Handle available jobs (like Listener.handleMessage)
-	if len(parsedMsg.jobsAvailable) > 0 {
-		acquiredJobIDs, err := s.acquireAvailableJobs(ctx, parsedMsg.jobsAvailable)
-		if err != nil {
-			return fmt.Errorf("failed to acquire jobs: %w", err)
-		}
-		s.logger.Info("Jobs acquired", "count", len(acquiredJobIDs), "requestIds", acquiredJobIDs)
+	if err := s.handleJobsAvailable(ctx, parsedMsg.jobsAvailable); err != nil {
+		return err
 	}
 
 	// Update last message ID
+	s.mu.Lock()
 	s.lastMessageID = msg.MessageID
+	s.mu.Unlock()
+	s.saveSessionState(ctx)
 
 	// Delete the processed message
 	if err := s.deleteLastMessage(ctx); err != nil {
@@ -334,6 +467,11 @@ func (s *MessageQueueScaler) handleMessage(ctx context.Context, msg *RunnerScale
 		}
 	}
 
+	// Handle runner lifecycle events
+	for _, event := range parsedMsg.runnerEvents {
+		s.handleRunnerLifecycle(ctx, event)
+	}
+
 	// Handle desired runner count based on statistics
 	desiredRunners, err := s.handleDesiredRunnerCount(ctx, parsedMsg.statistics.TotalAssignedJobs, len(parsedMsg.jobsCompleted))
 	if err != nil {
@@ -344,12 +482,36 @@ func (s *MessageQueueScaler) handleMessage(ctx context.Context, msg *RunnerScale
 	return nil
 }
 
+// handleJobsAvailable acquires the available jobs that pass the job policy, longest-waiting
+// first and within the capacity left below MaxRunners
+func (s *MessageQueueScaler) handleJobsAvailable(ctx context.Context, jobsAvailable []*JobAvailable) error {
+	sortOldestFirst(jobsAvailable)
+	s.metrics.Gauge(metricMaxJobWaitSeconds, maxWaitTime(jobsAvailable, time.Now()).Seconds())
+	jobsToAcquire := s.applyBackPressure(ctx, s.allowedJobs(jobsAvailable))
+	if s.paused.Load() && len(jobsToAcquire) > 0 {
+		// Jobs left unacquired stay available to other scale sets while paused
+		s.logger.Info("Scaling is paused, not acquiring jobs", "jobsAvailable", len(jobsToAcquire))
+		return nil
+	}
+	if len(jobsToAcquire) == 0 {
+		return nil
+	}
+
+	acquiredJobIDs, err := s.acquireAvailableJobs(ctx, jobsToAcquire)
+	if err != nil {
+		return fmt.Errorf("failed to acquire jobs: %w", err)
+	}
+	s.logger.Info("Jobs acquired", "count", len(acquiredJobIDs), "requestIds", acquiredJobIDs)
+	return nil
+}
+
 // parsedMessage holds parsed message components (like Listener.parsedMessage)
 type parsedMessage struct {
 	statistics    *RunnerScaleSetStatistic
 	jobsStarted   []*JobStarted
 	jobsAvailable []*JobAvailable
 	jobsCompleted []*JobCompleted
+	runnerEvents  []*RunnerLifecycle
 }
 
 // Job message types (following actions-runner-controller patterns)
@@ -369,6 +531,23 @@ type JobCompleted struct {
 	JobMessageBase
 }
 
+// Runner lifecycle message types. The Actions Service uses several names for the same transition
+// depending on server version, so each group maps to a single tracker update.
+const (
+	msgRunnerRegistered = "RunnerRegistered"
+	msgRunnerAdded      = "RunnerAdded"
+	msgRunnerRemoved    = "RunnerRemoved"
+	msgRunnerDeleted    = "RunnerDeleted"
+	msgRunnerOffline    = "RunnerOffline"
+)
+
+// RunnerLifecycle represents a runner registered, removed or offline message
+type RunnerLifecycle struct {
+	MessageType string `json:"messageType"`
+	RunnerID    int    `json:"runnerId"`
+	RunnerName  string `json:"runnerName"`
+}
+
 // parseMessage parses a message (like Listener.parseMessage)
 func (s *MessageQueueScaler) parseMessage(ctx context.Context, msg *RunnerScaleSetMessage) (*parsedMessage, error) {
 	if msg.MessageType != "RunnerScaleSetJobMessages" {
@@ -390,6 +569,11 @@ func (s *MessageQueueScaler) parseMessage(ctx context.Context, msg *RunnerScaleS
 		"busyRunners", msg.Statistics.TotalBusyRunners,
 		"idleRunners", msg.Statistics.TotalIdleRunners,
 	)
+	s.metrics.RecordStatistics(msg.Statistics)
+
+	s.mu.Lock()
+	s.lastStatistics = msg.Statistics
+	s.mu.Unlock()
 
 	// Parse batched messages in the body
 	var batchedMessages []json.RawMessage
@@ -430,6 +614,7 @@ func (s *MessageQueueScaler) parseMessage(ctx context.Context, msg *RunnerScaleS
 					"ownerName", jobAvailable.OwnerName,
 					"requestLabels", jobAvailable.RequestLabels)
 				parsedMsg.jobsAvailable = append(parsedMsg.jobsAvailable, &jobAvailable)
+				s.jobLatency.JobQueued(jobAvailable.RunnerRequestID, jobAvailable.QueueTime)
 			} else {
 				s.logger.Error(err, "Failed to unmarshal JobAvailable message", "rawMessage", string(rawMsg))
 			}
@@ -452,9 +637,21 @@ func (s *MessageQueueScaler) parseMessage(ctx context.Context, msg *RunnerScaleS
 					"runnerId", jobCompleted.RunnerID,
 					"result", jobCompleted.Result)
 				parsedMsg.jobsCompleted = append(parsedMsg.jobsCompleted, &jobCompleted)
+				s.jobLatency.JobDropped(jobCompleted.RunnerRequestID)
 			} else {
 				s.logger.Error(err, "Failed to unmarshal JobCompleted message", "rawMessage", string(rawMsg))
 			}
+		case msgRunnerRegistered, msgRunnerAdded, msgRunnerRemoved, msgRunnerDeleted, msgRunnerOffline:
+			var runnerEvent RunnerLifecycle
+			if err := json.Unmarshal(rawMsg, &runnerEvent); err == nil {
+				s.logger.Info("Found runner lifecycle message",
+					"messageType", runnerEvent.MessageType,
+					"runnerId", runnerEvent.RunnerID,
+					"runnerName", runnerEvent.RunnerName)
+				parsedMsg.runnerEvents = append(parsedMsg.runnerEvents, &runnerEvent)
+			} else {
+				s.logger.Error(err, "Failed to unmarshal runner lifecycle message", "rawMessage", string(rawMsg))
+			}
 		default:
 			s.logger.Info("Unknown message type in batch", "messageType", msgType.MessageType, "rawMessage", string(rawMsg))
 		}
@@ -463,13 +660,79 @@ func (s *MessageQueueScaler) parseMessage(ctx context.Context, msg *RunnerScaleS
 	s.logger.Info("Parsed message",
 		"jobsAvailable", len(parsedMsg.jobsAvailable),
 		"jobsStarted", len(parsedMsg.jobsStarted),
-		"jobsCompleted", len(parsedMsg.jobsCompleted))
+		"jobsCompleted", len(parsedMsg.jobsCompleted),
+		"runnerEvents", len(parsedMsg.runnerEvents))
 
 	return parsedMsg, nil
 }
 
+// allowedJobs returns the available jobs that pass the job policy, whatever the acquisition mode.
+// Jobs that fail the policy are left in the queue for other scale sets.
+func (s *MessageQueueScaler) allowedJobs(jobsAvailable []*JobAvailable) []*JobAvailable {
+	var allowed []*JobAvailable
+	for _, job := range jobsAvailable {
+		if ok, reason := s.jobPolicy.Allows(job); !ok {
+			s.logger.Info("Skipping job rejected by policy",
+				"runnerRequestId", job.RunnerRequestID,
+				"repositoryName", job.RepositoryName,
+				"ownerName", job.OwnerName,
+				"requestLabels", job.RequestLabels,
+				"reason", reason)
+			s.metrics.Count(metricJobsSkipped, 1)
+			s.jobLatency.JobDropped(job.RunnerRequestID)
+			continue
+		}
+		allowed = append(allowed, job)
+	}
+	return allowed
+}
+
+// applyBackPressure limits the available jobs to the capacity left below MaxRunners.
+// Jobs beyond that are not acquired, so they stay available to other scale sets or a later cycle
+// instead of sitting assigned to us with no runner to serve them.
+func (s *MessageQueueScaler) applyBackPressure(ctx context.Context, jobsAvailable []*JobAvailable) []*JobAvailable {
+	if len(jobsAvailable) == 0 {
+		return nil
+	}
+
+	currentRunners, err := s.getCurrentRunnerCount(ctx)
+	if err != nil {
+		s.logger.Error(err, "Failed to get current runner count, acquiring without back-pressure")
+		return jobsAvailable
+	}
+
+	_, maxRunners := s.scalingLimits()
+	capacity := maxRunners - currentRunners
+	if capacity >= len(jobsAvailable) {
+		return jobsAvailable
+	}
+	if capacity < 0 {
+		capacity = 0
+	}
+
+	s.logger.Info("At max capacity, leaving jobs for other scale sets",
+		"currentRunners", currentRunners,
+		"maxRunners", maxRunners,
+		"jobsAvailable", len(jobsAvailable),
+		"jobsAcquiring", capacity)
+	s.metrics.Count(metricJobsDeferred, float64(len(jobsAvailable)-capacity))
+
+	// Share the remaining capacity across repositories rather than serving jobs in message order
+	return fairOrder(jobsAvailable)[:capacity]
+}
+
+// Retry policy for jobs that AcquireJobs did not hand back to us
+const (
+	acquireRetryAttempts  = 3
+	acquireRetryBaseDelay = 1 * time.Second
+)
+
 // acquireAvailableJobs acquires available jobs (like Listener.acquireAvailableJobs)
 func (s *MessageQueueScaler) acquireAvailableJobs(ctx context.Context, jobsAvailable []*JobAvailable) ([]int64, error) {
+	if s.config.JobAcquisitionMode == acquisitionModePerJob {
+		return s.acquireJobsIndividually(ctx, jobsAvailable)
+	}
+
 	ids := make([]int64, 0, len(jobsAvailable))
 	for _, job := range jobsAvailable {
 		ids = append(ids, job.RunnerRequestID)
@@ -477,7 +740,114 @@ func (s *MessageQueueScaler) acquireAvailableJobs(ctx context.Context, jobsAvail
 
 	s.logger.Info("Acquiring jobs", "count", len(ids), "requestIds", ids)
 
-	idsAcquired, err := s.actionsClient.AcquireJobs(ctx, s.config.RunnerScaleSetID, s.actionsClient.adminToken, ids)
+	idsAcquired, err := s.acquireJobs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	// AcquireJobs may return fewer IDs than requested, e.g. when another scale set won the race
+	missing := missingJobIDs(ids, idsAcquired)
+	for attempt := 1; len(missing) > 0 && attempt <= acquireRetryAttempts; attempt++ {
+		delay := acquireRetryBaseDelay * time.Duration(1<<(attempt-1))
+		s.logger.Info("Some jobs were not acquired, retrying",
+			"missingRequestIds", missing,
+			"attempt", attempt,
+			"delay", delay)
+		sleepContext(ctx, delay)
+		if ctx.Err() != nil {
+			s.metrics.Count(metricJobsAcquired, float64(len(idsAcquired)))
+			return idsAcquired, ctx.Err()
+		}
+
+		retried, err := s.acquireJobs(ctx, missing)
+		if err != nil {
+			s.logger.Error(err, "Failed to retry acquiring jobs", "attempt", attempt)
+			continue
+		}
+
+		idsAcquired = append(idsAcquired, retried...)
+		missing = missingJobIDs(missing, retried)
+	}
+
+	if len(missing) > 0 {
+		s.logger.Info("Jobs could not be acquired, likely taken by another scaler",
+			"requestIds", missing,
+			"requested", len(ids),
+			"acquired", len(idsAcquired))
+		s.metrics.Count(metricJobsLost, float64(len(missing)))
+	}
+	s.metrics.Count(metricJobsAcquired, float64(len(idsAcquired)))
+
+	return idsAcquired, nil
+}
+
+// acquireJobsIndividually acquires jobs one request per job, through their acquireJobUrl
+func (s *MessageQueueScaler) acquireJobsIndividually(ctx context.Context, jobsAvailable []*JobAvailable) ([]int64, error) {
+	var idsAcquired []int64
+	var batch []*JobAvailable
+
+	for _, job := range jobsAvailable {
+		// Older servers do not send an acquire URL; fall back to the batch API for those jobs
+		if job.AcquireJobURL == "" {
+			batch = append(batch, job)
+			continue
+		}
+
+		acquired, err := s.acquireJob(ctx, job)
+		if err != nil {
+			s.logger.Error(err, "Failed to acquire job", "runnerRequestId", job.RunnerRequestID)
+			s.metrics.Count(metricErrors, 1)
+			continue
+		}
+		if !acquired {
+			s.logger.Info("Job no longer available, likely taken by another scaler", "runnerRequestId", job.RunnerRequestID)
+			s.metrics.Count(metricJobsLost, 1)
+			s.jobLatency.JobDropped(job.RunnerRequestID)
+			continue
+		}
+
+		s.metrics.Count(metricJobsAcquired, 1)
+		idsAcquired = append(idsAcquired, job.RunnerRequestID)
+	}
+
+	if len(batch) > 0 {
+		s.logger.Info("Acquiring jobs without an acquire URL in batch", "count", len(batch))
+		ids := make([]int64, 0, len(batch))
+		for _, job := range batch {
+			ids = append(ids, job.RunnerRequestID)
+		}
+
+		batchAcquired, err := s.acquireJobs(ctx, ids)
+		if err != nil {
+			return idsAcquired, err
+		}
+		s.metrics.Count(metricJobsAcquired, float64(len(batchAcquired)))
+		idsAcquired = append(idsAcquired, batchAcquired...)
+	}
+
+	return idsAcquired, nil
+}
+
+// acquireJob acquires a single job through its acquireJobUrl, refreshing the session once if the token has expired
+func (s *MessageQueueScaler) acquireJob(ctx context.Context, job *JobAvailable) (bool, error) {
+	client, session := s.connection()
+	acquired, err := client.AcquireJob(ctx, job.AcquireJobURL, session.MessageQueueAccessToken)
+	if err == nil || !isMessageQueueTokenExpiredError(err) {
+		return acquired, err
+	}
+
+	if err := s.refreshSession(ctx); err != nil {
+		return false, err
+	}
+
+	client, session = s.connection()
+	return client.AcquireJob(ctx, job.AcquireJobURL, session.MessageQueueAccessToken)
+}
+
+// acquireJobs calls AcquireJobs, refreshing the session once if the token has expired
+func (s *MessageQueueScaler) acquireJobs(ctx context.Context, ids []int64) ([]int64, error) {
+	client, session := s.connection()
+	idsAcquired, err := client.AcquireJobs(ctx, s.config.RunnerScaleSetID, session.MessageQueueAccessToken, ids)
 	if err == nil {
 		return idsAcquired, nil
 	}
@@ -488,7 +858,8 @@ func (s *MessageQueueScaler) acquireAvailableJobs(ctx context.Context, jobsAvail
 			return nil, err
 		}
 
-		idsAcquired, err = s.actionsClient.AcquireJobs(ctx, s.config.RunnerScaleSetID, s.session.MessageQueueAccessToken, ids)
+		client, session := s.connection()
+		idsAcquired, err = client.AcquireJobs(ctx, s.config.RunnerScaleSetID, session.MessageQueueAccessToken, ids)
 		if err != nil {
 			return nil, fmt.Errorf("failed to acquire jobs after session refresh: %w", err)
 		}
@@ -499,6 +870,22 @@ func (s *MessageQueueScaler) acquireAvailableJobs(ctx context.Context, jobsAvail
 	return idsAcquired, nil
 }
 
+// missingJobIDs returns the requested IDs that are not in the acquired list
+func missingJobIDs(requested, acquired []int64) []int64 {
+	acquiredSet := make(map[int64]struct{}, len(acquired))
+	for _, id := range acquired {
+		acquiredSet[id] = struct{}{}
+	}
+
+	var missing []int64
+	for _, id := range requested {
+		if _, ok := acquiredSet[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	return missing
+}
+
 // handleJobStarted handles a job started event
 func (s *MessageQueueScaler) handleJobStarted(ctx context.Context, jobInfo *JobStarted) error {
 	s.logger.Info("Job started",
@@ -507,6 +894,17 @@ func (s *MessageQueueScaler) handleJobStarted(ctx context.Context, jobInfo *JobS
 		"repository", jobInfo.RepositoryName,
 		"workflowRef", jobInfo.JobWorkflowRef)
 
+	startedAt := jobInfo.RunnerAssignTime
+	if startedAt.IsZero() {
+		startedAt = time.Now()
+	}
+	if latency, ok := s.jobLatency.JobStarted(jobInfo.RunnerRequestID, jobInfo.QueueTime, startedAt); ok {
+		s.logger.Info("Job start latency", "runnerRequestId", jobInfo.RunnerRequestID, "latency", latency.Round(time.Second))
+		s.metrics.Duration(metricJobStartLatency, latency, s.config.metricsPool())
+		s.tracer.Record(ctx, "job-queued", startedAt.Add(-latency), startedAt,
+			"runnerRequestId", jobInfo.RunnerRequestID, "repository", jobInfo.RepositoryName, "runnerName", jobInfo.RunnerName)
+	}
+
 	// Update our tracking
 	s.runnerTracker.mu.Lock()
 	for _, instance := range s.runnerTracker.instances {
@@ -521,97 +919,295 @@ func (s *MessageQueueScaler) handleJobStarted(ctx context.Context, jobInfo *JobS
 	return nil
 }
 
+// handleRunnerLifecycle updates the tracker and the runner table for a runner lifecycle event
+func (s *MessageQueueScaler) handleRunnerLifecycle(ctx context.Context, event *RunnerLifecycle) {
+	var status string
+
+	s.runnerTracker.mu.Lock()
+	instance := s.runnerTracker.findByRunner(event.RunnerID, event.RunnerName)
+	instanceID := ""
+	if instance != nil {
+		instanceID = instance.InstanceID
+	}
+
+	switch event.MessageType {
+	case msgRunnerRegistered, msgRunnerAdded:
+		status = runnerStatusRunning
+		if instance != nil {
+			if instance.State == "pending" {
+				s.metrics.Duration(metricInstanceLaunchLatency, time.Since(instance.LaunchTime), s.config.metricsPool())
+				s.tracer.Record(s.tracer.Extract(ctx, instance.traceparent), "runner-bootstrap", instance.LaunchTime, time.Now(),
+					"instanceId", instance.InstanceID, "runnerName", event.RunnerName)
+			}
+			instance.RunnerID = int64(event.RunnerID)
+			instance.RunnerName = event.RunnerName
+			instance.State = "running"
+			instance.LastActivity = time.Now()
+		}
+	case msgRunnerOffline:
+		status = runnerStatusOffline
+		if instance != nil {
+			instance.State = "offline"
+		}
+	case msgRunnerRemoved, msgRunnerDeleted:
+		status = runnerStatusRemoved
+		if instance != nil {
+			delete(s.runnerTracker.instances, instance.InstanceID)
+		}
+	}
+	s.runnerTracker.mu.Unlock()
+
+	s.logger.Info("Runner lifecycle event",
+		"messageType", event.MessageType,
+		"runnerId", event.RunnerID,
+		"runnerName", event.RunnerName,
+		"instanceId", instanceID,
+		"tracked", instance != nil)
+
+	if event.RunnerName == "" {
+		return
+	}
+	if err := s.runnerStore.UpdateStatus(ctx, event.RunnerName, instanceID, status); err != nil {
+		s.logger.Error(err, "Failed to reconcile runner record", "runnerName", event.RunnerName)
+	}
+}
+
+// findByRunner finds the tracked instance for a runner by ID, falling back to the runner name.
+// Runners launched by the scaler are named after their instance ID. The caller must hold t.mu.
+func (t *EC2RunnerTracker) findByRunner(runnerID int, runnerName string) *EC2RunnerInstance {
+	for _, instance := range t.instances {
+		if runnerID != 0 && instance.RunnerID == int64(runnerID) {
+			return instance
+		}
+	}
+	if runnerName == "" {
+		return nil
+	}
+	for _, instance := range t.instances {
+		if instance.RunnerName == runnerName || instance.InstanceID == runnerName {
+			return instance
+		}
+	}
+	return nil
+}
+
 // handleDesiredRunnerCount handles desired runner count calculation (like Handler.HandleDesiredRunnerCount)
-func (s *MessageQueueScaler) handleDesiredRunnerCount(ctx context.Context, assignedJobs, completedJobs int) (int, error) {
+func (s *MessageQueueScaler) handleDesiredRunnerCount(ctx context.Context, assignedJobs, completedJobs int) (desired int, err error) {
+	ctx, span := s.tracer.Start(ctx, "scaling-decision", "assignedJobs", assignedJobs, "completedJobs", completedJobs)
+	defer func() {
+		span.SetAttributes("desiredRunners", desired)
+		span.End(err)
+	}()
+
 	currentRunners, err := s.getCurrentRunnerCount(ctx)
 	if err != nil {
 		return 0, fmt.Errorf("failed to get current runner count: %w", err)
 	}
 
-	// Calculate desired runners based on assigned jobs (following actions-runner-controller logic)
-	desiredRunners := assignedJobs
-
-	// Ensure we stay within min/max bounds
-	if desiredRunners < s.config.MinRunners {
-		desiredRunners = s.config.MinRunners
-	}
-	if desiredRunners > s.config.MaxRunners {
-		desiredRunners = s.config.MaxRunners
+	s.updateLimitWindow(time.Now())
+	minRunners, maxRunners := s.scalingLimits()
+	desiredRunners, reason := desiredRunnerCount(assignedJobs, currentRunners, minRunners, maxRunners)
+	if s.paused.Load() {
+		s.logger.Info("Scaling is paused, keeping current runners",
+			"currentRunners", currentRunners, "desiredRunners", desiredRunners, "reason", reason)
+		s.metrics.Gauge(metricScalingPaused, 1)
+		s.metrics.Gauge(metricCurrentRunners, float64(currentRunners))
+		return currentRunners, nil
 	}
+	s.metrics.Gauge(metricScalingPaused, 0)
 
 	s.logger.Info("Scaling decision",
 		"currentRunners", currentRunners,
 		"assignedJobs", assignedJobs,
 		"completedJobs", completedJobs,
-		"desiredRunners", desiredRunners)
+		"desiredRunners", desiredRunners,
+		"reason", reason)
+	s.metrics.Gauge(metricCurrentRunners, float64(currentRunners))
+	s.metrics.Gauge(metricDesiredRunners, float64(desiredRunners))
+	s.metrics.ScaleDecision(reason)
+
+	decision := ScalingDecision{
+		Timestamp:      time.Now(),
+		AssignedJobs:   assignedJobs,
+		CompletedJobs:  completedJobs,
+		CurrentRunners: currentRunners,
+		MinRunners:     minRunners,
+		MaxRunners:     maxRunners,
+		DesiredRunners: desiredRunners,
+		Reason:         reason,
+	}
 
 	// Scale up if needed
 	if desiredRunners > currentRunners {
 		runnersToCreate := desiredRunners - currentRunners
+		if available, err := s.provider.GetCapacity(ctx); err != nil {
+			s.logger.Error(err, "Failed to get runner provider capacity")
+		} else if available != unlimitedCapacity && runnersToCreate > available {
+			s.logger.Info("Runner provider is short of capacity", "runnersToCreate", runnersToCreate, "available", available)
+			runnersToCreate = available
+		}
+		if limit := s.config.MaxScaleUpPerCycle; limit > 0 && runnersToCreate > limit {
+			s.logger.Info("Limiting scale-up, the rest follows on the next decisions",
+				"runnersToCreate", runnersToCreate, "maxScaleUpPerCycle", limit)
+			span.SetAttributes("scaleUpDeferred", runnersToCreate-limit)
+			runnersToCreate = limit
+		}
 		s.logger.Info("Scaling up", "runnersToCreate", runnersToCreate)
 
-		for i := 0; i < runnersToCreate; i++ {
-			if err := s.createRunner(ctx); err != nil {
-				s.logger.Error(err, "Failed to create runner", "attempt", i+1)
-			}
+		decision.LaunchedInstances = s.createRunners(ctx, runnersToCreate)
+		s.starvation.RecordLaunches(len(decision.LaunchedInstances), runnersToCreate-len(decision.LaunchedInstances))
+		if len(decision.LaunchedInstances) > 0 {
+			s.scaleDown.RecordScaleUp(time.Now())
 		}
 	}
+	s.starvation.Check(ctx, s.jobLatency.OldestQueued(time.Now()), reason == scaleReasonCappedAtMax, maxRunners)
 
-	// Scale down if needed
+	// Scale down if needed, as far as the scale-down policy allows
 	if desiredRunners < currentRunners {
-		runnersToTerminate := currentRunners - desiredRunners
-		s.logger.Info("Scaling down", "runnersToTerminate", runnersToTerminate)
+		runnersToTerminate, held := s.scaleDown.Terminations(time.Now(), currentRunners-desiredRunners)
+		decision.ScaleDownHeld = held
+		if held != "" {
+			s.logger.Info("Holding back scale-down", "excessRunners", currentRunners-desiredRunners,
+				"runnersToTerminate", runnersToTerminate, "held", held)
+			span.SetAttributes("scaleDownHeld", held)
+		}
+
+		if runnersToTerminate > 0 {
+			s.logger.Info("Scaling down", "runnersToTerminate", runnersToTerminate)
 
-		if err := s.terminateIdleRunners(ctx, runnersToTerminate); err != nil {
-			s.logger.Error(err, "Failed to terminate idle runners")
+			terminated, err := s.terminateIdleRunners(ctx, runnersToTerminate)
+			if err != nil {
+				s.logger.Error(err, "Failed to terminate idle runners")
+			}
+			decision.TerminatedInstances = terminated
 		}
+	} else {
+		s.scaleDown.RecordSteady()
+	}
+
+	if err := s.decisionStore.Put(ctx, decision); err != nil {
+		s.logger.Error(err, "Failed to persist scaling decision")
 	}
 
 	return desiredRunners, nil
 }
 
-// getCurrentRunnerCount gets the current number of EC2 runners
+// getCurrentRunnerCount gets the current number of EC2 runners. The tracker is kept in line
+// with EC2 by runRunnerSync.
 func (s *MessageQueueScaler) getCurrentRunnerCount(ctx context.Context) (int, error) {
-	// Implementation to count current EC2 instances with our tags
 	s.runnerTracker.mu.RLock()
 	count := len(s.runnerTracker.instances)
 	s.runnerTracker.mu.RUnlock()
 
-	// TODO: Also query EC2 to get actual current count and sync with tracker
 	return count, nil
 }
 
-// createRunner creates a new EC2 runner instance
-func (s *MessageQueueScaler) createRunner(ctx context.Context) error {
-	s.logger.Info("Creating new EC2 runner instance")
+// createRunners creates count runners in batches of SCALE_UP_BATCH_SIZE concurrent launches,
+// pausing SCALE_UP_BATCH_INTERVAL between batches so a burst does not trip EC2 API
+// throttling, and returns the instance IDs of the runners launched
+func (s *MessageQueueScaler) createRunners(ctx context.Context, count int) []string {
+	var (
+		mu       sync.Mutex
+		launched []string
+	)
+	for start := 0; start < count && ctx.Err() == nil; start += s.config.ScaleUpBatchSize {
+		if start > 0 {
+			sleepContext(ctx, s.config.ScaleUpBatchInterval)
+		}
+		end := min(start+s.config.ScaleUpBatchSize, count)
+
+		var wg sync.WaitGroup
+		for i := start; i < end; i++ {
+			wg.Add(1)
+			go func(attempt int) {
+				defer wg.Done()
+				instanceID, err := s.createRunner(ctx)
+				if err != nil {
+					s.logger.Error(err, "Failed to create runner", "attempt", attempt)
+					return
+				}
+				mu.Lock()
+				launched = append(launched, instanceID)
+				mu.Unlock()
+			}(i + 1)
+		}
+		wg.Wait()
+	}
+	return launched
+}
+
+// createRunner creates a new runner on the provider and returns its instance ID. The runner
+// carries the scaler's own labels and launches from its pool's EC2 settings; a label launch
+// spec runs as a pool of its own, so it never changes how this scaler launches.
+func (s *MessageQueueScaler) createRunner(ctx context.Context) (instanceID string, err error) {
+	s.logger.Info("Creating new runner")
+	ctx, span := s.tracer.Start(ctx, "create-runner")
+	defer func() {
+		span.SetAttributes("instanceId", instanceID)
+		span.End(err)
+	}()
+
+	runnerName := runnername.Format(s.config.RunnerNameTemplate, runnername.Fields{
+		Prefix:   s.config.RunnerNamePrefix,
+		ScaleSet: s.config.RunnerScaleSetName,
+		Pool:     s.config.PoolName,
+	})
+	runnerSpec := RunnerSpec{Name: runnerName}
+
+	// The runner is registered in the scale set up front and starts from its single-use JIT
+	// config, so no registration token reaches the instance. Without one it could not register.
+	client, _ := s.connection()
+	if s.currentScaleSet() == nil || !client.Capabilities().JITConfig {
+		return "", fmt.Errorf("cannot launch runner %s: JIT runner configs need a scale set session on a server that supports them", runnerName)
+	}
+	jit, err := client.GenerateJitRunnerConfig(ctx, s.config.RunnerScaleSetID, runnerName)
+	if err != nil {
+		return "", err
+	}
+	runnerSpec.JITConfig = jit.EncodedJITConfig
+	var runnerID int64
+	if jit.Runner != nil {
+		runnerID = jit.Runner.ID
+	}
 
-	// TODO: Implement actual EC2 instance creation
-	// This should:
-	// 1. Launch EC2 spot instance with runner configuration
-	// 2. Install GitHub Actions runner
-	// 3. Register runner with GitHub
-	// 4. Add to runnerTracker
+	labels := literalLabels(s.config.RunnerLabels)
+	span.SetAttributes("runnerName", runnerName)
+	runnerSpec.Labels = labels
+	instanceID, err = s.provider.CreateRunner(ctx, runnerSpec)
+	if err != nil {
+		// The runner registered for the JIT config would otherwise stay offline in the scale set
+		if runnerID != 0 {
+			if removeErr := client.RemoveRunner(context.WithoutCancel(ctx), runnerID); removeErr != nil {
+				s.logger.Error(removeErr, "Failed to remove the runner of a failed launch", "runnerName", runnerName, "runnerId", runnerID)
+			}
+		}
+		return "", err
+	}
 
-	// Placeholder implementation
-	instanceID := fmt.Sprintf("i-%s", uuid.New().String()[:8])
 	instance := &EC2RunnerInstance{
 		InstanceID:   instanceID,
+		RunnerName:   runnerName,
 		LaunchTime:   time.Now(),
 		State:        "pending",
-		Labels:       s.config.RunnerLabels,
+		RunnerID:     runnerID,
+		Labels:       labels,
 		LastActivity: time.Now(),
+		traceparent:  span.Traceparent(),
 	}
 
 	s.runnerTracker.mu.Lock()
 	s.runnerTracker.instances[instanceID] = instance
 	s.runnerTracker.mu.Unlock()
 
-	s.logger.Info("EC2 runner instance created", "instanceId", instanceID)
-	return nil
+	// Recorded as pending so a restarted process can reconcile an unfinished launch
+	if err := s.runnerStore.UpdateStatus(ctx, runnerName, instanceID, runnerStatusPending); err != nil {
+		s.logger.Error(err, "Failed to record launched runner", "runnerName", runnerName)
+	}
+	return instanceID, nil
 }
 
-// terminateIdleRunners terminates idle runner instances
-func (s *MessageQueueScaler) terminateIdleRunners(ctx context.Context, count int) error {
+// terminateIdleRunners terminates up to count idle runner instances and returns their instance IDs
+func (s *MessageQueueScaler) terminateIdleRunners(ctx context.Context, count int) ([]string, error) {
 	s.logger.Info("Terminating idle runners", "count", count)
 
 	s.runnerTracker.mu.Lock()
@@ -626,27 +1222,39 @@ func (s *MessageQueueScaler) terminateIdleRunners(ctx context.Context, count int
 	}
 
 	// Terminate the requested number of idle runners
-	terminated := 0
+	var terminated []string
 	for _, instance := range idleRunners {
-		if terminated >= count {
+		if len(terminated) >= count {
 			break
 		}
 
 		s.logger.Info("Terminating idle runner", "instanceId", instance.InstanceID)
 
-		// TODO: Implement actual EC2 termination
-		// This should:
-		// 1. Unregister runner from GitHub
-		// 2. Terminate EC2 instance
-		// 3. Remove from runnerTracker
-
-		// Placeholder implementation
+		if err := s.removeRunner(ctx, instance); err != nil {
+			s.logger.Error(err, "Failed to terminate idle runner", "instanceId", instance.InstanceID)
+			continue
+		}
 		delete(s.runnerTracker.instances, instance.InstanceID)
-		terminated++
+		terminated = append(terminated, instance.InstanceID)
 	}
 
-	s.logger.Info("Terminated idle runners", "terminated", terminated)
-	return nil
+	s.logger.Info("Terminated idle runners", "terminated", len(terminated))
+	return terminated, nil
+}
+
+// removeRunner removes the runner from its scale set, so it is assigned no job while its
+// instance shuts down, and terminates the instance
+func (s *MessageQueueScaler) removeRunner(ctx context.Context, instance *EC2RunnerInstance) error {
+	if instance.RunnerID != 0 {
+		client, _ := s.connection()
+		if client == nil {
+			return fmt.Errorf("cannot deregister runner %s: not connected to the Actions Service", instance.RunnerName)
+		}
+		if err := client.RemoveRunner(ctx, instance.RunnerID); err != nil {
+			return fmt.Errorf("failed to deregister runner %s: %w", instance.RunnerName, err)
+		}
+	}
+	return s.provider.TerminateRunner(ctx, instance.InstanceID)
 }
 
 // Helper functions
@@ -662,19 +1270,25 @@ func (s *MessageQueueScaler) extractLabelNames(labels []Label) []string {
 func (s *MessageQueueScaler) refreshSession(ctx context.Context) error {
 	s.logger.Info("Message queue token expired, refreshing session...")
 
-	session, err := s.actionsClient.RefreshMessageSession(ctx, s.session.RunnerScaleSet.ID, s.session.SessionID)
+	client, current := s.connection()
+	session, err := client.RefreshMessageSession(ctx, current.RunnerScaleSet.ID, current.SessionID)
 	if err != nil {
 		return fmt.Errorf("refresh message session failed: %w", err)
 	}
 
+	s.mu.Lock()
 	s.session = session
+	s.mu.Unlock()
+	s.saveSessionState(ctx)
 	return nil
 }
 
 func (s *MessageQueueScaler) deleteLastMessage(ctx context.Context) error {
-	s.logger.V(1).Info("Deleting last message", "lastMessageID", s.lastMessageID)
+	lastMessageID := s.messageCursor()
+	s.logger.V(1).Info("Deleting last message", "lastMessageID", lastMessageID)
 
-	err := s.actionsClient.DeleteMessage(ctx, s.session.MessageQueueURL, s.session.MessageQueueAccessToken, s.lastMessageID)
+	client, session := s.connection()
+	err := client.DeleteMessage(ctx, session.MessageQueueURL, session.MessageQueueAccessToken, lastMessageID)
 	if err == nil {
 		return nil
 	}
@@ -685,7 +1299,8 @@ func (s *MessageQueueScaler) deleteLastMessage(ctx context.Context) error {
 			return err
 		}
 
-		err = s.actionsClient.DeleteMessage(ctx, s.session.MessageQueueURL, s.session.MessageQueueAccessToken, s.lastMessageID)
+		client, session = s.connection()
+		err = client.DeleteMessage(ctx, session.MessageQueueURL, session.MessageQueueAccessToken, lastMessageID)
 		if err != nil {
 			return fmt.Errorf("failed to delete last message after session refresh: %w", err)
 		}
@@ -697,33 +1312,41 @@ func (s *MessageQueueScaler) deleteLastMessage(ctx context.Context) error {
 }
 
 func (s *MessageQueueScaler) cleanupSession(ctx context.Context) {
-	if s.session != nil && s.session.SessionID != nil {
-		ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	client, session := s.connection()
+	if session != nil && session.SessionID != nil {
+		// Runs on shutdown too, when ctx is already cancelled
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
 		defer cancel()
 
 		s.logger.Info("Deleting message session")
 
-		if err := s.actionsClient.DeleteMessageSession(ctx, s.session.RunnerScaleSet.ID, s.session.SessionID); err != nil {
+		if err := client.DeleteMessageSession(ctx, session.RunnerScaleSet.ID, session.SessionID); err != nil {
 			s.logger.Error(err, "Failed to delete message session")
 		}
+		if err := s.sessionStore.Delete(ctx, session.SessionID.String()); err != nil {
+			s.logger.Error(err, "Failed to delete persisted message session")
+		}
 	}
 }
 
 func isMessageQueueTokenExpiredError(err error) bool {
-	// TODO: Implement proper error type checking
-	return err != nil && (err.Error() == "message queue token expired" ||
-		err.Error() == "unauthorized")
+	return actions.IsStatus(err, http.StatusUnauthorized)
 }
 
 // runDiagnostics runs diagnostic checks to help troubleshoot issues
 func (s *MessageQueueScaler) runDiagnostics(ctx context.Context) error {
 	s.logger.Info("Running diagnostics to troubleshoot message queue issues")
 
+	// Pending jobs for the deadman check, preferring the live acquirable jobs count over possibly stale statistics
+	pendingJobs := s.pendingJobsFromStatistics()
+
 	// Check acquirable jobs directly
-	acquirableJobs, err := s.actionsClient.GetAcquirableJobs(ctx, s.config.RunnerScaleSetID)
+	client, session := s.connection()
+	acquirableJobs, err := client.GetAcquirableJobs(ctx, s.config.RunnerScaleSetID)
 	if err != nil {
 		s.logger.Error(err, "Failed to get acquirable jobs")
 	} else {
+		pendingJobs = acquirableJobs.Count
 		s.logger.Info("Acquirable jobs check", 
 			"count", acquirableJobs.Count,
 			"jobs", len(acquirableJobs.Jobs))
@@ -739,21 +1362,40 @@ func (s *MessageQueueScaler) runDiagnostics(ctx context.Context) error {
 		}
 	}
 
+	s.deadman.Check(ctx, pendingJobs)
+
 	// Log current scale set configuration
+	minRunners, maxRunners := s.scalingLimits()
 	s.logger.Info("Current scale set configuration",
 		"scaleSetId", s.config.RunnerScaleSetID,
 		"scaleSetName", s.config.RunnerScaleSetName,
 		"runnerLabels", s.config.RunnerLabels,
-		"minRunners", s.config.MinRunners,
-		"maxRunners", s.config.MaxRunners)
+		"minRunners", minRunners,
+		"maxRunners", maxRunners)
 
 	// Log session information
-	if s.session != nil {
+	if session != nil {
 		s.logger.Info("Current message session",
-			"sessionId", s.session.SessionID,
-			"messageQueueUrl", s.session.MessageQueueURL,
-			"lastMessageId", s.lastMessageID)
+			"sessionId", session.SessionID,
+			"messageQueueUrl", session.MessageQueueURL,
+			"lastMessageId", s.messageCursor())
 	}
 
 	return nil
 }
+
+// pendingJobsFromStatistics returns the number of jobs waiting for a runner according to the last statistics
+func (s *MessageQueueScaler) pendingJobsFromStatistics() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.lastStatistics == nil {
+		return 0
+	}
+
+	pending := s.lastStatistics.TotalAvailableJobs + s.lastStatistics.TotalAssignedJobs - s.lastStatistics.TotalRunningJobs
+	if pending < 0 {
+		return 0
+	}
+	return pending
+}
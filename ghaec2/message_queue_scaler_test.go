@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/Anshuman2121/actionsspot/internal/actions"
+	"github.com/go-logr/logr"
+)
+
+const (
+	testAdminToken        = "admin-token"
+	testMessageQueueToken = "message-queue-token"
+)
+
+// fakeActionsService records the jobs acquired through the Actions Service API
+type fakeActionsService struct {
+	mu       sync.Mutex
+	acquired []int64
+	tokens   []string
+}
+
+func (f *fakeActionsService) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.tokens = append(f.tokens, r.Header.Get("Authorization"))
+
+	var body struct {
+		RequestIDs []int64 `json:"requestIds"`
+	}
+	json.NewDecoder(r.Body).Decode(&body)
+	f.acquired = append(f.acquired, body.RequestIDs...)
+	json.NewEncoder(w).Encode(map[string][]int64{"value": body.RequestIDs})
+}
+
+// newTestScaler returns a scaler connected to a fake Actions Service, with a message session
+// and no provider. The metrics publisher has no CloudWatch client.
+func newTestScaler(t *testing.T, cfg *Config, service *fakeActionsService) *MessageQueueScaler {
+	t.Helper()
+	mux := http.NewServeMux()
+	var srv *httptest.Server
+	mux.HandleFunc("/api/v3/actions/runner-registration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"url": srv.URL + "/service", "token": testAdminToken})
+	})
+	mux.HandleFunc("/service/", service.serveHTTP)
+	srv = httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	metrics := NewMetricsPublisher(nil, "test", cfg.RunnerScaleSetName, "eu-north-1", logr.Discard())
+	s := NewMessageQueueScaler(cfg, nil, metrics, nil, nil, nil, nil, nil, nil, nil, logr.Discard())
+	s.actionsClient.service = actions.NewClient(srv.Client(), userAgent, 0)
+	if err := s.actionsClient.service.Connect(context.Background(), srv.URL, srv.URL+"/org", "registration-token"); err != nil {
+		t.Fatalf("Connect() = %v", err)
+	}
+	s.session = &RunnerScaleSetSession{MessageQueueAccessToken: testMessageQueueToken}
+	return s
+}
+
+func TestHandleJobsAvailableSkipsExcludedJobsInBatchMode(t *testing.T) {
+	cfg := &Config{
+		RunnerScaleSetName: "ghaec2-scaler",
+		RunnerScaleSetID:   1,
+		RunnerLabels:       []string{"self-hosted", "linux", "x64"},
+		ExcludedLabels:     []string{"gpu"},
+		JobAcquisitionMode: acquisitionModeBatch,
+		MaxRunners:         10,
+	}
+	service := &fakeActionsService{}
+	s := newTestScaler(t, cfg, service)
+
+	jobs := []*JobAvailable{
+		{RunnerRequestID: 1, RequestLabels: []string{"self-hosted", "linux"}},
+		{RunnerRequestID: 2, RequestLabels: []string{"self-hosted", "linux", "gpu"}},
+		{RunnerRequestID: 3, RequestLabels: []string{"self-hosted", "x64"}},
+	}
+	if err := s.handleJobsAvailable(context.Background(), jobs); err != nil {
+		t.Fatalf("handleJobsAvailable() = %v", err)
+	}
+
+	if len(service.acquired) != 2 || service.acquired[0] == 2 || service.acquired[1] == 2 {
+		t.Errorf("acquired request IDs = %v, want 1 and 3 without the excluded job 2", service.acquired)
+	}
+}
+
+func TestAcquireJobsUsesMessageQueueToken(t *testing.T) {
+	for _, mode := range []string{acquisitionModeBatch, acquisitionModePerJob} {
+		t.Run(mode, func(t *testing.T) {
+			cfg := &Config{RunnerScaleSetName: "ghaec2-scaler", RunnerScaleSetID: 1, JobAcquisitionMode: mode}
+			service := &fakeActionsService{}
+			s := newTestScaler(t, cfg, service)
+
+			job := &JobAvailable{RunnerRequestID: 1, AcquireJobURL: s.actionsClient.service.ServiceURL() + "/acquirejob/1"}
+			if _, err := s.acquireAvailableJobs(context.Background(), []*JobAvailable{job}); err != nil {
+				t.Fatalf("acquireAvailableJobs() = %v", err)
+			}
+
+			if len(service.tokens) != 1 || service.tokens[0] != "Bearer "+testMessageQueueToken {
+				t.Errorf("Authorization = %v, want the message queue access token", service.tokens)
+			}
+		})
+	}
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	cwtypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/go-logr/logr"
+)
+
+// Metric names published by the scaler. The alarms created by setup-alarms refer to these.
+const (
+	metricHeartbeat       = "Heartbeat"
+	metricErrors          = "Errors"
+	metricSuccessfulPolls = "SuccessfulPolls"
+	metricAvailableJobs   = "AvailableJobs"
+	metricAssignedJobs    = "AssignedJobs"
+	metricRunningJobs     = "RunningJobs"
+	metricQueuedJobs      = "QueuedJobs"
+	metricCurrentRunners  = "CurrentRunners"
+	metricDesiredRunners  = "DesiredRunners"
+	metricJobsAcquired    = "JobsAcquired"
+	metricJobsLost        = "JobsLostToOtherScalers"
+	metricJobsSkipped     = "JobsSkippedByPolicy"
+	metricJobsDeferred    = "JobsDeferredAtCapacity"
+
+	metricMaxJobWaitSeconds = "MaxJobWaitSeconds"
+
+	// Dashboard metrics
+	metricBusyRatio             = "BusyRatio"
+	metricInstanceLaunchLatency = "InstanceLaunchLatency"
+	metricSpotFulfillmentTime   = "SpotFulfillmentTime"
+	metricScaleDecisions        = "ScaleDecisions"
+	metricJobStartLatency       = "JobStartLatency"
+
+	metricSecondsSinceLastMessage = "SecondsSinceLastMessage"
+	metricDeadmanTriggered        = "DeadmanTriggered"
+	metricListenerRestarts        = "ListenerRestarts"
+	metricScalingPaused           = "ScalingPaused"
+	metricRESTScanThrottled       = "RESTScanThrottled"
+	metricOldestQueuedJobSeconds  = "OldestQueuedJobSeconds"
+	metricQueueStarvation         = "QueueStarvation"
+	metricRecommendedMaxRunners   = "RecommendedMaxRunners"
+	metricMessageLatency          = "MessageLatency"
+)
+
+// Scale decision reasons, published as the Reason dimension of ScaleDecisions
+const (
+	scaleReasonUp          = "ScaleUp"
+	scaleReasonDown        = "ScaleDown"
+	scaleReasonNoChange    = "NoChange"
+	scaleReasonCappedAtMax = "CappedAtMax"
+	scaleReasonHeldAtMin   = "HeldAtMin"
+)
+
+// defaultPool is the Pool dimension value for runners that do not belong to a named pool
+const defaultPool = "default"
+
+// maxDatumsPerRequest is the PutMetricData limit on metric data per call
+const maxDatumsPerRequest = 1000
+
+// MetricsPublisher buffers scaler metrics and publishes them to CloudWatch in batches
+type MetricsPublisher struct {
+	client     *cloudwatch.Client
+	namespace  string
+	dimensions []cwtypes.Dimension
+	logger     logr.Logger
+
+	mu      sync.Mutex
+	pending []cwtypes.MetricDatum
+	series  map[promSeriesKey]*promSeries // Prometheus view of every metric, for /metrics
+}
+
+// NewMetricsPublisher creates a metrics publisher. A nil client disables publishing to
+// CloudWatch; the metrics are still served in the Prometheus format.
+// Every metric carries the ScaleSetName and Region dimensions so dashboards can group consistently.
+func NewMetricsPublisher(client *cloudwatch.Client, namespace, scaleSetName, region string, logger logr.Logger) *MetricsPublisher {
+	return &MetricsPublisher{
+		client:     client,
+		namespace:  namespace,
+		dimensions: metricDimensions(scaleSetName, region),
+		logger:     logger,
+		series:     make(map[promSeriesKey]*promSeries),
+	}
+}
+
+// metricDimensions returns the dimensions shared by every scaler metric
+func metricDimensions(scaleSetName, region string) []cwtypes.Dimension {
+	return []cwtypes.Dimension{
+		{Name: aws.String("ScaleSetName"), Value: aws.String(scaleSetName)},
+		{Name: aws.String("Region"), Value: aws.String(region)},
+	}
+}
+
+// Count records a count metric
+func (m *MetricsPublisher) Count(name string, value float64) {
+	m.record(name, value, cwtypes.StandardUnitCount)
+}
+
+// Gauge records a point-in-time value
+func (m *MetricsPublisher) Gauge(name string, value float64) {
+	m.record(name, value, cwtypes.StandardUnitNone)
+}
+
+// PoolGauge records a point-in-time value for a runner pool
+func (m *MetricsPublisher) PoolGauge(name string, value float64, pool string) {
+	m.record(name, value, cwtypes.StandardUnitNone,
+		cwtypes.Dimension{Name: aws.String("Pool"), Value: aws.String(pool)})
+}
+
+// Duration records a latency for a runner pool. CloudWatch keeps the raw values, so
+// dashboards can chart percentiles of the distribution.
+func (m *MetricsPublisher) Duration(name string, d time.Duration, pool string) {
+	m.record(name, d.Seconds(), cwtypes.StandardUnitSeconds,
+		cwtypes.Dimension{Name: aws.String("Pool"), Value: aws.String(pool)})
+}
+
+// ScaleDecision counts a scaling decision by its reason
+func (m *MetricsPublisher) ScaleDecision(reason string) {
+	m.record(metricScaleDecisions, 1, cwtypes.StandardUnitCount,
+		cwtypes.Dimension{Name: aws.String("Reason"), Value: aws.String(reason)})
+}
+
+// QueueStarvation counts a starved scaling decision by its cause
+func (m *MetricsPublisher) QueueStarvation(cause string) {
+	m.record(metricQueueStarvation, 1, cwtypes.StandardUnitCount,
+		cwtypes.Dimension{Name: aws.String("Cause"), Value: aws.String(cause)})
+}
+
+// RecordStatistics records the queue statistics reported by the Actions Service
+func (m *MetricsPublisher) RecordStatistics(stats *RunnerScaleSetStatistic) {
+	if stats == nil {
+		return
+	}
+
+	queued := stats.TotalAvailableJobs + stats.TotalAssignedJobs - stats.TotalRunningJobs
+	if queued < 0 {
+		queued = 0
+	}
+
+	m.Gauge(metricAvailableJobs, float64(stats.TotalAvailableJobs))
+	m.Gauge(metricAssignedJobs, float64(stats.TotalAssignedJobs))
+	m.Gauge(metricRunningJobs, float64(stats.TotalRunningJobs))
+	m.Gauge(metricQueuedJobs, float64(queued))
+
+	busyRatio := 0.0
+	if stats.TotalRegisteredRunners > 0 {
+		busyRatio = float64(stats.TotalBusyRunners) / float64(stats.TotalRegisteredRunners)
+	}
+	m.Gauge(metricBusyRatio, busyRatio)
+}
+
+func (m *MetricsPublisher) record(name string, value float64, unit cwtypes.StandardUnit, extra ...cwtypes.Dimension) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.observe(name, value, unit, extra)
+	if m.client == nil {
+		return
+	}
+
+	dimensions := m.dimensions
+	if len(extra) > 0 {
+		dimensions = append(append([]cwtypes.Dimension{}, m.dimensions...), extra...)
+	}
+
+	m.pending = append(m.pending, cwtypes.MetricDatum{
+		MetricName: aws.String(name),
+		Dimensions: dimensions,
+		Timestamp:  aws.Time(time.Now()),
+		Unit:       unit,
+		Value:      aws.Float64(value),
+	})
+}
+
+// Flush publishes all buffered metrics to CloudWatch
+func (m *MetricsPublisher) Flush(ctx context.Context) error {
+	if m.client == nil {
+		return nil
+	}
+
+	m.mu.Lock()
+	data := m.pending
+	m.pending = nil
+	m.mu.Unlock()
+
+	for start := 0; start < len(data); start += maxDatumsPerRequest {
+		end := start + maxDatumsPerRequest
+		if end > len(data) {
+			end = len(data)
+		}
+
+		_, err := m.client.PutMetricData(ctx, &cloudwatch.PutMetricDataInput{
+			Namespace:  aws.String(m.namespace),
+			MetricData: data[start:end],
+		})
+		if err != nil {
+			return fmt.Errorf("failed to put metric data: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Run flushes buffered metrics on the given interval until the context is cancelled
+func (m *MetricsPublisher) Run(ctx context.Context, interval time.Duration) {
+	if m.client == nil {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// Final flush so the last cycle's metrics are not lost on shutdown
+			flushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+			if err := m.Flush(flushCtx); err != nil {
+				m.logger.Error(err, "Failed to flush metrics on shutdown")
+			}
+			cancel()
+			return
+		case <-ticker.C:
+			if err := m.Flush(ctx); err != nil {
+				m.logger.Error(err, "Failed to publish metrics")
+			}
+		}
+	}
+}
@@ -0,0 +1,336 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// tableMigrationTimeout bounds the wait for a created table or index to become active
+const tableMigrationTimeout = 10 * time.Minute
+
+// tableKey is a key attribute of a table or index
+type tableKey struct {
+	Name string
+	Type types.ScalarAttributeType
+}
+
+// tableIndex is a global secondary index projecting all attributes
+type tableIndex struct {
+	Name    string
+	HashKey tableKey
+}
+
+// tableSchema is the layout a store expects of its table
+type tableSchema struct {
+	Setting      string
+	Name         string
+	HashKey      tableKey
+	RangeKey     *tableKey
+	Indexes      []tableIndex
+	TTLAttribute string
+}
+
+// tableSchemas returns the schemas of the configured tables. The runner and session tables
+// match the ones the Lambda scaler creates, since the two scalers share them.
+func tableSchemas(cfg *Config) []tableSchema {
+	timeKey := &tableKey{Name: "timestamp", Type: types.ScalarAttributeTypeN}
+
+	schemas := []tableSchema{
+		{
+			Setting: "DYNAMODB_TABLE_NAME",
+			Name:    cfg.DynamoDBTableName,
+			HashKey: tableKey{Name: "runner_id", Type: types.ScalarAttributeTypeS},
+			Indexes: []tableIndex{
+				{Name: "JobRequestIndex", HashKey: tableKey{Name: "job_request_id", Type: types.ScalarAttributeTypeN}},
+				{Name: "StatusIndex", HashKey: tableKey{Name: "status", Type: types.ScalarAttributeTypeS}},
+			},
+			TTLAttribute: "expires_at",
+		},
+		{
+			Setting:      "SESSIONS_TABLE_NAME",
+			Name:         cfg.SessionsTableName,
+			HashKey:      tableKey{Name: "session_id", Type: types.ScalarAttributeTypeS},
+			TTLAttribute: "expires_at",
+		},
+		{
+			Setting:      "STATS_TABLE_NAME",
+			Name:         cfg.StatsTableName,
+			HashKey:      tableKey{Name: "scale_set_name", Type: types.ScalarAttributeTypeS},
+			RangeKey:     timeKey,
+			TTLAttribute: "expires_at",
+		},
+		{
+			Setting:      "DECISIONS_TABLE_NAME",
+			Name:         cfg.DecisionsTableName,
+			HashKey:      tableKey{Name: "scale_set_name", Type: types.ScalarAttributeTypeS},
+			RangeKey:     timeKey,
+			TTLAttribute: "expires_at",
+		},
+		{
+			Setting: "CONTROL_TABLE_NAME",
+			Name:    cfg.ControlTableName,
+			HashKey: tableKey{Name: "scale_set_name", Type: types.ScalarAttributeTypeS},
+		},
+		{
+			Setting:      "LEADER_ELECTION_TABLE_NAME",
+			Name:         cfg.LeaderElectionTableName,
+			HashKey:      tableKey{Name: "scale_set_name", Type: types.ScalarAttributeTypeS},
+			TTLAttribute: "expires_at",
+		},
+	}
+	return schemas
+}
+
+// runMigrate creates the configured DynamoDB tables, or brings existing ones up to date by
+// adding missing global secondary indexes and enabling TTL. Key schemas cannot be changed
+// in place, so a table with different keys is reported and left alone.
+func runMigrate(ctx context.Context, args []string, client *dynamodb.Client, cfg *Config, stdout io.Writer) int {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "print the changes without applying them")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	migrator := &tableMigrator{client: client, dryRun: *dryRun, out: stdout}
+	failed := 0
+	for _, schema := range tableSchemas(cfg) {
+		if schema.Name == "" {
+			fmt.Fprintf(stdout, "⏭️  %s is not set, skipping\n", schema.Setting)
+			continue
+		}
+		if err := migrator.migrate(ctx, schema); err != nil {
+			fmt.Fprintf(stdout, "❌ %s: %v\n", schema.Name, err)
+			failed++
+			continue
+		}
+		fmt.Fprintf(stdout, "✅ %s\n", schema.Name)
+	}
+
+	if failed > 0 {
+		fmt.Fprintf(os.Stderr, "%d tables failed to migrate\n", failed)
+		return 1
+	}
+	return 0
+}
+
+type tableMigrator struct {
+	client *dynamodb.Client
+	dryRun bool
+	out    io.Writer
+}
+
+func (m *tableMigrator) migrate(ctx context.Context, schema tableSchema) error {
+	output, err := m.client.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(schema.Name)})
+	var notFound *types.ResourceNotFoundException
+	switch {
+	case errors.As(err, &notFound):
+		if err := m.createTable(ctx, schema); err != nil {
+			return err
+		}
+	case err != nil:
+		return fmt.Errorf("failed to describe table: %w", err)
+	default:
+		if err := checkKeySchema(output.Table, schema); err != nil {
+			return err
+		}
+		if err := m.addMissingIndexes(ctx, output.Table, schema); err != nil {
+			return err
+		}
+	}
+
+	return m.enableTTL(ctx, schema)
+}
+
+func (m *tableMigrator) createTable(ctx context.Context, schema tableSchema) error {
+	fmt.Fprintf(m.out, "   creating table %s\n", schema.Name)
+	if m.dryRun {
+		return nil
+	}
+
+	keys := []tableKey{schema.HashKey}
+	keySchema := []types.KeySchemaElement{{AttributeName: aws.String(schema.HashKey.Name), KeyType: types.KeyTypeHash}}
+	if schema.RangeKey != nil {
+		keys = append(keys, *schema.RangeKey)
+		keySchema = append(keySchema, types.KeySchemaElement{AttributeName: aws.String(schema.RangeKey.Name), KeyType: types.KeyTypeRange})
+	}
+	var indexes []types.GlobalSecondaryIndex
+	for _, index := range schema.Indexes {
+		keys = append(keys, index.HashKey)
+		indexes = append(indexes, types.GlobalSecondaryIndex{
+			IndexName:  aws.String(index.Name),
+			KeySchema:  []types.KeySchemaElement{{AttributeName: aws.String(index.HashKey.Name), KeyType: types.KeyTypeHash}},
+			Projection: &types.Projection{ProjectionType: types.ProjectionTypeAll},
+		})
+	}
+
+	_, err := m.client.CreateTable(ctx, &dynamodb.CreateTableInput{
+		TableName:              aws.String(schema.Name),
+		BillingMode:            types.BillingModePayPerRequest,
+		AttributeDefinitions:   attributeDefinitions(keys),
+		KeySchema:              keySchema,
+		GlobalSecondaryIndexes: indexes,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create table: %w", err)
+	}
+	return m.waitActive(ctx, schema.Name, "")
+}
+
+// checkKeySchema verifies the primary key of an existing table matches the schema
+func checkKeySchema(table *types.TableDescription, schema tableSchema) error {
+	want := map[types.KeyType]string{types.KeyTypeHash: schema.HashKey.Name}
+	if schema.RangeKey != nil {
+		want[types.KeyTypeRange] = schema.RangeKey.Name
+	}
+
+	got := make(map[types.KeyType]string, len(table.KeySchema))
+	for _, element := range table.KeySchema {
+		got[element.KeyType] = aws.ToString(element.AttributeName)
+	}
+	if len(got) != len(want) || got[types.KeyTypeHash] != want[types.KeyTypeHash] || got[types.KeyTypeRange] != want[types.KeyTypeRange] {
+		return fmt.Errorf("key schema is hash=%q range=%q, expected hash=%q range=%q; recreate the table to change it",
+			got[types.KeyTypeHash], got[types.KeyTypeRange], want[types.KeyTypeHash], want[types.KeyTypeRange])
+	}
+	return nil
+}
+
+// addMissingIndexes creates the schema's indexes the table lacks. DynamoDB builds one index
+// per UpdateTable call, so each is awaited before the next.
+func (m *tableMigrator) addMissingIndexes(ctx context.Context, table *types.TableDescription, schema tableSchema) error {
+	existing := make(map[string]bool, len(table.GlobalSecondaryIndexes))
+	for _, index := range table.GlobalSecondaryIndexes {
+		existing[aws.ToString(index.IndexName)] = true
+	}
+
+	for _, index := range schema.Indexes {
+		if existing[index.Name] {
+			continue
+		}
+		fmt.Fprintf(m.out, "   adding index %s on %s\n", index.Name, index.HashKey.Name)
+		if m.dryRun {
+			continue
+		}
+
+		create := &types.CreateGlobalSecondaryIndexAction{
+			IndexName:  aws.String(index.Name),
+			KeySchema:  []types.KeySchemaElement{{AttributeName: aws.String(index.HashKey.Name), KeyType: types.KeyTypeHash}},
+			Projection: &types.Projection{ProjectionType: types.ProjectionTypeAll},
+		}
+		// Provisioned tables need a throughput for the index; reuse the table's
+		if table.BillingModeSummary == nil || table.BillingModeSummary.BillingMode != types.BillingModePayPerRequest {
+			if throughput := table.ProvisionedThroughput; throughput != nil && aws.ToInt64(throughput.ReadCapacityUnits) > 0 {
+				create.ProvisionedThroughput = &types.ProvisionedThroughput{
+					ReadCapacityUnits:  throughput.ReadCapacityUnits,
+					WriteCapacityUnits: throughput.WriteCapacityUnits,
+				}
+			}
+		}
+
+		_, err := m.client.UpdateTable(ctx, &dynamodb.UpdateTableInput{
+			TableName:                   aws.String(schema.Name),
+			AttributeDefinitions:        attributeDefinitions([]tableKey{index.HashKey}),
+			GlobalSecondaryIndexUpdates: []types.GlobalSecondaryIndexUpdate{{Create: create}},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to add index %s: %w", index.Name, err)
+		}
+		if err := m.waitActive(ctx, schema.Name, index.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// enableTTL turns on expiry through the schema's TTL attribute when it is not already enabled
+func (m *tableMigrator) enableTTL(ctx context.Context, schema tableSchema) error {
+	if schema.TTLAttribute == "" {
+		return nil
+	}
+
+	if !m.dryRun || m.tableExists(ctx, schema.Name) {
+		output, err := m.client.DescribeTimeToLive(ctx, &dynamodb.DescribeTimeToLiveInput{TableName: aws.String(schema.Name)})
+		if err != nil {
+			return fmt.Errorf("failed to describe TTL: %w", err)
+		}
+		if ttl := output.TimeToLiveDescription; ttl != nil && aws.ToString(ttl.AttributeName) == schema.TTLAttribute &&
+			(ttl.TimeToLiveStatus == types.TimeToLiveStatusEnabled || ttl.TimeToLiveStatus == types.TimeToLiveStatusEnabling) {
+			return nil
+		}
+	}
+
+	fmt.Fprintf(m.out, "   enabling TTL on %s\n", schema.TTLAttribute)
+	if m.dryRun {
+		return nil
+	}
+	_, err := m.client.UpdateTimeToLive(ctx, &dynamodb.UpdateTimeToLiveInput{
+		TableName: aws.String(schema.Name),
+		TimeToLiveSpecification: &types.TimeToLiveSpecification{
+			AttributeName: aws.String(schema.TTLAttribute),
+			Enabled:       aws.Bool(true),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to enable TTL: %w", err)
+	}
+	return nil
+}
+
+func (m *tableMigrator) tableExists(ctx context.Context, name string) bool {
+	_, err := m.client.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(name)})
+	return err == nil
+}
+
+// waitActive waits for a table, or one of its indexes when index is set, to become active
+func (m *tableMigrator) waitActive(ctx context.Context, name, index string) error {
+	ctx, cancel := context.WithTimeout(ctx, tableMigrationTimeout)
+	defer cancel()
+
+	for {
+		output, err := m.client.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(name)})
+		if err != nil {
+			return fmt.Errorf("failed to wait for table: %w", err)
+		}
+
+		active := output.Table.TableStatus == types.TableStatusActive
+		if index != "" {
+			active = false
+			for _, gsi := range output.Table.GlobalSecondaryIndexes {
+				if aws.ToString(gsi.IndexName) == index && gsi.IndexStatus == types.IndexStatusActive {
+					active = true
+				}
+			}
+		}
+		if active {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for %s to become active", name)
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
+
+// attributeDefinitions declares key attributes, each once
+func attributeDefinitions(keys []tableKey) []types.AttributeDefinition {
+	seen := make(map[string]bool, len(keys))
+	var definitions []types.AttributeDefinition
+	for _, key := range keys {
+		if seen[key.Name] {
+			continue
+		}
+		seen[key.Name] = true
+		definitions = append(definitions, types.AttributeDefinition{AttributeName: aws.String(key.Name), AttributeType: key.Type})
+	}
+	return definitions
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// nextPollDelay returns how long to wait before polling again after an empty poll.
+// In adaptive mode the scaler slows down once it has been idle for PollIdleAfter.
+func (s *MessageQueueScaler) nextPollDelay() time.Duration {
+	if s.config.AdaptivePolling && time.Since(s.lastActivity) >= s.config.PollIdleAfter {
+		if !s.pollingIdle {
+			s.logger.Info("No activity, slowing down polling",
+				"idleFor", time.Since(s.lastActivity).Round(time.Second),
+				"interval", s.config.PollIdleInterval)
+			s.pollingIdle = true
+		}
+		return s.config.PollIdleInterval
+	}
+	return s.config.PollInterval
+}
+
+// recordActivity resets the idle timer when statistics show jobs waiting or running,
+// snapping adaptive polling back to the fast interval
+func (s *MessageQueueScaler) recordActivity(stats *RunnerScaleSetStatistic) {
+	if stats == nil || stats.TotalAvailableJobs+stats.TotalAssignedJobs+stats.TotalRunningJobs == 0 {
+		return
+	}
+
+	s.lastActivity = time.Now()
+	if s.pollingIdle {
+		s.logger.Info("Jobs available, resuming fast polling", "interval", s.config.PollInterval)
+		s.pollingIdle = false
+	}
+}
+
+// sleepContext waits for the given duration or until the context is cancelled
+func sleepContext(ctx context.Context, d time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(d):
+	}
+}
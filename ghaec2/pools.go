@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"os"
+	"time"
+)
+
+// PoolConfig describes one runner pool of a multi-pool process: a scale set with its own
+// labels, EC2 profile and runner limits, reconciled by a scaler of its own. Zero values
+// inherit the top-level configuration; pool spot price ceilings are added to the top-level
+// ones.
+type PoolConfig struct {
+	Name               string            `json:"name"`
+	RunnerScaleSetName string            `json:"scaleSetName,omitempty"` // defaults to the pool name
+	RunnerGroupID      *int              `json:"runnerGroupId,omitempty"`
+	Labels             []string          `json:"labels"`
+	MinRunners         *int              `json:"minRunners,omitempty"`
+	MaxRunners         *int              `json:"maxRunners,omitempty"`
+	InstanceType       string            `json:"instanceType,omitempty"`
+	AMI                string            `json:"ami,omitempty"`
+	LaunchTemplateID   string            `json:"launchTemplateId,omitempty"`
+	LaunchTemplateVer  string            `json:"launchTemplateVersion,omitempty"`
+	SubnetID           string            `json:"subnetId,omitempty"`
+	SpotPrices         map[string]string `json:"spotPrices,omitempty"`
+	OnDemandOnly       *bool             `json:"onDemandOnly,omitempty"`
+}
+
+// loadPools reads the pools of POOLS_CONFIG_FILE, a JSON array such as
+// [{"name": "gpu", "labels": ["self-hosted", "linux", "gpu"], "instanceType": "g5.xlarge", "maxRunners": 4}]
+func loadPools(path string) ([]PoolConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var pools []PoolConfig
+	if err := json.Unmarshal(data, &pools); err != nil {
+		return nil, err
+	}
+	if len(pools) == 0 {
+		return nil, fmt.Errorf("no pools in %s", path)
+	}
+	return pools, nil
+}
+
+// validatePools checks that every pool has a unique name, scale set and labels
+func validatePools(pools []PoolConfig) error {
+	names := make(map[string]bool, len(pools))
+	scaleSets := make(map[string]bool, len(pools))
+	for i, pool := range pools {
+		if pool.Name == "" {
+			return fmt.Errorf("pool %d has no name", i)
+		}
+		if names[pool.Name] {
+			return fmt.Errorf("duplicate pool name %q", pool.Name)
+		}
+		names[pool.Name] = true
+
+		// Sessions, leases and scaling decisions are keyed by scale set
+		scaleSet := pool.scaleSetName()
+		if scaleSets[scaleSet] {
+			return fmt.Errorf("pool %q: scale set %q is used by another pool", pool.Name, scaleSet)
+		}
+		scaleSets[scaleSet] = true
+
+		if len(pool.Labels) == 0 {
+			return fmt.Errorf("pool %q has no labels", pool.Name)
+		}
+	}
+	return nil
+}
+
+// scaleSetName returns the name of the pool's scale set
+func (p PoolConfig) scaleSetName() string {
+	if p.RunnerScaleSetName != "" {
+		return p.RunnerScaleSetName
+	}
+	return p.Name
+}
+
+// forPool returns a copy of the configuration with the pool's overrides applied
+func (c *Config) forPool(pool PoolConfig) *Config {
+	poolConfig := *c
+	poolConfig.PoolName = pool.Name
+	poolConfig.Pools = nil
+	poolConfig.LaunchSpecs = nil
+	poolConfig.RunnerScaleSetName = pool.scaleSetName()
+	poolConfig.RunnerScaleSetID = 0
+	poolConfig.RunnerLabels = pool.Labels
+	if pool.RunnerGroupID != nil {
+		poolConfig.RunnerGroupID = *pool.RunnerGroupID
+	}
+	if pool.MinRunners != nil {
+		poolConfig.MinRunners = *pool.MinRunners
+	}
+	if pool.MaxRunners != nil {
+		poolConfig.MaxRunners = *pool.MaxRunners
+	}
+	if pool.InstanceType != "" {
+		poolConfig.EC2InstanceType = pool.InstanceType
+	}
+	if pool.AMI != "" {
+		poolConfig.EC2AMI = pool.AMI
+	}
+	if pool.LaunchTemplateID != "" {
+		poolConfig.EC2LaunchTemplateID = pool.LaunchTemplateID
+		poolConfig.EC2LaunchTemplateVersion = pool.LaunchTemplateVer
+	}
+	if pool.SubnetID != "" {
+		poolConfig.EC2SubnetID = pool.SubnetID
+	}
+	if len(pool.SpotPrices) > 0 {
+		poolConfig.EC2SpotPrices = make(map[string]string, len(c.EC2SpotPrices)+len(pool.SpotPrices))
+		maps.Copy(poolConfig.EC2SpotPrices, c.EC2SpotPrices)
+		maps.Copy(poolConfig.EC2SpotPrices, pool.SpotPrices)
+	}
+	if pool.OnDemandOnly != nil {
+		poolConfig.OnDemandOnly = *pool.OnDemandOnly
+	}
+	return &poolConfig
+}
+
+// poolConfigs returns the configuration of every scaler the process runs: one per pool, or
+// the top-level configuration when no pools are configured
+func (c *Config) poolConfigs() []*Config {
+	if len(c.Pools) == 0 {
+		return []*Config{c}
+	}
+	configs := make([]*Config, 0, len(c.Pools))
+	for _, pool := range c.Pools {
+		configs = append(configs, c.forPool(pool))
+	}
+	return configs
+}
+
+// metricsPool returns the Pool dimension value of the configuration's runners
+func (c *Config) metricsPool() string {
+	if c.PoolName != "" {
+		return c.PoolName
+	}
+	return defaultPool
+}
+
+// handlePoolsLive answers container liveness probes of a multi-pool process, which is live
+// as long as every pool's polling loop keeps heartbeating
+func handlePoolsLive(scalers []*MessageQueueScaler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status := http.StatusOK
+		pools := make(map[string]time.Time, len(scalers))
+		var stalled []string
+		for _, scaler := range scalers {
+			pools[scaler.config.PoolName] = scaler.LastHeartbeat()
+			if scaler.stalled(scaler.config.LivenessThreshold) {
+				stalled = append(stalled, scaler.config.PoolName)
+				status = http.StatusServiceUnavailable
+			}
+		}
+		writeJSON(w, status, map[string]interface{}{
+			"live":          status == http.StatusOK,
+			"lastHeartbeat": pools,
+			"stalledPools":  stalled,
+		})
+	}
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// ReachabilityCheck is the outcome of one preflight check of the runner subnet
+type ReachabilityCheck struct {
+	Name   string
+	OK     bool
+	Detail string
+	Hint   string // how to fix a failed check
+}
+
+// runnerNetwork is what the preflight knows about the subnet runners are launched into
+type runnerNetwork struct {
+	subnet         ec2types.Subnet
+	routes         []ec2types.Route
+	endpoints      map[string]bool // service names of available VPC endpoints
+	securityGroups []ec2types.SecurityGroup
+}
+
+// CheckRunnerReachability inspects the routes, VPC endpoints and security groups of the
+// runner subnet and reports whether runners launched there can reach GHES, the runner
+// downloads on github.com, S3 and SSM. Most "runner never registers" reports come down to
+// one of these. Nothing is launched; the checks only read the network configuration.
+func CheckRunnerReachability(ctx context.Context, client *ec2.Client, cfg *Config) ([]ReachabilityCheck, error) {
+	network, err := describeRunnerNetwork(ctx, client, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	ghesHost := cfg.GitHubEnterpriseURL
+	if u, err := url.Parse(cfg.GitHubEnterpriseURL); err == nil && u.Hostname() != "" {
+		ghesHost = u.Hostname()
+	}
+
+	checks := []ReachabilityCheck{
+		network.checkHost(ctx, "GHES", ghesHost,
+			"Route the GHES addresses from the runner subnet (VPC peering, transit gateway or a NAT gateway for a public GHES)."),
+		network.checkHost(ctx, "github.com runner downloads", "github.com",
+			"Add a 0.0.0.0/0 route to a NAT gateway. IPv6-only subnets need DNS64 and a 64:ff9b::/96 route to a NAT gateway."),
+		network.checkService(cfg.AWSRegion, "S3", []string{"s3"},
+			"Add an S3 gateway endpoint to the subnet's route table, or a 0.0.0.0/0 route to a NAT gateway."),
+		network.checkService(cfg.AWSRegion, "SSM", []string{"ssm", "ssmmessages", "ec2messages"},
+			"Add interface endpoints for ssm, ssmmessages and ec2messages, or a 0.0.0.0/0 route to a NAT gateway."),
+		network.checkHTTPSEgress(),
+	}
+	return checks, nil
+}
+
+func describeRunnerNetwork(ctx context.Context, client *ec2.Client, cfg *Config) (*runnerNetwork, error) {
+	subnets, err := client.DescribeSubnets(ctx, &ec2.DescribeSubnetsInput{SubnetIds: []string{cfg.EC2SubnetID}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe subnet %s: %w", cfg.EC2SubnetID, err)
+	}
+	if len(subnets.Subnets) == 0 {
+		return nil, fmt.Errorf("subnet %s not found", cfg.EC2SubnetID)
+	}
+	network := &runnerNetwork{subnet: subnets.Subnets[0], endpoints: make(map[string]bool)}
+	vpcID := aws.ToString(network.subnet.VpcId)
+
+	// Subnets without an explicit association use the VPC's main route table
+	tables, err := client.DescribeRouteTables(ctx, &ec2.DescribeRouteTablesInput{
+		Filters: []ec2types.Filter{{Name: aws.String("association.subnet-id"), Values: []string{cfg.EC2SubnetID}}},
+	})
+	if err == nil && len(tables.RouteTables) == 0 {
+		tables, err = client.DescribeRouteTables(ctx, &ec2.DescribeRouteTablesInput{
+			Filters: []ec2types.Filter{
+				{Name: aws.String("vpc-id"), Values: []string{vpcID}},
+				{Name: aws.String("association.main"), Values: []string{"true"}},
+			},
+		})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe route tables of %s: %w", cfg.EC2SubnetID, err)
+	}
+	for _, table := range tables.RouteTables {
+		network.routes = append(network.routes, table.Routes...)
+	}
+
+	endpoints, err := client.DescribeVpcEndpoints(ctx, &ec2.DescribeVpcEndpointsInput{
+		Filters: []ec2types.Filter{
+			{Name: aws.String("vpc-id"), Values: []string{vpcID}},
+			{Name: aws.String("vpc-endpoint-state"), Values: []string{"available"}},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe VPC endpoints of %s: %w", vpcID, err)
+	}
+	for _, endpoint := range endpoints.VpcEndpoints {
+		network.endpoints[aws.ToString(endpoint.ServiceName)] = true
+	}
+
+	if len(cfg.EC2SecurityGroupIDs) > 0 {
+		groups, err := client.DescribeSecurityGroups(ctx, &ec2.DescribeSecurityGroupsInput{GroupIds: cfg.EC2SecurityGroupIDs})
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe runner security groups: %w", err)
+		}
+		network.securityGroups = groups.SecurityGroups
+	}
+	return network, nil
+}
+
+// checkHost resolves host and checks that every address has a usable route
+func (n *runnerNetwork) checkHost(ctx context.Context, name, host, hint string) ReachabilityCheck {
+	check := ReachabilityCheck{Name: fmt.Sprintf("%s (%s)", name, host), Hint: hint}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		check.Detail = fmt.Sprintf("cannot resolve from this host: %v", err)
+		return check
+	}
+
+	var via []string
+	for _, addr := range addrs {
+		ip := addr.IP
+		// IPv4-only hosts are reached through NAT64 from IPv6-only subnets
+		if ip.To4() != nil && aws.ToBool(n.subnet.Ipv6Native) {
+			if !aws.ToBool(n.subnet.EnableDns64) {
+				check.Detail = "subnet is IPv6-only and DNS64 is disabled"
+				return check
+			}
+			ip = net.ParseIP("64:ff9b::" + ip.String())
+		}
+		route := n.routeFor(ip)
+		if route == nil {
+			check.Detail = fmt.Sprintf("no route to %s", addr.IP)
+			return check
+		}
+		target := routeTarget(*route)
+		if !n.usableTarget(target, ip) {
+			check.Detail = fmt.Sprintf("%s routes via %s but runners get no public IPv4 address", addr.IP, target)
+			return check
+		}
+		via = append(via, fmt.Sprintf("%s via %s", addr.IP, target))
+	}
+	check.OK = true
+	check.Detail = strings.Join(via, ", ")
+	return check
+}
+
+// checkService checks that the AWS services are reachable through VPC endpoints or the internet
+func (n *runnerNetwork) checkService(region, name string, services []string, hint string) ReachabilityCheck {
+	check := ReachabilityCheck{Name: name, Hint: hint}
+
+	var missing []string
+	for _, service := range services {
+		if !n.endpoints[fmt.Sprintf("com.amazonaws.%s.%s", region, service)] {
+			missing = append(missing, service)
+		}
+	}
+	if len(missing) == 0 {
+		check.OK = true
+		check.Detail = "via VPC endpoints"
+		return check
+	}
+
+	if route := n.routeFor(net.IPv4(1, 1, 1, 1)); route != nil && n.usableTarget(routeTarget(*route), net.IPv4(1, 1, 1, 1)) {
+		check.OK = true
+		check.Detail = fmt.Sprintf("via %s", routeTarget(*route))
+		return check
+	}
+	check.Detail = fmt.Sprintf("no internet route and no VPC endpoint for %s", strings.Join(missing, ", "))
+	return check
+}
+
+// checkHTTPSEgress checks that the runner security groups allow outbound HTTPS
+func (n *runnerNetwork) checkHTTPSEgress() ReachabilityCheck {
+	check := ReachabilityCheck{
+		Name: "Security group HTTPS egress",
+		Hint: "Allow outbound TCP 443 in one of the runner security groups.",
+	}
+	for _, group := range n.securityGroups {
+		for _, rule := range group.IpPermissionsEgress {
+			protocol := aws.ToString(rule.IpProtocol)
+			allPorts := protocol == "-1" || (protocol == "tcp" && rule.FromPort == nil)
+			if allPorts || (protocol == "tcp" && aws.ToInt32(rule.FromPort) <= 443 && aws.ToInt32(rule.ToPort) >= 443) {
+				check.OK = true
+				check.Detail = fmt.Sprintf("allowed by %s", aws.ToString(group.GroupId))
+				return check
+			}
+		}
+	}
+	check.Detail = "no egress rule covers TCP 443"
+	return check
+}
+
+// routeFor returns the most specific active route for ip
+func (n *runnerNetwork) routeFor(ip net.IP) *ec2types.Route {
+	var best *ec2types.Route
+	bestBits := -1
+	for i, route := range n.routes {
+		if route.State == ec2types.RouteStateBlackhole {
+			continue
+		}
+		destination := aws.ToString(route.DestinationCidrBlock)
+		if ip.To4() == nil {
+			destination = aws.ToString(route.DestinationIpv6CidrBlock)
+		}
+		_, cidr, err := net.ParseCIDR(destination)
+		if err != nil || !cidr.Contains(ip) {
+			continue
+		}
+		if bits, _ := cidr.Mask.Size(); bits > bestBits {
+			best, bestBits = &n.routes[i], bits
+		}
+	}
+	return best
+}
+
+// usableTarget reports whether traffic to ip can leave through target. An internet
+// gateway only carries IPv4 for instances with a public address.
+func (n *runnerNetwork) usableTarget(target string, ip net.IP) bool {
+	if strings.HasPrefix(target, "igw-") && ip.To4() != nil {
+		return aws.ToBool(n.subnet.MapPublicIpOnLaunch)
+	}
+	return true
+}
+
+// routeTarget names the gateway, NAT gateway, attachment or interface a route points at
+func routeTarget(route ec2types.Route) string {
+	for _, target := range []*string{
+		route.GatewayId, route.NatGatewayId, route.EgressOnlyInternetGatewayId, route.TransitGatewayId,
+		route.VpcPeeringConnectionId, route.NetworkInterfaceId, route.InstanceId, route.LocalGatewayId,
+	} {
+		if target != nil {
+			return *target
+		}
+	}
+	return "unknown"
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	cwtypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+)
+
+// promNamespace prefixes every metric served on /metrics
+const promNamespace = "ghaec2"
+
+// Prometheus metric types, derived from the CloudWatch unit a metric is recorded with
+const (
+	promCounter = "counter" // counts, e.g. JobsAcquired, exposed with a _total suffix
+	promGauge   = "gauge"   // point-in-time values, e.g. DesiredRunners
+	promSummary = "summary" // durations, exposed as _seconds_sum and _seconds_count
+)
+
+// promSeriesKey identifies a series: the metric and its extra dimensions as labels
+type promSeriesKey struct {
+	name   string
+	labels string // rendered label pairs, e.g. reason="ScaleUp"
+}
+
+// promSeries is the accumulated state of a series
+type promSeries struct {
+	kind  string
+	value float64 // counter total or last gauge value; summary sum in seconds
+	count int64   // summary observations
+}
+
+// observe folds a recorded metric into its Prometheus series. The caller holds m.mu.
+func (m *MetricsPublisher) observe(name string, value float64, unit cwtypes.StandardUnit, extra []cwtypes.Dimension) {
+	labels := make([]string, 0, len(extra))
+	for _, d := range extra {
+		labels = append(labels, fmt.Sprintf("%s=%q", promName(aws.ToString(d.Name)), aws.ToString(d.Value)))
+	}
+	key := promSeriesKey{name: name, labels: strings.Join(labels, ",")}
+
+	series, ok := m.series[key]
+	if !ok {
+		kind := promGauge
+		switch unit {
+		case cwtypes.StandardUnitCount:
+			kind = promCounter
+		case cwtypes.StandardUnitSeconds:
+			kind = promSummary
+		}
+		series = &promSeries{kind: kind}
+		m.series[key] = series
+	}
+
+	switch series.kind {
+	case promCounter:
+		series.value += value
+	case promSummary:
+		series.value += value
+		series.count++
+	default:
+		series.value = value
+	}
+}
+
+// handlePrometheus serves every metric recorded since startup in the Prometheus text format.
+// The ScaleSetName and Region dimensions are added as labels to every series.
+func (m *MetricsPublisher) handlePrometheus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	common := make([]string, 0, len(m.dimensions))
+	for _, d := range m.dimensions {
+		common = append(common, fmt.Sprintf("%s=%q", promName(aws.ToString(d.Name)), aws.ToString(d.Value)))
+	}
+
+	m.mu.Lock()
+	keys := make([]promSeriesKey, 0, len(m.series))
+	for key := range m.series {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].name != keys[j].name {
+			return keys[i].name < keys[j].name
+		}
+		return keys[i].labels < keys[j].labels
+	})
+
+	var body strings.Builder
+	typed := make(map[string]bool)
+	for _, key := range keys {
+		series := m.series[key]
+		labels := strings.Join(append(append([]string{}, common...), key.labels), ",")
+		labels = strings.TrimSuffix(labels, ",")
+
+		name := promNamespace + "_" + promName(key.name)
+		switch series.kind {
+		case promCounter:
+			name += "_total"
+		case promSummary:
+			name += "_seconds"
+		}
+		if !typed[name] {
+			fmt.Fprintf(&body, "# TYPE %s %s\n", name, series.kind)
+			typed[name] = true
+		}
+
+		if series.kind == promSummary {
+			fmt.Fprintf(&body, "%s_sum{%s} %g\n", name, labels, series.value)
+			fmt.Fprintf(&body, "%s_count{%s} %d\n", name, labels, series.count)
+			continue
+		}
+		fmt.Fprintf(&body, "%s{%s} %g\n", name, labels, series.value)
+	}
+	m.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	_, _ = w.Write([]byte(body.String()))
+}
+
+// promName turns a CloudWatch name such as RESTScanThrottled into rest_scan_throttled
+func promName(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (!unicode.IsUpper(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
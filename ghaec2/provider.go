@@ -0,0 +1,13 @@
+package main
+
+import "github.com/Anshuman2121/actionsspot/internal/provider"
+
+// unlimitedCapacity is the capacity reported by providers that set no fixed runner limit
+const unlimitedCapacity = provider.UnlimitedCapacity
+
+// The runner provider types are shared with the Lambda scaler
+type (
+	RunnerSpec        = provider.RunnerSpec
+	ProvisionedRunner = provider.ProvisionedRunner
+	RunnerProvider    = provider.Provider
+)
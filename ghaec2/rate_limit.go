@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Anshuman2121/actionsspot/internal/ratelimit"
+)
+
+const (
+	// secondaryRateLimitWait is used when a secondary rate limit carries no Retry-After
+	secondaryRateLimitWait = time.Minute
+	// maxSecondaryRateLimitWait bounds a single wait, whatever Retry-After asks for
+	maxSecondaryRateLimitWait = 5 * time.Minute
+	// maxSecondaryRateLimitRetries bounds the retries of one request
+	maxSecondaryRateLimitRetries = 3
+)
+
+// doGitHubRequest sends a GitHub API request, waiting out secondary rate limits instead of
+// failing. Waits are bounded by maxSecondaryRateLimitWait, and after
+// maxSecondaryRateLimitRetries the rate-limited response is returned to the caller.
+func (c *ActionsServiceClient) doGitHubRequest(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		wait, limited := ratelimit.Secondary(resp, secondaryRateLimitWait)
+		if !limited {
+			return resp, nil
+		}
+		c.secondaryRateLimits.Add(1)
+		if attempt >= maxSecondaryRateLimitRetries {
+			return resp, nil
+		}
+		resp.Body.Close()
+
+		if wait > maxSecondaryRateLimitWait {
+			wait = maxSecondaryRateLimitWait
+		}
+		c.logger.Info("Hit GitHub secondary rate limit, waiting before retrying",
+			"method", req.Method, "path", req.URL.Path, "wait", wait, "attempt", attempt+1)
+		sleepContext(req.Context(), wait)
+		if err := req.Context().Err(); err != nil {
+			return nil, err
+		}
+
+		if req.Body != nil {
+			if req.GetBody == nil {
+				return nil, fmt.Errorf("cannot retry %s %s after secondary rate limit: body not replayable", req.Method, req.URL.Path)
+			}
+			if req.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// SecondaryRateLimits returns how many secondary rate limit responses the client has received
+func (c *ActionsServiceClient) SecondaryRateLimits() int64 {
+	return c.secondaryRateLimits.Load()
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// recoverRunners reconciles the runner table with the runner provider after a restart. Recorded runners
+// whose instance is still pending or running are adopted into the tracker; the rest were
+// terminated or never finished launching when the previous process died, and are marked removed.
+func (s *MessageQueueScaler) recoverRunners(ctx context.Context) error {
+	records, err := s.runnerStore.ListActive(ctx)
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return nil
+	}
+
+	instanceIDs := make([]string, 0, len(records))
+	for _, record := range records {
+		if record.InstanceID != "" {
+			instanceIDs = append(instanceIDs, record.InstanceID)
+		}
+	}
+	live, err := s.liveInstances(ctx, instanceIDs)
+	if err != nil {
+		return err
+	}
+
+	var adopted int
+	var abandoned []RunnerRecord
+	s.runnerTracker.mu.Lock()
+	for _, record := range records {
+		if _, tracked := s.runnerTracker.instances[record.InstanceID]; tracked {
+			continue
+		}
+		instance, ok := live[record.InstanceID]
+		if !ok {
+			abandoned = append(abandoned, record)
+			continue
+		}
+
+		s.runnerTracker.instances[record.InstanceID] = &EC2RunnerInstance{
+			InstanceID:   record.InstanceID,
+			RunnerName:   record.RunnerName,
+			LaunchTime:   instance.LaunchTime,
+			State:        instance.State,
+			Labels:       literalLabels(s.config.RunnerLabels),
+			LastActivity: time.Now(),
+		}
+		adopted++
+	}
+	s.runnerTracker.mu.Unlock()
+
+	for _, record := range abandoned {
+		if err := s.runnerStore.UpdateStatus(ctx, record.RunnerName, record.InstanceID, runnerStatusRemoved); err != nil {
+			s.logger.Error(err, "Failed to mark abandoned runner removed", "runnerName", record.RunnerName)
+		}
+	}
+
+	s.logger.Info("Recovered runner state", "records", len(records), "adopted", adopted, "abandoned", len(abandoned))
+	return nil
+}
+
+// liveInstances returns the given instances the provider lists as live, keyed by instance ID
+func (s *MessageQueueScaler) liveInstances(ctx context.Context, instanceIDs []string) (map[string]ProvisionedRunner, error) {
+	live := make(map[string]ProvisionedRunner)
+	if len(instanceIDs) == 0 {
+		return live, nil
+	}
+
+	runners, err := s.provider.ListRunners(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, runner := range runners {
+		if slices.Contains(instanceIDs, runner.ID) {
+			live[runner.ID] = runner
+		}
+	}
+	return live, nil
+}
+
+// resumeMessageSession reattaches to the session a crashed process left behind and continues
+// after its last processed message. It reports false when there is no session to resume.
+func (s *MessageQueueScaler) resumeMessageSession(ctx context.Context) bool {
+	record, err := s.sessionStore.Latest(ctx, s.config.RunnerScaleSetID)
+	if err != nil {
+		s.logger.Error(err, "Failed to load persisted message session")
+		return false
+	}
+	if record == nil {
+		return false
+	}
+
+	sessionID, err := uuid.Parse(record.SessionID)
+	if err == nil {
+		var session *RunnerScaleSetSession
+		client, _ := s.connection()
+		session, err = client.RefreshMessageSession(ctx, s.config.RunnerScaleSetID, &sessionID)
+		if err == nil && session.Statistics != nil {
+			if session.RunnerScaleSet == nil {
+				session.RunnerScaleSet = s.currentScaleSet()
+			}
+			s.setSession(session, record.CreatedAt, record.LastMessageID)
+			s.logger.Info("Resumed message session",
+				"sessionId", record.SessionID,
+				"lastMessageID", record.LastMessageID,
+				"sessionAge", time.Since(record.CreatedAt).Round(time.Second))
+			return true
+		}
+	}
+
+	s.logger.Info("Persisted message session cannot be resumed, creating a new one",
+		"sessionId", record.SessionID, "reason", err)
+	if err := s.sessionStore.Delete(ctx, record.SessionID); err != nil {
+		s.logger.Error(err, "Failed to delete stale session record")
+	}
+	return false
+}
+
+// saveSessionState persists the session and the last processed message ID
+func (s *MessageQueueScaler) saveSessionState(ctx context.Context) {
+	s.mu.RLock()
+	session, lastMessageID, createdAt := s.session, s.lastMessageID, s.sessionCreatedAt
+	s.mu.RUnlock()
+	if session == nil || session.SessionID == nil {
+		return
+	}
+
+	err := s.sessionStore.Save(ctx, SessionRecord{
+		SessionID:       session.SessionID.String(),
+		ScaleSetID:      s.config.RunnerScaleSetID,
+		OwnerName:       session.OwnerName,
+		MessageQueueURL: session.MessageQueueURL,
+		LastMessageID:   lastMessageID,
+		CreatedAt:       createdAt,
+		RefreshedAt:     time.Now(),
+	})
+	if err != nil {
+		s.logger.Error(err, "Failed to persist message session")
+	}
+}
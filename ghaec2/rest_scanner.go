@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// restScanMaxPages caps pagination when listing organization repositories
+const restScanMaxPages = 10
+
+// restScanMaxSlowdown caps how far the scan interval is widened during a cool-off
+const restScanMaxSlowdown = 8
+
+// workflowJob is the subset of a REST workflow job the scanner needs
+type workflowJob struct {
+	ID     int64    `json:"id"`
+	Status string   `json:"status"`
+	Labels []string `json:"labels"`
+}
+
+// ListActiveWorkflowJobs returns the queued and in-progress workflow jobs of the organization
+// over the REST API, scanning up to concurrency repositories at once. It is the fallback for
+// servers without runner scale sets.
+func (c *ActionsServiceClient) ListActiveWorkflowJobs(ctx context.Context, org string, repositories []string, concurrency int) ([]*JobAvailable, error) {
+	if len(repositories) == 0 {
+		var err error
+		repositories, err = c.listOrganizationRepositories(ctx, org)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		jobs     []*JobAvailable
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	slots := make(chan struct{}, concurrency)
+	for _, repo := range repositories {
+		owner, name := org, repo
+		if i := strings.Index(repo, "/"); i >= 0 {
+			owner, name = repo[:i], repo[i+1:]
+		}
+
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer func() { <-slots; wg.Done() }()
+			repoJobs, err := c.listRepositoryActiveJobs(ctx, owner, name)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				return
+			}
+			jobs = append(jobs, repoJobs...)
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// listRepositoryActiveJobs returns the queued and in-progress workflow jobs of one repository
+func (c *ActionsServiceClient) listRepositoryActiveJobs(ctx context.Context, owner, name string) ([]*JobAvailable, error) {
+	var jobs []*JobAvailable
+	for _, status := range []string{"queued", "in_progress"} {
+		var runs struct {
+			WorkflowRuns []struct {
+				ID int64 `json:"id"`
+			} `json:"workflow_runs"`
+		}
+		path := fmt.Sprintf("/repos/%s/%s/actions/runs?status=%s&per_page=100", owner, name, status)
+		if err := c.getGitHubJSON(ctx, path, &runs); err != nil {
+			return nil, fmt.Errorf("failed to list %s runs for %s/%s: %w", status, owner, name, err)
+		}
+
+		for _, run := range runs.WorkflowRuns {
+			var runJobs struct {
+				Jobs []workflowJob `json:"jobs"`
+			}
+			path := fmt.Sprintf("/repos/%s/%s/actions/runs/%d/jobs?filter=latest&per_page=100", owner, name, run.ID)
+			if err := c.getGitHubJSON(ctx, path, &runJobs); err != nil {
+				return nil, fmt.Errorf("failed to list jobs for run %d: %w", run.ID, err)
+			}
+
+			for _, job := range runJobs.Jobs {
+				if job.Status != "queued" && job.Status != "in_progress" {
+					continue
+				}
+				jobs = append(jobs, &JobAvailable{
+					MessageType:     job.Status,
+					RunnerRequestID: job.ID,
+					OwnerName:       owner,
+					RepositoryName:  name,
+					RequestLabels:   job.Labels,
+				})
+			}
+		}
+	}
+	return jobs, nil
+}
+
+// listOrganizationRepositories returns the names of the organization's repositories
+func (c *ActionsServiceClient) listOrganizationRepositories(ctx context.Context, org string) ([]string, error) {
+	var names []string
+	for page := 1; page <= restScanMaxPages; page++ {
+		var repos []struct {
+			Name string `json:"name"`
+		}
+		path := fmt.Sprintf("/orgs/%s/repos?per_page=100&page=%d", url.PathEscape(org), page)
+		if err := c.getGitHubJSON(ctx, path, &repos); err != nil {
+			return nil, fmt.Errorf("failed to list repositories: %w", err)
+		}
+
+		for _, repo := range repos {
+			names = append(names, repo.Name)
+		}
+		if len(repos) < 100 {
+			break
+		}
+	}
+	return names, nil
+}
+
+// getGitHubJSON sends an authenticated GET to the GitHub API and decodes the JSON response
+func (c *ActionsServiceClient) getGitHubJSON(ctx context.Context, path string, out interface{}) error {
+	// GitHubAPIURL only sets the path, so the query string is added separately
+	query := ""
+	if i := strings.Index(path, "?"); i >= 0 {
+		path, query = path[:i], path[i+1:]
+	}
+
+	req, err := c.NewGitHubAPIRequest(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return err
+	}
+	req.URL.RawQuery = query
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.token()))
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+
+	resp, err := c.doGitHubRequest(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return c.parseErrorResponse(resp)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// runRESTScanning scales from workflow jobs listed over REST. It is used when the server
+// lacks runner scale sets or message queue long-polling.
+func (s *MessageQueueScaler) runRESTScanning(ctx context.Context) error {
+	s.logger.Info("Starting REST job scanning",
+		"pollInterval", s.config.PollCheckInterval,
+		"concurrency", s.config.RESTScanConcurrency,
+		"repositories", s.config.AllowedRepositories)
+
+	// Backed off while GitHub's abuse detection flags the scan; only touched by this loop
+	concurrency, interval := s.config.RESTScanConcurrency, s.config.PollCheckInterval
+	var cooloffUntil time.Time
+
+	client, _ := s.connection()
+	for {
+		s.heartbeat()
+
+		limitedBefore := client.SecondaryRateLimits()
+		jobs, err := client.ListActiveWorkflowJobs(ctx, s.config.OrganizationName, s.config.AllowedRepositories, concurrency)
+		switch hits := client.SecondaryRateLimits() - limitedBefore; {
+		case hits > 0:
+			if concurrency > 1 {
+				concurrency /= 2
+			}
+			if interval < restScanMaxSlowdown*s.config.PollCheckInterval {
+				interval *= 2
+			}
+			cooloffUntil = time.Now().Add(s.config.RESTScanCooloff)
+			s.logger.Info("REST scan triggered GitHub abuse detection, slowing down; consider lowering REST_SCAN_CONCURRENCY or raising POLL_CHECK_INTERVAL",
+				"rateLimitedRequests", hits, "concurrency", concurrency, "pollInterval", interval,
+				"cooloffUntil", cooloffUntil.Format(time.RFC3339))
+			s.metrics.Count(metricRESTScanThrottled, float64(hits))
+		case !cooloffUntil.IsZero() && time.Now().After(cooloffUntil):
+			concurrency, interval = s.config.RESTScanConcurrency, s.config.PollCheckInterval
+			cooloffUntil = time.Time{}
+			s.logger.Info("REST scan cool-off over, back to the configured pace",
+				"concurrency", concurrency, "pollInterval", interval)
+		}
+		if err != nil {
+			s.logger.Error(err, "Failed to scan workflow jobs, will retry", "backoff", s.config.PollErrorBackoff)
+			s.metrics.Count(metricErrors, 1)
+			sleepContext(ctx, s.config.PollErrorBackoff)
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			continue
+		}
+		s.metrics.Count(metricSuccessfulPolls, 1)
+
+		queued, running := 0, 0
+		for _, job := range jobs {
+			if allowed, _ := s.jobPolicy.Allows(job); !allowed {
+				continue
+			}
+			if job.MessageType == "queued" {
+				queued++
+			} else {
+				running++
+			}
+		}
+
+		s.logger.Info("Scanned workflow jobs", "queued", queued, "inProgress", running, "scanned", len(jobs))
+		s.metrics.Gauge(metricQueuedJobs, float64(queued))
+
+		// Queued and running jobs both need a runner, like assigned jobs in scale set statistics
+		if _, err := s.handleDesiredRunnerCount(ctx, queued+running, 0); err != nil {
+			s.logger.Error(err, "Failed to scale from scanned jobs")
+			s.metrics.Count(metricErrors, 1)
+		}
+		s.deadman.RecordMessageProcessed()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
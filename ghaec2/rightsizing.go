@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// Right-sizing thresholds
+const (
+	// rightsizingMinSamples is the fewest snapshots a recommendation is made from
+	rightsizingMinSamples = 60
+	// rightsizingAtCapRatio is the share of snapshots at MaxRunners with jobs still waiting
+	// from which the cap is considered too low
+	rightsizingAtCapRatio = 0.10
+	// rightsizingIdleRatio is the share of idle registered runners from which, together
+	// with peak demand well under the cap, the cap is considered too high
+	rightsizingIdleRatio = 0.50
+	// rightsizingPeakShare is how far under the cap peak demand must stay to lower it
+	rightsizingPeakShare = 0.60
+	// rightsizingHeadroom is kept above peak demand when lowering the cap
+	rightsizingHeadroom = 1.2
+)
+
+// Right-sizing actions
+const (
+	rightsizingRaise = "raise"
+	rightsizingLower = "lower"
+	rightsizingKeep  = "keep"
+)
+
+// MaxRunnersRecommendation is a right-sizing recommendation for MaxRunners, made from the
+// statistics snapshots of a window
+type MaxRunnersRecommendation struct {
+	Pool           string    `json:"pool"`
+	GeneratedAt    time.Time `json:"generatedAt"`
+	Window         string    `json:"window"`
+	Samples        int       `json:"samples"`
+	MaxRunners     int       `json:"maxRunners"`
+	RecommendedMax int       `json:"recommendedMaxRunners"`
+	Action         string    `json:"action"`
+	Reason         string    `json:"reason"`
+	AtCapRatio     float64   `json:"atCapRatio"`
+	P95Demand      int       `json:"p95Demand"`
+	IdleRatio      float64   `json:"idleRatio"`
+}
+
+// recommendMaxRunners looks for a cap of the pool that is too low (jobs waiting at MaxRunners
+// for a sustained share of the window) or too high (runners mostly idle and peak demand well
+// under the cap). Demand is the number of jobs assigned to the pool's scale set, each of
+// which wants a runner.
+func recommendMaxRunners(pool string, snapshots []StatisticsSnapshot, minRunners, maxRunners int) MaxRunnersRecommendation {
+	rec := MaxRunnersRecommendation{
+		Pool:           pool,
+		GeneratedAt:    time.Now(),
+		Samples:        len(snapshots),
+		MaxRunners:     maxRunners,
+		RecommendedMax: maxRunners,
+		Action:         rightsizingKeep,
+	}
+	if len(snapshots) < rightsizingMinSamples {
+		rec.Reason = fmt.Sprintf("not enough statistics (%d snapshots, need %d)", len(snapshots), rightsizingMinSamples)
+		return rec
+	}
+
+	var demand, excessAtCap []int
+	registered, idle := 0, 0
+	for _, snapshot := range snapshots {
+		demand = append(demand, snapshot.AssignedJobs)
+		if snapshot.CurrentRunners >= maxRunners && snapshot.AssignedJobs > maxRunners {
+			excessAtCap = append(excessAtCap, snapshot.AssignedJobs-maxRunners)
+		}
+		registered += snapshot.RegisteredRunners
+		idle += snapshot.IdleRunners
+	}
+	rec.AtCapRatio = float64(len(excessAtCap)) / float64(len(snapshots))
+	rec.P95Demand = percentileInt(demand, 0.95)
+	if registered > 0 {
+		rec.IdleRatio = float64(idle) / float64(registered)
+	}
+
+	switch {
+	case rec.AtCapRatio >= rightsizingAtCapRatio:
+		rec.Action = rightsizingRaise
+		rec.RecommendedMax = maxRunners + percentileInt(excessAtCap, 0.90)
+		rec.Reason = fmt.Sprintf("jobs waited at the cap in %.0f%% of snapshots", rec.AtCapRatio*100)
+	case rec.IdleRatio >= rightsizingIdleRatio && float64(rec.P95Demand) < rightsizingPeakShare*float64(maxRunners):
+		recommended := int(math.Ceil(float64(rec.P95Demand) * rightsizingHeadroom))
+		if recommended < minRunners {
+			recommended = minRunners
+		}
+		if recommended < 1 {
+			recommended = 1
+		}
+		if recommended < maxRunners {
+			rec.Action = rightsizingLower
+			rec.RecommendedMax = recommended
+			rec.Reason = fmt.Sprintf("%.0f%% of runners idle and p95 demand of %d well under the cap", rec.IdleRatio*100, rec.P95Demand)
+		}
+	}
+	if rec.Reason == "" {
+		rec.Reason = "the cap fits the demand"
+	}
+	return rec
+}
+
+// percentileInt returns the p-th percentile of the values, or 0 for none
+func percentileInt(values []int, p float64) int {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]int{}, values...)
+	sort.Ints(sorted)
+	return sorted[int(p*float64(len(sorted)-1))]
+}
+
+// runRightsizing makes a MaxRunners recommendation for the scaler's pool on the given
+// interval until the context is cancelled. The persisted statistics of the pool's scale set
+// are used when a statistics table is configured, and the pool's in-memory history otherwise.
+func (s *MessageQueueScaler) runRightsizing(ctx context.Context, interval, window time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		since := time.Now().Add(-window)
+		snapshots := s.history.Since(since)
+		if s.statsStore.Enabled() {
+			persisted, err := s.statsStore.Between(ctx, since, time.Now())
+			if err != nil {
+				s.logger.Error(err, "Failed to read persisted statistics, using the in-memory history")
+			} else {
+				snapshots = persisted
+			}
+		}
+
+		s.limitsMu.RLock()
+		minRunners, maxRunners := s.minRunners, s.maxRunners
+		s.limitsMu.RUnlock()
+
+		rec := recommendMaxRunners(s.config.metricsPool(), snapshots, minRunners, maxRunners)
+		rec.Window = window.String()
+		s.mu.Lock()
+		s.maxRunnersRecommendation = &rec
+		s.mu.Unlock()
+
+		s.metrics.PoolGauge(metricRecommendedMaxRunners, float64(rec.RecommendedMax), rec.Pool)
+		s.logger.Info("MaxRunners recommendation",
+			"pool", rec.Pool, "action", rec.Action,
+			"maxRunners", rec.MaxRunners, "recommendedMaxRunners", rec.RecommendedMax,
+			"reason", rec.Reason, "samples", rec.Samples, "window", rec.Window,
+			"atCapRatio", rec.AtCapRatio, "p95Demand", rec.P95Demand, "idleRatio", rec.IdleRatio)
+	}
+}
+
+// latestMaxRunnersRecommendation returns the latest recommendation of the scaler's pool, or
+// nil before the first one
+func (s *MessageQueueScaler) latestMaxRunnersRecommendation() *MaxRunnersRecommendation {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.maxRunnersRecommendation
+}
+
+// handleMaxRunnersRecommendation serves the latest MaxRunners recommendation of the
+// scaler's pool
+func (s *MessageQueueScaler) handleMaxRunnersRecommendation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	rec := s.latestMaxRunnersRecommendation()
+	if rec == nil {
+		http.Error(w, "no recommendation yet", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, []MaxRunnersRecommendation{*rec})
+}
+
+// handlePoolsMaxRunnersRecommendations serves the latest MaxRunners recommendation of every
+// pool of a multi-pool process. Pools without one yet are left out.
+func handlePoolsMaxRunnersRecommendations(scalers []*MessageQueueScaler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		recs := make([]MaxRunnersRecommendation, 0, len(scalers))
+		for _, scaler := range scalers {
+			if rec := scaler.latestMaxRunnersRecommendation(); rec != nil {
+				recs = append(recs, *rec)
+			}
+		}
+		if len(recs) == 0 {
+			http.Error(w, "no recommendation yet", http.StatusNotFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, recs)
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Anshuman2121/actionsspot/internal/runnerrecord"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/go-logr/logr"
+)
+
+// Runner statuses written to the runner table. These match the values used by the Lambda scaler.
+const (
+	runnerStatusPending = runnerrecord.Pending
+	runnerStatusRunning = runnerrecord.Running
+	runnerStatusOffline = runnerrecord.Offline
+	runnerStatusRemoved = runnerrecord.Removed
+)
+
+// runnerManagedBy marks the records written by this scaler, since the table is shared with the Lambda
+const runnerManagedBy = "ghaec2"
+
+// runnerUpdateAttempts bounds how often a status update that lost a race with another writer
+// is retried against the newer version
+const runnerUpdateAttempts = 3
+
+// RunnerStore keeps runner records in the DynamoDB table shared with the Lambda scaler.
+// Items expire through the table's TTL attribute once they have not been updated for the
+// retention, so records of removed runners do not accumulate.
+type RunnerStore struct {
+	client    *dynamodb.Client
+	tableName string
+	retention time.Duration
+	logger    logr.Logger
+}
+
+// NewRunnerStore creates a runner store. A nil client or empty table name disables persistence.
+func NewRunnerStore(client *dynamodb.Client, tableName string, retention time.Duration, logger logr.Logger) *RunnerStore {
+	return &RunnerStore{
+		client:    client,
+		tableName: tableName,
+		retention: retention,
+		logger:    logger,
+	}
+}
+
+// Enabled reports whether runner records are persisted
+func (r *RunnerStore) Enabled() bool {
+	return r.client != nil && r.tableName != ""
+}
+
+// UpdateStatus creates or updates the record for a runner with its latest status. Records
+// carry a version that every write of either scaler increments; the update only applies to
+// the version it read, so a concurrent write is never silently overwritten, and an update
+// that lost the race is re-derived from what the other writer stored. A finished runner's
+// record is never moved back to pending or running.
+func (r *RunnerStore) UpdateStatus(ctx context.Context, runnerName, instanceID, status string) error {
+	if !r.Enabled() {
+		return nil
+	}
+
+	for attempt := 1; ; attempt++ {
+		version, current, err := r.state(ctx, runnerName)
+		if err != nil {
+			return err
+		}
+		if runnerrecord.Reopens(current, status) {
+			return fmt.Errorf("runner %s is already %s in %s, not moving it back to %s", runnerName, current, r.tableName, status)
+		}
+
+		err = r.updateStatus(ctx, runnerName, instanceID, status, version)
+		var conditionFailed *types.ConditionalCheckFailedException
+		if !errors.As(err, &conditionFailed) {
+			return err
+		}
+		if attempt == runnerUpdateAttempts {
+			return fmt.Errorf("runner %s in %s changed concurrently on %d attempts", runnerName, r.tableName, attempt)
+		}
+		r.logger.V(1).Info("Runner record changed since it was read, retrying", "runnerName", runnerName, "version", version)
+	}
+}
+
+// updateStatus writes the status if the record is still at the given version, 0 meaning
+// the record does not exist or predates versioning. Version is a DynamoDB reserved word.
+func (r *RunnerStore) updateStatus(ctx context.Context, runnerName, instanceID, status string, version int64) error {
+	now := time.Now()
+	expression := "SET #status = :status, managed_by = :managed_by, updated_at = :updated_at, created_at = if_not_exists(created_at, :updated_at), expires_at = :expires_at, #version = :next_version"
+	values := map[string]types.AttributeValue{
+		":status":       &types.AttributeValueMemberS{Value: status},
+		":managed_by":   &types.AttributeValueMemberS{Value: runnerManagedBy},
+		":updated_at":   &types.AttributeValueMemberS{Value: now.Format(time.RFC3339)},
+		":expires_at":   &types.AttributeValueMemberN{Value: strconv.FormatInt(now.Add(r.retention).Unix(), 10)},
+		":next_version": &types.AttributeValueMemberN{Value: strconv.FormatInt(version+1, 10)},
+	}
+	if instanceID != "" {
+		expression += ", instance_id = :instance_id"
+		values[":instance_id"] = &types.AttributeValueMemberS{Value: instanceID}
+	}
+	condition := "attribute_not_exists(#version)"
+	if version > 0 {
+		condition = "#version = :version"
+		values[":version"] = &types.AttributeValueMemberN{Value: strconv.FormatInt(version, 10)}
+	}
+
+	_, err := r.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(r.tableName),
+		Key: map[string]types.AttributeValue{
+			"runner_id": &types.AttributeValueMemberS{Value: runnerName},
+		},
+		UpdateExpression:          aws.String(expression),
+		ConditionExpression:       aws.String(condition),
+		ExpressionAttributeNames:  map[string]string{"#status": "status", "#version": "version"},
+		ExpressionAttributeValues: values,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update runner %s in %s: %w", runnerName, r.tableName, err)
+	}
+	return nil
+}
+
+// state returns the version and status of a runner's record with a consistent read, so a
+// retry sees the write it lost to
+func (r *RunnerStore) state(ctx context.Context, runnerName string) (int64, string, error) {
+	output, err := r.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(r.tableName),
+		Key: map[string]types.AttributeValue{
+			"runner_id": &types.AttributeValueMemberS{Value: runnerName},
+		},
+		ProjectionExpression:     aws.String("#version, #status"),
+		ExpressionAttributeNames: map[string]string{"#version": "version", "#status": "status"},
+		ConsistentRead:           aws.Bool(true),
+	})
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to read runner %s from %s: %w", runnerName, r.tableName, err)
+	}
+	var status string
+	if s, ok := output.Item["status"].(*types.AttributeValueMemberS); ok {
+		status = s.Value
+	}
+	if v, ok := output.Item["version"].(*types.AttributeValueMemberN); ok {
+		version, err := strconv.ParseInt(v.Value, 10, 64)
+		return version, status, err
+	}
+	return 0, status, nil
+}
+
+// RunnerRecord is a runner as stored in the runner table
+type RunnerRecord struct {
+	RunnerName string
+	InstanceID string
+	Status     string
+	UpdatedAt  time.Time
+}
+
+// ListActive returns this scaler's runners recorded as pending or running
+func (r *RunnerStore) ListActive(ctx context.Context) ([]RunnerRecord, error) {
+	if !r.Enabled() {
+		return nil, nil
+	}
+
+	var records []RunnerRecord
+	paginator := dynamodb.NewScanPaginator(r.client, &dynamodb.ScanInput{
+		TableName:                aws.String(r.tableName),
+		FilterExpression:         aws.String("managed_by = :managed_by AND #status IN (:pending, :running)"),
+		ExpressionAttributeNames: map[string]string{"#status": "status"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":managed_by": &types.AttributeValueMemberS{Value: runnerManagedBy},
+			":pending":    &types.AttributeValueMemberS{Value: runnerStatusPending},
+			":running":    &types.AttributeValueMemberS{Value: runnerStatusRunning},
+		},
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan runners in %s: %w", r.tableName, err)
+		}
+		for _, item := range page.Items {
+			str := func(name string) string {
+				if v, ok := item[name].(*types.AttributeValueMemberS); ok {
+					return v.Value
+				}
+				return ""
+			}
+			record := RunnerRecord{
+				RunnerName: str("runner_id"),
+				InstanceID: str("instance_id"),
+				Status:     str("status"),
+			}
+			record.UpdatedAt, _ = time.Parse(time.RFC3339, str("updated_at"))
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// runnerSyncLaunchGrace keeps just-launched instances in the tracker while DescribeInstances,
+// which is eventually consistent, may not list them yet
+const runnerSyncLaunchGrace = 2 * time.Minute
+
+// runRunnerSync syncs the runner tracker and the runner table with the provider on the given
+// interval until the context is cancelled
+func (s *MessageQueueScaler) runRunnerSync(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := s.syncRunners(ctx); err != nil {
+			s.logger.Error(err, "Failed to sync runners with the provider")
+			s.metrics.Count(metricErrors, 1)
+		}
+	}
+}
+
+// syncRunners corrects the drift between the tracker and the instances the provider lists.
+// Live instances the tracker misses, such as launches whose tracking was lost, are adopted;
+// tracked instances that are gone, terminated outside the scaler or reclaimed without a
+// lifecycle message, are dropped. The runner table follows both, and records of instances
+// that are gone are marked removed.
+func (s *MessageQueueScaler) syncRunners(ctx context.Context) error {
+	runners, err := s.provider.ListRunners(ctx)
+	if err != nil {
+		return err
+	}
+	live := make(map[string]ProvisionedRunner, len(runners))
+	for _, runner := range runners {
+		live[runner.ID] = runner
+	}
+
+	var adopted []ProvisionedRunner
+	var dropped []*EC2RunnerInstance
+	s.runnerTracker.mu.Lock()
+	for instanceID, instance := range s.runnerTracker.instances {
+		if _, ok := live[instanceID]; ok || time.Since(instance.LaunchTime) < runnerSyncLaunchGrace {
+			continue
+		}
+		delete(s.runnerTracker.instances, instanceID)
+		dropped = append(dropped, instance)
+	}
+	for id, runner := range live {
+		if _, tracked := s.runnerTracker.instances[id]; tracked {
+			continue
+		}
+		s.runnerTracker.instances[id] = &EC2RunnerInstance{
+			InstanceID:   id,
+			RunnerName:   runner.Name,
+			LaunchTime:   runner.LaunchTime,
+			State:        runner.State,
+			Labels:       literalLabels(s.config.RunnerLabels),
+			LastActivity: time.Now(),
+		}
+		adopted = append(adopted, runner)
+	}
+	s.runnerTracker.mu.Unlock()
+
+	for _, instance := range dropped {
+		s.logger.Info("Dropping runner whose instance is gone", "instanceId", instance.InstanceID, "runnerName", instance.RunnerName)
+		if instance.RunnerName == "" {
+			continue
+		}
+		if err := s.runnerStore.UpdateStatus(ctx, instance.RunnerName, instance.InstanceID, runnerStatusRemoved); err != nil {
+			s.logger.Error(err, "Failed to mark runner removed", "runnerName", instance.RunnerName)
+		}
+	}
+	for _, runner := range adopted {
+		s.logger.Info("Adopting untracked runner instance", "instanceId", runner.ID, "runnerName", runner.Name, "state", runner.State)
+		if runner.Name == "" {
+			continue
+		}
+		if err := s.runnerStore.UpdateStatus(ctx, runner.Name, runner.ID, runnerStatusPending); err != nil {
+			s.logger.Error(err, "Failed to record adopted runner", "runnerName", runner.Name)
+		}
+	}
+
+	// Records can outlive their instance without ever having been tracked by this process
+	records, err := s.runnerStore.ListActive(ctx)
+	if err != nil {
+		return err
+	}
+	stale := 0
+	for _, record := range records {
+		if _, ok := live[record.InstanceID]; ok || time.Since(record.UpdatedAt) < runnerSyncLaunchGrace {
+			continue
+		}
+		if err := s.runnerStore.UpdateStatus(ctx, record.RunnerName, record.InstanceID, runnerStatusRemoved); err != nil {
+			s.logger.Error(err, "Failed to mark stale runner record removed", "runnerName", record.RunnerName)
+			continue
+		}
+		stale++
+	}
+
+	s.logger.V(1).Info("Synced runners with the provider",
+		"live", len(live), "adopted", len(adopted), "dropped", len(dropped), "staleRecords", stale)
+	return nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// ScaleDownPolicy dampens scale-downs so the runner count does not thrash with every
+// message: no runners are terminated for SCALE_DOWN_COOLDOWN after a scale-up, the desired
+// count has to stay below the current one for SCALE_DOWN_STABILIZATION consecutive
+// decisions, and at most SCALE_DOWN_MAX_PER_DECISION runners are terminated at once. It is
+// only used by the loop that makes scaling decisions.
+type ScaleDownPolicy struct {
+	cooldown       time.Duration
+	stabilization  int
+	maxPerDecision int // 0 is unlimited
+
+	lastScaleUp     time.Time
+	lowObservations int
+}
+
+// NewScaleDownPolicy creates the scale-down policy of the configuration
+func NewScaleDownPolicy(config *Config) *ScaleDownPolicy {
+	return &ScaleDownPolicy{
+		cooldown:       config.ScaleDownCooldown,
+		stabilization:  config.ScaleDownStabilization,
+		maxPerDecision: config.ScaleDownMaxPerDecision,
+	}
+}
+
+// RecordScaleUp starts the cooldown and resets the low observations
+func (p *ScaleDownPolicy) RecordScaleUp(now time.Time) {
+	p.lastScaleUp = now
+	p.lowObservations = 0
+}
+
+// RecordSteady resets the low observations after a decision that did not want fewer runners
+func (p *ScaleDownPolicy) RecordSteady() {
+	p.lowObservations = 0
+}
+
+// Terminations counts a decision that wants excess fewer runners and returns how many may
+// be terminated now, with the reason when that is fewer than wanted
+func (p *ScaleDownPolicy) Terminations(now time.Time, excess int) (int, string) {
+	p.lowObservations++
+
+	if remaining := p.cooldown - now.Sub(p.lastScaleUp); remaining > 0 {
+		return 0, fmt.Sprintf("cooldown after scale-up, %s left", remaining.Round(time.Second))
+	}
+	if p.lowObservations < p.stabilization {
+		return 0, fmt.Sprintf("low observation %d of %d", p.lowObservations, p.stabilization)
+	}
+	if p.maxPerDecision > 0 && excess > p.maxPerDecision {
+		return p.maxPerDecision, fmt.Sprintf("limited to %d per decision", p.maxPerDecision)
+	}
+	return excess, ""
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// ScalingPolicy is the part of the configuration that can be changed at runtime through
+// AWS AppConfig. Fields that are not set keep the value from the environment.
+type ScalingPolicy struct {
+	MinRunners *int `json:"minRunners,omitempty" yaml:"minRunners,omitempty"`
+	MaxRunners *int `json:"maxRunners,omitempty" yaml:"maxRunners,omitempty"`
+}
+
+// desiredRunnerCount calculates the number of runners wanted for the assigned jobs within
+// the min/max bounds (following actions-runner-controller logic), and the reason reported
+// for the decision
+func desiredRunnerCount(assignedJobs, currentRunners, minRunners, maxRunners int) (int, string) {
+	desiredRunners := assignedJobs
+	switch {
+	case desiredRunners > maxRunners:
+		return maxRunners, scaleReasonCappedAtMax
+	case desiredRunners < minRunners:
+		return minRunners, scaleReasonHeldAtMin
+	case desiredRunners > currentRunners:
+		return desiredRunners, scaleReasonUp
+	case desiredRunners < currentRunners:
+		return desiredRunners, scaleReasonDown
+	}
+	return desiredRunners, scaleReasonNoChange
+}
+
+// policyLimits applies a policy to the min and max runner counts and validates the result
+func policyLimits(minRunners, maxRunners int, policy *ScalingPolicy) (int, int, error) {
+	if policy.MinRunners != nil {
+		minRunners = *policy.MinRunners
+	}
+	if policy.MaxRunners != nil {
+		maxRunners = *policy.MaxRunners
+	}
+	if maxRunners <= 0 || minRunners < 0 || minRunners > maxRunners {
+		return 0, 0, fmt.Errorf("invalid runner limits min=%d max=%d", minRunners, maxRunners)
+	}
+	return minRunners, maxRunners, nil
+}
+
+// AppConfigSource reads the scaling policy from the AWS AppConfig agent, which polls
+// AppConfig and serves the latest deployed configuration on a local endpoint
+type AppConfigSource struct {
+	url        string
+	httpClient *http.Client
+	logger     logr.Logger
+}
+
+// NewAppConfigSource creates an AppConfig source, or returns nil when AppConfig is not configured
+func NewAppConfigSource(config *Config, logger logr.Logger) *AppConfigSource {
+	if config.AppConfigApplication == "" {
+		return nil
+	}
+
+	return &AppConfigSource{
+		url: fmt.Sprintf("%s/applications/%s/environments/%s/configurations/%s",
+			strings.TrimSuffix(config.AppConfigAgentURL, "/"),
+			config.AppConfigApplication, config.AppConfigEnvironment, config.AppConfigProfile),
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		logger:     logger,
+	}
+}
+
+// Fetch returns the deployed scaling policy along with its raw content
+func (a *AppConfigSource) Fetch(ctx context.Context) (*ScalingPolicy, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.url, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to reach AppConfig agent: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read AppConfig response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("AppConfig agent returned HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var policy ScalingPolicy
+	if err := json.Unmarshal(body, &policy); err != nil {
+		return nil, nil, fmt.Errorf("invalid scaling policy: %w", err)
+	}
+	return &policy, body, nil
+}
+
+// scalingLimits returns the current min and max runner counts. The active time window
+// replaces the max, lowering the min with it if needed, and the control item clamps both
+// without ever raising the max.
+func (s *MessageQueueScaler) scalingLimits() (int, int) {
+	s.limitsMu.RLock()
+	defer s.limitsMu.RUnlock()
+	minRunners, maxRunners := s.minRunners, s.maxRunners
+	if s.limitWindow != nil {
+		maxRunners = s.limitWindow.MaxRunners
+		minRunners = min(minRunners, maxRunners)
+	}
+	if s.controlLimits != nil {
+		return clampControlLimits(minRunners, maxRunners, s.controlLimits)
+	}
+	return minRunners, maxRunners
+}
+
+// applyScalingPolicy validates a policy and applies it on top of the environment configuration
+func (s *MessageQueueScaler) applyScalingPolicy(policy *ScalingPolicy) error {
+	s.limitsMu.Lock()
+	defer s.limitsMu.Unlock()
+
+	minRunners, maxRunners, err := policyLimits(s.config.MinRunners, s.config.MaxRunners, policy)
+	if err != nil {
+		return err
+	}
+
+	if minRunners != s.minRunners || maxRunners != s.maxRunners {
+		s.logger.Info("Applying scaling policy from AppConfig",
+			"minRunners", minRunners, "previousMinRunners", s.minRunners,
+			"maxRunners", maxRunners, "previousMaxRunners", s.maxRunners)
+	}
+	s.minRunners, s.maxRunners = minRunners, maxRunners
+	return nil
+}
+
+// watchScalingPolicy polls AppConfig and applies policy changes until the context is cancelled
+func (s *MessageQueueScaler) watchScalingPolicy(ctx context.Context, source *AppConfigSource, interval time.Duration) {
+	var lastContent []byte
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		policy, content, err := source.Fetch(ctx)
+		switch {
+		case err != nil:
+			s.logger.Error(err, "Failed to load scaling policy from AppConfig, keeping current limits")
+		case !bytes.Equal(content, lastContent):
+			if err := s.applyScalingPolicy(policy); err != nil {
+				s.logger.Error(err, "Rejected scaling policy from AppConfig")
+			}
+			lastContent = content
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
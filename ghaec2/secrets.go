@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Anshuman2121/actionsspot/internal/githubapp"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/go-logr/logr"
+)
+
+// SecretValue is a credential read from AWS Secrets Manager at startup and re-read every
+// refresh interval, so a rotated GitHub token is picked up without a restart. A failed
+// refresh keeps the last value.
+type SecretValue struct {
+	arn        string
+	region     string
+	creds      aws.CredentialsProvider
+	signer     *v4.Signer
+	httpClient *http.Client
+	logger     logr.Logger
+
+	mu    sync.RWMutex
+	value string
+}
+
+// NewSecretValue creates the secret read from the given ARN. The secret's region is taken
+// from the ARN, so it may live outside AWS_REGION.
+func NewSecretValue(awsConfig aws.Config, arn string, logger logr.Logger) *SecretValue {
+	region := awsConfig.Region
+	if parts := strings.Split(arn, ":"); len(parts) > 3 && parts[3] != "" {
+		region = parts[3]
+	}
+	return &SecretValue{
+		arn:        arn,
+		region:     region,
+		creds:      awsConfig.Credentials,
+		signer:     v4.NewSigner(),
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		logger:     logger,
+	}
+}
+
+// Get returns the current value of the secret
+func (s *SecretValue) Get() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.value
+}
+
+// Refresh reads the current version of the secret
+func (s *SecretValue) Refresh(ctx context.Context) error {
+	var result struct {
+		SecretString string `json:"SecretString"`
+	}
+	if err := s.call(ctx, "GetSecretValue", map[string]string{"SecretId": s.arn}, &result); err != nil {
+		return err
+	}
+	value := secretToken(result.SecretString)
+	if value == "" {
+		return fmt.Errorf("secret %s is empty", s.arn)
+	}
+
+	s.mu.Lock()
+	changed := s.value != "" && s.value != value
+	s.value = value
+	s.mu.Unlock()
+	if changed {
+		s.logger.Info("Secret was rotated", "secretArn", s.arn)
+	}
+	return nil
+}
+
+// Run refreshes the secret every interval until the context is cancelled
+func (s *SecretValue) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Refresh(ctx); err != nil {
+				s.logger.Error(err, "Failed to refresh secret, keeping the current value", "secretArn", s.arn)
+			}
+		}
+	}
+}
+
+// appTokenRenewBefore is how long before it expires an installation token is renewed
+const appTokenRenewBefore = 10 * time.Minute
+
+// AppToken is an installation token of a GitHub App, created from the App's private key in
+// Secrets Manager and renewed before it expires. A failed renewal keeps the last token,
+// which stays valid until its expiry.
+type AppToken struct {
+	privateKey     *SecretValue
+	apiURL         string
+	appID          int64
+	installationID int64
+	httpClient     *http.Client
+	logger         logr.Logger
+
+	mu    sync.RWMutex
+	token githubapp.Token
+}
+
+// NewAppToken creates the installation token of the configured GitHub App
+func NewAppToken(config *Config, privateKey *SecretValue, logger logr.Logger) *AppToken {
+	return &AppToken{
+		privateKey:     privateKey,
+		apiURL:         config.GitHubEnterpriseURL + "/api/v3",
+		appID:          config.GitHubAppID,
+		installationID: config.GitHubAppInstallationID,
+		httpClient:     &http.Client{Timeout: 30 * time.Second},
+		logger:         logger,
+	}
+}
+
+// Get returns the current installation token
+func (t *AppToken) Get() string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.token.Token
+}
+
+// Refresh re-reads the private key, so a rotated key is used, and creates a new
+// installation token
+func (t *AppToken) Refresh(ctx context.Context) error {
+	if err := t.privateKey.Refresh(ctx); err != nil {
+		return err
+	}
+	key, err := githubapp.ParsePrivateKey([]byte(t.privateKey.Get()))
+	if err != nil {
+		return fmt.Errorf("invalid GitHub App private key: %w", err)
+	}
+	token, err := githubapp.InstallationToken(ctx, t.httpClient, t.apiURL, t.appID, t.installationID, key)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	t.token = token
+	t.mu.Unlock()
+	t.logger.Info("Created GitHub App installation token", "expiresAt", token.ExpiresAt)
+	return nil
+}
+
+// Run renews the token every interval when it would otherwise expire before the next one,
+// until the context is cancelled
+func (t *AppToken) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			t.mu.RLock()
+			expiring := t.token.Expiring(time.Now(), interval+appTokenRenewBefore)
+			t.mu.RUnlock()
+			if !expiring {
+				continue
+			}
+			if err := t.Refresh(ctx); err != nil {
+				t.logger.Error(err, "Failed to renew GitHub App installation token, keeping the current one")
+			}
+		}
+	}
+}
+
+// gitHubToken returns the GitHub token: an installation token of the GitHub App when one is
+// configured, the current value of GITHUB_TOKEN_SECRET_ARN when one is set, GITHUB_TOKEN
+// otherwise
+func (c *Config) gitHubToken() string {
+	if c.gitHubAppToken != nil {
+		return c.gitHubAppToken.Get()
+	}
+	if c.gitHubTokenSecret != nil {
+		return c.gitHubTokenSecret.Get()
+	}
+	return c.GitHubToken
+}
+
+// secretToken returns the token stored in a secret: the secret string itself, or the
+// "token" key of a key/value secret as the Secrets Manager console creates them
+func secretToken(secretString string) string {
+	var fields map[string]string
+	if err := json.Unmarshal([]byte(secretString), &fields); err == nil {
+		return fields["token"]
+	}
+	return strings.TrimSpace(secretString)
+}
+
+// call invokes a Secrets Manager operation with a signed JSON request
+func (s *SecretValue) call(ctx context.Context, operation string, input, output interface{}) error {
+	payload, err := json.Marshal(input)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s request: %w", operation, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("https://secretsmanager.%s.amazonaws.com/", s.region), bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager."+operation)
+
+	credentials, err := s.creds.Retrieve(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve credentials: %w", err)
+	}
+	sum := sha256.Sum256(payload)
+	if err := s.signer.SignHTTP(ctx, credentials, req, hex.EncodeToString(sum[:]), "secretsmanager", s.region, time.Now()); err != nil {
+		return fmt.Errorf("failed to sign request: %w", err)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("secrets manager %s failed (HTTP %d): %s", operation, resp.StatusCode, string(body))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(output); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", operation, err)
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/go-logr/logr"
+)
+
+// SessionRecord is the persisted form of the message session. It uses the item layout of
+// the Lambda scaler's sessions table, plus the last processed message ID.
+type SessionRecord struct {
+	SessionID       string
+	ScaleSetID      int
+	OwnerName       string
+	MessageQueueURL string
+	LastMessageID   int64
+	CreatedAt       time.Time
+	RefreshedAt     time.Time
+}
+
+// SessionStore keeps the message session in DynamoDB so a restarted process can resume it.
+// Items expire through the table's TTL attribute once the session has not been refreshed
+// for the retention.
+type SessionStore struct {
+	client    *dynamodb.Client
+	tableName string
+	retention time.Duration
+	logger    logr.Logger
+}
+
+// NewSessionStore creates a session store. A nil client or empty table name disables persistence.
+func NewSessionStore(client *dynamodb.Client, tableName string, retention time.Duration, logger logr.Logger) *SessionStore {
+	return &SessionStore{
+		client:    client,
+		tableName: tableName,
+		retention: retention,
+		logger:    logger,
+	}
+}
+
+// Enabled reports whether sessions are persisted
+func (s *SessionStore) Enabled() bool {
+	return s.client != nil && s.tableName != ""
+}
+
+// Latest returns the most recently refreshed session stored for the scale set, or nil.
+// The table only holds a handful of items, so a filtered scan is sufficient.
+func (s *SessionStore) Latest(ctx context.Context, scaleSetID int) (*SessionRecord, error) {
+	if !s.Enabled() {
+		return nil, nil
+	}
+
+	output, err := s.client.Scan(ctx, &dynamodb.ScanInput{
+		TableName:        aws.String(s.tableName),
+		FilterExpression: aws.String("scale_set_id = :scale_set_id"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":scale_set_id": &types.AttributeValueMemberN{Value: strconv.Itoa(scaleSetID)},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan sessions in %s: %w", s.tableName, err)
+	}
+
+	var latest *SessionRecord
+	for _, item := range output.Items {
+		record := sessionRecordFromItem(item)
+		if latest == nil || record.RefreshedAt.After(latest.RefreshedAt) {
+			latest = &record
+		}
+	}
+	return latest, nil
+}
+
+// Save creates or replaces a session record
+func (s *SessionStore) Save(ctx context.Context, record SessionRecord) error {
+	if !s.Enabled() {
+		return nil
+	}
+
+	_, err := s.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(s.tableName),
+		Item: map[string]types.AttributeValue{
+			"session_id":        &types.AttributeValueMemberS{Value: record.SessionID},
+			"scale_set_id":      &types.AttributeValueMemberN{Value: strconv.Itoa(record.ScaleSetID)},
+			"owner_name":        &types.AttributeValueMemberS{Value: record.OwnerName},
+			"message_queue_url": &types.AttributeValueMemberS{Value: record.MessageQueueURL},
+			"last_message_id":   &types.AttributeValueMemberN{Value: strconv.FormatInt(record.LastMessageID, 10)},
+			"created_at":        &types.AttributeValueMemberS{Value: record.CreatedAt.Format(time.RFC3339)},
+			"refreshed_at":      &types.AttributeValueMemberS{Value: record.RefreshedAt.Format(time.RFC3339)},
+			"expires_at":        &types.AttributeValueMemberN{Value: strconv.FormatInt(record.RefreshedAt.Add(s.retention).Unix(), 10)},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save session %s in %s: %w", record.SessionID, s.tableName, err)
+	}
+	return nil
+}
+
+// Delete removes a session record
+func (s *SessionStore) Delete(ctx context.Context, sessionID string) error {
+	if !s.Enabled() {
+		return nil
+	}
+
+	_, err := s.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(s.tableName),
+		Key: map[string]types.AttributeValue{
+			"session_id": &types.AttributeValueMemberS{Value: sessionID},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete session %s from %s: %w", sessionID, s.tableName, err)
+	}
+	return nil
+}
+
+func sessionRecordFromItem(item map[string]types.AttributeValue) SessionRecord {
+	str := func(name string) string {
+		if v, ok := item[name].(*types.AttributeValueMemberS); ok {
+			return v.Value
+		}
+		return ""
+	}
+	num := func(name string) int64 {
+		if v, ok := item[name].(*types.AttributeValueMemberN); ok {
+			n, _ := strconv.ParseInt(v.Value, 10, 64)
+			return n
+		}
+		return 0
+	}
+
+	record := SessionRecord{
+		SessionID:       str("session_id"),
+		ScaleSetID:      int(num("scale_set_id")),
+		OwnerName:       str("owner_name"),
+		MessageQueueURL: str("message_queue_url"),
+		LastMessageID:   num("last_message_id"),
+	}
+	record.CreatedAt, _ = time.Parse(time.RFC3339, str("created_at"))
+	record.RefreshedAt, _ = time.Parse(time.RFC3339, str("refreshed_at"))
+	return record
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+// runSimulate runs the desired-runner calculation against a statistics snapshot and a
+// scaling policy and prints what the scaler would do, without touching GHES or AWS. The
+// statistics file holds one RunnerScaleSetStatistic object, as found in session and message
+// payloads, or an array of them that is replayed in order. The policy file uses the
+// AppConfig scaling policy format, in YAML or JSON.
+func runSimulate(args []string, cfg *Config, stdout io.Writer) int {
+	flags := flag.NewFlagSet("simulate", flag.ContinueOnError)
+	statsPath := flags.String("stats", "", "statistics snapshot JSON file (required)")
+	policyPath := flags.String("policy", "", "scaling policy YAML or JSON file; defaults to MIN_RUNNERS/MAX_RUNNERS")
+	current := flags.Int("current", -1, "runners running before the first snapshot; defaults to its registered runners")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *statsPath == "" {
+		fmt.Fprintln(os.Stderr, "simulate needs --stats")
+		flags.Usage()
+		return 2
+	}
+
+	snapshots, err := loadStatisticsSnapshots(*statsPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	policy := &ScalingPolicy{}
+	if *policyPath != "" {
+		if policy, err = loadScalingPolicyFile(*policyPath); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+	minRunners, maxRunners, err := policyLimits(cfg.MinRunners, cfg.MaxRunners, policy)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	currentRunners := *current
+	if currentRunners < 0 {
+		currentRunners = snapshots[0].TotalRegisteredRunners
+	}
+
+	fmt.Fprintf(stdout, "Limits: min=%d max=%d\n\n", minRunners, maxRunners)
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "#\tASSIGNED\tRUNNING\tCURRENT\tDESIRED\tREASON\tACTION")
+	for i, stats := range snapshots {
+		desired, reason := desiredRunnerCount(stats.TotalAssignedJobs, currentRunners, minRunners, maxRunners)
+		action := "none"
+		switch {
+		case desired > currentRunners:
+			action = fmt.Sprintf("launch %d runners", desired-currentRunners)
+		case desired < currentRunners:
+			action = fmt.Sprintf("terminate up to %d idle runners", currentRunners-desired)
+		}
+		fmt.Fprintf(w, "%d\t%d\t%d\t%d\t%d\t%s\t%s\n",
+			i+1, stats.TotalAssignedJobs, stats.TotalRunningJobs, currentRunners, desired, reason, action)
+		currentRunners = desired
+	}
+	w.Flush()
+	return 0
+}
+
+// loadStatisticsSnapshots reads one statistics object or an array of them
+func loadStatisticsSnapshots(path string) ([]RunnerScaleSetStatistic, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read statistics: %w", err)
+	}
+
+	var snapshots []RunnerScaleSetStatistic
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(trimmed, &snapshots)
+	} else {
+		var snapshot RunnerScaleSetStatistic
+		err = json.Unmarshal(trimmed, &snapshot)
+		snapshots = append(snapshots, snapshot)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid statistics in %s: %w", path, err)
+	}
+	if len(snapshots) == 0 {
+		return nil, fmt.Errorf("no statistics in %s", path)
+	}
+	return snapshots, nil
+}
+
+// loadScalingPolicyFile reads a scaling policy. YAML is a superset of JSON, so policies
+// exported from AppConfig load as they are.
+func loadScalingPolicyFile(path string) (*ScalingPolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy: %w", err)
+	}
+
+	var policy ScalingPolicy
+	if err := yaml.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("invalid policy in %s: %w", path, err)
+	}
+	return &policy, nil
+}
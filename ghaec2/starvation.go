@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/go-logr/logr"
+)
+
+// Causes of queue starvation, published as the Cause dimension of QueueStarvation
+const (
+	starvationCausePolicyCap    = "PolicyCap"
+	starvationCauseProvisioning = "ProvisioningBroken"
+)
+
+// StarvationMonitor alerts when jobs have been queued longer than a threshold while the
+// scaler cannot add runners. It tells a full scale set, which is the configured cap doing
+// its job, from launches that keep failing, which needs someone to fix provisioning.
+type StarvationMonitor struct {
+	threshold       time.Duration
+	snsClient       *sns.Client
+	topicARN        string
+	slackWebhookURL string
+	httpClient      *http.Client
+	scaleSet        string
+	metrics         *MetricsPublisher
+	logger          logr.Logger
+
+	mu sync.Mutex
+	// Launches that failed since the last successful one
+	failedLaunches int
+	// Cause of the alert currently raised, empty when none is
+	alertedCause string
+}
+
+// NewStarvationMonitor creates a starvation monitor. Notifications go to the SNS topic
+// and/or the Slack webhook that are configured.
+func NewStarvationMonitor(config *Config, snsClient *sns.Client, metrics *MetricsPublisher, logger logr.Logger) *StarvationMonitor {
+	return &StarvationMonitor{
+		threshold:       config.StarvationThreshold,
+		snsClient:       snsClient,
+		topicARN:        config.StarvationSNSTopicARN,
+		slackWebhookURL: config.StarvationSlackWebhookURL,
+		httpClient:      &http.Client{Timeout: 10 * time.Second},
+		scaleSet:        config.RunnerScaleSetName,
+		metrics:         metrics,
+		logger:          logger,
+	}
+}
+
+// RecordLaunches records the outcome of a scale up
+func (m *StarvationMonitor) RecordLaunches(launched, failed int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if launched > 0 {
+		m.failedLaunches = 0
+	}
+	m.failedLaunches += failed
+}
+
+// Check evaluates starvation after a scaling decision, given how long the oldest job has
+// been waiting and whether the decision was capped at the max runners
+func (m *StarvationMonitor) Check(ctx context.Context, oldestWait time.Duration, cappedAtMax bool, maxRunners int) {
+	m.mu.Lock()
+	cause := ""
+	switch {
+	case oldestWait <= m.threshold:
+	case m.failedLaunches > 0:
+		cause = starvationCauseProvisioning
+	case cappedAtMax:
+		cause = starvationCausePolicyCap
+	}
+	previous := m.alertedCause
+	m.alertedCause = cause
+	failedLaunches := m.failedLaunches
+	m.mu.Unlock()
+
+	m.metrics.Gauge(metricOldestQueuedJobSeconds, oldestWait.Seconds())
+	if cause != "" {
+		m.metrics.QueueStarvation(cause)
+	}
+
+	switch {
+	case cause == previous:
+		return
+	case cause == "":
+		m.logger.Info("Queue starvation cleared", "previousCause", previous, "oldestWait", oldestWait.Round(time.Second))
+		return
+	}
+
+	var message string
+	if cause == starvationCauseProvisioning {
+		message = fmt.Sprintf("Jobs for scale set %s have been queued for %s while the last %d runner launch(es) failed. "+
+			"Provisioning is broken; check the scaler logs for launch errors (capacity, quotas, AMI, IAM).",
+			m.scaleSet, oldestWait.Round(time.Second), failedLaunches)
+	} else {
+		message = fmt.Sprintf("Jobs for scale set %s have been queued for %s while the scaler is at its limit of %d runners. "+
+			"Provisioning works; raise MAX_RUNNERS (or the active limit window or control item) if this wait is not acceptable.",
+			m.scaleSet, oldestWait.Round(time.Second), maxRunners)
+	}
+	m.logger.Error(nil, "Queue starvation", "cause", cause, "oldestWait", oldestWait.Round(time.Second),
+		"threshold", m.threshold, "failedLaunches", failedLaunches, "maxRunners", maxRunners)
+
+	if err := m.notify(ctx, cause, message); err != nil {
+		m.logger.Error(err, "Failed to send queue starvation notification")
+	}
+}
+
+func (m *StarvationMonitor) notify(ctx context.Context, cause, message string) error {
+	if m.snsClient != nil && m.topicARN != "" {
+		_, err := m.snsClient.Publish(ctx, &sns.PublishInput{
+			TopicArn: aws.String(m.topicARN),
+			Subject:  aws.String(fmt.Sprintf("ghaec2 queue starvation (%s): %s", cause, m.scaleSet)),
+			Message:  aws.String(message),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to publish to SNS: %w", err)
+		}
+	}
+
+	if m.slackWebhookURL != "" {
+		payload, _ := json.Marshal(map[string]string{"text": fmt.Sprintf(":hourglass: *Queue starvation (%s)*\n%s", cause, message)})
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.slackWebhookURL, bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := m.httpClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to post to Slack: %w", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("Slack webhook returned HTTP %d", resp.StatusCode)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// StatisticsSnapshot is a point-in-time record of queue depth and runner counts
+type StatisticsSnapshot struct {
+	Timestamp         time.Time `json:"timestamp"`
+	AvailableJobs     int       `json:"availableJobs"`
+	AcquiredJobs      int       `json:"acquiredJobs"`
+	AssignedJobs      int       `json:"assignedJobs"`
+	RunningJobs       int       `json:"runningJobs"`
+	RegisteredRunners int       `json:"registeredRunners"`
+	BusyRunners       int       `json:"busyRunners"`
+	IdleRunners       int       `json:"idleRunners"`
+	CurrentRunners    int       `json:"currentRunners"`
+}
+
+// StatisticsHistory keeps the most recent snapshots in a fixed-size ring buffer
+type StatisticsHistory struct {
+	mu        sync.RWMutex
+	snapshots []StatisticsSnapshot
+	next      int
+	full      bool
+}
+
+// NewStatisticsHistory creates a history that holds up to size snapshots
+func NewStatisticsHistory(size int) *StatisticsHistory {
+	return &StatisticsHistory{
+		snapshots: make([]StatisticsSnapshot, size),
+	}
+}
+
+// Add records a snapshot, overwriting the oldest one when the buffer is full
+func (h *StatisticsHistory) Add(snapshot StatisticsSnapshot) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.snapshots[h.next] = snapshot
+	h.next = (h.next + 1) % len(h.snapshots)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// Since returns the snapshots taken at or after the given time, oldest first
+func (h *StatisticsHistory) Since(since time.Time) []StatisticsSnapshot {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	ordered := h.snapshots[:h.next]
+	if h.full {
+		ordered = append(append([]StatisticsSnapshot{}, h.snapshots[h.next:]...), h.snapshots[:h.next]...)
+	}
+
+	result := make([]StatisticsSnapshot, 0, len(ordered))
+	for _, snapshot := range ordered {
+		if !snapshot.Timestamp.Before(since) {
+			result = append(result, snapshot)
+		}
+	}
+	return result
+}
+
+// recordStatisticsHistory samples the latest statistics into the history on the given interval,
+// persisting each snapshot when a statistics table is configured.
+// Sampling on a timer rather than per message keeps quiet periods visible in the history.
+func (s *MessageQueueScaler) recordStatisticsHistory(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.mu.RLock()
+			stats := s.lastStatistics
+			s.mu.RUnlock()
+			if stats == nil {
+				continue
+			}
+
+			currentRunners, err := s.getCurrentRunnerCount(ctx)
+			if err != nil {
+				s.logger.Error(err, "Failed to get current runner count for statistics history")
+			}
+
+			snapshot := StatisticsSnapshot{
+				Timestamp:         time.Now(),
+				AvailableJobs:     stats.TotalAvailableJobs,
+				AcquiredJobs:      stats.TotalAcquiredJobs,
+				AssignedJobs:      stats.TotalAssignedJobs,
+				RunningJobs:       stats.TotalRunningJobs,
+				RegisteredRunners: stats.TotalRegisteredRunners,
+				BusyRunners:       stats.TotalBusyRunners,
+				IdleRunners:       stats.TotalIdleRunners,
+				CurrentRunners:    currentRunners,
+			}
+			s.history.Add(snapshot)
+
+			if err := s.statsStore.Put(ctx, snapshot); err != nil {
+				s.logger.Error(err, "Failed to persist statistics snapshot")
+			}
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/go-logr/logr"
+)
+
+// StatisticsStore persists statistics snapshots to a DynamoDB table keyed by scale set and time.
+// Items expire through the table's TTL attribute so the table does not grow without bound.
+type StatisticsStore struct {
+	client    *dynamodb.Client
+	tableName string
+	scaleSet  string
+	retention time.Duration
+	logger    logr.Logger
+}
+
+// NewStatisticsStore creates a statistics store. A nil client or empty table name disables persistence.
+func NewStatisticsStore(client *dynamodb.Client, config *Config, logger logr.Logger) *StatisticsStore {
+	return &StatisticsStore{
+		client:    client,
+		tableName: config.StatsTableName,
+		scaleSet:  config.RunnerScaleSetName,
+		retention: config.StatsRetention,
+		logger:    logger,
+	}
+}
+
+// Enabled reports whether snapshots are persisted
+func (st *StatisticsStore) Enabled() bool {
+	return st.client != nil && st.tableName != ""
+}
+
+// Put writes a snapshot to the table
+func (st *StatisticsStore) Put(ctx context.Context, snapshot StatisticsSnapshot) error {
+	if !st.Enabled() {
+		return nil
+	}
+
+	number := func(v int) types.AttributeValue {
+		return &types.AttributeValueMemberN{Value: strconv.Itoa(v)}
+	}
+
+	_, err := st.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(st.tableName),
+		Item: map[string]types.AttributeValue{
+			"scale_set_name":     &types.AttributeValueMemberS{Value: st.scaleSet},
+			"timestamp":          &types.AttributeValueMemberN{Value: strconv.FormatInt(snapshot.Timestamp.Unix(), 10)},
+			"available_jobs":     number(snapshot.AvailableJobs),
+			"acquired_jobs":      number(snapshot.AcquiredJobs),
+			"assigned_jobs":      number(snapshot.AssignedJobs),
+			"running_jobs":       number(snapshot.RunningJobs),
+			"registered_runners": number(snapshot.RegisteredRunners),
+			"busy_runners":       number(snapshot.BusyRunners),
+			"idle_runners":       number(snapshot.IdleRunners),
+			"current_runners":    number(snapshot.CurrentRunners),
+			"expires_at":         &types.AttributeValueMemberN{Value: strconv.FormatInt(snapshot.Timestamp.Add(st.retention).Unix(), 10)},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put statistics snapshot to %s: %w", st.tableName, err)
+	}
+
+	return nil
+}
+
+// Between returns the snapshots taken in [from, to], oldest first
+func (st *StatisticsStore) Between(ctx context.Context, from, to time.Time) ([]StatisticsSnapshot, error) {
+	paginator := dynamodb.NewQueryPaginator(st.client, &dynamodb.QueryInput{
+		TableName:              aws.String(st.tableName),
+		KeyConditionExpression: aws.String("scale_set_name = :scale_set_name AND #timestamp BETWEEN :from AND :to"),
+		ExpressionAttributeNames: map[string]string{
+			"#timestamp": "timestamp",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":scale_set_name": &types.AttributeValueMemberS{Value: st.scaleSet},
+			":from":           &types.AttributeValueMemberN{Value: strconv.FormatInt(from.Unix(), 10)},
+			":to":             &types.AttributeValueMemberN{Value: strconv.FormatInt(to.Unix(), 10)},
+		},
+	})
+
+	var snapshots []StatisticsSnapshot
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query statistics snapshots in %s: %w", st.tableName, err)
+		}
+		for _, item := range page.Items {
+			number := func(name string) int {
+				if v, ok := item[name].(*types.AttributeValueMemberN); ok {
+					n, _ := strconv.Atoi(v.Value)
+					return n
+				}
+				return 0
+			}
+			snapshots = append(snapshots, StatisticsSnapshot{
+				Timestamp:         time.Unix(int64(number("timestamp")), 0),
+				AvailableJobs:     number("available_jobs"),
+				AcquiredJobs:      number("acquired_jobs"),
+				AssignedJobs:      number("assigned_jobs"),
+				RunningJobs:       number("running_jobs"),
+				RegisteredRunners: number("registered_runners"),
+				BusyRunners:       number("busy_runners"),
+				IdleRunners:       number("idle_runners"),
+				CurrentRunners:    number("current_runners"),
+			})
+		}
+	}
+	return snapshots, nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Supervise runs the scaler and restarts it when Run fails, re-initializing the Actions
+// Service client and message session each time. Restarts back off exponentially from
+// SUPERVISOR_INITIAL_BACKOFF up to SUPERVISOR_MAX_BACKOFF. A run that stays up for
+// SUPERVISOR_STABLE_AFTER resets the backoff and the crash count; after
+// SUPERVISOR_MAX_RESTARTS consecutive crashes the last error is returned.
+func (s *MessageQueueScaler) Supervise(ctx context.Context) error {
+	backoff := s.config.SupervisorInitialBackoff
+	crashes := 0
+
+	for {
+		startedAt := time.Now()
+		err := s.Run(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err == nil {
+			err = fmt.Errorf("scaler stopped unexpectedly")
+		}
+
+		if time.Since(startedAt) >= s.config.SupervisorStableAfter {
+			backoff = s.config.SupervisorInitialBackoff
+			crashes = 0
+		}
+		crashes++
+		s.metrics.Count(metricListenerRestarts, 1)
+
+		if crashes > s.config.SupervisorMaxRestarts {
+			return fmt.Errorf("giving up after %d consecutive crashes: %w", crashes, err)
+		}
+
+		s.logger.Error(err, "Message queue scaler crashed, restarting",
+			"crash", crashes,
+			"maxRestarts", s.config.SupervisorMaxRestarts,
+			"backoff", backoff,
+			"uptime", time.Since(startedAt).Round(time.Second))
+
+		// Cleared by the first heartbeat of the restarted loop
+		s.restarting.Store(true)
+		sleepContext(ctx, backoff)
+		if ctx.Err() != nil {
+			return nil
+		}
+		s.resetConnection()
+
+		backoff *= 2
+		if backoff > s.config.SupervisorMaxBackoff {
+			backoff = s.config.SupervisorMaxBackoff
+		}
+	}
+}
+
+// resetConnection drops the Actions Service client and session state so the next Run
+// starts from a fresh connection
+func (s *MessageQueueScaler) resetConnection() {
+	actionsClient := NewActionsServiceClient(s.config.GitHubEnterpriseURL, s.config.gitHubToken, s.tracer, s.logger.WithName("actions-client"))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.actionsClient = actionsClient
+	s.scaleSet = nil
+	s.session = nil
+	s.sessionCreatedAt = time.Time{}
+	s.lastMessageID = 0
+}
+
+// connection returns the current Actions Service client and message session
+func (s *MessageQueueScaler) connection() (*ActionsServiceClient, *RunnerScaleSetSession) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.actionsClient, s.session
+}
+
+// currentScaleSet returns the scale set of the current connection, nil before it is initialized
+func (s *MessageQueueScaler) currentScaleSet() *RunnerScaleSet {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.scaleSet
+}
+
+// messageCursor returns the ID of the last processed message
+func (s *MessageQueueScaler) messageCursor() int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.lastMessageID
+}
+
+// setSession records a created or resumed message session
+func (s *MessageQueueScaler) setSession(session *RunnerScaleSetSession, createdAt time.Time, lastMessageID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.session = session
+	s.sessionCreatedAt = createdAt
+	s.lastMessageID = lastMessageID
+}
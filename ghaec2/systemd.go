@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// sdNotify sends a state update such as READY=1 to systemd. It is a no-op when the
+// process was not started by systemd with Type=notify.
+func sdNotify(state string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+	// Abstract namespace sockets are announced with a leading @
+	if socket[0] == '@' {
+		socket = "\x00" + socket[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.Write([]byte(state))
+	return err
+}
+
+// watchdogInterval returns the WatchdogSec configured for this service, or 0 when the
+// watchdog is disabled or meant for another process
+func watchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond
+}
+
+// notifySystemd reports READY=1 once the polling loop of every scaler is running or its
+// replica stands by for leadership, then pings the systemd watchdog at half its interval for
+// as long as the loops keep heartbeating. A wedged loop, in any pool, stops the pings and
+// systemd restarts the service. Supervisor restarts count as alive.
+func notifySystemd(ctx context.Context, scalers []*MessageQueueScaler, logger logr.Logger) {
+	if os.Getenv("NOTIFY_SOCKET") == "" {
+		return
+	}
+
+	started := func() bool {
+		for _, s := range scalers {
+			if s.LastHeartbeat().UnixNano() == 0 && !s.standby.Load() {
+				return false
+			}
+		}
+		return true
+	}
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for !started() {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+
+	if err := sdNotify("READY=1"); err != nil {
+		logger.Error(err, "Failed to notify systemd")
+	}
+
+	interval := watchdogInterval()
+	if interval <= 0 {
+		return
+	}
+	logger.Info("systemd watchdog enabled", "interval", interval)
+	ticker.Reset(interval / 2)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		alive := true
+		for _, s := range scalers {
+			if s.stalled(interval) {
+				s.logger.Info("Polling loop stalled, withholding systemd watchdog ping",
+					"lastHeartbeat", s.LastHeartbeat())
+				alive = false
+			}
+		}
+		if !alive {
+			continue
+		}
+		if err := sdNotify("WATCHDOG=1"); err != nil {
+			logger.Error(err, "Failed to ping systemd watchdog")
+		}
+	}
+}
@@ -1,5 +1,5 @@
 terraform {
-  required_version = ">= 1.0"
+  required_version = ">= 1.1"
   required_providers {
     aws = {
       source  = "hashicorp/aws"
@@ -109,7 +109,7 @@ resource "aws_iam_role_policy" "scaler_policy" {
         Action = [
           "ec2:DescribeInstances",
           "ec2:DescribeSpotInstanceRequests",
-          "ec2:RequestSpotInstances",
+          "ec2:RunInstances",
           "ec2:TerminateInstances",
           "ec2:CreateTags",
           "ec2:DescribeSpotPriceHistory",
@@ -118,7 +118,44 @@ resource "aws_iam_role_policy" "scaler_policy" {
           "ec2:DescribeKeyPairs",
           "ec2:DescribeSecurityGroups",
           "ec2:DescribeSubnets",
-          "ec2:DescribeVpcs"
+          "ec2:DescribeVpcs",
+          "ec2:DescribeRouteTables",
+          "ec2:DescribeVpcEndpoints"
+        ]
+        Resource = "*"
+      },
+      {
+        Effect = "Allow"
+        Action = [
+          "cloudwatch:PutMetricData",
+          "cloudwatch:PutMetricAlarm",
+          "sns:Publish"
+        ]
+        Resource = "*"
+      },
+      {
+        Effect = "Allow"
+        Action = [
+          "dynamodb:GetItem",
+          "dynamodb:PutItem",
+          "dynamodb:UpdateItem",
+          "dynamodb:DeleteItem",
+          "dynamodb:Query",
+          "dynamodb:Scan"
+        ]
+        Resource = [
+          "arn:aws:dynamodb:*:*:table/github-runners*",
+          aws_dynamodb_table.scaler_statistics.arn,
+          aws_dynamodb_table.scaler_decisions.arn,
+          aws_dynamodb_table.scaler_control.arn,
+          aws_dynamodb_table.scaler_leases.arn
+        ]
+      },
+      {
+        Effect = "Allow"
+        Action = [
+          "appconfig:StartConfigurationSession",
+          "appconfig:GetLatestConfiguration"
         ]
         Resource = "*"
       },
@@ -137,6 +174,126 @@ resource "aws_iam_role_policy" "scaler_policy" {
   })
 }
 
+# DynamoDB table for scale statistics snapshots (expired through TTL)
+resource "aws_dynamodb_table" "scaler_statistics" {
+  name         = "ghaec2-scaler-statistics"
+  billing_mode = "PAY_PER_REQUEST"
+  hash_key     = "scale_set_name"
+  range_key    = "timestamp"
+
+  attribute {
+    name = "scale_set_name"
+    type = "S"
+  }
+
+  attribute {
+    name = "timestamp"
+    type = "N"
+  }
+
+  ttl {
+    attribute_name = "expires_at"
+    enabled        = true
+  }
+
+  tags = {
+    Name = "ghaec2-scaler-statistics"
+    Type = "ghaec2-scaler"
+  }
+}
+
+# DynamoDB table for the scaling decision history (expired through TTL)
+resource "aws_dynamodb_table" "scaler_decisions" {
+  name         = "ghaec2-scaler-decisions"
+  billing_mode = "PAY_PER_REQUEST"
+  hash_key     = "scale_set_name"
+  range_key    = "timestamp"
+
+  attribute {
+    name = "scale_set_name"
+    type = "S"
+  }
+
+  attribute {
+    name = "timestamp"
+    type = "N"
+  }
+
+  ttl {
+    attribute_name = "expires_at"
+    enabled        = true
+  }
+
+  tags = {
+    Name = "ghaec2-scaler-decisions"
+    Type = "ghaec2-scaler"
+  }
+}
+
+# DynamoDB table for the runtime overrides (paused, min_runners, max_runners) of each scale set.
+# It is the former maintenance table under its original name, so its pause items carry over.
+moved {
+  from = aws_dynamodb_table.scaler_maintenance
+  to   = aws_dynamodb_table.scaler_control
+}
+
+resource "aws_dynamodb_table" "scaler_control" {
+  name         = "ghaec2-scaler-maintenance"
+  billing_mode = "PAY_PER_REQUEST"
+  hash_key     = "scale_set_name"
+
+  attribute {
+    name = "scale_set_name"
+    type = "S"
+  }
+
+  tags = {
+    Name = "ghaec2-scaler-maintenance"
+    Type = "ghaec2-scaler"
+  }
+}
+
+# DynamoDB table for the leader lease of each scale set, when running several replicas
+resource "aws_dynamodb_table" "scaler_leases" {
+  name         = "ghaec2-scaler-leases"
+  billing_mode = "PAY_PER_REQUEST"
+  hash_key     = "scale_set_name"
+
+  attribute {
+    name = "scale_set_name"
+    type = "S"
+  }
+
+  ttl {
+    attribute_name = "expires_at"
+    enabled        = true
+  }
+
+  tags = {
+    Name = "ghaec2-scaler-leases"
+    Type = "ghaec2-scaler"
+  }
+}
+
+# Read access to the GitHub token or GitHub App private key secret, re-read periodically so
+# rotations are picked up
+resource "aws_iam_role_policy" "scaler_github_token" {
+  count = var.github_token_secret_arn != "" || var.github_app_private_key_secret_arn != "" ? 1 : 0
+  name  = "ghaec2-github-token"
+  role  = aws_iam_role.scaler_role.id
+
+  policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [
+      {
+        Effect   = "Allow"
+        Action   = ["secretsmanager:GetSecretValue"]
+        Resource = compact([var.github_token_secret_arn, var.github_app_private_key_secret_arn])
+      }
+    ]
+  })
+}
+
 resource "aws_iam_instance_profile" "scaler_profile" {
   name = "ghaec2-scaler-profile"
   role = aws_iam_role.scaler_role.name
@@ -179,7 +336,7 @@ locals {
     key_pair_name         = local.key_pair_name
     instance_type         = var.runner_instance_type
     ami_id                = var.runner_ami_id
-    spot_price            = var.spot_price
+    spot_prices           = join(",", [for instance_type, price in var.spot_prices : "${instance_type}=${price}"])
   }))
 }
 
@@ -68,19 +68,29 @@ output "ssh_command" {
 output "environment_variables" {
   description = "Environment variables for the .env file"
   value = {
-    GITHUB_TOKEN           = "SENSITIVE - Set manually"
+    GITHUB_TOKEN           = var.github_token_secret_arn != "" || var.github_app_private_key_secret_arn != "" ? "" : "SENSITIVE - Set manually"
+    GITHUB_TOKEN_SECRET_ARN = var.github_token_secret_arn
+    GITHUB_APP_ID           = var.github_app_id
+    GITHUB_APP_INSTALLATION_ID = var.github_app_installation_id
+    GITHUB_APP_PRIVATE_KEY_SECRET_ARN = var.github_app_private_key_secret_arn
     GITHUB_ENTERPRISE_URL  = var.github_enterprise_url
     ORGANIZATION_NAME      = var.organization_name
     RUNNER_LABELS          = join(",", var.runner_labels)
+    EXCLUDED_LABELS        = join(",", var.excluded_labels)
+    RUNNER_NAME_TEMPLATE   = var.runner_name_template
     MIN_RUNNERS           = var.min_runners
     MAX_RUNNERS           = var.max_runners
     RUNNER_SCALE_SET_NAME = var.runner_scale_set_name
     AWS_REGION            = var.aws_region
     EC2_SUBNET_ID         = local.subnet_id
     EC2_SECURITY_GROUP_ID = aws_security_group.runners.id
+    EC2_SECURITY_GROUP_IDS = join(",", var.additional_security_group_ids)
     EC2_KEY_PAIR_NAME     = local.key_pair_name
     EC2_INSTANCE_TYPE     = var.runner_instance_type
     EC2_AMI_ID            = data.aws_ami.ubuntu.id
-    EC2_SPOT_PRICE        = var.spot_price
+    ON_DEMAND_ONLY        = var.on_demand_only
+    EC2_LAUNCH_TEMPLATE_ID = var.launch_template_id
+    EC2_LAUNCH_TEMPLATE_VERSION = var.launch_template_version
+    EC2_SPOT_PRICES       = join(",", [for instance_type, price in var.spot_prices : "${instance_type}=${price}"])
   }
 } 
\ No newline at end of file
@@ -30,6 +30,31 @@ variable "github_token" {
   description = "GitHub personal access token with admin:org permissions"
   type        = string
   sensitive   = true
+  default     = ""
+}
+
+variable "github_token_secret_arn" {
+  description = "ARN of a Secrets Manager secret holding the GitHub token, read by the scaler instead of github_token"
+  type        = string
+  default     = ""
+}
+
+variable "github_app_id" {
+  description = "ID of a GitHub App to authenticate as instead of a token (with github_app_installation_id and github_app_private_key_secret_arn)"
+  type        = string
+  default     = ""
+}
+
+variable "github_app_installation_id" {
+  description = "ID of the GitHub App's installation on the organization"
+  type        = string
+  default     = ""
+}
+
+variable "github_app_private_key_secret_arn" {
+  description = "ARN of a Secrets Manager secret holding the GitHub App's PEM private key"
+  type        = string
+  default     = ""
 }
 
 variable "github_enterprise_url" {
@@ -51,6 +76,18 @@ variable "runner_labels" {
   default     = ["self-hosted", "linux", "x64", "ghalistener-managed"]
 }
 
+variable "excluded_labels" {
+  description = "Jobs carrying any of these labels are never acquired (e.g. no-spot, external-runner)"
+  type        = list(string)
+  default     = []
+}
+
+variable "runner_name_template" {
+  description = "Runner and EC2 instance name template; placeholders {prefix} {scaleset} {pool} {id} {timestamp}, {id} is required"
+  type        = string
+  default     = "{prefix}-{pool}-{timestamp}-{id}"
+}
+
 variable "min_runners" {
   description = "Minimum number of runners"
   type        = number
@@ -88,10 +125,34 @@ variable "runner_ami_id" {
   default     = "ami-00841acd225d6edb8"
 }
 
-variable "spot_price" {
-  description = "Maximum spot price for runner instances"
+variable "additional_security_group_ids" {
+  description = "Security groups attached to runners in addition to the runner security group (e.g. per-team groups)"
+  type        = list(string)
+  default     = []
+}
+
+variable "on_demand_only" {
+  description = "Launch runners as on-demand instances only, for accounts where spot is not allowed"
+  type        = bool
+  default     = false
+}
+
+variable "launch_template_id" {
+  description = "EC2 launch template providing the runners' AMI, block devices, IAM profile and metadata options (optional)"
   type        = string
-  default     = "0.05"
+  default     = ""
+}
+
+variable "launch_template_version" {
+  description = "Launch template version: a number, $Latest or $Default; empty uses the template's default version"
+  type        = string
+  default     = ""
+}
+
+variable "spot_prices" {
+  description = "Maximum spot price per runner instance type (\"default\" covers the rest); unlisted types bid up to the on-demand price"
+  type        = map(string)
+  default     = {}
 }
 
 variable "create_elastic_ip" {
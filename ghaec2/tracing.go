@@ -0,0 +1,357 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/smithy-go/middleware"
+	"github.com/go-logr/logr"
+)
+
+// maxPendingSpans bounds the spans buffered between exports; the oldest are dropped first
+const maxPendingSpans = 4096
+
+// traceparentHeader carries the W3C trace context of a request
+const traceparentHeader = "traceparent"
+
+// OTLP span kinds and status codes
+const (
+	spanKindInternal = 1
+	spanKindClient   = 3
+	statusCodeError  = 2
+)
+
+// Tracer records OpenTelemetry spans of the polling loop, scaling decisions and the
+// Actions Service and AWS calls they make, and exports them in batches to an OTLP/HTTP
+// collector. A nil Tracer records nothing, so tracing costs nothing when it is disabled.
+type Tracer struct {
+	endpoint    string // collector base URL; spans are posted to /v1/traces
+	serviceName string
+	client      *http.Client
+	logger      logr.Logger
+
+	mu      sync.Mutex
+	pending []*Span
+}
+
+// NewTracer creates a tracer exporting to the OTLP/HTTP endpoint, or returns nil when no
+// endpoint is configured
+func NewTracer(endpoint, serviceName string, logger logr.Logger) *Tracer {
+	if endpoint == "" {
+		return nil
+	}
+	return &Tracer{
+		endpoint:    strings.TrimSuffix(endpoint, "/"),
+		serviceName: serviceName,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		logger:      logger,
+	}
+}
+
+// Span is one timed operation of a trace
+type Span struct {
+	tracer     *Tracer
+	traceID    [16]byte
+	spanID     [8]byte
+	parentID   [8]byte // zero for root spans
+	name       string
+	kind       int
+	start      time.Time
+	end        time.Time
+	attributes []interface{} // alternating keys and values, like logr
+	err        error
+}
+
+type spanContextKey struct{}
+
+// Start begins a span as a child of the span in ctx, with keysAndValues as attributes.
+// The returned context carries the new span for the calls made under it.
+func (t *Tracer) Start(ctx context.Context, name string, keysAndValues ...interface{}) (context.Context, *Span) {
+	return t.start(ctx, name, spanKindInternal, keysAndValues)
+}
+
+func (t *Tracer) start(ctx context.Context, name string, kind int, keysAndValues []interface{}) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+	span := &Span{
+		tracer:     t,
+		name:       name,
+		kind:       kind,
+		start:      time.Now(),
+		attributes: keysAndValues,
+	}
+	if parent, ok := ctx.Value(spanContextKey{}).(*Span); ok && parent != nil {
+		span.traceID = parent.traceID
+		span.parentID = parent.spanID
+	} else {
+		_, _ = rand.Read(span.traceID[:])
+	}
+	_, _ = rand.Read(span.spanID[:])
+	return context.WithValue(ctx, spanContextKey{}, span), span
+}
+
+// Extract returns a context whose spans continue the trace of a W3C traceparent header
+// value, such as one received from a caller or stored with a runner. An invalid or empty
+// value leaves the context as it is.
+func (t *Tracer) Extract(ctx context.Context, traceparent string) context.Context {
+	if t == nil {
+		return ctx
+	}
+	traceID, spanID, ok := parseTraceparent(traceparent)
+	if !ok {
+		return ctx
+	}
+	// The remote parent is never finished, so it is not exported
+	return context.WithValue(ctx, spanContextKey{}, &Span{traceID: traceID, spanID: spanID})
+}
+
+// parseTraceparent parses a traceparent header value: version-traceid-parentid-flags, with
+// lowercase hex fields. Unknown versions are read as version 00, as the spec asks.
+func parseTraceparent(value string) (traceID [16]byte, spanID [8]byte, ok bool) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return traceID, spanID, false
+	}
+	if strings.ToLower(parts[1]) != parts[1] || strings.ToLower(parts[2]) != parts[2] {
+		return traceID, spanID, false
+	}
+	if _, err := hex.Decode(traceID[:], []byte(parts[1])); err != nil {
+		return traceID, spanID, false
+	}
+	if _, err := hex.Decode(spanID[:], []byte(parts[2])); err != nil {
+		return traceID, spanID, false
+	}
+	if traceID == [16]byte{} || spanID == [8]byte{} {
+		return traceID, spanID, false
+	}
+	return traceID, spanID, true
+}
+
+// Traceparent returns the W3C traceparent header value that makes the span the parent of
+// a remote operation, or "" for a nil span. Every recorded span is sampled.
+func (s *Span) Traceparent() string {
+	if s == nil {
+		return ""
+	}
+	return "00-" + hex.EncodeToString(s.traceID[:]) + "-" + hex.EncodeToString(s.spanID[:]) + "-01"
+}
+
+// Record adds a span that has already finished, such as the time a job waited in the
+// queue before a runner picked it up
+func (t *Tracer) Record(ctx context.Context, name string, start, end time.Time, keysAndValues ...interface{}) {
+	_, span := t.start(ctx, name, spanKindInternal, keysAndValues)
+	if span == nil {
+		return
+	}
+	span.start = start
+	span.finish(end, nil)
+}
+
+// SetAttributes adds attributes to the span
+func (s *Span) SetAttributes(keysAndValues ...interface{}) {
+	if s == nil {
+		return
+	}
+	s.attributes = append(s.attributes, keysAndValues...)
+}
+
+// End finishes the span, marking it failed when err is not nil
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+	s.finish(time.Now(), err)
+}
+
+func (s *Span) finish(end time.Time, err error) {
+	s.end, s.err = end, err
+
+	t := s.tracer
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.pending) >= maxPendingSpans {
+		t.pending = t.pending[1:]
+	}
+	t.pending = append(t.pending, s)
+}
+
+// Transport wraps an HTTP transport so every request becomes a client span whose trace
+// context is sent in the traceparent header. Only the host and path are recorded, as query
+// strings may carry tokens.
+func (t *Tracer) Transport(base http.RoundTripper) http.RoundTripper {
+	if t == nil {
+		return base
+	}
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		_, span := t.start(req.Context(), "HTTP "+req.Method+" "+req.URL.Host, spanKindClient, []interface{}{
+			"http.request.method", req.Method,
+			"server.address", req.URL.Host,
+			"url.path", req.URL.Path,
+		})
+		// A round tripper must not modify the request it is given
+		req = req.Clone(req.Context())
+		req.Header.Set(traceparentHeader, span.Traceparent())
+		resp, err := base.RoundTrip(req)
+		spanErr := err
+		if err == nil {
+			span.SetAttributes("http.response.status_code", resp.StatusCode)
+			if resp.StatusCode >= 400 {
+				spanErr = fmt.Errorf("HTTP %d", resp.StatusCode)
+			}
+		}
+		span.End(spanErr)
+		return resp, err
+	})
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+// AWSMiddleware adds a client span for every AWS API call, named after the service and
+// operation (e.g. EC2.RunInstances). Install it with config.WithAPIOptions.
+func (t *Tracer) AWSMiddleware(stack *middleware.Stack) error {
+	return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("ghaec2Tracing",
+		func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+			service, operation := awsmiddleware.GetServiceID(ctx), awsmiddleware.GetOperationName(ctx)
+			ctx, span := t.start(ctx, service+"."+operation, spanKindClient, []interface{}{
+				"rpc.system", "aws-api",
+				"rpc.service", service,
+				"rpc.method", operation,
+			})
+			out, metadata, err := next.HandleInitialize(ctx, in)
+			span.End(err)
+			return out, metadata, err
+		}), middleware.After)
+}
+
+// Flush exports the buffered spans
+func (t *Tracer) Flush(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	spans := t.pending
+	t.pending = nil
+	t.mu.Unlock()
+	if len(spans) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(t.otlpRequest(spans))
+	if err != nil {
+		return fmt.Errorf("failed to encode spans: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint+"/v1/traces", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to export %d spans: %w", len(spans), err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("failed to export %d spans: HTTP %d", len(spans), resp.StatusCode)
+	}
+	return nil
+}
+
+// Run exports buffered spans on the given interval until the context is cancelled
+func (t *Tracer) Run(ctx context.Context, interval time.Duration) {
+	if t == nil {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// Final export so the spans of the last decisions are not lost on shutdown
+			flushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+			if err := t.Flush(flushCtx); err != nil {
+				t.logger.Error(err, "Failed to export spans on shutdown")
+			}
+			cancel()
+			return
+		case <-ticker.C:
+			if err := t.Flush(ctx); err != nil {
+				t.logger.Error(err, "Failed to export spans")
+			}
+		}
+	}
+}
+
+// otlpRequest builds the OTLP/HTTP JSON export request for the spans
+func (t *Tracer) otlpRequest(spans []*Span) map[string]interface{} {
+	otlpSpans := make([]map[string]interface{}, 0, len(spans))
+	for _, s := range spans {
+		span := map[string]interface{}{
+			"traceId":           hex.EncodeToString(s.traceID[:]),
+			"spanId":            hex.EncodeToString(s.spanID[:]),
+			"name":              s.name,
+			"kind":              s.kind,
+			"startTimeUnixNano": strconv.FormatInt(s.start.UnixNano(), 10),
+			"endTimeUnixNano":   strconv.FormatInt(s.end.UnixNano(), 10),
+			"attributes":        otlpAttributes(s.attributes),
+		}
+		if s.parentID != [8]byte{} {
+			span["parentSpanId"] = hex.EncodeToString(s.parentID[:])
+		}
+		if s.err != nil {
+			span["status"] = map[string]interface{}{"code": statusCodeError, "message": s.err.Error()}
+		}
+		otlpSpans = append(otlpSpans, span)
+	}
+
+	return map[string]interface{}{
+		"resourceSpans": []interface{}{map[string]interface{}{
+			"resource": map[string]interface{}{
+				"attributes": otlpAttributes([]interface{}{"service.name", t.serviceName}),
+			},
+			"scopeSpans": []interface{}{map[string]interface{}{
+				"scope": map[string]interface{}{"name": "ghaec2"},
+				"spans": otlpSpans,
+			}},
+		}},
+	}
+}
+
+// otlpAttributes converts alternating keys and values to OTLP key-value attributes
+func otlpAttributes(keysAndValues []interface{}) []map[string]interface{} {
+	attributes := make([]map[string]interface{}, 0, len(keysAndValues)/2)
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		var value map[string]interface{}
+		switch v := keysAndValues[i+1].(type) {
+		case string:
+			value = map[string]interface{}{"stringValue": v}
+		case bool:
+			value = map[string]interface{}{"boolValue": v}
+		case int:
+			value = map[string]interface{}{"intValue": strconv.Itoa(v)}
+		case int64:
+			value = map[string]interface{}{"intValue": strconv.FormatInt(v, 10)}
+		case float64:
+			value = map[string]interface{}{"doubleValue": v}
+		default:
+			value = map[string]interface{}{"stringValue": fmt.Sprint(v)}
+		}
+		attributes = append(attributes, map[string]interface{}{"key": fmt.Sprint(keysAndValues[i]), "value": value})
+	}
+	return attributes
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Scaling sources
+const (
+	// scalingSourceMessageQueue listens on the scale set message queue, or scans workflow
+	// jobs over REST on servers without one
+	scalingSourceMessageQueue = "message-queue"
+	// scalingSourceWebhook scales from workflow_job webhooks delivered to /webhook
+	scalingSourceWebhook = "webhook"
+)
+
+// webhookMaxPayload caps the size of a webhook delivery; GitHub sends at most 25 MB
+const webhookMaxPayload = 25 << 20
+
+// webhookJobRetention is how long a job is counted without a completed event, so a lost
+// delivery does not keep a runner around forever
+const webhookJobRetention = 24 * time.Hour
+
+// workflowJobEvent is the subset of a workflow_job webhook the scaler needs
+type workflowJobEvent struct {
+	Action      string `json:"action"` // queued, waiting, in_progress or completed
+	WorkflowJob struct {
+		ID        int64     `json:"id"`
+		Labels    []string  `json:"labels"`
+		CreatedAt time.Time `json:"created_at"`
+		StartedAt time.Time `json:"started_at"`
+	} `json:"workflow_job"`
+	Repository struct {
+		Name  string `json:"name"`
+		Owner struct {
+			Login string `json:"login"`
+		} `json:"owner"`
+	} `json:"repository"`
+}
+
+// handleWorkflowJobWebhook receives workflow_job webhooks, checks their signature against
+// WEBHOOK_SECRET and hands the jobs passing the job policy to the webhook loop. A standby
+// replica runs no webhook loop, so it rejects deliveries at once and GitHub's redelivery or
+// the load balancer's health check sends them to the leader.
+func (s *MessageQueueScaler) handleWorkflowJobWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.standby.Load() {
+		http.Error(w, "standby replica", http.StatusServiceUnavailable)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, webhookMaxPayload))
+	if err != nil {
+		http.Error(w, "failed to read payload", http.StatusBadRequest)
+		return
+	}
+	if !validWebhookSignature(body, r.Header.Get("X-Hub-Signature-256"), s.config.WebhookSecret) {
+		s.logger.Info("Rejected webhook with an invalid signature", "remoteAddr", r.RemoteAddr,
+			"delivery", r.Header.Get("X-GitHub-Delivery"))
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Header.Get("X-GitHub-Event") {
+	case "ping":
+		writeJSON(w, http.StatusOK, map[string]interface{}{"status": "pong"})
+		return
+	case "workflow_job":
+	default:
+		writeJSON(w, http.StatusAccepted, map[string]interface{}{"status": "ignored"})
+		return
+	}
+
+	var event workflowJobEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		http.Error(w, "invalid workflow_job payload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	job := &JobAvailable{
+		MessageType:     event.Action,
+		RunnerRequestID: event.WorkflowJob.ID,
+		OwnerName:       event.Repository.Owner.Login,
+		RepositoryName:  event.Repository.Name,
+		RequestLabels:   event.WorkflowJob.Labels,
+		QueueTime:       event.WorkflowJob.CreatedAt,
+	}
+	if allowed, reason := s.jobPolicy.Allows(job); !allowed {
+		s.logger.V(1).Info("Ignoring workflow job", "jobId", job.RunnerRequestID, "action", event.Action, "reason", reason)
+		if event.Action == "queued" {
+			s.metrics.Count(metricJobsSkipped, 1)
+		}
+		writeJSON(w, http.StatusAccepted, map[string]interface{}{"status": "ignored", "reason": reason})
+		return
+	}
+
+	select {
+	case s.webhookEvents <- event:
+		writeJSON(w, http.StatusAccepted, map[string]interface{}{"status": "accepted"})
+	case <-r.Context().Done():
+		http.Error(w, "scaler busy", http.StatusServiceUnavailable)
+	}
+}
+
+// validWebhookSignature checks the X-Hub-Signature-256 HMAC of the payload
+func validWebhookSignature(body []byte, signature, secret string) bool {
+	hexDigest, ok := strings.CutPrefix(signature, "sha256=")
+	if !ok {
+		return false
+	}
+	expected, err := hex.DecodeString(hexDigest)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// runWebhookReceiver scales from workflow_job webhooks instead of the message queue, for
+// servers where the scale set message API is not available. Queued and running jobs both
+// need a runner; a completed event frees theirs. Jobs are counted from the events seen
+// since startup, so a restart forgets jobs queued before it until they change state.
+func (s *MessageQueueScaler) runWebhookReceiver(ctx context.Context) error {
+	s.logger.Info("Starting webhook receiver", "path", "/webhook", "checkInterval", s.config.PollCheckInterval)
+
+	type webhookJob struct {
+		seen    time.Time
+		running bool
+	}
+	active := make(map[int64]*webhookJob) // by workflow job ID
+	ticker := time.NewTicker(s.config.PollCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		s.heartbeat()
+
+		completed := 0
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case event := <-s.webhookEvents:
+			id := event.WorkflowJob.ID
+			switch event.Action {
+			case "queued":
+				if _, ok := active[id]; !ok {
+					active[id] = &webhookJob{seen: time.Now()}
+				}
+				s.jobLatency.JobQueued(id, event.WorkflowJob.CreatedAt)
+			case "in_progress":
+				if _, ok := active[id]; !ok {
+					active[id] = &webhookJob{seen: time.Now()}
+				}
+				active[id].running = true
+				if latency, ok := s.jobLatency.JobStarted(id, event.WorkflowJob.CreatedAt, event.WorkflowJob.StartedAt); ok {
+					s.metrics.Duration(metricJobStartLatency, latency, s.config.metricsPool())
+				}
+			case "completed":
+				if _, ok := active[id]; ok {
+					delete(active, id)
+					completed++
+				}
+				s.jobLatency.JobDropped(id)
+			default:
+				// Jobs waiting on an environment approval need no runner yet
+				continue
+			}
+			s.logger.Info("Workflow job event", "jobId", id, "action", event.Action,
+				"repository", event.Repository.Owner.Login+"/"+event.Repository.Name, "activeJobs", len(active))
+		case <-ticker.C:
+			for id, job := range active {
+				if time.Since(job.seen) > webhookJobRetention {
+					s.logger.Info("Forgetting workflow job without a completed event", "jobId", id, "seen", job.seen)
+					delete(active, id)
+					s.jobLatency.JobDropped(id)
+				}
+			}
+		case <-s.reconcileRequests:
+		}
+
+		queued := 0
+		for _, job := range active {
+			if !job.running {
+				queued++
+			}
+		}
+		s.metrics.Gauge(metricQueuedJobs, float64(queued))
+		if _, err := s.handleDesiredRunnerCount(ctx, len(active), completed); err != nil {
+			s.logger.Error(err, "Failed to scale from workflow job events")
+			s.metrics.Count(metricErrors, 1)
+		}
+		s.deadman.RecordMessageProcessed()
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+)
+
+const testWebhookSecret = "webhook-secret"
+
+const testWorkflowJobPayload = `{"action":"%s","workflow_job":{"id":42,"labels":["self-hosted","linux"]},` +
+	`"repository":{"name":"app","owner":{"login":"acme"}}}`
+
+func newWebhookTestScaler() *MessageQueueScaler {
+	cfg := &Config{
+		RunnerScaleSetName: "ghaec2-scaler",
+		RunnerLabels:       []string{"self-hosted", "linux"},
+		ScalingSource:      scalingSourceWebhook,
+		WebhookSecret:      testWebhookSecret,
+	}
+	metrics := NewMetricsPublisher(nil, "test", cfg.RunnerScaleSetName, "eu-north-1", logr.Discard())
+	return NewMessageQueueScaler(cfg, nil, metrics, nil, nil, nil, nil, nil, nil, nil, logr.Discard())
+}
+
+func signWebhook(body, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestHandleWorkflowJobWebhook(t *testing.T) {
+	queued := strings.Replace(testWorkflowJobPayload, "%s", "queued", 1)
+	tests := []struct {
+		name      string
+		event     string
+		body      string
+		signature string
+		standby   bool
+		want      int
+		wantEvent bool
+	}{
+		{"valid signature", "workflow_job", queued, signWebhook(queued, testWebhookSecret), false, http.StatusAccepted, true},
+		{"bad signature", "workflow_job", queued, signWebhook(queued, "other-secret"), false, http.StatusUnauthorized, false},
+		{"missing signature", "workflow_job", queued, "", false, http.StatusUnauthorized, false},
+		{"malformed signature", "workflow_job", queued, "sha256=not-hex", false, http.StatusUnauthorized, false},
+		{"unknown event", "push", queued, signWebhook(queued, testWebhookSecret), false, http.StatusAccepted, false},
+		{"standby replica", "workflow_job", queued, signWebhook(queued, testWebhookSecret), true, http.StatusServiceUnavailable, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newWebhookTestScaler()
+			s.standby.Store(tt.standby)
+
+			req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(tt.body))
+			req.Header.Set("X-GitHub-Event", tt.event)
+			if tt.signature != "" {
+				req.Header.Set("X-Hub-Signature-256", tt.signature)
+			}
+			rec := httptest.NewRecorder()
+			s.handleWorkflowJobWebhook(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body.String())
+			}
+			if got := len(s.webhookEvents) == 1; got != tt.wantEvent {
+				t.Errorf("event handed to the webhook loop = %v, want %v", got, tt.wantEvent)
+			}
+		})
+	}
+}
+
+func TestHandleWorkflowJobWebhookUnknownAction(t *testing.T) {
+	s := newWebhookTestScaler()
+	body := strings.Replace(testWorkflowJobPayload, "%s", "requested", 1)
+	req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+	req.Header.Set("X-GitHub-Event", "workflow_job")
+	req.Header.Set("X-Hub-Signature-256", signWebhook(body, testWebhookSecret))
+	rec := httptest.NewRecorder()
+	s.handleWorkflowJobWebhook(rec, req)
+
+	// The webhook loop ignores actions other than queued, in_progress and completed
+	if rec.Code != http.StatusAccepted {
+		t.Errorf("status = %d, want %d: %s", rec.Code, http.StatusAccepted, rec.Body.String())
+	}
+	if event := <-s.webhookEvents; event.Action != "requested" || event.WorkflowJob.ID != 42 {
+		t.Errorf("event = %+v, want the requested job 42", event)
+	}
+}
+
+func TestValidWebhookSignature(t *testing.T) {
+	body := []byte(`{"action":"queued"}`)
+	valid := signWebhook(string(body), testWebhookSecret)
+	tests := []struct {
+		name      string
+		signature string
+		want      bool
+	}{
+		{"valid", valid, true},
+		{"wrong secret", signWebhook(string(body), "other-secret"), false},
+		{"missing", "", false},
+		{"sha1 prefix", strings.Replace(valid, "sha256=", "sha1=", 1), false},
+		{"not hex", "sha256=zz", false},
+	}
+	for _, tt := range tests {
+		if got := validWebhookSignature(body, tt.signature, testWebhookSecret); got != tt.want {
+			t.Errorf("%s: validWebhookSignature() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
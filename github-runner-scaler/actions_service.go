@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Anshuman2121/actionsspot/internal/actions"
+	"github.com/google/uuid"
+)
+
+// Actions Service types, shared with the ghaec2 listener through the actions package
+type (
+	RunnerScaleSet          = actions.RunnerScaleSet
+	RunnerScaleSetStatistic = actions.RunnerScaleSetStatistic
+	RunnerScaleSetSession   = actions.RunnerScaleSetSession
+	ActionsServiceError     = actions.ActionsError
+)
+
+// isActionsServiceStatus reports whether err is an Actions Service error with the given status
+func isActionsServiceStatus(err error, statusCode int) bool {
+	return actions.IsStatus(err, statusCode)
+}
+
+// ActionsServiceClient is a compact client for the runner scale set API used by the Lambda.
+// It discovers the Actions Service URL and admin token the same way the ghaec2 listener does.
+type ActionsServiceClient struct {
+	config    Config
+	gheClient *GHEClient
+	service   *actions.Client
+}
+
+// NewActionsServiceClient creates a new Actions Service client
+func NewActionsServiceClient(gheClient *GHEClient, config Config) *ActionsServiceClient {
+	return &ActionsServiceClient{
+		config:    config,
+		gheClient: gheClient,
+		service:   actions.NewClient(&http.Client{Timeout: 30 * time.Second}, "github-runner-scaler", 0),
+	}
+}
+
+// Connect obtains the Actions Service URL and an admin token through runner registration.
+// A cached registration token that was revoked is dropped and the connection retried once.
+func (c *ActionsServiceClient) Connect(ctx context.Context) error {
+	err := c.connect(ctx)
+	if isActionsServiceStatus(err, http.StatusUnauthorized) {
+		loggerFrom(ctx).Info("Registration token was rejected, retrying with a fresh token")
+		err = c.connect(ctx)
+	}
+	return err
+}
+
+func (c *ActionsServiceClient) connect(ctx context.Context) error {
+	regToken, err := c.gheClient.GetRegistrationToken(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get registration token: %w", err)
+	}
+
+	baseURL := strings.TrimSuffix(c.config.GitHubEnterpriseURL, "/")
+	err = c.service.Connect(ctx, baseURL, fmt.Sprintf("%s/%s", baseURL, c.config.OrganizationName), regToken.Token)
+	if isActionsServiceStatus(err, http.StatusUnauthorized) {
+		c.gheClient.InvalidateRegistrationToken(regToken)
+	}
+	if err != nil {
+		return err
+	}
+
+	loggerFrom(ctx).Info("Connected to Actions Service", "url", c.service.ServiceURL())
+	return nil
+}
+
+// GetScaleSetByName looks up a runner scale set by name
+func (c *ActionsServiceClient) GetScaleSetByName(ctx context.Context, name string) (*RunnerScaleSet, error) {
+	scaleSet, err := c.service.GetScaleSetByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if scaleSet == nil {
+		return nil, fmt.Errorf("scale set %s not found", name)
+	}
+	return scaleSet, nil
+}
+
+// CreateMessageSession creates a new message session for a scale set
+func (c *ActionsServiceClient) CreateMessageSession(ctx context.Context, scaleSet *RunnerScaleSet, owner string) (*RunnerScaleSetSession, error) {
+	return c.service.CreateMessageSession(ctx, scaleSet.ID, owner)
+}
+
+// RefreshMessageSession refreshes an existing message session, which also validates it is still alive
+func (c *ActionsServiceClient) RefreshMessageSession(ctx context.Context, scaleSetID int, sessionID string) (*RunnerScaleSetSession, error) {
+	id, err := uuid.Parse(sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid session ID %s: %w", sessionID, err)
+	}
+	return c.service.RefreshMessageSession(ctx, scaleSetID, &id)
+}
+
+// DeleteMessageSession deletes a message session
+func (c *ActionsServiceClient) DeleteMessageSession(ctx context.Context, scaleSetID int, sessionID string) error {
+	id, err := uuid.Parse(sessionID)
+	if err != nil {
+		return fmt.Errorf("invalid session ID %s: %w", sessionID, err)
+	}
+	return c.service.DeleteMessageSession(ctx, scaleSetID, &id)
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+
+	"github.com/Anshuman2121/actionsspot/internal/ec2launch"
+)
+
+// Spot allocation strategies, named after their EC2 Fleet counterparts. Prioritized
+// stands for capacity-optimized-prioritized.
+const (
+	allocationRandom                 = "random"
+	allocationLowestPrice            = "lowest-price"
+	allocationCapacityOptimized      = "capacity-optimized"
+	allocationPrioritized            = "prioritized"
+	allocationPriceCapacityOptimized = "price-capacity-optimized"
+)
+
+// validateAllocationStrategy checks SPOT_ALLOCATION_STRATEGY
+func validateAllocationStrategy(strategy string) error {
+	switch strategy {
+	case allocationRandom, allocationLowestPrice, allocationCapacityOptimized, allocationPrioritized, allocationPriceCapacityOptimized:
+		return nil
+	}
+	return fmt.Errorf("unknown allocation strategy %q (want %s, %s, %s, %s or %s)", strategy, allocationRandom,
+		allocationLowestPrice, allocationCapacityOptimized, allocationPrioritized, allocationPriceCapacityOptimized)
+}
+
+// validateInterruptionBehavior checks SPOT_INTERRUPTION_BEHAVIOR
+func validateInterruptionBehavior(behavior string) error {
+	for _, valid := range ec2types.InstanceInterruptionBehavior("").Values() {
+		if ec2types.InstanceInterruptionBehavior(behavior) == valid {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown interruption behavior %q (want terminate, stop or hibernate)", behavior)
+}
+
+// spotRequestType returns the spot request type for the configured interruption behavior.
+// EC2 only stops or hibernates instances of persistent requests.
+func (c Config) spotRequestType() ec2types.SpotInstanceType {
+	if c.SpotInterruptionBehavior != string(ec2types.InstanceInterruptionBehaviorTerminate) {
+		return ec2types.SpotInstanceTypePersistent
+	}
+	return ec2types.SpotInstanceTypeOneTime
+}
+
+// launchInstanceTypes returns the instance types runners may be launched as
+func (c Config) launchInstanceTypes() []string {
+	if len(c.EC2InstanceTypes) > 0 {
+		return c.EC2InstanceTypes
+	}
+	return []string{c.EC2InstanceType}
+}
+
+// launchSubnetIDs returns the subnets runners may be launched in
+func (c Config) launchSubnetIDs() []string {
+	if len(c.EC2SubnetIDs) > 0 {
+		return c.EC2SubnetIDs
+	}
+	return []string{c.EC2SubnetID}
+}
+
+// allocateSpotTarget narrows a randomly chosen spot target down according to the allocation
+// strategy, for spot launches that do not go through EC2 Fleet. It keeps the random choice when there is nothing to choose between or the
+// lookup fails, so a pricing API outage never blocks launches.
+func (aws *AWSInfrastructure) allocateSpotTarget(ctx context.Context, target launchTarget) launchTarget {
+	strategy := aws.config.SpotAllocationStrategy
+	if strategy == "" || strategy == allocationRandom {
+		return target
+	}
+	if len(aws.config.launchInstanceTypes()) < 2 && len(aws.config.launchSubnetIDs()) < 2 {
+		return target
+	}
+
+	// Without fleet the prioritized strategies fall back to their closest lookup
+	var allocated launchTarget
+	var err error
+	switch strategy {
+	case allocationLowestPrice:
+		allocated, err = aws.lowestPriceTarget(ctx)
+	case allocationCapacityOptimized, allocationPrioritized, allocationPriceCapacityOptimized:
+		allocated, err = aws.capacityOptimizedTarget(ctx)
+	}
+	if err != nil {
+		loggerFrom(ctx).Error(err, "Spot allocation failed, using the configured target", "strategy", strategy,
+			"instanceType", target.InstanceType, "subnetId", target.SubnetID)
+		return target
+	}
+	loggerFrom(ctx).Info("Spot allocation chose a target", "strategy", strategy,
+		"instanceType", allocated.InstanceType, "subnetId", allocated.SubnetID)
+	return allocated
+}
+
+// lowestPriceTarget returns the instance type and subnet with the lowest current spot
+// price that is still within the configured price ceiling
+func (aws *AWSInfrastructure) lowestPriceTarget(ctx context.Context) (launchTarget, error) {
+	subnets, err := aws.launchSubnets(ctx)
+	if err != nil {
+		return launchTarget{}, err
+	}
+
+	instanceTypes := aws.config.launchInstanceTypes()
+	instanceTypeValues := make([]ec2types.InstanceType, len(instanceTypes))
+	for i, instanceType := range instanceTypes {
+		instanceTypeValues[i] = ec2types.InstanceType(instanceType)
+	}
+
+	var best launchTarget
+	bestPrice := math.Inf(1)
+	paginator := ec2.NewDescribeSpotPriceHistoryPaginator(aws.ec2Client, &ec2.DescribeSpotPriceHistoryInput{
+		InstanceTypes:       instanceTypeValues,
+		ProductDescriptions: []string{"Linux/UNIX"},
+		StartTime:           aws.Time(time.Now()),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return launchTarget{}, fmt.Errorf("failed to describe spot prices: %w", err)
+		}
+		for _, offer := range page.SpotPriceHistory {
+			price, err := strconv.ParseFloat(*offer.SpotPrice, 64)
+			if err != nil || price >= bestPrice {
+				continue
+			}
+			if ceiling := ec2launch.SpotPriceFor(aws.config.EC2SpotPrices, string(offer.InstanceType)); ceiling != nil {
+				if limit, err := strconv.ParseFloat(*ceiling, 64); err == nil && price > limit {
+					continue
+				}
+			}
+			for _, subnet := range subnets {
+				if *subnet.AvailabilityZone == *offer.AvailabilityZone {
+					best = launchTarget{InstanceType: string(offer.InstanceType), SubnetID: *subnet.SubnetId}
+					bestPrice = price
+					break
+				}
+			}
+		}
+	}
+	if best.InstanceType == "" {
+		return launchTarget{}, fmt.Errorf("no spot price within the ceiling in the configured subnets")
+	}
+	return best, nil
+}
+
+// capacityOptimizedTarget returns the instance type and subnet with the highest spot
+// placement score, i.e. the pool least likely to be short of capacity or interrupted
+func (aws *AWSInfrastructure) capacityOptimizedTarget(ctx context.Context) (launchTarget, error) {
+	subnets, err := aws.launchSubnets(ctx)
+	if err != nil {
+		return launchTarget{}, err
+	}
+
+	var best launchTarget
+	var bestScore int32
+	for _, instanceType := range aws.config.launchInstanceTypes() {
+		result, err := aws.ec2Client.GetSpotPlacementScores(ctx, &ec2.GetSpotPlacementScoresInput{
+			InstanceTypes:          []string{instanceType},
+			TargetCapacity:         aws.Int32(1),
+			SingleAvailabilityZone: aws.Bool(true),
+			RegionNames:            []string{os.Getenv("AWS_REGION")},
+		})
+		if err != nil {
+			return launchTarget{}, fmt.Errorf("failed to get spot placement scores for %s: %w", instanceType, err)
+		}
+		for _, score := range result.SpotPlacementScores {
+			if score.Score == nil || *score.Score <= bestScore || score.AvailabilityZoneId == nil {
+				continue
+			}
+			for _, subnet := range subnets {
+				if *subnet.AvailabilityZoneId == *score.AvailabilityZoneId {
+					best = launchTarget{InstanceType: instanceType, SubnetID: *subnet.SubnetId}
+					bestScore = *score.Score
+					break
+				}
+			}
+		}
+	}
+	if best.InstanceType == "" {
+		return launchTarget{}, fmt.Errorf("no spot placement scores for the configured subnets")
+	}
+	return best, nil
+}
+
+// launchSubnets describes the configured subnets to learn their availability zones
+func (aws *AWSInfrastructure) launchSubnets(ctx context.Context) ([]ec2types.Subnet, error) {
+	result, err := aws.ec2Client.DescribeSubnets(ctx, &ec2.DescribeSubnetsInput{
+		SubnetIds: aws.config.launchSubnetIDs(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe subnets: %w", err)
+	}
+	return result.Subnets, nil
+}
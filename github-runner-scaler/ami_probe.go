@@ -0,0 +1,292 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+const (
+	// amiReadyTagPrefix prefixes the AMI tag that marks an image as probed for a pool,
+	// e.g. "ReadyForPool:gpu". The top-level configuration uses the "default" pool name.
+	amiReadyTagPrefix = "ReadyForPool:"
+
+	// amiProbeResultTag records the outcome of the last probe of an AMI
+	amiProbeResultTag = "ProbeResult"
+
+	// amiProbeTimeout bounds a probe from launch to job completion, within the Lambda timeout
+	amiProbeTimeout = 12 * time.Minute
+
+	amiProbePollInterval = 15 * time.Second
+
+	// probedAMICacheTTL is how long a passed readiness check is reused before the tags are
+	// read again, so a rolled back AMI stops being used within a few minutes
+	probedAMICacheTTL = 5 * time.Minute
+)
+
+// probedAMIs caches AMIs found ready for a pool, keyed by AMI and pool
+var probedAMIs = struct {
+	sync.Mutex
+	checkedAt map[string]time.Time
+}{checkedAt: make(map[string]time.Time)}
+
+// probeWorkflow is the workflow an AMI probe dispatches to the probe runner. It must take
+// a runner_label input and run a trivial job on runs-on: ${{ inputs.runner_label }}.
+type probeWorkflow struct {
+	Owner    string
+	Repo     string
+	Workflow string // file name or ID of the workflow
+	Ref      string
+}
+
+// parseProbeWorkflow parses PROBE_WORKFLOW, e.g. "platform/ci-probes/ami-probe.yml@main".
+// The ref defaults to main.
+func parseProbeWorkflow(raw string) (probeWorkflow, error) {
+	spec, ref, _ := strings.Cut(raw, "@")
+	parts := strings.Split(spec, "/")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return probeWorkflow{}, fmt.Errorf("%q is not owner/repo/workflow-file[@ref]", raw)
+	}
+	if ref == "" {
+		ref = "main"
+	}
+	return probeWorkflow{Owner: parts[0], Repo: parts[1], Workflow: parts[2], Ref: ref}, nil
+}
+
+// amiReadyTagKey returns the tag that marks an AMI as ready for the pool
+func amiReadyTagKey(poolName string) string {
+	if poolName == "" {
+		poolName = "default"
+	}
+	return amiReadyTagPrefix + poolName
+}
+
+// runAMIProbe boots one on-demand runner from a new AMI with the pool's launch settings,
+// waits for it to register, runs the probe workflow on it and, when the job succeeds, tags
+// the AMI as ready for the pool. The probe runner only carries a label of its own, without
+// the default self-hosted labels, so regular jobs are not scheduled onto it. It is terminated whatever the outcome.
+func runAMIProbe(ctx context.Context, gheClient *GHEClient, awsInfra *AWSInfrastructure, config Config, ami string) error {
+	if ami == "" {
+		return fmt.Errorf("AMI probe needs an ami")
+	}
+	if config.ProbeWorkflow == "" {
+		return fmt.Errorf("AMI probe needs PROBE_WORKFLOW")
+	}
+	workflow, err := parseProbeWorkflow(config.ProbeWorkflow)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, amiProbeTimeout)
+	defer cancel()
+
+	probeConfig := config
+	probeConfig.EC2AMI = ami
+	probeInfra := *awsInfra
+	probeInfra.config = probeConfig
+	probeInfra.noDefaultLabels = true
+
+	runnerName := fmt.Sprintf("%s-ami-probe-%d", config.RunnerNamePrefix, time.Now().Unix())
+	label := runnerName
+
+	token, err := gheClient.GetRegistrationToken(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get registration token: %w", err)
+	}
+	userData, err := probeInfra.runnerUserData(ctx, runnerName, token.Token, []string{label})
+	if err != nil {
+		return err
+	}
+	target := launchTarget{
+		InstanceType: probeConfig.launchInstanceTypes()[0],
+		SubnetID:     probeConfig.launchSubnetIDs()[0],
+		OnDemand:     true, // a spot interruption would fail the probe for reasons unrelated to the AMI
+	}
+	instanceID, err := probeInfra.launchInstance(ctx, runnerName, base64.StdEncoding.EncodeToString([]byte(userData)), target, probeInfra.probeTags(runnerName))
+	if err != nil {
+		probeInfra.deleteRunnerToken(context.WithoutCancel(ctx), runnerName)
+		return err
+	}
+	defer probeInfra.cleanupProbeRunner(context.WithoutCancel(ctx), gheClient, runnerName)
+
+	loggerFrom(ctx).Info("Probe runner launched", "runnerName", runnerName, "instanceId", *instanceID, "ami", ami)
+	probeErr := probeRunner(ctx, gheClient, workflow, runnerName, label)
+	if tagErr := awsInfra.recordProbeResult(context.WithoutCancel(ctx), ami, config.PoolName, probeErr == nil); tagErr != nil {
+		loggerFrom(ctx).Error(tagErr, "Failed to record probe result", "ami", ami)
+	}
+	if probeErr != nil {
+		return fmt.Errorf("AMI %s failed its probe: %w", ami, probeErr)
+	}
+
+	loggerFrom(ctx).Info("AMI passed its probe", "ami", ami, "tag", amiReadyTagKey(config.PoolName))
+	return nil
+}
+
+// probeRunner waits for the probe runner to come online, dispatches the probe workflow to
+// it and waits for the job to complete successfully
+func probeRunner(ctx context.Context, gheClient *GHEClient, workflow probeWorkflow, runnerName, label string) error {
+	err := pollUntil(ctx, func() (bool, error) {
+		runners, err := gheClient.GetSelfHostedRunners(ctx)
+		if err != nil {
+			return false, err
+		}
+		for _, runner := range runners.Runners {
+			if runner.Name == runnerName && runner.Status == "online" {
+				return true, nil
+			}
+		}
+		return false, nil
+	})
+	if err != nil {
+		return fmt.Errorf("runner never came online: %w", err)
+	}
+	loggerFrom(ctx).Info("Probe runner registered", "runnerName", runnerName)
+
+	dispatchedAt := time.Now().Add(-time.Minute) // tolerate clock skew with GHES
+	if err := gheClient.DispatchWorkflow(ctx, workflow.Owner, workflow.Repo, workflow.Workflow, workflow.Ref,
+		map[string]string{"runner_label": label}); err != nil {
+		return err
+	}
+
+	var conclusion string
+	err = pollUntil(ctx, func() (bool, error) {
+		runs, err := gheClient.GetWorkflowDispatchRuns(ctx, workflow.Owner, workflow.Repo, workflow.Workflow, dispatchedAt)
+		if err != nil {
+			return false, err
+		}
+		for _, run := range runs.WorkflowRuns {
+			jobs, err := gheClient.GetWorkflowJobs(ctx, workflow.Owner, workflow.Repo, run.ID)
+			if err != nil {
+				return false, err
+			}
+			for _, job := range jobs {
+				if !slices.Contains(job.Labels, label) {
+					continue
+				}
+				if job.Status != "completed" {
+					return false, nil
+				}
+				conclusion = job.Conclusion
+				return true, nil
+			}
+		}
+		return false, nil
+	})
+	if err != nil {
+		return fmt.Errorf("probe job did not complete: %w", err)
+	}
+	if conclusion != "success" {
+		return fmt.Errorf("probe job concluded %s", conclusion)
+	}
+	return nil
+}
+
+// pollUntil calls done every poll interval until it reports true, fails or ctx ends
+func pollUntil(ctx context.Context, done func() (bool, error)) error {
+	ticker := time.NewTicker(amiProbePollInterval)
+	defer ticker.Stop()
+	for {
+		ok, err := done()
+		if err != nil || ok {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// probeTags returns the tags of a probe instance. It carries no bootstrap hash, so stale
+// runner recycling leaves it alone while it waits for the probe job.
+func (aws *AWSInfrastructure) probeTags(runnerName string) []ec2types.Tag {
+	tags := []ec2types.Tag{
+		{Key: aws.String("Name"), Value: aws.String(runnerName)},
+		{Key: aws.String("Purpose"), Value: aws.String("github-actions-runner-ami-probe")},
+		{Key: aws.String("RunnerName"), Value: aws.String(runnerName)},
+		{Key: aws.String("ManagedBy"), Value: aws.String("github-runner-scaler-lambda")},
+		{Key: aws.String("CreatedAt"), Value: aws.String(time.Now().Format(time.RFC3339))},
+	}
+	if aws.config.PoolName != "" {
+		tags = append(tags, ec2types.Tag{Key: aws.String("Pool"), Value: aws.String(aws.config.PoolName)})
+	}
+	return tags
+}
+
+// cleanupProbeRunner deregisters the probe runner if it is still registered and terminates its instance
+func (aws *AWSInfrastructure) cleanupProbeRunner(ctx context.Context, gheClient *GHEClient, runnerName string) {
+	if runners, err := gheClient.GetSelfHostedRunners(ctx); err == nil {
+		for _, runner := range runners.Runners {
+			if runner.Name == runnerName {
+				if err := gheClient.RemoveRunner(ctx, runner.ID); err != nil {
+					loggerFrom(ctx).Error(err, "Failed to deregister probe runner", "runnerName", runnerName)
+				}
+			}
+		}
+	}
+	if err := aws.terminateNamedRunner(ctx, runnerName); err != nil {
+		loggerFrom(ctx).Error(err, "Failed to terminate probe runner", "runnerName", runnerName)
+	}
+}
+
+// recordProbeResult tags the AMI with the probe outcome. A passed probe marks the AMI ready
+// for the pool; a failed one withdraws an earlier readiness mark.
+func (aws *AWSInfrastructure) recordProbeResult(ctx context.Context, ami, poolName string, passed bool) error {
+	readyTag := amiReadyTagKey(poolName)
+	if !passed {
+		if _, err := aws.ec2Client.DeleteTags(ctx, &ec2.DeleteTagsInput{
+			Resources: []string{ami},
+			Tags:      []ec2types.Tag{{Key: aws.String(readyTag)}},
+		}); err != nil {
+			return fmt.Errorf("failed to remove %s tag: %w", readyTag, err)
+		}
+	}
+
+	tags := []ec2types.Tag{{Key: aws.String(amiProbeResultTag), Value: aws.String("failed")}}
+	if passed {
+		now := time.Now().UTC().Format(time.RFC3339)
+		tags = []ec2types.Tag{
+			{Key: aws.String(amiProbeResultTag), Value: aws.String("passed")},
+			{Key: aws.String(readyTag), Value: aws.String(now)},
+		}
+	}
+	if _, err := aws.ec2Client.CreateTags(ctx, &ec2.CreateTagsInput{Resources: []string{ami}, Tags: tags}); err != nil {
+		return fmt.Errorf("failed to tag AMI: %w", err)
+	}
+	return nil
+}
+
+// ensureAMIReady returns an error unless the configured AMI passed a probe for this pool
+func (aws *AWSInfrastructure) ensureAMIReady(ctx context.Context) error {
+	ami := aws.config.EC2AMI
+	readyTag := amiReadyTagKey(aws.config.PoolName)
+	cacheKey := ami + "/" + readyTag
+
+	probedAMIs.Lock()
+	defer probedAMIs.Unlock()
+	if checkedAt, ok := probedAMIs.checkedAt[cacheKey]; ok && time.Since(checkedAt) < probedAMICacheTTL {
+		return nil
+	}
+
+	result, err := aws.ec2Client.DescribeImages(ctx, &ec2.DescribeImagesInput{ImageIds: []string{ami}})
+	if err != nil {
+		return fmt.Errorf("failed to describe AMI %s: %w", ami, err)
+	}
+	if len(result.Images) == 0 {
+		return fmt.Errorf("AMI %s not found", ami)
+	}
+	if _, ok := tagValues(result.Images[0].Tags)[readyTag]; !ok {
+		return fmt.Errorf("AMI %s has not passed a probe (no %s tag), run the probe-ami action first", ami, readyTag)
+	}
+
+	probedAMIs.checkedAt[cacheKey] = time.Now()
+	return nil
+}
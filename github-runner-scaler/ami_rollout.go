@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const (
+	// amiChannelTag records whether a runner was launched from the stable or the canary AMI
+	amiChannelTag = "AMIChannel"
+
+	amiChannelStable = "stable"
+	amiChannelCanary = "canary"
+)
+
+// AMIRollout is the blue/green AMI state of a pool. The stable AMI overrides the configured
+// one once a canary has been promoted; the canary AMI takes CanaryPercentage of new launches.
+type AMIRollout struct {
+	Pool             string
+	StableAMI        string
+	CanaryAMI        string
+	CanaryPercentage int
+	UpdatedAt        time.Time
+	UpdatedBy        string
+}
+
+// AMIRolloutStore persists AMI rollouts in the rollouts table, keyed by pool name
+type AMIRolloutStore struct {
+	client    *dynamodb.Client
+	tableName string
+}
+
+// NewAMIRolloutStore creates a rollout store for the given table
+func NewAMIRolloutStore(client *dynamodb.Client, tableName string) *AMIRolloutStore {
+	return &AMIRolloutStore{
+		client:    client,
+		tableName: tableName,
+	}
+}
+
+// Get returns the rollout of a pool, or an empty rollout when none was started
+func (s *AMIRolloutStore) Get(ctx context.Context, pool string) (AMIRollout, error) {
+	output, err := s.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: &s.tableName,
+		Key: map[string]types.AttributeValue{
+			"pool": &types.AttributeValueMemberS{Value: pool},
+		},
+	})
+	if err != nil {
+		return AMIRollout{}, fmt.Errorf("failed to get AMI rollout of %s: %w", pool, err)
+	}
+
+	str := func(name string) string {
+		if v, ok := output.Item[name].(*types.AttributeValueMemberS); ok {
+			return v.Value
+		}
+		return ""
+	}
+	rollout := AMIRollout{
+		Pool:      pool,
+		StableAMI: str("stable_ami"),
+		CanaryAMI: str("canary_ami"),
+		UpdatedBy: str("updated_by"),
+	}
+	if v, ok := output.Item["canary_percentage"].(*types.AttributeValueMemberN); ok {
+		rollout.CanaryPercentage, _ = strconv.Atoi(v.Value)
+	}
+	rollout.UpdatedAt, _ = time.Parse(time.RFC3339, str("updated_at"))
+	return rollout, nil
+}
+
+// Save creates or replaces the rollout of a pool
+func (s *AMIRolloutStore) Save(ctx context.Context, rollout AMIRollout) error {
+	_, err := s.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: &s.tableName,
+		Item: map[string]types.AttributeValue{
+			"pool":              &types.AttributeValueMemberS{Value: rollout.Pool},
+			"stable_ami":        &types.AttributeValueMemberS{Value: rollout.StableAMI},
+			"canary_ami":        &types.AttributeValueMemberS{Value: rollout.CanaryAMI},
+			"canary_percentage": &types.AttributeValueMemberN{Value: strconv.Itoa(rollout.CanaryPercentage)},
+			"updated_at":        &types.AttributeValueMemberS{Value: rollout.UpdatedAt.Format(time.RFC3339)},
+			"updated_by":        &types.AttributeValueMemberS{Value: rollout.UpdatedBy},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save AMI rollout of %s: %w", rollout.Pool, err)
+	}
+	return nil
+}
+
+// rolloutPoolName returns the rollout key of a pool; the top-level configuration uses "default"
+func rolloutPoolName(poolName string) string {
+	if poolName == "" {
+		return "default"
+	}
+	return poolName
+}
+
+// chooseAMI picks the AMI for a new runner of this pool and the channel it belongs to.
+// Without a rollout, or when it cannot be read, runners use the configured AMI.
+func (aws *AWSInfrastructure) chooseAMI(ctx context.Context) (string, string) {
+	rollout, err := NewAMIRolloutStore(aws.dynamoDBClient, aws.config.RolloutsTableName).Get(ctx, rolloutPoolName(aws.config.PoolName))
+	if err != nil {
+		loggerFrom(ctx).Error(err, "Failed to read the AMI rollout, using the configured AMI")
+		return aws.config.EC2AMI, ""
+	}
+
+	stable := aws.config.EC2AMI
+	if rollout.StableAMI != "" {
+		stable = rollout.StableAMI
+	}
+	if rollout.CanaryAMI == "" {
+		if rollout.StableAMI == "" {
+			return stable, ""
+		}
+		return stable, amiChannelStable
+	}
+	if rand.Intn(100) < rollout.CanaryPercentage {
+		return rollout.CanaryAMI, amiChannelCanary
+	}
+	return stable, amiChannelStable
+}
+
+// runAMIRollout starts, promotes or rolls back the canary AMI of a pool. Promoting makes
+// the canary the pool's stable AMI, overriding EC2_AMI_ID or the pool's ami until the
+// configuration catches up; rolling back stops canary launches. Runners already launched
+// from the canary finish their job and terminate as usual.
+func runAMIRollout(ctx context.Context, awsInfra *AWSInfrastructure, config Config, request ManualInvocation) error {
+	poolConfig := config
+	if request.Pool != "" {
+		pool, ok := findPool(config.Pools, request.Pool)
+		if !ok {
+			return fmt.Errorf("unknown pool %q in AMI rollout", request.Pool)
+		}
+		poolConfig = config.forPool(pool)
+	}
+
+	store := NewAMIRolloutStore(awsInfra.dynamoDBClient, config.RolloutsTableName)
+	rollout, err := store.Get(ctx, rolloutPoolName(poolConfig.PoolName))
+	if err != nil {
+		return err
+	}
+	stable := rollout.StableAMI
+	if stable == "" {
+		stable = poolConfig.EC2AMI
+	}
+
+	switch request.Action {
+	case manualActionAMICanary:
+		if request.AMI == "" {
+			return fmt.Errorf("ami-canary needs an ami")
+		}
+		if request.Percentage <= 0 || request.Percentage > 100 {
+			return fmt.Errorf("ami-canary percentage %d is not between 1 and 100", request.Percentage)
+		}
+		if request.AMI == stable {
+			return fmt.Errorf("%s is already the stable AMI of pool %s", request.AMI, rollout.Pool)
+		}
+		rollout.CanaryAMI = request.AMI
+		rollout.CanaryPercentage = request.Percentage
+		loggerFrom(ctx).Info("Canary AMI started", "pool", rollout.Pool, "canaryAmi", rollout.CanaryAMI,
+			"canaryPercentage", rollout.CanaryPercentage, "stableAmi", stable)
+	case manualActionAMIPromote:
+		if rollout.CanaryAMI == "" {
+			return fmt.Errorf("pool %s has no canary AMI to promote", rollout.Pool)
+		}
+		loggerFrom(ctx).Info("Canary AMI promoted to stable", "pool", rollout.Pool, "canaryAmi", rollout.CanaryAMI, "replacedAmi", stable)
+		rollout.StableAMI = rollout.CanaryAMI
+		rollout.CanaryAMI = ""
+		rollout.CanaryPercentage = 0
+	case manualActionAMIRollback:
+		if rollout.CanaryAMI == "" {
+			return fmt.Errorf("pool %s has no canary AMI to roll back", rollout.Pool)
+		}
+		loggerFrom(ctx).Info("Canary AMI rolled back", "pool", rollout.Pool, "canaryAmi", rollout.CanaryAMI, "stableAmi", stable)
+		rollout.CanaryAMI = ""
+		rollout.CanaryPercentage = 0
+	}
+
+	rollout.UpdatedAt = time.Now().UTC()
+	rollout.UpdatedBy = request.Requester
+	if err := store.Save(ctx, rollout); err != nil {
+		return err
+	}
+
+	if rollout.StableAMI != "" {
+		stable = rollout.StableAMI
+	}
+	auditLog(ctx, request.Action, map[string]string{
+		"pool":              rollout.Pool,
+		"stable_ami":        stable,
+		"canary_ami":        rollout.CanaryAMI,
+		"canary_percentage": strconv.Itoa(rollout.CanaryPercentage),
+		"requester":         request.Requester,
+		"reason":            request.Reason,
+	})
+	return nil
+}
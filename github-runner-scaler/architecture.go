@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Runner CPU architectures, named like the runner's own labels and release tarballs
+const (
+	architectureX64   = "x64"
+	architectureARM64 = "arm64"
+)
+
+// gravitonFamily matches the instance families of AWS Graviton processors, e.g. t4g, m7gd,
+// c6gn or im4gn, and the first-generation a1
+var gravitonFamily = regexp.MustCompile(`^(a1|[a-z]+\d+g[a-z]*)$`)
+
+// architectureProfile is what runners of one architecture are launched with
+type architectureProfile struct {
+	AMI          string // empty keeps EC2_AMI_ID
+	InstanceType string
+	TarballS3URI string // Optional: S3 mirror of the runner tarball for the architecture
+}
+
+// validateArchitecture checks RUNNER_ARCHITECTURE or a pool's architecture
+func validateArchitecture(arch string) error {
+	switch arch {
+	case architectureX64, architectureARM64:
+		return nil
+	}
+	return fmt.Errorf("unknown architecture %q (want %s or %s)", arch, architectureX64, architectureARM64)
+}
+
+// instanceTypeArchitecture returns the architecture of an instance type
+func instanceTypeArchitecture(instanceType string) string {
+	family, _, _ := strings.Cut(instanceType, ".")
+	if gravitonFamily.MatchString(family) {
+		return architectureARM64
+	}
+	return architectureX64
+}
+
+// forArchitecture returns a copy of the configuration launching runners of the given
+// architecture: its AMI, instance type and runner tarball mirror, and its label in place of
+// the other architecture's. EC2_INSTANCE_TYPES lists types of RUNNER_ARCHITECTURE, so it is
+// dropped when switching away from it.
+func (c Config) forArchitecture(arch string) Config {
+	profile := c.Architectures[arch]
+	archConfig := c
+	archConfig.RunnerArchitecture = arch
+	archConfig.RunnerLabels = architectureLabels(c.RunnerLabels, arch)
+	if profile.AMI != "" {
+		archConfig.EC2AMI = profile.AMI
+	}
+	if profile.InstanceType != "" {
+		archConfig.EC2InstanceType = profile.InstanceType
+	}
+	if c.RunnerArchitecture != "" && c.RunnerArchitecture != arch {
+		archConfig.EC2InstanceTypes = nil
+	}
+	archConfig.RunnerTarballS3URI = profile.TarballS3URI
+	return archConfig
+}
+
+// architectureLabels adds the architecture's label to runner labels and removes the other
+// architecture's, so jobs asking for runs-on arm64 find Graviton runners
+func architectureLabels(labels []string, arch string) []string {
+	return exclusiveLabel(labels, arch, architectureX64, architectureARM64)
+}
+
+// checkArchitecture checks that the instance types runners are launched as have the
+// configured architecture, since an AMI only boots on one, and that a private bootstrap has
+// the architecture's runner tarball mirror
+func (c Config) checkArchitecture() error {
+	for _, instanceType := range c.launchInstanceTypes() {
+		if arch := instanceTypeArchitecture(instanceType); arch != c.RunnerArchitecture {
+			return fmt.Errorf("instance type %s is %s, but runners are %s", instanceType, arch, c.RunnerArchitecture)
+		}
+	}
+	if c.PrivateBootstrap && c.RunnerTarballS3URI == "" {
+		return fmt.Errorf("PRIVATE_BOOTSTRAP requires a runner tarball mirror for %s, runners cannot download from github.com", c.RunnerArchitecture)
+	}
+	return nil
+}
+
+// checkPoolPlatforms runs checkArchitecture and checkRunnerOS on the configuration of every
+// pool
+func (c Config) checkPoolPlatforms() error {
+	for _, pool := range c.Pools {
+		poolConfig := c.forPool(pool)
+		if err := poolConfig.checkArchitecture(); err != nil {
+			return fmt.Errorf("pool %q: %w", pool.Name, err)
+		}
+		if err := poolConfig.checkRunnerOS(); err != nil {
+			return fmt.Errorf("pool %q: %w", pool.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"context"
+	"sort"
+)
+
+// auditLog records an operator action in the audit trail. Entries are log lines with an
+// "audit" field, so they can be pulled out of the function's log group with Logs Insights,
+// e.g. `filter ispresent(audit) | sort timestamp desc`.
+func auditLog(ctx context.Context, action string, fields map[string]string) {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	keysAndValues := make([]interface{}, 0, 2*len(fields)+2)
+	keysAndValues = append(keysAndValues, "audit", action)
+	for _, key := range keys {
+		keysAndValues = append(keysAndValues, key, fields[key])
+	}
+	loggerFrom(ctx).Info("Audit", keysAndValues...)
+}
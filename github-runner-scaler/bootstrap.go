@@ -0,0 +1,56 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// runnerVersion is the GitHub Actions runner release installed on runner instances
+const runnerVersion = "2.311.0"
+
+// bootstrapHash identifies the bootstrap template runners are currently launched from. It
+// hashes the user data rendered with placeholder runner names and tokens, so it changes with
+// the labels and bootstrap settings but not from one runner to the next.
+func (aws *AWSInfrastructure) bootstrapHash() string {
+	script := aws.generateUserDataScriptWithToken("{runner}", "{token}", aws.config.RunnerLabels)
+	sum := sha256.Sum256([]byte(script))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// packageInstallScript installs the tools the bootstrap needs. Without internet egress
+// the package mirrors are out of reach, so the tools must be baked into the AMI and are
+// only checked for. AWS calls then go to the regional endpoints, which private DNS
+// resolves to the VPC endpoints.
+func (c Config) packageInstallScript() string {
+	if !c.PrivateBootstrap {
+		return `# Update system
+apt-get update -y
+apt-get install -y curl jq unzip awscli
+`
+	}
+	return `# No internet egress: the AMI must provide the tools, AWS calls use VPC endpoints
+for tool in curl jq aws; do
+    command -v $tool > /dev/null || { echo "$tool is not installed in the AMI"; exit 1; }
+done
+export AWS_REGION=$REGION AWS_STS_REGIONAL_ENDPOINTS=regional
+`
+}
+
+// runnerDownloadScript downloads and unpacks the runner for the runner architecture, from
+// the S3 mirror when one is configured and from the GitHub release otherwise. It runs as
+// the runner user.
+func (c Config) runnerDownloadScript() string {
+	tarball := fmt.Sprintf("actions-runner-linux-%s-%s.tar.gz", c.RunnerArchitecture, runnerVersion)
+	if c.RunnerTarballS3URI != "" {
+		return fmt.Sprintf(`# Download and install GitHub Actions runner from the S3 mirror
+aws s3 cp --region $REGION %s ./%s
+tar xzf ./%s
+`, c.RunnerTarballS3URI, tarball, tarball)
+	}
+	return fmt.Sprintf(`# Download and install GitHub Actions runner. curl uses whichever address family works,
+# so IPv6-only subnets reach github.com through NAT64/DNS64.
+curl --retry 5 --retry-connrefused -o %s -L https://github.com/actions/runner/releases/download/v%s/%s
+tar xzf ./%s
+`, tarball, runnerVersion, tarball, tarball)
+}
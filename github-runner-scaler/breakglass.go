@@ -0,0 +1,325 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+const (
+	// breakglassRulePrefix names the one-shot rules that revoke breakglass access. The Lambda
+	// permission in terraform allows every rule with this prefix to invoke the function.
+	breakglassRulePrefix = "github-runner-scaler-breakglass-"
+
+	// breakglassTag marks an instance or security group with breakglass access. Its value is
+	// the RFC 3339 time the access expires. The breakglass SSM policy in terraform only
+	// allows sessions to instances carrying it.
+	breakglassTag = "breakglass"
+
+	breakglassModeSSH = "ssh"
+	breakglassModeSSM = "ssm"
+
+	defaultBreakglassTTL = time.Hour
+)
+
+// runBreakglass grants temporary access to a runner instance, over SSH from a CIDR through a
+// dedicated security group, or through Session Manager by tagging the instance. A one-shot
+// rule revokes the access when the TTL ends; the scaling cycle revokes expired grants the
+// rule missed. Grants and revocations are written to the audit trail.
+func runBreakglass(ctx context.Context, awsInfra *AWSInfrastructure, config Config, request ManualInvocation) error {
+	if request.InstanceID == "" || request.Requester == "" {
+		return fmt.Errorf("breakglass needs an instance_id and a requester")
+	}
+
+	ttl := defaultBreakglassTTL
+	if request.TTL != "" {
+		var err error
+		if ttl, err = time.ParseDuration(request.TTL); err != nil || ttl <= 0 {
+			return fmt.Errorf("invalid breakglass ttl %q", request.TTL)
+		}
+	}
+	if ttl > config.BreakglassMaxTTL {
+		return fmt.Errorf("breakglass ttl %s exceeds the maximum of %s", ttl, config.BreakglassMaxTTL)
+	}
+
+	instance, err := awsInfra.managedInstance(ctx, request.InstanceID)
+	if err != nil {
+		return err
+	}
+	expires := time.Now().Add(ttl).UTC()
+
+	switch request.Mode {
+	case breakglassModeSSH:
+		if _, _, err := net.ParseCIDR(request.CIDR); err != nil {
+			return fmt.Errorf("breakglass over SSH needs a valid cidr: %w", err)
+		}
+		if err := awsInfra.openBreakglassSSH(ctx, instance, request.CIDR, expires); err != nil {
+			return err
+		}
+	case breakglassModeSSM:
+		// Access follows from the tag below through the breakglass SSM policy
+	default:
+		return fmt.Errorf("unknown breakglass mode %q (want %s or %s)", request.Mode, breakglassModeSSH, breakglassModeSSM)
+	}
+
+	if _, err := awsInfra.ec2Client.CreateTags(ctx, &ec2.CreateTagsInput{
+		Resources: []string{request.InstanceID},
+		Tags:      []ec2types.Tag{{Key: awsInfra.String(breakglassTag), Value: awsInfra.String(expires.Format(time.RFC3339))}},
+	}); err != nil {
+		// Without the tag the grant is never swept, so take it back right away
+		_ = awsInfra.revokeBreakglass(context.WithoutCancel(ctx), request.InstanceID, "tagging failed")
+		return fmt.Errorf("failed to tag %s: %w", request.InstanceID, err)
+	}
+
+	ruleName := breakglassRulePrefix + request.InstanceID
+	if err := scheduleOneShotInvocation(ctx, awsInfra, ruleName, expires,
+		fmt.Sprintf("Revoke breakglass access to %s", request.InstanceID),
+		ManualInvocation{Action: manualActionBreakglassRevoke, InstanceID: request.InstanceID, Rule: ruleName}); err != nil {
+		loggerFrom(ctx).Error(err, "Failed to schedule breakglass revocation, the scaling cycle will revoke it")
+	}
+
+	auditLog(ctx, "breakglass-granted", map[string]string{
+		"instance_id": request.InstanceID,
+		"runner_name": tagValues(instance.Tags)["RunnerName"],
+		"mode":        request.Mode,
+		"cidr":        request.CIDR,
+		"requester":   request.Requester,
+		"reason":      request.Reason,
+		"expires_at":  expires.Format(time.RFC3339),
+	})
+	loggerFrom(ctx).Info("Breakglass access granted", "mode", request.Mode, "instanceId", request.InstanceID,
+		"requester", request.Requester, "expires", expires.Format(time.RFC3339))
+	return nil
+}
+
+// managedInstance describes an instance launched by the scaler that has not terminated
+func (aws *AWSInfrastructure) managedInstance(ctx context.Context, instanceID string) (ec2types.Instance, error) {
+	result, err := aws.ec2Client.DescribeInstances(ctx, &ec2.DescribeInstancesInput{
+		InstanceIds: []string{instanceID},
+		Filters: []ec2types.Filter{
+			{Name: aws.String("tag:ManagedBy"), Values: []string{"github-runner-scaler-lambda"}},
+			{Name: aws.String("instance-state-name"), Values: []string{"pending", "running", "stopping", "stopped"}},
+		},
+	})
+	if err != nil {
+		return ec2types.Instance{}, fmt.Errorf("failed to describe instance %s: %w", instanceID, err)
+	}
+	for _, reservation := range result.Reservations {
+		for _, instance := range reservation.Instances {
+			return instance, nil
+		}
+	}
+	return ec2types.Instance{}, fmt.Errorf("instance %s is not a live runner instance", instanceID)
+}
+
+// breakglassGroupName names the security group that opens SSH to an instance
+func breakglassGroupName(instanceID string) string {
+	return "breakglass-" + instanceID
+}
+
+// openBreakglassSSH creates a security group allowing SSH from the CIDR and adds it to the instance
+func (aws *AWSInfrastructure) openBreakglassSSH(ctx context.Context, instance ec2types.Instance, cidr string, expires time.Time) error {
+	instanceID := *instance.InstanceId
+	group, err := aws.ec2Client.CreateSecurityGroup(ctx, &ec2.CreateSecurityGroupInput{
+		GroupName:   aws.String(breakglassGroupName(instanceID)),
+		Description: aws.String("Temporary SSH access to runner " + instanceID),
+		VpcId:       instance.VpcId,
+		TagSpecifications: []ec2types.TagSpecification{{
+			ResourceType: ec2types.ResourceTypeSecurityGroup,
+			Tags: []ec2types.Tag{
+				{Key: aws.String("ManagedBy"), Value: aws.String("github-runner-scaler-lambda")},
+				{Key: aws.String(breakglassTag), Value: aws.String(expires.Format(time.RFC3339))},
+			},
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create breakglass security group: %w", err)
+	}
+
+	ingress := []ec2types.IpPermission{{
+		IpProtocol: aws.String("tcp"),
+		FromPort:   aws.Int32(22),
+		ToPort:     aws.Int32(22),
+	}}
+	if strings.Contains(cidr, ":") {
+		ingress[0].Ipv6Ranges = []ec2types.Ipv6Range{{CidrIpv6: aws.String(cidr)}}
+	} else {
+		ingress[0].IpRanges = []ec2types.IpRange{{CidrIp: aws.String(cidr)}}
+	}
+	if _, err := aws.ec2Client.AuthorizeSecurityGroupIngress(ctx, &ec2.AuthorizeSecurityGroupIngressInput{
+		GroupId:       group.GroupId,
+		IpPermissions: ingress,
+	}); err != nil {
+		aws.deleteBreakglassGroup(context.WithoutCancel(ctx), *group.GroupId)
+		return fmt.Errorf("failed to allow SSH from %s: %w", cidr, err)
+	}
+
+	// Security groups belong to network interfaces; ModifyInstanceAttribute only works on
+	// instances with a single one
+	for i, eni := range instance.NetworkInterfaces {
+		groupIDs := []string{*group.GroupId}
+		for _, current := range eni.Groups {
+			if !slices.Contains(groupIDs, *current.GroupId) {
+				groupIDs = append(groupIDs, *current.GroupId)
+			}
+		}
+		if err := aws.setInterfaceGroups(ctx, *eni.NetworkInterfaceId, groupIDs); err != nil {
+			// Restore the interfaces already opened before deleting the group
+			for _, opened := range instance.NetworkInterfaces[:i] {
+				var original []string
+				for _, current := range opened.Groups {
+					original = append(original, *current.GroupId)
+				}
+				if err := aws.setInterfaceGroups(context.WithoutCancel(ctx), *opened.NetworkInterfaceId, original); err != nil {
+					loggerFrom(ctx).Error(err, "Failed to roll back breakglass security groups", "networkInterfaceId", *opened.NetworkInterfaceId)
+				}
+			}
+			aws.deleteBreakglassGroup(context.WithoutCancel(ctx), *group.GroupId)
+			return fmt.Errorf("failed to attach breakglass security group: %w", err)
+		}
+	}
+	return nil
+}
+
+// setInterfaceGroups replaces the security groups of a network interface
+func (aws *AWSInfrastructure) setInterfaceGroups(ctx context.Context, eniID string, groupIDs []string) error {
+	_, err := aws.ec2Client.ModifyNetworkInterfaceAttribute(ctx, &ec2.ModifyNetworkInterfaceAttributeInput{
+		NetworkInterfaceId: aws.String(eniID),
+		Groups:             groupIDs,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set security groups of %s: %w", eniID, err)
+	}
+	return nil
+}
+
+// detachBreakglassGroup removes the breakglass group from every network interface of the
+// instance that carries it
+func (aws *AWSInfrastructure) detachBreakglassGroup(ctx context.Context, instance ec2types.Instance, groupID string) error {
+	for _, eni := range instance.NetworkInterfaces {
+		var keep []string
+		attached := false
+		for _, current := range eni.Groups {
+			if *current.GroupId == groupID {
+				attached = true
+				continue
+			}
+			keep = append(keep, *current.GroupId)
+		}
+		if !attached {
+			continue
+		}
+		if err := aws.setInterfaceGroups(ctx, *eni.NetworkInterfaceId, keep); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// revokeBreakglass removes breakglass access from an instance: the SSH security group is
+// detached and deleted, open Session Manager sessions are ended and the tag is removed
+func (aws *AWSInfrastructure) revokeBreakglass(ctx context.Context, instanceID, reason string) error {
+	instance, err := aws.managedInstance(ctx, instanceID)
+	if err != nil {
+		// A terminated instance no longer needs revoking, only its group deleting
+		loggerFrom(ctx).Error(err, "Breakglass instance not found, deleting its group only", "instanceId", instanceID)
+	}
+
+	var breakglassGroupID string
+	for _, group := range instance.SecurityGroups {
+		if *group.GroupName == breakglassGroupName(instanceID) {
+			breakglassGroupID = *group.GroupId
+		}
+	}
+	if breakglassGroupID != "" {
+		if err := aws.detachBreakglassGroup(ctx, instance, breakglassGroupID); err != nil {
+			return fmt.Errorf("failed to detach breakglass security group: %w", err)
+		}
+		aws.deleteBreakglassGroup(ctx, breakglassGroupID)
+	}
+
+	if instance.InstanceId != nil {
+		if ended, err := aws.ssmClient.TerminateInstanceSessions(ctx, instanceID); err != nil {
+			loggerFrom(ctx).Error(err, "Failed to end sessions", "instanceId", instanceID)
+		} else if ended > 0 {
+			loggerFrom(ctx).Info("Ended sessions", "instanceId", instanceID, "sessions", ended)
+		}
+		if _, err := aws.ec2Client.DeleteTags(ctx, &ec2.DeleteTagsInput{
+			Resources: []string{instanceID},
+			Tags:      []ec2types.Tag{{Key: aws.String(breakglassTag)}},
+		}); err != nil {
+			return fmt.Errorf("failed to remove breakglass tag from %s: %w", instanceID, err)
+		}
+	}
+
+	auditLog(ctx, "breakglass-revoked", map[string]string{
+		"instance_id": instanceID,
+		"reason":      reason,
+	})
+	loggerFrom(ctx).Info("Breakglass access revoked", "instanceId", instanceID, "reason", reason)
+	return nil
+}
+
+// deleteBreakglassGroup deletes a breakglass security group, logging failures. Groups still
+// attached to a terminating instance are deleted by a later sweep.
+func (aws *AWSInfrastructure) deleteBreakglassGroup(ctx context.Context, groupID string) {
+	if _, err := aws.ec2Client.DeleteSecurityGroup(ctx, &ec2.DeleteSecurityGroupInput{GroupId: aws.String(groupID)}); err != nil {
+		loggerFrom(ctx).Error(err, "Failed to delete breakglass security group", "groupId", groupID)
+	}
+}
+
+// revokeExpiredBreakglass revokes breakglass grants past their expiry and deletes expired
+// breakglass groups left behind by terminated instances. Grants are not pool specific, so
+// it runs once per invocation rather than per pool.
+func revokeExpiredBreakglass(ctx context.Context, awsInfra *AWSInfrastructure) error {
+	expired := func(tags map[string]string) bool {
+		expires, err := time.Parse(time.RFC3339, tags[breakglassTag])
+		return err != nil || time.Now().After(expires)
+	}
+
+	paginator := ec2.NewDescribeInstancesPaginator(awsInfra.ec2Client, &ec2.DescribeInstancesInput{
+		Filters: []ec2types.Filter{
+			{Name: awsInfra.String("tag:ManagedBy"), Values: []string{"github-runner-scaler-lambda"}},
+			{Name: awsInfra.String("tag-key"), Values: []string{breakglassTag}},
+			{Name: awsInfra.String("instance-state-name"), Values: []string{"pending", "running", "stopping", "stopped"}},
+		},
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to describe breakglass instances: %w", err)
+		}
+		for _, reservation := range page.Reservations {
+			for _, instance := range reservation.Instances {
+				if !expired(tagValues(instance.Tags)) {
+					continue
+				}
+				if err := awsInfra.revokeBreakglass(ctx, *instance.InstanceId, "expired"); err != nil {
+					loggerFrom(ctx).Error(err, "Failed to revoke breakglass access", "instanceId", *instance.InstanceId)
+				}
+			}
+		}
+	}
+
+	groups, err := awsInfra.ec2Client.DescribeSecurityGroups(ctx, &ec2.DescribeSecurityGroupsInput{
+		Filters: []ec2types.Filter{
+			{Name: awsInfra.String("tag:ManagedBy"), Values: []string{"github-runner-scaler-lambda"}},
+			{Name: awsInfra.String("tag-key"), Values: []string{breakglassTag}},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to describe breakglass security groups: %w", err)
+	}
+	for _, group := range groups.SecurityGroups {
+		if expired(tagValues(group.Tags)) {
+			awsInfra.deleteBreakglassGroup(ctx, *group.GroupId)
+		}
+	}
+	return nil
+}
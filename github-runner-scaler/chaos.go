@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// chaosTag marks an instance chaos mode acted on, with the kind of fault as its value
+const chaosTag = "ChaosInjected"
+
+const (
+	chaosIdleTermination = "idle-termination"
+	chaosSpotInterrupt   = "spot-interruption"
+)
+
+// injectChaos injects faults into this pool's runners when CHAOS_MODE is enabled, so the
+// recovery paths are exercised before a real capacity event. Each cycle every idle runner
+// is terminated with CHAOS_IDLE_TERMINATION_PCT probability without being deregistered,
+// as if the instance was lost, and every busy spot runner is interrupted with
+// CHAOS_SPOT_INTERRUPT_PCT probability the way EC2 would, following
+// SPOT_INTERRUPTION_BEHAVIOR and leaving its spot request alone. The percentages apply per
+// cycle, so keep them small.
+func injectChaos(ctx context.Context, gheClient *GHEClient, awsInfra *AWSInfrastructure, config Config) error {
+	if !config.ChaosMode {
+		return nil
+	}
+
+	registered, err := gheClient.GetSelfHostedRunners(ctx)
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]SelfHostedRunner, len(registered.Runners))
+	for _, runner := range registered.Runners {
+		byName[runner.Name] = runner
+	}
+
+	paginator := ec2.NewDescribeInstancesPaginator(awsInfra.ec2Client, &ec2.DescribeInstancesInput{
+		Filters: []ec2types.Filter{
+			{Name: awsInfra.String("tag:ManagedBy"), Values: []string{"github-runner-scaler-lambda"}},
+			{Name: awsInfra.String("tag:Purpose"), Values: []string{"github-actions-runner"}},
+			{Name: awsInfra.String("instance-state-name"), Values: []string{"running"}},
+		},
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to describe runner instances: %w", err)
+		}
+		for _, reservation := range page.Reservations {
+			for _, instance := range reservation.Instances {
+				tags := tagValues(instance.Tags)
+				if tags["Pool"] != config.PoolName {
+					continue
+				}
+				// Instances kept for inspection or breakglass access are never touched
+				if _, held := tags[debugHoldTag]; held {
+					continue
+				}
+				if _, open := tags[breakglassTag]; open {
+					continue
+				}
+				ghRunner, ok := byName[tags["RunnerName"]]
+				if !ok || ghRunner.Status != "online" {
+					continue
+				}
+
+				switch {
+				case !ghRunner.Busy && rand.Intn(100) < config.ChaosIdleTermination:
+					awsInfra.injectFault(ctx, instance, tags["RunnerName"], chaosIdleTermination)
+				case ghRunner.Busy && instance.InstanceLifecycle == ec2types.InstanceLifecycleTypeSpot &&
+					rand.Intn(100) < config.ChaosSpotInterruption:
+					awsInfra.injectFault(ctx, instance, tags["RunnerName"], chaosSpotInterrupt)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// injectFault tags the instance with the fault and then terminates it, or for a spot
+// interruption with the stop or hibernate behavior, stops it
+func (aws *AWSInfrastructure) injectFault(ctx context.Context, instance ec2types.Instance, runnerName, fault string) {
+	instanceID := *instance.InstanceId
+	if _, err := aws.ec2Client.CreateTags(ctx, &ec2.CreateTagsInput{
+		Resources: []string{instanceID},
+		Tags:      []ec2types.Tag{{Key: aws.String(chaosTag), Value: aws.String(fault)}},
+	}); err != nil {
+		loggerFrom(ctx).Error(err, "Failed to tag chaos target", "instanceId", instanceID)
+		return
+	}
+
+	var err error
+	behavior := ec2types.InstanceInterruptionBehavior(aws.config.SpotInterruptionBehavior)
+	if fault == chaosSpotInterrupt && behavior != ec2types.InstanceInterruptionBehaviorTerminate {
+		_, err = aws.ec2Client.StopInstances(ctx, &ec2.StopInstancesInput{
+			InstanceIds: []string{instanceID},
+			Hibernate:   aws.Bool(behavior == ec2types.InstanceInterruptionBehaviorHibernate),
+		})
+	} else {
+		err = aws.runnerProvider().TerminateRunner(ctx, instanceID)
+	}
+	if err != nil {
+		loggerFrom(ctx).Error(err, "Failed to inject fault", "fault", fault, "instanceId", instanceID)
+		return
+	}
+
+	loggerFrom(ctx).Info("Chaos fault injected", "fault", fault, "runnerName", runnerName, "instanceId", instanceID)
+	auditLog(ctx, "chaos", map[string]string{
+		"fault":       fault,
+		"pool":        aws.config.PoolName,
+		"runner_name": runnerName,
+		"instance_id": instanceID,
+		"spot":        strconv.FormatBool(instance.InstanceLifecycle == ec2types.InstanceLifecycleTypeSpot),
+	})
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Anshuman2121/actionsspot/internal/configsource"
+	"github.com/Anshuman2121/actionsspot/internal/runnername"
+)
+
+// Configuration is resolved per setting by configsource: flags (config commands only),
+// then the environment, then CONFIG_FILE, then the defaults in configSettings.
+
+// configSettings lists every setting the scaler reads
+var configSettings = []configsource.Setting{
+	{Name: "ACTIONS_CACHE_PROXY_URL"},
+	{Name: "APPCONFIG_AGENT_URL", Default: "http://localhost:2772"},
+	{Name: "APPCONFIG_APPLICATION"},
+	{Name: "APPCONFIG_ENVIRONMENT"},
+	{Name: "APPCONFIG_PROFILE"},
+	{Name: "BREAKGLASS_MAX_TTL", Default: "4h"},
+	{Name: "CHAOS_IDLE_TERMINATION_PCT", Default: "5"},
+	{Name: "CHAOS_MODE", Default: "false"},
+	{Name: "CHAOS_SPOT_INTERRUPT_PCT", Default: "2"},
+	{Name: "CLEANUP_OFFLINE_RUNNERS", Default: "true"},
+	{Name: "DEBUG_HOLD_HOURS", Default: "0"},
+	{Name: "DEBUG_HOLD_LABELS"},
+	{Name: "DIAGNOSTICS_S3_URI"},
+	{Name: "DYNAMODB_TABLE_NAME", Default: "github-runners"},
+	{Name: "EC2_AMI_ID"},
+	{Name: "EC2_AMI_ID_ARM64"},
+	{Name: "EC2_AMI_ID_WINDOWS"},
+	{Name: "EC2_ASSOCIATE_PUBLIC_IP"},
+	{Name: "EC2_INSTANCE_PROFILE"},
+	{Name: "EC2_INSTANCE_TYPE", Default: "t3.medium"},
+	{Name: "EC2_INSTANCE_TYPE_ARM64", Default: "t4g.medium"},
+	{Name: "EC2_INSTANCE_TYPES"},
+	{Name: "EC2_IPV6_ADDRESS_COUNT", Default: "0"},
+	{Name: "EC2_KEY_PAIR_NAME"},
+	{Name: "EC2_LAUNCH_TEMPLATE_ID"},
+	{Name: "EC2_LAUNCH_TEMPLATE_VERSION"},
+	{Name: "EC2_PLACEMENT_GROUP"},
+	{Name: "EC2_SECURITY_GROUP_ID"},
+	{Name: "EC2_SECURITY_GROUP_IDS"},
+	{Name: "EC2_SPOT_PRICE"},
+	{Name: "EC2_SPOT_PRICES"},
+	{Name: "EC2_SUBNET_ID"},
+	{Name: "EC2_SUBNET_IDS"},
+	{Name: "EC2_TAGS"},
+	{Name: "EC2_TENANCY", Default: "default"},
+	{Name: "EXCLUDED_LABELS"},
+	{Name: "GENERATION_DRAIN_BATCH", Default: "2"},
+	{Name: "GITHUB_APP_ID"},
+	{Name: "GITHUB_APP_INSTALLATION_ID"},
+	{Name: "GITHUB_APP_PRIVATE_KEY_SECRET_ARN"},
+	{Name: "GITHUB_ENTERPRISE_URL", Default: "https://TelenorSwedenAB.ghe.com"},
+	{Name: "GITHUB_TOKEN", Secret: true},
+	{Name: "GITHUB_TOKEN_SECRET_ARN"},
+	{Name: "GITHUB_TOKEN_SECRET_TTL", Default: "5m"},
+	{Name: "IDLE_TIMEOUT", Default: "0s"},
+	{Name: "INTERRUPTIONS_TABLE_NAME"},
+	{Name: "INTERRUPTION_JOB_THRESHOLD", Default: "5"},
+	{Name: "INTERRUPTION_REPORT_WINDOW", Default: "168h"},
+	{Name: "LOCK_LEASE", Default: "15m"},
+	{Name: "LOCK_TABLE_NAME", Default: "github-runners-locks"},
+	{Name: "LOG_FORMAT", Default: "json"},
+	{Name: "LOG_LEVEL", Default: "info"},
+	{Name: "MAX_RUNNERS", Default: "10"},
+	{Name: "METRICS_EMF", Default: "true"},
+	{Name: "METRICS_NAMESPACE", Default: "GitHubRunnerScaler"},
+	{Name: "MIN_RUNNERS", Default: "0"},
+	{Name: "ON_DEMAND_ONLY", Default: "false"},
+	{Name: "ON_DEMAND_PERCENTAGE", Default: "0"},
+	{Name: "ORGANIZATION_NAME", Default: "TelenorSweden"},
+	{Name: "ORPHAN_MAX_AGE", Default: "3h"},
+	{Name: "PREWARM_IMAGES"},
+	{Name: "PRIVATE_BOOTSTRAP", Default: "false"},
+	{Name: "PROBE_WORKFLOW"},
+	{Name: "PUSHGATEWAY_URL"},
+	{Name: "RECYCLE_STALE_RUNNERS", Default: "false"},
+	{Name: "REPLACE_INTERRUPTED_RUNNERS", Default: "true"},
+	{Name: "REPOSITORY_NAMES"},
+	{Name: "REQUIRE_PROBED_AMI", Default: "false"},
+	{Name: "ROLLOUTS_TABLE_NAME", Default: "github-runners-ami-rollouts"},
+	{Name: "RUNNER_ARCHITECTURE", Default: architectureX64},
+	{Name: "RUNNER_LABELS"},
+	{Name: "RUNNER_NAME_PREFIX", Default: "lambda-runner"},
+	{Name: "RUNNER_OS", Default: runnerOSLinux},
+	{Name: "RUNNER_NAME_TEMPLATE", Default: runnername.DefaultTemplate},
+	{Name: "RUNNER_RECORD_RETENTION", Default: "720h"},
+	{Name: "RUNNER_REGISTRATION_TIMEOUT", Default: "0s"},
+	{Name: "RUNNER_SCALE_SET_NAME"},
+	{Name: "RUNNER_TARBALL_S3_URI"},
+	{Name: "RUNNER_TARBALL_S3_URI_ARM64"},
+	{Name: "RUNNER_TOKEN_PARAMETER_PATH"},
+	{Name: "SCALE_DOWN_DELAY", Default: "0s"},
+	{Name: "SCALE_POOLS"},
+	{Name: "SCHEDULE_FAST_INTERVAL", Default: "1m"},
+	{Name: "SCHEDULE_IDLE_INTERVAL", Default: "5m"},
+	{Name: "SCHEDULE_RULE_NAME", Default: "github-runner-scaler-schedule"},
+	{Name: "SELF_SCHEDULING", Default: "false"},
+	{Name: "SESSIONS_TABLE_NAME", Default: "github-runners-sessions"},
+	{Name: "SESSION_MAX_AGE", Default: "1h"},
+	{Name: "SESSION_RECORD_RETENTION", Default: "168h"},
+	{Name: "SPOT_ALLOCATION_STRATEGY", Default: allocationRandom},
+	{Name: "SPOT_INTERRUPTION_BEHAVIOR", Default: "terminate"},
+	{Name: "SPOT_REQUEST_FALLBACK", Default: spotFallbackNone},
+	{Name: "SPOT_REQUEST_TIMEOUT", Default: "10m"},
+	{Name: "TOOLCACHE_EFS_ID"},
+	{Name: "TOOLCACHE_SNAPSHOT_ID"},
+	{Name: "WEBHOOK_SECRET", Secret: true},
+	{Name: "WORKSPACE_CLEANUP", Default: "false"},
+	{Name: "WORKSPACE_CLEANUP_SCRIPT"},
+}
+
+// configSource resolves the settings of the scaler
+type configSource = configsource.Source
+
+// newConfigSource creates a source with the given flag values, keyed by setting name
+func newConfigSource(flags map[string]string) (*configSource, error) {
+	return configsource.New(configSettings, flags)
+}
+
+// parseConfigFlags turns --setting-name=value arguments into setting values
+func parseConfigFlags(args []string) (map[string]string, []string, error) {
+	return configsource.ParseFlags(configSettings, args)
+}
+
+// runConfigCommand runs the config subcommands when the binary is started outside Lambda:
+//
+//	bootstrap config dump [--redacted] [--config=file.json] [--max-runners=5 ...]
+func runConfigCommand(args []string) int {
+	flags, rest, err := parseConfigFlags(args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	redacted := false
+	var command []string
+	for _, arg := range rest {
+		if arg == "--redacted" {
+			redacted = true
+			continue
+		}
+		command = append(command, arg)
+	}
+	if len(command) != 2 || command[0] != "config" || command[1] != "dump" {
+		fmt.Fprintln(os.Stderr, "usage: config dump [--redacted] [--config=file.json] [--setting-name=value ...]")
+		return 2
+	}
+
+	src, err := newConfigSource(flags)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	src.Dump(os.Stdout, redacted)
+
+	if _, err := loadConfigFrom(src); err != nil {
+		fmt.Fprintf(os.Stderr, "\n❌ Configuration is invalid: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -3,8 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
-	"strings"
+	"time"
 )
 
 // CRDStyleJobAnalyzer implements the same logic as actions-runner-controller CRD
@@ -24,6 +23,12 @@ type JobCount struct {
 	
 	// Necessary replicas is the core metric used by ARC
 	NecessaryReplicas int `json:"necessary_replicas"`
+	
+	// Labels requested by counted jobs that only matched a configured pattern
+	MatchedLabels []string `json:"matched_labels,omitempty"`
+	
+	// How long the longest-waiting queued job has been waiting
+	MaxQueueWait time.Duration `json:"max_queue_wait,omitempty"`
 }
 
 // NewCRDStyleJobAnalyzer creates a new analyzer using CRD logic
@@ -37,27 +42,30 @@ func NewCRDStyleJobAnalyzer(client *GHEClient, config Config) *CRDStyleJobAnalyz
 // AnalyzeJobDemand implements the exact logic from actions-runner-controller
 // controllers/actions.summerwind.net/autoscaling.go:suggestReplicasByQueuedAndInProgressWorkflowRuns
 func (analyzer *CRDStyleJobAnalyzer) AnalyzeJobDemand(ctx context.Context) (*JobCount, error) {
-	log.Printf("🎯 Starting CRD-style job demand analysis...")
+	logger := loggerFrom(ctx)
+	logger.V(1).Info("Starting CRD-style job demand analysis")
 	
 	// Initialize counters like in ARC
 	var total, inProgress, queued, completed, unknown int
+	var matchedLabels []string
+	var oldestQueued time.Time
 	
 	// Get repositories to process
-	repos, err := analyzer.getRepositoriesToProcess(ctx)
+	repos, err := analyzer.client.GetMonitoredRepositories(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get repositories: %w", err)
 	}
 	
-	log.Printf("📊 Processing %d repositories for job analysis", len(repos))
+	logger.V(1).Info("Processing repositories for job analysis", "repositories", len(repos))
 	
 	// Process each repository (following ARC pattern)
 	for _, repo := range repos {
-		log.Printf("🔍 Processing repository: %s", repo.FullName)
+		logger.V(1).Info("Processing repository", "repository", repo.FullName)
 		
 		// Get workflow runs for this repository
 		workflowRuns, err := analyzer.client.getRepositoryWorkflowRuns(ctx, repo.Owner.Login, repo.Name, "")
 		if err != nil {
-			log.Printf("⚠️  Failed to get workflow runs for %s: %v", repo.FullName, err)
+			logger.Error(err, "Failed to get workflow runs", "repository", repo.FullName)
 			continue
 		}
 		
@@ -70,16 +78,15 @@ func (analyzer *CRDStyleJobAnalyzer) AnalyzeJobDemand(ctx context.Context) (*Job
 			case "completed":
 				completed++
 				// Don't fetch jobs for completed workflows to minimize API calls
-			case "in_progress":
-				jobCounts := analyzer.analyzeWorkflowJobs(ctx, repo.Owner.Login, repo.Name, run.ID)
-				inProgress += jobCounts.inProgress
-				queued += jobCounts.queued
-				unknown += jobCounts.unknown
-			case "queued":
+			case "in_progress", "queued":
 				jobCounts := analyzer.analyzeWorkflowJobs(ctx, repo.Owner.Login, repo.Name, run.ID)
 				inProgress += jobCounts.inProgress
 				queued += jobCounts.queued
 				unknown += jobCounts.unknown
+				matchedLabels = appendUniqueLabels(matchedLabels, jobCounts.matchedLabels)
+				if !jobCounts.oldestQueued.IsZero() && (oldestQueued.IsZero() || jobCounts.oldestQueued.Before(oldestQueued)) {
+					oldestQueued = jobCounts.oldestQueued
+				}
 			default:
 				unknown++
 			}
@@ -96,132 +103,89 @@ func (analyzer *CRDStyleJobAnalyzer) AnalyzeJobDemand(ctx context.Context) (*Job
 		Completed:         completed,
 		Unknown:           unknown,
 		NecessaryReplicas: necessaryReplicas,
+		MatchedLabels:     matchedLabels,
+	}
+	if !oldestQueued.IsZero() {
+		result.MaxQueueWait = time.Since(oldestQueued)
 	}
 	
-	log.Printf("🎯 CRD-style analysis complete: NecessaryReplicas=%d (queued=%d, inProgress=%d, total=%d)", 
-		necessaryReplicas, queued, inProgress, total)
+	logger.Info("CRD-style analysis complete", "necessaryReplicas", necessaryReplicas,
+		"queued", queued, "inProgress", inProgress, "total", total)
 	
 	return result, nil
 }
 
 // jobAnalysisResult represents job counts for a single workflow
 type jobAnalysisResult struct {
-	queued     int
-	inProgress int
-	unknown    int
+	queued        int
+	inProgress    int
+	unknown       int
+	matchedLabels []string
+	oldestQueued  time.Time // queue time of the longest-waiting queued job
 }
 
 // analyzeWorkflowJobs processes jobs for a specific workflow run
 // This implements the exact logic from ARC's listWorkflowJobs function
 func (analyzer *CRDStyleJobAnalyzer) analyzeWorkflowJobs(ctx context.Context, owner, repo string, runID int) jobAnalysisResult {
 	result := jobAnalysisResult{}
+	logger := loggerFrom(ctx).WithValues("repository", owner+"/"+repo, "runId", runID)
 	
 	// Get jobs for this workflow run
 	jobs, err := analyzer.client.GetWorkflowJobs(ctx, owner, repo, runID)
 	if err != nil {
-		log.Printf("⚠️  Failed to get jobs for workflow %d in %s/%s: %v", runID, owner, repo, err)
+		logger.Error(err, "Failed to get workflow jobs")
 		return result
 	}
 	
 	if len(jobs) == 0 {
-		log.Printf("🟡 Workflow %d in %s/%s has no jobs - ignoring for scaling", runID, owner, repo)
+		logger.V(1).Info("Workflow has no jobs, ignoring it for scaling")
 		return result
 	}
 	
-	log.Printf("📋 Analyzing %d jobs in workflow %d (%s/%s)", len(jobs), runID, owner, repo)
+	logger.V(1).Info("Analyzing workflow jobs", "jobs", len(jobs))
 	
-	// Create runner labels map for efficient lookup (following ARC pattern)
-	runnerLabels := make(map[string]struct{}, len(analyzer.config.RunnerLabels))
-	for _, label := range analyzer.config.RunnerLabels {
-		runnerLabels[label] = struct{}{}
-	}
+	// Runner label matcher (self-hosted is implicit, following ARC)
+	matcher := NewLabelMatcher(analyzer.config.RunnerLabels, jobLabelOptions.WithExclusions(analyzer.config.ExcludedLabels))
 	
 	// Process each job (following ARC's JOB loop)
 	JOB: for _, job := range jobs {
 		// Check if job has labels (following ARC validation)
 		if len(job.Labels) == 0 {
-			log.Printf("🟡 Job %d has no labels - skipping (not supported by ARC pattern)", job.ID)
+			logger.V(1).Info("Skipping job without labels", "jobId", job.ID)
 			continue JOB
 		}
 		
-		log.Printf("   🔍 Job %d: status=%s, labels=%v", job.ID, job.Status, job.Labels)
+		logger.V(1).Info("Job", "jobId", job.ID, "status", job.Status, "labels", job.Labels)
 		
-		// Check label compatibility (exact ARC logic)
-		for _, label := range job.Labels {
-			// Skip self-hosted label check (it's implicit)
-			if label == "self-hosted" {
-				continue
-			}
-			
-			// If runner doesn't have this required label, skip this job
-			if _, ok := runnerLabels[label]; !ok {
-				log.Printf("   ❌ Job %d requires label '%s' which runner doesn't have - skipping", job.ID, label)
-				continue JOB
-			}
+		// If runner labels, patterns or expressions don't cover the job, skip it
+		if reason := matcher.Reject(job.Labels); reason != "" {
+			logger.V(1).Info("Skipping job that does not match the runner", "jobId", job.ID, "reason", reason)
+			continue JOB
 		}
 		
 		// Job matches our runner capabilities - count it based on status
 		switch job.Status {
 		case "completed":
 			// Don't count completed jobs (following ARC logic)
-			log.Printf("   ⏭️  Job %d completed - not counted", job.ID)
+			logger.V(1).Info("Completed job not counted", "jobId", job.ID)
 		case "in_progress":
 			result.inProgress++
-			log.Printf("   🟢 Job %d in_progress - counted", job.ID)
+			result.matchedLabels = appendUniqueLabels(result.matchedLabels, matcher.PatternMatched(job.Labels))
+			logger.V(1).Info("In-progress job counted", "jobId", job.ID)
 		case "queued":
 			result.queued++
-			log.Printf("   🟡 Job %d queued - counted", job.ID)
+			result.matchedLabels = appendUniqueLabels(result.matchedLabels, matcher.PatternMatched(job.Labels))
+			if !job.CreatedAt.IsZero() && (result.oldestQueued.IsZero() || job.CreatedAt.Before(result.oldestQueued)) {
+				result.oldestQueued = job.CreatedAt
+			}
+			logger.V(1).Info("Queued job counted", "jobId", job.ID)
 		default:
 			result.unknown++
-			log.Printf("   ❓ Job %d has unknown status '%s'", job.ID, job.Status)
+			logger.Info("Job has an unknown status", "jobId", job.ID, "status", job.Status)
 		}
 	}
 	
-	log.Printf("   📊 Workflow %d results: queued=%d, inProgress=%d, unknown=%d", 
-		runID, result.queued, result.inProgress, result.unknown)
+	logger.V(1).Info("Workflow results", "queued", result.queued, "inProgress", result.inProgress, "unknown", result.unknown)
 	
 	return result
 }
-
-// getRepositoriesToProcess gets the list of repositories to analyze
-func (analyzer *CRDStyleJobAnalyzer) getRepositoriesToProcess(ctx context.Context) ([]Repository, error) {
-	// If specific repositories are configured, use them
-	if len(analyzer.config.RepositoryNames) > 0 {
-		var repos []Repository
-		for _, repoName := range analyzer.config.RepositoryNames {
-			owner, name := analyzer.config.OrganizationName, repoName
-			
-			// Handle "owner/repo" format
-			if strings.Contains(repoName, "/") {
-				parts := strings.Split(repoName, "/")
-				if len(parts) == 2 {
-					owner, name = parts[0], parts[1]
-				}
-			}
-			
-			repos = append(repos, Repository{
-				Name:     name,
-				FullName: fmt.Sprintf("%s/%s", owner, name),
-				Owner:    &Owner{Login: owner},
-			})
-		}
-		return repos, nil
-	}
-	
-	// Otherwise get all repositories in organization (but filter for Actions-enabled)
-	allRepos, err := analyzer.client.GetRepositoriesInOrganization(ctx)
-	if err != nil {
-		return nil, err
-	}
-	
-	// Filter to only include repositories with Actions enabled
-	var enabledRepos []Repository
-	for _, repo := range allRepos {
-		if analyzer.client.IsGitHubActionsEnabled(ctx, repo.Owner.Login, repo.Name) {
-			enabledRepos = append(enabledRepos, repo)
-		}
-	}
-	
-	log.Printf("📊 Found %d total repositories, %d with Actions enabled", len(allRepos), len(enabledRepos))
-	return enabledRepos, nil
-} 
\ No newline at end of file
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// debugHoldTag marks an instance kept alive after a failed job. Its value is the RFC 3339
+// deadline after which the instance is terminated.
+const debugHoldTag = "debug-hold"
+
+// debugHoldHoursFor returns how long runners with this configuration are held after a
+// failed job, or 0 when they are not. With DEBUG_HOLD_LABELS set, only runners carrying
+// one of those labels are held.
+func (c Config) debugHoldHoursFor() int {
+	if c.DebugHoldHours <= 0 {
+		return 0
+	}
+	if len(c.DebugHoldLabels) == 0 {
+		return c.DebugHoldHours
+	}
+	for _, label := range literalLabels(c.RunnerLabels) {
+		if slices.Contains(c.DebugHoldLabels, label) {
+			return c.DebugHoldHours
+		}
+	}
+	return 0
+}
+
+// debugHoldScript keeps the instance alive after a failed job instead of letting it
+// terminate itself, so developers can inspect the environment. The instance is tagged with
+// the deadline and terminates itself when it is reached; the scaler terminates held
+// instances past their deadline in case that never happens.
+func (c Config) debugHoldScript() string {
+	hours := c.debugHoldHoursFor()
+	if hours == 0 {
+		return ""
+	}
+	return fmt.Sprintf(`# Debug hold: keep the instance of a failed job for inspection until the deadline
+if grep -qs "Job result after all job steps finish: Failed" /home/runner/_diag/Worker_*.log; then
+    DEADLINE=$(date -u -d "+%d hours" +%%Y-%%m-%%dT%%H:%%M:%%SZ)
+    aws ec2 create-tags --resources $(curl -s $IMDS/latest/meta-data/instance-id) --tags Key=%s,Value=$DEADLINE --region $REGION || true
+    echo "Job failed, holding instance for debugging until $DEADLINE"
+    sleep %d
+fi
+`, hours, debugHoldTag, hours*3600)
+}
+
+// expireDebugHolds terminates this pool's held instances whose deadline has passed
+func expireDebugHolds(ctx context.Context, awsInfra *AWSInfrastructure, config Config) error {
+	paginator := ec2.NewDescribeInstancesPaginator(awsInfra.ec2Client, &ec2.DescribeInstancesInput{
+		Filters: []ec2types.Filter{
+			{Name: awsInfra.String("tag:ManagedBy"), Values: []string{"github-runner-scaler-lambda"}},
+			{Name: awsInfra.String("tag-key"), Values: []string{debugHoldTag}},
+			{Name: awsInfra.String("instance-state-name"), Values: []string{"pending", "running", "stopping", "stopped"}},
+		},
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to describe held instances: %w", err)
+		}
+		for _, reservation := range page.Reservations {
+			for _, instance := range reservation.Instances {
+				tags := tagValues(instance.Tags)
+				if tags["Pool"] != config.PoolName {
+					continue
+				}
+				// An unreadable deadline counts as expired, so nothing is held forever
+				deadline, err := time.Parse(time.RFC3339, tags[debugHoldTag])
+				if err == nil && time.Now().Before(deadline) {
+					continue
+				}
+
+				runner := launchedRunner{RunnerName: tags["RunnerName"], InstanceID: *instance.InstanceId}
+				if instance.SpotInstanceRequestId != nil {
+					runner.SpotRequestID = *instance.SpotInstanceRequestId
+				}
+				if err := awsInfra.terminateLaunchedRunner(ctx, runner); err != nil {
+					loggerFrom(ctx).Error(err, "Failed to terminate held instance", "instanceId", runner.InstanceID)
+					continue
+				}
+				loggerFrom(ctx).Info("Debug hold expired, runner terminated", "runnerName", runner.RunnerName, "instanceId", runner.InstanceID)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+const (
+	// diagnosticsCommand is installed on runner instances by the bootstrap when
+	// DIAGNOSTICS_S3_URI is set. It takes the reason for the upload as its argument.
+	diagnosticsCommand = "/usr/local/bin/upload-runner-diagnostics"
+
+	// diagnosticsRequestedTag marks an instance the scaler asked to upload its diagnostics
+	diagnosticsRequestedTag = "DiagnosticsRequestedAt"
+
+	// diagnosticsUploadGrace is how long an instance is given to upload its diagnostics
+	// before the scaler terminates it
+	diagnosticsUploadGrace = 2 * time.Minute
+)
+
+// diagnosticsScript installs the diagnostics upload command and uploads on any bootstrap
+// failure. The archive holds the runner _diag directory and the cloud-init logs and is
+// stored under the instance ID, so it outlives the instance. It runs as root.
+func (c Config) diagnosticsScript() string {
+	if c.DiagnosticsS3URI == "" {
+		return ""
+	}
+	return fmt.Sprintf(`# Upload the runner _diag directory and cloud-init logs to S3 when the runner fails
+printf 'REGION=%%s\n' "$REGION" > /etc/default/runner-diagnostics
+cat > %s << 'DIAG'
+#!/bin/bash
+. /etc/default/runner-diagnostics
+INSTANCE_ID=$(cat /var/lib/cloud/data/instance-id)
+ARCHIVE=/tmp/runner-diagnostics.tar.gz
+tar czf $ARCHIVE --ignore-failed-read /home/runner/_diag /var/log/cloud-init.log /var/log/cloud-init-output.log 2> /dev/null
+aws s3 cp --region $REGION $ARCHIVE %s/$INSTANCE_ID/$(date -u +%%Y%%m%%dT%%H%%M%%SZ)-${1:-unknown}.tar.gz
+DIAG
+chmod +x %s
+trap '%s bootstrap-failed' ERR
+`, diagnosticsCommand, strings.TrimSuffix(c.DiagnosticsS3URI, "/"), diagnosticsCommand, diagnosticsCommand)
+}
+
+// ssmCommandClient sends Run Command, Session Manager and Parameter Store requests through
+// the SSM JSON API
+type ssmCommandClient struct {
+	credentials awssdk.CredentialsProvider
+	region      string
+	httpClient  *http.Client
+	signer      *v4.Signer
+}
+
+func newSSMCommandClient(cfg awssdk.Config) *ssmCommandClient {
+	return &ssmCommandClient{
+		credentials: cfg.Credentials,
+		region:      cfg.Region,
+		httpClient:  &http.Client{Timeout: 30 * time.Second},
+		signer:      v4.NewSigner(),
+	}
+}
+
+// SendShellCommand runs a shell command on the instances with AWS-RunShellScript and
+// returns the command ID. It does not wait for the command to finish.
+func (c *ssmCommandClient) SendShellCommand(ctx context.Context, instanceIDs []string, comment, command string) (string, error) {
+	var result struct {
+		Command struct {
+			CommandID string `json:"CommandId"`
+		} `json:"Command"`
+	}
+	err := c.call(ctx, "SendCommand", map[string]interface{}{
+		"DocumentName": "AWS-RunShellScript",
+		"InstanceIds":  instanceIDs,
+		"Comment":      comment,
+		"Parameters":   map[string][]string{"commands": {command}},
+	}, &result)
+	if err != nil {
+		return "", err
+	}
+	return result.Command.CommandID, nil
+}
+
+// TerminateInstanceSessions ends the active Session Manager sessions on an instance and
+// returns how many were ended
+func (c *ssmCommandClient) TerminateInstanceSessions(ctx context.Context, instanceID string) (int, error) {
+	var sessions struct {
+		Sessions []struct {
+			SessionID string `json:"SessionId"`
+		} `json:"Sessions"`
+	}
+	err := c.call(ctx, "DescribeSessions", map[string]interface{}{
+		"State":   "Active",
+		"Filters": []map[string]string{{"key": "Target", "value": instanceID}},
+	}, &sessions)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, session := range sessions.Sessions {
+		if err := c.call(ctx, "TerminateSession", map[string]string{"SessionId": session.SessionID}, nil); err != nil {
+			return 0, err
+		}
+	}
+	return len(sessions.Sessions), nil
+}
+
+// PutSecureParameter stores a value as a SecureString parameter encrypted with the
+// account's default SSM key, overwriting an earlier one
+func (c *ssmCommandClient) PutSecureParameter(ctx context.Context, name, value, description string) error {
+	return c.call(ctx, "PutParameter", map[string]interface{}{
+		"Name":        name,
+		"Value":       value,
+		"Type":        "SecureString",
+		"Description": description,
+		"Overwrite":   true,
+	}, nil)
+}
+
+// DeleteParameter deletes a parameter
+func (c *ssmCommandClient) DeleteParameter(ctx context.Context, name string) error {
+	return c.call(ctx, "DeleteParameter", map[string]string{"Name": name}, nil)
+}
+
+// call invokes an SSM operation with a signed JSON request and decodes the response into
+// output unless it is nil
+func (c *ssmCommandClient) call(ctx context.Context, operation string, input, output interface{}) error {
+	return c.callService(ctx, "ssm", c.region, "AmazonSSM."+operation, input, output)
+}
+
+// callService invokes an operation of an AWS JSON 1.1 service, such as SSM or Secrets
+// Manager, in the given region with a request signed by the client's credentials
+func (c *ssmCommandClient) callService(ctx context.Context, service, region, target string, input, output interface{}) error {
+	payload, err := json.Marshal(input)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s request: %w", target, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("https://%s.%s.amazonaws.com/", service, region), bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", target)
+
+	credentials, err := c.credentials.Retrieve(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve credentials: %w", err)
+	}
+	sum := sha256.Sum256(payload)
+	if err := c.signer.SignHTTP(ctx, credentials, req, hex.EncodeToString(sum[:]), service, region, time.Now()); err != nil {
+		return fmt.Errorf("failed to sign request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%s failed (HTTP %d): %s", target, resp.StatusCode, string(body))
+	}
+
+	if output == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(output); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", target, err)
+	}
+	return nil
+}
+
+// reconcileUnregisteredRunners terminates this pool's runner instances that have not
+// registered within the registration timeout. With diagnostics enabled the instance is
+// first asked through SSM to upload its diagnostics, and terminated on a later cycle once
+// the upload had time to finish.
+func reconcileUnregisteredRunners(ctx context.Context, gheClient *GHEClient, awsInfra *AWSInfrastructure, config Config) error {
+	if config.RegistrationTimeout <= 0 {
+		return nil
+	}
+
+	registered, err := gheClient.GetSelfHostedRunners(ctx)
+	if err != nil {
+		return err
+	}
+	names := make(map[string]bool, len(registered.Runners))
+	for _, runner := range registered.Runners {
+		names[runner.Name] = true
+	}
+
+	paginator := ec2.NewDescribeInstancesPaginator(awsInfra.ec2Client, &ec2.DescribeInstancesInput{
+		Filters: []ec2types.Filter{
+			{Name: awsInfra.String("tag:ManagedBy"), Values: []string{"github-runner-scaler-lambda"}},
+			{Name: awsInfra.String("tag:Purpose"), Values: []string{"github-actions-runner"}},
+			{Name: awsInfra.String("instance-state-name"), Values: []string{"running"}},
+		},
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to describe runner instances: %w", err)
+		}
+		for _, reservation := range page.Reservations {
+			for _, instance := range reservation.Instances {
+				tags := tagValues(instance.Tags)
+				if tags["Pool"] != config.PoolName || names[tags["RunnerName"]] {
+					continue
+				}
+				// Instances held after a failed job have deregistered on purpose
+				if _, held := tags[debugHoldTag]; held {
+					continue
+				}
+				if instance.LaunchTime == nil || time.Since(*instance.LaunchTime) < config.RegistrationTimeout {
+					continue
+				}
+				awsInfra.handleUnregisteredRunner(ctx, instance, tags)
+			}
+		}
+	}
+	return nil
+}
+
+// handleUnregisteredRunner requests a diagnostics upload from an instance whose runner never
+// registered, or terminates it once the upload grace period has passed
+func (aws *AWSInfrastructure) handleUnregisteredRunner(ctx context.Context, instance ec2types.Instance, tags map[string]string) {
+	runner := launchedRunner{RunnerName: tags["RunnerName"], InstanceID: *instance.InstanceId}
+	if instance.SpotInstanceRequestId != nil {
+		runner.SpotRequestID = *instance.SpotInstanceRequestId
+	}
+
+	if aws.config.DiagnosticsS3URI != "" {
+		requestedAt, requested := tags[diagnosticsRequestedTag]
+		if !requested {
+			loggerFrom(ctx).Info("Runner has not registered, requesting diagnostics", "runnerName", runner.RunnerName, "instanceId", runner.InstanceID)
+			if _, err := aws.ssmClient.SendShellCommand(ctx, []string{runner.InstanceID},
+				"Upload diagnostics of unregistered runner "+runner.RunnerName, diagnosticsCommand+" registration-timeout"); err != nil {
+				loggerFrom(ctx).Error(err, "Failed to request diagnostics", "instanceId", runner.InstanceID)
+			}
+			// Tag even when the command failed, so an instance without SSM is not kept forever
+			if _, err := aws.ec2Client.CreateTags(ctx, &ec2.CreateTagsInput{
+				Resources: []string{runner.InstanceID},
+				Tags:      []ec2types.Tag{{Key: aws.String(diagnosticsRequestedTag), Value: aws.String(time.Now().UTC().Format(time.RFC3339))}},
+			}); err != nil {
+				loggerFrom(ctx).Error(err, "Failed to tag instance", "instanceId", runner.InstanceID, "tag", diagnosticsRequestedTag)
+			}
+			return
+		}
+		if at, err := time.Parse(time.RFC3339, requestedAt); err == nil && time.Since(at) < diagnosticsUploadGrace {
+			return
+		}
+	}
+
+	if err := aws.terminateLaunchedRunner(ctx, runner); err != nil {
+		loggerFrom(ctx).Error(err, "Failed to terminate unregistered runner", "runnerName", runner.RunnerName)
+		return
+	}
+	loggerFrom(ctx).Info("Terminated runner that never registered", "runnerName", runner.RunnerName, "instanceId", runner.InstanceID)
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// EC2SpotProvider runs runners on EC2 spot instances, or on-demand ones where configured
+type EC2SpotProvider struct {
+	aws *AWSInfrastructure
+}
+
+var _ RunnerProvider = (*EC2SpotProvider)(nil)
+
+// CreateRunner launches a spot instance, or an on-demand one where the pool asks for it,
+// that registers the runner, and returns its instance ID
+func (p *EC2SpotProvider) CreateRunner(ctx context.Context, spec RunnerSpec) (string, error) {
+	aws, runnerName := p.aws, spec.Name
+
+	// During a blue/green rollout a share of the pool's runners boots from the canary AMI
+	launchInfra := aws
+	ami, channel := aws.chooseAMI(ctx)
+	if ami != aws.config.EC2AMI {
+		canaryInfra := *aws
+		canaryInfra.config.EC2AMI = ami
+		launchInfra = &canaryInfra
+	}
+	tags := launchInfra.runnerTags(runnerName)
+	if channel != "" {
+		tags = append(tags, ec2types.Tag{Key: aws.String(amiChannelTag), Value: aws.String(channel)})
+	}
+
+	if aws.config.RequireProbedAMI {
+		if err := launchInfra.ensureAMIReady(ctx); err != nil {
+			return "", err
+		}
+	}
+
+	// Generate user data script for runner installation
+	userData, err := aws.runnerUserData(ctx, runnerName, spec.RegistrationToken, spec.Labels)
+	if err != nil {
+		return "", err
+	}
+
+	// Base64 encode the user data script (required by AWS)
+	userDataEncoded := base64.StdEncoding.EncodeToString([]byte(userData))
+
+	// Pools may spread launches over several instance types and subnets, and mix in on-demand.
+	// Spot launches over several types go through EC2 Fleet, which falls back among them.
+	var instanceID *string
+	target := aws.chooseLaunchTarget()
+	if !target.OnDemand && aws.useFleet() {
+		instanceID, err = launchInfra.launchFleetInstance(ctx, runnerName, userDataEncoded, tags)
+	} else {
+		if !target.OnDemand {
+			target = aws.allocateSpotTarget(ctx, target)
+		}
+		instanceID, err = launchInfra.launchInstance(ctx, runnerName, userDataEncoded, target, tags)
+	}
+	if err != nil {
+		aws.deleteRunnerToken(context.WithoutCancel(ctx), runnerName)
+		return "", err
+	}
+
+	// Store runner record in DynamoDB
+	if err := aws.storeRunnerRecord(ctx, RunnerRecord{
+		RunnerID:   runnerName,
+		InstanceID: *instanceID,
+		Status:     "pending",
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	}); err != nil {
+		loggerFrom(ctx).Error(err, "Failed to store runner record", "runnerName", runnerName)
+	}
+
+	return *instanceID, nil
+}
+
+// TerminateRunner terminates a runner instance. A persistent spot request would relaunch
+// the instance, so it is cancelled first.
+func (p *EC2SpotProvider) TerminateRunner(ctx context.Context, instanceID string) error {
+	aws := p.aws
+
+	if aws.config.spotRequestType() == ec2types.SpotInstanceTypePersistent {
+		result, err := aws.ec2Client.DescribeInstances(ctx, &ec2.DescribeInstancesInput{InstanceIds: []string{instanceID}})
+		if err != nil {
+			return fmt.Errorf("failed to describe instance: %w", err)
+		}
+		var spotRequestIDs []string
+		for _, reservation := range result.Reservations {
+			for _, instance := range reservation.Instances {
+				if instance.SpotInstanceRequestId != nil {
+					spotRequestIDs = append(spotRequestIDs, *instance.SpotInstanceRequestId)
+				}
+			}
+		}
+		if len(spotRequestIDs) > 0 {
+			if _, err := aws.ec2Client.CancelSpotInstanceRequests(ctx, &ec2.CancelSpotInstanceRequestsInput{
+				SpotInstanceRequestIds: spotRequestIDs,
+			}); err != nil {
+				return fmt.Errorf("failed to cancel spot instance requests: %w", err)
+			}
+		}
+	}
+
+	if _, err := aws.ec2Client.TerminateInstances(ctx, &ec2.TerminateInstancesInput{
+		InstanceIds: []string{instanceID},
+	}); err != nil {
+		return fmt.Errorf("failed to terminate instance: %w", err)
+	}
+	return nil
+}
+
+// ListRunners lists the pending and running instances the scaler launched for the pool
+func (p *EC2SpotProvider) ListRunners(ctx context.Context) ([]ProvisionedRunner, error) {
+	aws := p.aws
+	filters := []ec2types.Filter{
+		{Name: aws.String("tag:ManagedBy"), Values: []string{"github-runner-scaler-lambda"}},
+		{Name: aws.String("tag:Purpose"), Values: []string{"github-actions-runner"}},
+		{Name: aws.String("instance-state-name"), Values: []string{"pending", "running"}},
+	}
+	if aws.config.PoolName != "" {
+		filters = append(filters, ec2types.Filter{Name: aws.String("tag:Pool"), Values: []string{aws.config.PoolName}})
+	}
+
+	var runners []ProvisionedRunner
+	paginator := ec2.NewDescribeInstancesPaginator(aws.ec2Client, &ec2.DescribeInstancesInput{Filters: filters})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe runner instances: %w", err)
+		}
+		for _, reservation := range page.Reservations {
+			for _, instance := range reservation.Instances {
+				runner := ProvisionedRunner{
+					ID:   *instance.InstanceId,
+					Name: tagValues(instance.Tags)["RunnerName"],
+				}
+				if instance.State != nil {
+					runner.State = string(instance.State.Name)
+				}
+				if instance.LaunchTime != nil {
+					runner.LaunchTime = *instance.LaunchTime
+				}
+				runners = append(runners, runner)
+			}
+		}
+	}
+	return runners, nil
+}
+
+// GetCapacity reports no fixed limit: EC2 capacity and quota shortfalls surface as failed launches
+func (p *EC2SpotProvider) GetCapacity(ctx context.Context) (int, error) {
+	return unlimitedCapacity, nil
+}
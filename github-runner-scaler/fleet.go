@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+
+	"github.com/Anshuman2121/actionsspot/internal/ec2launch"
+)
+
+// launchTemplateNameInvalid matches the characters EC2 does not allow in launch template names
+var launchTemplateNameInvalid = regexp.MustCompile(`[^a-zA-Z0-9().\-/_]`)
+
+// fleetAllocationStrategies maps the allocation strategies EC2 Fleet applies itself to
+// their fleet names. Random allocation is done by the scaler and launches single types.
+var fleetAllocationStrategies = map[string]ec2types.SpotAllocationStrategy{
+	allocationLowestPrice:            ec2types.SpotAllocationStrategyLowestPrice,
+	allocationCapacityOptimized:      ec2types.SpotAllocationStrategyCapacityOptimized,
+	allocationPrioritized:            ec2types.SpotAllocationStrategyCapacityOptimizedPrioritized,
+	allocationPriceCapacityOptimized: ec2types.SpotAllocationStrategyPriceCapacityOptimized,
+}
+
+// useFleet reports whether spot runners are launched through EC2 Fleet. Fleet gets every
+// instance type and subnet at once and falls back among them when a spot pool is out of
+// capacity. Instant fleets only place one-time requests, so runners that EC2 stops or
+// hibernates on interruption are launched with RunInstances, as are runners from a
+// configured launch template, whose user data a fleet cannot override.
+func (aws *AWSInfrastructure) useFleet() bool {
+	if _, ok := fleetAllocationStrategies[aws.config.SpotAllocationStrategy]; !ok {
+		return false
+	}
+	if aws.config.EC2LaunchTemplateID != "" {
+		return false
+	}
+	if aws.config.spotRequestType() != ec2types.SpotInstanceTypeOneTime {
+		return false
+	}
+	return len(aws.config.launchInstanceTypes()) > 1 || len(aws.config.launchSubnetIDs()) > 1
+}
+
+// launchFleetInstance launches one spot runner with an instant EC2 Fleet over every
+// configured instance type and subnet and returns its instance ID. Instance types take
+// their configured order as priority, which the prioritized strategy honours. Fleets only
+// launch from templates, so the runner's settings go into a launch template that is
+// deleted again once the fleet has launched.
+func (aws *AWSInfrastructure) launchFleetInstance(ctx context.Context, runnerName, userDataEncoded string, tags []ec2types.Tag) (*string, error) {
+	template, err := aws.ec2Client.CreateLaunchTemplate(ctx, &ec2.CreateLaunchTemplateInput{
+		LaunchTemplateName: aws.String(launchTemplateNameInvalid.ReplaceAllString("github-runner-"+runnerName, "-")),
+		LaunchTemplateData: aws.launchTemplateData(userDataEncoded, tags),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create launch template: %w", err)
+	}
+	templateID := template.LaunchTemplate.LaunchTemplateId
+	defer func() {
+		if _, err := aws.ec2Client.DeleteLaunchTemplate(ctx, &ec2.DeleteLaunchTemplateInput{LaunchTemplateId: templateID}); err != nil {
+			loggerFrom(ctx).Error(err, "Failed to delete launch template", "launchTemplateId", *templateID, "runnerName", runnerName)
+		}
+	}()
+
+	var overrides []ec2types.FleetLaunchTemplateOverridesRequest
+	for priority, instanceType := range aws.config.launchInstanceTypes() {
+		for _, subnetID := range aws.config.launchSubnetIDs() {
+			overrides = append(overrides, ec2types.FleetLaunchTemplateOverridesRequest{
+				InstanceType: ec2types.InstanceType(instanceType),
+				SubnetId:     aws.String(subnetID),
+				MaxPrice:     ec2launch.SpotPriceFor(aws.config.EC2SpotPrices, instanceType),
+				Priority:     aws.Float64(float64(priority)),
+			})
+		}
+	}
+
+	result, err := aws.ec2Client.CreateFleet(ctx, &ec2.CreateFleetInput{
+		Type: ec2types.FleetTypeInstant,
+		LaunchTemplateConfigs: []ec2types.FleetLaunchTemplateConfigRequest{{
+			LaunchTemplateSpecification: &ec2types.FleetLaunchTemplateSpecificationRequest{
+				LaunchTemplateId: templateID,
+				Version:          aws.String("$Latest"),
+			},
+			Overrides: overrides,
+		}},
+		TargetCapacitySpecification: &ec2types.TargetCapacitySpecificationRequest{
+			TotalTargetCapacity:       aws.Int32(1),
+			DefaultTargetCapacityType: ec2types.DefaultTargetCapacityTypeSpot,
+		},
+		SpotOptions: &ec2types.SpotOptionsRequest{
+			AllocationStrategy: fleetAllocationStrategies[aws.config.SpotAllocationStrategy],
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create spot fleet: %w", err)
+	}
+	for _, instance := range result.Instances {
+		if len(instance.InstanceIds) > 0 {
+			instanceID := instance.InstanceIds[0]
+			loggerFrom(ctx).Info("Created spot instance through EC2 Fleet", "instanceId", instanceID,
+				"instanceType", instance.InstanceType, "runnerName", runnerName)
+			return &instanceID, nil
+		}
+	}
+
+	// Without an instance the fleet reports why each spot pool failed
+	var reasons []string
+	for _, fleetErr := range result.Errors {
+		reason := fmt.Sprintf("%s: %s", aws.ToString(fleetErr.ErrorCode), aws.ToString(fleetErr.ErrorMessage))
+		if o := fleetErr.LaunchTemplateAndOverrides; o != nil && o.Overrides != nil {
+			reason = fmt.Sprintf("%s in %s: %s", o.Overrides.InstanceType, aws.ToString(o.Overrides.SubnetId), reason)
+		}
+		reasons = append(reasons, reason)
+	}
+	return nil, fmt.Errorf("no spot instance launched by EC2 Fleet: %s", strings.Join(reasons, "; "))
+}
+
+// launchTemplateData returns the launch template settings for a runner, the counterpart of
+// the RunInstances input built by launchInstance. Instance type and subnet come from the
+// fleet overrides.
+func (aws *AWSInfrastructure) launchTemplateData(userDataEncoded string, tags []ec2types.Tag) *ec2types.RequestLaunchTemplateData {
+	data := &ec2types.RequestLaunchTemplateData{
+		ImageId:          aws.String(aws.config.EC2AMI),
+		KeyName:          aws.String(aws.config.EC2KeyPairName),
+		SecurityGroupIds: aws.config.EC2SecurityGroupIDs,
+		UserData:         aws.String(userDataEncoded),
+		Monitoring:       &ec2types.LaunchTemplatesMonitoringRequest{Enabled: aws.Bool(true)},
+		TagSpecifications: []ec2types.LaunchTemplateTagSpecificationRequest{
+			{ResourceType: ec2types.ResourceTypeInstance, Tags: tags},
+			{ResourceType: ec2types.ResourceTypeVolume, Tags: tags},
+		},
+	}
+	if placement := aws.config.instancePlacement(); placement != nil {
+		data.Placement = &ec2types.LaunchTemplatePlacementRequest{
+			GroupName: placement.GroupName,
+			Tenancy:   placement.Tenancy,
+		}
+	}
+	for _, device := range aws.toolcacheBlockDevices() {
+		data.BlockDeviceMappings = append(data.BlockDeviceMappings, ec2types.LaunchTemplateBlockDeviceMappingRequest{
+			DeviceName: device.DeviceName,
+			Ebs: &ec2types.LaunchTemplateEbsBlockDeviceRequest{
+				SnapshotId:          device.Ebs.SnapshotId,
+				VolumeType:          device.Ebs.VolumeType,
+				DeleteOnTermination: device.Ebs.DeleteOnTermination,
+			},
+		})
+	}
+	if aws.config.EC2InstanceProfile != "" {
+		data.IamInstanceProfile = &ec2types.LaunchTemplateIamInstanceProfileSpecificationRequest{Name: aws.String(aws.config.EC2InstanceProfile)}
+	}
+	// The subnet of the network interface is set by the fleet override
+	if nics := aws.networkInterfaces(""); nics != nil {
+		nic := nics[0]
+		data.NetworkInterfaces = []ec2types.LaunchTemplateInstanceNetworkInterfaceSpecificationRequest{{
+			DeviceIndex:              nic.DeviceIndex,
+			Groups:                   nic.Groups,
+			AssociatePublicIpAddress: nic.AssociatePublicIpAddress,
+			DeleteOnTermination:      nic.DeleteOnTermination,
+			Ipv6AddressCount:         nic.Ipv6AddressCount,
+		}}
+		data.SecurityGroupIds = nil
+	}
+	if aws.config.EC2IPv6AddressCount > 0 {
+		data.MetadataOptions = &ec2types.LaunchTemplateInstanceMetadataOptionsRequest{
+			HttpProtocolIpv6: ec2types.LaunchTemplateInstanceMetadataProtocolIpv6Enabled,
+		}
+	}
+	return data
+}
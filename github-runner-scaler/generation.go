@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+const (
+	// poolGenerationTag carries the generation of the pool configuration a runner was launched with
+	poolGenerationTag = "PoolGeneration"
+
+	// drainingSinceTag marks an old-generation runner whose labels were replaced so no new
+	// jobs are routed to it
+	drainingSinceTag = "DrainingSince"
+
+	// drainLabel replaces the job labels of a draining runner
+	drainLabel = "draining"
+)
+
+// poolGeneration returns the generation of the pool configuration: a hash of the AMI,
+// the labels, the instance types and the bootstrap template. Any change to them starts a
+// new generation.
+func (aws *AWSInfrastructure) poolGeneration() string {
+	labels := slices.Clone(aws.config.RunnerLabels)
+	slices.Sort(labels)
+	instanceTypes := slices.Clone(aws.config.launchInstanceTypes())
+	slices.Sort(instanceTypes)
+
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		aws.config.EC2AMI,
+		strings.Join(labels, ","),
+		strings.Join(instanceTypes, ","),
+		aws.bootstrapHash(),
+	}, "\n")))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// currentGenerations returns the generations new runners of this pool are launched with.
+// During a blue/green AMI rollout both the stable and the canary AMI are current.
+func (aws *AWSInfrastructure) currentGenerations(ctx context.Context) (map[string]bool, error) {
+	rollout, err := NewAMIRolloutStore(aws.dynamoDBClient, aws.config.RolloutsTableName).Get(ctx, rolloutPoolName(aws.config.PoolName))
+	if err != nil {
+		return nil, err
+	}
+
+	amis := []string{aws.config.EC2AMI}
+	if rollout.StableAMI != "" {
+		amis = []string{rollout.StableAMI}
+	}
+	if rollout.CanaryAMI != "" {
+		amis = append(amis, rollout.CanaryAMI)
+	}
+
+	generations := make(map[string]bool, len(amis))
+	for _, ami := range amis {
+		generationInfra := *aws
+		generationInfra.config.EC2AMI = ami
+		generations[generationInfra.poolGeneration()] = true
+	}
+	return generations, nil
+}
+
+// drainOldGenerations drains this pool's runners launched with an earlier pool
+// configuration. Idle old-generation runners get their job labels replaced by "draining",
+// so jobs are no longer routed to them, and at most GENERATION_DRAIN_BATCH of the drained
+// runners are deregistered and terminated per cycle, letting demand move to current-generation
+// runners gradually. Busy runners finish their job first.
+func drainOldGenerations(ctx context.Context, gheClient *GHEClient, awsInfra *AWSInfrastructure, config Config) error {
+	if config.GenerationDrainBatch <= 0 {
+		return nil
+	}
+
+	current, err := awsInfra.currentGenerations(ctx)
+	if err != nil {
+		return err
+	}
+
+	registered, err := gheClient.GetSelfHostedRunners(ctx)
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]SelfHostedRunner, len(registered.Runners))
+	for _, runner := range registered.Runners {
+		byName[runner.Name] = runner
+	}
+
+	var draining []launchedRunner
+	paginator := ec2.NewDescribeInstancesPaginator(awsInfra.ec2Client, &ec2.DescribeInstancesInput{
+		Filters: []ec2types.Filter{
+			{Name: awsInfra.String("tag:ManagedBy"), Values: []string{"github-runner-scaler-lambda"}},
+			{Name: awsInfra.String("tag-key"), Values: []string{poolGenerationTag}},
+			{Name: awsInfra.String("instance-state-name"), Values: []string{"running"}},
+		},
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to describe runner instances: %w", err)
+		}
+		for _, reservation := range page.Reservations {
+			for _, instance := range reservation.Instances {
+				tags := tagValues(instance.Tags)
+				if tags["Pool"] != config.PoolName || current[tags[poolGenerationTag]] {
+					continue
+				}
+				if _, held := tags[debugHoldTag]; held {
+					continue
+				}
+				ghRunner, ok := byName[tags["RunnerName"]]
+				if !ok || ghRunner.Busy {
+					continue
+				}
+
+				runner := launchedRunner{RunnerName: ghRunner.Name, InstanceID: *instance.InstanceId}
+				if instance.SpotInstanceRequestId != nil {
+					runner.SpotRequestID = *instance.SpotInstanceRequestId
+				}
+				if _, ok := tags[drainingSinceTag]; ok {
+					draining = append(draining, runner)
+					continue
+				}
+
+				if err := gheClient.SetRunnerLabels(ctx, ghRunner.ID, []string{drainLabel}); err != nil {
+					loggerFrom(ctx).Error(err, "Failed to drain runner", "runnerName", runner.RunnerName)
+					continue
+				}
+				if _, err := awsInfra.ec2Client.CreateTags(ctx, &ec2.CreateTagsInput{
+					Resources: []string{runner.InstanceID},
+					Tags:      []ec2types.Tag{{Key: awsInfra.String(drainingSinceTag), Value: awsInfra.String(time.Now().UTC().Format(time.RFC3339))}},
+				}); err != nil {
+					loggerFrom(ctx).Error(err, "Failed to tag instance", "instanceId", runner.InstanceID, "tag", drainingSinceTag)
+					continue
+				}
+				loggerFrom(ctx).Info("Draining runner", "runnerName", runner.RunnerName, "instanceId", runner.InstanceID,
+					"generation", tags[poolGenerationTag])
+			}
+		}
+	}
+
+	for i, runner := range draining {
+		if i >= config.GenerationDrainBatch {
+			loggerFrom(ctx).Info("Drained runners left for later cycles", "runners", len(draining)-i)
+			break
+		}
+		if err := gheClient.RemoveRunner(ctx, byName[runner.RunnerName].ID); err != nil {
+			loggerFrom(ctx).Error(err, "Failed to deregister drained runner", "runnerName", runner.RunnerName)
+			continue
+		}
+		if err := awsInfra.terminateLaunchedRunner(ctx, runner); err != nil {
+			loggerFrom(ctx).Error(err, "Failed to terminate drained runner", "runnerName", runner.RunnerName)
+			continue
+		}
+		loggerFrom(ctx).Info("Retired old-generation runner", "runnerName", runner.RunnerName)
+	}
+	return nil
+}
@@ -11,8 +11,8 @@ import (
 	"strings"
 	"time"
 
+	"github.com/Anshuman2121/actionsspot/internal/actions"
 	"github.com/go-logr/logr"
-	"github.com/google/uuid"
 )
 
 // GitHub Actions Service API endpoints
@@ -21,106 +21,19 @@ const (
 	apiVersion           = "6.0-preview"
 )
 
-// AcquirableJob represents a job that can be acquired by a runner
-type AcquirableJob struct {
-	AcquireJobURL   string   `json:"acquireJobUrl"`
-	MessageType     string   `json:"messageType"`
-	RunnerRequestID int64    `json:"runnerRequestId"`
-	RepositoryName  string   `json:"repositoryName"`
-	OwnerName       string   `json:"ownerName"`
-	JobWorkflowRef  string   `json:"jobWorkflowRef"`
-	EventName       string   `json:"eventName"`
-	RequestLabels   []string `json:"requestLabels"`
-}
-
-// AcquirableJobList represents the response from the acquirable jobs API
-type AcquirableJobList struct {
-	Count int             `json:"count"`
-	Jobs  []AcquirableJob `json:"value"`
-}
-
-// RunnerScaleSetSession represents a session for message polling
-type RunnerScaleSetSession struct {
-	SessionID               *uuid.UUID               `json:"sessionId,omitempty"`
-	OwnerName               string                   `json:"ownerName,omitempty"`
-	RunnerScaleSet          *RunnerScaleSet          `json:"runnerScaleSet,omitempty"`
-	MessageQueueURL         string                   `json:"messageQueueUrl,omitempty"`
-	MessageQueueAccessToken string                   `json:"messageQueueAccessToken,omitempty"`
-	Statistics              *RunnerScaleSetStatistic `json:"statistics,omitempty"`
-}
-
-// RunnerScaleSet represents a GitHub Actions runner scale set
-type RunnerScaleSet struct {
-	ID              int                      `json:"id,omitempty"`
-	Name            string                   `json:"name,omitempty"`
-	RunnerGroupID   int                      `json:"runnerGroupId,omitempty"`
-	RunnerGroupName string                   `json:"runnerGroupName,omitempty"`
-	Labels          []Label                  `json:"labels,omitempty"`
-	Statistics      *RunnerScaleSetStatistic `json:"statistics,omitempty"`
-}
-
-// Label represents a runner label
-type Label struct {
-	Type string `json:"type"`
-	Name string `json:"name"`
-}
-
-// RunnerScaleSetStatistic represents current statistics for a scale set
-type RunnerScaleSetStatistic struct {
-	TotalAvailableJobs     int `json:"totalAvailableJobs"`
-	TotalAcquiredJobs      int `json:"totalAcquiredJobs"`
-	TotalAssignedJobs      int `json:"totalAssignedJobs"`
-	TotalRunningJobs       int `json:"totalRunningJobs"`
-	TotalRegisteredRunners int `json:"totalRegisteredRunners"`
-	TotalBusyRunners       int `json:"totalBusyRunners"`
-	TotalIdleRunners       int `json:"totalIdleRunners"`
-}
-
-// RunnerScaleSetMessage represents a message from the Actions service
-type RunnerScaleSetMessage struct {
-	MessageID   int64                    `json:"messageId"`
-	MessageType string                   `json:"messageType"`
-	Body        string                   `json:"body"`
-	Statistics  *RunnerScaleSetStatistic `json:"statistics,omitempty"`
-}
-
-// JobAvailable represents a job available message
-type JobAvailable struct {
-	AcquireJobURL string `json:"acquireJobUrl"`
-	JobMessageBase
-}
-
-// JobMessageBase contains common job message fields
-type JobMessageBase struct {
-	MessageType        string    `json:"messageType"`
-	RunnerRequestID    int64     `json:"runnerRequestId"`
-	RepositoryName     string    `json:"repositoryName"`
-	OwnerName          string    `json:"ownerName"`
-	JobWorkflowRef     string    `json:"jobWorkflowRef"`
-	JobDisplayName     string    `json:"jobDisplayName"`
-	WorkflowRunID      int64     `json:"workflowRunId"`
-	EventName          string    `json:"eventName"`
-	RequestLabels      []string  `json:"requestLabels"`
-	QueueTime          time.Time `json:"queueTime"`
-	ScaleSetAssignTime time.Time `json:"scaleSetAssignTime"`
-	RunnerAssignTime   time.Time `json:"runnerAssignTime"`
-	FinishTime         time.Time `json:"finishTime"`
-}
-
-// ActionsError represents an error from the Actions service
-type ActionsError struct {
-	StatusCode int
-	ActivityID string
-	Message    string
-	Err        error
-}
-
-func (e *ActionsError) Error() string {
-	if e.Err != nil {
-		return fmt.Sprintf("Actions API error (status: %d, activity: %s): %v", e.StatusCode, e.ActivityID, e.Err)
-	}
-	return fmt.Sprintf("Actions API error (status: %d, activity: %s): %s", e.StatusCode, e.ActivityID, e.Message)
-}
+// Actions Service types, shared with ghaec2 and the Lambda through the actions package
+type (
+	AcquirableJob           = actions.AcquirableJob
+	AcquirableJobList       = actions.AcquirableJobList
+	RunnerScaleSetSession   = actions.RunnerScaleSetSession
+	RunnerScaleSet          = actions.RunnerScaleSet
+	Label                   = actions.Label
+	RunnerScaleSetStatistic = actions.RunnerScaleSetStatistic
+	RunnerScaleSetMessage   = actions.RunnerScaleSetMessage
+	JobAvailable            = actions.JobAvailable
+	JobMessageBase          = actions.JobMessageBase
+	ActionsError            = actions.ActionsError
+)
 
 // ActionsServiceClient provides access to GitHub Actions Service APIs
 type ActionsServiceClient struct {
@@ -291,6 +204,50 @@ func (c *ActionsServiceClient) CreateMessageSession(ctx context.Context, scaleSe
 	return &session, nil
 }
 
+// GenerateJitRunnerConfig registers a runner in the scale set and returns its just-in-time
+// configuration, which the instance passes to run.sh --jitconfig
+func (c *ActionsServiceClient) GenerateJitRunnerConfig(ctx context.Context, scaleSetID int, runnerName string) (*actions.RunnerScaleSetJitRunnerConfig, error) {
+	if err := c.refreshTokenIfNeeded(ctx); err != nil {
+		return nil, fmt.Errorf("failed to refresh token: %w", err)
+	}
+	
+	path := fmt.Sprintf("/%s/%d/generatejitconfig", scaleSetEndpoint, scaleSetID)
+	url := fmt.Sprintf("%s%s?api-version=%s", c.actionsTokenURL, path, apiVersion)
+	
+	body, err := json.Marshal(&actions.RunnerScaleSetJitRunnerSetting{Name: runnerName, WorkFolder: "_work"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal JIT runner setting: %w", err)
+	}
+	
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.adminToken))
+	req.Header.Set("Content-Type", "application/json")
+	
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+	
+	if resp.StatusCode != http.StatusOK {
+		return nil, c.parseErrorResponse(resp)
+	}
+	
+	var config actions.RunnerScaleSetJitRunnerConfig
+	if err := json.NewDecoder(resp.Body).Decode(&config); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if config.EncodedJITConfig == "" {
+		return nil, fmt.Errorf("empty JIT config for runner %s", runnerName)
+	}
+	
+	return &config, nil
+}
+
 // GetMessage polls for new messages from the message queue
 func (c *ActionsServiceClient) GetMessage(ctx context.Context, messageQueueURL, accessToken string, lastMessageID int64, maxCapacity int) (*RunnerScaleSetMessage, error) {
 	params := url.Values{}
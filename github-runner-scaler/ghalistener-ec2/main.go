@@ -9,13 +9,10 @@ import (
 	"strconv"
 	"strings"
 	"syscall"
-	"time"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
-	"github.com/go-logr/logr"
 	"github.com/go-logr/zapr"
 	"go.uber.org/zap"
 )
@@ -42,6 +39,7 @@ type Config struct {
 	EC2InstanceType     string
 	EC2AMI              string
 	EC2SpotPrice        string
+	DynamoDBTableName   string // runner state, keyed by InstanceId
 	
 	// Optional Repository Configuration
 	RepositoryNames []string
@@ -61,6 +59,7 @@ func LoadConfig() (*Config, error) {
 		EC2InstanceType:    os.Getenv("EC2_INSTANCE_TYPE"),
 		EC2AMI:             os.Getenv("EC2_AMI_ID"),
 		EC2SpotPrice:       os.Getenv("EC2_SPOT_PRICE"),
+		DynamoDBTableName:  os.Getenv("DYNAMODB_TABLE_NAME"),
 	}
 	
 	// Parse runner labels
@@ -116,6 +115,9 @@ func LoadConfig() (*Config, error) {
 	if config.AWSRegion == "" {
 		config.AWSRegion = "us-east-1"
 	}
+	if config.DynamoDBTableName == "" {
+		config.DynamoDBTableName = "gha-runner-state"
+	}
 	
 	return config, nil
 }
@@ -169,29 +171,29 @@ func main() {
 	logger := zapr.NewLogger(zapLogger)
 	
 	// Load configuration
-	config, err := LoadConfig()
+	cfg, err := LoadConfig()
 	if err != nil {
 		logger.Error(err, "Failed to load configuration")
 		os.Exit(1)
 	}
 	
-	if err := config.Validate(); err != nil {
+	if err := cfg.Validate(); err != nil {
 		logger.Error(err, "Configuration validation failed")
 		os.Exit(1)
 	}
 	
 	logger.Info("Starting GitHub Actions Listener EC2 Scaler",
-		"scaleSetID", config.RunnerScaleSetID,
-		"scaleSetName", config.RunnerScaleSetName,
-		"organization", config.OrganizationName,
-		"minRunners", config.MinRunners,
-		"maxRunners", config.MaxRunners,
-		"runnerLabels", config.RunnerLabels,
+		"scaleSetID", cfg.RunnerScaleSetID,
+		"scaleSetName", cfg.RunnerScaleSetName,
+		"organization", cfg.OrganizationName,
+		"minRunners", cfg.MinRunners,
+		"maxRunners", cfg.MaxRunners,
+		"runnerLabels", cfg.RunnerLabels,
 	)
 	
 	// Initialize AWS clients
 	ctx := context.Background()
-	awsConfig, err := config.LoadDefaultConfig(ctx, config.WithRegion(config.AWSRegion))
+	awsConfig, err := config.LoadDefaultConfig(ctx, config.WithRegion(cfg.AWSRegion))
 	if err != nil {
 		logger.Error(err, "Failed to load AWS configuration")
 		os.Exit(1)
@@ -201,7 +203,7 @@ func main() {
 	dynamoClient := dynamodb.NewFromConfig(awsConfig)
 	
 	// Create the scaler service
-	scaler, err := NewGHAListenerScaler(ctx, config, ec2Client, dynamoClient, logger)
+	scaler, err := NewGHAListenerScaler(ctx, cfg, ec2Client, dynamoClient, logger)
 	if err != nil {
 		logger.Error(err, "Failed to create scaler service")
 		os.Exit(1)
@@ -2,15 +2,22 @@ package main
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"os"
 	"slices"
-	"strings"
+	"strconv"
 	"time"
 
+	"github.com/Anshuman2121/actionsspot/internal/runnername"
+	"github.com/Anshuman2121/actionsspot/internal/runnerrecord"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamotypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/go-logr/logr"
 )
 
@@ -341,22 +348,138 @@ func (s *GHAListenerScaler) labelsMatch(jobLabels, runnerLabels []string) bool {
 	return true
 }
 
-// getCurrentRunnerCount gets the current number of running EC2 instances
+// getCurrentRunnerCount counts the pending and running runner instances. EC2 is the source
+// of truth and the runner state table refines it: an instance counts unless its record
+// already marks it finished, and records of instances that are gone are ignored.
 func (s *GHAListenerScaler) getCurrentRunnerCount(ctx context.Context) (int, error) {
-	// Implementation would use the same logic as your current Lambda
-	// For now, return a placeholder
-	return 0, nil
+	statusByInstance := make(map[string]string)
+	scan := dynamodb.NewScanPaginator(s.dynamoClient, &dynamodb.ScanInput{
+		TableName:                &s.config.DynamoDBTableName,
+		ProjectionExpression:     aws.String("InstanceId, #status"),
+		ExpressionAttributeNames: map[string]string{"#status": "Status"},
+	})
+	for scan.HasMorePages() {
+		page, err := scan.NextPage(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("failed to scan runner state: %w", err)
+		}
+		for _, item := range page.Items {
+			instanceID, _ := item["InstanceId"].(*dynamotypes.AttributeValueMemberS)
+			status, _ := item["Status"].(*dynamotypes.AttributeValueMemberS)
+			if instanceID != nil && status != nil {
+				statusByInstance[instanceID.Value] = status.Value
+			}
+		}
+	}
+
+	count := 0
+	describe := ec2.NewDescribeInstancesPaginator(s.ec2Client, &ec2.DescribeInstancesInput{
+		Filters: []ec2types.Filter{
+			{Name: aws.String("tag:Type"), Values: []string{"github-runner"}},
+			{Name: aws.String("instance-state-name"), Values: []string{"pending", "running"}},
+		},
+	})
+	for describe.HasMorePages() {
+		page, err := describe.NextPage(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("failed to describe runner instances: %w", err)
+		}
+		for _, reservation := range page.Reservations {
+			for _, instance := range reservation.Instances {
+				switch status, ok := statusByInstance[aws.ToString(instance.InstanceId)]; {
+				case !ok, status == runnerrecord.Pending, status == runnerrecord.Running:
+					count++
+				default:
+					s.logger.V(1).Info("Not counting finished runner", "instanceId", aws.ToString(instance.InstanceId), "status", status)
+				}
+			}
+		}
+	}
+	return count, nil
 }
 
-// createRunner creates a new EC2 spot instance
+// createRunner registers a JIT runner in the scale set and launches a spot instance for it.
+// The instance is tagged Type=github-runner and gets a pending record in the runner state
+// table, which is what getCurrentRunnerCount counts.
 func (s *GHAListenerScaler) createRunner(ctx context.Context) error {
-	s.logger.Info("Creating new runner instance")
+	if s.scaleSet == nil {
+		return fmt.Errorf("no runner scale set initialized")
+	}
+	runnerName := runnername.Format("{scaleset}-{timestamp}-{id}", runnername.Fields{ScaleSet: s.scaleSet.Name})
+	s.logger.Info("Creating new runner instance", "runnerName", runnerName)
+	
+	jitConfig, err := s.actionsClient.GenerateJitRunnerConfig(ctx, s.scaleSet.ID, runnerName)
+	if err != nil {
+		return fmt.Errorf("failed to generate JIT config for runner %s: %w", runnerName, err)
+	}
 	
-	// Implementation would use the same EC2 spot instance creation logic as your current Lambda
-	// Including the runner registration script and proper labeling
+	userData := fmt.Sprintf(`#!/bin/bash
+set -e
+cd /home/runner/actions-runner
+sudo -u runner ./run.sh --jitconfig %s || true
+shutdown -h now
+`, jitConfig.EncodedJITConfig)
 	
-	// Placeholder implementation
-	s.logger.Info("Runner creation logic to be implemented")
+	spotOptions := &ec2types.SpotMarketOptions{
+		SpotInstanceType:             ec2types.SpotInstanceTypeOneTime,
+		InstanceInterruptionBehavior: ec2types.InstanceInterruptionBehaviorTerminate,
+	}
+	if s.config.EC2SpotPrice != "" {
+		spotOptions.MaxPrice = aws.String(s.config.EC2SpotPrice)
+	}
+	input := &ec2.RunInstancesInput{
+		ImageId:                           aws.String(s.config.EC2AMI),
+		InstanceType:                      ec2types.InstanceType(s.config.EC2InstanceType),
+		MinCount:                          aws.Int32(1),
+		MaxCount:                          aws.Int32(1),
+		SubnetId:                          aws.String(s.config.EC2SubnetID),
+		SecurityGroupIds:                  []string{s.config.EC2SecurityGroupID},
+		UserData:                          aws.String(base64.StdEncoding.EncodeToString([]byte(userData))),
+		InstanceInitiatedShutdownBehavior: ec2types.ShutdownBehaviorTerminate,
+		InstanceMarketOptions: &ec2types.InstanceMarketOptionsRequest{
+			MarketType:  ec2types.MarketTypeSpot,
+			SpotOptions: spotOptions,
+		},
+		TagSpecifications: []ec2types.TagSpecification{{
+			ResourceType: ec2types.ResourceTypeInstance,
+			Tags: []ec2types.Tag{
+				{Key: aws.String("Name"), Value: aws.String(runnerName)},
+				{Key: aws.String("Type"), Value: aws.String("github-runner")},
+				{Key: aws.String("RunnerName"), Value: aws.String(runnerName)},
+				{Key: aws.String("ScaleSet"), Value: aws.String(s.scaleSet.Name)},
+			},
+		}},
+	}
+	if s.config.EC2KeyPairName != "" {
+		input.KeyName = aws.String(s.config.EC2KeyPairName)
+	}
+	
+	result, err := s.ec2Client.RunInstances(ctx, input)
+	if err != nil {
+		return fmt.Errorf("failed to launch instance for runner %s: %w", runnerName, err)
+	}
+	if len(result.Instances) == 0 {
+		return fmt.Errorf("no instance launched for runner %s", runnerName)
+	}
+	instanceID := aws.ToString(result.Instances[0].InstanceId)
+	
+	// Without a record the instance is still counted from EC2, so a failed write is not fatal
+	_, err = s.dynamoClient.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: &s.config.DynamoDBTableName,
+		Item: map[string]dynamotypes.AttributeValue{
+			"InstanceId": &dynamotypes.AttributeValueMemberS{Value: instanceID},
+			"RunnerName": &dynamotypes.AttributeValueMemberS{Value: runnerName},
+			"Status":     &dynamotypes.AttributeValueMemberS{Value: runnerrecord.Pending},
+			"ScaleSetId": &dynamotypes.AttributeValueMemberN{Value: strconv.Itoa(s.scaleSet.ID)},
+			"CreatedAt":  &dynamotypes.AttributeValueMemberS{Value: time.Now().UTC().Format(time.RFC3339)},
+		},
+	})
+	if err != nil {
+		s.logger.Error(err, "Failed to write runner state", "runnerName", runnerName, "instanceId", instanceID)
+	}
+	
+	s.currentRunners++
+	s.logger.Info("Runner instance launched", "runnerName", runnerName, "instanceId", instanceID)
 	return nil
 }
 
@@ -5,16 +5,36 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
+	neturl "net/url"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/Anshuman2121/actionsspot/internal/ratelimit"
 )
 
 const (
 	gheAPIURL = "https://TelenorSwedenAB.ghe.com/api/v3"
+
+	// registrationTokenMinValidity is how long a cached registration token must still be
+	// valid to be reused, leaving new instances time to boot and register
+	registrationTokenMinValidity = 10 * time.Minute
+
+	// Secondary rate limits are waited out rather than failing the invocation. The waits are
+	// short since the invocation pays for them, and never run past its deadline.
+	secondaryRateLimitWait       = 30 * time.Second
+	maxSecondaryRateLimitWait    = time.Minute
+	maxSecondaryRateLimitRetries = 2
 )
 
+// registrationTokens caches registration tokens per organization. Tokens live about an hour,
+// so launches in the same window and warm invocations share one token.
+var registrationTokens = struct {
+	sync.Mutex
+	byOrg map[string]*RegistrationToken
+}{byOrg: make(map[string]*RegistrationToken)}
+
 type GHEClient struct {
 	config     Config
 	httpClient *http.Client
@@ -50,15 +70,21 @@ type WorkflowRun struct {
 	Status     string `json:"status"`     // queued, in_progress, completed
 	Conclusion string `json:"conclusion"` // success, failure, cancelled
 	RunnerName string `json:"runner_name,omitempty"`
+	RunAttempt   int       `json:"run_attempt,omitempty"`
+	RunStartedAt time.Time `json:"run_started_at,omitempty"` // start of the latest attempt
 	Repository *Repository `json:"repository,omitempty"`
 	Jobs       []WorkflowJob `json:"jobs,omitempty"` // Jobs with runner requirements
 }
 
 type WorkflowJob struct {
-	ID       int      `json:"id"`
-	Status   string   `json:"status"`
-	RunsOn   []string `json:"runs_on,omitempty"` // Runner labels required by this job
-	Labels   []string `json:"labels,omitempty"`  // Alternative field name
+	ID         int       `json:"id"`
+	Name       string    `json:"name,omitempty"`
+	Status     string    `json:"status"`
+	Conclusion string    `json:"conclusion,omitempty"`
+	RunnerName string    `json:"runner_name,omitempty"`
+	RunsOn     []string  `json:"runs_on,omitempty"` // Runner labels required by this job
+	Labels     []string  `json:"labels,omitempty"`  // Alternative field name
+	CreatedAt  time.Time `json:"created_at"`        // When the job was queued
 }
 
 type Repository struct {
@@ -86,10 +112,71 @@ func NewGHEClient(config Config) *GHEClient {
 	}
 }
 
-// GetSelfHostedRunners gets all self-hosted runners for the organization
+// runnersPageSize is the largest page the runners API returns
+const runnersPageSize = 100
+
+// GetSelfHostedRunners gets all self-hosted runners for the organization, following every
+// page. It fails rather than return a partial list, since callers terminate instances
+// whose runner is missing from it.
 func (c *GHEClient) GetSelfHostedRunners(ctx context.Context) (*SelfHostedRunnerList, error) {
-	url := fmt.Sprintf("%s/orgs/%s/actions/runners", c.baseURL, c.config.OrganizationName)
-	
+	all := &SelfHostedRunnerList{}
+	seen := make(map[int]bool)
+	for page := 1; ; page++ {
+		runners, err := c.getSelfHostedRunnersPage(ctx, page)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list runners page %d: %w", page, err)
+		}
+		all.TotalCount = runners.TotalCount
+		for _, runner := range runners.Runners {
+			// A runner registering between two pages shifts the rest of the list
+			if !seen[runner.ID] {
+				seen[runner.ID] = true
+				all.Runners = append(all.Runners, runner)
+			}
+		}
+		if len(runners.Runners) < runnersPageSize || len(all.Runners) >= all.TotalCount {
+			break
+		}
+	}
+
+	if len(all.Runners) < all.TotalCount {
+		return nil, fmt.Errorf("runner list is incomplete: got %d of %d runners", len(all.Runners), all.TotalCount)
+	}
+	return all, nil
+}
+
+// GetSelfHostedRunnerByName looks up a single runner of the organization by name. It
+// returns nil without error when no runner has that name.
+func (c *GHEClient) GetSelfHostedRunnerByName(ctx context.Context, name string) (*SelfHostedRunner, error) {
+	url := fmt.Sprintf("%s/orgs/%s/actions/runners?name=%s", c.baseURL, c.config.OrganizationName, neturl.QueryEscape(name))
+
+	resp, err := c.makeRequest(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to get runner %s (HTTP %d): %s", name, resp.StatusCode, string(body))
+	}
+
+	var runners SelfHostedRunnerList
+	if err := json.NewDecoder(resp.Body).Decode(&runners); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	for _, runner := range runners.Runners {
+		if runner.Name == name {
+			return &runner, nil
+		}
+	}
+	return nil, nil
+}
+
+// getSelfHostedRunnersPage gets one page of the organization's self-hosted runners
+func (c *GHEClient) getSelfHostedRunnersPage(ctx context.Context, page int) (*SelfHostedRunnerList, error) {
+	url := fmt.Sprintf("%s/orgs/%s/actions/runners?per_page=%d&page=%d", c.baseURL, c.config.OrganizationName, runnersPageSize, page)
+
 	resp, err := c.makeRequest(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to make request: %w", err)
@@ -160,63 +247,79 @@ func (c *GHEClient) GetRunningWorkflowRuns(ctx context.Context) (*WorkflowRunsLi
 	return c.getWorkflowRunsAcrossRepos(ctx, "in_progress")
 }
 
-// getWorkflowRunsAcrossRepos gets workflow runs with specified status across organization repositories
-func (c *GHEClient) getWorkflowRunsAcrossRepos(ctx context.Context, status string) (*WorkflowRunsList, error) {
-	var repos []Repository
-	var err error
-
-	// If specific repositories are configured, use them; otherwise get all org repos
+// GetMonitoredRepositories returns the repositories to scan for workflow runs: the configured
+// REPOSITORY_NAMES when set, otherwise every organization repository with Actions enabled.
+// There is no organization-wide runs endpoint on GHES, so runs are always listed per repository.
+func (c *GHEClient) GetMonitoredRepositories(ctx context.Context) ([]Repository, error) {
 	if len(c.config.RepositoryNames) > 0 {
+		repos := make([]Repository, 0, len(c.config.RepositoryNames))
 		for _, repoName := range c.config.RepositoryNames {
-			// Parse repo name (could be "owner/repo" or just "repo")
-			var owner, name string
-			if strings.Contains(repoName, "/") {
-				parts := strings.Split(repoName, "/")
-				if len(parts) == 2 {
-					owner, name = parts[0], parts[1]
-				} else {
-					continue // Invalid format, skip
-				}
-			} else {
-				owner, name = c.config.OrganizationName, repoName
+			owner, name, ok := parseRepositoryName(repoName, c.config.OrganizationName)
+			if !ok {
+				loggerFrom(ctx).Info("Ignoring invalid repository name", "repository", repoName)
+				continue
 			}
-
 			repos = append(repos, Repository{
 				Name:     name,
 				FullName: fmt.Sprintf("%s/%s", owner, name),
 				Owner:    &Owner{Login: owner},
 			})
 		}
-	} else {
-		// Get all repositories in the organization
-		repos, err = c.GetRepositoriesInOrganization(ctx)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get repositories: %w", err)
+		return repos, nil
+	}
+
+	allRepos, err := c.GetRepositoriesInOrganization(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get repositories: %w", err)
+	}
+
+	var enabledRepos []Repository
+	for _, repo := range allRepos {
+		if c.IsGitHubActionsEnabled(ctx, repo.Owner.Login, repo.Name) {
+			enabledRepos = append(enabledRepos, repo)
 		}
 	}
 
+	loggerFrom(ctx).Info("Found repositories", "repositories", len(allRepos), "actionsEnabled", len(enabledRepos))
+	return enabledRepos, nil
+}
+
+// parseRepositoryName splits "owner/repo" or "repo" (owned by the organization) into its parts
+func parseRepositoryName(repoName, organization string) (string, string, bool) {
+	parts := strings.Split(repoName, "/")
+	switch {
+	case len(parts) == 1 && parts[0] != "":
+		return organization, parts[0], true
+	case len(parts) == 2 && parts[0] != "" && parts[1] != "":
+		return parts[0], parts[1], true
+	default:
+		return "", "", false
+	}
+}
+
+// getWorkflowRunsAcrossRepos gets workflow runs with specified status across the monitored repositories
+func (c *GHEClient) getWorkflowRunsAcrossRepos(ctx context.Context, status string) (*WorkflowRunsList, error) {
+	repos, err := c.GetMonitoredRepositories(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	var allRuns []WorkflowRun
 	totalCount := 0
 	repoStats := make(map[string]int) // Track workflows per repository
 
 	// Get workflow runs for each repository
 	for _, repo := range repos {
-		// First check if GitHub Actions is enabled for this repository
-		if !c.IsGitHubActionsEnabled(ctx, repo.Owner.Login, repo.Name) {
-			log.Printf("⏭️  Skipping %s - GitHub Actions disabled", repo.FullName)
-			continue
-		}
-
 		repoRuns, err := c.getRepositoryWorkflowRuns(ctx, repo.Owner.Login, repo.Name, status)
 		if err != nil {
-			log.Printf("⚠️  Failed to get workflow runs for %s: %v", repo.FullName, err)
+			loggerFrom(ctx).Error(err, "Failed to get workflow runs", "repository", repo.FullName)
 			continue
 		}
 
 		repoWorkflowCount := len(repoRuns.WorkflowRuns)
 		if repoWorkflowCount > 0 {
 			repoStats[repo.FullName] = repoWorkflowCount
-			log.Printf("📊 Repository %s has %d %s workflows", repo.FullName, repoWorkflowCount, status)
+			loggerFrom(ctx).V(1).Info("Repository workflows", "repository", repo.FullName, "workflows", repoWorkflowCount, "status", status)
 		}
 
 		// Add repository info to each run
@@ -228,10 +331,7 @@ func (c *GHEClient) getWorkflowRunsAcrossRepos(ctx context.Context, status strin
 	}
 
 	// Log summary of repository distribution
-	log.Printf("📈 Workflow distribution across repositories:")
-	for repoName, count := range repoStats {
-		log.Printf("   %s: %d workflows", repoName, count)
-	}
+	loggerFrom(ctx).Info("Workflow distribution across repositories", "workflows", repoStats)
 
 	return &WorkflowRunsList{
 		TotalCount:   totalCount,
@@ -262,6 +362,28 @@ func (c *GHEClient) getRepositoryWorkflowRuns(ctx context.Context, owner, repo,
 	return &runs, nil
 }
 
+// GetWorkflowRun gets a workflow run with its latest attempt
+func (c *GHEClient) GetWorkflowRun(ctx context.Context, owner, repo string, runID int) (*WorkflowRun, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/actions/runs/%d", c.baseURL, owner, repo, runID)
+
+	resp, err := c.makeRequest(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to get workflow run %d (HTTP %d): %s", runID, resp.StatusCode, string(body))
+	}
+
+	var run WorkflowRun
+	if err := json.NewDecoder(resp.Body).Decode(&run); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &run, nil
+}
+
 // GetWorkflowJobs gets jobs for a specific workflow run
 func (c *GHEClient) GetWorkflowJobs(ctx context.Context, owner, repo string, runID int) ([]WorkflowJob, error) {
 	url := fmt.Sprintf("%s/repos/%s/%s/actions/runs/%d/jobs", c.baseURL, owner, repo, runID)
@@ -287,6 +409,53 @@ func (c *GHEClient) GetWorkflowJobs(ctx context.Context, owner, repo string, run
 	return response.Jobs, nil
 }
 
+// DispatchWorkflow triggers a workflow_dispatch run of a workflow on ref with the given inputs
+func (c *GHEClient) DispatchWorkflow(ctx context.Context, owner, repo, workflow, ref string, inputs map[string]string) error {
+	url := fmt.Sprintf("%s/repos/%s/%s/actions/workflows/%s/dispatches", c.baseURL, owner, repo, workflow)
+
+	payload, err := json.Marshal(map[string]interface{}{"ref": ref, "inputs": inputs})
+	if err != nil {
+		return fmt.Errorf("failed to encode dispatch: %w", err)
+	}
+
+	resp, err := c.makeRequest(ctx, "POST", url, strings.NewReader(string(payload)))
+	if err != nil {
+		return fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to dispatch workflow %s (HTTP %d): %s", workflow, resp.StatusCode, string(body))
+	}
+
+	return nil
+}
+
+// GetWorkflowDispatchRuns gets the workflow_dispatch runs of a workflow created since the given time
+func (c *GHEClient) GetWorkflowDispatchRuns(ctx context.Context, owner, repo, workflow string, since time.Time) (*WorkflowRunsList, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/actions/workflows/%s/runs?event=workflow_dispatch&created=%%3E%%3D%s&per_page=100",
+		c.baseURL, owner, repo, workflow, since.UTC().Format(time.RFC3339))
+
+	resp, err := c.makeRequest(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to get workflow runs (HTTP %d): %s", resp.StatusCode, string(body))
+	}
+
+	var runs WorkflowRunsList
+	if err := json.NewDecoder(resp.Body).Decode(&runs); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &runs, nil
+}
+
 // IsGitHubActionsEnabled checks if GitHub Actions is enabled for a repository
 func (c *GHEClient) IsGitHubActionsEnabled(ctx context.Context, owner, repo string) bool {
 	// Try to access the Actions API endpoint for the repository
@@ -294,7 +463,7 @@ func (c *GHEClient) IsGitHubActionsEnabled(ctx context.Context, owner, repo stri
 	
 	resp, err := c.makeRequest(ctx, "GET", url, nil)
 	if err != nil {
-		log.Printf("🔍 Error checking Actions status for %s/%s: %v", owner, repo, err)
+		loggerFrom(ctx).V(1).Info("Failed to check Actions status", "repository", owner+"/"+repo, "error", err.Error())
 		return false
 	}
 	defer resp.Body.Close()
@@ -304,14 +473,44 @@ func (c *GHEClient) IsGitHubActionsEnabled(ctx context.Context, owner, repo stri
 	enabled := resp.StatusCode == http.StatusOK
 	
 	if !enabled {
-		log.Printf("🚫 GitHub Actions appears to be disabled for %s/%s (HTTP %d)", owner, repo, resp.StatusCode)
+		loggerFrom(ctx).Info("GitHub Actions appears to be disabled", "repository", owner+"/"+repo, "status", resp.StatusCode)
 	}
 	
 	return enabled
 }
 
-// GetRegistrationToken gets a new runner registration token
+// GetRegistrationToken returns a runner registration token, reusing the cached one while it
+// stays valid long enough. A 401 means the GitHub token itself was rejected, which a retry
+// cannot fix, so it is returned at once.
 func (c *GHEClient) GetRegistrationToken(ctx context.Context) (*RegistrationToken, error) {
+	registrationTokens.Lock()
+	defer registrationTokens.Unlock()
+
+	if token, ok := registrationTokens.byOrg[c.config.OrganizationName]; ok && time.Until(token.ExpiresAt) > registrationTokenMinValidity {
+		return token, nil
+	}
+
+	token, err := c.requestRegistrationToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	registrationTokens.byOrg[c.config.OrganizationName] = token
+	return token, nil
+}
+
+// InvalidateRegistrationToken drops the cached token, e.g. after it was rejected as revoked
+func (c *GHEClient) InvalidateRegistrationToken(token *RegistrationToken) {
+	registrationTokens.Lock()
+	defer registrationTokens.Unlock()
+
+	if cached, ok := registrationTokens.byOrg[c.config.OrganizationName]; ok && cached.Token == token.Token {
+		delete(registrationTokens.byOrg, c.config.OrganizationName)
+	}
+}
+
+// requestRegistrationToken creates a new registration token
+func (c *GHEClient) requestRegistrationToken(ctx context.Context) (*RegistrationToken, error) {
 	url := fmt.Sprintf("%s/orgs/%s/actions/runners/registration-token", c.baseURL, c.config.OrganizationName)
 	
 	resp, err := c.makeRequest(ctx, "POST", url, nil)
@@ -320,6 +519,9 @@ func (c *GHEClient) GetRegistrationToken(ctx context.Context) (*RegistrationToke
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusUnauthorized {
+		return nil, fmt.Errorf("GitHub token was rejected when requesting a registration token (HTTP 401), check GITHUB_TOKEN")
+	}
 	if resp.StatusCode != http.StatusCreated {
 		body, _ := io.ReadAll(resp.Body)
 		return nil, fmt.Errorf("failed to get registration token (HTTP %d): %s", resp.StatusCode, string(body))
@@ -351,6 +553,30 @@ func (c *GHEClient) RemoveRunner(ctx context.Context, runnerID int) error {
 	return nil
 }
 
+// SetRunnerLabels replaces the custom labels of a self-hosted runner. The default labels
+// (self-hosted, OS and architecture) cannot be removed.
+func (c *GHEClient) SetRunnerLabels(ctx context.Context, runnerID int, labels []string) error {
+	url := fmt.Sprintf("%s/orgs/%s/actions/runners/%d/labels", c.baseURL, c.config.OrganizationName, runnerID)
+
+	payload, err := json.Marshal(map[string][]string{"labels": labels})
+	if err != nil {
+		return fmt.Errorf("failed to encode labels: %w", err)
+	}
+
+	resp, err := c.makeRequest(ctx, "PUT", url, strings.NewReader(string(payload)))
+	if err != nil {
+		return fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to set runner labels (HTTP %d): %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
+
 // makeRequest makes an authenticated request to the GitHub Enterprise API
 func (c *GHEClient) makeRequest(ctx context.Context, method, url string, body io.Reader) (*http.Response, error) {
 	req, err := http.NewRequestWithContext(ctx, method, url, body)
@@ -364,7 +590,36 @@ func (c *GHEClient) makeRequest(ctx context.Context, method, url string, body io
 		req.Header.Set("Content-Type", "application/json")
 	}
 
-	return c.httpClient.Do(req)
+	for attempt := 0; ; attempt++ {
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		wait, limited := ratelimit.Secondary(resp, secondaryRateLimitWait)
+		if !limited || attempt >= maxSecondaryRateLimitRetries {
+			return resp, nil
+		}
+		if wait > maxSecondaryRateLimitWait {
+			wait = maxSecondaryRateLimitWait
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			loggerFrom(ctx).Info("Secondary rate limit, no time left to wait", "method", method, "path", req.URL.Path, "wait", wait.String())
+			return resp, nil
+		}
+		resp.Body.Close()
+
+		loggerFrom(ctx).Info("Secondary rate limit, retrying", "method", method, "path", req.URL.Path, "wait", wait.String())
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+		if req.GetBody != nil {
+			if req.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+	}
 }
 
 // AnalyzeRunnerDemand analyzes current demand for runners
@@ -423,23 +678,25 @@ type RunnerDemandAnalysis struct {
 // FilterWorkflowsMatchingLabels filters workflow runs to only include those that match the configured runner labels
 func (c *GHEClient) FilterWorkflowsMatchingLabels(ctx context.Context, workflows []WorkflowRun, configuredLabels []string) ([]WorkflowRun, error) {
 	var matchingWorkflows []WorkflowRun
+	matcher := NewLabelMatcher(configuredLabels, jobLabelOptions.WithExclusions(c.config.ExcludedLabels))
 
-	log.Printf("🔍 Checking %d workflows against configured labels %v", len(workflows), configuredLabels)
+	logger := loggerFrom(ctx)
+	logger.V(1).Info("Checking workflows against configured labels", "workflows", len(workflows), "labels", configuredLabels)
 
 	for i, workflow := range workflows {
 		if workflow.Repository == nil {
-			log.Printf("⚠️  Workflow %d has no repository info, skipping", workflow.ID)
+			logger.Info("Skipping workflow without repository info", "workflowId", workflow.ID)
 			continue
 		}
 
-		log.Printf("🔄 [%d/%d] Checking workflow %d in %s (status: %s)", 
-			i+1, len(workflows), workflow.ID, workflow.Repository.FullName, workflow.Status)
+		logger.V(1).Info("Checking workflow", "index", i+1, "workflows", len(workflows), "workflowId", workflow.ID,
+			"repository", workflow.Repository.FullName, "status", workflow.Status)
 
 		// Quick check: if this repository frequently has 404 errors, check if Actions is enabled
 		if strings.Contains(workflow.Repository.FullName, "prepared-images-collection") {
 			if !c.IsGitHubActionsEnabled(ctx, workflow.Repository.Owner.Login, workflow.Repository.Name) {
-				log.Printf("⏭️  Skipping workflow %d - repository %s has Actions disabled", 
-					workflow.ID, workflow.Repository.FullName)
+				logger.V(1).Info("Skipping workflow of a repository with Actions disabled", "workflowId", workflow.ID,
+					"repository", workflow.Repository.FullName)
 				continue
 			}
 		}
@@ -447,11 +704,11 @@ func (c *GHEClient) FilterWorkflowsMatchingLabels(ctx context.Context, workflows
 		// Get jobs for this workflow
 		jobs, err := c.GetWorkflowJobs(ctx, workflow.Repository.Owner.Login, workflow.Repository.Name, workflow.ID)
 		if err != nil {
-			log.Printf("⚠️  Failed to get jobs for workflow %d in %s: %v", workflow.ID, workflow.Repository.FullName, err)
+			logger.Error(err, "Failed to get workflow jobs", "workflowId", workflow.ID, "repository", workflow.Repository.FullName)
 			
 			// Special handling for known test repositories where we expect self-hosted runners
 			if strings.Contains(workflow.Repository.FullName, "test-spot-runner") && workflow.Status == "queued" {
-				log.Printf("🎯 Special case: test-spot-runner repository with queued workflow - creating runner")
+				logger.Info("Queued workflow of the test-spot-runner repository, creating a runner", "workflowId", workflow.ID)
 				
 				// Create a placeholder job for test repository
 				placeholderJob := WorkflowJob{
@@ -462,32 +719,31 @@ func (c *GHEClient) FilterWorkflowsMatchingLabels(ctx context.Context, workflows
 				
 				workflow.Jobs = []WorkflowJob{placeholderJob}
 				matchingWorkflows = append(matchingWorkflows, workflow)
-				log.Printf("✅ Test repository workflow %d added to matching list", workflow.ID)
+				logger.V(1).Info("Test repository workflow added to matching list", "workflowId", workflow.ID)
 				continue
 			}
 			
 			// GitHub Enterprise limitation: queued workflows often don't have jobs available via API
 			// We'll skip these workflows for now to avoid over-provisioning
-			log.Printf("🔄 Skipping workflow %d - will check again in next execution", workflow.ID)
+			logger.V(1).Info("Skipping workflow until the next execution", "workflowId", workflow.ID)
 			continue
 		}
 
-		log.Printf("📋 Found %d jobs for workflow %d", len(jobs), workflow.ID)
+		logger.V(1).Info("Found workflow jobs", "workflowId", workflow.ID, "jobs", len(jobs))
 
 		// Check if any job requires labels that match our configured labels
 		hasMatchingJob := false
 		for j, job := range jobs {
-			log.Printf("   🔍 Job %d/%d: ID=%d, Status=%s, Labels=%v", 
-				j+1, len(jobs), job.ID, job.Status, job.Labels)
+			logger.V(1).Info("Job", "index", j+1, "jobs", len(jobs), "jobId", job.ID, "status", job.Status, "labels", job.Labels)
 
 			// For debugging, also check if RunsOn field has data
 			if len(job.RunsOn) > 0 {
-				log.Printf("   📌 Job %d also has RunsOn field: %v", job.ID, job.RunsOn)
+				logger.V(1).Info("Job also has a RunsOn field", "jobId", job.ID, "runsOn", job.RunsOn)
 			}
 
 			// Only check jobs that are waiting for a runner (not yet assigned)
 			if job.Status != "queued" && job.Status != "waiting" {
-				log.Printf("   ⏭️  Skipping job %d with status: %s", job.ID, job.Status)
+				logger.V(1).Info("Skipping job", "jobId", job.ID, "status", job.Status)
 				continue
 			}
 
@@ -497,63 +753,28 @@ func (c *GHEClient) FilterWorkflowsMatchingLabels(ctx context.Context, workflows
 				jobLabels = job.RunsOn // Fallback to RunsOn if Labels is empty
 			}
 
-			log.Printf("   🏷️  Checking if job labels %v match configured %v", jobLabels, configuredLabels)
+			logger.V(1).Info("Checking job labels", "jobId", job.ID, "jobLabels", jobLabels, "labels", configuredLabels)
 			
-			if c.labelsMatch(jobLabels, configuredLabels) {
-				log.Printf("   ✅ Job %d matches! Required: %v, Available: %v", job.ID, jobLabels, configuredLabels)
+			if matcher.Matches(jobLabels) {
+				logger.V(1).Info("Job matches", "jobId", job.ID, "jobLabels", jobLabels, "labels", configuredLabels)
 				hasMatchingJob = true
 				break
 			} else {
-				log.Printf("   ❌ Job %d doesn't match. Required: %v, Available: %v", job.ID, jobLabels, configuredLabels)
+				logger.V(1).Info("Job does not match", "jobId", job.ID, "reason", matcher.Reject(jobLabels), "labels", configuredLabels)
 			}
 		}
 
 		if hasMatchingJob {
 			workflow.Jobs = jobs // Store jobs for reference
 			matchingWorkflows = append(matchingWorkflows, workflow)
-			log.Printf("✅ Workflow %d added to matching list", workflow.ID)
+			logger.V(1).Info("Workflow added to matching list", "workflowId", workflow.ID)
 		} else {
-			log.Printf("❌ Workflow %d has no matching jobs", workflow.ID)
+			logger.V(1).Info("Workflow has no matching jobs", "workflowId", workflow.ID)
 		}
 	}
 
-	log.Printf("🎯 Final result: Filtered %d/%d workflows that match configured labels %v", 
-		len(matchingWorkflows), len(workflows), configuredLabels)
+	logger.Info("Filtered workflows that match configured labels", "matching", len(matchingWorkflows),
+		"workflows", len(workflows), "labels", configuredLabels)
 	
 	return matchingWorkflows, nil
 }
-
-// labelsMatch checks if job's required labels are compatible with runner's configured labels
-// Job can run on the runner if the runner has ALL the labels that the job requires
-func (c *GHEClient) labelsMatch(jobRequiredLabels, runnerConfiguredLabels []string) bool {
-	if len(jobRequiredLabels) == 0 {
-		// If no specific labels required, job can run on any self-hosted runner
-		log.Printf("   🟡 Job has no specific label requirements, checking for self-hosted")
-		return contains(runnerConfiguredLabels, "self-hosted")
-	}
-
-	log.Printf("   🔍 Checking if runner labels %v contain all required job labels %v", 
-		runnerConfiguredLabels, jobRequiredLabels)
-
-	// Check if runner has ALL the labels that the job requires
-	for _, requiredLabel := range jobRequiredLabels {
-		if !contains(runnerConfiguredLabels, requiredLabel) {
-			log.Printf("   ❌ Runner missing required label: %s", requiredLabel)
-			return false
-		}
-		log.Printf("   ✅ Runner has required label: %s", requiredLabel)
-	}
-
-	log.Printf("   🎉 Runner has all required labels!")
-	return true
-}
-
-// contains checks if a slice contains a specific string
-func contains(slice []string, item string) bool {
-	for _, s := range slice {
-		if s == item {
-			return true
-		}
-	}
-	return false
-} 
\ No newline at end of file
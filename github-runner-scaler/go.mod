@@ -1,21 +1,30 @@
-module github-runner-scaler
+module github.com/Anshuman2121/actionsspot/github-runner-scaler
 
 go 1.21
 
 require (
+	github.com/Anshuman2121/actionsspot v0.0.0-00010101000000-000000000000
 	github.com/aws/aws-lambda-go v1.41.0
+	github.com/aws/aws-sdk-go-v2 v1.21.2
 	github.com/aws/aws-sdk-go-v2/config v1.18.45
 	github.com/aws/aws-sdk-go-v2/service/dynamodb v1.21.5
 	github.com/aws/aws-sdk-go-v2/service/ec2 v1.118.0
+	github.com/aws/aws-sdk-go-v2/service/eventbridge v1.22.2
+	github.com/aws/aws-sdk-go-v2/service/lambda v1.40.0
+	github.com/go-logr/logr v1.3.0
+	github.com/go-logr/zapr v1.3.0
+	github.com/google/uuid v1.4.0
+	go.uber.org/zap v1.26.0
 )
 
 require (
-	github.com/aws/aws-sdk-go-v2 v1.21.2 // indirect
+	github.com/aws/aws-sdk-go-v2/aws/protocol/eventstream v1.4.14 // indirect
 	github.com/aws/aws-sdk-go-v2/credentials v1.13.43 // indirect
 	github.com/aws/aws-sdk-go-v2/feature/ec2/imds v1.13.13 // indirect
 	github.com/aws/aws-sdk-go-v2/internal/configsources v1.1.43 // indirect
 	github.com/aws/aws-sdk-go-v2/internal/endpoints/v2 v2.4.37 // indirect
 	github.com/aws/aws-sdk-go-v2/internal/ini v1.3.45 // indirect
+	github.com/aws/aws-sdk-go-v2/internal/v4a v1.1.6 // indirect
 	github.com/aws/aws-sdk-go-v2/service/internal/accept-encoding v1.9.14 // indirect
 	github.com/aws/aws-sdk-go-v2/service/internal/endpoint-discovery v1.7.35 // indirect
 	github.com/aws/aws-sdk-go-v2/service/internal/presigned-url v1.9.37 // indirect
@@ -24,4 +33,7 @@ require (
 	github.com/aws/aws-sdk-go-v2/service/sts v1.23.2 // indirect
 	github.com/aws/smithy-go v1.15.0 // indirect
 	github.com/jmespath/go-jmespath v0.4.0 // indirect
+	go.uber.org/multierr v1.10.0 // indirect
 )
+
+replace github.com/Anshuman2121/actionsspot => ../
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// idleSinceTag carries the RFC 3339 time a runner was first seen idle. It is removed when
+// the runner is seen busy again.
+const idleSinceTag = "IdleSince"
+
+// idleRunner is a runner past the idle timeout
+type idleRunner struct {
+	launchedRunner
+	RunnerID  int
+	IdleSince time.Time
+}
+
+// scaleDownIdleRunners deregisters and terminates this pool's runners that have been idle
+// longer than IDLE_TIMEOUT, longest idle first, while keeping MIN_RUNNERS online. Idleness is
+// sampled once per invocation, so a job shorter than the schedule interval may not reset a
+// runner's idle time. Runners in a debug hold or draining for a new generation are left to
+// their own cleanup.
+func scaleDownIdleRunners(ctx context.Context, gheClient *GHEClient, awsInfra *AWSInfrastructure, config Config) error {
+	if config.IdleTimeout <= 0 {
+		return nil
+	}
+
+	registered, err := gheClient.GetSelfHostedRunners(ctx)
+	if err != nil {
+		return err
+	}
+	online := 0
+	byName := make(map[string]SelfHostedRunner, len(registered.Runners))
+	for _, runner := range registered.Runners {
+		if config.PoolName != "" && !runnerHasLabels(runner, config.RunnerLabels) {
+			continue
+		}
+		byName[runner.Name] = runner
+		if runner.Status == "online" {
+			online++
+		}
+	}
+
+	var idle []idleRunner
+	now := time.Now().UTC()
+	paginator := ec2.NewDescribeInstancesPaginator(awsInfra.ec2Client, &ec2.DescribeInstancesInput{
+		Filters: []ec2types.Filter{
+			{Name: awsInfra.String("tag:ManagedBy"), Values: []string{"github-runner-scaler-lambda"}},
+			{Name: awsInfra.String("tag:Purpose"), Values: []string{"github-actions-runner"}},
+			{Name: awsInfra.String("instance-state-name"), Values: []string{"running"}},
+		},
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to describe runner instances: %w", err)
+		}
+		for _, reservation := range page.Reservations {
+			for _, instance := range reservation.Instances {
+				tags := tagValues(instance.Tags)
+				if tags["Pool"] != config.PoolName {
+					continue
+				}
+				if _, held := tags[debugHoldTag]; held {
+					continue
+				}
+				if _, draining := tags[drainingSinceTag]; draining {
+					continue
+				}
+				ghRunner, ok := byName[tags["RunnerName"]]
+				if !ok || ghRunner.Status != "online" {
+					continue
+				}
+
+				instanceID := *instance.InstanceId
+				value, tagged := tags[idleSinceTag]
+				idleSince, err := time.Parse(time.RFC3339, value)
+				switch {
+				case ghRunner.Busy:
+					if tagged {
+						awsInfra.setIdleSince(ctx, instanceID, nil)
+					}
+					continue
+				case !tagged || err != nil:
+					awsInfra.setIdleSince(ctx, instanceID, &now)
+					continue
+				case now.Sub(idleSince) < config.IdleTimeout:
+					continue
+				}
+
+				runner := launchedRunner{RunnerName: ghRunner.Name, InstanceID: instanceID}
+				if instance.SpotInstanceRequestId != nil {
+					runner.SpotRequestID = *instance.SpotInstanceRequestId
+				}
+				idle = append(idle, idleRunner{launchedRunner: runner, RunnerID: ghRunner.ID, IdleSince: idleSince})
+			}
+		}
+	}
+
+	sort.Slice(idle, func(i, j int) bool { return idle[i].IdleSince.Before(idle[j].IdleSince) })
+
+	terminated := 0
+	for _, runner := range idle {
+		if online <= config.MinRunners {
+			loggerFrom(ctx).Info("Keeping idle runners to stay at the minimum", "idleRunners", len(idle)-terminated, "minRunners", config.MinRunners)
+			break
+		}
+		// GitHub refuses to remove a runner that picked up a job since it was listed
+		if err := gheClient.RemoveRunner(ctx, runner.RunnerID); err != nil {
+			loggerFrom(ctx).Error(err, "Failed to deregister idle runner", "runnerName", runner.RunnerName)
+			continue
+		}
+		if err := awsInfra.terminateLaunchedRunner(ctx, runner.launchedRunner); err != nil {
+			loggerFrom(ctx).Error(err, "Failed to terminate idle runner", "runnerName", runner.RunnerName, "instanceId", runner.InstanceID)
+			continue
+		}
+		loggerFrom(ctx).Info("Terminated idle runner", "runnerName", runner.RunnerName, "instanceId", runner.InstanceID,
+			"idleFor", now.Sub(runner.IdleSince).Round(time.Minute).String())
+		online--
+		terminated++
+	}
+	awsInfra.metrics.Count(metricIdleRunnersTerminated, config.PoolName, float64(terminated))
+	return nil
+}
+
+// setIdleSince tags an instance with the time its runner was first seen idle, or removes
+// the tag when since is nil. Failures only delay the scale-down, so they are logged.
+func (aws *AWSInfrastructure) setIdleSince(ctx context.Context, instanceID string, since *time.Time) {
+	var err error
+	if since == nil {
+		_, err = aws.ec2Client.DeleteTags(ctx, &ec2.DeleteTagsInput{
+			Resources: []string{instanceID},
+			Tags:      []ec2types.Tag{{Key: aws.String(idleSinceTag)}},
+		})
+	} else {
+		_, err = aws.ec2Client.CreateTags(ctx, &ec2.CreateTagsInput{
+			Resources: []string{instanceID},
+			Tags:      []ec2types.Tag{{Key: aws.String(idleSinceTag), Value: aws.String(since.Format(time.RFC3339))}},
+		})
+	}
+	if err != nil {
+		loggerFrom(ctx).Error(err, "Failed to update tag", "tag", idleSinceTag, "instanceId", instanceID)
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// imageReferencePattern matches the image references PREWARM_IMAGES accepts. They are
+// written into the bootstrap script, so nothing a shell would interpret is allowed.
+var imageReferencePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._/:@-]*$`)
+
+// validatePrewarmImages checks the images to pre-pull
+func validatePrewarmImages(images []string) error {
+	for _, image := range images {
+		if !imageReferencePattern.MatchString(image) {
+			return fmt.Errorf("invalid image reference %q", image)
+		}
+	}
+	return nil
+}
+
+// imagePrewarmScript pulls the configured container images in the background while the
+// runner downloads and registers, so jobs using them start on warm layers. Images in ECR
+// are pulled after logging in to their registry with the instance role. Failures are only
+// logged, to /var/log/runner-image-prewarm.log; the job pulls whatever is missing itself.
+// It runs as root.
+func (c Config) imagePrewarmScript() string {
+	if len(c.PrewarmImages) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(`# Pre-pull container images in the background while the runner registers
+if command -v docker > /dev/null; then
+    (
+        for IMAGE in %s; do
+            REGISTRY=${IMAGE%%%%/*}
+            case $REGISTRY in
+                *.dkr.ecr.*.amazonaws.com)
+                    aws ecr get-login-password --region $(echo $REGISTRY | cut -d. -f4) | docker login --username AWS --password-stdin $REGISTRY || true
+                    ;;
+            esac
+            (docker pull -q $IMAGE && echo "Pre-pulled $IMAGE" || echo "Failed to pre-pull $IMAGE") &
+        done
+        wait
+    ) > /var/log/runner-image-prewarm.log 2>&1 &
+else
+    echo "Docker is not installed in the AMI, not pre-pulling images"
+fi
+`, strings.Join(c.PrewarmImages, " "))
+}
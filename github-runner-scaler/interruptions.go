@@ -0,0 +1,469 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Anshuman2121/actionsspot/internal/runnerrecord"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+)
+
+// spotInterruptionDetailType is the EventBridge detail type of the two-minute warning EC2
+// sends before reclaiming a spot instance
+const spotInterruptionDetailType = "EC2 Spot Instance Interruption Warning"
+
+// interruptionRetention is how long interruption records are kept for reports
+const interruptionRetention = 30 * 24 * time.Hour
+
+// spotInterruptionEvent is the subset of the interruption warning the scaler needs
+type spotInterruptionEvent struct {
+	Time   time.Time `json:"time"`
+	Detail struct {
+		InstanceID     string `json:"instance-id"`
+		InstanceAction string `json:"instance-action"`
+	} `json:"detail"`
+}
+
+// InterruptionRecord is a spot interruption of a runner and the job it was running, if any.
+// RerunAt is filled in by the report once a later attempt of the job's workflow run starts.
+type InterruptionRecord struct {
+	InstanceID    string    `dynamodbav:"instance_id"`
+	RunnerName    string    `dynamodbav:"runner_name"`
+	Pool          string    `dynamodbav:"pool"`
+	InstanceType  string    `dynamodbav:"instance_type"`
+	InterruptedAt time.Time `dynamodbav:"interrupted_at"`
+	Repository    string    `dynamodbav:"repository"` // owner/repo
+	RunID         int       `dynamodbav:"run_id"`
+	RunAttempt    int       `dynamodbav:"run_attempt"`
+	JobID         int       `dynamodbav:"job_id"`
+	JobName       string    `dynamodbav:"job_name"`
+	Replacement   string    `dynamodbav:"replacement"` // runner launched in its place
+	RerunAt       time.Time `dynamodbav:"rerun_at"`
+}
+
+// InterruptionStore keeps interruption records in the interruptions table, keyed by
+// instance ID. Records expire through TTL after interruptionRetention.
+type InterruptionStore struct {
+	client    *dynamodb.Client
+	tableName string
+}
+
+// NewInterruptionStore creates an interruption store for the given table
+func NewInterruptionStore(client *dynamodb.Client, tableName string) *InterruptionStore {
+	return &InterruptionStore{
+		client:    client,
+		tableName: tableName,
+	}
+}
+
+// Save creates or replaces an interruption record
+func (s *InterruptionStore) Save(ctx context.Context, record InterruptionRecord) error {
+	item := map[string]types.AttributeValue{
+		"instance_id":    &types.AttributeValueMemberS{Value: record.InstanceID},
+		"runner_name":    &types.AttributeValueMemberS{Value: record.RunnerName},
+		"pool":           &types.AttributeValueMemberS{Value: record.Pool},
+		"instance_type":  &types.AttributeValueMemberS{Value: record.InstanceType},
+		"interrupted_at": &types.AttributeValueMemberS{Value: record.InterruptedAt.UTC().Format(time.RFC3339)},
+		"expires_at":     &types.AttributeValueMemberN{Value: strconv.FormatInt(record.InterruptedAt.Add(interruptionRetention).Unix(), 10)},
+	}
+	if record.JobID != 0 {
+		item["repository"] = &types.AttributeValueMemberS{Value: record.Repository}
+		item["run_id"] = &types.AttributeValueMemberN{Value: strconv.Itoa(record.RunID)}
+		item["run_attempt"] = &types.AttributeValueMemberN{Value: strconv.Itoa(record.RunAttempt)}
+		item["job_id"] = &types.AttributeValueMemberN{Value: strconv.Itoa(record.JobID)}
+		item["job_name"] = &types.AttributeValueMemberS{Value: record.JobName}
+	}
+	if record.Replacement != "" {
+		item["replacement"] = &types.AttributeValueMemberS{Value: record.Replacement}
+	}
+
+	_, err := s.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: &s.tableName,
+		Item:      item,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save interruption of %s: %w", record.InstanceID, err)
+	}
+	return nil
+}
+
+// ListSince returns the interruptions since the given time. The table only holds a month
+// of interruptions, so a filtered scan is sufficient.
+func (s *InterruptionStore) ListSince(ctx context.Context, since time.Time) ([]InterruptionRecord, error) {
+	var records []InterruptionRecord
+	paginator := dynamodb.NewScanPaginator(s.client, &dynamodb.ScanInput{
+		TableName:        &s.tableName,
+		FilterExpression: stringPtr("interrupted_at >= :since"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":since": &types.AttributeValueMemberS{Value: since.UTC().Format(time.RFC3339)},
+		},
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan interruptions: %w", err)
+		}
+		for _, item := range page.Items {
+			records = append(records, interruptionRecordFromItem(item))
+		}
+	}
+	return records, nil
+}
+
+// SetRerun records when the interrupted job's workflow run was re-run
+func (s *InterruptionStore) SetRerun(ctx context.Context, instanceID string, rerunAt time.Time) error {
+	_, err := s.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: &s.tableName,
+		Key: map[string]types.AttributeValue{
+			"instance_id": &types.AttributeValueMemberS{Value: instanceID},
+		},
+		UpdateExpression: stringPtr("SET rerun_at = :rerun_at"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":rerun_at": &types.AttributeValueMemberS{Value: rerunAt.UTC().Format(time.RFC3339)},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record re-run of %s: %w", instanceID, err)
+	}
+	return nil
+}
+
+func interruptionRecordFromItem(item map[string]types.AttributeValue) InterruptionRecord {
+	str := func(name string) string {
+		if v, ok := item[name].(*types.AttributeValueMemberS); ok {
+			return v.Value
+		}
+		return ""
+	}
+	num := func(name string) int {
+		if v, ok := item[name].(*types.AttributeValueMemberN); ok {
+			n, _ := strconv.Atoi(v.Value)
+			return n
+		}
+		return 0
+	}
+
+	record := InterruptionRecord{
+		InstanceID:   str("instance_id"),
+		RunnerName:   str("runner_name"),
+		Pool:         str("pool"),
+		InstanceType: str("instance_type"),
+		Repository:   str("repository"),
+		RunID:        num("run_id"),
+		RunAttempt:   num("run_attempt"),
+		JobID:        num("job_id"),
+		JobName:      str("job_name"),
+		Replacement:  str("replacement"),
+	}
+	record.InterruptedAt, _ = time.Parse(time.RFC3339, str("interrupted_at"))
+	record.RerunAt, _ = time.Parse(time.RFC3339, str("rerun_at"))
+	return record
+}
+
+// handleSpotInterruption records the interruption warning of one of the scaler's runners
+// along with the job it is about to lose
+func handleSpotInterruption(ctx context.Context, raw json.RawMessage, gheClient *GHEClient, awsInfra *AWSInfrastructure, config Config) error {
+	var event spotInterruptionEvent
+	if err := json.Unmarshal(raw, &event); err != nil {
+		return fmt.Errorf("invalid spot interruption event: %w", err)
+	}
+
+	output, err := awsInfra.ec2Client.DescribeInstances(ctx, &ec2.DescribeInstancesInput{
+		InstanceIds: []string{event.Detail.InstanceID},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to describe interrupted instance %s: %w", event.Detail.InstanceID, err)
+	}
+	if len(output.Reservations) == 0 || len(output.Reservations[0].Instances) == 0 {
+		loggerFrom(ctx).Info("Interrupted instance no longer exists", "instanceId", event.Detail.InstanceID)
+		return nil
+	}
+	instance := output.Reservations[0].Instances[0]
+	tags := tagValues(instance.Tags)
+	if tags["ManagedBy"] != "github-runner-scaler-lambda" {
+		loggerFrom(ctx).Info("Ignoring interruption of an instance that is not a runner of this scaler", "instanceId", event.Detail.InstanceID)
+		return nil
+	}
+
+	record := InterruptionRecord{
+		InstanceID:    event.Detail.InstanceID,
+		RunnerName:    tags["RunnerName"],
+		Pool:          tags["Pool"],
+		InstanceType:  string(instance.InstanceType),
+		InterruptedAt: event.Time,
+	}
+	if record.InterruptedAt.IsZero() {
+		record.InterruptedAt = time.Now()
+	}
+	awsInfra.metrics.Count(metricSpotInterruptions, record.Pool, 1)
+
+	if err := findInterruptedJob(ctx, gheClient, &record); err != nil {
+		loggerFrom(ctx).Error(err, "Could not find the job of interrupted runner", "runnerName", record.RunnerName)
+	}
+	if record.JobID != 0 {
+		awsInfra.metrics.Count(metricInterruptedJobs, record.Pool, 1)
+		loggerFrom(ctx).Info("Spot interruption of a busy runner", "action", event.Detail.InstanceAction,
+			"runnerName", record.RunnerName, "instanceId", record.InstanceID, "pool", metricPool(record.Pool),
+			"repository", record.Repository, "jobId", record.JobID, "jobName", record.JobName)
+	} else {
+		loggerFrom(ctx).Info("Spot interruption of an idle runner", "action", event.Detail.InstanceAction,
+			"runnerName", record.RunnerName, "instanceId", record.InstanceID, "pool", metricPool(record.Pool))
+	}
+
+	if record.JobID != 0 && config.ReplaceInterruptedRunners {
+		if err := replaceInterruptedRunner(ctx, awsInfra, config, &record); err != nil {
+			loggerFrom(ctx).Error(err, "Failed to replace interrupted runner", "runnerName", record.RunnerName)
+		}
+	}
+
+	if config.InterruptionsTableName == "" {
+		return nil
+	}
+	return NewInterruptionStore(awsInfra.dynamoDBClient, config.InterruptionsTableName).Save(ctx, record)
+}
+
+// replaceInterruptedRunner launches a runner of the interrupted runner's pool right away, so
+// its job finds a runner when it is re-queued or re-run instead of waiting for the next
+// scaling cycle, and marks the interrupted runner's record with the replacement. The pool's
+// MAX_RUNNERS still applies. Scale set pools are left to the scale set: a runner registered
+// with a registration token does not join it, so it would not pick up the job.
+func replaceInterruptedRunner(ctx context.Context, awsInfra *AWSInfrastructure, config Config, record *InterruptionRecord) error {
+	if record.Pool != "" {
+		pool, ok := findPool(config.Pools, record.Pool)
+		if !ok {
+			return fmt.Errorf("unknown pool %q", record.Pool)
+		}
+		config = config.forPool(pool)
+		poolInfra := *awsInfra
+		poolInfra.config = config
+		awsInfra = &poolInfra
+	}
+	if config.RunnerScaleSetName != "" {
+		loggerFrom(ctx).Info("Not replacing interrupted runner, its job is re-assigned through the scale set",
+			"runnerName", record.RunnerName, "scaleSet", config.RunnerScaleSetName)
+		return nil
+	}
+
+	current, err := awsInfra.getCurrentRunnerCount(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to count runners: %w", err)
+	}
+	// The interrupted instance still counts until EC2 reclaims it
+	if current-1 >= config.MaxRunners {
+		loggerFrom(ctx).Info("Not replacing interrupted runner, the pool is at its maximum",
+			"runnerName", record.RunnerName, "maxRunners", config.MaxRunners)
+		return nil
+	}
+	gheClient := NewGHEClient(config)
+
+	runnerName, err := availableRunnerName(ctx, gheClient, config, nil)
+	if err != nil {
+		return err
+	}
+	token, err := gheClient.GetRegistrationToken(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get registration token: %w", err)
+	}
+	instanceID, err := awsInfra.runnerProvider().CreateRunner(ctx, RunnerSpec{
+		Name:              runnerName,
+		RegistrationToken: token.Token,
+		Labels:            literalLabels(config.RunnerLabels),
+	})
+	if err != nil {
+		return err
+	}
+	record.Replacement = runnerName
+	awsInfra.metrics.Count(metricReplacementRunners, record.Pool, 1)
+	loggerFrom(ctx).Info("Launched replacement runner", "runnerName", runnerName, "instanceId", instanceID,
+		"repository", record.Repository, "jobId", record.JobID, "interruptedRunner", record.RunnerName)
+
+	if err := awsInfra.markRunnerReplaced(ctx, record.RunnerName, runnerName); err != nil {
+		loggerFrom(ctx).Error(err, "Failed to mark interrupted runner as replaced", "runnerName", record.RunnerName)
+	}
+	return nil
+}
+
+// markRunnerReplaced sets an interrupted runner's record to interrupted and names the runner
+// launched for its job
+func (aws *AWSInfrastructure) markRunnerReplaced(ctx context.Context, runnerName, replacement string) error {
+	err := aws.writeRunnerRecord(ctx, runnerName, runnerrecord.Interrupted, func(v recordVersion) error {
+		now := time.Now()
+		_, err := aws.dynamoDBClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+			TableName: aws.String(aws.config.DynamoDBTableName),
+			Key: map[string]types.AttributeValue{
+				"runner_id": &types.AttributeValueMemberS{Value: runnerName},
+			},
+			UpdateExpression: aws.String("SET #status = :status, replaced_by = :replaced_by, updated_at = :updated_at, expires_at = :expires_at, #version = :next_version"),
+			// Only mark an existing record, an update would otherwise create a bare one
+			ConditionExpression: aws.String("attribute_exists(runner_id) AND " + v.Condition),
+			ExpressionAttributeNames: map[string]string{
+				"#status":  "status",
+				"#version": "version",
+			},
+			ExpressionAttributeValues: v.mergeValues(map[string]types.AttributeValue{
+				":status":       &types.AttributeValueMemberS{Value: runnerrecord.Interrupted},
+				":replaced_by":  &types.AttributeValueMemberS{Value: replacement},
+				":updated_at":   &types.AttributeValueMemberS{Value: now.Format(time.RFC3339)},
+				":expires_at":   aws.runnerRecordExpiry(now),
+				":next_version": v.Next,
+			}),
+		})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to mark runner %s as replaced: %w", runnerName, err)
+	}
+	return nil
+}
+
+// findInterruptedJob fills in the in-progress job of the record's runner, if it has one
+func findInterruptedJob(ctx context.Context, gheClient *GHEClient, record *InterruptionRecord) error {
+	if record.RunnerName == "" {
+		return nil
+	}
+	runs, err := gheClient.GetRunningWorkflowRuns(ctx)
+	if err != nil {
+		return err
+	}
+	for _, run := range runs.WorkflowRuns {
+		if run.Repository == nil || run.Repository.Owner == nil {
+			continue
+		}
+		jobs, err := gheClient.GetWorkflowJobs(ctx, run.Repository.Owner.Login, run.Repository.Name, run.ID)
+		if err != nil {
+			return err
+		}
+		for _, job := range jobs {
+			if job.Status == "in_progress" && job.RunnerName == record.RunnerName {
+				record.Repository = run.Repository.FullName
+				record.RunID = run.ID
+				record.RunAttempt = run.RunAttempt
+				record.JobID = job.ID
+				record.JobName = job.Name
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+// InterruptionPoolReport summarizes a pool's spot interruptions over the report window
+type InterruptionPoolReport struct {
+	Pool              string `json:"pool"`
+	Interruptions     int    `json:"interruptions"`
+	JobsImpacted      int    `json:"jobs_impacted"`
+	JobsRerun         int    `json:"jobs_rerun"`
+	AvgRerunLatency   string `json:"avg_rerun_latency,omitempty"`
+	MaxRerunLatency   string `json:"max_rerun_latency,omitempty"`
+	RecommendOnDemand bool   `json:"recommend_on_demand"`
+}
+
+// runInterruptionReport summarizes the interruptions of the last INTERRUPTION_REPORT_WINDOW
+// per pool: how many jobs were lost and how long they took to be re-run. Pools losing
+// INTERRUPTION_JOB_THRESHOLD jobs or more are flagged as candidates for on-demand.
+func runInterruptionReport(ctx context.Context, gheClient *GHEClient, awsInfra *AWSInfrastructure, config Config) ([]InterruptionPoolReport, error) {
+	if config.InterruptionsTableName == "" {
+		return nil, fmt.Errorf("INTERRUPTIONS_TABLE_NAME is not set")
+	}
+	store := NewInterruptionStore(awsInfra.dynamoDBClient, config.InterruptionsTableName)
+	records, err := store.ListSince(ctx, time.Now().Add(-config.InterruptionReportWindow))
+	if err != nil {
+		return nil, err
+	}
+
+	type poolTotals struct {
+		report       InterruptionPoolReport
+		totalLatency time.Duration
+		maxLatency   time.Duration
+	}
+	pools := make(map[string]*poolTotals)
+	for _, record := range records {
+		pool := metricPool(record.Pool)
+		totals, ok := pools[pool]
+		if !ok {
+			totals = &poolTotals{report: InterruptionPoolReport{Pool: pool}}
+			pools[pool] = totals
+		}
+		totals.report.Interruptions++
+		if record.JobID == 0 {
+			continue
+		}
+		totals.report.JobsImpacted++
+
+		if record.RerunAt.IsZero() {
+			record.RerunAt, err = rerunStartedAt(ctx, gheClient, record)
+			if err != nil {
+				loggerFrom(ctx).Error(err, "Could not check re-run of interrupted job", "repository", record.Repository, "jobId", record.JobID)
+				continue
+			}
+			if !record.RerunAt.IsZero() {
+				if err := store.SetRerun(ctx, record.InstanceID, record.RerunAt); err != nil {
+					loggerFrom(ctx).Error(err, "Failed to record re-run of interrupted job", "instanceId", record.InstanceID)
+				}
+			}
+		}
+		if record.RerunAt.IsZero() {
+			continue
+		}
+		latency := record.RerunAt.Sub(record.InterruptedAt)
+		totals.report.JobsRerun++
+		totals.totalLatency += latency
+		if latency > totals.maxLatency {
+			totals.maxLatency = latency
+		}
+	}
+
+	reports := make([]InterruptionPoolReport, 0, len(pools))
+	for pool, totals := range pools {
+		report := totals.report
+		report.RecommendOnDemand = report.JobsImpacted >= config.InterruptionJobThreshold
+		awsInfra.metrics.Gauge(metricInterruptedJobsInWindow, pool, float64(report.JobsImpacted))
+		if report.JobsRerun > 0 {
+			avgLatency := totals.totalLatency / time.Duration(report.JobsRerun)
+			report.AvgRerunLatency = avgLatency.Round(time.Second).String()
+			report.MaxRerunLatency = totals.maxLatency.Round(time.Second).String()
+			awsInfra.metrics.Duration(metricRerunLatency, pool, avgLatency)
+		}
+		reports = append(reports, report)
+	}
+	sort.Slice(reports, func(i, j int) bool { return reports[i].Pool < reports[j].Pool })
+
+	for _, report := range reports {
+		loggerFrom(ctx).Info("Spot interruption report", "window", config.InterruptionReportWindow.String(),
+			"pool", report.Pool, "interruptions", report.Interruptions, "jobsImpacted", report.JobsImpacted,
+			"jobsRerun", report.JobsRerun, "avgRerunLatency", orNone(report.AvgRerunLatency),
+			"maxRerunLatency", orNone(report.MaxRerunLatency), "recommendOnDemand", report.RecommendOnDemand)
+	}
+	return reports, nil
+}
+
+// rerunStartedAt returns when a later attempt of the interrupted job's workflow run
+// started, or the zero time when the run has not been re-run yet
+func rerunStartedAt(ctx context.Context, gheClient *GHEClient, record InterruptionRecord) (time.Time, error) {
+	owner, repo, ok := strings.Cut(record.Repository, "/")
+	if !ok {
+		return time.Time{}, fmt.Errorf("invalid repository %q", record.Repository)
+	}
+	run, err := gheClient.GetWorkflowRun(ctx, owner, repo, record.RunID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if run.RunAttempt <= record.RunAttempt || run.RunStartedAt.Before(record.InterruptedAt) {
+		return time.Time{}, nil
+	}
+	return run.RunStartedAt, nil
+}
+
+func orNone(value string) string {
+	if value == "" {
+		return "n/a"
+	}
+	return value
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// InventoryEntry is one runner as seen by GitHub, EC2 and the runner table. Fields stay
+// empty for the sources that do not know the runner, which is what an audit looks for.
+type InventoryEntry struct {
+	Name         string   `json:"name"`
+	InstanceID   string   `json:"instance_id,omitempty"`
+	State        string   `json:"state,omitempty"`
+	Market       string   `json:"market,omitempty"`
+	Pool         string   `json:"pool,omitempty"`
+	GitHubStatus string   `json:"github_status,omitempty"`
+	RecordStatus string   `json:"record_status,omitempty"`
+	Labels       []string `json:"labels,omitempty"`
+	LaunchedAt   string   `json:"launched_at,omitempty"`
+	Age          string   `json:"age,omitempty"`
+	CurrentJob   string   `json:"current_job,omitempty"`
+
+	launchedAt time.Time
+}
+
+// collectInventory joins the organization's registered runners, the scaler's live
+// instances and the runner table records into one entry per runner. Runners are matched
+// to instances by the RunnerName tag and records to instances by instance ID.
+func collectInventory(ctx context.Context, gheClient *GHEClient, awsInfra *AWSInfrastructure) ([]InventoryEntry, error) {
+	entries := make(map[string]*InventoryEntry)
+	byInstance := make(map[string]*InventoryEntry)
+	entry := func(name string) *InventoryEntry {
+		if e, ok := entries[name]; ok {
+			return e
+		}
+		e := &InventoryEntry{Name: name}
+		entries[name] = e
+		return e
+	}
+
+	registered, err := gheClient.GetSelfHostedRunners(ctx)
+	if err != nil {
+		return nil, err
+	}
+	busy := false
+	for _, runner := range registered.Runners {
+		e := entry(runner.Name)
+		e.GitHubStatus = runner.Status
+		if runner.Busy {
+			e.GitHubStatus = "busy"
+			busy = true
+		}
+		for _, label := range runner.Labels {
+			e.Labels = append(e.Labels, label.Name)
+		}
+	}
+
+	paginator := ec2.NewDescribeInstancesPaginator(awsInfra.ec2Client, &ec2.DescribeInstancesInput{
+		Filters: []ec2types.Filter{
+			{Name: awsInfra.String("tag:ManagedBy"), Values: []string{"github-runner-scaler-lambda"}},
+			{Name: awsInfra.String("tag:Purpose"), Values: []string{"github-actions-runner"}},
+			{Name: awsInfra.String("instance-state-name"), Values: []string{"pending", "running", "stopping", "stopped"}},
+		},
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe runner instances: %w", err)
+		}
+		for _, reservation := range page.Reservations {
+			for _, instance := range reservation.Instances {
+				tags := tagValues(instance.Tags)
+				name := tags["RunnerName"]
+				if name == "" {
+					name = tags["Name"]
+				}
+				e := entry(name)
+				e.InstanceID = *instance.InstanceId
+				e.State = string(instance.State.Name)
+				e.Pool = tags["Pool"]
+				e.Market = "on-demand"
+				if instance.InstanceLifecycle == ec2types.InstanceLifecycleTypeSpot {
+					e.Market = "spot"
+				}
+				if instance.LaunchTime != nil {
+					e.launchedAt = *instance.LaunchTime
+				}
+				byInstance[e.InstanceID] = e
+			}
+		}
+	}
+
+	records, err := awsInfra.scanRunnerRecords(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		e, ok := byInstance[record.InstanceID]
+		if !ok {
+			// Records of runners that finished are history, not inventory
+			if record.Status != "pending" && record.Status != "running" {
+				continue
+			}
+			e = entry(record.RunnerID)
+			e.InstanceID = record.InstanceID
+		}
+		e.RecordStatus = record.Status
+		if e.launchedAt.IsZero() {
+			e.launchedAt = record.CreatedAt
+		}
+	}
+
+	if busy {
+		jobs, err := runnerJobs(ctx, gheClient)
+		if err != nil {
+			return nil, err
+		}
+		for name, job := range jobs {
+			if e, ok := entries[name]; ok {
+				e.CurrentJob = job
+			}
+		}
+	}
+
+	inventory := make([]InventoryEntry, 0, len(entries))
+	for _, e := range entries {
+		if !e.launchedAt.IsZero() {
+			e.LaunchedAt = e.launchedAt.UTC().Format(time.RFC3339)
+			e.Age = time.Since(e.launchedAt).Round(time.Minute).String()
+		}
+		inventory = append(inventory, *e)
+	}
+	sort.Slice(inventory, func(i, j int) bool { return inventory[i].Name < inventory[j].Name })
+	return inventory, nil
+}
+
+// runnerJobs returns the in-progress job of each busy runner as owner/repo#job-id
+func runnerJobs(ctx context.Context, gheClient *GHEClient) (map[string]string, error) {
+	runs, err := gheClient.GetRunningWorkflowRuns(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make(map[string]string)
+	for _, run := range runs.WorkflowRuns {
+		if run.Repository == nil || run.Repository.Owner == nil {
+			continue
+		}
+		runJobs, err := gheClient.GetWorkflowJobs(ctx, run.Repository.Owner.Login, run.Repository.Name, run.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, job := range runJobs {
+			if job.Status == "in_progress" && job.RunnerName != "" {
+				jobs[job.RunnerName] = fmt.Sprintf("%s#%d", run.Repository.FullName, job.ID)
+			}
+		}
+	}
+	return jobs, nil
+}
+
+// scanRunnerRecords reads every record of the runner table
+func (aws *AWSInfrastructure) scanRunnerRecords(ctx context.Context) ([]RunnerRecord, error) {
+	var records []RunnerRecord
+	paginator := dynamodb.NewScanPaginator(aws.dynamoDBClient, &dynamodb.ScanInput{
+		TableName: aws.String(aws.config.DynamoDBTableName),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan runner records: %w", err)
+		}
+		for _, item := range page.Items {
+			str := func(name string) string {
+				if v, ok := item[name].(*types.AttributeValueMemberS); ok {
+					return v.Value
+				}
+				return ""
+			}
+			record := RunnerRecord{
+				RunnerID:      str("runner_id"),
+				InstanceID:    str("instance_id"),
+				Status:        str("status"),
+				SpotRequestID: str("spot_request_id"),
+				ReplacedBy:    str("replaced_by"),
+				FailureReason: str("failure_reason"),
+			}
+			if v, ok := item["job_request_id"].(*types.AttributeValueMemberN); ok {
+				record.JobRequestID, _ = strconv.ParseInt(v.Value, 10, 64)
+			}
+			record.CreatedAt, _ = time.Parse(time.RFC3339, str("created_at"))
+			record.UpdatedAt, _ = time.Parse(time.RFC3339, str("updated_at"))
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+// writeInventory writes the inventory as a JSON array or as CSV with a header row
+func writeInventory(w io.Writer, inventory []InventoryEntry, format string) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(inventory)
+	case "csv":
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"name", "instance_id", "state", "market", "pool", "github_status", "record_status", "labels", "launched_at", "age", "current_job"})
+		for _, e := range inventory {
+			cw.Write([]string{e.Name, e.InstanceID, e.State, e.Market, e.Pool, e.GitHubStatus, e.RecordStatus,
+				strings.Join(e.Labels, ";"), e.LaunchedAt, e.Age, e.CurrentJob})
+		}
+		cw.Flush()
+		return cw.Error()
+	default:
+		return fmt.Errorf("unknown format %q, expected json or csv", format)
+	}
+}
+
+// runExportCommand writes the runner inventory for audits and capacity reviews:
+//
+//	bootstrap export [--format=json|csv] [--output=file] [--config=file.json] [--setting-name=value ...]
+func runExportCommand(args []string) int {
+	format, output := "json", ""
+	var rest []string
+	for _, arg := range args {
+		switch {
+		case strings.HasPrefix(arg, "--format="):
+			format = strings.TrimPrefix(arg, "--format=")
+		case strings.HasPrefix(arg, "--output="):
+			output = strings.TrimPrefix(arg, "--output=")
+		default:
+			rest = append(rest, arg)
+		}
+	}
+
+	flags, rest, err := parseConfigFlags(rest)
+	if err != nil || len(rest) > 0 {
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		fmt.Fprintln(os.Stderr, "usage: export [--format=json|csv] [--output=file] [--config=file.json] [--setting-name=value ...]")
+		return 2
+	}
+	if format != "json" && format != "csv" {
+		fmt.Fprintf(os.Stderr, "unknown format %q, expected json or csv\n", format)
+		return 2
+	}
+
+	src, err := newConfigSource(flags)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	config, err := loadConfigFrom(src)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Configuration is invalid: %v\n", err)
+		return 1
+	}
+
+	ctx := context.Background()
+	awsInfra, err := NewAWSInfrastructure(ctx, config)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	inventory, err := collectInventory(ctx, NewGHEClient(config), awsInfra)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Failed to collect inventory: %v\n", err)
+		return 1
+	}
+
+	w := io.Writer(os.Stdout)
+	if output != "" {
+		file, err := os.Create(output)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer file.Close()
+		w = file
+	}
+	if err := writeInventory(w, inventory, format); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if output != "" {
+		fmt.Fprintf(os.Stderr, "✅ Wrote %d runners to %s\n", len(inventory), output)
+	}
+	return 0
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// errLockHeld is returned when another invocation holds an unexpired lease
+var errLockHeld = errors.New("invocation lock is held by another invocation")
+
+// InvocationLock is a DynamoDB lease that keeps overlapping scheduled invocations from
+// scaling at the same time. The lease expires on its own if an invocation dies without
+// releasing it.
+type InvocationLock struct {
+	client    *dynamodb.Client
+	tableName string
+	lockID    string
+	owner     string
+	lease     time.Duration
+}
+
+// NewInvocationLock creates a lock for the given lock ID, owned by this invocation
+func NewInvocationLock(client *dynamodb.Client, tableName, lockID, owner string, lease time.Duration) *InvocationLock {
+	return &InvocationLock{
+		client:    client,
+		tableName: tableName,
+		lockID:    lockID,
+		owner:     owner,
+		lease:     lease,
+	}
+}
+
+// Acquire takes the lock if it is free or its lease has expired. It returns errLockHeld
+// when another invocation holds a live lease.
+func (l *InvocationLock) Acquire(ctx context.Context) error {
+	now := time.Now()
+	_, err := l.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: &l.tableName,
+		Item: map[string]types.AttributeValue{
+			"lock_id":    &types.AttributeValueMemberS{Value: l.lockID},
+			"owner":      &types.AttributeValueMemberS{Value: l.owner},
+			"expires_at": &types.AttributeValueMemberN{Value: strconv.FormatInt(now.Add(l.lease).Unix(), 10)},
+		},
+		ConditionExpression: stringPtr("attribute_not_exists(lock_id) OR expires_at < :now"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":now": &types.AttributeValueMemberN{Value: strconv.FormatInt(now.Unix(), 10)},
+		},
+	})
+	if err != nil {
+		var conditionFailed *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionFailed) {
+			return errLockHeld
+		}
+		return fmt.Errorf("failed to acquire invocation lock: %w", err)
+	}
+	return nil
+}
+
+// Release gives the lock up, but only if this invocation still owns it
+func (l *InvocationLock) Release(ctx context.Context) error {
+	_, err := l.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: &l.tableName,
+		Key: map[string]types.AttributeValue{
+			"lock_id": &types.AttributeValueMemberS{Value: l.lockID},
+		},
+		ConditionExpression: stringPtr("#owner = :owner"),
+		ExpressionAttributeNames: map[string]string{
+			"#owner": "owner",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":owner": &types.AttributeValueMemberS{Value: l.owner},
+		},
+	})
+	if err != nil {
+		var conditionFailed *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionFailed) {
+			// Our lease expired and another invocation took over; nothing to release
+			return nil
+		}
+		return fmt.Errorf("failed to release invocation lock: %w", err)
+	}
+	return nil
+}
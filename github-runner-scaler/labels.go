@@ -0,0 +1,37 @@
+package main
+
+import "github.com/Anshuman2121/actionsspot/internal/labels"
+
+// The label matcher is shared with the Lambda scaler
+type (
+	LabelMatcher      = labels.Matcher
+	LabelMatchOptions = labels.MatchOptions
+)
+
+// jobLabelOptions matches workflow jobs against runners, which all carry self-hosted
+var jobLabelOptions = labels.JobOptions
+
+// NewLabelMatcher creates a matcher for a runner carrying the given labels
+func NewLabelMatcher(runnerLabels []string, options LabelMatchOptions) *LabelMatcher {
+	return labels.NewMatcher(runnerLabels, options)
+}
+
+// literalLabels returns the plain labels a runner can register with
+func literalLabels(configured []string) []string {
+	return labels.Literal(configured)
+}
+
+// appendUniqueLabels appends the labels not already present, comparing case-insensitively
+func appendUniqueLabels(existing []string, more []string) []string {
+	return labels.AppendUnique(existing, more)
+}
+
+// exclusiveLabel returns the labels with label in place of any of its alternatives
+func exclusiveLabel(configured []string, label string, alternatives ...string) []string {
+	return labels.Exclusive(configured, label, alternatives...)
+}
+
+// validateLabels checks that every configured pattern and expression parses
+func validateLabels(configured []string) error {
+	return labels.Validate(configured)
+}
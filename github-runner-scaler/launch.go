@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+
+	"github.com/Anshuman2121/actionsspot/internal/ec2launch"
+)
+
+// launchTarget is where and how a single runner instance is launched
+type launchTarget struct {
+	InstanceType string
+	SubnetID     string
+	OnDemand     bool
+}
+
+// chooseLaunchTarget picks an instance type and subnet from the configured alternatives and
+// decides between spot and on-demand according to the on-demand percentage, or always picks
+// on-demand in on-demand-only mode. Spreading launches over several types and subnets makes
+// spot capacity shortages less likely.
+func (aws *AWSInfrastructure) chooseLaunchTarget() launchTarget {
+	target := launchTarget{
+		InstanceType: aws.config.EC2InstanceType,
+		SubnetID:     aws.config.EC2SubnetID,
+	}
+	if n := len(aws.config.EC2InstanceTypes); n > 0 {
+		target.InstanceType = aws.config.EC2InstanceTypes[rand.Intn(n)]
+	}
+	if n := len(aws.config.EC2SubnetIDs); n > 0 {
+		target.SubnetID = aws.config.EC2SubnetIDs[rand.Intn(n)]
+	}
+	target.OnDemand = aws.config.OnDemandOnly || rand.Intn(100) < aws.config.OnDemandPercentage
+	return target
+}
+
+// validateTenancy checks EC2_TENANCY. Host tenancy needs dedicated hosts, which the
+// scaler does not allocate, so only shared and dedicated instances are supported.
+func validateTenancy(tenancy string) error {
+	switch ec2types.Tenancy(tenancy) {
+	case ec2types.TenancyDefault, ec2types.TenancyDedicated:
+		return nil
+	}
+	return fmt.Errorf("unsupported tenancy %q (want default or dedicated)", tenancy)
+}
+
+// instancePlacement returns the placement for runner instances, or nil when neither a
+// placement group nor dedicated tenancy is configured
+func (c Config) instancePlacement() *ec2types.Placement {
+	if c.EC2PlacementGroup == "" && c.EC2Tenancy == string(ec2types.TenancyDefault) {
+		return nil
+	}
+	placement := &ec2types.Placement{Tenancy: ec2types.Tenancy(c.EC2Tenancy)}
+	if c.EC2PlacementGroup != "" {
+		placement.GroupName = &c.EC2PlacementGroup
+	}
+	return placement
+}
+
+// networkInterfaces returns the primary network interface for a launch when IPv6
+// addresses or an explicit public IPv4 setting are configured, and nil otherwise. EC2
+// rejects a subnet or security groups outside the interface once one is specified.
+func (aws *AWSInfrastructure) networkInterfaces(subnetID string) []ec2types.InstanceNetworkInterfaceSpecification {
+	if aws.config.EC2IPv6AddressCount == 0 && aws.config.EC2AssociatePublicIP == nil {
+		return nil
+	}
+	nic := ec2types.InstanceNetworkInterfaceSpecification{
+		DeviceIndex:              aws.Int32(0),
+		SubnetId:                 aws.String(subnetID),
+		Groups:                   aws.config.EC2SecurityGroupIDs,
+		AssociatePublicIpAddress: aws.config.EC2AssociatePublicIP,
+		DeleteOnTermination:      aws.Bool(true),
+	}
+	if aws.config.EC2IPv6AddressCount > 0 {
+		nic.Ipv6AddressCount = aws.Int32(int32(aws.config.EC2IPv6AddressCount))
+	}
+	return []ec2types.InstanceNetworkInterfaceSpecification{nic}
+}
+
+// runnerTags returns the tags for a runner's instance or spot request, including the
+// pool's own tags. The scaler's tags win over pool tags with the same key.
+func (aws *AWSInfrastructure) runnerTags(runnerName string) []ec2types.Tag {
+	tags := make([]ec2types.Tag, 0, len(aws.config.EC2Tags)+7)
+	for key, value := range aws.config.EC2Tags {
+		switch key {
+		case "Name", "Purpose", "RunnerName", "ManagedBy", "CreatedAt", "Pool", bootstrapHashTag, poolGenerationTag:
+			continue
+		}
+		tags = append(tags, ec2types.Tag{Key: aws.String(key), Value: aws.String(value)})
+	}
+
+	tags = append(tags,
+		ec2types.Tag{Key: aws.String("Name"), Value: aws.String(runnerName)},
+		ec2types.Tag{Key: aws.String("Purpose"), Value: aws.String("github-actions-runner")},
+		ec2types.Tag{Key: aws.String("RunnerName"), Value: aws.String(runnerName)},
+		ec2types.Tag{Key: aws.String("ManagedBy"), Value: aws.String("github-runner-scaler-lambda")},
+		ec2types.Tag{Key: aws.String("CreatedAt"), Value: aws.String(time.Now().Format(time.RFC3339))},
+		ec2types.Tag{Key: aws.String(bootstrapHashTag), Value: aws.String(aws.bootstrapHash())},
+		ec2types.Tag{Key: aws.String(poolGenerationTag), Value: aws.String(aws.poolGeneration())},
+	)
+	if aws.config.PoolName != "" {
+		tags = append(tags, ec2types.Tag{Key: aws.String("Pool"), Value: aws.String(aws.config.PoolName)})
+	}
+	return tags
+}
+
+// launchInstance launches a runner instance with RunInstances, as a spot instance through
+// the spot market options unless the target is on-demand, and returns its instance ID.
+// Unlike spot requests, RunInstances tags the instance and its volumes at launch.
+//
+// With a launch template, the template supplies the AMI, key pair, block devices, IAM
+// profile and metadata options, and the scaler only adds the user data, tags, instance
+// type, subnet and market options, plus the settings that are explicitly configured.
+func (aws *AWSInfrastructure) launchInstance(ctx context.Context, runnerName, userDataEncoded string, target launchTarget, tags []ec2types.Tag) (*string, error) {
+	input := &ec2.RunInstancesInput{
+		ImageId:          aws.String(aws.config.EC2AMI),
+		InstanceType:     ec2types.InstanceType(target.InstanceType),
+		KeyName:          aws.String(aws.config.EC2KeyPairName),
+		SecurityGroupIds: aws.config.EC2SecurityGroupIDs,
+		SubnetId:         aws.String(target.SubnetID),
+		Placement:        aws.config.instancePlacement(),
+		UserData:         aws.String(userDataEncoded),
+		MinCount:         aws.Int32(1),
+		MaxCount:         aws.Int32(1),
+		Monitoring:       &ec2types.RunInstancesMonitoringEnabled{Enabled: aws.Bool(true)},
+		TagSpecifications: []ec2types.TagSpecification{
+			{ResourceType: ec2types.ResourceTypeInstance, Tags: tags},
+			{ResourceType: ec2types.ResourceTypeVolume, Tags: tags},
+		},
+	}
+	if aws.config.EC2LaunchTemplateID != "" {
+		input.LaunchTemplate = &ec2types.LaunchTemplateSpecification{LaunchTemplateId: aws.String(aws.config.EC2LaunchTemplateID)}
+		if aws.config.EC2LaunchTemplateVersion != "" {
+			input.LaunchTemplate.Version = aws.String(aws.config.EC2LaunchTemplateVersion)
+		}
+		input.Monitoring = nil
+		if aws.config.EC2AMI == "" {
+			input.ImageId = nil
+		}
+		if aws.config.EC2KeyPairName == "" {
+			input.KeyName = nil
+		}
+	}
+	market := "on-demand"
+	if !target.OnDemand {
+		market = "spot"
+		// Tagged so requests left open by a capacity shortage can be found and cancelled
+		input.TagSpecifications = append(input.TagSpecifications,
+			ec2types.TagSpecification{ResourceType: ec2types.ResourceTypeSpotInstancesRequest, Tags: tags})
+		input.InstanceMarketOptions = &ec2types.InstanceMarketOptionsRequest{
+			MarketType: ec2types.MarketTypeSpot,
+			SpotOptions: &ec2types.SpotMarketOptions{
+				MaxPrice:                     ec2launch.SpotPriceFor(aws.config.EC2SpotPrices, target.InstanceType),
+				SpotInstanceType:             aws.config.spotRequestType(),
+				InstanceInterruptionBehavior: ec2types.InstanceInterruptionBehavior(aws.config.SpotInterruptionBehavior),
+			},
+		}
+	}
+	input.BlockDeviceMappings = aws.toolcacheBlockDevices()
+	if aws.config.EC2InstanceProfile != "" {
+		input.IamInstanceProfile = &ec2types.IamInstanceProfileSpecification{Name: aws.String(aws.config.EC2InstanceProfile)}
+	}
+	if nics := aws.networkInterfaces(target.SubnetID); nics != nil {
+		input.NetworkInterfaces = nics
+		input.SubnetId, input.SecurityGroupIds = nil, nil
+	}
+	if aws.config.EC2IPv6AddressCount > 0 {
+		input.MetadataOptions = &ec2types.InstanceMetadataOptionsRequest{
+			HttpProtocolIpv6: ec2types.InstanceMetadataProtocolStateEnabled,
+		}
+	}
+
+	result, err := aws.ec2Client.RunInstances(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run %s instance: %w", market, err)
+	}
+	if len(result.Instances) == 0 {
+		return nil, fmt.Errorf("no %s instance created", market)
+	}
+
+	instanceID := result.Instances[0].InstanceId
+	loggerFrom(ctx).Info("Created instance", "market", market, "instanceId", *instanceID,
+		"instanceType", target.InstanceType, "runnerName", runnerName)
+	return instanceID, nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/go-logr/logr"
+	"github.com/go-logr/zapr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Log formats
+const (
+	logFormatJSON    = "json"    // one JSON object per line, for CloudWatch Logs Insights
+	logFormatConsole = "console" // human-readable lines for local runs
+)
+
+// baseLogger logs where no invocation logger is at hand: before the configuration of an
+// invocation is loaded, and in the CLI commands
+var baseLogger = defaultLogger()
+
+// validateLogSettings checks LOG_LEVEL and LOG_FORMAT
+func validateLogSettings(level, format string) error {
+	if _, err := zapcore.ParseLevel(level); err != nil {
+		return fmt.Errorf("invalid LOG_LEVEL: %w", err)
+	}
+	if format != logFormatJSON && format != logFormatConsole {
+		return fmt.Errorf("invalid LOG_FORMAT: %q is not %s or %s", format, logFormatJSON, logFormatConsole)
+	}
+	return nil
+}
+
+// newLogger builds a zap-backed logr.Logger at the given level and format
+func newLogger(level, format string) (logr.Logger, error) {
+	if err := validateLogSettings(level, format); err != nil {
+		return logr.Logger{}, err
+	}
+	zapLevel, _ := zapcore.ParseLevel(level)
+
+	zapConfig := zap.NewProductionConfig()
+	if format == logFormatConsole {
+		zapConfig = zap.NewDevelopmentConfig()
+	}
+	zapConfig.Level = zap.NewAtomicLevelAt(zapLevel)
+	zapConfig.Sampling = nil // every scaling decision matters, never drop lines
+	zapConfig.DisableStacktrace = true
+	zapConfig.EncoderConfig.TimeKey = "timestamp"
+	zapConfig.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+
+	zapLogger, err := zapConfig.Build()
+	if err != nil {
+		return logr.Logger{}, err
+	}
+	return zapr.NewLogger(zapLogger), nil
+}
+
+// defaultLogger logs at info level in JSON until the configuration of an invocation is loaded
+func defaultLogger() logr.Logger {
+	l, err := newLogger("info", logFormatJSON)
+	if err != nil {
+		panic(err)
+	}
+	return l
+}
+
+// withLogger returns a context carrying the logger, which every function called with the
+// context logs through
+func withLogger(ctx context.Context, l logr.Logger) context.Context {
+	return logr.NewContext(ctx, l)
+}
+
+// loggerFrom returns the logger of the invocation, or pool, the context belongs to
+func loggerFrom(ctx context.Context) logr.Logger {
+	if l, err := logr.FromContext(ctx); err == nil {
+		return l
+	}
+	return baseLogger
+}
+
+// invocationLogger returns the logger for one invocation, with the Lambda request ID and
+// the scale set as fields of every line
+func invocationLogger(ctx context.Context, config Config) (logr.Logger, error) {
+	l, err := newLogger(config.LogLevel, config.LogFormat)
+	if err != nil {
+		return logr.Logger{}, err
+	}
+	if lc, ok := lambdacontext.FromContext(ctx); ok {
+		l = l.WithValues("requestId", lc.AwsRequestID)
+	}
+	if config.RunnerScaleSetName != "" {
+		l = l.WithValues("scaleSet", config.RunnerScaleSetName)
+	}
+	return l, nil
+}
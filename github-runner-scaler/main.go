@@ -5,63 +5,157 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"log"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
-	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-lambda-go/lambdacontext"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	lambdaservice "github.com/aws/aws-sdk-go-v2/service/lambda"
+
+	"github.com/Anshuman2121/actionsspot/internal/ec2launch"
+	"github.com/Anshuman2121/actionsspot/internal/runnername"
 )
 
 // No longer using runner scale set types - using pipeline monitor approach
 
 // Lambda handler configuration
 type Config struct {
-	GitHubToken              string
-	GitHubEnterpriseURL      string
-	OrganizationName         string
-	MinRunners               int
-	MaxRunners               int
-	EC2InstanceType          string
-	EC2AMI                   string
-	EC2SubnetID              string
-	EC2SecurityGroupID       string
-	EC2KeyPairName           string
-	EC2SpotPrice             string
-	DynamoDBTableName        string
-	RunnerLabels             []string
-	CleanupOfflineRunners    bool
-	RepositoryNames          []string // Optional: specific repositories to monitor, if empty monitors all org repos
+	GitHubToken          string
+	GitHubTokenSecretARN string        // Optional: Secrets Manager secret holding the token instead
+	GitHubTokenSecretTTL time.Duration // how long warm invocations reuse the token read from the secret
+	// Optional: authenticate as a GitHub App installation instead, with the App's private
+	// key read from Secrets Manager
+	GitHubAppID                  int64
+	GitHubAppInstallationID      int64
+	GitHubAppPrivateKeySecretARN string
+	GitHubEnterpriseURL          string
+	OrganizationName             string
+	MinRunners                   int
+	MaxRunners                   int
+	EC2InstanceType              string
+	EC2AMI                       string
+	EC2SubnetID                  string
+	EC2SecurityGroupIDs          []string // Base security group followed by any additional ones
+	EC2KeyPairName               string
+	EC2SpotPrices                map[string]string              // Spot price ceilings per instance type, "default" for the rest
+	EC2InstanceTypes             []string                       // Optional: launch from these types instead of EC2InstanceType
+	EC2SubnetIDs                 []string                       // Optional: spread launches over these subnets
+	OnDemandPercentage           int                            // Share of runners launched on-demand instead of spot
+	OnDemandOnly                 bool                           // Never issue spot requests, for accounts that forbid spot
+	SpotAllocationStrategy       string                         // random, lowest-price or capacity-optimized
+	SpotInterruptionBehavior     string                         // terminate, stop or hibernate
+	EC2Tenancy                   string                         // default or dedicated
+	EC2PlacementGroup            string                         // Optional: launch runners into this placement group
+	EC2IPv6AddressCount          int                            // IPv6 addresses per runner, for dual-stack and IPv6-only subnets
+	EC2AssociatePublicIP         *bool                          // Optional: override the subnet's public IPv4 setting
+	PrivateBootstrap             bool                           // Bootstrap runners without internet egress, through VPC endpoints
+	RunnerTarballS3URI           string                         // Optional: S3 mirror of the runner tarball, required for private bootstrap
+	RunnerTokenParameterPath     string                         // Optional: SSM path runners read their registration token from, instead of the user data
+	RunnerArchitecture           string                         // x64 or arm64, for Graviton runners
+	Architectures                map[string]architectureProfile // AMI, instance type and tarball mirror per architecture
+	RunnerOS                     string                         // linux or windows
+	EC2AMIWindows                string                         // Optional: AMI of windows pools of a linux scaler
+	RecycleStaleRunners          bool                           // Replace idle runners launched from an outdated bootstrap template
+	GenerationDrainBatch         int                            // Old-generation runners retired per cycle after a pool configuration change; 0 disables draining
+	ProbeWorkflow                string                         // Optional: owner/repo/workflow-file[@ref] run by AMI probes
+	RequireProbedAMI             bool                           // Only launch runners from AMIs that passed a probe for their pool
+	EC2InstanceProfile           string                         // Optional: instance profile runners are launched with
+	EC2LaunchTemplateID          string                         // Optional: launch template providing AMI, block devices, IAM profile and metadata options
+	EC2LaunchTemplateVersion     string                         // Optional: template version, a number, $Latest or $Default (the default)
+	DiagnosticsS3URI             string                         // Optional: s3:// prefix failed runners upload their diagnostics to
+	RegistrationTimeout          time.Duration                  // Optional: terminate runners not registered after this long
+	OrphanMaxAge                 time.Duration                  // Terminate instances without a live runner after this long (0 disables)
+	SpotRequestTimeout           time.Duration                  // Cancel spot requests open this long (0 disables)
+	SpotRequestFallback          string                         // none, instance-type or on-demand relaunch after a cancelled request
+	IdleTimeout                  time.Duration                  // Terminate runners idle this long, above MinRunners (0 disables)
+	DebugHoldHours               int                            // Keep instances of failed jobs this long for inspection
+	DebugHoldLabels              []string                       // Optional: only hold runners carrying one of these labels
+	WorkspaceCleanup             bool                           // Wipe the workspace, Docker state and credentials between jobs
+	WorkspaceCleanupScript       string                         // Optional: shell commands run as root after the built-in wipe
+	PrewarmImages                []string                       // Optional: container images pulled while the runner registers
+	ActionsCacheProxyURL         string                         // Optional: in-VPC actions cache server runners use instead of GitHub's
+	ToolcacheEFSID               string                         // Optional: EFS file system holding a shared read-only toolcache
+	ToolcacheSnapshotID          string                         // Optional: EBS snapshot holding a shared read-only toolcache
+	BreakglassMaxTTL             time.Duration                  // Longest breakglass access that may be granted
+	ChaosMode                    bool                           // Inject faults into runners for resilience testing
+	ChaosIdleTermination         int                            // Chaos: percentage chance per cycle that an idle runner is terminated
+	ChaosSpotInterruption        int                            // Chaos: percentage chance per cycle that a busy spot runner is interrupted
+	EC2Tags                      map[string]string              // Extra tags for runner instances and spot requests
+	DynamoDBTableName            string
+	RunnerRecordRetention        time.Duration // Runner records expire through TTL this long after their last write
+	RunnerLabels                 []string
+	ExcludedLabels               []string // Jobs carrying any of these labels are never provisioned for
+	CleanupOfflineRunners        bool
+	RepositoryNames              []string // Optional: specific repositories to monitor, if empty monitors all org repos
+	RunnerScaleSetName           string   // Optional: scale set whose message session is kept alive between invocations
+	SessionsTableName            string
+	SessionMaxAge                time.Duration
+	SessionRecordRetention       time.Duration // Session records expire through TTL this long after their last refresh
+	LockTableName                string
+	LockLease                    time.Duration
+	RolloutsTableName            string // Blue/green AMI rollouts, keyed by pool
+	WebhookSecret                string
+	Pools                        []PoolConfig // Optional: evaluate several label pools per invocation
+	PoolName                     string       // Set on the per-pool copy of the config
+	SelfScheduling               bool         // Manage the EventBridge schedule rate from the Lambda
+	ScheduleRuleName             string
+	ScheduleFastInterval         time.Duration // Rate while jobs are queued
+	ScheduleIdleInterval         time.Duration // Rate while nothing is queued
+	ScaleDownDelay               time.Duration // Optional: check launched runners for idleness after this delay
+	RunnerNamePrefix             string
+	RunnerNameTemplate           string // Placeholders: {prefix} {scaleset} {pool} {id} {timestamp}
+	AppConfigApplication         string // Optional: load the scaling policy from AWS AppConfig
+	AppConfigEnvironment         string
+	AppConfigProfile             string
+	AppConfigAgentURL            string
+	MetricsEMF                   bool          // Write invocation metrics as CloudWatch Embedded Metric Format
+	MetricsNamespace             string        // CloudWatch namespace of the EMF metrics
+	LogLevel                     string        // debug, info, warn or error
+	LogFormat                    string        // json or console
+	PushgatewayURL               string        // Optional: also push invocation metrics to a Prometheus Pushgateway
+	InterruptionsTableName       string        // Optional: record spot interruptions and the jobs they hit
+	InterruptionReportWindow     time.Duration // Period covered by the interruption report
+	InterruptionJobThreshold     int           // Jobs lost in the window before a pool is flagged for on-demand
+	ReplaceInterruptedRunners    bool          // Launch a replacement when a busy runner's spot instance is reclaimed
 }
 
-
-
 // AWS infrastructure
 type AWSInfrastructure struct {
 	ec2Client      *ec2.Client
 	dynamoDBClient *dynamodb.Client
+	eventsClient   *eventbridge.Client
+	lambdaClient   *lambdaservice.Client
+	ssmClient      *ssmCommandClient
+	metrics        *metricsRecorder
 	config         Config
+
+	// noDefaultLabels registers runners with only their own labels, without the
+	// self-hosted, OS and architecture labels the runner adds by default
+	noDefaultLabels bool
 }
 
 // DynamoDB schema for tracking runners and sessions
 type RunnerRecord struct {
-	RunnerID           string    `dynamodbav:"runner_id"`
-	InstanceID         string    `dynamodbav:"instance_id"`
-	JobRequestID       int64     `dynamodbav:"job_request_id"`
-	Status             string    `dynamodbav:"status"` // pending, running, completed, failed
-	CreatedAt          time.Time `dynamodbav:"created_at"`
-	UpdatedAt          time.Time `dynamodbav:"updated_at"`
-	SpotRequestID      string    `dynamodbav:"spot_request_id,omitempty"`
+	RunnerID      string    `dynamodbav:"runner_id"`
+	InstanceID    string    `dynamodbav:"instance_id"`
+	JobRequestID  int64     `dynamodbav:"job_request_id"`
+	Status        string    `dynamodbav:"status"` // pending, running, completed, failed, interrupted
+	CreatedAt     time.Time `dynamodbav:"created_at"`
+	UpdatedAt     time.Time `dynamodbav:"updated_at"`
+	SpotRequestID string    `dynamodbav:"spot_request_id,omitempty"`
+	ReplacedBy    string    `dynamodbav:"replaced_by,omitempty"` // runner launched for the job of an interrupted runner
+	FailureReason string    `dynamodbav:"failure_reason,omitempty"`
+	Version       int64     `dynamodbav:"version"` // optimistic lock, incremented by every write
 }
 
-
-
 // Initialize AWS infrastructure
 func NewAWSInfrastructure(ctx context.Context, cfg Config) (*AWSInfrastructure, error) {
 	awsCfg, err := config.LoadDefaultConfig(ctx)
@@ -72,198 +166,550 @@ func NewAWSInfrastructure(ctx context.Context, cfg Config) (*AWSInfrastructure,
 	return &AWSInfrastructure{
 		ec2Client:      ec2.NewFromConfig(awsCfg),
 		dynamoDBClient: dynamodb.NewFromConfig(awsCfg),
+		eventsClient:   eventbridge.NewFromConfig(awsCfg),
+		lambdaClient:   lambdaservice.NewFromConfig(awsCfg),
+		ssmClient:      newSSMCommandClient(awsCfg),
+		metrics:        newMetricsRecorder(cfg),
 		config:         cfg,
 	}, nil
 }
 
-// Load configuration from environment variables
+// LoadConfig loads the configuration from the environment, the optional CONFIG_FILE and
+// the built-in defaults
 func LoadConfig() (Config, error) {
-	minRunners, err := strconv.Atoi(getEnvOrDefault("MIN_RUNNERS", "0"))
+	src, err := newConfigSource(nil)
+	if err != nil {
+		return Config{}, err
+	}
+	return loadConfigFrom(src)
+}
+
+// loadConfigFrom parses and validates the configuration resolved by src
+func loadConfigFrom(src *configSource) (Config, error) {
+	minRunners, err := strconv.Atoi(src.Get("MIN_RUNNERS"))
 	if err != nil {
 		return Config{}, fmt.Errorf("invalid MIN_RUNNERS: %w", err)
 	}
 
-	maxRunners, err := strconv.Atoi(getEnvOrDefault("MAX_RUNNERS", "10"))
+	maxRunners, err := strconv.Atoi(src.Get("MAX_RUNNERS"))
 	if err != nil {
 		return Config{}, fmt.Errorf("invalid MAX_RUNNERS: %w", err)
 	}
 
 	var runnerLabels []string
-	if labels := os.Getenv("RUNNER_LABELS"); labels != "" {
+	if labels := src.Get("RUNNER_LABELS"); labels != "" {
 		if err := json.Unmarshal([]byte(labels), &runnerLabels); err != nil {
 			return Config{}, fmt.Errorf("invalid RUNNER_LABELS JSON: %w", err)
 		}
 	}
+	if err := validateLabels(runnerLabels); err != nil {
+		return Config{}, fmt.Errorf("invalid RUNNER_LABELS: %w", err)
+	}
+
+	var excludedLabels []string
+	if labels := src.Get("EXCLUDED_LABELS"); labels != "" {
+		if err := json.Unmarshal([]byte(labels), &excludedLabels); err != nil {
+			return Config{}, fmt.Errorf("invalid EXCLUDED_LABELS JSON: %w", err)
+		}
+	}
+	if err := validateLabels(excludedLabels); err != nil {
+		return Config{}, fmt.Errorf("invalid EXCLUDED_LABELS: %w", err)
+	}
+
+	var instanceTypes []string
+	if types := src.Get("EC2_INSTANCE_TYPES"); types != "" {
+		if err := json.Unmarshal([]byte(types), &instanceTypes); err != nil {
+			return Config{}, fmt.Errorf("invalid EC2_INSTANCE_TYPES JSON: %w", err)
+		}
+	}
 
-	cleanupOffline, _ := strconv.ParseBool(getEnvOrDefault("CLEANUP_OFFLINE_RUNNERS", "true"))
+	var subnetIDs []string
+	if subnets := src.Get("EC2_SUBNET_IDS"); subnets != "" {
+		if err := json.Unmarshal([]byte(subnets), &subnetIDs); err != nil {
+			return Config{}, fmt.Errorf("invalid EC2_SUBNET_IDS JSON: %w", err)
+		}
+	}
+
+	onDemandPercentage, err := strconv.Atoi(src.Get("ON_DEMAND_PERCENTAGE"))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid ON_DEMAND_PERCENTAGE: %w", err)
+	}
+	if onDemandPercentage < 0 || onDemandPercentage > 100 {
+		return Config{}, fmt.Errorf("invalid ON_DEMAND_PERCENTAGE: %d is not between 0 and 100", onDemandPercentage)
+	}
+
+	onDemandOnly, err := strconv.ParseBool(src.Get("ON_DEMAND_ONLY"))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid ON_DEMAND_ONLY: %w", err)
+	}
+
+	spotAllocationStrategy := src.Get("SPOT_ALLOCATION_STRATEGY")
+	if err := validateAllocationStrategy(spotAllocationStrategy); err != nil {
+		return Config{}, fmt.Errorf("invalid SPOT_ALLOCATION_STRATEGY: %w", err)
+	}
+
+	spotInterruptionBehavior := src.Get("SPOT_INTERRUPTION_BEHAVIOR")
+	if err := validateInterruptionBehavior(spotInterruptionBehavior); err != nil {
+		return Config{}, fmt.Errorf("invalid SPOT_INTERRUPTION_BEHAVIOR: %w", err)
+	}
+
+	tenancy := src.Get("EC2_TENANCY")
+	if err := validateTenancy(tenancy); err != nil {
+		return Config{}, fmt.Errorf("invalid EC2_TENANCY: %w", err)
+	}
+
+	ipv6AddressCount, err := strconv.Atoi(src.Get("EC2_IPV6_ADDRESS_COUNT"))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid EC2_IPV6_ADDRESS_COUNT: %w", err)
+	}
+	if ipv6AddressCount < 0 {
+		return Config{}, fmt.Errorf("invalid EC2_IPV6_ADDRESS_COUNT: %d is negative", ipv6AddressCount)
+	}
+
+	// Unset leaves public IPv4 to the subnet; false suits subnets that egress via NAT or IPv6
+	var associatePublicIP *bool
+	if value := src.Get("EC2_ASSOCIATE_PUBLIC_IP"); value != "" {
+		associate, err := strconv.ParseBool(value)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid EC2_ASSOCIATE_PUBLIC_IP: %w", err)
+		}
+		associatePublicIP = &associate
+	}
+
+	var securityGroupIDs []string
+	if groupID := src.Get("EC2_SECURITY_GROUP_ID"); groupID != "" {
+		securityGroupIDs = append(securityGroupIDs, groupID)
+	}
+	if groups := src.Get("EC2_SECURITY_GROUP_IDS"); groups != "" {
+		var additional []string
+		if err := json.Unmarshal([]byte(groups), &additional); err != nil {
+			return Config{}, fmt.Errorf("invalid EC2_SECURITY_GROUP_IDS JSON: %w", err)
+		}
+		securityGroupIDs = appendUniqueStrings(securityGroupIDs, additional...)
+	}
+
+	privateBootstrap, err := strconv.ParseBool(src.Get("PRIVATE_BOOTSTRAP"))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid PRIVATE_BOOTSTRAP: %w", err)
+	}
+	runnerTarballS3URI := src.Get("RUNNER_TARBALL_S3_URI")
+	if runnerTarballS3URI != "" && !strings.HasPrefix(runnerTarballS3URI, "s3://") {
+		return Config{}, fmt.Errorf("invalid RUNNER_TARBALL_S3_URI: %q is not an s3:// URI", runnerTarballS3URI)
+	}
+	runnerTarballS3URIARM64 := src.Get("RUNNER_TARBALL_S3_URI_ARM64")
+	if runnerTarballS3URIARM64 != "" && !strings.HasPrefix(runnerTarballS3URIARM64, "s3://") {
+		return Config{}, fmt.Errorf("invalid RUNNER_TARBALL_S3_URI_ARM64: %q is not an s3:// URI", runnerTarballS3URIARM64)
+	}
+	runnerTokenParameterPath := src.Get("RUNNER_TOKEN_PARAMETER_PATH")
+	if err := validateRunnerTokenParameterPath(runnerTokenParameterPath); err != nil {
+		return Config{}, fmt.Errorf("invalid RUNNER_TOKEN_PARAMETER_PATH: %w", err)
+	}
+	runnerArchitecture := src.Get("RUNNER_ARCHITECTURE")
+	if err := validateArchitecture(runnerArchitecture); err != nil {
+		return Config{}, fmt.Errorf("invalid RUNNER_ARCHITECTURE: %w", err)
+	}
+	runnerOS := src.Get("RUNNER_OS")
+	if err := validateRunnerOS(runnerOS); err != nil {
+		return Config{}, fmt.Errorf("invalid RUNNER_OS: %w", err)
+	}
+
+	recycleStaleRunners, err := strconv.ParseBool(src.Get("RECYCLE_STALE_RUNNERS"))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid RECYCLE_STALE_RUNNERS: %w", err)
+	}
+
+	generationDrainBatch, err := strconv.Atoi(src.Get("GENERATION_DRAIN_BATCH"))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid GENERATION_DRAIN_BATCH: %w", err)
+	}
+
+	probeWorkflow := src.Get("PROBE_WORKFLOW")
+	if probeWorkflow != "" {
+		if _, err := parseProbeWorkflow(probeWorkflow); err != nil {
+			return Config{}, fmt.Errorf("invalid PROBE_WORKFLOW: %w", err)
+		}
+	}
+
+	requireProbedAMI, err := strconv.ParseBool(src.Get("REQUIRE_PROBED_AMI"))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid REQUIRE_PROBED_AMI: %w", err)
+	}
+
+	diagnosticsS3URI := src.Get("DIAGNOSTICS_S3_URI")
+	if diagnosticsS3URI != "" && !strings.HasPrefix(diagnosticsS3URI, "s3://") {
+		return Config{}, fmt.Errorf("invalid DIAGNOSTICS_S3_URI: %q is not an s3:// URI", diagnosticsS3URI)
+	}
+
+	registrationTimeout, err := time.ParseDuration(src.Get("RUNNER_REGISTRATION_TIMEOUT"))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid RUNNER_REGISTRATION_TIMEOUT: %w", err)
+	}
+
+	orphanMaxAge, err := time.ParseDuration(src.Get("ORPHAN_MAX_AGE"))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid ORPHAN_MAX_AGE: %w", err)
+	}
+
+	spotRequestTimeout, err := time.ParseDuration(src.Get("SPOT_REQUEST_TIMEOUT"))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid SPOT_REQUEST_TIMEOUT: %w", err)
+	}
+
+	idleTimeout, err := time.ParseDuration(src.Get("IDLE_TIMEOUT"))
+	if err != nil || idleTimeout < 0 {
+		return Config{}, fmt.Errorf("invalid IDLE_TIMEOUT: %q", src.Get("IDLE_TIMEOUT"))
+	}
+
+	spotRequestFallback := src.Get("SPOT_REQUEST_FALLBACK")
+	if err := validateSpotFallback(spotRequestFallback); err != nil {
+		return Config{}, fmt.Errorf("invalid SPOT_REQUEST_FALLBACK: %w", err)
+	}
+
+	debugHoldHours, err := strconv.Atoi(src.Get("DEBUG_HOLD_HOURS"))
+	if err != nil || debugHoldHours < 0 {
+		return Config{}, fmt.Errorf("invalid DEBUG_HOLD_HOURS: %q", src.Get("DEBUG_HOLD_HOURS"))
+	}
+
+	var debugHoldLabels []string
+	if labels := src.Get("DEBUG_HOLD_LABELS"); labels != "" {
+		if err := json.Unmarshal([]byte(labels), &debugHoldLabels); err != nil {
+			return Config{}, fmt.Errorf("invalid DEBUG_HOLD_LABELS JSON: %w", err)
+		}
+	}
+
+	var prewarmImages []string
+	if images := src.Get("PREWARM_IMAGES"); images != "" {
+		if err := json.Unmarshal([]byte(images), &prewarmImages); err != nil {
+			return Config{}, fmt.Errorf("invalid PREWARM_IMAGES JSON: %w", err)
+		}
+		if err := validatePrewarmImages(prewarmImages); err != nil {
+			return Config{}, fmt.Errorf("invalid PREWARM_IMAGES: %w", err)
+		}
+	}
+
+	if err := ec2launch.ValidateLaunchTemplate(src.Get("EC2_LAUNCH_TEMPLATE_ID"), src.Get("EC2_LAUNCH_TEMPLATE_VERSION")); err != nil {
+		return Config{}, fmt.Errorf("invalid EC2_LAUNCH_TEMPLATE_ID: %w", err)
+	}
+
+	if err := validateRunnerCaches(src.Get("ACTIONS_CACHE_PROXY_URL"), src.Get("TOOLCACHE_EFS_ID"), src.Get("TOOLCACHE_SNAPSHOT_ID")); err != nil {
+		return Config{}, err
+	}
+
+	workspaceCleanup, err := strconv.ParseBool(src.Get("WORKSPACE_CLEANUP"))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid WORKSPACE_CLEANUP: %w", err)
+	}
+
+	breakglassMaxTTL, err := time.ParseDuration(src.Get("BREAKGLASS_MAX_TTL"))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid BREAKGLASS_MAX_TTL: %w", err)
+	}
+
+	chaosMode, err := strconv.ParseBool(src.Get("CHAOS_MODE"))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid CHAOS_MODE: %w", err)
+	}
+	chaosIdleTermination, err := strconv.Atoi(src.Get("CHAOS_IDLE_TERMINATION_PCT"))
+	if err != nil || chaosIdleTermination < 0 || chaosIdleTermination > 100 {
+		return Config{}, fmt.Errorf("invalid CHAOS_IDLE_TERMINATION_PCT: %q is not between 0 and 100", src.Get("CHAOS_IDLE_TERMINATION_PCT"))
+	}
+	chaosSpotInterruption, err := strconv.Atoi(src.Get("CHAOS_SPOT_INTERRUPT_PCT"))
+	if err != nil || chaosSpotInterruption < 0 || chaosSpotInterruption > 100 {
+		return Config{}, fmt.Errorf("invalid CHAOS_SPOT_INTERRUPT_PCT: %q is not between 0 and 100", src.Get("CHAOS_SPOT_INTERRUPT_PCT"))
+	}
+
+	metricsEMF, err := strconv.ParseBool(src.Get("METRICS_EMF"))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid METRICS_EMF: %w", err)
+	}
+
+	var spotPrices map[string]string
+	if prices := src.Get("EC2_SPOT_PRICES"); prices != "" {
+		if err := json.Unmarshal([]byte(prices), &spotPrices); err != nil {
+			return Config{}, fmt.Errorf("invalid EC2_SPOT_PRICES JSON: %w", err)
+		}
+	}
+	// EC2_SPOT_PRICE predates per-type ceilings and still sets the default ceiling
+	if price := src.Get("EC2_SPOT_PRICE"); price != "" {
+		if _, ok := spotPrices[ec2launch.DefaultSpotPriceKey]; !ok {
+			if spotPrices == nil {
+				spotPrices = make(map[string]string)
+			}
+			spotPrices[ec2launch.DefaultSpotPriceKey] = price
+		}
+	}
+	if err := ec2launch.ValidateSpotPrices(spotPrices); err != nil {
+		return Config{}, fmt.Errorf("invalid EC2_SPOT_PRICES: %w", err)
+	}
+
+	var ec2Tags map[string]string
+	if tags := src.Get("EC2_TAGS"); tags != "" {
+		if err := json.Unmarshal([]byte(tags), &ec2Tags); err != nil {
+			return Config{}, fmt.Errorf("invalid EC2_TAGS JSON: %w", err)
+		}
+	}
+
+	cleanupOffline, _ := strconv.ParseBool(src.Get("CLEANUP_OFFLINE_RUNNERS"))
 
 	var repositoryNames []string
-	if repoNames := os.Getenv("REPOSITORY_NAMES"); repoNames != "" {
+	if repoNames := src.Get("REPOSITORY_NAMES"); repoNames != "" {
 		if err := json.Unmarshal([]byte(repoNames), &repositoryNames); err != nil {
 			return Config{}, fmt.Errorf("invalid REPOSITORY_NAMES JSON: %w", err)
 		}
 	}
 
-	return Config{
-		GitHubToken:              os.Getenv("GITHUB_TOKEN"),
-		GitHubEnterpriseURL:      getEnvOrDefault("GITHUB_ENTERPRISE_URL", "https://TelenorSwedenAB.ghe.com"),
-		OrganizationName:         getEnvOrDefault("ORGANIZATION_NAME", "TelenorSweden"),
-		MinRunners:               minRunners,
-		MaxRunners:               maxRunners,
-		EC2InstanceType:          getEnvOrDefault("EC2_INSTANCE_TYPE", "t3.medium"),
-		EC2AMI:                   os.Getenv("EC2_AMI_ID"),
-		EC2SubnetID:              os.Getenv("EC2_SUBNET_ID"),
-		EC2SecurityGroupID:       os.Getenv("EC2_SECURITY_GROUP_ID"),
-		EC2KeyPairName:           os.Getenv("EC2_KEY_PAIR_NAME"),
-		EC2SpotPrice:             getEnvOrDefault("EC2_SPOT_PRICE", "0.05"),
-		DynamoDBTableName:        getEnvOrDefault("DYNAMODB_TABLE_NAME", "github-runners"),
-		RunnerLabels:             runnerLabels,
-		CleanupOfflineRunners:    cleanupOffline,
-		RepositoryNames:          repositoryNames,
-	}, nil
-}
+	gitHubTokenSecretARN := src.Get("GITHUB_TOKEN_SECRET_ARN")
+	if err := validateSecretARN(gitHubTokenSecretARN); err != nil {
+		return Config{}, fmt.Errorf("invalid GITHUB_TOKEN_SECRET_ARN: %w", err)
+	}
+	gitHubTokenSecretTTL, err := time.ParseDuration(src.Get("GITHUB_TOKEN_SECRET_TTL"))
+	if err != nil || gitHubTokenSecretTTL < 0 {
+		return Config{}, fmt.Errorf("invalid GITHUB_TOKEN_SECRET_TTL: %q", src.Get("GITHUB_TOKEN_SECRET_TTL"))
+	}
+	gitHubApp, err := parseGitHubApp(src)
+	if err != nil {
+		return Config{}, err
+	}
 
-func getEnvOrDefault(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+	sessionMaxAge, err := time.ParseDuration(src.Get("SESSION_MAX_AGE"))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid SESSION_MAX_AGE: %w", err)
 	}
-	return defaultValue
-}
 
-// Create Spot Instance for GitHub Runner
-func (aws *AWSInfrastructure) CreateSpotInstance(ctx context.Context, jobID int64, labels []string) (*string, error) {
-	// Generate user data script for runner installation
-	userData := aws.generateUserDataScriptForJob(jobID, labels)
-	
-	// Base64 encode the user data script (required by AWS)
-	userDataEncoded := base64.StdEncoding.EncodeToString([]byte(userData))
+	sessionRecordRetention, err := time.ParseDuration(src.Get("SESSION_RECORD_RETENTION"))
+	if err != nil || sessionRecordRetention <= 0 {
+		return Config{}, fmt.Errorf("invalid SESSION_RECORD_RETENTION: %q", src.Get("SESSION_RECORD_RETENTION"))
+	}
 
-	// Spot instance request specification
-	spotPrice := aws.config.EC2SpotPrice
-	launchSpec := &ec2types.RequestSpotLaunchSpecification{
-		ImageId:          aws.String(aws.config.EC2AMI),
-		InstanceType:     ec2types.InstanceType(aws.config.EC2InstanceType),
-		KeyName:          aws.String(aws.config.EC2KeyPairName),
-		SecurityGroupIds: []string{aws.config.EC2SecurityGroupID},
-		SubnetId:         aws.String(aws.config.EC2SubnetID),
-		UserData:         aws.String(userDataEncoded),
-		Monitoring: &ec2types.RunInstancesMonitoringEnabled{
-			Enabled: aws.Bool(true),
-		},
+	runnerRecordRetention, err := time.ParseDuration(src.Get("RUNNER_RECORD_RETENTION"))
+	if err != nil || runnerRecordRetention <= 0 {
+		return Config{}, fmt.Errorf("invalid RUNNER_RECORD_RETENTION: %q", src.Get("RUNNER_RECORD_RETENTION"))
 	}
 
-	// Create spot instance request
-	input := &ec2.RequestSpotInstancesInput{
-		SpotPrice:           aws.String(spotPrice),
-		InstanceCount:       aws.Int32(1),
-		Type:                ec2types.SpotInstanceTypeOneTime,
-		LaunchSpecification: launchSpec,
-		TagSpecifications: []ec2types.TagSpecification{
-			{
-				ResourceType: ec2types.ResourceTypeSpotInstancesRequest,
-				Tags: []ec2types.Tag{
-					{Key: aws.String("Name"), Value: aws.String(fmt.Sprintf("github-runner-job-%d", jobID))},
-					{Key: aws.String("Purpose"), Value: aws.String("github-actions-runner")},
-					{Key: aws.String("JobID"), Value: aws.String(strconv.FormatInt(jobID, 10))},
-					{Key: aws.String("ManagedBy"), Value: aws.String("github-runner-scaler-lambda")},
-				},
-			},
-		},
+	lockLease, err := time.ParseDuration(src.Get("LOCK_LEASE"))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid LOCK_LEASE: %w", err)
+	}
+
+	selfScheduling, err := strconv.ParseBool(src.Get("SELF_SCHEDULING"))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid SELF_SCHEDULING: %w", err)
 	}
 
-	result, err := aws.ec2Client.RequestSpotInstances(ctx, input)
+	scheduleFastInterval, err := time.ParseDuration(src.Get("SCHEDULE_FAST_INTERVAL"))
 	if err != nil {
-		return nil, fmt.Errorf("failed to request spot instance: %w", err)
+		return Config{}, fmt.Errorf("invalid SCHEDULE_FAST_INTERVAL: %w", err)
 	}
 
-	if len(result.SpotInstanceRequests) == 0 {
-		return nil, fmt.Errorf("no spot instance requests created")
+	scheduleIdleInterval, err := time.ParseDuration(src.Get("SCHEDULE_IDLE_INTERVAL"))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid SCHEDULE_IDLE_INTERVAL: %w", err)
 	}
 
-	spotRequestID := result.SpotInstanceRequests[0].SpotInstanceRequestId
-	log.Printf("Created spot instance request: %s for job %d", *spotRequestID, jobID)
+	scaleDownDelay, err := time.ParseDuration(src.Get("SCALE_DOWN_DELAY"))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid SCALE_DOWN_DELAY: %w", err)
+	}
 
-	// Store runner record in DynamoDB
-	if err := aws.storeRunnerRecord(ctx, RunnerRecord{
-		RunnerID:      fmt.Sprintf("runner-%d-%d", jobID, time.Now().Unix()),
-		JobRequestID:  jobID,
-		Status:        "pending",
-		CreatedAt:     time.Now(),
-		UpdatedAt:     time.Now(),
-		SpotRequestID: *spotRequestID,
-	}); err != nil {
-		log.Printf("Failed to store runner record: %v", err)
+	runnerNameTemplate := src.Get("RUNNER_NAME_TEMPLATE")
+	if err := runnername.ValidateTemplate(runnerNameTemplate); err != nil {
+		return Config{}, fmt.Errorf("invalid RUNNER_NAME_TEMPLATE: %w", err)
 	}
 
-	return spotRequestID, nil
-}
+	interruptionReportWindow, err := time.ParseDuration(src.Get("INTERRUPTION_REPORT_WINDOW"))
+	if err != nil || interruptionReportWindow <= 0 {
+		return Config{}, fmt.Errorf("invalid INTERRUPTION_REPORT_WINDOW: %q", src.Get("INTERRUPTION_REPORT_WINDOW"))
+	}
 
-// CreateSpotInstanceForPipeline creates a spot instance specifically for pipeline execution
-func (aws *AWSInfrastructure) CreateSpotInstanceForPipeline(ctx context.Context, runnerName, registrationToken string, labels []string) (*string, error) {
-	// Generate user data script for runner installation
-	userData := aws.generateUserDataScriptWithToken(runnerName, registrationToken, labels)
-	
-	// Base64 encode the user data script (required by AWS)
-	userDataEncoded := base64.StdEncoding.EncodeToString([]byte(userData))
+	interruptionJobThreshold, err := strconv.Atoi(src.Get("INTERRUPTION_JOB_THRESHOLD"))
+	if err != nil || interruptionJobThreshold < 1 {
+		return Config{}, fmt.Errorf("invalid INTERRUPTION_JOB_THRESHOLD: %q is not a positive number", src.Get("INTERRUPTION_JOB_THRESHOLD"))
+	}
 
-	// Spot instance request specification
-	spotPrice := aws.config.EC2SpotPrice
-	launchSpec := &ec2types.RequestSpotLaunchSpecification{
-		ImageId:          aws.String(aws.config.EC2AMI),
-		InstanceType:     ec2types.InstanceType(aws.config.EC2InstanceType),
-		KeyName:          aws.String(aws.config.EC2KeyPairName),
-		SecurityGroupIds: []string{aws.config.EC2SecurityGroupID},
-		SubnetId:         aws.String(aws.config.EC2SubnetID),
-		UserData:         aws.String(userDataEncoded),
-		Monitoring: &ec2types.RunInstancesMonitoringEnabled{
-			Enabled: aws.Bool(true),
-		},
+	replaceInterruptedRunners, err := strconv.ParseBool(src.Get("REPLACE_INTERRUPTED_RUNNERS"))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid REPLACE_INTERRUPTED_RUNNERS: %w", err)
 	}
 
-	// Create spot instance request
-	input := &ec2.RequestSpotInstancesInput{
-		SpotPrice:           aws.String(spotPrice),
-		InstanceCount:       aws.Int32(1),
-		Type:                ec2types.SpotInstanceTypeOneTime,
-		LaunchSpecification: launchSpec,
-		TagSpecifications: []ec2types.TagSpecification{
-			{
-				ResourceType: ec2types.ResourceTypeSpotInstancesRequest,
-				Tags: []ec2types.Tag{
-					{Key: aws.String("Name"), Value: aws.String(runnerName)},
-					{Key: aws.String("Purpose"), Value: aws.String("github-actions-runner")},
-					{Key: aws.String("RunnerName"), Value: aws.String(runnerName)},
-					{Key: aws.String("ManagedBy"), Value: aws.String("github-runner-scaler-lambda")},
-					{Key: aws.String("CreatedAt"), Value: aws.String(time.Now().Format(time.RFC3339))},
-				},
+	if err := validateLogSettings(src.Get("LOG_LEVEL"), src.Get("LOG_FORMAT")); err != nil {
+		return Config{}, err
+	}
+
+	var pools []PoolConfig
+	if rawPools := src.Get("SCALE_POOLS"); rawPools != "" {
+		pools, err = parsePools(rawPools)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid SCALE_POOLS JSON: %w", err)
+		}
+	}
+
+	config := Config{
+		GitHubToken:          src.Get("GITHUB_TOKEN"),
+		GitHubTokenSecretARN: gitHubTokenSecretARN,
+		GitHubTokenSecretTTL: gitHubTokenSecretTTL,
+
+		GitHubAppID:                  gitHubApp.id,
+		GitHubAppInstallationID:      gitHubApp.installationID,
+		GitHubAppPrivateKeySecretARN: gitHubApp.privateKeySecretARN,
+		GitHubEnterpriseURL:          src.Get("GITHUB_ENTERPRISE_URL"),
+		OrganizationName:             src.Get("ORGANIZATION_NAME"),
+		MinRunners:                   minRunners,
+		MaxRunners:                   maxRunners,
+		EC2InstanceType:              src.Get("EC2_INSTANCE_TYPE"),
+		EC2AMI:                       src.Get("EC2_AMI_ID"),
+		EC2SubnetID:                  src.Get("EC2_SUBNET_ID"),
+		EC2SecurityGroupIDs:          securityGroupIDs,
+		EC2KeyPairName:               src.Get("EC2_KEY_PAIR_NAME"),
+		EC2SpotPrices:                spotPrices,
+		EC2InstanceTypes:             instanceTypes,
+		EC2SubnetIDs:                 subnetIDs,
+		OnDemandPercentage:           onDemandPercentage,
+		OnDemandOnly:                 onDemandOnly,
+		SpotAllocationStrategy:       spotAllocationStrategy,
+		SpotInterruptionBehavior:     spotInterruptionBehavior,
+		EC2Tenancy:                   tenancy,
+		EC2PlacementGroup:            src.Get("EC2_PLACEMENT_GROUP"),
+		EC2IPv6AddressCount:          ipv6AddressCount,
+		EC2AssociatePublicIP:         associatePublicIP,
+		PrivateBootstrap:             privateBootstrap,
+		RunnerTarballS3URI:           runnerTarballS3URI,
+		RunnerTokenParameterPath:     runnerTokenParameterPath,
+		Architectures: map[string]architectureProfile{
+			architectureX64: {
+				InstanceType: src.Get("EC2_INSTANCE_TYPE"),
+				TarballS3URI: runnerTarballS3URI,
+			},
+			architectureARM64: {
+				AMI:          src.Get("EC2_AMI_ID_ARM64"),
+				InstanceType: src.Get("EC2_INSTANCE_TYPE_ARM64"),
+				TarballS3URI: runnerTarballS3URIARM64,
 			},
 		},
+		EC2AMIWindows:             src.Get("EC2_AMI_ID_WINDOWS"),
+		RecycleStaleRunners:       recycleStaleRunners,
+		GenerationDrainBatch:      generationDrainBatch,
+		ProbeWorkflow:             probeWorkflow,
+		RequireProbedAMI:          requireProbedAMI,
+		EC2InstanceProfile:        src.Get("EC2_INSTANCE_PROFILE"),
+		EC2LaunchTemplateID:       src.Get("EC2_LAUNCH_TEMPLATE_ID"),
+		EC2LaunchTemplateVersion:  src.Get("EC2_LAUNCH_TEMPLATE_VERSION"),
+		DiagnosticsS3URI:          diagnosticsS3URI,
+		RegistrationTimeout:       registrationTimeout,
+		OrphanMaxAge:              orphanMaxAge,
+		SpotRequestTimeout:        spotRequestTimeout,
+		SpotRequestFallback:       spotRequestFallback,
+		IdleTimeout:               idleTimeout,
+		DebugHoldHours:            debugHoldHours,
+		DebugHoldLabels:           debugHoldLabels,
+		WorkspaceCleanup:          workspaceCleanup,
+		WorkspaceCleanupScript:    src.Get("WORKSPACE_CLEANUP_SCRIPT"),
+		PrewarmImages:             prewarmImages,
+		ActionsCacheProxyURL:      src.Get("ACTIONS_CACHE_PROXY_URL"),
+		ToolcacheEFSID:            src.Get("TOOLCACHE_EFS_ID"),
+		ToolcacheSnapshotID:       src.Get("TOOLCACHE_SNAPSHOT_ID"),
+		BreakglassMaxTTL:          breakglassMaxTTL,
+		ChaosMode:                 chaosMode,
+		ChaosIdleTermination:      chaosIdleTermination,
+		ChaosSpotInterruption:     chaosSpotInterruption,
+		EC2Tags:                   ec2Tags,
+		DynamoDBTableName:         src.Get("DYNAMODB_TABLE_NAME"),
+		RunnerRecordRetention:     runnerRecordRetention,
+		RunnerLabels:              runnerLabels,
+		ExcludedLabels:            excludedLabels,
+		CleanupOfflineRunners:     cleanupOffline,
+		RepositoryNames:           repositoryNames,
+		RunnerScaleSetName:        src.Get("RUNNER_SCALE_SET_NAME"),
+		SessionsTableName:         src.Get("SESSIONS_TABLE_NAME"),
+		SessionMaxAge:             sessionMaxAge,
+		SessionRecordRetention:    sessionRecordRetention,
+		LockTableName:             src.Get("LOCK_TABLE_NAME"),
+		RolloutsTableName:         src.Get("ROLLOUTS_TABLE_NAME"),
+		LockLease:                 lockLease,
+		WebhookSecret:             src.Get("WEBHOOK_SECRET"),
+		Pools:                     pools,
+		SelfScheduling:            selfScheduling,
+		ScheduleRuleName:          src.Get("SCHEDULE_RULE_NAME"),
+		ScheduleFastInterval:      scheduleFastInterval,
+		ScheduleIdleInterval:      scheduleIdleInterval,
+		ScaleDownDelay:            scaleDownDelay,
+		RunnerNamePrefix:          src.Get("RUNNER_NAME_PREFIX"),
+		RunnerNameTemplate:        runnerNameTemplate,
+		AppConfigApplication:      src.Get("APPCONFIG_APPLICATION"),
+		AppConfigEnvironment:      src.Get("APPCONFIG_ENVIRONMENT"),
+		AppConfigProfile:          src.Get("APPCONFIG_PROFILE"),
+		AppConfigAgentURL:         src.Get("APPCONFIG_AGENT_URL"),
+		MetricsEMF:                metricsEMF,
+		MetricsNamespace:          src.Get("METRICS_NAMESPACE"),
+		LogLevel:                  src.Get("LOG_LEVEL"),
+		LogFormat:                 src.Get("LOG_FORMAT"),
+		PushgatewayURL:            src.Get("PUSHGATEWAY_URL"),
+		InterruptionsTableName:    src.Get("INTERRUPTIONS_TABLE_NAME"),
+		InterruptionReportWindow:  interruptionReportWindow,
+		InterruptionJobThreshold:  interruptionJobThreshold,
+		ReplaceInterruptedRunners: replaceInterruptedRunners,
 	}
 
-	result, err := aws.ec2Client.RequestSpotInstances(ctx, input)
-	if err != nil {
-		return nil, fmt.Errorf("failed to request spot instance: %w", err)
+	// EC2_AMI_ID and EC2_INSTANCE_TYPE describe RUNNER_ARCHITECTURE, the _ARM64 settings
+	// Graviton pools of an x64 scaler. x64 pools of an arm64 scaler set their own.
+	if runnerArchitecture == architectureARM64 {
+		config.Architectures = map[string]architectureProfile{
+			architectureX64: {TarballS3URI: runnerTarballS3URI},
+			architectureARM64: {
+				AMI:          config.EC2AMI,
+				InstanceType: config.EC2InstanceType,
+				TarballS3URI: runnerTarballS3URIARM64,
+			},
+		}
 	}
-
-	if len(result.SpotInstanceRequests) == 0 {
-		return nil, fmt.Errorf("no spot instance requests created")
+	config = config.forArchitecture(runnerArchitecture).forRunnerOS(runnerOS)
+	if err := config.checkArchitecture(); err != nil {
+		return Config{}, fmt.Errorf("invalid RUNNER_ARCHITECTURE: %w", err)
 	}
+	if err := config.checkRunnerOS(); err != nil {
+		return Config{}, fmt.Errorf("invalid RUNNER_OS: %w", err)
+	}
+	if err := config.checkPoolPlatforms(); err != nil {
+		return Config{}, fmt.Errorf("invalid SCALE_POOLS: %w", err)
+	}
+	return config, nil
+}
 
-	spotRequestID := result.SpotInstanceRequests[0].SpotInstanceRequestId
-	log.Printf("Created spot instance request: %s for runner %s", *spotRequestID, runnerName)
+// Create Spot Instance for GitHub Runner
+func (aws *AWSInfrastructure) CreateSpotInstance(ctx context.Context, jobID int64, labels []string) (*string, error) {
+	// Generate user data script for runner installation
+	userData := aws.generateUserDataScriptForJob(jobID, labels)
+
+	// Base64 encode the user data script (required by AWS)
+	userDataEncoded := base64.StdEncoding.EncodeToString([]byte(userData))
+
+	runnerName := fmt.Sprintf("github-runner-job-%d", jobID)
+	instanceID, err := aws.launchInstance(ctx, runnerName, userDataEncoded, launchTarget{
+		InstanceType: aws.config.EC2InstanceType,
+		SubnetID:     aws.config.EC2SubnetID,
+		OnDemand:     aws.config.OnDemandOnly,
+	}, []ec2types.Tag{
+		{Key: aws.String("Name"), Value: aws.String(runnerName)},
+		{Key: aws.String("Purpose"), Value: aws.String("github-actions-runner")},
+		{Key: aws.String("JobID"), Value: aws.String(strconv.FormatInt(jobID, 10))},
+		{Key: aws.String("ManagedBy"), Value: aws.String("github-runner-scaler-lambda")},
+	})
+	if err != nil {
+		return nil, err
+	}
 
 	// Store runner record in DynamoDB
 	if err := aws.storeRunnerRecord(ctx, RunnerRecord{
-		RunnerID:      runnerName,
-		Status:        "pending",
-		CreatedAt:     time.Now(),
-		UpdatedAt:     time.Now(),
-		SpotRequestID: *spotRequestID,
+		RunnerID:     fmt.Sprintf("runner-%d-%d", jobID, time.Now().Unix()),
+		InstanceID:   *instanceID,
+		JobRequestID: jobID,
+		Status:       "pending",
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
 	}); err != nil {
-		log.Printf("Failed to store runner record: %v", err)
+		loggerFrom(ctx).Error(err, "Failed to store runner record", "instanceId", *instanceID)
 	}
 
-	return spotRequestID, nil
+	return instanceID, nil
 }
 
 // Generate user data script for EC2 instance for a specific job (legacy method)
@@ -293,7 +739,9 @@ echo "Runner: %s, Labels: %s, Job: %d"
 
 // Generate user data script for EC2 instance with registration token
 func (aws *AWSInfrastructure) generateUserDataScriptWithToken(runnerName, registrationToken string, labels []string) string {
-	labelsStr := "self-hosted,linux,x64"
+	// Patterns and expressions only take part in matching, the runner registers plain labels
+	labels = literalLabels(labels)
+	labelsStr := aws.config.defaultRunnerLabels()
 	if len(labels) > 0 {
 		labelsStr = ""
 		for i, label := range labels {
@@ -303,36 +751,57 @@ func (aws *AWSInfrastructure) generateUserDataScriptWithToken(runnerName, regist
 			labelsStr += label
 		}
 	}
+	if aws.noDefaultLabels {
+		labelsStr += " --no-default-labels"
+	}
+	if aws.config.RunnerOS == runnerOSWindows {
+		return aws.generateWindowsUserData(runnerName, registrationToken, labelsStr)
+	}
 
 	script := fmt.Sprintf(`#!/bin/bash
 set -e
 
-# Update system
-apt-get update -y
-apt-get install -y curl jq unzip awscli
-
+# Instance metadata is reachable over IPv4 link-local, or only over IPv6 on IPv6-only subnets
+IMDS=http://169.254.169.254
+if ! curl -s --connect-timeout 2 -o /dev/null $IMDS/latest/meta-data/; then
+    IMDS="http://[fd00:ec2::254]"
+fi
+# Dual-stack AWS endpoints let the CLI work without IPv4 egress
+if ip -6 route show default | grep -q default; then
+    export AWS_USE_DUALSTACK_ENDPOINT=true
+fi
+REGION=$(curl -s $IMDS/latest/meta-data/placement/region)
+
+%s
+%s
+%s
+%s
 # Create runner user
 useradd -m -s /bin/bash runner
 usermod -aG sudo runner
 echo 'runner ALL=(ALL) NOPASSWD:ALL' >> /etc/sudoers
 
+%s
 # Switch to runner user and setup runner
-sudo -u runner bash << 'EOF'
+sudo -u runner env REGION=$REGION AWS_USE_DUALSTACK_ENDPOINT=$AWS_USE_DUALSTACK_ENDPOINT bash << 'EOF'
 cd /home/runner
 
-# Download and install GitHub Actions runner
-curl -o actions-runner-linux-x64-2.311.0.tar.gz -L https://github.com/actions/runner/releases/download/v2.311.0/actions-runner-linux-x64-2.311.0.tar.gz
-tar xzf ./actions-runner-linux-x64-2.311.0.tar.gz
-
+%s
+%s
 # Configure runner for GHE
 ./config.sh --url %s/orgs/%s --token %s --name %s --labels %s --work _work --replace --ephemeral
 
-# Start runner
-./run.sh &
+%s%s
+# Start runner, keeping its exit code for the diagnostics check
+(./run.sh; echo $? > /home/runner/.run-exit-code) &
 EOF
 
+# config.sh writes .runner once the runner is registered
+if [ ! -f /home/runner/.runner ] && [ -x /usr/local/bin/upload-runner-diagnostics ]; then
+    /usr/local/bin/upload-runner-diagnostics registration-failed
+fi
+
 # Signal completion
-REGION=$(curl -s http://169.254.169.254/latest/meta-data/placement/region)
 aws logs create-log-group --log-group-name "/aws/ec2/github-runner" --region $REGION || true
 aws logs create-log-stream --log-group-name "/aws/ec2/github-runner" --log-stream-name "%s" --region $REGION || true
 aws logs put-log-events --log-group-name "/aws/ec2/github-runner" --log-stream-name "%s" --log-events timestamp=$(date +%%s000),message="Runner %s started successfully" --region $REGION || true
@@ -342,76 +811,49 @@ while pgrep -f "Runner.Listener" > /dev/null; do
     sleep 30
 done
 
+# A runner that exits with an error did not finish its job normally
+RUN_EXIT_CODE=$(cat /home/runner/.run-exit-code 2> /dev/null || echo unknown)
+if [ -f /home/runner/.runner ] && [ "$RUN_EXIT_CODE" != "0" ] && [ -x /usr/local/bin/upload-runner-diagnostics ]; then
+    /usr/local/bin/upload-runner-diagnostics runner-exit-$RUN_EXIT_CODE
+fi
+
+%s
+%s
 # Self-terminate when runner job is done
-aws ec2 terminate-instances --instance-ids $(curl -s http://169.254.169.254/latest/meta-data/instance-id) --region $REGION || true
+aws ec2 terminate-instances --instance-ids $(curl -s $IMDS/latest/meta-data/instance-id) --region $REGION || true
 `,
+		aws.config.packageInstallScript(),
+		aws.config.diagnosticsScript(),
+		aws.config.workspaceCleanupScript(),
+		aws.config.imagePrewarmScript(),
+		aws.config.toolcacheScript(),
+		aws.config.runnerDownloadScript(),
+		aws.config.runnerTokenScript(runnerName),
 		aws.config.GitHubEnterpriseURL,
 		aws.config.OrganizationName,
-		registrationToken,
+		aws.config.runnerTokenArg(registrationToken),
 		runnerName,
 		labelsStr,
+		aws.config.workspaceCleanupHookEnv(),
+		aws.config.runnerCacheEnv(),
+		runnerName,
 		runnerName,
 		runnerName,
-		runnerName)
+		aws.config.debugHoldScript(),
+		aws.config.workspaceCleanupReleaseScript())
 
 	return script
 }
 
-// TerminateRunnerInstance terminates EC2 instance by runner name
-func (aws *AWSInfrastructure) TerminateRunnerInstance(ctx context.Context, runnerName string) error {
-	// Find instance by tag
-	input := &ec2.DescribeInstancesInput{
-		Filters: []ec2types.Filter{
-			{
-				Name:   aws.String("tag:RunnerName"),
-				Values: []string{runnerName},
-			},
-			{
-				Name:   aws.String("instance-state-name"),
-				Values: []string{"running", "pending"},
-			},
-		},
-	}
-
-	result, err := aws.ec2Client.DescribeInstances(ctx, input)
-	if err != nil {
-		return fmt.Errorf("failed to describe instances: %w", err)
-	}
-
-	var instanceIDs []string
-	for _, reservation := range result.Reservations {
-		for _, instance := range reservation.Instances {
-			instanceIDs = append(instanceIDs, *instance.InstanceId)
-		}
-	}
-
-	if len(instanceIDs) == 0 {
-		log.Printf("No instances found for runner: %s", runnerName)
-		return nil
-	}
-
-	// Terminate instances
-	terminateInput := &ec2.TerminateInstancesInput{
-		InstanceIds: instanceIDs,
-	}
-
-	_, err = aws.ec2Client.TerminateInstances(ctx, terminateInput)
-	if err != nil {
-		return fmt.Errorf("failed to terminate instances: %w", err)
-	}
-
-	log.Printf("Terminated %d instances for runner: %s", len(instanceIDs), runnerName)
-	return nil
-}
-
 // Store runner record in DynamoDB
 func (aws *AWSInfrastructure) storeRunnerRecord(ctx context.Context, record RunnerRecord) error {
 	item := map[string]types.AttributeValue{
-		"runner_id":        &types.AttributeValueMemberS{Value: record.RunnerID},
-		"job_request_id":   &types.AttributeValueMemberN{Value: strconv.FormatInt(record.JobRequestID, 10)},
-		"status":           &types.AttributeValueMemberS{Value: record.Status},
-		"created_at":       &types.AttributeValueMemberS{Value: record.CreatedAt.Format(time.RFC3339)},
-		"updated_at":       &types.AttributeValueMemberS{Value: record.UpdatedAt.Format(time.RFC3339)},
+		"runner_id":      &types.AttributeValueMemberS{Value: record.RunnerID},
+		"job_request_id": &types.AttributeValueMemberN{Value: strconv.FormatInt(record.JobRequestID, 10)},
+		"status":         &types.AttributeValueMemberS{Value: record.Status},
+		"created_at":     &types.AttributeValueMemberS{Value: record.CreatedAt.Format(time.RFC3339)},
+		"updated_at":     &types.AttributeValueMemberS{Value: record.UpdatedAt.Format(time.RFC3339)},
+		"expires_at":     aws.runnerRecordExpiry(record.UpdatedAt),
 	}
 
 	if record.InstanceID != "" {
@@ -421,12 +863,23 @@ func (aws *AWSInfrastructure) storeRunnerRecord(ctx context.Context, record Runn
 		item["spot_request_id"] = &types.AttributeValueMemberS{Value: record.SpotRequestID}
 	}
 
-	_, err := aws.dynamoDBClient.PutItem(ctx, &dynamodb.PutItemInput{
-		TableName: aws.String(aws.config.DynamoDBTableName),
-		Item:      item,
+	return aws.writeRunnerRecord(ctx, record.RunnerID, record.Status, func(v recordVersion) error {
+		item["version"] = v.Next
+		_, err := aws.dynamoDBClient.PutItem(ctx, &dynamodb.PutItemInput{
+			TableName:                 aws.String(aws.config.DynamoDBTableName),
+			Item:                      item,
+			ConditionExpression:       aws.String(v.Condition),
+			ExpressionAttributeNames:  map[string]string{"#version": "version"},
+			ExpressionAttributeValues: v.Values,
+		})
+		return err
 	})
+}
 
-	return err
+// runnerRecordExpiry is the TTL attribute of a runner record written at the given time.
+// Records only outlive their runner by RUNNER_RECORD_RETENTION; no runner lives that long.
+func (aws *AWSInfrastructure) runnerRecordExpiry(written time.Time) types.AttributeValue {
+	return &types.AttributeValueMemberN{Value: strconv.FormatInt(written.Add(aws.config.RunnerRecordRetention).Unix(), 10)}
 }
 
 // Helper functions
@@ -442,70 +895,334 @@ func (aws *AWSInfrastructure) Bool(b bool) *bool {
 	return &b
 }
 
-// Main Lambda handler
-func Handler(ctx context.Context, event events.CloudWatchEvent) error {
-	log.Printf("🚀 GitHub Runner Scaler Lambda triggered at %s", time.Now().Format(time.RFC3339))
+func (aws *AWSInfrastructure) Time(t time.Time) *time.Time {
+	return &t
+}
+
+func (aws *AWSInfrastructure) Float64(f float64) *float64 {
+	return &f
+}
+
+func (aws *AWSInfrastructure) ToString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// Main Lambda handler. It accepts any event and routes CloudWatch schedules, API Gateway
+// webhook deliveries and manual invokes to the matching flow.
+func Handler(ctx context.Context, raw json.RawMessage) (result interface{}, err error) {
+	startedAt := time.Now()
 
 	// Load configuration
 	config, err := LoadConfig()
 	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	// Every line of this invocation carries its request ID, so Logs Insights can group them
+	invocationLog, err := invocationLogger(ctx, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure logging: %w", err)
+	}
+	ctx = withLogger(ctx, invocationLog)
+	logger := invocationLog
+	logger.Info("GitHub Runner Scaler Lambda triggered", "startedAt", startedAt.Format(time.RFC3339))
+
+	// Apply the scaling policy from AppConfig on top of the environment, keeping the
+	// environment values when the policy cannot be loaded
+	if config.AppConfigApplication != "" {
+		policy, err := loadScalingPolicy(ctx, config)
+		if err == nil {
+			config, err = config.withScalingPolicy(policy)
+		}
+		if err != nil {
+			logger.Error(err, "Ignoring AppConfig scaling policy")
+		} else {
+			logger.Info("Scaling policy from AppConfig", "minRunners", config.MinRunners, "maxRunners", config.MaxRunners, "pools", len(config.Pools))
+		}
 	}
 
 	// Initialize AWS infrastructure
 	awsInfra, err := NewAWSInfrastructure(ctx, config)
 	if err != nil {
-		return fmt.Errorf("failed to initialize AWS infrastructure: %w", err)
+		return nil, fmt.Errorf("failed to initialize AWS infrastructure: %w", err)
 	}
+	defer func() {
+		awsInfra.metrics.Count(metricInvocations, "", 1)
+		if err != nil {
+			logger.Error(err, "Invocation failed")
+			awsInfra.metrics.Count(metricInvocationErrors, "", 1)
+		}
+		awsInfra.metrics.Duration(metricInvocationDuration, "", time.Since(startedAt))
+		awsInfra.metrics.Flush(context.WithoutCancel(ctx))
+	}()
+
+	// The GitHub token may come from Secrets Manager, read once per GITHUB_TOKEN_SECRET_TTL
+	if config.GitHubToken, err = awsInfra.gitHubToken(ctx); err != nil {
+		return nil, err
+	}
+	awsInfra.config.GitHubToken = config.GitHubToken
 
 	// Initialize GitHub Enterprise client
 	gheClient := NewGHEClient(config)
 
+	evaluate := func(ctx context.Context) error {
+		return withInvocationLock(ctx, awsInfra, config, func() error {
+			queuedJobs, err := runScalingCycle(ctx, gheClient, awsInfra, config)
+			if err := revokeExpiredBreakglass(ctx, awsInfra); err != nil {
+				logger.Error(err, "Failed to check breakglass access")
+			}
+			if config.SelfScheduling {
+				scheduler := NewSelfScheduler(awsInfra.eventsClient, awsInfra.lambdaClient, config)
+				if err := scheduler.ScheduleNextExecution(ctx, queuedJobs); err != nil {
+					logger.Error(err, "Failed to update schedule")
+				}
+			}
+			return err
+		})
+	}
+
+	kind := detectInvocation(raw)
+	logger.Info("Routing invocation", "invocationType", kind)
+
+	switch kind {
+	case invocationSchedule:
+		return nil, evaluate(ctx)
+	case invocationWebhook:
+		return handleWebhookInvocation(ctx, raw, evaluate, config), nil
+	case invocationSpotInterruption:
+		return nil, handleSpotInterruption(ctx, raw, gheClient, awsInfra, config)
+	default:
+		var manual ManualInvocation
+		if err := json.Unmarshal(raw, &manual); err != nil {
+			return nil, fmt.Errorf("invalid manual invocation payload: %w", err)
+		}
+
+		switch manual.Action {
+		case "", manualActionEvaluate:
+			return nil, evaluate(ctx)
+		case manualActionScale:
+			logger.Info("Manual scale requested", "runners", manual.Runners)
+			return nil, withInvocationLock(ctx, awsInfra, config, func() error {
+				return executeCRDBasedScaling(ctx, &JobCount{NecessaryReplicas: manual.Runners}, gheClient, awsInfra, config)
+			})
+		case manualActionProbeAMI:
+			probeConfig := config
+			if manual.Pool != "" {
+				pool, ok := findPool(config.Pools, manual.Pool)
+				if !ok {
+					return nil, fmt.Errorf("unknown pool %q in AMI probe", manual.Pool)
+				}
+				probeConfig = config.forPool(pool)
+			}
+			logger.Info("AMI probe requested", "ami", manual.AMI)
+			return nil, runAMIProbe(ctx, NewGHEClient(probeConfig), awsInfra, probeConfig, manual.AMI)
+		case manualActionBreakglass:
+			return nil, runBreakglass(ctx, awsInfra, config, manual)
+		case manualActionInterruptionReport:
+			return runInterruptionReport(ctx, gheClient, awsInfra, config)
+		case manualActionAMICanary, manualActionAMIPromote, manualActionAMIRollback:
+			return nil, runAMIRollout(ctx, awsInfra, config, manual)
+		case manualActionBreakglassRevoke:
+			if strings.HasPrefix(manual.Rule, breakglassRulePrefix) {
+				defer deleteOneShotRule(context.WithoutCancel(ctx), awsInfra, manual.Rule)
+			}
+			reason := manual.Reason
+			if reason == "" {
+				reason = "ttl ended"
+			}
+			return nil, awsInfra.revokeBreakglass(ctx, manual.InstanceID, reason)
+		case manualActionScaleDownCheck:
+			checkConfig := config
+			if manual.Pool != "" {
+				pool, ok := findPool(config.Pools, manual.Pool)
+				if !ok {
+					return nil, fmt.Errorf("unknown pool %q in scale-down check", manual.Pool)
+				}
+				checkConfig = config.forPool(pool)
+			}
+			if strings.HasPrefix(manual.Rule, scaleDownRulePrefix) {
+				defer deleteOneShotRule(context.WithoutCancel(ctx), awsInfra, manual.Rule)
+			}
+			logger.Info("Scale-down check requested", "runnerNames", manual.RunnerNames)
+			return nil, withScaleDownLock(ctx, awsInfra, config, func() error {
+				return runScaleDownCheck(ctx, NewGHEClient(checkConfig), awsInfra, checkConfig, manual)
+			})
+		default:
+			return nil, fmt.Errorf("unknown manual action %q", manual.Action)
+		}
+	}
+}
+
+// withInvocationLock runs fn while holding the invocation lock. Only one invocation may
+// scale at a time, so an overlapping run exits early without error.
+func withInvocationLock(ctx context.Context, awsInfra *AWSInfrastructure, config Config, fn func() error) error {
+	err := runLocked(ctx, awsInfra, config, fn)
+	if err == errLockHeld {
+		loggerFrom(ctx).Info("Another invocation is still running, skipping this one")
+		return nil
+	}
+	return err
+}
+
+// runLocked runs fn while holding the invocation lock, or returns errLockHeld
+func runLocked(ctx context.Context, awsInfra *AWSInfrastructure, config Config, fn func() error) error {
+	lock := NewInvocationLock(awsInfra.dynamoDBClient, config.LockTableName, "scaler", invocationID(ctx), config.LockLease)
+	if err := lock.Acquire(ctx); err != nil {
+		return err
+	}
+	defer func() {
+		if err := lock.Release(context.WithoutCancel(ctx)); err != nil {
+			loggerFrom(ctx).Error(err, "Failed to release the invocation lock")
+		}
+	}()
+
+	return fn()
+}
+
+// runScalingCycle evaluates job demand and launches the runners needed. It returns the
+// number of queued jobs seen so the schedule can follow demand.
+func runScalingCycle(ctx context.Context, gheClient *GHEClient, awsInfra *AWSInfrastructure, config Config) (int, error) {
+	if len(config.Pools) > 0 {
+		return runPools(ctx, awsInfra, config)
+	}
+	logger := loggerFrom(ctx)
+
+	// Keep the scale set's message session alive across invocations when one is configured
+	if config.RunnerScaleSetName != "" {
+		if err := reportScaleSetStatistics(ctx, gheClient, awsInfra, config); err != nil {
+			logger.Error(err, "Failed to resume scale set session")
+		}
+	}
+
 	// Use CRD-style job analysis (following actions-runner-controller pattern)
-	log.Printf("🎯 Using CRD-style job demand analysis...")
+	logger.V(1).Info("Using CRD-style job demand analysis")
 	crdAnalyzer := NewCRDStyleJobAnalyzer(gheClient, config)
-	
+
+	method := "CRD-style analysis"
 	jobCount, err := crdAnalyzer.AnalyzeJobDemand(ctx)
 	if err != nil {
-		log.Printf("❌ CRD-style analysis failed, falling back to legacy method: %v", err)
-		
-		// Fallback to original pipeline monitor
+		logger.Error(err, "CRD-style analysis failed, falling back to workflow run scanning")
+
+		// Fallback to the pipeline monitor's run-level demand, scaled through the same path
 		monitor := NewPipelineMonitor(gheClient, awsInfra, config)
-		if err := monitor.MonitorAndScale(ctx); err != nil {
-			log.Printf("❌ Fallback pipeline monitoring also failed: %v", err)
-			return err
+		jobCount, err = monitor.JobDemand(ctx)
+		if err != nil {
+			logger.Error(err, "Fallback pipeline monitoring also failed")
+			return 0, err
+		}
+		method = "fallback workflow run scanning"
+
+		if config.CleanupOfflineRunners {
+			if err := monitor.CleanupOfflineRunners(ctx, nil); err != nil {
+				logger.Error(err, "Failed to clean up offline runners")
+			}
 		}
-		
-		log.Printf("✅ Lambda execution completed successfully using fallback method")
-		return nil
 	}
-	
-	// Execute scaling based on CRD-style analysis
+
+	// Execute scaling based on the measured demand
 	if err := executeCRDBasedScaling(ctx, jobCount, gheClient, awsInfra, config); err != nil {
-		log.Printf("❌ CRD-based scaling failed: %v", err)
+		logger.Error(err, "CRD-based scaling failed")
+		return jobCount.Queued, err
+	}
+
+	if err := reconcileStaleRunners(ctx, gheClient, awsInfra, config); err != nil {
+		logger.Error(err, "Failed to check for stale runners")
+	}
+
+	if err := drainOldGenerations(ctx, gheClient, awsInfra, config); err != nil {
+		logger.Error(err, "Failed to drain old-generation runners")
+	}
+
+	if err := reconcileUnregisteredRunners(ctx, gheClient, awsInfra, config); err != nil {
+		logger.Error(err, "Failed to check for unregistered runners")
+	}
+
+	if err := collectOrphanedRunners(ctx, gheClient, awsInfra, config); err != nil {
+		logger.Error(err, "Failed to collect orphaned runners")
+	}
+
+	if err := cancelStaleSpotRequests(ctx, gheClient, awsInfra, config); err != nil {
+		logger.Error(err, "Failed to cancel stale spot requests")
+	}
+
+	if err := scaleDownIdleRunners(ctx, gheClient, awsInfra, config); err != nil {
+		logger.Error(err, "Failed to scale down idle runners")
+	}
+
+	if err := expireDebugHolds(ctx, awsInfra, config); err != nil {
+		logger.Error(err, "Failed to check debug holds")
+	}
+
+	if err := injectChaos(ctx, gheClient, awsInfra, config); err != nil {
+		logger.Error(err, "Failed to inject chaos")
+	}
+
+	logger.Info("Lambda execution completed successfully", "method", method)
+	return jobCount.Queued, nil
+}
+
+// reportScaleSetStatistics resumes (or creates) the scale set's message session and logs its statistics
+func reportScaleSetStatistics(ctx context.Context, gheClient *GHEClient, awsInfra *AWSInfrastructure, config Config) error {
+	actionsClient := NewActionsServiceClient(gheClient, config)
+	if err := actionsClient.Connect(ctx); err != nil {
+		return err
+	}
+
+	scaleSet, err := actionsClient.GetScaleSetByName(ctx, config.RunnerScaleSetName)
+	if err != nil {
 		return err
 	}
+	logger := loggerFrom(ctx).WithValues("scaleSetId", scaleSet.ID)
 
-	log.Printf("✅ Lambda execution completed successfully using CRD-style analysis")
+	sessions := NewSessionManager(actionsClient, NewSessionStore(awsInfra.dynamoDBClient, config.SessionsTableName, config.SessionRecordRetention), config)
+	session, err := sessions.GetSession(ctx, scaleSet)
+	if err != nil {
+		return err
+	}
+
+	if stats := session.Statistics; stats != nil {
+		logger.Info("Scale set statistics", "availableJobs", stats.TotalAvailableJobs,
+			"assignedJobs", stats.TotalAssignedJobs, "runningJobs", stats.TotalRunningJobs,
+			"registeredRunners", stats.TotalRegisteredRunners, "busyRunners", stats.TotalBusyRunners,
+			"idleRunners", stats.TotalIdleRunners)
+	}
 	return nil
 }
 
+// invocationID identifies this invocation as the lock owner
+func invocationID(ctx context.Context) string {
+	if lc, ok := lambdacontext.FromContext(ctx); ok {
+		return lc.AwsRequestID
+	}
+	return fmt.Sprintf("local-%d", time.Now().UnixNano())
+}
+
 // executeCRDBasedScaling implements scaling based on CRD-style job analysis
 func executeCRDBasedScaling(ctx context.Context, jobCount *JobCount, gheClient *GHEClient, awsInfra *AWSInfrastructure, config Config) error {
-	log.Printf("🎯 Executing CRD-based scaling logic...")
-	log.Printf("📊 Job Analysis: NecessaryReplicas=%d, Queued=%d, InProgress=%d", 
-		jobCount.NecessaryReplicas, jobCount.Queued, jobCount.InProgress)
-	
+	scaleLogger := loggerFrom(ctx)
+	scaleLogger.Info("Job analysis", "necessaryReplicas", jobCount.NecessaryReplicas,
+		"queuedJobs", jobCount.Queued, "inProgressJobs", jobCount.InProgress, "maxQueueWait", jobCount.MaxQueueWait.String())
+
 	// Get current runners to determine scaling need
 	runners, err := gheClient.GetSelfHostedRunners(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to get current runners: %w", err)
 	}
-	
-	// Count current active runners
+
+	// Count current active runners (only the pool's own runners when evaluating a pool)
 	activeRunners := 0
 	idleRunners := 0
 	for _, runner := range runners.Runners {
+		if config.PoolName != "" && !runnerHasLabels(runner, config.RunnerLabels) {
+			continue
+		}
+		// Draining runners take no new jobs
+		if runnerHasLabels(runner, []string{drainLabel}) {
+			continue
+		}
 		if runner.Status == "online" {
 			activeRunners++
 			if !runner.Busy {
@@ -513,130 +1230,244 @@ func executeCRDBasedScaling(ctx context.Context, jobCount *JobCount, gheClient *
 			}
 		}
 	}
-	
-	log.Printf("📊 Current Runners: Active=%d, Idle=%d, Busy=%d", 
-		activeRunners, idleRunners, activeRunners-idleRunners)
-	
+
+	scaleLogger.Info("Current runners", "activeRunners", activeRunners, "idleRunners", idleRunners,
+		"busyRunners", activeRunners-idleRunners)
+
 	// Calculate how many new runners we need (following ARC logic)
-	// We need enough runners to handle queued + in_progress jobs
-	runnersNeeded := jobCount.NecessaryReplicas - activeRunners
-	
+	// We need enough runners to handle queued + in_progress jobs, and never fewer than the minimum
+	desiredRunners := jobCount.NecessaryReplicas
+	if desiredRunners < config.MinRunners {
+		desiredRunners = config.MinRunners
+	}
+	runnersNeeded := desiredRunners - activeRunners
+
 	// Apply max runners constraint
-	if activeRunners + runnersNeeded > config.MaxRunners {
+	if activeRunners+runnersNeeded > config.MaxRunners {
 		runnersNeeded = config.MaxRunners - activeRunners
 		if runnersNeeded < 0 {
 			runnersNeeded = 0
 		}
 	}
-	
+
 	// Apply min runners constraint
 	if runnersNeeded < 0 && activeRunners > config.MinRunners {
 		// We have too many runners but still respect min runners
 		// Note: We don't implement scale-down in this Lambda (that would be done by the runner lifecycle)
 		runnersNeeded = 0
 	}
-	
-	log.Printf("🎯 Scaling Decision: Need %d new runners (necessary=%d, current=%d, max=%d)", 
-		runnersNeeded, jobCount.NecessaryReplicas, activeRunners, config.MaxRunners)
-	
+
+	awsInfra.metrics.Gauge(metricQueuedJobs, config.PoolName, float64(jobCount.Queued))
+	awsInfra.metrics.Duration(metricQueueWaitTime, config.PoolName, jobCount.MaxQueueWait)
+	awsInfra.metrics.Gauge(metricInProgressJobs, config.PoolName, float64(jobCount.InProgress))
+	awsInfra.metrics.Gauge(metricCurrentRunners, config.PoolName, float64(activeRunners))
+	awsInfra.metrics.Gauge(metricIdleRunners, config.PoolName, float64(idleRunners))
+	awsInfra.metrics.Gauge(metricDesiredRunners, config.PoolName, float64(activeRunners+runnersNeeded))
+
+	scaleLogger.Info("Scaling decision", "runnersNeeded", runnersNeeded, "necessaryReplicas", jobCount.NecessaryReplicas,
+		"activeRunners", activeRunners, "maxRunners", config.MaxRunners)
+
 	if runnersNeeded <= 0 {
-		log.Printf("✅ No new runners needed - current capacity is sufficient")
 		return nil
 	}
-	
+
+	provider := awsInfra.runnerProvider()
+	if available, err := provider.GetCapacity(ctx); err != nil {
+		scaleLogger.Error(err, "Failed to get runner provider capacity")
+	} else if available != unlimitedCapacity && runnersNeeded > available {
+		scaleLogger.Info("Runner provider is short of capacity", "available", available, "runnersNeeded", runnersNeeded)
+		runnersNeeded = available
+	}
+
+	// Runners also register the concrete labels that jobs matched through a pattern
+	launchLabels := append(literalLabels(config.RunnerLabels), jobCount.MatchedLabels...)
+
 	// Create the needed runners
 	successCount := 0
+	var created []string
+	existing := runners.Runners
 	for i := 0; i < runnersNeeded; i++ {
-		runnerName := fmt.Sprintf("arc-lambda-runner-%d-%d", time.Now().Unix(), i+1)
-		
+		runnerName, err := availableRunnerName(ctx, gheClient, config, existing)
+		if err != nil {
+			scaleLogger.Error(err, "Failed to name runner", "runner", i+1)
+			continue
+		}
+		// Reserve the name so later runners in this batch cannot collide with it
+		existing = append(existing, SelfHostedRunner{Name: runnerName, Status: "online"})
+
 		// Get registration token
 		token, err := gheClient.GetRegistrationToken(ctx)
 		if err != nil {
-			log.Printf("❌ Failed to get registration token for runner %d: %v", i+1, err)
+			scaleLogger.Error(err, "Failed to get registration token", "runner", i+1, "runnerName", runnerName)
 			continue
 		}
-		
-		// Create spot instance with token
-		spotRequestID, err := awsInfra.CreateSpotInstanceForPipeline(ctx, runnerName, token.Token, config.RunnerLabels)
+
+		// Launch the runner on the provider with the token
+		instanceID, err := provider.CreateRunner(ctx, RunnerSpec{Name: runnerName, RegistrationToken: token.Token, Labels: launchLabels})
 		if err != nil {
-			log.Printf("❌ Failed to create runner %d: %v", i+1, err)
+			scaleLogger.Error(err, "Failed to create runner", "runner", i+1, "runnerName", runnerName)
 			continue
 		}
-		
-		log.Printf("✅ Created runner %d: %s (spot request: %s)", i+1, runnerName, *spotRequestID)
+
+		scaleLogger.Info("Created runner", "runner", i+1, "runnerName", runnerName, "instanceId", instanceID)
 		successCount++
+		created = append(created, runnerName)
 	}
-	
-	log.Printf("🎯 Scaling Result: Successfully created %d/%d requested runners", successCount, runnersNeeded)
-	
+
+	scaleLogger.Info("Scaling result", "runnersCreated", successCount, "runnersNeeded", runnersNeeded)
+	awsInfra.metrics.Count(metricRunnersLaunched, config.PoolName, float64(successCount))
+	awsInfra.metrics.Count(metricLaunchFailures, config.PoolName, float64(runnersNeeded-successCount))
+
 	if successCount == 0 && runnersNeeded > 0 {
 		return fmt.Errorf("failed to create any of the %d needed runners", runnersNeeded)
 	}
-	
+
+	if err := scheduleScaleDownCheck(ctx, awsInfra, config, created); err != nil {
+		scaleLogger.Error(err, "Failed to schedule scale-down check")
+	}
+
 	return nil
 }
 
+// runnerHasLabels reports whether a runner carries every one of the given labels
+func runnerHasLabels(runner SelfHostedRunner, labels []string) bool {
+	names := make([]string, 0, len(runner.Labels))
+	for _, label := range runner.Labels {
+		names = append(names, label.Name)
+	}
+	return NewLabelMatcher(names, LabelMatchOptions{}).Matches(literalLabels(labels))
+}
+
+// newRunnerName names a runner from the configured template, so it is identifiable in GitHub and EC2
+func (c Config) newRunnerName() string {
+	return runnername.Format(c.RunnerNameTemplate, runnername.Fields{
+		Prefix:   c.RunnerNamePrefix,
+		ScaleSet: c.RunnerScaleSetName,
+		Pool:     c.PoolName,
+	})
+}
+
+// runnerNameAttempts bounds how often a colliding runner name is regenerated
+const runnerNameAttempts = 5
+
+// availableRunnerName generates a runner name that no registration in existing uses. An
+// offline registration with the generated name is removed first, because config.sh --replace
+// would otherwise silently take it over; a name held by an online runner is regenerated.
+func availableRunnerName(ctx context.Context, gheClient *GHEClient, config Config, existing []SelfHostedRunner) (string, error) {
+	for attempt := 1; attempt <= runnerNameAttempts; attempt++ {
+		name := config.newRunnerName()
+
+		var taken *SelfHostedRunner
+		for i := range existing {
+			if strings.EqualFold(existing[i].Name, name) {
+				taken = &existing[i]
+				break
+			}
+		}
+		if taken == nil {
+			return name, nil
+		}
+
+		if taken.Status != "offline" {
+			loggerFrom(ctx).Info("Runner name is used by an online runner, generating another", "runnerName", name, "attempt", attempt)
+			continue
+		}
+
+		loggerFrom(ctx).Info("Removing stale offline registration before reusing its name", "runnerName", taken.Name, "runnerId", taken.ID)
+		if err := gheClient.RemoveRunner(ctx, taken.ID); err != nil {
+			loggerFrom(ctx).Error(err, "Failed to remove stale runner, generating another name", "runnerName", taken.Name)
+			continue
+		}
+		return name, nil
+	}
+	return "", fmt.Errorf("no free runner name after %d attempts with template %q", runnerNameAttempts, config.RunnerNameTemplate)
+}
+
 // executeRunnerScaling contains the main logic for checking jobs and scaling runners (legacy)
 func executeRunnerScaling(ctx context.Context, awsInfra *AWSInfrastructure, config Config) error {
-	log.Printf("Checking for queued GitHub Actions workflows")
+	loggerFrom(ctx).Info("Checking for queued GitHub Actions workflows")
 
 	// Create GHE client for pipeline monitoring
 	gheClient := NewGHEClient(config)
-	
+
 	// Create pipeline monitor
 	monitor := NewPipelineMonitor(gheClient, awsInfra, config)
-	
+
 	// Check for pending pipelines and scale accordingly
 	return monitor.MonitorAndScale(ctx)
 }
 
-
-
 // maintainMinRunners ensures we have at least the minimum number of runners
 func (aws *AWSInfrastructure) maintainMinRunners(ctx context.Context, minRunners int) error {
 	if minRunners <= 0 {
 		return nil
 	}
 
-	// Get current running runners count from DynamoDB
+	// Without a count every cycle would launch the full minimum again, so skip instead
 	currentRunners, err := aws.getCurrentRunnerCount(ctx)
 	if err != nil {
-		log.Printf("Failed to get current runner count: %v", err)
-		currentRunners = 0
+		return fmt.Errorf("failed to get current runner count: %w", err)
 	}
 
 	needed := minRunners - currentRunners
 	if needed <= 0 {
-		log.Printf("Have %d runners, minimum is %d - no action needed", currentRunners, minRunners)
+		loggerFrom(ctx).V(1).Info("Minimum runners are running", "currentRunners", currentRunners, "minRunners", minRunners)
 		return nil
 	}
 
-	log.Printf("Need to create %d additional runners to maintain minimum", needed)
+	loggerFrom(ctx).Info("Creating runners to maintain the minimum", "needed", needed)
 
 	// Create the needed minimum runners
 	for i := 0; i < needed; i++ {
 		jobID := time.Now().UnixNano() // Use timestamp as unique job ID
 		_, err := aws.CreateSpotInstance(ctx, jobID, aws.config.RunnerLabels)
 		if err != nil {
-			log.Printf("Failed to create minimum runner %d: %v", i+1, err)
+			loggerFrom(ctx).Error(err, "Failed to create minimum runner", "runner", i+1)
 		}
 	}
 
 	return nil
 }
 
-// getCurrentRunnerCount gets the number of currently active runners
+// getCurrentRunnerCount counts the pool's pending and running runners. EC2 is the source
+// of truth and the runner records refine it: an instance counts unless its record already
+// marks the runner completed, failed or interrupted, and records of instances that are gone
+// are ignored. Instances without a record, whose record write failed at launch, still count.
 func (aws *AWSInfrastructure) getCurrentRunnerCount(ctx context.Context) (int, error) {
-	// Query DynamoDB for active runners
-	// For simplicity, we'll return 0 for now
-	return 0, nil
-}
-
-
-
+	instances, err := aws.runnerProvider().ListRunners(ctx)
+	if err != nil {
+		return 0, err
+	}
 
+	records, err := aws.scanRunnerRecords(ctx)
+	if err != nil {
+		return 0, err
+	}
+	statusByInstance := make(map[string]string, len(records))
+	for _, record := range records {
+		statusByInstance[record.InstanceID] = record.Status
+	}
 
+	count := 0
+	for _, instance := range instances {
+		switch status, ok := statusByInstance[instance.ID]; {
+		case !ok, status == "pending", status == "running":
+			count++
+		default:
+			loggerFrom(ctx).V(1).Info("Not counting instance whose record is finished", "instanceId", instance.ID, "runnerName", instance.Name, "status", status)
+		}
+	}
+	return count, nil
+}
 
 func main() {
+	// Lambda starts the binary without arguments; with arguments it runs the CLI commands
+	if len(os.Args) > 1 {
+		if os.Args[1] == "export" {
+			os.Exit(runExportCommand(os.Args[2:]))
+		}
+		os.Exit(runConfigCommand(os.Args[1:]))
+	}
+
 	lambda.Start(Handler)
-} 
\ No newline at end of file
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// Metric names recorded per invocation. A Lambda has no long-lived process to scrape, so
+// the values are written out at the end of each invocation instead.
+const (
+	metricInvocations           = "Invocations"
+	metricInvocationErrors      = "InvocationErrors"
+	metricInvocationDuration    = "InvocationDuration"
+	metricQueuedJobs            = "QueuedJobs"
+	metricInProgressJobs        = "InProgressJobs"
+	metricCurrentRunners        = "CurrentRunners"
+	metricIdleRunners           = "IdleRunners"
+	metricDesiredRunners        = "DesiredRunners"
+	metricRunnersLaunched       = "RunnersLaunched"
+	metricLaunchFailures        = "LaunchFailures"
+	metricQueueWaitTime         = "QueueWaitTime"
+	metricOrphansTerminated     = "OrphansTerminated"
+	metricIdleRunnersTerminated = "IdleRunnersTerminated"
+
+	// Spot interruptions, from interruption warnings and the periodic report
+	metricSpotInterruptions       = "SpotInterruptions"
+	metricInterruptedJobs         = "InterruptedJobs"
+	metricInterruptedJobsInWindow = "InterruptedJobsInReportWindow"
+	metricRerunLatency            = "InterruptedJobRerunLatency"
+	metricReplacementRunners      = "ReplacementRunners"
+	metricSpotRequestTimeouts     = "SpotRequestTimeouts"
+)
+
+const (
+	unitCount        = "Count"
+	unitMilliseconds = "Milliseconds"
+)
+
+// metricsJobName is the Pushgateway job the metrics are grouped under
+const metricsJobName = "github-runner-scaler"
+
+type metricKey struct {
+	Name string
+	Pool string
+}
+
+type metricValue struct {
+	Unit  string
+	Value float64
+}
+
+// metricsRecorder collects the metrics of one invocation, per pool. Flush writes them as
+// CloudWatch Embedded Metric Format log lines, which CloudWatch turns into metrics without
+// any API calls, and pushes them to a Prometheus Pushgateway when one is configured.
+type metricsRecorder struct {
+	namespace      string
+	emf            bool
+	pushgatewayURL string
+	out            io.Writer
+
+	mu     sync.Mutex
+	values map[metricKey]*metricValue
+}
+
+func newMetricsRecorder(config Config) *metricsRecorder {
+	return &metricsRecorder{
+		namespace:      config.MetricsNamespace,
+		emf:            config.MetricsEMF,
+		pushgatewayURL: strings.TrimSuffix(config.PushgatewayURL, "/"),
+		out:            os.Stdout,
+		values:         make(map[metricKey]*metricValue),
+	}
+}
+
+// Gauge sets a metric of a pool to its latest value
+func (m *metricsRecorder) Gauge(name, pool string, value float64) {
+	m.record(name, pool, unitCount, value, true)
+}
+
+// Count adds to a metric of a pool
+func (m *metricsRecorder) Count(name, pool string, value float64) {
+	m.record(name, pool, unitCount, value, false)
+}
+
+// Duration sets a timing metric of a pool in milliseconds
+func (m *metricsRecorder) Duration(name, pool string, d time.Duration) {
+	m.record(name, pool, unitMilliseconds, float64(d.Milliseconds()), true)
+}
+
+func (m *metricsRecorder) record(name, pool, unit string, value float64, gauge bool) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := metricKey{Name: name, Pool: metricPool(pool)}
+	if existing, ok := m.values[key]; ok && !gauge {
+		existing.Value += value
+		return
+	}
+	m.values[key] = &metricValue{Unit: unit, Value: value}
+}
+
+// Flush writes the recorded metrics and clears them
+func (m *metricsRecorder) Flush(ctx context.Context) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	values := m.values
+	m.values = make(map[metricKey]*metricValue)
+	m.mu.Unlock()
+	if len(values) == 0 {
+		return
+	}
+
+	if m.emf {
+		m.writeEMF(ctx, values)
+	}
+	if m.pushgatewayURL != "" {
+		if err := m.push(ctx, values); err != nil {
+			loggerFrom(ctx).Error(err, "Failed to push metrics", "pushgatewayUrl", m.pushgatewayURL)
+		}
+	}
+}
+
+// writeEMF writes one EMF document per pool. CloudWatch only extracts metrics from log
+// lines that are a bare JSON object, so these bypass the log package and its prefix.
+// Each metric is also published without dimensions, so one alarm covers every pool.
+func (m *metricsRecorder) writeEMF(ctx context.Context, values map[metricKey]*metricValue) {
+	byPool := make(map[string]map[string]*metricValue)
+	for key, value := range values {
+		if byPool[key.Pool] == nil {
+			byPool[key.Pool] = make(map[string]*metricValue)
+		}
+		byPool[key.Pool][key.Name] = value
+	}
+
+	timestamp := time.Now().UnixMilli()
+	for pool, metrics := range byPool {
+		type emfMetric struct {
+			Name string
+			Unit string
+		}
+		definitions := make([]emfMetric, 0, len(metrics))
+		document := map[string]interface{}{"Pool": pool}
+		for name, value := range metrics {
+			definitions = append(definitions, emfMetric{Name: name, Unit: value.Unit})
+			document[name] = value.Value
+		}
+		sort.Slice(definitions, func(i, j int) bool { return definitions[i].Name < definitions[j].Name })
+		document["_aws"] = map[string]interface{}{
+			"Timestamp": timestamp,
+			"CloudWatchMetrics": []interface{}{map[string]interface{}{
+				"Namespace":  m.namespace,
+				"Dimensions": [][]string{{"Pool"}, {}}, // per pool, and summed over all pools
+				"Metrics":    definitions,
+			}},
+		}
+
+		line, err := json.Marshal(document)
+		if err != nil {
+			loggerFrom(ctx).Error(err, "Failed to encode metrics", "pool", pool)
+			continue
+		}
+		fmt.Fprintln(m.out, string(line))
+	}
+}
+
+// push replaces this job's metric group on the Pushgateway in the Prometheus text format.
+// Every push carries one invocation's values, so all metrics are exposed as gauges.
+func (m *metricsRecorder) push(ctx context.Context, values map[metricKey]*metricValue) error {
+	keys := make([]metricKey, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Name != keys[j].Name {
+			return keys[i].Name < keys[j].Name
+		}
+		return keys[i].Pool < keys[j].Pool
+	})
+
+	var body bytes.Buffer
+	typed := make(map[string]bool)
+	for _, key := range keys {
+		value := values[key]
+		name := prometheusMetricName(key.Name, value.Unit)
+		if !typed[name] {
+			fmt.Fprintf(&body, "# TYPE %s gauge\n", name)
+			typed[name] = true
+		}
+		fmt.Fprintf(&body, "%s{pool=%q} %g\n", name, key.Pool, value.Value)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, m.pushgatewayURL+"/metrics/job/"+metricsJobName, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// prometheusMetricName turns a metric name such as QueuedJobs into
+// github_runner_scaler_queued_jobs, with a _milliseconds suffix for timings
+func prometheusMetricName(name, unit string) string {
+	var b strings.Builder
+	b.WriteString("github_runner_scaler")
+	for i, r := range name {
+		if unicode.IsUpper(r) {
+			if i == 0 || !unicode.IsUpper(rune(name[i-1])) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	if unit == unitMilliseconds {
+		b.WriteString("_milliseconds")
+	}
+	return b.String()
+}
+
+// metricPool returns the Pool dimension value; the top-level configuration is "default"
+func metricPool(pool string) string {
+	if pool == "" {
+		return "default"
+	}
+	return pool
+}
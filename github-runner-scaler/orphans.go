@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// collectOrphanedRunners terminates this pool's instances that have been up longer than
+// ORPHAN_MAX_AGE without a live runner on them: runners that never registered after a failed
+// bootstrap, ephemeral runners whose job completed but whose instance did not shut down, and
+// runners that registered but have been offline since. It is the backstop behind
+// RUNNER_REGISTRATION_TIMEOUT, and does not wait for diagnostics. Busy runners and
+// instances in a debug hold are never touched. Nothing is terminated when the runner list
+// cannot be read in full.
+func collectOrphanedRunners(ctx context.Context, gheClient *GHEClient, awsInfra *AWSInfrastructure, config Config) error {
+	if config.OrphanMaxAge <= 0 {
+		return nil
+	}
+
+	registered, err := gheClient.GetSelfHostedRunners(ctx)
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]SelfHostedRunner, len(registered.Runners))
+	for _, runner := range registered.Runners {
+		byName[runner.Name] = runner
+	}
+
+	var orphans []launchedRunner
+	reasons := make(map[string]string)
+	paginator := ec2.NewDescribeInstancesPaginator(awsInfra.ec2Client, &ec2.DescribeInstancesInput{
+		Filters: []ec2types.Filter{
+			{Name: awsInfra.String("tag:ManagedBy"), Values: []string{"github-runner-scaler-lambda"}},
+			{Name: awsInfra.String("tag:Purpose"), Values: []string{"github-actions-runner"}},
+			{Name: awsInfra.String("instance-state-name"), Values: []string{"pending", "running"}},
+		},
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to describe runner instances: %w", err)
+		}
+		for _, reservation := range page.Reservations {
+			for _, instance := range reservation.Instances {
+				tags := tagValues(instance.Tags)
+				if tags["Pool"] != config.PoolName {
+					continue
+				}
+				if _, held := tags[debugHoldTag]; held {
+					continue
+				}
+				if instance.LaunchTime == nil || time.Since(*instance.LaunchTime) < config.OrphanMaxAge {
+					continue
+				}
+
+				reason := "not registered with GitHub"
+				if ghRunner, ok := byName[tags["RunnerName"]]; ok {
+					if ghRunner.Status == "online" || ghRunner.Busy {
+						continue
+					}
+					reason = "registered but offline"
+				}
+
+				runner := launchedRunner{RunnerName: tags["RunnerName"], InstanceID: *instance.InstanceId}
+				if instance.SpotInstanceRequestId != nil {
+					runner.SpotRequestID = *instance.SpotInstanceRequestId
+				}
+				orphans = append(orphans, runner)
+				reasons[runner.InstanceID] = fmt.Sprintf("%s, launched %s ago", reason, time.Since(*instance.LaunchTime).Round(time.Minute))
+			}
+		}
+	}
+
+	terminated := 0
+	for _, runner := range orphans {
+		// Check the runner on its own before terminating, so one missing from the list
+		// because it registered while the list was read is not taken for an orphan
+		ghRunner, err := gheClient.GetSelfHostedRunnerByName(ctx, runner.RunnerName)
+		if err != nil {
+			loggerFrom(ctx).Error(err, "Failed to check orphaned runner, not terminating it", "runnerName", runner.RunnerName)
+			continue
+		}
+		if ghRunner != nil && (ghRunner.Status == "online" || ghRunner.Busy) {
+			continue
+		}
+		if ghRunner != nil {
+			if err := gheClient.RemoveRunner(ctx, ghRunner.ID); err != nil {
+				loggerFrom(ctx).Error(err, "Failed to deregister orphaned runner", "runnerName", runner.RunnerName)
+				continue
+			}
+		}
+		if err := awsInfra.terminateLaunchedRunner(ctx, runner); err != nil {
+			loggerFrom(ctx).Error(err, "Failed to terminate orphaned runner", "runnerName", runner.RunnerName, "instanceId", runner.InstanceID)
+			continue
+		}
+		loggerFrom(ctx).Info("Terminated orphaned runner", "runnerName", runner.RunnerName, "instanceId", runner.InstanceID,
+			"reason", reasons[runner.InstanceID])
+		terminated++
+	}
+	awsInfra.metrics.Count(metricOrphansTerminated, config.PoolName, float64(terminated))
+	return nil
+}
@@ -3,8 +3,6 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
-	"time"
 )
 
 type PipelineMonitor struct {
@@ -32,7 +30,7 @@ func NewPipelineMonitor(gheClient *GHEClient, awsInfra *AWSInfrastructure, confi
 
 // CheckPendingPipelines checks for pending workflows and determines if runners are needed
 func (pm *PipelineMonitor) CheckPendingPipelines(ctx context.Context) (*PipelineStatus, error) {
-	log.Printf("🔍 Checking for pending pipelines...")
+	loggerFrom(ctx).V(1).Info("Checking for pending pipelines")
 
 	// Get queued workflows
 	allQueuedRuns, err := pm.gheClient.GetQueuedWorkflowRuns(ctx)
@@ -77,10 +75,9 @@ func (pm *PipelineMonitor) CheckPendingPipelines(ctx context.Context) (*Pipeline
 	// Analyze the situation
 	status := pm.analyzePipelineStatus(filteredQueuedRuns, filteredRunningRuns, runners)
 
-	log.Printf("📊 Pipeline Status: Total Queued=%d, Matching Queued=%d, Total Running=%d, Matching Running=%d, Available Runners=%d, Busy Runners=%d", 
-		allQueuedRuns.TotalCount, len(status.QueuedPipelines), 
-		allRunningRuns.TotalCount, len(status.RunningPipelines),
-		len(status.AvailableRunners), len(status.BusyRunners))
+	loggerFrom(ctx).Info("Pipeline status", "queued", allQueuedRuns.TotalCount, "matchingQueued", len(status.QueuedPipelines),
+		"running", allRunningRuns.TotalCount, "matchingRunning", len(status.RunningPipelines),
+		"availableRunners", len(status.AvailableRunners), "busyRunners", len(status.BusyRunners))
 
 	return status, nil
 }
@@ -88,16 +85,16 @@ func (pm *PipelineMonitor) CheckPendingPipelines(ctx context.Context) (*Pipeline
 // CreateRunnersForPendingPipelines creates runners for pending workflows
 func (pm *PipelineMonitor) CreateRunnersForPendingPipelines(ctx context.Context, status *PipelineStatus) error {
 	if status.RunnersNeeded <= 0 {
-		log.Printf("✅ No additional runners needed")
+		loggerFrom(ctx).Info("No additional runners needed")
 		return nil
 	}
 
 	if !status.CanCreateRunners {
-		log.Printf("⚠️  Cannot create more runners (already at max: %d)", pm.config.MaxRunners)
+		loggerFrom(ctx).Info("Cannot create more runners, already at the maximum", "maxRunners", pm.config.MaxRunners)
 		return nil
 	}
 
-	log.Printf("🚀 Creating %d new runners for pending pipelines", status.RunnersNeeded)
+	loggerFrom(ctx).Info("Creating runners for pending pipelines", "runners", status.RunnersNeeded)
 
 	// Get registration token
 	token, err := pm.gheClient.GetRegistrationToken(ctx)
@@ -105,30 +102,42 @@ func (pm *PipelineMonitor) CreateRunnersForPendingPipelines(ctx context.Context,
 		return fmt.Errorf("failed to get registration token: %w", err)
 	}
 
+	// Existing registrations, to keep new runner names from colliding with them
+	runners, err := pm.gheClient.GetSelfHostedRunners(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get current runners: %w", err)
+	}
+
 	// Create runners
 	successCount := 0
+	existing := runners.Runners
 	for i := 0; i < status.RunnersNeeded; i++ {
-		runnerName := fmt.Sprintf("lambda-runner-%d-%d", time.Now().Unix(), i)
+		runnerName, err := availableRunnerName(ctx, pm.gheClient, pm.config, existing)
+		if err != nil {
+			loggerFrom(ctx).Error(err, "Failed to name runner", "index", i+1)
+			continue
+		}
+		existing = append(existing, SelfHostedRunner{Name: runnerName, Status: "online"})
 		
-		// Create spot instance with runner setup
-		spotRequestID, err := pm.awsInfra.CreateSpotInstanceForPipeline(ctx, runnerName, token.Token, pm.config.RunnerLabels)
+		// Launch the runner on the provider
+		instanceID, err := pm.awsInfra.runnerProvider().CreateRunner(ctx, RunnerSpec{Name: runnerName, RegistrationToken: token.Token, Labels: pm.config.RunnerLabels})
 		if err != nil {
-			log.Printf("❌ Failed to create runner %d: %v", i+1, err)
+			loggerFrom(ctx).Error(err, "Failed to create runner", "index", i+1, "runnerName", runnerName)
 			continue
 		}
 
-		log.Printf("✅ Created runner %d/%d: %s (spot request: %s)", 
-			i+1, status.RunnersNeeded, runnerName, *spotRequestID)
+		loggerFrom(ctx).Info("Created runner", "index", i+1, "runners", status.RunnersNeeded,
+			"runnerName", runnerName, "instanceId", instanceID)
 		successCount++
 	}
 
-	log.Printf("🎯 Successfully created %d/%d runners", successCount, status.RunnersNeeded)
+	loggerFrom(ctx).Info("Created runners for pending pipelines", "created", successCount, "runners", status.RunnersNeeded)
 	return nil
 }
 
 // MonitorAndScale performs the complete monitoring and scaling cycle
 func (pm *PipelineMonitor) MonitorAndScale(ctx context.Context) error {
-	log.Printf("🔄 Starting pipeline monitoring cycle at %s", time.Now().Format(time.RFC3339))
+	loggerFrom(ctx).Info("Starting pipeline monitoring cycle")
 
 	// Check current pipeline status
 	status, err := pm.CheckPendingPipelines(ctx)
@@ -137,7 +146,7 @@ func (pm *PipelineMonitor) MonitorAndScale(ctx context.Context) error {
 	}
 
 	// Log detailed status
-	pm.logDetailedStatus(status)
+	pm.logDetailedStatus(ctx, status)
 
 	// Create runners if needed
 	if status.RunnersNeeded > 0 {
@@ -151,14 +160,34 @@ func (pm *PipelineMonitor) MonitorAndScale(ctx context.Context) error {
 	if pm.config.CleanupOfflineRunners {
 		err = pm.CleanupOfflineRunners(ctx, status)
 		if err != nil {
-			log.Printf("⚠️  Failed to cleanup offline runners: %v", err)
+			loggerFrom(ctx).Error(err, "Failed to clean up offline runners")
 		}
 	}
 
-	log.Printf("✅ Pipeline monitoring cycle completed")
+	loggerFrom(ctx).Info("Pipeline monitoring cycle completed")
 	return nil
 }
 
+// JobDemand estimates job demand from matching queued and running workflow runs. It is the
+// REST fallback when job-level analysis fails and feeds the same scaling path, counting one
+// runner per workflow run.
+func (pm *PipelineMonitor) JobDemand(ctx context.Context) (*JobCount, error) {
+	status, err := pm.CheckPendingPipelines(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check pending pipelines: %w", err)
+	}
+	pm.logDetailedStatus(ctx, status)
+
+	queued := len(status.QueuedPipelines)
+	running := len(status.RunningPipelines)
+	return &JobCount{
+		Total:             queued + running,
+		Queued:            queued,
+		InProgress:        running,
+		NecessaryReplicas: queued + running,
+	}, nil
+}
+
 // analyzePipelineStatus analyzes the current state and determines actions needed
 func (pm *PipelineMonitor) analyzePipelineStatus(queued, running *WorkflowRunsList, runners *SelfHostedRunnerList) *PipelineStatus {
 	status := &PipelineStatus{
@@ -205,39 +234,26 @@ func (pm *PipelineMonitor) analyzePipelineStatus(queued, running *WorkflowRunsLi
 }
 
 // logDetailedStatus logs detailed information about the current status
-func (pm *PipelineMonitor) logDetailedStatus(status *PipelineStatus) {
-	log.Printf("📋 Detailed Pipeline Status:")
-	
-	if len(status.QueuedPipelines) > 0 {
-		log.Printf("   ⏳ Queued Pipelines (%d):", len(status.QueuedPipelines))
-		for i, pipeline := range status.QueuedPipelines {
-			if i >= 3 { // Limit output
-				log.Printf("      ... and %d more", len(status.QueuedPipelines)-3)
-				break
-			}
-			log.Printf("      - ID: %d, Status: %s", pipeline.ID, pipeline.Status)
-		}
-	}
+func (pm *PipelineMonitor) logDetailedStatus(ctx context.Context, status *PipelineStatus) {
+	logger := loggerFrom(ctx).V(1)
 
-	if len(status.RunningPipelines) > 0 {
-		log.Printf("   🏃 Running Pipelines (%d):", len(status.RunningPipelines))
-		for i, pipeline := range status.RunningPipelines {
-			if i >= 3 {
-				log.Printf("      ... and %d more", len(status.RunningPipelines)-3)
-				break
-			}
-			log.Printf("      - ID: %d, Runner: %s", pipeline.ID, pipeline.RunnerName)
+	for i, pipeline := range status.QueuedPipelines {
+		if i >= 3 { // Limit output
+			logger.Info("More queued pipelines", "count", len(status.QueuedPipelines)-3)
+			break
 		}
+		logger.Info("Queued pipeline", "workflowId", pipeline.ID, "status", pipeline.Status)
 	}
-
-	log.Printf("   🤖 Runners - Available: %d, Busy: %d", 
-		len(status.AvailableRunners), len(status.BusyRunners))
-
-	if status.RunnersNeeded > 0 {
-		log.Printf("   🎯 Action: Need to create %d runners", status.RunnersNeeded)
-	} else {
-		log.Printf("   ✅ Action: No runners needed")
+	for i, pipeline := range status.RunningPipelines {
+		if i >= 3 {
+			logger.Info("More running pipelines", "count", len(status.RunningPipelines)-3)
+			break
+		}
+		logger.Info("Running pipeline", "workflowId", pipeline.ID, "runnerName", pipeline.RunnerName)
 	}
+	logger.Info("Detailed pipeline status", "queued", len(status.QueuedPipelines), "running", len(status.RunningPipelines),
+		"availableRunners", len(status.AvailableRunners), "busyRunners", len(status.BusyRunners),
+		"runnersNeeded", status.RunnersNeeded)
 }
 
 // CleanupOfflineRunners removes offline runners from GitHub and terminates EC2 instances
@@ -253,23 +269,23 @@ func (pm *PipelineMonitor) CleanupOfflineRunners(ctx context.Context, status *Pi
 			// Remove from GitHub
 			err := pm.gheClient.RemoveRunner(ctx, runner.ID)
 			if err != nil {
-				log.Printf("Failed to remove offline runner %s: %v", runner.Name, err)
+				loggerFrom(ctx).Error(err, "Failed to remove offline runner", "runnerName", runner.Name)
 				continue
 			}
 
 			// Find and terminate corresponding EC2 instance
-			err = pm.awsInfra.TerminateRunnerInstance(ctx, runner.Name)
+			err = pm.awsInfra.terminateNamedRunner(ctx, runner.Name)
 			if err != nil {
-				log.Printf("Failed to terminate instance for runner %s: %v", runner.Name, err)
+				loggerFrom(ctx).Error(err, "Failed to terminate instance of runner", "runnerName", runner.Name)
 			}
 
-			log.Printf("🧹 Cleaned up offline runner: %s", runner.Name)
+			loggerFrom(ctx).Info("Cleaned up offline runner", "runnerName", runner.Name)
 			cleanedCount++
 		}
 	}
 
 	if cleanedCount > 0 {
-		log.Printf("🧹 Cleaned up %d offline runners", cleanedCount)
+		loggerFrom(ctx).Info("Cleaned up offline runners", "runners", cleanedCount)
 	}
 
 	return nil
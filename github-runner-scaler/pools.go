@@ -0,0 +1,276 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Anshuman2121/actionsspot/internal/ec2launch"
+)
+
+// poolDeadlineMargin is kept free at the end of the invocation so results can be logged
+// and the invocation lock released before Lambda kills the execution
+const poolDeadlineMargin = 30 * time.Second
+
+// PoolConfig describes one scale set / label pool evaluated by the Lambda, including the
+// EC2 profile its runners are launched with. Zero values inherit the top-level configuration;
+// pool security groups, tags, spot price ceilings and pre-warmed images are added to the
+// top-level ones.
+type PoolConfig struct {
+	Name               string            `json:"name"`
+	Labels             []string          `json:"labels"`
+	MinRunners         *int              `json:"minRunners,omitempty"`
+	MaxRunners         *int              `json:"maxRunners,omitempty"`
+	InstanceType       string            `json:"instanceType,omitempty"`
+	InstanceTypes      []string          `json:"instanceTypes,omitempty"`
+	AMI                string            `json:"ami,omitempty"`
+	LaunchTemplateID   string            `json:"launchTemplateId,omitempty"`
+	LaunchTemplateVer  string            `json:"launchTemplateVersion,omitempty"`
+	SubnetIDs          []string          `json:"subnetIds,omitempty"`
+	SecurityGroupIDs   []string          `json:"securityGroupIds,omitempty"`
+	Tenancy            string            `json:"tenancy,omitempty"`
+	PlacementGroup     string            `json:"placementGroup,omitempty"`
+	SpotPrices         map[string]string `json:"spotPrices,omitempty"`
+	OnDemandPercentage *int              `json:"onDemandPercentage,omitempty"`
+	Tags               map[string]string `json:"tags,omitempty"`
+	DebugHoldHours     *int              `json:"debugHoldHours,omitempty"`
+	IdleTimeout        string            `json:"idleTimeout,omitempty"` // duration, e.g. "15m"; "0s" disables
+	PrewarmImages      []string          `json:"prewarmImages,omitempty"`
+	CacheProxyURL      string            `json:"actionsCacheProxyUrl,omitempty"`
+	ToolcacheEFSID     string            `json:"toolcacheEfsId,omitempty"`
+	ToolcacheSnapshot  string            `json:"toolcacheSnapshotId,omitempty"`
+	RunnerScaleSetName string            `json:"scaleSetName,omitempty"`
+	Architecture       string            `json:"architecture,omitempty"` // x64 or arm64
+	OS                 string            `json:"os,omitempty"`           // linux or windows
+}
+
+// parsePools parses the SCALE_POOLS JSON array
+func parsePools(raw string) ([]PoolConfig, error) {
+	var pools []PoolConfig
+	if err := json.Unmarshal([]byte(raw), &pools); err != nil {
+		return nil, err
+	}
+	if err := validatePools(pools); err != nil {
+		return nil, err
+	}
+	return pools, nil
+}
+
+// validatePools checks that every pool has a unique name and at least one label
+func validatePools(pools []PoolConfig) error {
+	seen := make(map[string]bool, len(pools))
+	for i, pool := range pools {
+		if pool.Name == "" {
+			return fmt.Errorf("pool %d has no name", i)
+		}
+		if seen[pool.Name] {
+			return fmt.Errorf("duplicate pool name %q", pool.Name)
+		}
+		if len(pool.Labels) == 0 {
+			return fmt.Errorf("pool %q has no labels", pool.Name)
+		}
+		if err := validateLabels(pool.Labels); err != nil {
+			return fmt.Errorf("pool %q: %w", pool.Name, err)
+		}
+		if err := ec2launch.ValidateSpotPrices(pool.SpotPrices); err != nil {
+			return fmt.Errorf("pool %q: %w", pool.Name, err)
+		}
+		if pool.Architecture != "" {
+			if err := validateArchitecture(pool.Architecture); err != nil {
+				return fmt.Errorf("pool %q: %w", pool.Name, err)
+			}
+		}
+		if pool.OS != "" {
+			if err := validateRunnerOS(pool.OS); err != nil {
+				return fmt.Errorf("pool %q: %w", pool.Name, err)
+			}
+		}
+		if pool.Tenancy != "" {
+			if err := validateTenancy(pool.Tenancy); err != nil {
+				return fmt.Errorf("pool %q: %w", pool.Name, err)
+			}
+		}
+		if h := pool.DebugHoldHours; h != nil && *h < 0 {
+			return fmt.Errorf("pool %q: debugHoldHours %d is negative", pool.Name, *h)
+		}
+		if pool.IdleTimeout != "" {
+			if d, err := time.ParseDuration(pool.IdleTimeout); err != nil || d < 0 {
+				return fmt.Errorf("pool %q: invalid idleTimeout %q", pool.Name, pool.IdleTimeout)
+			}
+		}
+		if p := pool.OnDemandPercentage; p != nil && (*p < 0 || *p > 100) {
+			return fmt.Errorf("pool %q: onDemandPercentage %d is not between 0 and 100", pool.Name, *p)
+		}
+		if err := validatePrewarmImages(pool.PrewarmImages); err != nil {
+			return fmt.Errorf("pool %q: %w", pool.Name, err)
+		}
+		if err := validateRunnerCaches(pool.CacheProxyURL, pool.ToolcacheEFSID, pool.ToolcacheSnapshot); err != nil {
+			return fmt.Errorf("pool %q: %w", pool.Name, err)
+		}
+		if err := ec2launch.ValidateLaunchTemplate(pool.LaunchTemplateID, pool.LaunchTemplateVer); err != nil {
+			return fmt.Errorf("pool %q: %w", pool.Name, err)
+		}
+		seen[pool.Name] = true
+	}
+	return nil
+}
+
+// forPool returns a copy of the configuration with the pool's overrides applied
+func (c Config) forPool(pool PoolConfig) Config {
+	poolConfig := c
+	if pool.Architecture != "" && pool.Architecture != c.RunnerArchitecture {
+		poolConfig = c.forArchitecture(pool.Architecture)
+	}
+	if pool.OS != "" && pool.OS != c.RunnerOS {
+		poolConfig = poolConfig.forRunnerOS(pool.OS)
+	}
+	poolConfig.PoolName = pool.Name
+	poolConfig.RunnerLabels = exclusiveLabel(architectureLabels(pool.Labels, poolConfig.RunnerArchitecture),
+		poolConfig.RunnerOS, runnerOSLinux, runnerOSWindows)
+	poolConfig.Pools = nil
+	if pool.MinRunners != nil {
+		poolConfig.MinRunners = *pool.MinRunners
+	}
+	if pool.MaxRunners != nil {
+		poolConfig.MaxRunners = *pool.MaxRunners
+	}
+	if pool.InstanceType != "" {
+		poolConfig.EC2InstanceType = pool.InstanceType
+		poolConfig.EC2InstanceTypes = nil
+	}
+	if len(pool.InstanceTypes) > 0 {
+		poolConfig.EC2InstanceTypes = pool.InstanceTypes
+	}
+	if pool.AMI != "" {
+		poolConfig.EC2AMI = pool.AMI
+	}
+	if pool.LaunchTemplateID != "" {
+		poolConfig.EC2LaunchTemplateID = pool.LaunchTemplateID
+		poolConfig.EC2LaunchTemplateVersion = pool.LaunchTemplateVer
+	}
+	if len(pool.SubnetIDs) > 0 {
+		poolConfig.EC2SubnetIDs = pool.SubnetIDs
+	}
+	if len(pool.SecurityGroupIDs) > 0 {
+		poolConfig.EC2SecurityGroupIDs = appendUniqueStrings(append([]string(nil), c.EC2SecurityGroupIDs...), pool.SecurityGroupIDs...)
+	}
+	if pool.Tenancy != "" {
+		poolConfig.EC2Tenancy = pool.Tenancy
+	}
+	if pool.PlacementGroup != "" {
+		poolConfig.EC2PlacementGroup = pool.PlacementGroup
+	}
+	if len(pool.SpotPrices) > 0 {
+		poolConfig.EC2SpotPrices = mergeStringMaps(c.EC2SpotPrices, pool.SpotPrices)
+	}
+	if pool.OnDemandPercentage != nil {
+		poolConfig.OnDemandPercentage = *pool.OnDemandPercentage
+	}
+	if len(pool.Tags) > 0 {
+		poolConfig.EC2Tags = mergeStringMaps(c.EC2Tags, pool.Tags)
+	}
+	if pool.DebugHoldHours != nil {
+		poolConfig.DebugHoldHours = *pool.DebugHoldHours
+	}
+	if pool.IdleTimeout != "" {
+		// Validated by validatePools
+		poolConfig.IdleTimeout, _ = time.ParseDuration(pool.IdleTimeout)
+	}
+	if len(pool.PrewarmImages) > 0 {
+		poolConfig.PrewarmImages = appendUniqueStrings(append([]string(nil), c.PrewarmImages...), pool.PrewarmImages...)
+	}
+	if pool.CacheProxyURL != "" {
+		poolConfig.ActionsCacheProxyURL = pool.CacheProxyURL
+	}
+	// A pool's toolcache replaces the top-level one, whichever kind either is
+	if pool.ToolcacheEFSID != "" {
+		poolConfig.ToolcacheEFSID, poolConfig.ToolcacheSnapshotID = pool.ToolcacheEFSID, ""
+	}
+	if pool.ToolcacheSnapshot != "" {
+		poolConfig.ToolcacheEFSID, poolConfig.ToolcacheSnapshotID = "", pool.ToolcacheSnapshot
+	}
+	if pool.RunnerScaleSetName != "" {
+		poolConfig.RunnerScaleSetName = pool.RunnerScaleSetName
+	}
+	return poolConfig
+}
+
+// mergeStringMaps returns a new map holding base overridden by overrides
+func mergeStringMaps(base, overrides map[string]string) map[string]string {
+	merged := make(map[string]string, len(base)+len(overrides))
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range overrides {
+		merged[key] = value
+	}
+	return merged
+}
+
+// appendUniqueStrings appends the values not already in list
+func appendUniqueStrings(list []string, values ...string) []string {
+	for _, value := range values {
+		if !slices.Contains(list, value) {
+			list = append(list, value)
+		}
+	}
+	return list
+}
+
+// findPool returns the pool with the given name
+func findPool(pools []PoolConfig, name string) (PoolConfig, bool) {
+	for _, pool := range pools {
+		if pool.Name == name {
+			return pool, true
+		}
+	}
+	return PoolConfig{}, false
+}
+
+// runPools evaluates every configured pool in parallel under a shared deadline and
+// returns the total number of queued jobs across pools
+func runPools(ctx context.Context, awsInfra *AWSInfrastructure, config Config) (int, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline.Add(-poolDeadlineMargin))
+		defer cancel()
+	}
+
+	loggerFrom(ctx).Info("Evaluating pools", "pools", len(config.Pools))
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(config.Pools))
+	queued := make([]int, len(config.Pools))
+	for i, pool := range config.Pools {
+		wg.Add(1)
+		go func(i int, pool PoolConfig) {
+			defer wg.Done()
+
+			poolConfig := config.forPool(pool)
+			poolInfra := *awsInfra
+			poolInfra.config = poolConfig
+
+			ctx := withLogger(ctx, loggerFrom(ctx).WithValues("pool", pool.Name))
+			loggerFrom(ctx).Info("Evaluating pool", "labels", pool.Labels)
+			queuedJobs, err := runScalingCycle(ctx, NewGHEClient(poolConfig), &poolInfra, poolConfig)
+			queued[i] = queuedJobs
+			if err != nil {
+				errs[i] = fmt.Errorf("pool %s: %w", pool.Name, err)
+				loggerFrom(ctx).Error(err, "Pool evaluation failed")
+				return
+			}
+			loggerFrom(ctx).Info("Pool evaluation completed")
+		}(i, pool)
+	}
+	wg.Wait()
+
+	totalQueued := 0
+	for _, n := range queued {
+		totalQueued += n
+	}
+	return totalQueued, errors.Join(errs...)
+}
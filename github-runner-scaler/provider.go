@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Anshuman2121/actionsspot/internal/provider"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// unlimitedCapacity is the capacity reported by providers that set no fixed runner limit
+const unlimitedCapacity = provider.UnlimitedCapacity
+
+// The runner provider types are shared with the ghaec2 scaler
+type (
+	RunnerSpec        = provider.RunnerSpec
+	ProvisionedRunner = provider.ProvisionedRunner
+	RunnerProvider    = provider.Provider
+)
+
+// runnerProvider returns the provider for the configuration being scaled
+func (aws *AWSInfrastructure) runnerProvider() RunnerProvider {
+	return &EC2SpotProvider{aws: aws}
+}
+
+// terminateNamedRunner terminates the instances of a runner known only by name, found by
+// their RunnerName tag, through the provider
+func (aws *AWSInfrastructure) terminateNamedRunner(ctx context.Context, runnerName string) error {
+	result, err := aws.ec2Client.DescribeInstances(ctx, &ec2.DescribeInstancesInput{
+		Filters: []ec2types.Filter{
+			{Name: aws.String("tag:RunnerName"), Values: []string{runnerName}},
+			{Name: aws.String("instance-state-name"), Values: []string{"running", "pending"}},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to describe instances: %w", err)
+	}
+
+	runnerProvider := aws.runnerProvider()
+	terminated := 0
+	for _, reservation := range result.Reservations {
+		for _, instance := range reservation.Instances {
+			if err := runnerProvider.TerminateRunner(ctx, *instance.InstanceId); err != nil {
+				return err
+			}
+			terminated++
+		}
+	}
+	if terminated == 0 {
+		loggerFrom(ctx).Info("No instances found for runner", "runnerName", runnerName)
+		return nil
+	}
+	loggerFrom(ctx).Info("Terminated runner instances", "runnerName", runnerName, "instances", terminated)
+	return nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/Anshuman2121/actionsspot/internal/runnerrecord"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// runnerRecordWriteAttempts bounds how often a runner record write that lost a race with
+// another writer is retried against the newer version
+const runnerRecordWriteAttempts = 3
+
+// recordVersion is the optimistic lock of one runner record write. Writes reference the
+// attribute as #version, since version is a DynamoDB reserved word.
+type recordVersion struct {
+	Condition string                          // the record still has the version that was read
+	Next      types.AttributeValue            // the version the write stores
+	Values    map[string]types.AttributeValue // expression values of Condition, nil when it has none
+}
+
+// mergeValues adds the condition's expression values to a write's values
+func (v recordVersion) mergeValues(values map[string]types.AttributeValue) map[string]types.AttributeValue {
+	for name, value := range v.Values {
+		values[name] = value
+	}
+	return values
+}
+
+// writeRunnerRecord performs a write of a runner record with optimistic locking. The write
+// is conditioned on the version read before it and stores the next one, so concurrent
+// invocations, or a listener sharing the table, never silently overwrite each other's
+// status updates; a write that lost the race is re-derived from what the winner wrote. A
+// write of status that would move a finished runner back to pending or running is refused.
+func (aws *AWSInfrastructure) writeRunnerRecord(ctx context.Context, runnerName, status string, write func(v recordVersion) error) error {
+	for attempt := 1; ; attempt++ {
+		version, current, err := aws.runnerRecordState(ctx, runnerName)
+		if err != nil {
+			return err
+		}
+		if runnerrecord.Reopens(current, status) {
+			return fmt.Errorf("runner %s is already %s, not moving it back to %s", runnerName, current, status)
+		}
+
+		v := recordVersion{
+			Condition: "attribute_not_exists(#version)",
+			Next:      &types.AttributeValueMemberN{Value: strconv.FormatInt(version+1, 10)},
+		}
+		if version > 0 {
+			v.Condition = "#version = :version"
+			v.Values = map[string]types.AttributeValue{
+				":version": &types.AttributeValueMemberN{Value: strconv.FormatInt(version, 10)},
+			}
+		}
+
+		err = write(v)
+		var conditionFailed *types.ConditionalCheckFailedException
+		if !errors.As(err, &conditionFailed) {
+			return err
+		}
+		if attempt == runnerRecordWriteAttempts {
+			return fmt.Errorf("runner %s record changed concurrently on %d attempts", runnerName, attempt)
+		}
+		loggerFrom(ctx).V(1).Info("Runner record changed, retrying", "runnerName", runnerName, "version", version)
+	}
+}
+
+// runnerRecordState returns the version and status of a runner record, version 0 when the
+// record does not exist or was written before records were versioned. The read is
+// consistent, so a retry sees the write it lost to.
+func (aws *AWSInfrastructure) runnerRecordState(ctx context.Context, runnerName string) (int64, string, error) {
+	output, err := aws.dynamoDBClient.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(aws.config.DynamoDBTableName),
+		Key: map[string]types.AttributeValue{
+			"runner_id": &types.AttributeValueMemberS{Value: runnerName},
+		},
+		ProjectionExpression:     aws.String("#version, #status"),
+		ExpressionAttributeNames: map[string]string{"#version": "version", "#status": "status"},
+		ConsistentRead:           aws.Bool(true),
+	})
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to read runner %s record: %w", runnerName, err)
+	}
+	var status string
+	if s, ok := output.Item["status"].(*types.AttributeValueMemberS); ok {
+		status = s.Value
+	}
+	if v, ok := output.Item["version"].(*types.AttributeValueMemberN); ok {
+		version, err := strconv.ParseInt(v.Value, 10, 64)
+		return version, status, err
+	}
+	return 0, status, nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// validateRunnerTokenParameterPath checks RUNNER_TOKEN_PARAMETER_PATH, an SSM parameter
+// path such as /github-runner-scaler/runner-tokens
+func validateRunnerTokenParameterPath(path string) error {
+	if path == "" {
+		return nil
+	}
+	if !strings.HasPrefix(path, "/") || strings.HasSuffix(path, "/") {
+		return fmt.Errorf("%q must start with / and not end with /", path)
+	}
+	return nil
+}
+
+// runnerTokenParameter returns the SSM parameter holding the registration token of a runner
+func (c Config) runnerTokenParameter(runnerName string) string {
+	return c.RunnerTokenParameterPath + "/" + runnerName
+}
+
+// runnerUserData renders the user data of a runner. With RUNNER_TOKEN_PARAMETER_PATH the
+// registration token is stored as a SecureString parameter that the instance profile reads
+// and deletes at boot, so the user data, readable by anything on the instance and through
+// DescribeInstanceAttribute, carries no token.
+func (aws *AWSInfrastructure) runnerUserData(ctx context.Context, runnerName, registrationToken string, labels []string) (string, error) {
+	if aws.config.RunnerTokenParameterPath == "" {
+		return aws.generateUserDataScriptWithToken(runnerName, registrationToken, labels), nil
+	}
+	err := aws.ssmClient.PutSecureParameter(ctx, aws.config.runnerTokenParameter(runnerName), registrationToken,
+		fmt.Sprintf("Registration token of runner %s", runnerName))
+	if err != nil {
+		return "", fmt.Errorf("failed to store registration token: %w", err)
+	}
+	return aws.generateUserDataScriptWithToken(runnerName, "", labels), nil
+}
+
+// deleteRunnerToken deletes the registration token parameter of a runner whose instance
+// never launched to read it
+func (aws *AWSInfrastructure) deleteRunnerToken(ctx context.Context, runnerName string) {
+	if aws.config.RunnerTokenParameterPath == "" {
+		return
+	}
+	if err := aws.ssmClient.DeleteParameter(ctx, aws.config.runnerTokenParameter(runnerName)); err != nil {
+		loggerFrom(ctx).Error(err, "Failed to delete registration token parameter", "runnerName", runnerName)
+	}
+}
+
+// runnerTokenArg returns the --token argument of config.sh: the token itself, or the
+// variable runnerTokenScript reads it into
+func (c Config) runnerTokenArg(registrationToken string) string {
+	if c.RunnerTokenParameterPath == "" {
+		return registrationToken
+	}
+	return `"$RUNNER_TOKEN"`
+}
+
+// runnerTokenScript reads the registration token from its parameter before config.sh and
+// deletes the parameter, so the token cannot be read again. It runs as the runner user.
+func (c Config) runnerTokenScript(runnerName string) string {
+	if c.RunnerTokenParameterPath == "" {
+		return ""
+	}
+	parameter := c.runnerTokenParameter(runnerName)
+	return fmt.Sprintf(`# Read the registration token through the instance profile, it is not in the user data
+RUNNER_TOKEN=$(aws ssm get-parameter --region $REGION --name %s --with-decryption --query Parameter.Value --output text)
+aws ssm delete-parameter --region $REGION --name %s || true
+`, parameter, parameter)
+}
+
+// windowsRunnerTokenScript is runnerTokenScript for Windows runners
+func (c Config) windowsRunnerTokenScript(runnerName, registrationToken string) string {
+	if c.RunnerTokenParameterPath == "" {
+		return fmt.Sprintf(`$RunnerToken = "%s"`, registrationToken)
+	}
+	parameter := c.runnerTokenParameter(runnerName)
+	return fmt.Sprintf(`# Read the registration token through the instance profile, it is not in the user data
+$RunnerToken = (Get-SSMParameter -Name "%s" -WithDecryption $true -Region $Region).Value
+try { Remove-SSMParameter -Name "%s" -Region $Region -Force } catch {}`, parameter, parameter)
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// scaleDownRulePrefix names the one-shot rules created for scale-down checks. The Lambda
+// permission in terraform allows every rule with this prefix to invoke the function.
+const scaleDownRulePrefix = "github-runner-scaler-scale-down-"
+
+const (
+	// scaleDownLockAttempts bounds how often a scale-down check waits for a scaling cycle
+	// holding the invocation lock. Its rule fires only once, so it cannot simply be skipped.
+	scaleDownLockAttempts      = 6
+	scaleDownLockRetryInterval = 10 * time.Second
+)
+
+// scheduleScaleDownCheck creates a one-shot EventBridge rule that invokes the Lambda after
+// the scale-down delay to check whether the runners just launched are still needed
+func scheduleScaleDownCheck(ctx context.Context, awsInfra *AWSInfrastructure, config Config, runnerNames []string) error {
+	if config.ScaleDownDelay <= 0 || len(runnerNames) == 0 {
+		return nil
+	}
+
+	at := time.Now().Add(config.ScaleDownDelay).UTC()
+	ruleName := fmt.Sprintf("%s%d", scaleDownRulePrefix, at.Unix())
+	if config.PoolName != "" {
+		ruleName = fmt.Sprintf("%s%s-%d", scaleDownRulePrefix, config.PoolName, at.Unix())
+	}
+
+	err := scheduleOneShotInvocation(ctx, awsInfra, ruleName, at,
+		fmt.Sprintf("Scale-down check for %d runners", len(runnerNames)),
+		ManualInvocation{
+			Action:      manualActionScaleDownCheck,
+			RunnerNames: runnerNames,
+			Pool:        config.PoolName,
+			Rule:        ruleName,
+		})
+	if err != nil {
+		return fmt.Errorf("failed to schedule scale-down check: %w", err)
+	}
+
+	loggerFrom(ctx).Info("Scale-down check scheduled", "runners", len(runnerNames), "at", at.Format(time.RFC3339))
+	return nil
+}
+
+// withScaleDownLock runs fn under the invocation lock, waiting for a running scaling cycle
+// to release it. A check that never gets the lock fails, so the async invocation is retried.
+func withScaleDownLock(ctx context.Context, awsInfra *AWSInfrastructure, config Config, fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := runLocked(ctx, awsInfra, config, fn)
+		if err != errLockHeld {
+			return err
+		}
+		if attempt >= scaleDownLockAttempts {
+			return fmt.Errorf("scale-down check gave up waiting for the invocation lock after %d attempts", attempt)
+		}
+		loggerFrom(ctx).Info("Invocation lock is held, retrying the scale-down check", "retryIn", scaleDownLockRetryInterval.String())
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(scaleDownLockRetryInterval):
+		}
+	}
+}
+
+// runScaleDownCheck removes runners launched by an earlier scale-up that are still idle,
+// never going below the minimum number of runners
+func runScaleDownCheck(ctx context.Context, gheClient *GHEClient, awsInfra *AWSInfrastructure, config Config, check ManualInvocation) error {
+	runners, err := gheClient.GetSelfHostedRunners(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get current runners: %w", err)
+	}
+
+	activeRunners := 0
+	byName := make(map[string]SelfHostedRunner, len(runners.Runners))
+	for _, runner := range runners.Runners {
+		if config.PoolName != "" && !runnerHasLabels(runner, config.RunnerLabels) {
+			continue
+		}
+		byName[runner.Name] = runner
+		if runner.Status == "online" {
+			activeRunners++
+		}
+	}
+
+	removed := 0
+	for _, name := range check.RunnerNames {
+		runner, ok := byName[name]
+		switch {
+		case !ok:
+			loggerFrom(ctx).Info("Runner is not registered, it finished its job or is still starting", "runnerName", name)
+			continue
+		case runner.Busy:
+			loggerFrom(ctx).Info("Runner is busy, keeping it", "runnerName", name)
+			continue
+		case runner.Status == "online" && activeRunners <= config.MinRunners:
+			loggerFrom(ctx).Info("Keeping idle runner to stay at the minimum", "runnerName", name, "minRunners", config.MinRunners)
+			continue
+		}
+
+		if err := gheClient.RemoveRunner(ctx, runner.ID); err != nil {
+			loggerFrom(ctx).Error(err, "Failed to remove idle runner", "runnerName", name)
+			continue
+		}
+		if err := awsInfra.terminateNamedRunner(ctx, name); err != nil {
+			loggerFrom(ctx).Error(err, "Failed to terminate instance of runner", "runnerName", name)
+		}
+		if runner.Status == "online" {
+			activeRunners--
+		}
+		removed++
+		loggerFrom(ctx).Info("Removed idle runner", "runnerName", name)
+	}
+
+	loggerFrom(ctx).Info("Scale-down check completed", "removed", removed, "runners", len(check.RunnerNames))
+	return nil
+}
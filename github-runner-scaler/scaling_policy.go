@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ScalingPolicy is the part of the configuration that can be tuned through AWS AppConfig
+// without redeploying. Fields that are not set keep the value from the environment.
+type ScalingPolicy struct {
+	MinRunners     *int         `json:"minRunners,omitempty"`
+	MaxRunners     *int         `json:"maxRunners,omitempty"`
+	ScaleDownDelay string       `json:"scaleDownDelay,omitempty"`
+	Pools          []PoolConfig `json:"pools,omitempty"`
+}
+
+// loadScalingPolicy reads the deployed scaling policy from the AppConfig Lambda extension,
+// which polls AppConfig in the background and serves the latest version locally
+func loadScalingPolicy(ctx context.Context, config Config) (*ScalingPolicy, error) {
+	endpoint := fmt.Sprintf("%s/applications/%s/environments/%s/configurations/%s",
+		strings.TrimSuffix(config.AppConfigAgentURL, "/"),
+		config.AppConfigApplication, config.AppConfigEnvironment, config.AppConfigProfile)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := (&http.Client{Timeout: 5 * time.Second}).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach AppConfig extension: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read AppConfig response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("AppConfig extension returned HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var policy ScalingPolicy
+	if err := json.Unmarshal(body, &policy); err != nil {
+		return nil, fmt.Errorf("invalid scaling policy: %w", err)
+	}
+	return &policy, nil
+}
+
+// withScalingPolicy returns a copy of the configuration with the policy applied
+func (c Config) withScalingPolicy(policy *ScalingPolicy) (Config, error) {
+	updated := c
+	if policy.MinRunners != nil {
+		updated.MinRunners = *policy.MinRunners
+	}
+	if policy.MaxRunners != nil {
+		updated.MaxRunners = *policy.MaxRunners
+	}
+	if updated.MaxRunners <= 0 || updated.MinRunners < 0 || updated.MinRunners > updated.MaxRunners {
+		return c, fmt.Errorf("invalid runner limits min=%d max=%d", updated.MinRunners, updated.MaxRunners)
+	}
+
+	if policy.ScaleDownDelay != "" {
+		delay, err := time.ParseDuration(policy.ScaleDownDelay)
+		if err != nil {
+			return c, fmt.Errorf("invalid scaleDownDelay: %w", err)
+		}
+		updated.ScaleDownDelay = delay
+	}
+
+	if len(policy.Pools) > 0 {
+		if err := validatePools(policy.Pools); err != nil {
+			return c, err
+		}
+		updated.Pools = policy.Pools
+		if err := updated.checkPoolPlatforms(); err != nil {
+			return c, err
+		}
+	}
+	return updated, nil
+}
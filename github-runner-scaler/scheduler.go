@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	eventbridgetypes "github.com/aws/aws-sdk-go-v2/service/eventbridge/types"
+	lambdaservice "github.com/aws/aws-sdk-go-v2/service/lambda"
+	lambdatypes "github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// selfScheduleTargetID is the EventBridge target ID used for the scaler Lambda
+const selfScheduleTargetID = "GitHubRunnerScalerTarget"
+
+// SelfScheduler keeps the EventBridge schedule that triggers the Lambda pointed at the
+// function and adjusts its rate to the current demand
+type SelfScheduler struct {
+	events *eventbridge.Client
+	lambda *lambdaservice.Client
+	config Config
+}
+
+// NewSelfScheduler creates a self scheduler
+func NewSelfScheduler(events *eventbridge.Client, lambda *lambdaservice.Client, config Config) *SelfScheduler {
+	return &SelfScheduler{
+		events: events,
+		lambda: lambda,
+		config: config,
+	}
+}
+
+// ScheduleNextExecution sets the schedule rule to the fast interval while jobs are queued
+// and the idle interval otherwise, making sure the rule targets this function and is
+// allowed to invoke it
+func (s *SelfScheduler) ScheduleNextExecution(ctx context.Context, queuedJobs int) error {
+	interval := s.config.ScheduleIdleInterval
+	if queuedJobs > 0 {
+		interval = s.config.ScheduleFastInterval
+	}
+	expression := rateExpression(interval)
+
+	// Leave the rule alone when nothing changes so steady state costs a single read
+	current, err := s.events.DescribeRule(ctx, &eventbridge.DescribeRuleInput{
+		Name: &s.config.ScheduleRuleName,
+	})
+	if err == nil && current.ScheduleExpression != nil && *current.ScheduleExpression == expression {
+		return nil
+	}
+
+	functionARN, err := functionARN(ctx)
+	if err != nil {
+		return err
+	}
+
+	rule, err := s.events.PutRule(ctx, &eventbridge.PutRuleInput{
+		Name:               &s.config.ScheduleRuleName,
+		ScheduleExpression: &expression,
+		State:              eventbridgetypes.RuleStateEnabled,
+		Description:        stringPtr("Trigger GitHub Runner Scaler Lambda (managed by the scaler)"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update schedule rule: %w", err)
+	}
+
+	_, err = s.events.PutTargets(ctx, &eventbridge.PutTargetsInput{
+		Rule: &s.config.ScheduleRuleName,
+		Targets: []eventbridgetypes.Target{
+			{Id: stringPtr(selfScheduleTargetID), Arn: &functionARN},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set schedule target: %w", err)
+	}
+
+	_, err = s.lambda.AddPermission(ctx, &lambdaservice.AddPermissionInput{
+		FunctionName: &functionARN,
+		StatementId:  stringPtr("AllowSelfSchedule-" + s.config.ScheduleRuleName),
+		Action:       stringPtr("lambda:InvokeFunction"),
+		Principal:    stringPtr("events.amazonaws.com"),
+		SourceArn:    rule.RuleArn,
+	})
+	var conflict *lambdatypes.ResourceConflictException
+	if err != nil && !errors.As(err, &conflict) {
+		return fmt.Errorf("failed to grant EventBridge invoke permission: %w", err)
+	}
+
+	loggerFrom(ctx).Info("Next executions scheduled", "schedule", expression, "queuedJobs", queuedJobs)
+	return nil
+}
+
+// rateExpression converts an interval to an EventBridge rate expression, which only supports whole minutes
+func rateExpression(interval time.Duration) string {
+	minutes := int(interval.Round(time.Minute) / time.Minute)
+	if minutes <= 1 {
+		return "rate(1 minute)"
+	}
+	return fmt.Sprintf("rate(%d minutes)", minutes)
+}
+
+// functionARN returns the ARN of the running function
+func functionARN(ctx context.Context) (string, error) {
+	lc, ok := lambdacontext.FromContext(ctx)
+	if !ok || lc.InvokedFunctionArn == "" {
+		return "", fmt.Errorf("function ARN is not available outside Lambda")
+	}
+	return lc.InvokedFunctionArn, nil
+}
+
+// scheduleOneShotInvocation creates an EventBridge rule that invokes the Lambda once at the
+// given time with the manual invocation as payload. The invoked action deletes the rule.
+// The Lambda permission in terraform must allow rules with the name's prefix.
+func scheduleOneShotInvocation(ctx context.Context, awsInfra *AWSInfrastructure, ruleName string, at time.Time, description string, invocation ManualInvocation) error {
+	functionARN, err := functionARN(ctx)
+	if err != nil {
+		return err
+	}
+
+	input, err := json.Marshal(invocation)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s invocation: %w", invocation.Action, err)
+	}
+
+	// A cron expression with a year only matches once
+	at = at.UTC()
+	expression := fmt.Sprintf("cron(%d %d %d %d ? %d)", at.Minute(), at.Hour(), at.Day(), int(at.Month()), at.Year())
+	_, err = awsInfra.eventsClient.PutRule(ctx, &eventbridge.PutRuleInput{
+		Name:               &ruleName,
+		ScheduleExpression: &expression,
+		State:              eventbridgetypes.RuleStateEnabled,
+		Description:        &description,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create rule %s: %w", ruleName, err)
+	}
+
+	_, err = awsInfra.eventsClient.PutTargets(ctx, &eventbridge.PutTargetsInput{
+		Rule: &ruleName,
+		Targets: []eventbridgetypes.Target{
+			{Id: stringPtr(selfScheduleTargetID), Arn: &functionARN, Input: stringPtr(string(input))},
+		},
+	})
+	if err != nil {
+		deleteOneShotRule(context.WithoutCancel(ctx), awsInfra, ruleName)
+		return fmt.Errorf("failed to set target of rule %s: %w", ruleName, err)
+	}
+	return nil
+}
+
+// deleteOneShotRule removes a one-shot rule and its target
+func deleteOneShotRule(ctx context.Context, awsInfra *AWSInfrastructure, ruleName string) {
+	_, err := awsInfra.eventsClient.RemoveTargets(ctx, &eventbridge.RemoveTargetsInput{
+		Rule: &ruleName,
+		Ids:  []string{selfScheduleTargetID},
+	})
+	if err != nil {
+		loggerFrom(ctx).Error(err, "Failed to remove targets from rule", "rule", ruleName)
+	}
+
+	if _, err := awsInfra.eventsClient.DeleteRule(ctx, &eventbridge.DeleteRuleInput{Name: &ruleName}); err != nil {
+		loggerFrom(ctx).Error(err, "Failed to delete rule", "rule", ruleName)
+	}
+}
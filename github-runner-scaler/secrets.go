@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Anshuman2121/actionsspot/internal/githubapp"
+)
+
+// gitHubTokenCache keeps the token read from GITHUB_TOKEN_SECRET_ARN across warm
+// invocations, so Secrets Manager is read once per GITHUB_TOKEN_SECRET_TTL and a rotated
+// token is picked up within that time
+var gitHubTokenCache struct {
+	mu        sync.Mutex
+	arn       string
+	value     string
+	fetchedAt time.Time
+}
+
+// gitHubAppTokenCache keeps the installation token of the GitHub App across warm
+// invocations until it is about to expire
+var gitHubAppTokenCache struct {
+	mu    sync.Mutex
+	token githubapp.Token
+}
+
+// gitHubAppTokenRenewBefore is how long before it expires an installation token is renewed,
+// so a token handed to an invocation outlasts the invocation
+const gitHubAppTokenRenewBefore = 10 * time.Minute
+
+// validateSecretARN checks GITHUB_TOKEN_SECRET_ARN
+func validateSecretARN(arn string) error {
+	if arn != "" && !strings.HasPrefix(arn, "arn:") {
+		return fmt.Errorf("%q is not an ARN", arn)
+	}
+	return nil
+}
+
+// secretRegion returns the region of a secret ARN (arn:aws:secretsmanager:<region>:...), so
+// a secret may live outside the Lambda's region
+func secretRegion(arn, fallback string) string {
+	if parts := strings.Split(arn, ":"); len(parts) > 3 && parts[3] != "" {
+		return parts[3]
+	}
+	return fallback
+}
+
+// gitHubApp is the GitHub App the scaler authenticates as, if any
+type gitHubApp struct {
+	id                  int64
+	installationID      int64
+	privateKeySecretARN string
+}
+
+// parseGitHubApp reads GITHUB_APP_ID, GITHUB_APP_INSTALLATION_ID and
+// GITHUB_APP_PRIVATE_KEY_SECRET_ARN, which are set together or not at all
+func parseGitHubApp(src *configSource) (gitHubApp, error) {
+	var app gitHubApp
+	app.privateKeySecretARN = src.Get("GITHUB_APP_PRIVATE_KEY_SECRET_ARN")
+	if err := validateSecretARN(app.privateKeySecretARN); err != nil {
+		return gitHubApp{}, fmt.Errorf("invalid GITHUB_APP_PRIVATE_KEY_SECRET_ARN: %w", err)
+	}
+	for _, id := range []struct {
+		name   string
+		target *int64
+	}{
+		{"GITHUB_APP_ID", &app.id},
+		{"GITHUB_APP_INSTALLATION_ID", &app.installationID},
+	} {
+		value := src.Get(id.name)
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed <= 0 {
+			return gitHubApp{}, fmt.Errorf("invalid %s: %q", id.name, value)
+		}
+		*id.target = parsed
+	}
+
+	set := app.privateKeySecretARN != ""
+	if (app.id != 0) != set || (app.installationID != 0) != set {
+		return gitHubApp{}, fmt.Errorf("GITHUB_APP_ID, GITHUB_APP_INSTALLATION_ID and GITHUB_APP_PRIVATE_KEY_SECRET_ARN must be set together")
+	}
+	return app, nil
+}
+
+// gitHubToken returns the GitHub token of the invocation: an installation token of the
+// GitHub App when one is configured, the cached value of GITHUB_TOKEN_SECRET_ARN when one is
+// set, and GITHUB_TOKEN otherwise. A failed refresh keeps using the cached token.
+func (aws *AWSInfrastructure) gitHubToken(ctx context.Context) (string, error) {
+	if aws.config.GitHubAppPrivateKeySecretARN != "" {
+		return aws.gitHubAppToken(ctx)
+	}
+	arn := aws.config.GitHubTokenSecretARN
+	if arn == "" {
+		return aws.config.GitHubToken, nil
+	}
+
+	cache := &gitHubTokenCache
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if cache.arn == arn && time.Since(cache.fetchedAt) < aws.config.GitHubTokenSecretTTL {
+		return cache.value, nil
+	}
+
+	value, err := aws.readSecretToken(ctx, arn)
+	if err != nil {
+		if cache.arn == arn && cache.value != "" {
+			loggerFrom(ctx).Error(err, "Failed to refresh GITHUB_TOKEN_SECRET_ARN, using the cached token")
+			return cache.value, nil
+		}
+		return "", fmt.Errorf("failed to read GITHUB_TOKEN_SECRET_ARN: %w", err)
+	}
+	if cache.arn == arn && cache.value != value {
+		loggerFrom(ctx).Info("GitHub token secret was rotated")
+	}
+	cache.arn, cache.value, cache.fetchedAt = arn, value, time.Now()
+	return value, nil
+}
+
+// gitHubAppToken returns an installation token of the GitHub App, created from the App's
+// private key and reused by warm invocations until it is about to expire. The key is read
+// from Secrets Manager for every new token, so a rotated key is picked up within the hour.
+func (aws *AWSInfrastructure) gitHubAppToken(ctx context.Context) (string, error) {
+	cache := &gitHubAppTokenCache
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if !cache.token.Expiring(time.Now(), gitHubAppTokenRenewBefore) {
+		return cache.token.Token, nil
+	}
+
+	pemKey, err := aws.readSecretString(ctx, aws.config.GitHubAppPrivateKeySecretARN)
+	if err != nil {
+		return "", fmt.Errorf("failed to read GITHUB_APP_PRIVATE_KEY_SECRET_ARN: %w", err)
+	}
+	key, err := githubapp.ParsePrivateKey([]byte(pemKey))
+	if err != nil {
+		return "", fmt.Errorf("invalid GitHub App private key: %w", err)
+	}
+	token, err := githubapp.InstallationToken(ctx, &http.Client{Timeout: 30 * time.Second}, gheAPIURL,
+		aws.config.GitHubAppID, aws.config.GitHubAppInstallationID, key)
+	if err != nil {
+		return "", fmt.Errorf("failed to create GitHub App installation token: %w", err)
+	}
+	loggerFrom(ctx).Info("Created GitHub App installation token", "expiresAt", token.ExpiresAt)
+	cache.token = token
+	return token.Token, nil
+}
+
+// readSecretToken reads a token from Secrets Manager: the secret string itself, or the
+// "token" key of a key/value secret as the Secrets Manager console creates them
+func (aws *AWSInfrastructure) readSecretToken(ctx context.Context, arn string) (string, error) {
+	secretString, err := aws.readSecretString(ctx, arn)
+	if err != nil {
+		return "", err
+	}
+
+	token := strings.TrimSpace(secretString)
+	var fields map[string]string
+	if err := json.Unmarshal([]byte(secretString), &fields); err == nil {
+		token = fields["token"]
+	}
+	if token == "" {
+		return "", fmt.Errorf("secret %s holds no token", arn)
+	}
+	return token, nil
+}
+
+// readSecretString reads the current secret string of a secret, from the region in its ARN
+func (aws *AWSInfrastructure) readSecretString(ctx context.Context, arn string) (string, error) {
+	var result struct {
+		SecretString string `json:"SecretString"`
+	}
+	err := aws.ssmClient.callService(ctx, "secretsmanager", secretRegion(arn, aws.ssmClient.region),
+		"secretsmanager.GetSecretValue", map[string]string{"SecretId": arn}, &result)
+	if err != nil {
+		return "", err
+	}
+	return result.SecretString, nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// SessionManager reuses a persisted message session across Lambda invocations.
+// Without it every scheduled run would create a new session and leave the previous
+// one orphaned on the Actions Service.
+type SessionManager struct {
+	client *ActionsServiceClient
+	store  *SessionStore
+	config Config
+}
+
+// NewSessionManager creates a session manager
+func NewSessionManager(client *ActionsServiceClient, store *SessionStore, config Config) *SessionManager {
+	return &SessionManager{
+		client: client,
+		store:  store,
+		config: config,
+	}
+}
+
+// GetSession returns a live session for the scale set. It resumes the stored session when
+// it is still valid, deletes sessions older than the max age, and only creates a new one
+// when nothing reusable is left.
+func (m *SessionManager) GetSession(ctx context.Context, scaleSet *RunnerScaleSet) (*RunnerScaleSetSession, error) {
+	records, err := m.store.ListForScaleSet(ctx, scaleSet.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep the most recently refreshed session that is within the max age; delete the rest
+	sort.Slice(records, func(i, j int) bool { return records[i].RefreshedAt.After(records[j].RefreshedAt) })
+
+	var current *SessionRecord
+	for i := range records {
+		if current == nil && time.Since(records[i].CreatedAt) <= m.config.SessionMaxAge {
+			current = &records[i]
+			continue
+		}
+		loggerFrom(ctx).Info("Deleting stale session", "sessionId", records[i].SessionID, "createdAt", records[i].CreatedAt.Format(time.RFC3339))
+		m.deleteSession(ctx, records[i])
+	}
+
+	if current != nil {
+		session, err := m.refreshSession(ctx, scaleSet.ID, current.SessionID)
+		if err == nil {
+			current.RefreshedAt = time.Now()
+			current.MessageQueueURL = session.MessageQueueURL
+			if err := m.store.Save(ctx, *current); err != nil {
+				loggerFrom(ctx).Error(err, "Failed to update session record", "sessionId", current.SessionID)
+			}
+			loggerFrom(ctx).Info("Reusing message session", "sessionId", current.SessionID)
+			return session, nil
+		}
+
+		loggerFrom(ctx).Info("Stored session is no longer valid, creating a new one", "sessionId", current.SessionID, "error", err.Error())
+		if err := m.store.Delete(ctx, current.SessionID); err != nil {
+			loggerFrom(ctx).Error(err, "Failed to delete session record", "sessionId", current.SessionID)
+		}
+	}
+
+	return m.createSession(ctx, scaleSet)
+}
+
+// refreshSession validates a session by refreshing it, reconnecting once when the admin token has expired
+func (m *SessionManager) refreshSession(ctx context.Context, scaleSetID int, sessionID string) (*RunnerScaleSetSession, error) {
+	session, err := m.client.RefreshMessageSession(ctx, scaleSetID, sessionID)
+	if err == nil || !isActionsServiceStatus(err, http.StatusUnauthorized) {
+		return session, err
+	}
+
+	loggerFrom(ctx).Info("Admin token rejected, reconnecting to the Actions Service")
+	if err := m.client.Connect(ctx); err != nil {
+		return nil, err
+	}
+	return m.client.RefreshMessageSession(ctx, scaleSetID, sessionID)
+}
+
+// createSession creates and persists a new message session
+func (m *SessionManager) createSession(ctx context.Context, scaleSet *RunnerScaleSet) (*RunnerScaleSetSession, error) {
+	owner := fmt.Sprintf("lambda-%s", m.config.RunnerScaleSetName)
+	session, err := m.client.CreateMessageSession(ctx, scaleSet, owner)
+	if err != nil {
+		if isActionsServiceStatus(err, http.StatusConflict) {
+			return nil, fmt.Errorf("scale set %s already has an active session that is not in %s: %w",
+				scaleSet.Name, m.config.SessionsTableName, err)
+		}
+		return nil, fmt.Errorf("failed to create message session: %w", err)
+	}
+	if session.SessionID == nil {
+		return nil, fmt.Errorf("message session for scale set %s was created without an ID", scaleSet.Name)
+	}
+
+	now := time.Now()
+	record := SessionRecord{
+		SessionID:       session.SessionID.String(),
+		ScaleSetID:      scaleSet.ID,
+		OwnerName:       owner,
+		MessageQueueURL: session.MessageQueueURL,
+		CreatedAt:       now,
+		RefreshedAt:     now,
+	}
+	if err := m.store.Save(ctx, record); err != nil {
+		// Without the record the next invocation would orphan this session, so give it back
+		m.deleteSession(ctx, record)
+		return nil, err
+	}
+
+	loggerFrom(ctx).Info("Created message session", "sessionId", session.SessionID, "scaleSet", scaleSet.Name)
+	return session, nil
+}
+
+// deleteSession removes a session from the Actions Service and the table
+func (m *SessionManager) deleteSession(ctx context.Context, record SessionRecord) {
+	err := m.client.DeleteMessageSession(ctx, record.ScaleSetID, record.SessionID)
+	if err != nil && !isActionsServiceStatus(err, http.StatusNotFound) {
+		loggerFrom(ctx).Error(err, "Failed to delete session from the Actions Service", "sessionId", record.SessionID)
+	}
+	if err := m.store.Delete(ctx, record.SessionID); err != nil {
+		loggerFrom(ctx).Error(err, "Failed to delete session record", "sessionId", record.SessionID)
+	}
+}
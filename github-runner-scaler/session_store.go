@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// SessionRecord is the persisted form of a message session. The message queue access
+// token is not stored; a refresh returns a fresh one on every invocation.
+type SessionRecord struct {
+	SessionID       string    `dynamodbav:"session_id"`
+	ScaleSetID      int       `dynamodbav:"scale_set_id"`
+	OwnerName       string    `dynamodbav:"owner_name"`
+	MessageQueueURL string    `dynamodbav:"message_queue_url"`
+	CreatedAt       time.Time `dynamodbav:"created_at"`
+	RefreshedAt     time.Time `dynamodbav:"refreshed_at"`
+}
+
+// SessionStore persists message sessions in the sessions table so short-lived
+// Lambda invocations can reuse them. Records expire through TTL once they have not
+// been refreshed for the retention, e.g. after the scale set was removed.
+type SessionStore struct {
+	client    *dynamodb.Client
+	tableName string
+	retention time.Duration
+}
+
+// NewSessionStore creates a session store for the given table
+func NewSessionStore(client *dynamodb.Client, tableName string, retention time.Duration) *SessionStore {
+	return &SessionStore{
+		client:    client,
+		tableName: tableName,
+		retention: retention,
+	}
+}
+
+// ListForScaleSet returns every stored session for a scale set. The table is keyed by
+// session ID and holds only a handful of items, so a filtered scan is sufficient.
+func (s *SessionStore) ListForScaleSet(ctx context.Context, scaleSetID int) ([]SessionRecord, error) {
+	output, err := s.client.Scan(ctx, &dynamodb.ScanInput{
+		TableName:        &s.tableName,
+		FilterExpression: stringPtr("scale_set_id = :scale_set_id"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":scale_set_id": &types.AttributeValueMemberN{Value: strconv.Itoa(scaleSetID)},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan sessions: %w", err)
+	}
+
+	records := make([]SessionRecord, 0, len(output.Items))
+	for _, item := range output.Items {
+		records = append(records, sessionRecordFromItem(item))
+	}
+	return records, nil
+}
+
+// Save creates or replaces a session record
+func (s *SessionStore) Save(ctx context.Context, record SessionRecord) error {
+	_, err := s.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: &s.tableName,
+		Item: map[string]types.AttributeValue{
+			"session_id":        &types.AttributeValueMemberS{Value: record.SessionID},
+			"scale_set_id":      &types.AttributeValueMemberN{Value: strconv.Itoa(record.ScaleSetID)},
+			"owner_name":        &types.AttributeValueMemberS{Value: record.OwnerName},
+			"message_queue_url": &types.AttributeValueMemberS{Value: record.MessageQueueURL},
+			"created_at":        &types.AttributeValueMemberS{Value: record.CreatedAt.Format(time.RFC3339)},
+			"refreshed_at":      &types.AttributeValueMemberS{Value: record.RefreshedAt.Format(time.RFC3339)},
+			"expires_at":        &types.AttributeValueMemberN{Value: strconv.FormatInt(record.RefreshedAt.Add(s.retention).Unix(), 10)},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save session %s: %w", record.SessionID, err)
+	}
+	return nil
+}
+
+// Delete removes a session record
+func (s *SessionStore) Delete(ctx context.Context, sessionID string) error {
+	_, err := s.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: &s.tableName,
+		Key: map[string]types.AttributeValue{
+			"session_id": &types.AttributeValueMemberS{Value: sessionID},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete session %s: %w", sessionID, err)
+	}
+	return nil
+}
+
+func sessionRecordFromItem(item map[string]types.AttributeValue) SessionRecord {
+	str := func(name string) string {
+		if v, ok := item[name].(*types.AttributeValueMemberS); ok {
+			return v.Value
+		}
+		return ""
+	}
+
+	record := SessionRecord{
+		SessionID:       str("session_id"),
+		OwnerName:       str("owner_name"),
+		MessageQueueURL: str("message_queue_url"),
+	}
+	if v, ok := item["scale_set_id"].(*types.AttributeValueMemberN); ok {
+		record.ScaleSetID, _ = strconv.Atoi(v.Value)
+	}
+	record.CreatedAt, _ = time.Parse(time.RFC3339, str("created_at"))
+	record.RefreshedAt, _ = time.Parse(time.RFC3339, str("refreshed_at"))
+	return record
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Anshuman2121/actionsspot/internal/runnerrecord"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// Retries of a runner whose spot request timed out
+const (
+	spotFallbackNone         = "none"          // only cancel the request
+	spotFallbackInstanceType = "instance-type" // relaunch as spot on one of the other instance types
+	spotFallbackOnDemand     = "on-demand"     // relaunch on demand
+)
+
+// validateSpotFallback checks SPOT_REQUEST_FALLBACK
+func validateSpotFallback(fallback string) error {
+	switch fallback {
+	case spotFallbackNone, spotFallbackInstanceType, spotFallbackOnDemand:
+		return nil
+	}
+	return fmt.Errorf("unknown fallback %q (want %s, %s or %s)", fallback, spotFallbackNone, spotFallbackInstanceType, spotFallbackOnDemand)
+}
+
+// cancelStaleSpotRequests cancels this pool's spot requests that have been open longer than
+// SPOT_REQUEST_TIMEOUT, e.g. waiting on capacity or priced out, so they stop counting
+// against the account's spot request limits. Persistent requests stay open after their
+// instance is stopped or hibernated, so its instance is terminated with them. The runner's
+// record is marked failed with the request's status, and SPOT_REQUEST_FALLBACK can launch
+// a replacement runner on another instance type or on demand. Only requests tagged at
+// launch are found.
+func cancelStaleSpotRequests(ctx context.Context, gheClient *GHEClient, awsInfra *AWSInfrastructure, config Config) error {
+	if config.SpotRequestTimeout <= 0 {
+		return nil
+	}
+
+	result, err := awsInfra.ec2Client.DescribeSpotInstanceRequests(ctx, &ec2.DescribeSpotInstanceRequestsInput{
+		Filters: []ec2types.Filter{
+			{Name: awsInfra.String("tag:ManagedBy"), Values: []string{"github-runner-scaler-lambda"}},
+			{Name: awsInfra.String("state"), Values: []string{string(ec2types.SpotInstanceStateOpen)}},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to describe spot instance requests: %w", err)
+	}
+
+	for _, request := range result.SpotInstanceRequests {
+		tags := tagValues(request.Tags)
+		if tags["Pool"] != config.PoolName {
+			continue
+		}
+		var openSince time.Time
+		if request.CreateTime != nil {
+			openSince = *request.CreateTime
+		}
+		if request.Status != nil && request.Status.UpdateTime != nil {
+			openSince = *request.Status.UpdateTime
+		}
+		if time.Since(openSince) < config.SpotRequestTimeout {
+			continue
+		}
+
+		runner := launchedRunner{
+			RunnerName:    tags["RunnerName"],
+			InstanceID:    awsInfra.ToString(request.InstanceId),
+			SpotRequestID: awsInfra.ToString(request.SpotInstanceRequestId),
+		}
+		reason := "open"
+		if request.Status != nil {
+			reason = awsInfra.ToString(request.Status.Code)
+		}
+		if err := awsInfra.cancelSpotRequest(ctx, runner); err != nil {
+			loggerFrom(ctx).Error(err, "Failed to cancel spot request", "spotRequestId", runner.SpotRequestID, "runnerName", runner.RunnerName)
+			continue
+		}
+		loggerFrom(ctx).Info("Cancelled spot request", "spotRequestId", runner.SpotRequestID, "runnerName", runner.RunnerName,
+			"reason", reason, "openFor", time.Since(openSince).Round(time.Minute).String())
+		awsInfra.metrics.Count(metricSpotRequestTimeouts, config.PoolName, 1)
+
+		if runner.RunnerName == "" {
+			continue
+		}
+		if err := awsInfra.markRunnerFailed(ctx, runner.RunnerName, "spot request "+reason); err != nil {
+			loggerFrom(ctx).Error(err, "Failed to mark runner failed", "runnerName", runner.RunnerName)
+		}
+		if config.SpotRequestFallback != spotFallbackNone {
+			instanceType := ""
+			if request.LaunchSpecification != nil {
+				instanceType = string(request.LaunchSpecification.InstanceType)
+			}
+			if err := relaunchSpotRunner(ctx, gheClient, awsInfra, config, instanceType); err != nil {
+				loggerFrom(ctx).Error(err, "Failed to relaunch runner", "runnerName", runner.RunnerName, "fallback", config.SpotRequestFallback)
+			}
+		}
+	}
+	return nil
+}
+
+// cancelSpotRequest cancels a spot request and terminates the instance it launched, if any
+func (aws *AWSInfrastructure) cancelSpotRequest(ctx context.Context, runner launchedRunner) error {
+	if _, err := aws.ec2Client.CancelSpotInstanceRequests(ctx, &ec2.CancelSpotInstanceRequestsInput{
+		SpotInstanceRequestIds: []string{runner.SpotRequestID},
+	}); err != nil {
+		return fmt.Errorf("failed to cancel spot instance request: %w", err)
+	}
+	if runner.InstanceID == "" {
+		return nil
+	}
+	return aws.runnerProvider().TerminateRunner(ctx, runner.InstanceID)
+}
+
+// relaunchSpotRunner launches a new runner in place of one whose spot request timed out,
+// on demand or as spot on the configured instance types other than the one that failed
+func relaunchSpotRunner(ctx context.Context, gheClient *GHEClient, awsInfra *AWSInfrastructure, config Config, failedType string) error {
+	switch config.SpotRequestFallback {
+	case spotFallbackOnDemand:
+		config.OnDemandOnly = true
+	case spotFallbackInstanceType:
+		var others []string
+		for _, instanceType := range config.launchInstanceTypes() {
+			if instanceType != failedType {
+				others = append(others, instanceType)
+			}
+		}
+		if len(others) == 0 {
+			return fmt.Errorf("no instance type other than %s is configured", failedType)
+		}
+		config.EC2InstanceTypes = others
+	}
+	fallbackInfra := *awsInfra
+	fallbackInfra.config = config
+
+	runnerName, err := availableRunnerName(ctx, gheClient, config, nil)
+	if err != nil {
+		return err
+	}
+	token, err := gheClient.GetRegistrationToken(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get registration token: %w", err)
+	}
+	instanceID, err := fallbackInfra.runnerProvider().CreateRunner(ctx, RunnerSpec{
+		Name:              runnerName,
+		RegistrationToken: token.Token,
+		Labels:            literalLabels(config.RunnerLabels),
+	})
+	if err != nil {
+		return err
+	}
+	loggerFrom(ctx).Info("Launched runner with fallback", "runnerName", runnerName, "instanceId", instanceID, "fallback", config.SpotRequestFallback)
+	return nil
+}
+
+// markRunnerFailed sets a runner's record to failed with the reason
+func (aws *AWSInfrastructure) markRunnerFailed(ctx context.Context, runnerName, reason string) error {
+	err := aws.writeRunnerRecord(ctx, runnerName, runnerrecord.Failed, func(v recordVersion) error {
+		now := time.Now()
+		_, err := aws.dynamoDBClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+			TableName: aws.String(aws.config.DynamoDBTableName),
+			Key: map[string]types.AttributeValue{
+				"runner_id": &types.AttributeValueMemberS{Value: runnerName},
+			},
+			UpdateExpression:    aws.String("SET #status = :status, failure_reason = :reason, updated_at = :updated_at, expires_at = :expires_at, #version = :next_version"),
+			ConditionExpression: aws.String(v.Condition),
+			ExpressionAttributeNames: map[string]string{
+				"#status":  "status",
+				"#version": "version",
+			},
+			ExpressionAttributeValues: v.mergeValues(map[string]types.AttributeValue{
+				":status":       &types.AttributeValueMemberS{Value: runnerrecord.Failed},
+				":reason":       &types.AttributeValueMemberS{Value: reason},
+				":updated_at":   &types.AttributeValueMemberS{Value: now.Format(time.RFC3339)},
+				":expires_at":   aws.runnerRecordExpiry(now),
+				":next_version": v.Next,
+			}),
+		})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to mark runner %s as failed: %w", runnerName, err)
+	}
+	return nil
+}
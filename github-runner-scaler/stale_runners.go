@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// bootstrapHashTag carries the hash of the bootstrap template a runner was launched from
+const bootstrapHashTag = "BootstrapHash"
+
+// launchedRunner is a runner instance found through its tags
+type launchedRunner struct {
+	RunnerName    string
+	InstanceID    string
+	SpotRequestID string
+	BootstrapHash string
+}
+
+// staleRunners returns this pool's runners that were launched from a bootstrap template
+// other than the current one, e.g. before a label, network or bootstrap setting changed.
+// Runners launched before instances were stamped carry no hash and are left alone.
+func (aws *AWSInfrastructure) staleRunners(ctx context.Context) ([]launchedRunner, error) {
+	current := aws.bootstrapHash()
+
+	var stale []launchedRunner
+	paginator := ec2.NewDescribeInstancesPaginator(aws.ec2Client, &ec2.DescribeInstancesInput{
+		Filters: []ec2types.Filter{
+			{Name: aws.String("tag:ManagedBy"), Values: []string{"github-runner-scaler-lambda"}},
+			{Name: aws.String("tag-key"), Values: []string{bootstrapHashTag}},
+			{Name: aws.String("instance-state-name"), Values: []string{"pending", "running"}},
+		},
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe runner instances: %w", err)
+		}
+		for _, reservation := range page.Reservations {
+			for _, instance := range reservation.Instances {
+				tags := tagValues(instance.Tags)
+				if tags["Pool"] != aws.config.PoolName || tags[bootstrapHashTag] == current {
+					continue
+				}
+				runner := launchedRunner{
+					RunnerName:    tags["RunnerName"],
+					InstanceID:    *instance.InstanceId,
+					BootstrapHash: tags[bootstrapHashTag],
+				}
+				if instance.SpotInstanceRequestId != nil {
+					runner.SpotRequestID = *instance.SpotInstanceRequestId
+				}
+				stale = append(stale, runner)
+			}
+		}
+	}
+	return stale, nil
+}
+
+// reconcileStaleRunners reports runners launched from an outdated bootstrap template. With
+// RECYCLE_STALE_RUNNERS enabled, idle ones are deregistered and terminated so demand is met
+// by runners with the current configuration; busy ones finish their job first.
+func reconcileStaleRunners(ctx context.Context, gheClient *GHEClient, awsInfra *AWSInfrastructure, config Config) error {
+	stale, err := awsInfra.staleRunners(ctx)
+	if err != nil || len(stale) == 0 {
+		return err
+	}
+
+	for _, runner := range stale {
+		loggerFrom(ctx).Info("Runner was launched from an outdated bootstrap template", "runnerName", runner.RunnerName,
+			"instanceId", runner.InstanceID, "bootstrapHash", runner.BootstrapHash, "currentBootstrapHash", awsInfra.bootstrapHash())
+	}
+	if !config.RecycleStaleRunners {
+		return nil
+	}
+
+	registered, err := gheClient.GetSelfHostedRunners(ctx)
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]SelfHostedRunner, len(registered.Runners))
+	for _, runner := range registered.Runners {
+		byName[runner.Name] = runner
+	}
+
+	for _, runner := range stale {
+		// Runners that have not registered yet may still be bootstrapping
+		ghRunner, ok := byName[runner.RunnerName]
+		if !ok || ghRunner.Busy {
+			continue
+		}
+		if err := gheClient.RemoveRunner(ctx, ghRunner.ID); err != nil {
+			loggerFrom(ctx).Error(err, "Failed to deregister stale runner", "runnerName", runner.RunnerName)
+			continue
+		}
+		if err := awsInfra.terminateLaunchedRunner(ctx, runner); err != nil {
+			loggerFrom(ctx).Error(err, "Failed to terminate stale runner", "runnerName", runner.RunnerName)
+			continue
+		}
+		loggerFrom(ctx).Info("Recycled stale runner", "runnerName", runner.RunnerName)
+	}
+	return nil
+}
+
+// terminateLaunchedRunner terminates a runner's instance through the provider
+func (aws *AWSInfrastructure) terminateLaunchedRunner(ctx context.Context, runner launchedRunner) error {
+	return aws.runnerProvider().TerminateRunner(ctx, runner.InstanceID)
+}
+
+// tagValues returns the tags as a key-value map
+func tagValues(tags []ec2types.Tag) map[string]string {
+	values := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		if tag.Key != nil && tag.Value != nil {
+			values[*tag.Key] = *tag.Value
+		}
+	}
+	return values
+}
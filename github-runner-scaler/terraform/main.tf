@@ -20,9 +20,40 @@ variable "aws_region" {
 }
 
 variable "github_token" {
-  description = "GitHub Personal Access Token"
+  description = "GitHub Personal Access Token, unless github_token_secret_arn is set"
   type        = string
   sensitive   = true
+  default     = ""
+}
+
+variable "github_token_secret_arn" {
+  description = "ARN of a Secrets Manager secret holding the GitHub token, read instead of github_token"
+  type        = string
+  default     = ""
+}
+
+variable "github_token_secret_ttl" {
+  description = "How long warm Lambda invocations reuse the token read from github_token_secret_arn"
+  type        = string
+  default     = "5m"
+}
+
+variable "github_app_id" {
+  description = "ID of a GitHub App to authenticate as instead of a token (with github_app_installation_id and github_app_private_key_secret_arn)"
+  type        = string
+  default     = ""
+}
+
+variable "github_app_installation_id" {
+  description = "ID of the GitHub App's installation on the organization"
+  type        = string
+  default     = ""
+}
+
+variable "github_app_private_key_secret_arn" {
+  description = "ARN of a Secrets Manager secret holding the GitHub App's PEM private key"
+  type        = string
+  default     = ""
 }
 
 variable "github_enterprise_url" {
@@ -56,8 +87,39 @@ variable "ec2_instance_type" {
 }
 
 variable "ec2_ami_id" {
-  description = "AMI ID for EC2 instances"
+  description = "AMI ID for EC2 instances; leave empty to use the AMI of ec2_launch_template_id"
   type        = string
+  default     = ""
+}
+
+variable "runner_architecture" {
+  description = "CPU architecture of the runners, x64 or arm64 for Graviton; ec2_ami_id and ec2_instance_type must match it"
+  type        = string
+  default     = "x64"
+}
+
+variable "ec2_ami_id_arm64" {
+  description = "AMI ID of arm64 pools when runner_architecture is x64"
+  type        = string
+  default     = ""
+}
+
+variable "ec2_instance_type_arm64" {
+  description = "Instance type of arm64 pools when runner_architecture is x64"
+  type        = string
+  default     = "t4g.medium"
+}
+
+variable "runner_os" {
+  description = "Operating system of the runners, linux or windows; ec2_ami_id must match it"
+  type        = string
+  default     = "linux"
+}
+
+variable "ec2_ami_id_windows" {
+  description = "AMI ID of windows pools when runner_os is linux"
+  type        = string
+  default     = ""
 }
 
 variable "ec2_subnet_id" {
@@ -65,6 +127,324 @@ variable "ec2_subnet_id" {
   type        = string
 }
 
+variable "ec2_instance_types" {
+  description = "Optional instance types to spread runner launches over instead of ec2_instance_type"
+  type        = list(string)
+  default     = []
+}
+
+variable "ec2_launch_template_id" {
+  description = "Optional launch template runners launch from; it provides the AMI, block devices, IAM profile and metadata options, and the scaler only adds user data, tags, instance type, subnet and spot options"
+  type        = string
+  default     = ""
+}
+
+variable "ec2_launch_template_version" {
+  description = "Launch template version: a number, $Latest or $Default (empty uses the template's default version)"
+  type        = string
+  default     = ""
+}
+
+variable "ec2_subnet_ids" {
+  description = "Optional subnets to spread runner launches over instead of ec2_subnet_id"
+  type        = list(string)
+  default     = []
+}
+
+variable "on_demand_percentage" {
+  description = "Percentage of runners launched as on-demand instead of spot instances"
+  type        = number
+  default     = 0
+}
+
+variable "on_demand_only" {
+  description = "Launch all runners as on-demand instances, for accounts where spot is not allowed"
+  type        = bool
+  default     = false
+}
+
+variable "spot_allocation_strategy" {
+  description = "How spot launches choose among instance types and subnets: random, lowest-price, capacity-optimized, prioritized (instance types in listed order) or price-capacity-optimized. All but random launch through EC2 Fleet, which falls back to another type or subnet when one is out of spot capacity"
+  type        = string
+  default     = "random"
+}
+
+variable "spot_interruption_behavior" {
+  description = "What EC2 does with interrupted spot runners: terminate, stop or hibernate (stop and hibernate use persistent requests)"
+  type        = string
+  default     = "terminate"
+}
+
+variable "ec2_tenancy" {
+  description = "Tenancy of runner instances: default or dedicated (e.g. for licensing-restricted workloads)"
+  type        = string
+  default     = "default"
+}
+
+variable "ec2_placement_group" {
+  description = "Optional placement group to launch runner instances into"
+  type        = string
+  default     = ""
+}
+
+variable "ec2_ipv6_address_count" {
+  description = "IPv6 addresses assigned to each runner, for dual-stack and IPv6-only subnets"
+  type        = number
+  default     = 0
+}
+
+variable "ec2_associate_public_ip" {
+  description = "Whether runners get a public IPv4 address (\"true\"/\"false\"); empty keeps the subnet default"
+  type        = string
+  default     = ""
+}
+
+variable "additional_security_group_ids" {
+  description = "Security groups attached to runners in addition to the runner security group (e.g. per-team groups)"
+  type        = list(string)
+  default     = []
+}
+
+variable "private_bootstrap" {
+  description = "Bootstrap runners without internet egress: tools come from the AMI, the runner from runner_tarball_s3_uri, AWS APIs via VPC endpoints"
+  type        = bool
+  default     = false
+}
+
+variable "runner_tarball_s3_uri" {
+  description = "Optional s3:// URI of a mirrored actions-runner-linux-x64 tarball, required for private_bootstrap"
+  type        = string
+  default     = ""
+}
+
+variable "runner_tarball_s3_uri_arm64" {
+  description = "Optional s3:// URI of a mirrored actions-runner-linux-arm64 tarball, required for arm64 runners with private_bootstrap"
+  type        = string
+  default     = ""
+}
+
+variable "runner_token_parameter_path" {
+  description = "Optional SSM parameter path, e.g. /github-runner-scaler/runner-tokens, runners read their registration token from instead of the user data"
+  type        = string
+  default     = ""
+}
+
+variable "recycle_stale_runners" {
+  description = "Deregister and terminate idle runners launched from an outdated bootstrap template"
+  type        = bool
+  default     = false
+}
+
+variable "generation_drain_batch" {
+  description = "Idle runners from an earlier pool configuration (AMI, labels, instance types) retired per cycle; 0 disables draining"
+  type        = number
+  default     = 2
+}
+
+variable "diagnostics_s3_uri" {
+  description = "Optional s3:// prefix failed runners upload their _diag directory and cloud-init logs to"
+  type        = string
+  default     = ""
+}
+
+variable "runner_registration_timeout" {
+  description = "Terminate runner instances not registered after this long, e.g. 15m (0s disables); diagnostics are requested first"
+  type        = string
+  default     = "0s"
+}
+
+variable "spot_request_timeout" {
+  description = "Cancel spot requests left open this long, e.g. for lack of capacity or a price ceiling (0s disables)"
+  type        = string
+  default     = "10m"
+}
+
+variable "spot_request_fallback" {
+  description = "Relaunch a runner whose spot request was cancelled: none, instance-type (spot on another type) or on-demand"
+  type        = string
+  default     = "none"
+}
+
+variable "orphan_max_age" {
+  description = "Terminate runner instances up this long without an online runner, e.g. never registered or left running after their job (0s disables)"
+  type        = string
+  default     = "3h"
+}
+
+variable "idle_timeout" {
+  description = "Deregister and terminate runners idle this long, keeping min_runners online (0s disables)"
+  type        = string
+  default     = "0s"
+}
+
+variable "runner_record_retention" {
+  description = "Expire runner records through DynamoDB TTL this long after their last update"
+  type        = string
+  default     = "720h"
+}
+
+variable "session_record_retention" {
+  description = "Expire message session records through DynamoDB TTL this long after their last refresh"
+  type        = string
+  default     = "168h"
+}
+
+variable "debug_hold_hours" {
+  description = "Keep runner instances of failed jobs alive this many hours for inspection, tagged debug-hold (0 disables)"
+  type        = number
+  default     = 0
+}
+
+variable "debug_hold_labels" {
+  description = "Optional: only hold runners carrying one of these labels after a failed job"
+  type        = list(string)
+  default     = []
+}
+
+variable "prewarm_images" {
+  description = "Optional: container images runners pull in the background while they register, e.g. [\"node:20\", \"123456789012.dkr.ecr.us-east-1.amazonaws.com/build:latest\"]; the AMI must provide Docker"
+  type        = list(string)
+  default     = []
+}
+
+variable "actions_cache_proxy_url" {
+  description = "Optional: in-VPC actions cache server (for example an S3-backed github-actions-cache-server) runners use instead of GitHub's cache, e.g. http://cache.internal:3000"
+  type        = string
+  default     = ""
+}
+
+variable "toolcache_efs_id" {
+  description = "Optional: EFS file system with a shared toolcache, mounted read-only under a local overlay at /opt/hostedtoolcache; its mount targets must accept NFS from the runner security group"
+  type        = string
+  default     = ""
+}
+
+variable "toolcache_snapshot_id" {
+  description = "Optional: EBS snapshot of a filesystem labelled toolcache, attached to every runner and mounted like toolcache_efs_id (mutually exclusive with it)"
+  type        = string
+  default     = ""
+}
+
+variable "workspace_cleanup" {
+  description = "Wipe the runner _work directory, Docker containers and volumes, and credential caches between jobs and before the instance is released (always on with stop or hibernate interruption behavior)"
+  type        = bool
+  default     = false
+}
+
+variable "workspace_cleanup_script" {
+  description = "Optional: shell commands run as root after the built-in workspace wipe; setting it enables workspace cleanup"
+  type        = string
+  default     = ""
+}
+
+variable "breakglass_max_ttl" {
+  description = "Longest breakglass access to a runner instance that may be granted, e.g. 4h"
+  type        = string
+  default     = "4h"
+}
+
+variable "chaos_mode" {
+  description = "Randomly terminate idle runners and interrupt busy spot runners to test recovery. Never enable in production"
+  type        = bool
+  default     = false
+}
+
+variable "chaos_idle_termination_percentage" {
+  description = "Chaos mode: chance per evaluation cycle that an idle runner is terminated"
+  type        = number
+  default     = 5
+}
+
+variable "chaos_spot_interruption_percentage" {
+  description = "Chaos mode: chance per evaluation cycle that a busy spot runner is interrupted"
+  type        = number
+  default     = 2
+}
+
+variable "interruption_job_threshold" {
+  description = "Jobs lost to spot interruptions over the report window before a pool is flagged as an on-demand candidate"
+  type        = number
+  default     = 5
+}
+
+variable "replace_interrupted_runners" {
+  description = "Launch a replacement runner as soon as a busy runner's spot instance gets its interruption warning"
+  type        = bool
+  default     = true
+}
+
+variable "interruption_report_schedule" {
+  description = "EventBridge schedule of the spot interruption impact report"
+  type        = string
+  default     = "rate(1 day)"
+}
+
+variable "metrics_namespace" {
+  description = "CloudWatch namespace of the metrics each invocation publishes through EMF log lines"
+  type        = string
+  default     = "GitHubRunnerScaler"
+}
+
+variable "log_level" {
+  description = "Lambda log level: debug, info, warn or error"
+  type        = string
+  default     = "info"
+}
+
+variable "log_format" {
+  description = "Lambda log format: json for CloudWatch Logs Insights, or console"
+  type        = string
+  default     = "json"
+}
+
+variable "launch_failures_alarm_threshold" {
+  description = "Runner launch failures across all pools within 5 minutes that raise the LaunchFailures alarm"
+  type        = number
+  default     = 1
+}
+
+variable "alarm_sns_topic_arn" {
+  description = "Optional SNS topic notified by the scaler's CloudWatch alarms; no alarms are created without it"
+  type        = string
+  default     = ""
+}
+
+variable "pushgateway_url" {
+  description = "Optional Prometheus Pushgateway URL that receives the metrics of each invocation, in addition to the CloudWatch EMF log lines"
+  type        = string
+  default     = ""
+}
+
+variable "enable_breakglass_ssm" {
+  description = "Register runners with Session Manager so breakglass access can be granted over SSM"
+  type        = bool
+  default     = false
+}
+
+variable "probe_workflow" {
+  description = "Optional owner/repo/workflow-file[@ref] dispatched by the probe-ami action, see sample-workflows/ami-probe.yml"
+  type        = string
+  default     = ""
+}
+
+variable "require_probed_ami" {
+  description = "Only launch runners from AMIs tagged ReadyForPool:<pool> by a passed AMI probe"
+  type        = bool
+  default     = false
+}
+
+variable "ec2_spot_prices" {
+  description = "Maximum spot price per instance type (\"default\" covers the rest); unlisted types bid up to the on-demand price"
+  type        = map(string)
+  default     = {}
+}
+
+variable "ec2_tags" {
+  description = "Extra tags applied to runner instances and spot requests"
+  type        = map(string)
+  default     = {}
+}
+
 variable "ec2_key_pair_name" {
   description = "EC2 Key Pair name"
   type        = string
@@ -76,13 +456,74 @@ variable "runner_labels" {
   default     = ["self-hosted", "linux", "x64", "lambda-managed"]
 }
 
+variable "excluded_labels" {
+  description = "Jobs carrying any of these labels are never provisioned for (e.g. no-spot)"
+  type        = list(string)
+  default     = []
+}
+
+variable "runner_name_prefix" {
+  description = "Value of {prefix} in the runner name template"
+  type        = string
+  default     = "lambda-runner"
+}
+
+variable "runner_name_template" {
+  description = "Runner and EC2 instance name template; placeholders {prefix} {scaleset} {pool} {id} {timestamp}, {id} is required"
+  type        = string
+  default     = "{prefix}-{pool}-{timestamp}-{id}"
+}
+
 variable "cleanup_offline_runners" {
   description = "Automatically cleanup offline runners"
   type        = bool
   default     = true
 }
 
-# DynamoDB table for tracking runners
+variable "webhook_secret" {
+  description = "Secret used to verify workflow_job webhook deliveries (webhook deliveries are rejected while it is empty)"
+  type        = string
+  default     = ""
+  sensitive   = true
+}
+
+variable "scale_down_delay" {
+  description = "Delay after a scale-up before idle launched runners are removed (0s disables)"
+  type        = string
+  default     = "0s"
+}
+
+variable "appconfig_application" {
+  description = "AppConfig application holding the scaling policy (leave empty to use environment values only)"
+  type        = string
+  default     = ""
+}
+
+variable "appconfig_environment" {
+  description = "AppConfig environment of the scaling policy"
+  type        = string
+  default     = ""
+}
+
+variable "appconfig_profile" {
+  description = "AppConfig configuration profile of the scaling policy"
+  type        = string
+  default     = ""
+}
+
+variable "appconfig_extension_layer_arn" {
+  description = "ARN of the AWS AppConfig Lambda extension layer for the deployment region"
+  type        = string
+  default     = ""
+}
+
+variable "self_scheduling" {
+  description = "Let the Lambda speed up its schedule while jobs are queued and slow it down when idle"
+  type        = bool
+  default     = false
+}
+
+# DynamoDB table for tracking runners (records expire through TTL)
 resource "aws_dynamodb_table" "github_runners" {
   name           = "github-runners"
   billing_mode   = "PAY_PER_REQUEST"
@@ -115,12 +556,17 @@ resource "aws_dynamodb_table" "github_runners" {
     projection_type = "ALL"
   }
 
+  ttl {
+    attribute_name = "expires_at"
+    enabled        = true
+  }
+
   tags = {
     Name = "GitHub Runners"
   }
 }
 
-# DynamoDB table for sessions
+# DynamoDB table for sessions (abandoned sessions expire through TTL)
 resource "aws_dynamodb_table" "github_sessions" {
   name           = "github-runners-sessions"
   billing_mode   = "PAY_PER_REQUEST"
@@ -131,11 +577,74 @@ resource "aws_dynamodb_table" "github_sessions" {
     type = "S"
   }
 
+  ttl {
+    attribute_name = "expires_at"
+    enabled        = true
+  }
+
   tags = {
     Name = "GitHub Runner Sessions"
   }
 }
 
+# DynamoDB table for the invocation lock (stale leases expire through TTL)
+resource "aws_dynamodb_table" "github_locks" {
+  name           = "github-runners-locks"
+  billing_mode   = "PAY_PER_REQUEST"
+  hash_key       = "lock_id"
+
+  attribute {
+    name = "lock_id"
+    type = "S"
+  }
+
+  ttl {
+    attribute_name = "expires_at"
+    enabled        = true
+  }
+
+  tags = {
+    Name = "GitHub Runner Scaler Locks"
+  }
+}
+
+# DynamoDB table for blue/green AMI rollouts, one item per pool
+resource "aws_dynamodb_table" "github_ami_rollouts" {
+  name           = "github-runners-ami-rollouts"
+  billing_mode   = "PAY_PER_REQUEST"
+  hash_key       = "pool"
+
+  attribute {
+    name = "pool"
+    type = "S"
+  }
+
+  tags = {
+    Name = "GitHub Runner AMI Rollouts"
+  }
+}
+
+# DynamoDB table for spot interruptions and the jobs they hit, kept for a month
+resource "aws_dynamodb_table" "github_interruptions" {
+  name           = "github-runners-interruptions"
+  billing_mode   = "PAY_PER_REQUEST"
+  hash_key       = "instance_id"
+
+  attribute {
+    name = "instance_id"
+    type = "S"
+  }
+
+  ttl {
+    attribute_name = "expires_at"
+    enabled        = true
+  }
+
+  tags = {
+    Name = "GitHub Runner Spot Interruptions"
+  }
+}
+
 # Security group for EC2 instances
 resource "aws_security_group" "github_runners" {
   name_prefix = "github-runners-"
@@ -211,6 +720,9 @@ resource "aws_iam_policy" "lambda_policy" {
         Resource = [
           aws_dynamodb_table.github_runners.arn,
           aws_dynamodb_table.github_sessions.arn,
+          aws_dynamodb_table.github_locks.arn,
+          aws_dynamodb_table.github_ami_rollouts.arn,
+          aws_dynamodb_table.github_interruptions.arn,
           "${aws_dynamodb_table.github_runners.arn}/index/*",
           "${aws_dynamodb_table.github_sessions.arn}/index/*"
         ]
@@ -218,12 +730,27 @@ resource "aws_iam_policy" "lambda_policy" {
       {
         Effect = "Allow"
         Action = [
-          "ec2:RequestSpotInstances",
+          "ec2:RunInstances",
+          "ec2:CreateFleet",
+          "ec2:CreateLaunchTemplate",
+          "ec2:DeleteLaunchTemplate",
+          "ec2:CancelSpotInstanceRequests",
+          "ec2:DescribeSpotPriceHistory",
+          "ec2:GetSpotPlacementScores",
+          "ec2:DescribeSubnets",
           "ec2:DescribeSpotInstanceRequests",
           "ec2:DescribeInstances",
           "ec2:TerminateInstances",
+          "ec2:StopInstances",
           "ec2:CreateTags",
-          "ec2:DescribeTags"
+          "ec2:DeleteTags",
+          "ec2:DescribeTags",
+          "ec2:DescribeImages",
+          "ec2:CreateSecurityGroup",
+          "ec2:AuthorizeSecurityGroupIngress",
+          "ec2:DeleteSecurityGroup",
+          "ec2:DescribeSecurityGroups",
+          "ec2:ModifyNetworkInterfaceAttribute"
         ]
         Resource = "*"
       },
@@ -233,7 +760,32 @@ resource "aws_iam_policy" "lambda_policy" {
           "events:PutRule",
           "events:PutTargets",
           "events:DeleteRule",
-          "events:RemoveTargets"
+          "events:RemoveTargets",
+          "events:DescribeRule"
+        ]
+        Resource = "*"
+      },
+      {
+        Effect = "Allow"
+        Action = [
+          "lambda:AddPermission"
+        ]
+        Resource = "*"
+      },
+      {
+        Effect = "Allow"
+        Action = [
+          "appconfig:StartConfigurationSession",
+          "appconfig:GetLatestConfiguration"
+        ]
+        Resource = "*"
+      },
+      {
+        Effect = "Allow"
+        Action = [
+          "ssm:SendCommand",
+          "ssm:DescribeSessions",
+          "ssm:TerminateSession"
         ]
         Resource = "*"
       },
@@ -243,6 +795,17 @@ resource "aws_iam_policy" "lambda_policy" {
           "iam:PassRole"
         ]
         Resource = aws_iam_role.ec2_role.arn
+      },
+      {
+        # EC2 Fleet launches need its service-linked role, created on first use
+        Effect   = "Allow"
+        Action   = "iam:CreateServiceLinkedRole"
+        Resource = "arn:aws:iam::*:role/aws-service-role/ec2fleet.amazonaws.com/AWSServiceRoleForEC2Fleet"
+        Condition = {
+          StringEquals = {
+            "iam:AWSServiceName" = "ec2fleet.amazonaws.com"
+          }
+        }
       }
     ]
   })
@@ -300,7 +863,8 @@ resource "aws_iam_policy" "ec2_policy" {
         Effect = "Allow"
         Action = [
           "ec2:TerminateInstances",
-          "ec2:DescribeInstances"
+          "ec2:DescribeInstances",
+          "ec2:CreateTags"
         ]
         Resource = "*"
         Condition = {
@@ -313,6 +877,109 @@ resource "aws_iam_policy" "ec2_policy" {
   })
 }
 
+# Read access to the runner tarball mirror used for private bootstrap
+resource "aws_iam_role_policy" "ec2_runner_mirror" {
+  count = length(compact([var.runner_tarball_s3_uri, var.runner_tarball_s3_uri_arm64])) > 0 ? 1 : 0
+  name  = "github-runner-tarball-mirror"
+  role  = aws_iam_role.ec2_role.id
+
+  policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [
+      {
+        Effect   = "Allow"
+        Action   = ["s3:GetObject"]
+        Resource = [for uri in compact([var.runner_tarball_s3_uri, var.runner_tarball_s3_uri_arm64]) : "arn:aws:s3:::${trimprefix(uri, "s3://")}"]
+      }
+    ]
+  })
+}
+
+# The GitHub token or GitHub App private key read from Secrets Manager
+resource "aws_iam_role_policy" "lambda_github_token" {
+  count = var.github_token_secret_arn != "" || var.github_app_private_key_secret_arn != "" ? 1 : 0
+  name  = "github-token-secret"
+  role  = aws_iam_role.lambda_role.id
+
+  policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [
+      {
+        Effect   = "Allow"
+        Action   = ["secretsmanager:GetSecretValue"]
+        Resource = compact([var.github_token_secret_arn, var.github_app_private_key_secret_arn])
+      }
+    ]
+  })
+}
+
+# Registration tokens handed to runners through Parameter Store: the Lambda writes them,
+# the runner reads and deletes its own at boot
+resource "aws_iam_role_policy" "lambda_runner_tokens" {
+  count = var.runner_token_parameter_path != "" ? 1 : 0
+  name  = "github-runner-tokens"
+  role  = aws_iam_role.lambda_role.id
+
+  policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [
+      {
+        Effect   = "Allow"
+        Action   = ["ssm:PutParameter", "ssm:DeleteParameter"]
+        Resource = "arn:aws:ssm:${data.aws_region.current.name}:${data.aws_caller_identity.current.account_id}:parameter${var.runner_token_parameter_path}/*"
+      }
+    ]
+  })
+}
+
+resource "aws_iam_role_policy" "ec2_runner_tokens" {
+  count = var.runner_token_parameter_path != "" ? 1 : 0
+  name  = "github-runner-tokens"
+  role  = aws_iam_role.ec2_role.id
+
+  policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [
+      {
+        Effect   = "Allow"
+        Action   = ["ssm:GetParameter", "ssm:DeleteParameter"]
+        Resource = "arn:aws:ssm:${data.aws_region.current.name}:${data.aws_caller_identity.current.account_id}:parameter${var.runner_token_parameter_path}/*"
+      }
+    ]
+  })
+}
+
+# Diagnostics uploads from failed runners, triggered by the bootstrap or through SSM
+resource "aws_iam_role_policy" "ec2_runner_diagnostics" {
+  count = var.diagnostics_s3_uri != "" ? 1 : 0
+  name  = "github-runner-diagnostics"
+  role  = aws_iam_role.ec2_role.id
+
+  policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [
+      {
+        Effect   = "Allow"
+        Action   = ["s3:PutObject"]
+        Resource = "arn:aws:s3:::${trimsuffix(trimprefix(var.diagnostics_s3_uri, "s3://"), "/")}/*"
+      }
+    ]
+  })
+}
+
+# Pulls of pre-warmed images from ECR
+resource "aws_iam_role_policy_attachment" "ec2_ecr_read" {
+  count      = length(var.prewarm_images) > 0 ? 1 : 0
+  role       = aws_iam_role.ec2_role.name
+  policy_arn = "arn:aws:iam::aws:policy/AmazonEC2ContainerRegistryReadOnly"
+}
+
+resource "aws_iam_role_policy_attachment" "ec2_ssm" {
+  count      = var.diagnostics_s3_uri != "" || var.enable_breakglass_ssm ? 1 : 0
+  role       = aws_iam_role.ec2_role.name
+  policy_arn = "arn:aws:iam::aws:policy/AmazonSSMManagedInstanceCore"
+}
+
 resource "aws_iam_role_policy_attachment" "ec2_policy" {
   role       = aws_iam_role.ec2_role.name
   policy_arn = aws_iam_policy.ec2_policy.arn
@@ -335,22 +1002,98 @@ resource "aws_lambda_function" "github_runner_scaler" {
   architectures    = ["x86_64"]
   source_code_hash = filebase64sha256("github-runner-scaler.zip")
 
+  # The AppConfig extension serves the scaling policy on localhost:2772
+  layers = var.appconfig_extension_layer_arn != "" ? [var.appconfig_extension_layer_arn] : []
+
   environment {
     variables = {
       GITHUB_TOKEN                 = var.github_token
+      GITHUB_TOKEN_SECRET_ARN      = var.github_token_secret_arn
+      GITHUB_TOKEN_SECRET_TTL      = var.github_token_secret_ttl
+      GITHUB_APP_ID                = var.github_app_id
+      GITHUB_APP_INSTALLATION_ID   = var.github_app_installation_id
+      GITHUB_APP_PRIVATE_KEY_SECRET_ARN = var.github_app_private_key_secret_arn
       GITHUB_ENTERPRISE_URL        = var.github_enterprise_url
       ORGANIZATION_NAME            = var.organization_name
       MIN_RUNNERS                  = var.min_runners
       MAX_RUNNERS                  = var.max_runners
       EC2_INSTANCE_TYPE            = var.ec2_instance_type
       EC2_AMI_ID                   = var.ec2_ami_id
+      RUNNER_ARCHITECTURE          = var.runner_architecture
+      EC2_AMI_ID_ARM64             = var.ec2_ami_id_arm64
+      EC2_INSTANCE_TYPE_ARM64      = var.ec2_instance_type_arm64
+      RUNNER_OS                    = var.runner_os
+      EC2_AMI_ID_WINDOWS           = var.ec2_ami_id_windows
       EC2_SUBNET_ID                = var.ec2_subnet_id
       EC2_SECURITY_GROUP_ID        = aws_security_group.github_runners.id
+      EC2_SECURITY_GROUP_IDS       = jsonencode(var.additional_security_group_ids)
       EC2_KEY_PAIR_NAME            = var.ec2_key_pair_name
-      EC2_SPOT_PRICE               = "0.05"
+      EC2_LAUNCH_TEMPLATE_ID       = var.ec2_launch_template_id
+      EC2_LAUNCH_TEMPLATE_VERSION  = var.ec2_launch_template_version
+      EC2_SPOT_PRICES              = jsonencode(var.ec2_spot_prices)
+      EC2_INSTANCE_TYPES           = jsonencode(var.ec2_instance_types)
+      EC2_SUBNET_IDS               = jsonencode(var.ec2_subnet_ids)
+      ON_DEMAND_PERCENTAGE         = var.on_demand_percentage
+      ON_DEMAND_ONLY               = var.on_demand_only
+      SPOT_ALLOCATION_STRATEGY     = var.spot_allocation_strategy
+      SPOT_INTERRUPTION_BEHAVIOR   = var.spot_interruption_behavior
+      EC2_TENANCY                  = var.ec2_tenancy
+      EC2_PLACEMENT_GROUP          = var.ec2_placement_group
+      EC2_IPV6_ADDRESS_COUNT       = var.ec2_ipv6_address_count
+      EC2_ASSOCIATE_PUBLIC_IP      = var.ec2_associate_public_ip
+      PRIVATE_BOOTSTRAP            = var.private_bootstrap
+      RUNNER_TARBALL_S3_URI        = var.runner_tarball_s3_uri
+      RUNNER_TOKEN_PARAMETER_PATH  = var.runner_token_parameter_path
+      RUNNER_TARBALL_S3_URI_ARM64  = var.runner_tarball_s3_uri_arm64
+      RECYCLE_STALE_RUNNERS        = var.recycle_stale_runners
+      GENERATION_DRAIN_BATCH       = var.generation_drain_batch
+      PROBE_WORKFLOW               = var.probe_workflow
+      EC2_INSTANCE_PROFILE         = aws_iam_instance_profile.ec2_profile.name
+      DIAGNOSTICS_S3_URI           = var.diagnostics_s3_uri
+      RUNNER_REGISTRATION_TIMEOUT  = var.runner_registration_timeout
+      ORPHAN_MAX_AGE               = var.orphan_max_age
+      SPOT_REQUEST_TIMEOUT         = var.spot_request_timeout
+      SPOT_REQUEST_FALLBACK        = var.spot_request_fallback
+      IDLE_TIMEOUT                 = var.idle_timeout
+      RUNNER_RECORD_RETENTION      = var.runner_record_retention
+      SESSION_RECORD_RETENTION     = var.session_record_retention
+      DEBUG_HOLD_HOURS             = var.debug_hold_hours
+      DEBUG_HOLD_LABELS            = jsonencode(var.debug_hold_labels)
+      PREWARM_IMAGES               = jsonencode(var.prewarm_images)
+      ACTIONS_CACHE_PROXY_URL      = var.actions_cache_proxy_url
+      TOOLCACHE_EFS_ID             = var.toolcache_efs_id
+      TOOLCACHE_SNAPSHOT_ID        = var.toolcache_snapshot_id
+      WORKSPACE_CLEANUP            = var.workspace_cleanup
+      WORKSPACE_CLEANUP_SCRIPT     = var.workspace_cleanup_script
+      BREAKGLASS_MAX_TTL           = var.breakglass_max_ttl
+      CHAOS_MODE                   = var.chaos_mode
+      CHAOS_IDLE_TERMINATION_PCT   = var.chaos_idle_termination_percentage
+      CHAOS_SPOT_INTERRUPT_PCT     = var.chaos_spot_interruption_percentage
+      REQUIRE_PROBED_AMI           = var.require_probed_ami
+      PUSHGATEWAY_URL              = var.pushgateway_url
+      METRICS_NAMESPACE            = var.metrics_namespace
+      LOG_LEVEL                    = var.log_level
+      LOG_FORMAT                   = var.log_format
+      EC2_TAGS                     = jsonencode(var.ec2_tags)
       DYNAMODB_TABLE_NAME          = aws_dynamodb_table.github_runners.name
       RUNNER_LABELS                = jsonencode(var.runner_labels)
+      EXCLUDED_LABELS              = jsonencode(var.excluded_labels)
+      RUNNER_NAME_PREFIX           = var.runner_name_prefix
+      RUNNER_NAME_TEMPLATE         = var.runner_name_template
       CLEANUP_OFFLINE_RUNNERS      = var.cleanup_offline_runners
+      SESSIONS_TABLE_NAME          = aws_dynamodb_table.github_sessions.name
+      LOCK_TABLE_NAME              = aws_dynamodb_table.github_locks.name
+      ROLLOUTS_TABLE_NAME          = aws_dynamodb_table.github_ami_rollouts.name
+      INTERRUPTIONS_TABLE_NAME     = aws_dynamodb_table.github_interruptions.name
+      INTERRUPTION_JOB_THRESHOLD   = var.interruption_job_threshold
+      REPLACE_INTERRUPTED_RUNNERS  = var.replace_interrupted_runners
+      WEBHOOK_SECRET               = var.webhook_secret
+      SELF_SCHEDULING              = var.self_scheduling
+      SCHEDULE_RULE_NAME           = "github-runner-scaler-schedule"
+      SCALE_DOWN_DELAY             = var.scale_down_delay
+      APPCONFIG_APPLICATION        = var.appconfig_application
+      APPCONFIG_ENVIRONMENT        = var.appconfig_environment
+      APPCONFIG_PROFILE            = var.appconfig_profile
     }
   }
 
@@ -371,6 +1114,11 @@ resource "aws_cloudwatch_event_rule" "github_runner_scaler_schedule" {
   name                = "github-runner-scaler-schedule"
   description         = "Trigger GitHub Runner Scaler Lambda every 60 seconds"
   schedule_expression = "rate(1 minute)"
+
+  # The Lambda adjusts the rate itself when SELF_SCHEDULING is enabled
+  lifecycle {
+    ignore_changes = [schedule_expression]
+  }
 }
 
 resource "aws_cloudwatch_event_target" "lambda_target" {
@@ -387,6 +1135,129 @@ resource "aws_lambda_permission" "allow_eventbridge" {
   source_arn    = aws_cloudwatch_event_rule.github_runner_scaler_schedule.arn
 }
 
+# Alarm on runners failing to launch, from the metrics written by each invocation
+resource "aws_cloudwatch_metric_alarm" "launch_failures" {
+  count = var.alarm_sns_topic_arn != "" ? 1 : 0
+
+  alarm_name          = "github-runner-scaler-launch-failures"
+  alarm_description   = "GitHub runner scaler failed to launch runners"
+  namespace           = var.metrics_namespace
+  metric_name         = "LaunchFailures"
+  statistic           = "Sum"
+  period              = 300
+  evaluation_periods  = 1
+  threshold           = var.launch_failures_alarm_threshold
+  comparison_operator = "GreaterThanOrEqualToThreshold"
+  treat_missing_data  = "notBreaching"
+  alarm_actions       = [var.alarm_sns_topic_arn]
+  ok_actions          = [var.alarm_sns_topic_arn]
+}
+
+# Spot interruption warnings of runners, correlated with the jobs they were running
+resource "aws_cloudwatch_event_rule" "spot_interruptions" {
+  name        = "github-runner-scaler-spot-interruptions"
+  description = "Send EC2 spot interruption warnings to the GitHub Runner Scaler Lambda"
+
+  event_pattern = jsonencode({
+    source      = ["aws.ec2"]
+    detail-type = ["EC2 Spot Instance Interruption Warning"]
+  })
+}
+
+resource "aws_cloudwatch_event_target" "spot_interruptions" {
+  rule      = aws_cloudwatch_event_rule.spot_interruptions.name
+  target_id = "GitHubRunnerScalerSpotInterruptions"
+  arn       = aws_lambda_function.github_runner_scaler.arn
+}
+
+resource "aws_lambda_permission" "allow_spot_interruptions" {
+  statement_id  = "AllowSpotInterruptionsFromEventBridge"
+  action        = "lambda:InvokeFunction"
+  function_name = aws_lambda_function.github_runner_scaler.function_name
+  principal     = "events.amazonaws.com"
+  source_arn    = aws_cloudwatch_event_rule.spot_interruptions.arn
+}
+
+# Periodic spot interruption impact report (jobs lost and re-run latency per pool)
+resource "aws_cloudwatch_event_rule" "interruption_report" {
+  name                = "github-runner-scaler-interruption-report"
+  description         = "Run the GitHub Runner Scaler spot interruption impact report"
+  schedule_expression = var.interruption_report_schedule
+}
+
+resource "aws_cloudwatch_event_target" "interruption_report" {
+  rule      = aws_cloudwatch_event_rule.interruption_report.name
+  target_id = "GitHubRunnerScalerInterruptionReport"
+  arn       = aws_lambda_function.github_runner_scaler.arn
+  input     = jsonencode({ action = "interruption-report" })
+}
+
+resource "aws_lambda_permission" "allow_interruption_report" {
+  statement_id  = "AllowInterruptionReportFromEventBridge"
+  action        = "lambda:InvokeFunction"
+  function_name = aws_lambda_function.github_runner_scaler.function_name
+  principal     = "events.amazonaws.com"
+  source_arn    = aws_cloudwatch_event_rule.interruption_report.arn
+}
+
+# One-shot scale-down check rules are created by the Lambda after each scale-up
+resource "aws_lambda_permission" "allow_scale_down_checks" {
+  statement_id  = "AllowScaleDownChecksFromEventBridge"
+  action        = "lambda:InvokeFunction"
+  function_name = aws_lambda_function.github_runner_scaler.function_name
+  principal     = "events.amazonaws.com"
+  source_arn    = "arn:aws:events:${data.aws_region.current.name}:${data.aws_caller_identity.current.account_id}:rule/github-runner-scaler-scale-down-*"
+}
+
+# One-shot breakglass revocation rules are created by the Lambda when access is granted
+resource "aws_lambda_permission" "allow_breakglass_revocations" {
+  statement_id  = "AllowBreakglassRevocationsFromEventBridge"
+  action        = "lambda:InvokeFunction"
+  function_name = aws_lambda_function.github_runner_scaler.function_name
+  principal     = "events.amazonaws.com"
+  source_arn    = "arn:aws:events:${data.aws_region.current.name}:${data.aws_caller_identity.current.account_id}:rule/github-runner-scaler-breakglass-*"
+}
+
+# Attach to the developers allowed breakglass sessions. Sessions are only allowed to runner
+# instances the Lambda tagged with an unexpired breakglass grant.
+resource "aws_iam_policy" "breakglass_ssm" {
+  count = var.enable_breakglass_ssm ? 1 : 0
+  name  = "github-runner-breakglass-ssm"
+
+  policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [
+      {
+        Effect   = "Allow"
+        Action   = ["ssm:StartSession"]
+        Resource = "arn:aws:ec2:${data.aws_region.current.name}:${data.aws_caller_identity.current.account_id}:instance/*"
+        Condition = {
+          StringEquals = {
+            "ssm:resourceTag/ManagedBy" = "github-runner-scaler-lambda"
+          }
+          Null = {
+            "ssm:resourceTag/breakglass" = "false"
+          }
+        }
+      },
+      {
+        Effect   = "Allow"
+        Action   = ["ssm:StartSession"]
+        Resource = "arn:aws:ssm:${data.aws_region.current.name}::document/SSM-SessionManagerRunShell"
+      },
+      {
+        Effect   = "Allow"
+        Action   = ["ssm:TerminateSession", "ssm:ResumeSession"]
+        Resource = "arn:aws:ssm:*:*:session/$${aws:username}-*"
+      }
+    ]
+  })
+}
+
+data "aws_region" "current" {}
+
+data "aws_caller_identity" "current" {}
+
 # Outputs
 output "lambda_function_arn" {
   description = "ARN of the Lambda function"
@@ -398,6 +1269,11 @@ output "dynamodb_table_name" {
   value       = aws_dynamodb_table.github_runners.name
 }
 
+output "breakglass_ssm_policy_arn" {
+  description = "Policy allowing Session Manager access to runners with a breakglass grant"
+  value       = var.enable_breakglass_ssm ? aws_iam_policy.breakglass_ssm[0].arn : null
+}
+
 output "security_group_id" {
   description = "Security Group ID for runners"
   value       = aws_security_group.github_runners.id
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+const (
+	// toolcacheDir is where runners find the shared toolcache. Jobs write what is missing
+	// from it to a local overlay, so the shared copy stays read-only.
+	toolcacheDir = "/opt/hostedtoolcache"
+
+	// toolcacheDevice is the device name the toolcache snapshot volume is attached as.
+	// The bootstrap mounts it by its filesystem label, which survives NVMe renaming.
+	toolcacheDevice = "/dev/sdt"
+
+	// toolcacheLabel is the filesystem label toolcache snapshots must carry
+	toolcacheLabel = "toolcache"
+)
+
+var (
+	// cacheProxyURLPattern only admits URLs that are safe to write into the bootstrap script
+	cacheProxyURLPattern = regexp.MustCompile(`^https?://[a-zA-Z0-9._:/-]+$`)
+	efsIDPattern         = regexp.MustCompile(`^fs-[0-9a-f]+$`)
+	snapshotIDPattern    = regexp.MustCompile(`^snap-[0-9a-f]+$`)
+)
+
+// validateRunnerCaches checks the actions cache proxy and toolcache settings
+func validateRunnerCaches(cacheProxyURL, efsID, snapshotID string) error {
+	if cacheProxyURL != "" && !cacheProxyURLPattern.MatchString(cacheProxyURL) {
+		return fmt.Errorf("actions cache proxy %q is not an http(s) URL", cacheProxyURL)
+	}
+	if efsID != "" && !efsIDPattern.MatchString(efsID) {
+		return fmt.Errorf("invalid toolcache EFS file system ID %q", efsID)
+	}
+	if snapshotID != "" && !snapshotIDPattern.MatchString(snapshotID) {
+		return fmt.Errorf("invalid toolcache snapshot ID %q", snapshotID)
+	}
+	if efsID != "" && snapshotID != "" {
+		return fmt.Errorf("a toolcache EFS file system and snapshot are mutually exclusive")
+	}
+	return nil
+}
+
+// toolcacheBlockDevices returns the volume created from the toolcache snapshot for a
+// launch, or nil when no snapshot is configured. It is deleted with the instance.
+func (aws *AWSInfrastructure) toolcacheBlockDevices() []ec2types.BlockDeviceMapping {
+	if aws.config.ToolcacheSnapshotID == "" {
+		return nil
+	}
+	return []ec2types.BlockDeviceMapping{{
+		DeviceName: aws.String(toolcacheDevice),
+		Ebs: &ec2types.EbsBlockDevice{
+			SnapshotId:          aws.String(aws.config.ToolcacheSnapshotID),
+			VolumeType:          ec2types.VolumeTypeGp3,
+			DeleteOnTermination: aws.Bool(true),
+		},
+	}}
+}
+
+// toolcacheScript mounts the shared toolcache read-only, from EFS or from the volume made
+// of the toolcache snapshot, and lays a writable local overlay over it at toolcacheDir, so
+// setup-node, setup-java and the like find their versions already installed. It runs as
+// root after the runner user exists.
+func (c Config) toolcacheScript() string {
+	var mount string
+	switch {
+	case c.ToolcacheEFSID != "":
+		install := "apt-get install -y nfs-common\n"
+		if c.PrivateBootstrap {
+			install = "command -v mount.nfs4 > /dev/null || { echo \"mount.nfs4 is not installed in the AMI\"; exit 1; }\n"
+		}
+		mount = fmt.Sprintf(`%smount -t nfs4 -o ro,nfsvers=4.1,rsize=1048576,hard,timeo=600,retrans=2,noresvport %s.efs.$REGION.amazonaws.com:/ /mnt/toolcache
+`, install, c.ToolcacheEFSID)
+	case c.ToolcacheSnapshotID != "":
+		mount = fmt.Sprintf(`for i in $(seq 60); do [ -e /dev/disk/by-label/%s ] && break; sleep 2; done
+mount -o ro,noatime LABEL=%s /mnt/toolcache
+`, toolcacheLabel, toolcacheLabel)
+	default:
+		return ""
+	}
+	return fmt.Sprintf(`# Shared read-only toolcache with a local writable overlay
+mkdir -p /mnt/toolcache /var/lib/toolcache/upper /var/lib/toolcache/work %s
+%smount -t overlay overlay -o lowerdir=/mnt/toolcache,upperdir=/var/lib/toolcache/upper,workdir=/var/lib/toolcache/work %s
+chown runner:runner %s
+`, toolcacheDir, mount, toolcacheDir, toolcacheDir)
+}
+
+// runnerCacheEnv points the runner at the shared toolcache and the actions cache proxy. The
+// runner takes the cache URL from the server for every job, so the name it reads it under
+// is patched in the worker, leaving ACTIONS_CACHE_URL from the environment in force. It
+// runs as the runner user before the runner starts.
+func (c Config) runnerCacheEnv() string {
+	var env string
+	if c.ToolcacheEFSID != "" || c.ToolcacheSnapshotID != "" {
+		env += fmt.Sprintf("export RUNNER_TOOL_CACHE=%s AGENT_TOOLSDIRECTORY=%s\n", toolcacheDir, toolcacheDir)
+	}
+	if c.ActionsCacheProxyURL != "" {
+		env += fmt.Sprintf(`# Use the in-VPC actions cache proxy instead of the server's cache
+sed -i 's/\x41\x00\x43\x00\x54\x00\x49\x00\x4F\x00\x4E\x00\x53\x00\x5F\x00\x43\x00\x41\x00\x43\x00\x48\x00\x45\x00\x5F\x00\x55\x00\x52\x00\x4C\x00/\x41\x00\x43\x00\x54\x00\x49\x00\x4F\x00\x4E\x00\x53\x00\x5F\x00\x43\x00\x41\x00\x43\x00\x48\x00\x45\x00\x5F\x00\x4F\x00\x52\x00\x4C\x00/g' bin/Runner.Worker.dll
+export ACTIONS_CACHE_URL=%s/
+`, strings.TrimSuffix(c.ActionsCacheProxyURL, "/"))
+	}
+	return env
+}
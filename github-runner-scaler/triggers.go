@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// invocationKind identifies what triggered the Lambda
+type invocationKind string
+
+const (
+	invocationSchedule invocationKind = "schedule"
+	invocationWebhook  invocationKind = "webhook"
+	invocationManual   invocationKind = "manual"
+
+	invocationSpotInterruption invocationKind = "spot-interruption"
+)
+
+// Manual invocation actions
+const (
+	manualActionEvaluate         = "evaluate"
+	manualActionScale            = "scale"
+	manualActionScaleDownCheck   = "scale-down-check"
+	manualActionProbeAMI         = "probe-ami"
+	manualActionBreakglass       = "breakglass"
+	manualActionBreakglassRevoke = "breakglass-revoke"
+	manualActionAMICanary        = "ami-canary"
+	manualActionAMIPromote       = "ami-promote"
+	manualActionAMIRollback      = "ami-rollback"
+
+	manualActionInterruptionReport = "interruption-report"
+)
+
+// ManualInvocation is the payload for a manual invoke, e.g. {"action":"scale","runners":5}.
+// Scale-down checks scheduled after a scale-up use the same payload.
+type ManualInvocation struct {
+	Action      string   `json:"action"`
+	Runners     int      `json:"runners,omitempty"`
+	RunnerNames []string `json:"runner_names,omitempty"`
+	Pool        string   `json:"pool,omitempty"`
+	Rule        string   `json:"rule,omitempty"`
+	AMI         string   `json:"ami,omitempty"`
+	InstanceID  string   `json:"instance_id,omitempty"`
+	Mode        string   `json:"mode,omitempty"` // breakglass: ssh or ssm
+	CIDR        string   `json:"cidr,omitempty"`
+	TTL         string   `json:"ttl,omitempty"`
+	Requester   string   `json:"requester,omitempty"`
+	Reason      string   `json:"reason,omitempty"`
+	Percentage  int      `json:"percentage,omitempty"` // ami-canary: share of new runners on the canary AMI
+}
+
+// webhookRequest holds the fields shared by API Gateway REST (v1) and HTTP API (v2) proxy events
+type webhookRequest struct {
+	Headers         map[string]string `json:"headers"`
+	Body            string            `json:"body"`
+	IsBase64Encoded bool              `json:"isBase64Encoded"`
+}
+
+// workflowJobEvent is the subset of the workflow_job webhook payload the scaler needs
+type workflowJobEvent struct {
+	Action      string `json:"action"`
+	WorkflowJob struct {
+		ID     int64    `json:"id"`
+		Labels []string `json:"labels"`
+	} `json:"workflow_job"`
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+}
+
+// detectInvocation works out which trigger produced the raw event
+func detectInvocation(raw json.RawMessage) invocationKind {
+	var probe struct {
+		Source         string          `json:"source"`
+		DetailType     string          `json:"detail-type"`
+		HTTPMethod     string          `json:"httpMethod"`
+		RouteKey       string          `json:"routeKey"`
+		RequestContext json.RawMessage `json:"requestContext"`
+	}
+	if err := json.Unmarshal(raw, &probe); err != nil {
+		return invocationManual
+	}
+
+	switch {
+	case probe.Source == "aws.ec2" && probe.DetailType == spotInterruptionDetailType:
+		return invocationSpotInterruption
+	case probe.Source == "aws.events" || probe.DetailType == "Scheduled Event":
+		return invocationSchedule
+	case probe.HTTPMethod != "" || probe.RouteKey != "" || len(probe.RequestContext) > 0:
+		return invocationWebhook
+	default:
+		return invocationManual
+	}
+}
+
+// handleWebhookInvocation verifies a workflow_job delivery and runs a scaling cycle for queued jobs.
+// Without WEBHOOK_SECRET deliveries cannot be verified, so none is accepted.
+func handleWebhookInvocation(ctx context.Context, raw json.RawMessage, run func(context.Context) error, config Config) events.APIGatewayProxyResponse {
+	if config.WebhookSecret == "" {
+		loggerFrom(ctx).Info("Rejected webhook delivery, WEBHOOK_SECRET is not set")
+		return webhookResponse(http.StatusServiceUnavailable, "webhook scaling is not configured")
+	}
+
+	var req webhookRequest
+	if err := json.Unmarshal(raw, &req); err != nil {
+		return webhookResponse(http.StatusBadRequest, "invalid request")
+	}
+
+	body := []byte(req.Body)
+	if req.IsBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(req.Body)
+		if err != nil {
+			return webhookResponse(http.StatusBadRequest, "invalid body encoding")
+		}
+		body = decoded
+	}
+
+	if !validWebhookSignature(body, header(req.Headers, "X-Hub-Signature-256"), config.WebhookSecret) {
+		loggerFrom(ctx).Info("Rejected webhook delivery with an invalid signature")
+		return webhookResponse(http.StatusUnauthorized, "invalid signature")
+	}
+
+	if eventType := header(req.Headers, "X-GitHub-Event"); eventType != "workflow_job" {
+		return webhookResponse(http.StatusOK, fmt.Sprintf("ignored %s event", eventType))
+	}
+
+	var event workflowJobEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return webhookResponse(http.StatusBadRequest, "invalid workflow_job payload")
+	}
+
+	loggerFrom(ctx).Info("Received workflow_job webhook", "action", event.Action, "jobId", event.WorkflowJob.ID,
+		"repository", event.Repository.FullName, "labels", event.WorkflowJob.Labels)
+
+	if event.Action != "queued" {
+		return webhookResponse(http.StatusOK, "no scaling needed")
+	}
+	if !NewLabelMatcher(config.RunnerLabels, jobLabelOptions.WithExclusions(config.ExcludedLabels)).Matches(event.WorkflowJob.Labels) {
+		return webhookResponse(http.StatusOK, "job labels do not match this scaler")
+	}
+
+	if err := run(ctx); err != nil {
+		loggerFrom(ctx).Error(err, "Webhook-triggered scaling failed")
+		return webhookResponse(http.StatusInternalServerError, "scaling failed")
+	}
+	return webhookResponse(http.StatusAccepted, "scaling evaluated")
+}
+
+// validWebhookSignature checks the X-Hub-Signature-256 HMAC of the payload
+func validWebhookSignature(body []byte, signature, secret string) bool {
+	if !strings.HasPrefix(signature, "sha256=") {
+		return false
+	}
+	expected, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// header looks up a header case-insensitively; HTTP APIs lower-case header names, REST APIs do not
+func header(headers map[string]string, name string) string {
+	for key, value := range headers {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
+}
+
+func webhookResponse(statusCode int, message string) events.APIGatewayProxyResponse {
+	body, _ := json.Marshal(map[string]string{"message": message})
+	return events.APIGatewayProxyResponse{
+		StatusCode: statusCode,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       string(body),
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestValidWebhookSignature(t *testing.T) {
+	const secret = "webhook-secret"
+	body := []byte(`{"action":"queued","workflow_job":{"id":42}}`)
+	sign := func(secret string) string {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+
+	tests := []struct {
+		name      string
+		signature string
+		want      bool
+	}{
+		{"valid", sign(secret), true},
+		{"wrong secret", sign("other-secret"), false},
+		{"missing", "", false},
+		{"sha1 prefix", strings.Replace(sign(secret), "sha256=", "sha1=", 1), false},
+		{"not hex", "sha256=zz", false},
+		{"truncated", sign(secret)[:20], false},
+	}
+	for _, tt := range tests {
+		if got := validWebhookSignature(body, tt.signature, secret); got != tt.want {
+			t.Errorf("%s: validWebhookSignature() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
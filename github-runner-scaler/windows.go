@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Runner operating systems, named like the runner's own labels
+const (
+	runnerOSLinux   = "linux"
+	runnerOSWindows = "windows"
+)
+
+// validateRunnerOS checks RUNNER_OS or a pool's os
+func validateRunnerOS(runnerOS string) error {
+	switch runnerOS {
+	case runnerOSLinux, runnerOSWindows:
+		return nil
+	}
+	return fmt.Errorf("unknown runner OS %q (want %s or %s)", runnerOS, runnerOSLinux, runnerOSWindows)
+}
+
+// forRunnerOS returns a copy of the configuration launching runners of the given operating
+// system, with its label in place of the other one's. Windows pools of a Linux scaler boot
+// EC2_AMI_ID_WINDOWS unless they set their own AMI.
+func (c Config) forRunnerOS(runnerOS string) Config {
+	osConfig := c
+	osConfig.RunnerOS = runnerOS
+	osConfig.RunnerLabels = exclusiveLabel(c.RunnerLabels, runnerOS, runnerOSLinux, runnerOSWindows)
+	if runnerOS == runnerOSWindows && c.RunnerOS == runnerOSLinux && c.EC2AMIWindows != "" {
+		osConfig.EC2AMI = c.EC2AMIWindows
+	}
+	return osConfig
+}
+
+// checkRunnerOS checks that Windows runners can be bootstrapped: there is no arm64 Windows
+// runner build for them here, and without internet egress they cannot download the runner
+func (c Config) checkRunnerOS() error {
+	if c.RunnerOS != runnerOSWindows {
+		return nil
+	}
+	if c.RunnerArchitecture != architectureX64 {
+		return fmt.Errorf("windows runners must be %s", architectureX64)
+	}
+	if c.PrivateBootstrap {
+		return fmt.Errorf("windows runners do not support PRIVATE_BOOTSTRAP")
+	}
+	return nil
+}
+
+// generateWindowsUserData renders the PowerShell user data of a Windows runner: it
+// downloads the runner zip, registers an ephemeral runner running as a Windows service,
+// waits for the service to stop after its job and terminates the instance. The AMI must
+// provide the AWS Tools for PowerShell, as the Amazon Windows AMIs do. Diagnostics uploads,
+// workspace cleanup, image pre-warming, toolcaches and the actions cache proxy are only
+// available to Linux runners.
+func (aws *AWSInfrastructure) generateWindowsUserData(runnerName, registrationToken, labels string) string {
+	zip := fmt.Sprintf("actions-runner-win-x64-%s.zip", runnerVersion)
+	return fmt.Sprintf(`<powershell>
+$ErrorActionPreference = "Stop"
+$ProgressPreference = "SilentlyContinue"
+
+$Imds = "http://169.254.169.254/latest/meta-data"
+$Region = Invoke-RestMethod "$Imds/placement/region"
+$InstanceId = Invoke-RestMethod "$Imds/instance-id"
+
+# Download and install GitHub Actions runner
+$RunnerDir = "C:\actions-runner"
+New-Item -ItemType Directory -Force -Path $RunnerDir | Out-Null
+Set-Location $RunnerDir
+Invoke-WebRequest -UseBasicParsing -Uri "https://github.com/actions/runner/releases/download/v%s/%s" -OutFile "%s"
+Expand-Archive -Path "%s" -DestinationPath $RunnerDir -Force
+
+%s
+
+# Configure runner for GHE as a service, which exits after its one job
+.\config.cmd --unattended --url %s/orgs/%s --token $RunnerToken --name %s --labels %s --work _work --replace --ephemeral --runasservice
+
+# Signal completion
+$LogGroup = "/aws/ec2/github-runner"
+try { New-CWLLogGroup -LogGroupName $LogGroup -Region $Region } catch {}
+try { New-CWLLogStream -LogGroupName $LogGroup -LogStreamName "%s" -Region $Region } catch {}
+try {
+    Write-CWLLogEvent -LogGroupName $LogGroup -LogStreamName "%s" -Region $Region -LogEvent @{
+        Timestamp = (Get-Date).ToUniversalTime(); Message = "Runner %s started successfully"
+    }
+} catch {}
+
+# Keep instance alive while runner is working
+$ErrorActionPreference = "Continue"
+do {
+    Start-Sleep -Seconds 30
+    $Service = Get-Service -Name "actions.runner.*" -ErrorAction SilentlyContinue
+} while ($Service -and $Service.Status -ne "Stopped")
+
+%s
+# Self-terminate when runner job is done
+Remove-EC2Instance -InstanceId $InstanceId -Region $Region -Force
+</powershell>
+`,
+		runnerVersion, zip, zip, zip,
+		aws.config.windowsRunnerTokenScript(runnerName, registrationToken),
+		aws.config.GitHubEnterpriseURL,
+		aws.config.OrganizationName,
+		runnerName,
+		labels,
+		runnerName,
+		runnerName,
+		runnerName,
+		aws.config.windowsDebugHoldScript())
+}
+
+// windowsDebugHoldScript is debugHoldScript for Windows runners
+func (c Config) windowsDebugHoldScript() string {
+	hours := c.debugHoldHoursFor()
+	if hours == 0 {
+		return ""
+	}
+	return fmt.Sprintf(`# Debug hold: keep the instance of a failed job for inspection until the deadline
+if (Select-String -Path "$RunnerDir\_diag\Worker_*.log" -Pattern "Job result after all job steps finish: Failed" -SimpleMatch -Quiet) {
+    $Deadline = (Get-Date).ToUniversalTime().AddHours(%d).ToString("yyyy-MM-ddTHH:mm:ssZ")
+    try { New-EC2Tag -Resource $InstanceId -Tag @{Key = "%s"; Value = $Deadline} -Region $Region } catch {}
+    Write-Output "Job failed, holding instance for debugging until $Deadline"
+    Start-Sleep -Seconds %d
+}
+`, hours, debugHoldTag, hours*3600)
+}
+
+// defaultRunnerLabels returns the labels runners register when none are configured
+func (c Config) defaultRunnerLabels() string {
+	return strings.Join([]string{"self-hosted", c.RunnerOS, c.RunnerArchitecture}, ",")
+}
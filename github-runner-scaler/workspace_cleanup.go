@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+const (
+	// workspaceCleanupCommand is installed on runner instances when workspace cleanup is
+	// enabled. It runs as root and wipes what a job leaves behind.
+	workspaceCleanupCommand = "/usr/local/bin/cleanup-runner-workspace"
+
+	// workspaceCleanupHook is the runner's job-completed hook, which runs the cleanup command
+	workspaceCleanupHook = "/usr/local/bin/runner-job-completed.sh"
+)
+
+// workspaceCleanupEnabled reports whether runners wipe their workspace between jobs and
+// before the instance is released. Instances of persistent spot requests come back after
+// an interruption with the disk of the job they were running, so they always clean up.
+func (c Config) workspaceCleanupEnabled() bool {
+	return c.WorkspaceCleanup || c.WorkspaceCleanupScript != "" || c.spotRequestType() == ec2types.SpotInstanceTypePersistent
+}
+
+// workspaceCleanupScript installs the cleanup command and the job-completed hook that runs
+// it. The built-in wipe removes the _work directory, Docker containers, volumes and
+// registry logins, and the credential caches of git and common package managers and cloud
+// CLIs, so one repository's secrets can't reach the next job on the instance.
+// WORKSPACE_CLEANUP_SCRIPT runs after it. It runs as root.
+func (c Config) workspaceCleanupScript() string {
+	if !c.workspaceCleanupEnabled() {
+		return ""
+	}
+	return fmt.Sprintf(`# Wipe the workspace, Docker state and credential caches between jobs
+cat > %s << 'CLEANUP'
+#!/bin/bash
+find /home/runner/_work -mindepth 1 -maxdepth 1 -exec rm -rf {} + 2> /dev/null
+if command -v docker > /dev/null; then
+    docker ps -aq | xargs -r docker rm -f > /dev/null
+    docker volume ls -q | xargs -r docker volume rm -f > /dev/null
+    docker network prune -f > /dev/null
+fi
+sudo -u runner git credential-cache exit 2> /dev/null
+for HOME_DIR in /home/runner /root; do
+    rm -rf $HOME_DIR/.docker/config.json $HOME_DIR/.git-credentials $HOME_DIR/.netrc \
+        $HOME_DIR/.npmrc $HOME_DIR/.yarnrc $HOME_DIR/.pypirc $HOME_DIR/.config/pip \
+        $HOME_DIR/.m2/settings.xml $HOME_DIR/.gradle/gradle.properties $HOME_DIR/.nuget/NuGet \
+        $HOME_DIR/.aws $HOME_DIR/.azure $HOME_DIR/.config/gcloud $HOME_DIR/.kube \
+        $HOME_DIR/.config/gh $HOME_DIR/.cache/git
+done
+%s
+exit 0
+CLEANUP
+chmod +x %s
+printf '#!/bin/bash\nsudo -n %s\n' > %s
+chmod +x %s
+`, workspaceCleanupCommand, c.WorkspaceCleanupScript, workspaceCleanupCommand, workspaceCleanupCommand, workspaceCleanupHook, workspaceCleanupHook)
+}
+
+// workspaceCleanupHookEnv points the runner's job-completed hook at the cleanup command. It
+// runs as the runner user before the runner starts.
+func (c Config) workspaceCleanupHookEnv() string {
+	if !c.workspaceCleanupEnabled() {
+		return ""
+	}
+	return fmt.Sprintf("export ACTIONS_RUNNER_HOOK_JOB_COMPLETED=%s\n", workspaceCleanupHook)
+}
+
+// workspaceCleanupReleaseScript wipes the instance once more after the runner exits, so an
+// instance that outlives its runner (a failed termination, or a stopped spot instance that
+// is started again) holds nothing of the last job. It runs after the debug hold, which
+// needs the workspace.
+func (c Config) workspaceCleanupReleaseScript() string {
+	if !c.workspaceCleanupEnabled() {
+		return ""
+	}
+	return fmt.Sprintf("# Wipe the workspace before releasing the instance\n%s || true\n", workspaceCleanupCommand)
+}
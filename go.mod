@@ -0,0 +1,5 @@
+module github.com/Anshuman2121/actionsspot
+
+go 1.21
+
+require github.com/google/uuid v1.4.0
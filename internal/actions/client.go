@@ -0,0 +1,364 @@
+package actions
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	scaleSetEndpoint = "_apis/runtime/runnerscalesets"
+	runnerEndpoint   = "_apis/distributedtask/pools/0/agents"
+	apiVersion       = "6.0-preview"
+)
+
+// Client talks to the Actions Service. Connect must succeed before any other call.
+type Client struct {
+	httpClient  *http.Client
+	userAgent   string
+	authRetries int
+
+	serviceURL string
+	adminToken string
+}
+
+// NewClient creates an Actions Service client. The HTTP client's timeout must exceed the
+// message queue long poll of about 50 seconds when GetMessage is used. authRetries is how
+// often an admin connection rejected as unauthorized is retried with backoff, which rides
+// out registration tokens that have not propagated yet.
+func NewClient(httpClient *http.Client, userAgent string, authRetries int) *Client {
+	return &Client{
+		httpClient:  httpClient,
+		userAgent:   userAgent,
+		authRetries: authRetries,
+	}
+}
+
+// Connect obtains the Actions Service URL and an admin token with a runner registration
+// token. gitHubURL is the GitHub Enterprise Server URL and configURL the organization,
+// enterprise or repository URL runners register against.
+func (c *Client) Connect(ctx context.Context, gitHubURL, configURL, registrationToken string) error {
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(map[string]string{"url": configURL, "runner_event": "register"}); err != nil {
+		return fmt.Errorf("failed to encode body: %w", err)
+	}
+	endpoint := strings.TrimSuffix(gitHubURL, "/") + "/api/v3/actions/runner-registration"
+
+	for retry := 0; ; retry++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body.Bytes()))
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "RemoteAuth "+registrationToken)
+		req.Header.Set("User-Agent", c.userAgent)
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to get Actions Service admin connection: %w", err)
+		}
+		if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+			var connection AdminConnection
+			err := json.NewDecoder(resp.Body).Decode(&connection)
+			resp.Body.Close()
+			if err != nil {
+				return fmt.Errorf("failed to decode Actions Service admin connection: %w", err)
+			}
+			if connection.ActionsServiceURL == nil || connection.AdminToken == nil {
+				return fmt.Errorf("invalid Actions Service connection response - missing URL or token")
+			}
+			c.serviceURL = strings.TrimSuffix(*connection.ActionsServiceURL, "/")
+			c.adminToken = *connection.AdminToken
+			return nil
+		}
+
+		err = ParseErrorResponse(resp)
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusNotFound || strings.Contains(err.Error(), "<html") || strings.Contains(err.Error(), "<!DOCTYPE") {
+			return fmt.Errorf("Actions Service API not supported on this GitHub Enterprise Server version. "+
+				"The endpoint '/actions/runner-registration' returned HTML instead of JSON. "+
+				"Please upgrade to a GHES version that supports Actions Service API (3.5+) or use traditional runners: %w", err)
+		}
+		if (resp.StatusCode != http.StatusUnauthorized && resp.StatusCode != http.StatusForbidden) || retry >= c.authRetries {
+			return err
+		}
+
+		// Exponential backoff with jitter, like the official controller
+		delay := 500*time.Millisecond*(1<<(retry+1)) + time.Duration(rand.Intn(1000))*time.Millisecond
+		if delay > 20*time.Second {
+			delay = 20 * time.Second
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// ServiceURL returns the Actions Service URL, without a trailing slash
+func (c *Client) ServiceURL() string {
+	return c.serviceURL
+}
+
+// AdminToken returns the admin token obtained by Connect
+func (c *Client) AdminToken() string {
+	return c.adminToken
+}
+
+// ListScaleSets lists the runner scale sets
+func (c *Client) ListScaleSets(ctx context.Context) ([]RunnerScaleSet, error) {
+	var result struct {
+		Count     int              `json:"count"`
+		ScaleSets []RunnerScaleSet `json:"value"`
+	}
+	endpoint := fmt.Sprintf("%s/%s?api-version=%s", c.serviceURL, scaleSetEndpoint, apiVersion)
+	if err := c.do(ctx, http.MethodGet, endpoint, c.adminToken, nil, &result); err != nil {
+		return nil, fmt.Errorf("failed to list scale sets: %w", err)
+	}
+	return result.ScaleSets, nil
+}
+
+// GetScaleSetByName looks up a runner scale set by name, returning nil when there is none
+func (c *Client) GetScaleSetByName(ctx context.Context, name string) (*RunnerScaleSet, error) {
+	var result struct {
+		Count     int              `json:"count"`
+		ScaleSets []RunnerScaleSet `json:"value"`
+	}
+	endpoint := fmt.Sprintf("%s/%s?name=%s&api-version=%s", c.serviceURL, scaleSetEndpoint, url.QueryEscape(name), apiVersion)
+	if err := c.do(ctx, http.MethodGet, endpoint, c.adminToken, nil, &result); err != nil {
+		return nil, fmt.Errorf("failed to get scale set %s: %w", name, err)
+	}
+
+	for _, scaleSet := range result.ScaleSets {
+		if scaleSet.Name == name {
+			return &scaleSet, nil
+		}
+	}
+	return nil, nil
+}
+
+// CreateScaleSet creates a runner scale set
+func (c *Client) CreateScaleSet(ctx context.Context, scaleSet *RunnerScaleSet) (*RunnerScaleSet, error) {
+	var created RunnerScaleSet
+	endpoint := fmt.Sprintf("%s/%s?api-version=%s", c.serviceURL, scaleSetEndpoint, apiVersion)
+	if err := c.do(ctx, http.MethodPost, endpoint, c.adminToken, scaleSet, &created); err != nil {
+		return nil, fmt.Errorf("failed to create scale set %s: %w", scaleSet.Name, err)
+	}
+	if created.ID == 0 || created.Name == "" {
+		return nil, fmt.Errorf("invalid scale set response: ID=%d, Name='%s'", created.ID, created.Name)
+	}
+	return &created, nil
+}
+
+// GetAcquirableJobs gets the jobs the scale set can acquire
+func (c *Client) GetAcquirableJobs(ctx context.Context, scaleSetID int) (*AcquirableJobList, error) {
+	jobs := &AcquirableJobList{Jobs: []AcquirableJob{}}
+	endpoint := fmt.Sprintf("%s/%s/%d/acquirablejobs?api-version=%s", c.serviceURL, scaleSetEndpoint, scaleSetID, apiVersion)
+	if err := c.do(ctx, http.MethodGet, endpoint, c.adminToken, nil, jobs); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// GenerateJitRunnerConfig registers a runner in the scale set and returns its just-in-time
+// configuration
+func (c *Client) GenerateJitRunnerConfig(ctx context.Context, scaleSetID int, setting *RunnerScaleSetJitRunnerSetting) (*RunnerScaleSetJitRunnerConfig, error) {
+	var config RunnerScaleSetJitRunnerConfig
+	endpoint := fmt.Sprintf("%s/%s/%d/generatejitconfig?api-version=%s", c.serviceURL, scaleSetEndpoint, scaleSetID, apiVersion)
+	if err := c.do(ctx, http.MethodPost, endpoint, c.adminToken, setting, &config); err != nil {
+		return nil, fmt.Errorf("failed to generate JIT config for runner %s: %w", setting.Name, err)
+	}
+	if config.EncodedJITConfig == "" {
+		return nil, fmt.Errorf("empty JIT config for runner %s", setting.Name)
+	}
+	return &config, nil
+}
+
+// RemoveRunner removes a runner registered in a scale set, such as one registered for a JIT
+// config that never started. A runner that is already gone is not an error.
+func (c *Client) RemoveRunner(ctx context.Context, runnerID int64) error {
+	endpoint := fmt.Sprintf("%s/%s/%d?api-version=%s", c.serviceURL, runnerEndpoint, runnerID, apiVersion)
+	err := c.do(ctx, http.MethodDelete, endpoint, c.adminToken, nil, nil)
+	if err != nil && !IsStatus(err, http.StatusNotFound) {
+		return fmt.Errorf("failed to remove runner %d: %w", runnerID, err)
+	}
+	return nil
+}
+
+// CreateMessageSession creates a message session for a scale set. The Actions Service
+// answers 409 when the scale set already has an active session.
+func (c *Client) CreateMessageSession(ctx context.Context, scaleSetID int, owner string) (*RunnerScaleSetSession, error) {
+	var session RunnerScaleSetSession
+	endpoint := fmt.Sprintf("%s/%s/%d/sessions?api-version=%s", c.serviceURL, scaleSetEndpoint, scaleSetID, apiVersion)
+	if err := c.do(ctx, http.MethodPost, endpoint, c.adminToken, &RunnerScaleSetSession{OwnerName: owner}, &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// RefreshMessageSession refreshes a message session, which renews its message queue token
+// and validates it is still alive
+func (c *Client) RefreshMessageSession(ctx context.Context, scaleSetID int, sessionID *uuid.UUID) (*RunnerScaleSetSession, error) {
+	if sessionID == nil {
+		return nil, fmt.Errorf("session ID is nil")
+	}
+
+	var session RunnerScaleSetSession
+	endpoint := fmt.Sprintf("%s/%s/%d/sessions/%s?api-version=%s", c.serviceURL, scaleSetEndpoint, scaleSetID, sessionID, apiVersion)
+	if err := c.do(ctx, http.MethodPatch, endpoint, c.adminToken, nil, &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// DeleteMessageSession deletes a message session
+func (c *Client) DeleteMessageSession(ctx context.Context, scaleSetID int, sessionID *uuid.UUID) error {
+	if sessionID == nil {
+		return nil
+	}
+	endpoint := fmt.Sprintf("%s/%s/%d/sessions/%s?api-version=%s", c.serviceURL, scaleSetEndpoint, scaleSetID, sessionID, apiVersion)
+	return c.do(ctx, http.MethodDelete, endpoint, c.adminToken, nil, nil)
+}
+
+// GetMessage long-polls the message queue for the message after lastMessageID, returning
+// nil when none arrived. maxCapacity tells the service how many more runners the scale set
+// can take. A 401 means the message queue token expired and the session must be refreshed.
+func (c *Client) GetMessage(ctx context.Context, messageQueueURL, accessToken string, lastMessageID int64, maxCapacity int) (*RunnerScaleSetMessage, error) {
+	if maxCapacity < 0 {
+		return nil, fmt.Errorf("maxCapacity must be greater than or equal to 0")
+	}
+	u, err := url.Parse(messageQueueURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse message queue URL: %w", err)
+	}
+	if lastMessageID > 0 {
+		params := u.Query()
+		params.Set("lastMessageId", strconv.FormatInt(lastMessageID, 10))
+		u.RawQuery = params.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json; api-version="+apiVersion)
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("User-Agent", c.userAgent)
+	req.Header.Set("X-GitHub-Actions-Scale-Set-Max-Capacity", strconv.Itoa(maxCapacity))
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusAccepted {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, ParseErrorResponse(resp)
+	}
+
+	var message RunnerScaleSetMessage
+	if err := json.NewDecoder(resp.Body).Decode(&message); err != nil {
+		return nil, fmt.Errorf("failed to decode message: %w", err)
+	}
+	return &message, nil
+}
+
+// DeleteMessage deletes a processed message from the message queue
+func (c *Client) DeleteMessage(ctx context.Context, messageQueueURL, accessToken string, messageID int64) error {
+	if messageQueueURL == "" || messageID == 0 {
+		return nil
+	}
+	u, err := url.Parse(messageQueueURL)
+	if err != nil {
+		return fmt.Errorf("failed to parse message queue URL: %w", err)
+	}
+	params := u.Query()
+	params.Set("messageId", strconv.FormatInt(messageID, 10))
+	u.RawQuery = params.Encode()
+
+	return c.do(ctx, http.MethodDelete, u.String(), accessToken, nil, nil)
+}
+
+// AcquireJobs acquires jobs by runner request ID and returns the IDs that were acquired
+func (c *Client) AcquireJobs(ctx context.Context, scaleSetID int, accessToken string, requestIDs []int64) ([]int64, error) {
+	var result struct {
+		Value []int64 `json:"value"`
+	}
+	endpoint := fmt.Sprintf("%s/%s/%d/jobs?api-version=%s", c.serviceURL, scaleSetEndpoint, scaleSetID, apiVersion)
+	if err := c.do(ctx, http.MethodPost, endpoint, accessToken, map[string][]int64{"requestIds": requestIDs}, &result); err != nil {
+		return nil, fmt.Errorf("failed to acquire jobs: %w", err)
+	}
+	return result.Value, nil
+}
+
+// AcquireJob acquires a single job through the acquireJobUrl supplied with the job. It
+// returns false without an error when the job is no longer available to this scale set.
+func (c *Client) AcquireJob(ctx context.Context, acquireJobURL, accessToken string) (bool, error) {
+	err := c.do(ctx, http.MethodPost, acquireJobURL, accessToken, nil, nil)
+	switch {
+	case err == nil:
+		return true, nil
+	case IsStatus(err, http.StatusNotFound), IsStatus(err, http.StatusConflict):
+		// Another scale set acquired the job first, or it was cancelled
+		return false, nil
+	default:
+		return false, fmt.Errorf("failed to acquire job: %w", err)
+	}
+}
+
+// do sends an authenticated request to the Actions Service and decodes the JSON response
+// into out. Non-success statuses are returned as ActionsError.
+func (c *Client) do(ctx context.Context, method, endpoint, token string, payload, out interface{}) error {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("failed to marshal payload: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", c.userAgent)
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return ParseErrorResponse(resp)
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,342 @@
+package actions
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+const (
+	testRegistrationToken = "registration-token"
+	testAdminToken        = "admin-token"
+)
+
+// newTestClient serves the runner registration endpoint and, under /service, the Actions
+// Service API handled by service. The returned client is connected.
+func newTestClient(t *testing.T, service http.HandlerFunc) (*Client, *httptest.Server) {
+	t.Helper()
+	mux := http.NewServeMux()
+	var srv *httptest.Server
+	mux.HandleFunc("/api/v3/actions/runner-registration", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "RemoteAuth "+testRegistrationToken {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"url": srv.URL + "/service/", "token": testAdminToken})
+	})
+	mux.HandleFunc("/service/", func(w http.ResponseWriter, r *http.Request) {
+		if auth := r.Header.Get("Authorization"); !strings.HasPrefix(auth, "Bearer ") {
+			t.Errorf("%s %s has Authorization %q, want a bearer token", r.Method, r.URL.Path, auth)
+		}
+		service(w, r)
+	})
+	srv = httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	client := NewClient(srv.Client(), "test", 0)
+	if err := client.Connect(context.Background(), srv.URL, "https://github.example.com/org", testRegistrationToken); err != nil {
+		t.Fatalf("Connect() = %v", err)
+	}
+	return client, srv
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func TestConnect(t *testing.T) {
+	client, srv := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {})
+	if got, want := client.ServiceURL(), srv.URL+"/service"; got != want {
+		t.Errorf("ServiceURL() = %q, want %q", got, want)
+	}
+	if client.AdminToken() != testAdminToken {
+		t.Errorf("AdminToken() = %q, want %q", client.AdminToken(), testAdminToken)
+	}
+}
+
+func TestConnectRetriesUnauthorized(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The first attempt is rejected, like a registration token that has not propagated
+		if calls.Add(1) == 1 {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"message": "Bad credentials"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"url": "https://pipelines.example.com/abc", "token": "refreshed"})
+	}))
+	defer srv.Close()
+
+	client := NewClient(srv.Client(), "test", 1)
+	if err := client.Connect(context.Background(), srv.URL, "https://github.example.com/org", testRegistrationToken); err != nil {
+		t.Fatalf("Connect() = %v", err)
+	}
+	if calls.Load() != 2 || client.AdminToken() != "refreshed" {
+		t.Errorf("Connect() made %d calls and got token %q, want 2 calls and token refreshed", calls.Load(), client.AdminToken())
+	}
+}
+
+func TestConnectErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   string
+	}{
+		{"unauthorized", http.StatusUnauthorized, `{"message":"Bad credentials"}`, "Bad credentials"},
+		{"not supported", http.StatusNotFound, "<html>Not Found</html>", "not supported"},
+		{"missing token", http.StatusOK, `{"url":"https://pipelines.example.com/abc"}`, "missing URL or token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			err := NewClient(srv.Client(), "test", 0).Connect(context.Background(), srv.URL, "https://github.example.com/org", testRegistrationToken)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Connect() = %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestScaleSets(t *testing.T) {
+	var created RunnerScaleSet
+	client, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+testAdminToken {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Query().Get("api-version") != apiVersion {
+			t.Errorf("%s %s without api-version", r.Method, r.URL)
+		}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/service/"+scaleSetEndpoint:
+			sets := []RunnerScaleSet{{ID: 1, Name: "linux"}, {ID: 2, Name: "linux-gpu"}}
+			if name := r.URL.Query().Get("name"); name != "" {
+				// The service matches by prefix, GetScaleSetByName must pick the exact name
+				sets = []RunnerScaleSet{{ID: 2, Name: "linux-gpu"}}
+				if name == "linux" {
+					sets = append(sets, RunnerScaleSet{ID: 1, Name: "linux"})
+				}
+			}
+			writeJSON(w, http.StatusOK, map[string]interface{}{"count": len(sets), "value": sets})
+		case r.Method == http.MethodPost && r.URL.Path == "/service/"+scaleSetEndpoint:
+			if err := json.NewDecoder(r.Body).Decode(&created); err != nil {
+				t.Errorf("failed to decode scale set: %v", err)
+			}
+			created.ID = 3
+			writeJSON(w, http.StatusOK, created)
+		case r.Method == http.MethodPost && r.URL.Path == "/service/"+scaleSetEndpoint+"/3/generatejitconfig":
+			writeJSON(w, http.StatusOK, RunnerScaleSetJitRunnerConfig{Runner: &RunnerReference{ID: 42, Name: "runner-1"}, EncodedJITConfig: "jit"})
+		case r.Method == http.MethodDelete && r.URL.Path == "/service/"+runnerEndpoint+"/42":
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodDelete && r.URL.Path == "/service/"+runnerEndpoint+"/43":
+			writeJSON(w, http.StatusNotFound, map[string]string{"message": "runner not found"})
+		default:
+			t.Errorf("unexpected %s %s", r.Method, r.URL)
+			w.WriteHeader(http.StatusNotImplemented)
+		}
+	})
+	ctx := context.Background()
+
+	sets, err := client.ListScaleSets(ctx)
+	if err != nil || len(sets) != 2 {
+		t.Fatalf("ListScaleSets() = %v, %v, want 2 scale sets", sets, err)
+	}
+
+	set, err := client.GetScaleSetByName(ctx, "linux")
+	if err != nil || set == nil || set.ID != 1 {
+		t.Errorf("GetScaleSetByName(linux) = %+v, %v, want scale set 1", set, err)
+	}
+	set, err = client.GetScaleSetByName(ctx, "windows")
+	if err != nil || set != nil {
+		t.Errorf("GetScaleSetByName(windows) = %+v, %v, want none", set, err)
+	}
+
+	set, err = client.CreateScaleSet(ctx, &RunnerScaleSet{
+		Name:          "windows",
+		RunnerGroupID: 1,
+		Labels:        []Label{{Name: "windows", Type: "System"}},
+		RunnerSetting: RunnerSetting{Ephemeral: true, DisableUpdate: true},
+	})
+	if err != nil || set.ID != 3 || set.Name != "windows" {
+		t.Fatalf("CreateScaleSet() = %+v, %v, want scale set 3", set, err)
+	}
+	if !created.RunnerSetting.Ephemeral || len(created.Labels) != 1 {
+		t.Errorf("CreateScaleSet() sent %+v, want the ephemeral scale set with its label", created)
+	}
+
+	config, err := client.GenerateJitRunnerConfig(ctx, 3, &RunnerScaleSetJitRunnerSetting{Name: "runner-1"})
+	if err != nil || config.Runner.ID != 42 || config.EncodedJITConfig != "jit" {
+		t.Errorf("GenerateJitRunnerConfig() = %+v, %v, want runner 42", config, err)
+	}
+
+	if err := client.RemoveRunner(ctx, 42); err != nil {
+		t.Errorf("RemoveRunner(42) = %v", err)
+	}
+	if err := client.RemoveRunner(ctx, 43); err != nil {
+		t.Errorf("RemoveRunner() of a runner that is gone = %v, want nil", err)
+	}
+}
+
+func TestCreateScaleSetInvalidResponse(t *testing.T) {
+	client, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, RunnerScaleSet{})
+	})
+	if _, err := client.CreateScaleSet(context.Background(), &RunnerScaleSet{Name: "linux"}); err == nil {
+		t.Error("CreateScaleSet() accepted a scale set without ID and name")
+	}
+}
+
+func TestServiceErrors(t *testing.T) {
+	client, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-GitHub-Request-Id", "req-1")
+		writeJSON(w, http.StatusConflict, map[string]string{"message": "scale set already has a session"})
+	})
+
+	_, err := client.CreateMessageSession(context.Background(), 1, "owner")
+	if !IsStatus(err, http.StatusConflict) {
+		t.Fatalf("CreateMessageSession() = %v, want a 409 ActionsError", err)
+	}
+	if !strings.Contains(err.Error(), "req-1") || !strings.Contains(err.Error(), "already has a session") {
+		t.Errorf("CreateMessageSession() = %v, want the activity ID and message", err)
+	}
+}
+
+func TestMessageSessions(t *testing.T) {
+	sessionID := uuid.New()
+	var deleted atomic.Bool
+	client, srv := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		sessions := "/service/" + scaleSetEndpoint + "/1/sessions"
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == sessions:
+			var request RunnerScaleSetSession
+			json.NewDecoder(r.Body).Decode(&request)
+			writeJSON(w, http.StatusOK, RunnerScaleSetSession{
+				SessionID:               &sessionID,
+				OwnerName:               request.OwnerName,
+				MessageQueueURL:         "http://" + r.Host + "/queue",
+				MessageQueueAccessToken: "queue-token-1",
+			})
+		case r.Method == http.MethodPatch && r.URL.Path == sessions+"/"+sessionID.String():
+			writeJSON(w, http.StatusOK, RunnerScaleSetSession{SessionID: &sessionID, MessageQueueAccessToken: "queue-token-2"})
+		case r.Method == http.MethodDelete && r.URL.Path == sessions+"/"+sessionID.String():
+			deleted.Store(true)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("unexpected %s %s", r.Method, r.URL)
+			w.WriteHeader(http.StatusNotImplemented)
+		}
+	})
+	ctx := context.Background()
+
+	session, err := client.CreateMessageSession(ctx, 1, "owner")
+	if err != nil || *session.SessionID != sessionID || session.OwnerName != "owner" || session.MessageQueueURL != srv.URL+"/queue" {
+		t.Fatalf("CreateMessageSession() = %+v, %v", session, err)
+	}
+
+	refreshed, err := client.RefreshMessageSession(ctx, 1, session.SessionID)
+	if err != nil || refreshed.MessageQueueAccessToken != "queue-token-2" {
+		t.Errorf("RefreshMessageSession() = %+v, %v, want the renewed queue token", refreshed, err)
+	}
+	if _, err := client.RefreshMessageSession(ctx, 1, nil); err == nil {
+		t.Error("RefreshMessageSession() without session ID succeeded")
+	}
+
+	if err := client.DeleteMessageSession(ctx, 1, session.SessionID); err != nil || !deleted.Load() {
+		t.Errorf("DeleteMessageSession() = %v, deleted %v", err, deleted.Load())
+	}
+}
+
+func TestGetMessage(t *testing.T) {
+	client, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {})
+	queue := http.NewServeMux()
+	queue.HandleFunc("/queue", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && r.Header.Get("X-GitHub-Actions-Scale-Set-Max-Capacity") != "5" {
+			t.Errorf("GetMessage sent max capacity %q, want 5", r.Header.Get("X-GitHub-Actions-Scale-Set-Max-Capacity"))
+		}
+		switch r.Header.Get("Authorization") {
+		case "Bearer expired":
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"message": "token expired"})
+			return
+		case "Bearer queue-token":
+		default:
+			t.Errorf("GetMessage sent Authorization %q", r.Header.Get("Authorization"))
+		}
+		switch r.Method + " " + r.URL.Query().Get("lastMessageId") + r.URL.Query().Get("messageId") {
+		case "GET ":
+			writeJSON(w, http.StatusOK, RunnerScaleSetMessage{MessageID: 7, MessageType: "RunnerScaleSetJobMessages", Body: "[]"})
+		case "GET 7":
+			// No message arrived before the long poll ended
+			w.WriteHeader(http.StatusAccepted)
+		case "DELETE 7":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("unexpected %s %s", r.Method, r.URL)
+			w.WriteHeader(http.StatusNotImplemented)
+		}
+	})
+	queueSrv := httptest.NewServer(queue)
+	defer queueSrv.Close()
+	ctx := context.Background()
+	queueURL := queueSrv.URL + "/queue"
+
+	message, err := client.GetMessage(ctx, queueURL, "queue-token", 0, 5)
+	if err != nil || message == nil || message.MessageID != 7 {
+		t.Fatalf("GetMessage() = %+v, %v, want message 7", message, err)
+	}
+	message, err = client.GetMessage(ctx, queueURL, "queue-token", 7, 5)
+	if err != nil || message != nil {
+		t.Errorf("GetMessage() after the last message = %+v, %v, want none", message, err)
+	}
+	if err := client.DeleteMessage(ctx, queueURL, "queue-token", 7); err != nil {
+		t.Errorf("DeleteMessage() = %v", err)
+	}
+	if _, err := client.GetMessage(ctx, queueURL, "expired", 7, 5); !IsStatus(err, http.StatusUnauthorized) {
+		t.Errorf("GetMessage() with an expired token = %v, want a 401 ActionsError", err)
+	}
+	if _, err := client.GetMessage(ctx, queueURL, "queue-token", 7, -1); err == nil {
+		t.Error("GetMessage() accepted a negative max capacity")
+	}
+}
+
+func TestAcquireJob(t *testing.T) {
+	client, srv := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/service/acquire/1":
+			w.WriteHeader(http.StatusOK)
+		case "/service/acquire/2":
+			writeJSON(w, http.StatusConflict, map[string]string{"message": "job already acquired"})
+		default:
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"message": "boom"})
+		}
+	})
+	ctx := context.Background()
+
+	tests := []struct {
+		path     string
+		acquired bool
+		fails    bool
+	}{
+		{"/service/acquire/1", true, false},
+		{"/service/acquire/2", false, false},
+		{"/service/acquire/3", false, true},
+	}
+	for _, tt := range tests {
+		acquired, err := client.AcquireJob(ctx, srv.URL+tt.path, "queue-token")
+		if acquired != tt.acquired || (err != nil) != tt.fails {
+			t.Errorf("AcquireJob(%s) = %v, %v, want %v, error %v", tt.path, acquired, err, tt.acquired, tt.fails)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package actions
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ActionsError represents an error response from the Actions Service or the GitHub API
+type ActionsError struct {
+	StatusCode int
+	ActivityID string
+	Message    string
+	Err        error
+}
+
+func (e *ActionsError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("Actions API error (status: %d, activity: %s): %s: %v", e.StatusCode, e.ActivityID, e.Message, e.Err)
+	}
+	return fmt.Sprintf("Actions API error (status: %d, activity: %s): %s", e.StatusCode, e.ActivityID, e.Message)
+}
+
+func (e *ActionsError) Unwrap() error {
+	return e.Err
+}
+
+// IsStatus reports whether err is, or wraps, an ActionsError with the given status
+func IsStatus(err error, statusCode int) bool {
+	var actionsErr *ActionsError
+	return errors.As(err, &actionsErr) && actionsErr.StatusCode == statusCode
+}
+
+// ParseErrorResponse turns a non-success response into an ActionsError, keeping the
+// message and field errors of GitHub API error bodies and the raw body otherwise
+func ParseErrorResponse(resp *http.Response) error {
+	activityID := resp.Header.Get("X-GitHub-Request-Id")
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return &ActionsError{
+			StatusCode: resp.StatusCode,
+			ActivityID: activityID,
+			Message:    "Failed to read error response",
+			Err:        err,
+		}
+	}
+
+	var ghErr struct {
+		Message string `json:"message"`
+		Errors  []struct {
+			Message string `json:"message"`
+			Code    string `json:"code"`
+			Field   string `json:"field"`
+		} `json:"errors"`
+		DocumentationURL string `json:"documentation_url"`
+	}
+	if err := json.Unmarshal(body, &ghErr); err != nil || ghErr.Message == "" {
+		return &ActionsError{
+			StatusCode: resp.StatusCode,
+			ActivityID: activityID,
+			Message:    strings.TrimSpace(string(body)),
+		}
+	}
+
+	messages := []string{ghErr.Message}
+	for _, e := range ghErr.Errors {
+		if e.Message != "" {
+			messages = append(messages, fmt.Sprintf("%s: %s", e.Field, e.Message))
+		}
+	}
+	actionsErr := &ActionsError{
+		StatusCode: resp.StatusCode,
+		ActivityID: activityID,
+		Message:    strings.Join(messages, "; "),
+	}
+	if ghErr.DocumentationURL != "" {
+		actionsErr.Err = fmt.Errorf("documentation: %s", ghErr.DocumentationURL)
+	}
+	return actionsErr
+}
@@ -0,0 +1,82 @@
+package actions
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestParseErrorResponse(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		message string
+		wrapped string
+	}{
+		{
+			name:    "GitHub error",
+			status:  http.StatusUnprocessableEntity,
+			body:    `{"message":"Validation Failed","errors":[{"field":"name","code":"already_exists","message":"name is taken"}],"documentation_url":"https://docs.github.com/rest"}`,
+			message: "Validation Failed; name: name is taken",
+			wrapped: "documentation: https://docs.github.com/rest",
+		},
+		{
+			name:    "GitHub error without details",
+			status:  http.StatusNotFound,
+			body:    `{"message":"Not Found"}`,
+			message: "Not Found",
+		},
+		{
+			name:    "plain body",
+			status:  http.StatusBadGateway,
+			body:    "  upstream unavailable\n",
+			message: "upstream unavailable",
+		},
+		{
+			name:    "JSON without message",
+			status:  http.StatusBadRequest,
+			body:    `{"error":"bad"}`,
+			message: `{"error":"bad"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{
+				StatusCode: tt.status,
+				Header:     http.Header{"X-Github-Request-Id": []string{"ABCD:1234"}},
+				Body:       io.NopCloser(strings.NewReader(tt.body)),
+			}
+			err := ParseErrorResponse(resp)
+			actionsErr, ok := err.(*ActionsError)
+			if !ok {
+				t.Fatalf("ParseErrorResponse() = %T, want *ActionsError", err)
+			}
+			if actionsErr.StatusCode != tt.status || actionsErr.ActivityID != "ABCD:1234" || actionsErr.Message != tt.message {
+				t.Errorf("ParseErrorResponse() = %+v, want status %d, activity ABCD:1234, message %q", actionsErr, tt.status, tt.message)
+			}
+			wrapped := ""
+			if actionsErr.Err != nil {
+				wrapped = actionsErr.Err.Error()
+			}
+			if wrapped != tt.wrapped {
+				t.Errorf("wrapped error = %q, want %q", wrapped, tt.wrapped)
+			}
+			if !IsStatus(fmt.Errorf("call failed: %w", err), tt.status) {
+				t.Errorf("IsStatus() of the wrapped error is false for status %d", tt.status)
+			}
+		})
+	}
+}
+
+func TestIsStatus(t *testing.T) {
+	err := &ActionsError{StatusCode: http.StatusConflict}
+	if IsStatus(err, http.StatusNotFound) {
+		t.Error("IsStatus(409, 404) = true")
+	}
+	if IsStatus(fmt.Errorf("plain"), http.StatusConflict) {
+		t.Error("IsStatus() of a plain error = true")
+	}
+}
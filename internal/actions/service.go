@@ -0,0 +1,42 @@
+package actions
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// ScaleSetService manages runner scale sets
+type ScaleSetService interface {
+	ListScaleSets(ctx context.Context) ([]RunnerScaleSet, error)
+	GetScaleSetByName(ctx context.Context, name string) (*RunnerScaleSet, error)
+	CreateScaleSet(ctx context.Context, scaleSet *RunnerScaleSet) (*RunnerScaleSet, error)
+	GetAcquirableJobs(ctx context.Context, scaleSetID int) (*AcquirableJobList, error)
+	GenerateJitRunnerConfig(ctx context.Context, scaleSetID int, setting *RunnerScaleSetJitRunnerSetting) (*RunnerScaleSetJitRunnerConfig, error)
+	RemoveRunner(ctx context.Context, runnerID int64) error
+}
+
+// SessionService manages the message sessions of scale sets
+type SessionService interface {
+	CreateMessageSession(ctx context.Context, scaleSetID int, owner string) (*RunnerScaleSetSession, error)
+	RefreshMessageSession(ctx context.Context, scaleSetID int, sessionID *uuid.UUID) (*RunnerScaleSetSession, error)
+	DeleteMessageSession(ctx context.Context, scaleSetID int, sessionID *uuid.UUID) error
+}
+
+// MessageQueue receives the messages of a session and acquires the jobs they announce
+type MessageQueue interface {
+	GetMessage(ctx context.Context, messageQueueURL, accessToken string, lastMessageID int64, maxCapacity int) (*RunnerScaleSetMessage, error)
+	DeleteMessage(ctx context.Context, messageQueueURL, accessToken string, messageID int64) error
+	AcquireJobs(ctx context.Context, scaleSetID int, accessToken string, requestIDs []int64) ([]int64, error)
+	AcquireJob(ctx context.Context, acquireJobURL, accessToken string) (bool, error)
+}
+
+// Service is the Actions Service API used by the scalers
+type Service interface {
+	Connect(ctx context.Context, gitHubURL, configURL, registrationToken string) error
+	ScaleSetService
+	SessionService
+	MessageQueue
+}
+
+var _ Service = (*Client)(nil)
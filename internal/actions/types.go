@@ -0,0 +1,149 @@
+// Package actions is the client for the GitHub Actions Service runner scale set API shared by
+// the ghaec2 listener, the Lambda scaler and the ghalistener-ec2 prototype. It follows the
+// protocol of actions-runner-controller: an admin connection obtained with a runner
+// registration token, scale sets, message sessions, the message queue and job acquisition.
+package actions
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AcquirableJob represents a job that can be acquired by a runner
+type AcquirableJob struct {
+	AcquireJobURL   string   `json:"acquireJobUrl"`
+	MessageType     string   `json:"messageType"`
+	RunnerRequestID int64    `json:"runnerRequestId"`
+	RepositoryName  string   `json:"repositoryName"`
+	OwnerName       string   `json:"ownerName"`
+	JobWorkflowRef  string   `json:"jobWorkflowRef"`
+	EventName       string   `json:"eventName"`
+	RequestLabels   []string `json:"requestLabels"`
+}
+
+// AcquirableJobList represents the response from the acquirable jobs API
+type AcquirableJobList struct {
+	Count int             `json:"count"`
+	Jobs  []AcquirableJob `json:"value"`
+}
+
+// RunnerScaleSetSession represents a session for message polling
+type RunnerScaleSetSession struct {
+	SessionID               *uuid.UUID               `json:"sessionId,omitempty"`
+	OwnerName               string                   `json:"ownerName,omitempty"`
+	RunnerScaleSet          *RunnerScaleSet          `json:"runnerScaleSet,omitempty"`
+	MessageQueueURL         string                   `json:"messageQueueUrl,omitempty"`
+	MessageQueueAccessToken string                   `json:"messageQueueAccessToken,omitempty"`
+	Statistics              *RunnerScaleSetStatistic `json:"statistics,omitempty"`
+}
+
+// RunnerScaleSet represents a GitHub Actions runner scale set
+type RunnerScaleSet struct {
+	ID              int                      `json:"id,omitempty"`
+	Name            string                   `json:"name,omitempty"`
+	RunnerGroupID   int                      `json:"runnerGroupId,omitempty"`
+	RunnerGroupName string                   `json:"runnerGroupName,omitempty"`
+	Labels          []Label                  `json:"labels,omitempty"`
+	RunnerSetting   RunnerSetting            `json:"runnerSetting"`
+	Statistics      *RunnerScaleSetStatistic `json:"statistics,omitempty"`
+}
+
+// LabelNames returns the names of the scale set's labels
+func (s *RunnerScaleSet) LabelNames() []string {
+	names := make([]string, len(s.Labels))
+	for i, label := range s.Labels {
+		names[i] = label.Name
+	}
+	return names
+}
+
+// Label represents a runner label
+type Label struct {
+	ID   int    `json:"id,omitempty"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// RunnerSetting represents runner configuration
+type RunnerSetting struct {
+	Ephemeral     bool `json:"ephemeral"`
+	IsElastic     bool `json:"isElastic"`
+	DisableUpdate bool `json:"disableUpdate"`
+}
+
+// RunnerScaleSetStatistic represents the job and runner counts reported for a scale set
+type RunnerScaleSetStatistic struct {
+	TotalAvailableJobs     int `json:"totalAvailableJobs"`
+	TotalAcquiredJobs      int `json:"totalAcquiredJobs"`
+	TotalAssignedJobs      int `json:"totalAssignedJobs"`
+	TotalRunningJobs       int `json:"totalRunningJobs"`
+	TotalRegisteredRunners int `json:"totalRegisteredRunners"`
+	TotalBusyRunners       int `json:"totalBusyRunners"`
+	TotalIdleRunners       int `json:"totalIdleRunners"`
+}
+
+// RunnerScaleSetMessage represents a message from the Actions Service
+type RunnerScaleSetMessage struct {
+	MessageID   int64                    `json:"messageId"`
+	MessageType string                   `json:"messageType"`
+	Body        string                   `json:"body"`
+	Statistics  *RunnerScaleSetStatistic `json:"statistics,omitempty"`
+}
+
+// JobAvailable represents a job available message
+type JobAvailable struct {
+	AcquireJobURL   string    `json:"acquireJobUrl"`
+	MessageType     string    `json:"messageType"`
+	RunnerRequestID int64     `json:"runnerRequestId"`
+	RepositoryName  string    `json:"repositoryName"`
+	OwnerName       string    `json:"ownerName"`
+	JobWorkflowRef  string    `json:"jobWorkflowRef"`
+	EventName       string    `json:"eventName"`
+	RequestLabels   []string  `json:"requestLabels"`
+	QueueTime       time.Time `json:"queueTime"`
+}
+
+// JobMessageBase represents the fields common to job messages
+type JobMessageBase struct {
+	MessageType        string    `json:"messageType"`
+	RunnerRequestID    int64     `json:"runnerRequestId"`
+	RepositoryName     string    `json:"repositoryName"`
+	OwnerName          string    `json:"ownerName"`
+	JobWorkflowRef     string    `json:"jobWorkflowRef"`
+	JobDisplayName     string    `json:"jobDisplayName"`
+	WorkflowRunID      int64     `json:"workflowRunId"`
+	EventName          string    `json:"eventName"`
+	RequestLabels      []string  `json:"requestLabels"`
+	QueueTime          time.Time `json:"queueTime"`
+	ScaleSetAssignTime time.Time `json:"scaleSetAssignTime"`
+	RunnerAssignTime   time.Time `json:"runnerAssignTime"`
+	FinishTime         time.Time `json:"finishTime"`
+}
+
+// RunnerScaleSetJitRunnerSetting names the runner a JIT config is generated for
+type RunnerScaleSetJitRunnerSetting struct {
+	Name       string `json:"name"`
+	WorkFolder string `json:"workFolder"`
+}
+
+// RunnerReference is a runner registered with the Actions Service
+type RunnerReference struct {
+	ID               int64  `json:"id"`
+	Name             string `json:"name"`
+	RunnerScaleSetID int    `json:"runnerScaleSetId"`
+}
+
+// RunnerScaleSetJitRunnerConfig is a just-in-time runner configuration: the runner it
+// registered in the scale set and the single-use config it starts with, passed to
+// run.sh --jitconfig in place of config.sh and a registration token
+type RunnerScaleSetJitRunnerConfig struct {
+	Runner           *RunnerReference `json:"runner"`
+	EncodedJITConfig string           `json:"encodedJITConfig"`
+}
+
+// AdminConnection is the Actions Service URL and admin token returned for a registration token
+type AdminConnection struct {
+	ActionsServiceURL *string `json:"url,omitempty"`
+	AdminToken        *string `json:"token,omitempty"`
+}
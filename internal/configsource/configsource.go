@@ -0,0 +1,162 @@
+// Package configsource resolves the settings of the ghaec2 scaler and the Lambda scaler.
+// Each setting is resolved from, in order of precedence:
+//
+//  1. command line flags, e.g. --max-runners=5 for MAX_RUNNERS: ghaec2 takes them at startup
+//     and for every subcommand, the Lambda scaler only for its config commands
+//  2. environment variables, ignored when empty
+//  3. the JSON file named by CONFIG_FILE (or --config), an object keyed by setting name
+//  4. the setting's default
+//
+// Every setting a scaler reads must be listed in its settings, so the precedence and the
+// defaults live in one place and `config dump` can show the effective configuration.
+package configsource
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Setting is one configuration setting
+type Setting struct {
+	Name    string
+	Default string
+	Secret  bool // redacted by Dump
+}
+
+// Configuration sources, as reported by Lookup
+const (
+	SourceFlag    = "flag"
+	SourceEnv     = "env"
+	SourceFile    = "file"
+	SourceDefault = "default"
+)
+
+// Source resolves settings from flags, the environment, a config file and the defaults
+type Source struct {
+	settings []Setting
+	flags    map[string]string
+	file     map[string]string
+	fileName string
+}
+
+// New creates a source of the given settings with the given flag values, keyed by setting
+// name, and reads the config file when one is set
+func New(settings []Setting, flags map[string]string) (*Source, error) {
+	src := &Source{settings: settings, flags: flags}
+	if flags["CONFIG_FILE"] != "" {
+		src.fileName = flags["CONFIG_FILE"]
+	} else {
+		src.fileName = os.Getenv("CONFIG_FILE")
+	}
+	if src.fileName == "" {
+		return src, nil
+	}
+
+	data, err := os.ReadFile(src.fileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CONFIG_FILE: %w", err)
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid CONFIG_FILE %s: %w", src.fileName, err)
+	}
+
+	src.file = make(map[string]string, len(raw))
+	for name, value := range raw {
+		if _, ok := find(settings, name); !ok {
+			return nil, fmt.Errorf("invalid CONFIG_FILE %s: unknown setting %s", src.fileName, name)
+		}
+		// Strings are taken as they are; lists, objects, numbers and booleans keep their
+		// JSON text, which is the form the environment variables use
+		var text string
+		if err := json.Unmarshal(value, &text); err != nil {
+			text = string(value)
+		}
+		src.file[name] = text
+	}
+	return src, nil
+}
+
+// find returns the setting with the given name
+func find(settings []Setting, name string) (Setting, bool) {
+	for _, setting := range settings {
+		if setting.Name == name {
+			return setting, true
+		}
+	}
+	return Setting{}, false
+}
+
+// FileName returns the config file the source read, if any
+func (src *Source) FileName() string {
+	return src.fileName
+}
+
+// Get returns the effective value of a setting
+func (src *Source) Get(name string) string {
+	value, _ := src.Lookup(name)
+	return value
+}
+
+// Lookup returns the effective value of a setting and the source it came from
+func (src *Source) Lookup(name string) (string, string) {
+	if value := src.flags[name]; value != "" {
+		return value, SourceFlag
+	}
+	if value := os.Getenv(name); value != "" {
+		return value, SourceEnv
+	}
+	if value := src.file[name]; value != "" {
+		return value, SourceFile
+	}
+	setting, _ := find(src.settings, name)
+	return setting.Default, SourceDefault
+}
+
+// ParseFlags turns --setting-name=value arguments into values of the given settings;
+// --config names the config file. Other arguments are returned unparsed.
+func ParseFlags(settings []Setting, args []string) (map[string]string, []string, error) {
+	flags := make(map[string]string)
+	var rest []string
+	for _, arg := range args {
+		flag, value, ok := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+		if !strings.HasPrefix(arg, "--") || !ok {
+			rest = append(rest, arg)
+			continue
+		}
+		name := strings.ToUpper(strings.ReplaceAll(flag, "-", "_"))
+		if name == "CONFIG" {
+			name = "CONFIG_FILE"
+		} else if _, known := find(settings, name); !known {
+			return nil, nil, fmt.Errorf("unknown flag --%s", flag)
+		}
+		flags[name] = value
+	}
+	return flags, rest, nil
+}
+
+// Dump writes every setting with its effective value and source. Secrets are replaced
+// when redacted is set.
+func (src *Source) Dump(w io.Writer, redacted bool) {
+	settings := append([]Setting(nil), src.settings...)
+	sort.Slice(settings, func(i, j int) bool { return settings[i].Name < settings[j].Name })
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SETTING\tVALUE\tSOURCE")
+	for _, setting := range settings {
+		value, source := src.Lookup(setting.Name)
+		if setting.Secret && redacted && value != "" {
+			value = "<redacted>"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", setting.Name, value, source)
+	}
+	tw.Flush()
+	if src.fileName != "" {
+		fmt.Fprintf(w, "\nConfig file: %s\n", src.fileName)
+	}
+}
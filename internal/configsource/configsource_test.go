@@ -0,0 +1,101 @@
+package configsource
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var testSettings = []Setting{
+	{Name: "MAX_RUNNERS", Default: "10"},
+	{Name: "MIN_RUNNERS", Default: "0"},
+	{Name: "RUNNER_LABELS"},
+	{Name: "GITHUB_TOKEN", Secret: true},
+}
+
+func TestLookup(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(file, []byte(`{"MAX_RUNNERS": 20, "MIN_RUNNERS": "2", "RUNNER_LABELS": "linux,x64"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_FILE", file)
+	t.Setenv("MIN_RUNNERS", "3")
+	t.Setenv("RUNNER_LABELS", "")
+
+	src, err := New(testSettings, map[string]string{"MAX_RUNNERS": "5"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	tests := []struct {
+		name   string
+		value  string
+		source string
+	}{
+		{"MAX_RUNNERS", "5", SourceFlag},
+		{"MIN_RUNNERS", "3", SourceEnv},
+		{"RUNNER_LABELS", "linux,x64", SourceFile},
+		{"GITHUB_TOKEN", "", SourceDefault},
+	}
+	for _, tt := range tests {
+		value, source := src.Lookup(tt.name)
+		if value != tt.value || source != tt.source {
+			t.Errorf("Lookup(%s) = %q, %q, want %q, %q", tt.name, value, source, tt.value, tt.source)
+		}
+	}
+	if src.FileName() != file {
+		t.Errorf("FileName() = %q, want %q", src.FileName(), file)
+	}
+}
+
+func TestNewRejectsUnknownSettings(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(file, []byte(`{"MAX_RUNER": 20}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New(testSettings, map[string]string{"CONFIG_FILE": file}); err == nil || !strings.Contains(err.Error(), "MAX_RUNER") {
+		t.Errorf("New() error = %v, want an unknown setting error", err)
+	}
+}
+
+func TestParseFlags(t *testing.T) {
+	flags, rest, err := ParseFlags(testSettings, []string{"config", "--max-runners=5", "--config=c.json", "--redacted", "dump"})
+	if err != nil {
+		t.Fatalf("ParseFlags() error = %v", err)
+	}
+	if flags["MAX_RUNNERS"] != "5" || flags["CONFIG_FILE"] != "c.json" || len(flags) != 2 {
+		t.Errorf("ParseFlags() flags = %v", flags)
+	}
+	if strings.Join(rest, " ") != "config --redacted dump" {
+		t.Errorf("ParseFlags() rest = %v", rest)
+	}
+
+	if _, _, err := ParseFlags(testSettings, []string{"--max-runer=5"}); err == nil {
+		t.Error("ParseFlags() accepted an unknown flag")
+	}
+}
+
+func TestDump(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("GITHUB_TOKEN", "ghp_secret")
+	src, err := New(testSettings, nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	var out bytes.Buffer
+	src.Dump(&out, true)
+	if strings.Contains(out.String(), "ghp_secret") || !strings.Contains(out.String(), "<redacted>") {
+		t.Errorf("Dump(redacted) did not redact the secret:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "MAX_RUNNERS") || !strings.Contains(out.String(), SourceDefault) {
+		t.Errorf("Dump() is missing the defaults:\n%s", out.String())
+	}
+
+	out.Reset()
+	src.Dump(&out, false)
+	if !strings.Contains(out.String(), "ghp_secret") {
+		t.Errorf("Dump() redacted without redacted set:\n%s", out.String())
+	}
+}
@@ -0,0 +1,61 @@
+// Package ec2launch validates and resolves the EC2 launch settings shared by the ghaec2
+// scaler and the Lambda scaler.
+package ec2launch
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// DefaultSpotPriceKey sets the ceiling for instance types without an entry of their own
+const DefaultSpotPriceKey = "default"
+
+// ValidateSpotPrices checks that every spot price ceiling is a positive number
+func ValidateSpotPrices(prices map[string]string) error {
+	for instanceType, price := range prices {
+		value, err := strconv.ParseFloat(price, 64)
+		if err != nil || value <= 0 {
+			return fmt.Errorf("spot price %q for %s is not a positive number", price, instanceType)
+		}
+	}
+	return nil
+}
+
+// SpotPriceFor returns the maximum spot bid for an instance type. It returns nil when no
+// ceiling is configured, which makes EC2 cap the bid at the type's on-demand price so an
+// expensive type is never launched at a bid meant for a cheaper one.
+func SpotPriceFor(prices map[string]string, instanceType string) *string {
+	if price, ok := prices[instanceType]; ok {
+		return &price
+	}
+	if price, ok := prices[DefaultSpotPriceKey]; ok {
+		return &price
+	}
+	return nil
+}
+
+// launchTemplateIDPattern matches EC2 launch template IDs
+var launchTemplateIDPattern = regexp.MustCompile(`^lt-[0-9a-f]+$`)
+
+// ValidateLaunchTemplate checks a launch template ID and version. The version may be a
+// number, $Latest or $Default, and is left empty for the template's default version.
+func ValidateLaunchTemplate(id, version string) error {
+	if id == "" {
+		if version != "" {
+			return fmt.Errorf("launch template version %q is set without a launch template", version)
+		}
+		return nil
+	}
+	if !launchTemplateIDPattern.MatchString(id) {
+		return fmt.Errorf("invalid launch template ID %q", id)
+	}
+	switch version {
+	case "", "$Latest", "$Default":
+		return nil
+	}
+	if n, err := strconv.Atoi(version); err != nil || n < 1 {
+		return fmt.Errorf("invalid launch template version %q (want a number, $Latest or $Default)", version)
+	}
+	return nil
+}
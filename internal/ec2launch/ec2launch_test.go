@@ -0,0 +1,65 @@
+package ec2launch
+
+import "testing"
+
+func TestSpotPriceFor(t *testing.T) {
+	prices := map[string]string{"c5.xlarge": "0.10", DefaultSpotPriceKey: "0.05"}
+	tests := []struct {
+		prices       map[string]string
+		instanceType string
+		want         string
+	}{
+		{prices, "c5.xlarge", "0.10"},
+		{prices, "m5.large", "0.05"},
+		{map[string]string{"c5.xlarge": "0.10"}, "m5.large", ""},
+		{nil, "m5.large", ""},
+	}
+	for _, tt := range tests {
+		got := ""
+		if price := SpotPriceFor(tt.prices, tt.instanceType); price != nil {
+			got = *price
+		}
+		if got != tt.want {
+			t.Errorf("SpotPriceFor(%v, %q) = %q, want %q", tt.prices, tt.instanceType, got, tt.want)
+		}
+	}
+}
+
+func TestValidateSpotPrices(t *testing.T) {
+	tests := []struct {
+		prices map[string]string
+		valid  bool
+	}{
+		{map[string]string{"c5.xlarge": "0.10", DefaultSpotPriceKey: "1"}, true},
+		{map[string]string{"c5.xlarge": "0"}, false},
+		{map[string]string{"c5.xlarge": "-0.1"}, false},
+		{map[string]string{"c5.xlarge": "cheap"}, false},
+	}
+	for _, tt := range tests {
+		if err := ValidateSpotPrices(tt.prices); (err == nil) != tt.valid {
+			t.Errorf("ValidateSpotPrices(%v) = %v, want valid %v", tt.prices, err, tt.valid)
+		}
+	}
+}
+
+func TestValidateLaunchTemplate(t *testing.T) {
+	tests := []struct {
+		id, version string
+		valid       bool
+	}{
+		{"", "", true},
+		{"", "3", false},
+		{"lt-0abc123", "", true},
+		{"lt-0abc123", "$Latest", true},
+		{"lt-0abc123", "$Default", true},
+		{"lt-0abc123", "7", true},
+		{"lt-0abc123", "0", false},
+		{"lt-0abc123", "latest", false},
+		{"my-template", "", false},
+	}
+	for _, tt := range tests {
+		if err := ValidateLaunchTemplate(tt.id, tt.version); (err == nil) != tt.valid {
+			t.Errorf("ValidateLaunchTemplate(%q, %q) = %v, want valid %v", tt.id, tt.version, err, tt.valid)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+// Package githubapp authenticates the ghaec2 scaler and the Lambda scaler as a GitHub App
+// installation: a JWT signed with the App's private key is exchanged for an installation
+// access token, which the GitHub API accepts like a personal access token for an hour.
+package githubapp
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Token is an installation access token
+type Token struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Expiring reports whether the token is missing or expires within d
+func (t Token) Expiring(now time.Time, d time.Duration) bool {
+	return t.Token == "" || !now.Add(d).Before(t.ExpiresAt)
+}
+
+// ParsePrivateKey parses the PEM private key GitHub generates for an App, in PKCS#1 or
+// PKCS#8 form
+func ParsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(bytes.TrimSpace(data))
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block in private key")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key is not an RSA key")
+	}
+	return key, nil
+}
+
+// JWT returns the RS256 token that authenticates as the App itself. It is backdated a
+// minute against clock drift and valid for the ten minutes GitHub allows at most.
+func JWT(appID int64, key *rsa.PrivateKey, now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": strconv.FormatInt(appID, 10),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	sum := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign JWT: %w", err)
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// InstallationToken creates an installation access token through the REST API at apiURL,
+// such as https://ghes.example.com/api/v3
+func InstallationToken(ctx context.Context, client *http.Client, apiURL string, appID, installationID int64, key *rsa.PrivateKey) (Token, error) {
+	jwt, err := JWT(appID, key, time.Now())
+	if err != nil {
+		return Token{}, err
+	}
+
+	url := fmt.Sprintf("%s/app/installations/%d/access_tokens", strings.TrimSuffix(apiURL, "/"), installationID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, nil)
+	if err != nil {
+		return Token{}, err
+	}
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return Token{}, fmt.Errorf("failed to request installation token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(resp.Body)
+		return Token{}, fmt.Errorf("installation token request failed (HTTP %d): %s", resp.StatusCode, string(body))
+	}
+	var token Token
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return Token{}, fmt.Errorf("failed to decode installation token: %w", err)
+	}
+	if token.Token == "" {
+		return Token{}, fmt.Errorf("installation token response holds no token")
+	}
+	return token, nil
+}
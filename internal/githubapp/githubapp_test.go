@@ -0,0 +1,161 @@
+package githubapp
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestParsePrivateKey(t *testing.T) {
+	key := testKey(t)
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{"pkcs1", string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})), false},
+		{"pkcs8", string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8})), false},
+		{"surrounding whitespace", "\n  " + string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})), false},
+		{"not pem", "ghp_token", true},
+		{"garbage", string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte("garbage")})), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := ParsePrivateKey([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePrivateKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && !parsed.Equal(key) {
+				t.Error("ParsePrivateKey() returned a different key")
+			}
+		})
+	}
+}
+
+// verifyJWT checks the signature of a JWT and returns its claims
+func verifyJWT(t *testing.T, jwt string, key *rsa.PublicKey) map[string]interface{} {
+	t.Helper()
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		t.Fatalf("JWT has %d parts, want 3", len(parts))
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, sum[:], signature); err != nil {
+		t.Fatalf("JWT signature does not verify: %v", err)
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var claims map[string]interface{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		t.Fatal(err)
+	}
+	return claims
+}
+
+func TestJWT(t *testing.T) {
+	key := testKey(t)
+	now := time.Unix(1700000000, 0)
+	jwt, err := JWT(12345, key, now)
+	if err != nil {
+		t.Fatalf("JWT() error = %v", err)
+	}
+	claims := verifyJWT(t, jwt, &key.PublicKey)
+	if claims["iss"] != "12345" {
+		t.Errorf("iss = %v, want 12345", claims["iss"])
+	}
+	if iat := int64(claims["iat"].(float64)); iat != now.Add(-time.Minute).Unix() {
+		t.Errorf("iat = %d, want a minute before now", iat)
+	}
+	if exp := int64(claims["exp"].(float64)); exp-now.Unix() > 10*60 {
+		t.Errorf("exp = %d is more than ten minutes after now", exp)
+	}
+}
+
+func TestInstallationToken(t *testing.T) {
+	key := testKey(t)
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v3/app/installations/42/access_tokens" {
+			t.Errorf("request = %s %s", r.Method, r.URL.Path)
+		}
+		jwt, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			t.Errorf("Authorization = %q, want a bearer JWT", r.Header.Get("Authorization"))
+		}
+		if claims := verifyJWT(t, jwt, &key.PublicKey); claims["iss"] != "7" {
+			t.Errorf("iss = %v, want 7", claims["iss"])
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{"token": "ghs_installation", "expires_at": expiresAt})
+	}))
+	defer server.Close()
+
+	token, err := InstallationToken(context.Background(), server.Client(), server.URL+"/api/v3/", 7, 42, key)
+	if err != nil {
+		t.Fatalf("InstallationToken() error = %v", err)
+	}
+	if token.Token != "ghs_installation" || !token.ExpiresAt.Equal(expiresAt) {
+		t.Errorf("InstallationToken() = %+v", token)
+	}
+}
+
+func TestInstallationTokenError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message":"A JSON web token could not be decoded"}`, http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	_, err := InstallationToken(context.Background(), server.Client(), server.URL, 7, 42, testKey(t))
+	if err == nil || !strings.Contains(err.Error(), "HTTP 401") {
+		t.Errorf("InstallationToken() error = %v, want the HTTP 401", err)
+	}
+}
+
+func TestTokenExpiring(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name  string
+		token Token
+		want  bool
+	}{
+		{"missing", Token{}, true},
+		{"fresh", Token{Token: "t", ExpiresAt: now.Add(time.Hour)}, false},
+		{"within the margin", Token{Token: "t", ExpiresAt: now.Add(5 * time.Minute)}, true},
+		{"expired", Token{Token: "t", ExpiresAt: now.Add(-time.Minute)}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.token.Expiring(now, 10*time.Minute); got != tt.want {
+			t.Errorf("%s: Expiring() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,434 @@
+// Package labels matches requested runner labels against the labels, patterns and
+// expressions a runner is configured with, shared by the ghaec2 scaler and the Lambda scaler.
+package labels
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// MatchOptions tunes how labels are compared
+type MatchOptions struct {
+	// ImplicitLabels are treated as present on every runner even when not configured
+	ImplicitLabels []string
+	// CaseSensitive compares labels exactly; GitHub itself matches labels case-insensitively
+	CaseSensitive bool
+	// ExcludedLabels reject any request carrying one of them, even when the runner has it.
+	// Globs and regular expressions are allowed, and an expression rejects every request
+	// that satisfies it.
+	ExcludedLabels []string
+}
+
+// WithExclusions returns a copy of the options that rejects requests carrying the given labels
+func (o MatchOptions) WithExclusions(labels []string) MatchOptions {
+	o.ExcludedLabels = labels
+	return o
+}
+
+// JobOptions matches workflow jobs against runners: every self-hosted runner carries the
+// self-hosted label whether or not it is configured
+var JobOptions = MatchOptions{ImplicitLabels: []string{"self-hosted"}}
+
+// Matcher decides whether a runner with a given set of labels can serve a request.
+// A request matches when every label it asks for is carried by the runner (subset match,
+// extra runner labels are fine). A request without labels never matches, since a job
+// without runs-on labels cannot target a self-hosted runner.
+//
+// Besides plain labels the runner labels may contain:
+//   - glob patterns such as team-* that cover every matching label
+//   - regular expressions between slashes such as /^team-[a-z]+$/
+//   - expressions such as "linux AND NOT gpu" that the requested labels must satisfy;
+//     terms may be globs and AND, OR, NOT and parentheses are supported. The labels its
+//     terms name outside a NOT are covered by the expression, so a runner configured
+//     with only "linux AND NOT gpu" serves [linux] but neither [linux gpu] nor [linux x64].
+type Matcher struct {
+	labels      map[string]struct{}
+	patterns    []func(string) bool
+	expressions []expression
+	excluded    *Matcher
+	options     MatchOptions
+}
+
+// expression is a parsed label expression together with its source text
+type expression struct {
+	source string
+	eval   func(labels []string) bool
+	// covers reports whether a label is named by a term outside a NOT
+	covers func(label string) bool
+}
+
+// NewMatcher creates a matcher for a runner carrying the given labels. Patterns that fail
+// to parse are treated as plain labels; use Validate to reject them up front.
+func NewMatcher(runnerLabels []string, options MatchOptions) *Matcher {
+	m := &Matcher{
+		labels:  make(map[string]struct{}, len(runnerLabels)+len(options.ImplicitLabels)),
+		options: options,
+	}
+	for _, label := range runnerLabels {
+		if err := m.add(label); err != nil {
+			m.labels[m.normalize(label)] = struct{}{}
+		}
+	}
+	for _, label := range options.ImplicitLabels {
+		m.labels[m.normalize(label)] = struct{}{}
+	}
+	if len(options.ExcludedLabels) > 0 {
+		m.excluded = NewMatcher(options.ExcludedLabels, MatchOptions{CaseSensitive: options.CaseSensitive})
+	}
+	return m
+}
+
+// Matches reports whether the runner can serve a request with the given labels
+func (m *Matcher) Matches(requested []string) bool {
+	return len(requested) > 0 && m.Reject(requested) == ""
+}
+
+// Reject explains why the requested labels do not match, or returns "" when they do.
+// Unlike Matches it accepts a request without labels.
+func (m *Matcher) Reject(requested []string) string {
+	if m.excluded != nil {
+		if excluded := m.excluded.Covered(requested); len(excluded) > 0 {
+			return "excluded labels " + strings.Join(excluded, ",")
+		}
+		for _, expr := range m.excluded.expressions {
+			if expr.eval(requested) {
+				return "labels satisfy excluded " + expr.source
+			}
+		}
+	}
+	for _, expr := range m.expressions {
+		if !expr.eval(requested) {
+			return "labels do not satisfy " + expr.source
+		}
+	}
+	if missing := m.Missing(requested); len(missing) > 0 {
+		return "unsupported labels " + strings.Join(missing, ",")
+	}
+	return ""
+}
+
+// Missing returns the requested labels that no runner label, pattern or expression covers
+func (m *Matcher) Missing(requested []string) []string {
+	var missing []string
+	for _, label := range requested {
+		if _, ok := m.labels[m.normalize(label)]; !ok && !m.matchesPattern(label) && !m.inExpression(label) {
+			missing = append(missing, label)
+		}
+	}
+	return missing
+}
+
+// Covered returns the requested labels that a runner label or pattern covers
+func (m *Matcher) Covered(requested []string) []string {
+	var covered []string
+	for _, label := range requested {
+		if _, ok := m.labels[m.normalize(label)]; ok || m.matchesPattern(label) {
+			covered = append(covered, label)
+		}
+	}
+	return covered
+}
+
+// PatternMatched returns the requested labels that are only covered by a pattern or an
+// expression. A runner launched for those jobs has to register with these concrete labels.
+func (m *Matcher) PatternMatched(requested []string) []string {
+	var matched []string
+	for _, label := range requested {
+		if _, ok := m.labels[m.normalize(label)]; !ok && (m.matchesPattern(label) || m.inExpression(label)) {
+			matched = append(matched, label)
+		}
+	}
+	return matched
+}
+
+// inExpression reports whether an expression covers a label
+func (m *Matcher) inExpression(label string) bool {
+	for _, expr := range m.expressions {
+		if expr.covers(label) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Matcher) matchesPattern(label string) bool {
+	label = m.normalize(label)
+	for _, match := range m.patterns {
+		if match(label) {
+			return true
+		}
+	}
+	return false
+}
+
+// add registers one configured label, pattern or expression
+func (m *Matcher) add(label string) error {
+	label = strings.TrimSpace(label)
+	switch {
+	case IsExpression(label):
+		expr, err := parseExpression(label, m.termMatcher)
+		if err != nil {
+			return err
+		}
+		m.expressions = append(m.expressions, expr)
+	case IsPattern(label):
+		match, err := m.compilePattern(label)
+		if err != nil {
+			return err
+		}
+		m.patterns = append(m.patterns, match)
+	default:
+		m.labels[m.normalize(label)] = struct{}{}
+	}
+	return nil
+}
+
+// compilePattern turns a glob or /regex/ label into a predicate over normalized labels
+func (m *Matcher) compilePattern(pattern string) (func(string) bool, error) {
+	if isRegex(pattern) {
+		expr := pattern[1 : len(pattern)-1]
+		if !m.options.CaseSensitive {
+			expr = "(?i)" + expr
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid label regex %s: %w", pattern, err)
+		}
+		return re.MatchString, nil
+	}
+
+	glob := m.normalize(pattern)
+	if _, err := path.Match(glob, ""); err != nil {
+		return nil, fmt.Errorf("invalid label pattern %s: %w", pattern, err)
+	}
+	return func(label string) bool {
+		ok, _ := path.Match(glob, label)
+		return ok
+	}, nil
+}
+
+// termMatcher returns a predicate reporting whether a label matches an expression term
+func (m *Matcher) termMatcher(term string) (func(string) bool, error) {
+	match := func(label string) bool { return label == m.normalize(term) }
+	if IsPattern(term) {
+		var err error
+		if match, err = m.compilePattern(term); err != nil {
+			return nil, err
+		}
+	}
+	return func(label string) bool { return match(m.normalize(label)) }, nil
+}
+
+func (m *Matcher) normalize(label string) string {
+	label = strings.TrimSpace(label)
+	if m.options.CaseSensitive {
+		return label
+	}
+	return strings.ToLower(label)
+}
+
+// isRegex reports whether a configured label is a regular expression between slashes
+func isRegex(label string) bool {
+	return len(label) > 2 && strings.HasPrefix(label, "/") && strings.HasSuffix(label, "/")
+}
+
+// IsPattern reports whether a configured label is a glob or regular expression
+func IsPattern(label string) bool {
+	return isRegex(label) || strings.ContainsAny(label, "*?[")
+}
+
+// IsExpression reports whether a configured label is a boolean expression
+func IsExpression(label string) bool {
+	if isRegex(label) {
+		return false
+	}
+	if strings.ContainsAny(label, "()") {
+		return true
+	}
+	for _, token := range strings.Fields(label) {
+		if token == "AND" || token == "OR" || token == "NOT" {
+			return true
+		}
+	}
+	return false
+}
+
+// Literal returns the plain labels from a configured label list. Patterns and
+// expressions cannot be registered on a runner.
+func Literal(labels []string) []string {
+	literal := make([]string, 0, len(labels))
+	for _, label := range labels {
+		if label = strings.TrimSpace(label); !IsExpression(label) && !IsPattern(label) {
+			literal = append(literal, label)
+		}
+	}
+	return literal
+}
+
+// AppendUnique appends the labels not already present, comparing case-insensitively
+func AppendUnique(labels []string, more []string) []string {
+	for _, label := range more {
+		seen := false
+		for _, existing := range labels {
+			if strings.EqualFold(existing, label) {
+				seen = true
+				break
+			}
+		}
+		if !seen {
+			labels = append(labels, label)
+		}
+	}
+	return labels
+}
+
+// Exclusive returns the labels with label in place of any of its alternatives, such as
+// the runner's architecture instead of the other one. Empty labels stay empty: runners then
+// register their default labels.
+func Exclusive(labels []string, label string, alternatives ...string) []string {
+	if len(labels) == 0 {
+		return labels
+	}
+	result := make([]string, 0, len(labels)+1)
+	for _, existing := range labels {
+		if !strings.EqualFold(existing, label) && slices.ContainsFunc(alternatives, func(alternative string) bool {
+			return strings.EqualFold(existing, alternative)
+		}) {
+			continue
+		}
+		result = append(result, existing)
+	}
+	return AppendUnique(result, []string{label})
+}
+
+// Validate checks that every configured pattern and expression parses
+func Validate(labels []string) error {
+	m := NewMatcher(nil, MatchOptions{})
+	for _, label := range labels {
+		if err := m.add(label); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseExpression parses "a AND (b OR NOT c)" with NOT binding tighter than AND,
+// and AND tighter than OR. term compiles one term into a predicate over single labels.
+func parseExpression(source string, term func(string) (func(string) bool, error)) (expression, error) {
+	tokens := strings.Fields(strings.NewReplacer("(", " ( ", ")", " ) ").Replace(source))
+	p := &expressionParser{tokens: tokens, term: term}
+
+	eval, err := p.parseOr()
+	if err != nil {
+		return expression{}, fmt.Errorf("invalid label expression %q: %w", source, err)
+	}
+	if p.pos < len(p.tokens) {
+		return expression{}, fmt.Errorf("invalid label expression %q: unexpected %q", source, p.tokens[p.pos])
+	}
+	positive := p.positive
+	return expression{
+		source: source,
+		eval:   eval,
+		covers: func(label string) bool {
+			return slices.ContainsFunc(positive, func(match func(string) bool) bool { return match(label) })
+		},
+	}, nil
+}
+
+type expressionParser struct {
+	tokens []string
+	pos    int
+	term   func(string) (func(string) bool, error)
+	// negated is set while parsing inside an odd number of NOTs
+	negated bool
+	// positive holds the terms parsed outside a NOT
+	positive []func(string) bool
+}
+
+func (p *expressionParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *expressionParser) parseOr() (func([]string) bool, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "OR" {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(labels []string) bool { return l(labels) || right(labels) }
+	}
+	return left, nil
+}
+
+func (p *expressionParser) parseAnd() (func([]string) bool, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "AND" {
+		p.pos++
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(labels []string) bool { return l(labels) && right(labels) }
+	}
+	return left, nil
+}
+
+func (p *expressionParser) parseNot() (func([]string) bool, error) {
+	if p.peek() == "NOT" {
+		p.pos++
+		p.negated = !p.negated
+		inner, err := p.parseNot()
+		p.negated = !p.negated
+		if err != nil {
+			return nil, err
+		}
+		return func(labels []string) bool { return !inner(labels) }, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *expressionParser) parsePrimary() (func([]string) bool, error) {
+	token := p.peek()
+	switch token {
+	case "":
+		return nil, fmt.Errorf("unexpected end of expression")
+	case "(":
+		p.pos++
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("missing closing parenthesis")
+		}
+		p.pos++
+		return inner, nil
+	case ")", "AND", "OR":
+		return nil, fmt.Errorf("unexpected %q", token)
+	}
+	p.pos++
+	match, err := p.term(token)
+	if err != nil {
+		return nil, err
+	}
+	if !p.negated {
+		p.positive = append(p.positive, match)
+	}
+	return func(labels []string) bool { return slices.ContainsFunc(labels, match) }, nil
+}
@@ -0,0 +1,111 @@
+package labels
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		name      string
+		runner    []string
+		options   MatchOptions
+		requested []string
+		want      bool
+	}{
+		{"subset", []string{"linux", "x64"}, MatchOptions{}, []string{"linux"}, true},
+		{"extra requested label", []string{"linux"}, MatchOptions{}, []string{"linux", "gpu"}, false},
+		{"no labels", []string{"linux"}, MatchOptions{}, nil, false},
+		{"case insensitive", []string{"Linux"}, MatchOptions{}, []string{"LINUX"}, true},
+		{"case sensitive", []string{"Linux"}, MatchOptions{CaseSensitive: true}, []string{"linux"}, false},
+		{"implicit label", []string{"linux"}, JobOptions, []string{"self-hosted", "linux"}, true},
+		{"glob", []string{"team-*"}, MatchOptions{}, []string{"team-a"}, true},
+		{"glob mismatch", []string{"team-*"}, MatchOptions{}, []string{"other"}, false},
+		{"regex", []string{"/^team-[a-z]+$/"}, MatchOptions{}, []string{"team-ab"}, true},
+		{"regex mismatch", []string{"/^team-[a-z]+$/"}, MatchOptions{}, []string{"team-1"}, false},
+
+		{"and not, allowed", []string{"linux AND NOT gpu"}, MatchOptions{}, []string{"linux"}, true},
+		{"and not, excluded term", []string{"linux AND NOT gpu"}, MatchOptions{}, []string{"linux", "gpu"}, false},
+		{"and not, unnamed label", []string{"linux AND NOT gpu"}, MatchOptions{}, []string{"linux", "x64"}, false},
+		{"and not, required term missing", []string{"linux AND NOT gpu", "x64"}, MatchOptions{}, []string{"x64"}, false},
+		{"and not, with plain labels", []string{"x64", "linux AND NOT gpu"}, JobOptions, []string{"self-hosted", "linux", "x64"}, true},
+		{"or", []string{"linux OR windows"}, MatchOptions{}, []string{"windows"}, true},
+		{"or, neither", []string{"linux OR windows"}, MatchOptions{}, []string{"macos"}, false},
+		{"and", []string{"linux AND x64"}, MatchOptions{}, []string{"linux"}, false},
+		{"and, both", []string{"linux AND x64"}, MatchOptions{}, []string{"x64", "linux"}, true},
+		{"precedence", []string{"linux OR windows AND gpu"}, MatchOptions{}, []string{"linux"}, true},
+		{"precedence, and binds tighter", []string{"linux OR windows AND gpu"}, MatchOptions{}, []string{"windows"}, false},
+		{"parentheses", []string{"(linux OR windows) AND gpu"}, MatchOptions{}, []string{"linux"}, false},
+		{"parentheses, both", []string{"(linux OR windows) AND gpu"}, MatchOptions{}, []string{"windows", "gpu"}, true},
+		{"not or", []string{"linux AND NOT (gpu OR arm64)"}, MatchOptions{}, []string{"linux", "arm64"}, false},
+		{"double not", []string{"NOT NOT linux"}, MatchOptions{}, []string{"linux"}, true},
+		{"glob term", []string{"team-* AND NOT team-legacy"}, MatchOptions{}, []string{"team-a"}, true},
+		{"glob term, excluded", []string{"team-* AND NOT team-legacy"}, MatchOptions{}, []string{"team-legacy"}, false},
+		{"expression case insensitive", []string{"Linux AND NOT GPU"}, MatchOptions{}, []string{"linux", "gpu"}, false},
+
+		{"excluded label", []string{"linux", "gpu"}, MatchOptions{ExcludedLabels: []string{"gpu"}}, []string{"linux", "gpu"}, false},
+		{"excluded glob", []string{"linux", "gpu-*"}, MatchOptions{ExcludedLabels: []string{"gpu-*"}}, []string{"gpu-a100"}, false},
+		{"excluded expression", []string{"linux", "gpu"}, MatchOptions{ExcludedLabels: []string{"gpu AND NOT linux"}}, []string{"gpu"}, false},
+		{"excluded expression, unsatisfied", []string{"linux", "gpu"}, MatchOptions{ExcludedLabels: []string{"gpu AND NOT linux"}}, []string{"linux", "gpu"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMatcher(tt.runner, tt.options)
+			if got := m.Matches(tt.requested); got != tt.want {
+				t.Errorf("NewMatcher(%q).Matches(%q) = %v (%s), want %v", tt.runner, tt.requested, got, m.Reject(tt.requested), tt.want)
+			}
+		})
+	}
+}
+
+func TestPatternMatched(t *testing.T) {
+	m := NewMatcher([]string{"linux", "team-*", "x64 AND NOT gpu"}, MatchOptions{})
+	got := m.PatternMatched([]string{"linux", "team-a", "x64"})
+	if want := []string{"team-a", "x64"}; !slices.Equal(got, want) {
+		t.Errorf("PatternMatched() = %q, want %q", got, want)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		label string
+		valid bool
+	}{
+		{"linux", true},
+		{"team-*", true},
+		{"/^team-[a-z]+$/", true},
+		{"linux AND NOT gpu", true},
+		{"(linux OR windows) AND NOT (gpu OR arm64)", true},
+		{"team-[", false},
+		{"/team-(/", false},
+		{"linux AND", false},
+		{"AND linux", false},
+		{"NOT", false},
+		{"(linux OR windows", false},
+		{"linux OR windows)", false},
+		{"linux gpu AND x64", false},
+		{"team-[ AND linux", false},
+	}
+	for _, tt := range tests {
+		if err := Validate([]string{tt.label}); (err == nil) != tt.valid {
+			t.Errorf("Validate(%q) = %v, want valid %v", tt.label, err, tt.valid)
+		}
+	}
+}
+
+func TestLiteral(t *testing.T) {
+	got := Literal([]string{"linux", " x64 ", "team-*", "/^a$/", "gpu AND NOT a100"})
+	if want := []string{"linux", "x64"}; !slices.Equal(got, want) {
+		t.Errorf("Literal() = %q, want %q", got, want)
+	}
+}
+
+func TestExclusive(t *testing.T) {
+	got := Exclusive([]string{"linux", "X64", "gpu"}, "arm64", "x64", "arm64")
+	if want := []string{"linux", "gpu", "arm64"}; !slices.Equal(got, want) {
+		t.Errorf("Exclusive() = %q, want %q", got, want)
+	}
+	if got := Exclusive(nil, "arm64", "x64", "arm64"); len(got) != 0 {
+		t.Errorf("Exclusive(nil) = %q, want no labels", got)
+	}
+}
@@ -0,0 +1,45 @@
+// Package provider defines the backend interface the scalers launch runners through, shared
+// by the ghaec2 scaler and the Lambda scaler.
+package provider
+
+import (
+	"context"
+	"time"
+)
+
+// UnlimitedCapacity is the capacity reported by providers that set no fixed runner limit
+const UnlimitedCapacity = -1
+
+// RunnerSpec describes a runner for a provider to create
+type RunnerSpec struct {
+	Name   string
+	Labels []string
+	// RegistrationToken registers a runner that configures itself with config.sh
+	RegistrationToken string
+	// JITConfig is the encoded just-in-time configuration a runner registered in a scale
+	// set starts with
+	JITConfig string
+}
+
+// ProvisionedRunner is a runner machine as its provider reports it
+type ProvisionedRunner struct {
+	ID         string
+	Name       string
+	State      string
+	LaunchTime time.Time
+}
+
+// Provider is the backend runners are created on. The scalers decide how many runners they
+// need and leave launching, terminating and listing machines to the provider, so on-demand
+// EC2, ECS or bare metal backends can be added without touching them. Every termination of
+// a runner machine goes through TerminateRunner.
+type Provider interface {
+	// CreateRunner launches a runner and returns its provider ID
+	CreateRunner(ctx context.Context, spec RunnerSpec) (string, error)
+	// TerminateRunner terminates the runner with the ID CreateRunner returned
+	TerminateRunner(ctx context.Context, id string) error
+	// ListRunners lists the provider's live runners
+	ListRunners(ctx context.Context) ([]ProvisionedRunner, error)
+	// GetCapacity returns how many more runners the provider can create, or UnlimitedCapacity
+	GetCapacity(ctx context.Context) (int, error)
+}
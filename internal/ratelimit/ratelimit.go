@@ -0,0 +1,42 @@
+// Package ratelimit recognizes GitHub secondary rate limits for the ghaec2 scaler and the
+// Lambda scaler, which each decide how long they can afford to wait.
+package ratelimit
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Secondary reports whether a response is a GitHub secondary rate limit, and how long to
+// wait before retrying. GitHub answers these with 403 (or 429) and usually a Retry-After
+// header; older servers only say so in the message, such as "You have exceeded a secondary
+// rate limit" or "You have triggered an abuse detection mechanism". Those get the fallback
+// wait. The response body stays readable.
+func Secondary(resp *http.Response, fallback time.Duration) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return fallback, true
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return 0, false
+	}
+	message := strings.ToLower(string(body))
+	if strings.Contains(message, "secondary rate limit") || strings.Contains(message, "abuse detection") {
+		return fallback, true
+	}
+	return 0, false
+}
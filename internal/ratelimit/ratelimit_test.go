@@ -0,0 +1,43 @@
+package ratelimit
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSecondary(t *testing.T) {
+	const fallback = time.Minute
+	tests := []struct {
+		name       string
+		status     int
+		retryAfter string
+		body       string
+		wait       time.Duration
+		limited    bool
+	}{
+		{"ok", http.StatusOK, "", "", 0, false},
+		{"retry after", http.StatusForbidden, "30", "", 30 * time.Second, true},
+		{"too many requests", http.StatusTooManyRequests, "", "", fallback, true},
+		{"secondary message", http.StatusForbidden, "", `{"message":"You have exceeded a secondary rate limit"}`, fallback, true},
+		{"abuse message", http.StatusForbidden, "", `{"message":"You have triggered an abuse detection mechanism"}`, fallback, true},
+		{"forbidden", http.StatusForbidden, "", `{"message":"Resource not accessible by integration"}`, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tt.status, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(tt.body))}
+			if tt.retryAfter != "" {
+				resp.Header.Set("Retry-After", tt.retryAfter)
+			}
+			wait, limited := Secondary(resp, fallback)
+			if wait != tt.wait || limited != tt.limited {
+				t.Errorf("Secondary() = %v, %v, want %v, %v", wait, limited, tt.wait, tt.limited)
+			}
+			if body, _ := io.ReadAll(resp.Body); string(body) != tt.body {
+				t.Errorf("body after Secondary() = %q, want %q", body, tt.body)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+// Package runnername renders runner names from templates, shared by the ghaec2 scaler and
+// the Lambda scaler.
+package runnername
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultTemplate names runners like <prefix>-<pool>-1718000000-3f9a2c1b
+const DefaultTemplate = "{prefix}-{pool}-{timestamp}-{id}"
+
+// MaxLength is the longest runner name GitHub accepts
+const MaxLength = 64
+
+// placeholder matches {placeholder} in a runner name template
+var placeholder = regexp.MustCompile(`\{([a-z]+)\}`)
+
+// unsafe matches characters that are not allowed in runner names or EC2 tags
+var unsafe = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// Fields are the values a runner name template can refer to. Empty values are dropped
+// together with the separator in front of them.
+type Fields struct {
+	Prefix    string
+	ScaleSet  string
+	Pool      string
+	ShortID   string
+	Timestamp time.Time
+}
+
+// values maps each supported placeholder to its value
+func (f Fields) values() map[string]string {
+	return map[string]string{
+		"prefix":    f.Prefix,
+		"scaleset":  f.ScaleSet,
+		"pool":      f.Pool,
+		"id":        f.ShortID,
+		"timestamp": strconv.FormatInt(f.Timestamp.Unix(), 10),
+	}
+}
+
+// Format renders a runner name template such as "{prefix}-{pool}-{id}". A short random ID
+// is generated when none is given.
+func Format(template string, fields Fields) string {
+	if fields.ShortID == "" {
+		fields.ShortID = NewShortID()
+	}
+	if fields.Timestamp.IsZero() {
+		fields.Timestamp = time.Now()
+	}
+
+	values := fields.values()
+	name := placeholder.ReplaceAllStringFunc(template, func(match string) string {
+		return unsafe.ReplaceAllString(values[match[1:len(match)-1]], "-")
+	})
+
+	// Collapse the separators left behind by empty placeholders
+	for _, sep := range []string{"-", "_", "."} {
+		for strings.Contains(name, sep+sep) {
+			name = strings.ReplaceAll(name, sep+sep, sep)
+		}
+		name = strings.Trim(name, sep)
+	}
+
+	// Trim from the front so the unique suffix survives
+	if len(name) > MaxLength {
+		name = strings.TrimLeft(name[len(name)-MaxLength:], "-_.")
+	}
+	return name
+}
+
+// ValidateTemplate checks that a template only uses known placeholders and includes {id},
+// which keeps names unique when several runners launch at once
+func ValidateTemplate(template string) error {
+	values := Fields{}.values()
+	for _, match := range placeholder.FindAllStringSubmatch(template, -1) {
+		if _, ok := values[match[1]]; !ok {
+			return fmt.Errorf("unknown placeholder {%s}", match[1])
+		}
+	}
+	if !strings.Contains(template, "{id}") {
+		return fmt.Errorf("template %q must contain {id}", template)
+	}
+	return nil
+}
+
+// NewShortID returns 8 random hex characters
+func NewShortID() string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano()%0xffffffff, 16)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,51 @@
+package runnername
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormat(t *testing.T) {
+	at := time.Unix(1718000000, 0)
+	tests := []struct {
+		template string
+		fields   Fields
+		want     string
+	}{
+		{DefaultTemplate, Fields{Prefix: "gha", Pool: "linux", ShortID: "3f9a2c1b", Timestamp: at}, "gha-linux-1718000000-3f9a2c1b"},
+		{DefaultTemplate, Fields{Prefix: "gha", ShortID: "3f9a2c1b", Timestamp: at}, "gha-1718000000-3f9a2c1b"},
+		{"{scaleset}_{id}", Fields{ScaleSet: "my set/x86", ShortID: "ab"}, "my-set-x86_ab"},
+		{"{pool}-{id}", Fields{ShortID: "ab"}, "ab"},
+		{"{prefix}-{id}", Fields{Prefix: strings.Repeat("p", 70), ShortID: "3f9a2c1b"}, strings.Repeat("p", 55) + "-3f9a2c1b"},
+	}
+	for _, tt := range tests {
+		if got := Format(tt.template, tt.fields); got != tt.want {
+			t.Errorf("Format(%q, %+v) = %q, want %q", tt.template, tt.fields, got, tt.want)
+		}
+	}
+}
+
+func TestFormatGeneratesID(t *testing.T) {
+	first, second := Format("{id}", Fields{}), Format("{id}", Fields{})
+	if len(first) != 8 || first == second {
+		t.Errorf("Format generated IDs %q and %q, want two distinct 8 character IDs", first, second)
+	}
+}
+
+func TestValidateTemplate(t *testing.T) {
+	tests := []struct {
+		template string
+		valid    bool
+	}{
+		{DefaultTemplate, true},
+		{"{scaleset}-{id}", true},
+		{"{prefix}-{timestamp}", false},
+		{"{prefix}-{host}-{id}", false},
+	}
+	for _, tt := range tests {
+		if err := ValidateTemplate(tt.template); (err == nil) != tt.valid {
+			t.Errorf("ValidateTemplate(%q) = %v, want valid %v", tt.template, err, tt.valid)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+// Package runnerrecord holds the runner statuses of the DynamoDB runner table shared by the
+// ghaec2 scaler and the Lambda scaler.
+package runnerrecord
+
+// Runner statuses. Removed, failed and interrupted runners are finished: their instance is
+// gone or going, so they never come back.
+const (
+	Pending     = "pending"
+	Running     = "running"
+	Offline     = "offline"
+	Removed     = "removed"
+	Failed      = "failed"
+	Interrupted = "interrupted"
+)
+
+// Finished reports whether the status is one a runner never leaves
+func Finished(status string) bool {
+	switch status {
+	case Removed, Failed, Interrupted:
+		return true
+	}
+	return false
+}
+
+// Reopens reports whether writing status over a record holding current would move a
+// finished runner back to pending or running, which a late or retried write must not do
+func Reopens(current, status string) bool {
+	return Finished(current) && (status == Pending || status == Running)
+}
@@ -0,0 +1,25 @@
+package runnerrecord
+
+import "testing"
+
+func TestReopens(t *testing.T) {
+	tests := []struct {
+		current, status string
+		want            bool
+	}{
+		{"", Pending, false},
+		{Pending, Running, false},
+		{Running, Removed, false},
+		{Offline, Running, false},
+		{Removed, Pending, true},
+		{Failed, Running, true},
+		{Interrupted, Running, true},
+		{Interrupted, Removed, false},
+		{Removed, Offline, false},
+	}
+	for _, tt := range tests {
+		if got := Reopens(tt.current, tt.status); got != tt.want {
+			t.Errorf("Reopens(%q, %q) = %v, want %v", tt.current, tt.status, got, tt.want)
+		}
+	}
+}